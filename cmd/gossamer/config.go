@@ -580,6 +580,11 @@ func setDotCoreConfig(ctx *cli.Context, tomlCfg ctoml.CoreConfig, cfg *dot.CoreC
 	cfg.BabeAuthority = tomlCfg.Roles == types.AuthorityRole
 	cfg.GrandpaAuthority = tomlCfg.Roles == types.AuthorityRole
 	cfg.GrandpaInterval = time.Second * time.Duration(tomlCfg.GrandpaInterval)
+	cfg.RuntimePoolSize = tomlCfg.RuntimePoolSize
+	cfg.TrieCacheSize = tomlCfg.TrieCacheSize
+	cfg.OffchainWorkerConcurrency = tomlCfg.OffchainWorkerConcurrency
+	cfg.OffchainWorkerTimeout = time.Second * time.Duration(tomlCfg.OffchainWorkerTimeout)
+	cfg.ParallelHashingThreshold = tomlCfg.ParallelHashingThreshold
 
 	cfg.BABELead = tomlCfg.BABELead
 	if ctx.IsSet(BABELeadFlag.Name) {
@@ -635,8 +640,12 @@ func setDotCoreConfig(ctx *cli.Context, tomlCfg ctoml.CoreConfig, cfg *dot.CoreC
 	}
 
 	logger.Debugf(
-		"core configuration: babe-authority=%t, grandpa-authority=%t wasm-interpreter=%s grandpa-interval=%s",
-		cfg.BabeAuthority, cfg.GrandpaAuthority, cfg.WasmInterpreter, cfg.GrandpaInterval)
+		"core configuration: babe-authority=%t, grandpa-authority=%t wasm-interpreter=%s "+
+			"grandpa-interval=%s runtime-pool-size=%d trie-cache-size=%d "+
+			"offchain-worker-concurrency=%d offchain-worker-timeout=%s parallel-hashing-threshold=%d",
+		cfg.BabeAuthority, cfg.GrandpaAuthority, cfg.WasmInterpreter, cfg.GrandpaInterval,
+		cfg.RuntimePoolSize, cfg.TrieCacheSize, cfg.OffchainWorkerConcurrency, cfg.OffchainWorkerTimeout,
+		cfg.ParallelHashingThreshold)
 }
 
 // setDotNetworkConfig sets dot.NetworkConfig using flag values from the cli context
@@ -413,6 +413,36 @@ func TestCoreConfigFromFlags(t *testing.T) {
 	}
 }
 
+// TestCoreConfigOffchainWorkers tests the offchain worker options are set from the toml config
+func TestCoreConfigOffchainWorkers(t *testing.T) {
+	ctx, err := newTestContext(t.Name(), nil, nil)
+	require.NoError(t, err)
+
+	tomlCfg := ctoml.CoreConfig{
+		OffchainWorkerConcurrency: 2,
+		OffchainWorkerTimeout:     30,
+	}
+	cfg := new(dot.CoreConfig)
+	setDotCoreConfig(ctx, tomlCfg, cfg)
+
+	assert.Equal(t, uint32(2), cfg.OffchainWorkerConcurrency)
+	assert.Equal(t, 30*time.Second, cfg.OffchainWorkerTimeout)
+}
+
+// TestCoreConfigParallelHashingThreshold tests the parallel hashing threshold is set from the toml config
+func TestCoreConfigParallelHashingThreshold(t *testing.T) {
+	ctx, err := newTestContext(t.Name(), nil, nil)
+	require.NoError(t, err)
+
+	tomlCfg := ctoml.CoreConfig{
+		ParallelHashingThreshold: 128,
+	}
+	cfg := new(dot.CoreConfig)
+	setDotCoreConfig(ctx, tomlCfg, cfg)
+
+	assert.Equal(t, uint32(128), cfg.ParallelHashingThreshold)
+}
+
 // TestNetworkConfigFromFlags tests createDotNetworkConfig using relevant network flags
 func TestNetworkConfigFromFlags(t *testing.T) {
 	testCfg, testCfgFile := newTestConfigWithFile(t)
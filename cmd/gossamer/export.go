@@ -105,6 +105,12 @@ func dotConfigToToml(dcfg *dot.Config) *ctoml.Config {
 		BabeAuthority:    dcfg.Core.BabeAuthority,
 		GrandpaAuthority: dcfg.Core.GrandpaAuthority,
 		GrandpaInterval:  uint32(dcfg.Core.GrandpaInterval / time.Second),
+		RuntimePoolSize:  dcfg.Core.RuntimePoolSize,
+		TrieCacheSize:    dcfg.Core.TrieCacheSize,
+
+		OffchainWorkerConcurrency: dcfg.Core.OffchainWorkerConcurrency,
+		OffchainWorkerTimeout:     uint32(dcfg.Core.OffchainWorkerTimeout / time.Second),
+		ParallelHashingThreshold:  dcfg.Core.ParallelHashingThreshold,
 	}
 
 	cfg.Network = ctoml.NetworkConfig{
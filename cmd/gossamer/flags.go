@@ -179,6 +179,19 @@
 	}
 )
 
+// CheckRuntime-only flags
+var (
+	WasmFlag = cli.StringFlag{
+		Name:  "wasm",
+		Usage: "Path to the .wasm runtime binary to check",
+	}
+	CheckBlocksFlag = cli.UintFlag{
+		Name:  "blocks",
+		Usage: "Number of the last stored blocks to execute with the runtime",
+		Value: 10,
+	}
+)
+
 // BuildSpec-only flags
 var (
 	RawFlag = cli.BoolFlag{
@@ -499,6 +512,14 @@
 		FirstSlotFlag,
 	}
 
+	CheckRuntimeFlags = []cli.Flag{
+		BasePathFlag,
+		ChainFlag,
+		ConfigFlag,
+		WasmFlag,
+		CheckBlocksFlag,
+	}
+
 	PruningFlags = []cli.Flag{
 		ChainFlag,
 		ConfigFlag,
@@ -7,6 +7,7 @@
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/ChainSafe/gossamer/dot"
 	"github.com/ChainSafe/gossamer/dot/state"
@@ -23,6 +24,7 @@
 	buildSpecCommandName     = "build-spec"
 	importRuntimeCommandName = "import-runtime"
 	importStateCommandName   = "import-state"
+	checkRuntimeCommandName  = "check-runtime"
 	pruningStateCommandName  = "prune-state"
 )
 
@@ -110,6 +112,21 @@
 			"\tUsage: gossamer import-state --state state.json --header header.json --first-slot <first slot of network>\n",
 	}
 
+	// checkRuntimeCommand rehearses a runtime upgrade against the state of the node database.
+	checkRuntimeCommand = cli.Command{
+		Action:    FixFlagOrder(checkRuntimeAction),
+		Name:      checkRuntimeCommandName,
+		Usage:     "Rehearse a runtime upgrade against the best state of the node database",
+		ArgsUsage: "",
+		Flags:     CheckRuntimeFlags,
+		Category:  "CHECK-RUNTIME",
+		Description: "The check-runtime command instantiates a .wasm runtime binary on the best state " +
+			"of the node database, calls its version, metadata, BABE and GRANDPA runtime APIs, and executes " +
+			"the last stored blocks with it, reporting failures and diverging state roots. " +
+			"The database is not modified.\n" +
+			"\tUsage: gossamer check-runtime --wasm runtime.wasm --blocks 10 --basepath ~/.gossamer/gssmr\n",
+	}
+
 	pruningCommand = cli.Command{
 		Action:    FixFlagOrder(pruneState),
 		Name:      pruningStateCommandName,
@@ -138,6 +155,7 @@ func init() {
 		buildSpecCommand,
 		importRuntimeCommand,
 		importStateCommand,
+		checkRuntimeCommand,
 		pruningCommand,
 	}
 	app.Flags = RootFlags
@@ -196,6 +214,39 @@ func importRuntimeAction(ctx *cli.Context) error {
 	return nil
 }
 
+// checkRuntimeAction rehearses a runtime upgrade to the given .wasm runtime binary
+// against the best state of the node database, and prints the report.
+func checkRuntimeAction(ctx *cli.Context) error {
+	wasmFP := ctx.String(WasmFlag.Name)
+	if wasmFP == "" {
+		return errors.New("must provide argument to --wasm")
+	}
+
+	code, err := os.ReadFile(filepath.Clean(wasmFP))
+	if err != nil {
+		return fmt.Errorf("cannot read runtime code: %w", err)
+	}
+
+	cfg, err := createImportStateConfig(ctx)
+	if err != nil {
+		logger.Errorf("failed to create node configuration: %s", err)
+		return err
+	}
+	cfg.Global.BasePath = utils.ExpandDir(cfg.Global.BasePath)
+
+	report, err := dot.CheckRuntime(cfg.Global.BasePath, code, ctx.Uint(CheckBlocksFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(report)
+
+	if failed := len(report.Failed()); failed > 0 {
+		return fmt.Errorf("%w: %d of %d calls failed", dot.ErrRuntimeCheckFailed, failed, len(report.Checks))
+	}
+	return nil
+}
+
 // gossamerAction is the root action for the gossamer command, creates a node
 // configuration, loads the keystore, initialises the node if not initialised,
 // then creates and starts the node and node services
@@ -0,0 +1,280 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package dot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ChainSafe/gossamer/dot/state"
+	"github.com/ChainSafe/gossamer/dot/telemetry"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/internal/log"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
+)
+
+// ErrRuntimeCheckFailed is returned when a runtime call failed or diverged during a runtime check
+var ErrRuntimeCheckFailed = errors.New("runtime check failed")
+
+// RuntimeCheck is the result of a runtime call made with the candidate runtime code
+type RuntimeCheck struct {
+	Call   string
+	Result string
+	Err    error
+}
+
+func (c RuntimeCheck) String() string {
+	if c.Err != nil {
+		return fmt.Sprintf("%s: FAILED: %s", c.Call, c.Err)
+	}
+	return fmt.Sprintf("%s: ok: %s", c.Call, c.Result)
+}
+
+// RuntimeCheckReport is the report of the runtime calls made by CheckRuntime
+type RuntimeCheckReport struct {
+	BestBlockHash   common.Hash
+	BestBlockNumber uint
+	Checks          []RuntimeCheck
+}
+
+// Failed returns the runtime calls which failed or diverged from the stored chain
+func (r *RuntimeCheckReport) Failed() (failed []RuntimeCheck) {
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+func (r *RuntimeCheckReport) String() string {
+	lines := make([]string, 0, len(r.Checks)+2)
+	lines = append(lines, fmt.Sprintf("checked runtime against best block #%d (%s)",
+		r.BestBlockNumber, r.BestBlockHash))
+	for _, check := range r.Checks {
+		lines = append(lines, check.String())
+	}
+	lines = append(lines, fmt.Sprintf("%d calls, %d failed", len(r.Checks), len(r.Failed())))
+	return strings.Join(lines, "\n")
+}
+
+// CheckRuntime rehearses a runtime upgrade to the given code against the state of the node database
+// with the given path. It calls the version, metadata, BABE configuration and GRANDPA authorities
+// runtime APIs on the best block state, and executes the last given number of blocks on the state
+// of their parent, reporting failures and state roots diverging from the stored blocks.
+// The database is opened read-write, since chaindb cannot open it read-only, so the node must
+// not be running, and the state service may write to it when started. The runtime calls and the
+// blocks executed do not write to it: their state changes and offchain storages are kept in memory.
+func CheckRuntime(basepath string, code []byte, blocks uint) (*RuntimeCheckReport, error) {
+	// BootstrapMailer should not return an error here since there is no URLs to connect to
+	disabledTelemetry, err := telemetry.BootstrapMailer(context.TODO(), nil, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create telemetry mailer: %w", err)
+	}
+
+	stateSrvc := state.NewService(state.Config{
+		Path:      basepath,
+		LogLevel:  log.Info,
+		Telemetry: disabledTelemetry,
+	})
+
+	err = stateSrvc.SetupBase()
+	if err != nil {
+		return nil, fmt.Errorf("cannot setup state database: %w", err)
+	}
+
+	err = stateSrvc.Start()
+	if err != nil {
+		return nil, fmt.Errorf("cannot start state service: %w", err)
+	}
+	defer func() {
+		stopErr := stateSrvc.Stop()
+		if stopErr != nil {
+			logger.Errorf("cannot stop state service: %s", stopErr)
+		}
+	}()
+
+	bestHeader, err := stateSrvc.Block.BestBlockHeader()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get best block header: %w", err)
+	}
+
+	lastBlocks, err := getLastBlocks(stateSrvc.Block, bestHeader, blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := stateSrvc.Storage.TrieState(&bestHeader.StateRoot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get trie state for best block: %w", err)
+	}
+
+	instance, err := newCheckRuntimeInstance(code, ts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot instantiate runtime code: %w", err)
+	}
+	defer instance.Stop()
+
+	return checkRuntime(instance, stateSrvc.Storage, bestHeader, lastBlocks), nil
+}
+
+// newCheckRuntimeInstance instantiates the given code with in-memory offchain storages
+// and an empty keystore, so the runtime calls cannot modify the node database.
+func newCheckRuntimeInstance(code []byte, ts *rtstorage.TrieState) (runtime.Instance, error) {
+	localStorage, err := newInMemoryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	persistentStorage, err := newInMemoryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	baseDB, err := newInMemoryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rtCfg := &wasmer.Config{
+		Imports: wasmer.ImportsNodeRuntime,
+	}
+	rtCfg.Storage = ts
+	rtCfg.Keystore = keystore.NewGlobalKeystore()
+	rtCfg.LogLvl = log.Info
+	rtCfg.NodeStorage = runtime.NodeStorage{
+		LocalStorage:      localStorage,
+		PersistentStorage: persistentStorage,
+		BaseDB:            baseDB,
+	}
+	rtCfg.CodeHash = common.MustBlake2bHash(code)
+
+	return wasmer.NewInstance(code, rtCfg)
+}
+
+// executedBlock is a stored block executed by a runtime check
+type executedBlock struct {
+	block           *types.Block
+	parentStateRoot common.Hash
+}
+
+// getLastBlocks returns up to the given number of blocks ending with the given best block,
+// in ascending order. The genesis block is never returned since it cannot be executed.
+func getLastBlocks(blockState *state.BlockState, best *types.Header, number uint) (
+	blocks []executedBlock, err error) {
+	hash := best.Hash()
+	for i := uint(0); i < number && i < best.Number; i++ {
+		block, err := blockState.GetBlockByHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get block %s: %w", hash, err)
+		}
+
+		parentHeader, err := blockState.GetHeader(block.Header.ParentHash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get parent header %s: %w", block.Header.ParentHash, err)
+		}
+
+		blocks = append(blocks, executedBlock{
+			block:           block,
+			parentStateRoot: parentHeader.StateRoot,
+		})
+		hash = block.Header.ParentHash
+	}
+
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks, nil
+}
+
+// trieStateGetter gets the trie state with the given root
+type trieStateGetter interface {
+	TrieState(root *common.Hash) (*rtstorage.TrieState, error)
+}
+
+// checkRuntime makes the runtime calls of a runtime check with the given instance,
+// whose storage must be set to the state of the best block given.
+func checkRuntime(instance runtime.Instance, trieStates trieStateGetter,
+	best *types.Header, blocks []executedBlock) *RuntimeCheckReport {
+	report := &RuntimeCheckReport{
+		BestBlockHash:   best.Hash(),
+		BestBlockNumber: best.Number,
+	}
+
+	version, err := instance.Version()
+	check := RuntimeCheck{Call: runtime.CoreVersion, Err: err}
+	if err == nil {
+		check.Result = fmt.Sprintf("%s version %d", version.SpecName(), version.SpecVersion())
+	}
+	report.Checks = append(report.Checks, check)
+
+	metadata, err := instance.Metadata()
+	check = RuntimeCheck{Call: runtime.Metadata, Err: err}
+	if err == nil {
+		check.Result = fmt.Sprintf("%d bytes", len(metadata))
+	}
+	report.Checks = append(report.Checks, check)
+
+	babeConfig, err := instance.BabeConfiguration()
+	check = RuntimeCheck{Call: runtime.BabeAPIConfiguration, Err: err}
+	if err == nil {
+		check.Result = fmt.Sprintf("%d authorities, epoch length %d",
+			len(babeConfig.GenesisAuthorities), babeConfig.EpochLength)
+	}
+	report.Checks = append(report.Checks, check)
+
+	authorities, err := instance.GrandpaAuthorities()
+	check = RuntimeCheck{Call: runtime.GrandpaAuthorities, Err: err}
+	if err == nil {
+		check.Result = fmt.Sprintf("%d authorities", len(authorities))
+	}
+	report.Checks = append(report.Checks, check)
+
+	for _, executed := range blocks {
+		report.Checks = append(report.Checks, checkExecuteBlock(instance, trieStates, executed))
+	}
+
+	return report
+}
+
+// checkExecuteBlock executes the given block on the state of its parent block, and returns
+// a failed check if the execution fails or the resulting state root differs from the block.
+func checkExecuteBlock(instance runtime.Instance, trieStates trieStateGetter,
+	executed executedBlock) (check RuntimeCheck) {
+	header := executed.block.Header
+	check.Call = fmt.Sprintf("%s block #%d (%s)", runtime.CoreExecuteBlock, header.Number, header.Hash())
+
+	ts, err := trieStates.TrieState(&executed.parentStateRoot)
+	if err != nil {
+		check.Err = fmt.Errorf("cannot get trie state for parent block: %w", err)
+		return check
+	}
+
+	instance.SetContextStorage(ts)
+	_, err = instance.ExecuteBlock(executed.block)
+	if err != nil {
+		check.Err = fmt.Errorf("cannot execute block: %w", err)
+		return check
+	}
+
+	root, err := ts.Root()
+	if err != nil {
+		check.Err = fmt.Errorf("cannot compute state root: %w", err)
+		return check
+	}
+
+	if root != header.StateRoot {
+		check.Err = fmt.Errorf("state root %s diverges from block state root %s", root, header.StateRoot)
+		return check
+	}
+
+	check.Result = fmt.Sprintf("state root %s", root)
+	return check
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package dot
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	mocksruntime "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type testTrieStates map[common.Hash]*trie.Trie
+
+func (t testTrieStates) TrieState(root *common.Hash) (*rtstorage.TrieState, error) {
+	tr, ok := t[*root]
+	if !ok {
+		return nil, fmt.Errorf("no trie for root %s", root)
+	}
+	return rtstorage.NewTrieState(tr.Snapshot())
+}
+
+func Test_checkRuntime(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+
+	parentTrie := trie.NewEmptyTrie()
+	parentTrie.Put([]byte("a"), []byte{1})
+	parentRoot := parentTrie.MustHash()
+	trieStates := testTrieStates{parentRoot: parentTrie}
+
+	afterTrie := trie.NewEmptyTrie()
+	afterTrie.Put([]byte("a"), []byte{2})
+
+	best := types.NewEmptyHeader()
+	best.Number = 3
+
+	newBlock := func(number uint, stateRoot common.Hash) *types.Block {
+		header := types.NewEmptyHeader()
+		header.Number = number
+		header.StateRoot = stateRoot
+		return &types.Block{Header: *header, Body: *types.NewBody(nil)}
+	}
+	matchingBlock := newBlock(1, afterTrie.MustHash())
+	divergingBlock := newBlock(2, common.Hash{9})
+	failingBlock := newBlock(3, afterTrie.MustHash())
+
+	instance := new(mocksruntime.Instance)
+	version := runtime.NewVersionData([]byte("node"), nil, 0, 268, 0, nil, 2)
+	instance.On("Version").Return(version, nil)
+	instance.On("Metadata").Return([]byte{1, 2, 3}, nil)
+	instance.On("BabeConfiguration").Return(&types.BabeConfiguration{
+		EpochLength:        200,
+		GenesisAuthorities: []types.AuthorityRaw{{}},
+	}, nil)
+	instance.On("GrandpaAuthorities").Return(nil, errTest)
+	var storage runtime.Storage
+	instance.On("SetContextStorage", mock.AnythingOfType("*storage.TrieState")).Run(func(args mock.Arguments) {
+		storage = args.Get(0).(runtime.Storage)
+	})
+	instance.On("ExecuteBlock", matchingBlock).Run(func(mock.Arguments) {
+		storage.Set([]byte("a"), []byte{2})
+	}).Return(nil, nil)
+	instance.On("ExecuteBlock", divergingBlock).Return(nil, nil)
+	instance.On("ExecuteBlock", failingBlock).Return(nil, errTest)
+
+	report := checkRuntime(instance, trieStates, best, []executedBlock{
+		{block: matchingBlock, parentStateRoot: parentRoot},
+		{block: divergingBlock, parentStateRoot: parentRoot},
+		{block: failingBlock, parentStateRoot: parentRoot},
+		{block: failingBlock, parentStateRoot: common.Hash{1}},
+	})
+
+	expected := &RuntimeCheckReport{
+		BestBlockHash:   best.Hash(),
+		BestBlockNumber: 3,
+		Checks: []RuntimeCheck{
+			{Call: runtime.CoreVersion, Result: "node version 268"},
+			{Call: runtime.Metadata, Result: "3 bytes"},
+			{Call: runtime.BabeAPIConfiguration, Result: "1 authorities, epoch length 200"},
+			{Call: runtime.GrandpaAuthorities, Err: errTest},
+			{
+				Call:   fmt.Sprintf("Core_execute_block block #1 (%s)", matchingBlock.Header.Hash()),
+				Result: fmt.Sprintf("state root %s", afterTrie.MustHash()),
+			},
+			{
+				Call: fmt.Sprintf("Core_execute_block block #2 (%s)", divergingBlock.Header.Hash()),
+				Err: fmt.Errorf("state root %s diverges from block state root %s",
+					parentRoot, common.Hash{9}),
+			},
+			{
+				Call: fmt.Sprintf("Core_execute_block block #3 (%s)", failingBlock.Header.Hash()),
+				Err:  fmt.Errorf("cannot execute block: %w", errTest),
+			},
+			{
+				Call: fmt.Sprintf("Core_execute_block block #3 (%s)", failingBlock.Header.Hash()),
+				Err: fmt.Errorf("cannot get trie state for parent block: %w",
+					fmt.Errorf("no trie for root %s", common.Hash{1})),
+			},
+		},
+	}
+	assert.Equal(t, expected, report)
+	assert.Len(t, report.Failed(), 4)
+	instance.AssertExpectations(t)
+}
+
+func Test_RuntimeCheckReport_String(t *testing.T) {
+	t.Parallel()
+
+	report := &RuntimeCheckReport{
+		BestBlockHash:   common.Hash{1},
+		BestBlockNumber: 5,
+		Checks: []RuntimeCheck{
+			{Call: "Core_version", Result: "node version 268"},
+			{Call: "Metadata_metadata", Err: errors.New("test error")},
+		},
+	}
+
+	const expected = "checked runtime against best block #5 " +
+		"(0x0100000000000000000000000000000000000000000000000000000000000000)\n" +
+		"Core_version: ok: node version 268\n" +
+		"Metadata_metadata: FAILED: test error\n" +
+		"2 calls, 1 failed"
+	require.Equal(t, expected, report.String())
+}
@@ -112,6 +112,23 @@ type CoreConfig struct {
 	GrandpaAuthority bool
 	WasmInterpreter  string
 	GrandpaInterval  time.Duration
+	// RuntimePoolSize is the maximum number of runtime instances per runtime code,
+	// used to run runtime calls concurrently. runtime.DefaultPoolSize is used if it is 0.
+	RuntimePoolSize uint32
+	// TrieCacheSize is the maximum size in MiB of the cache of trie nodes decoded
+	// from the database, shared by all the state tries. trie.DefaultNodeCacheSize
+	// is used if it is 0.
+	TrieCacheSize uint32
+	// OffchainWorkerConcurrency is the maximum number of offchain workers running at once.
+	// core.DefaultOffchainWorkerConcurrency is used if it is 0.
+	OffchainWorkerConcurrency uint32
+	// OffchainWorkerTimeout is the duration after which an offchain worker times out.
+	// core.DefaultOffchainWorkerTimeout is used if it is 0.
+	OffchainWorkerTimeout time.Duration
+	// ParallelHashingThreshold is the minimum number of modified nodes in the subtrie
+	// of a branch for it to be hashed concurrently with its siblings.
+	// node.DefaultParallelEncodingThreshold is used if it is 0.
+	ParallelHashingThreshold uint32
 }
 
 // RPCConfig is to marshal/unmarshal toml RPC config vars
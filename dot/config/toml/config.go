@@ -75,6 +75,15 @@ type CoreConfig struct {
 	WasmInterpreter  string `toml:"wasm-interpreter,omitempty"`
 	GrandpaInterval  uint32 `toml:"grandpa-interval,omitempty"`
 	BABELead         bool   `toml:"babe-lead,omitempty"`
+	RuntimePoolSize  uint32 `toml:"runtime-pool-size,omitempty"`
+	TrieCacheSize    uint32 `toml:"trie-cache-size,omitempty"`
+	// OffchainWorkerConcurrency is the maximum number of offchain workers running at once.
+	OffchainWorkerConcurrency uint32 `toml:"offchain-worker-concurrency,omitempty"`
+	// OffchainWorkerTimeout is the duration in seconds after which an offchain worker times out.
+	OffchainWorkerTimeout uint32 `toml:"offchain-worker-timeout,omitempty"`
+	// ParallelHashingThreshold is the minimum number of modified nodes in the
+	// subtrie of a branch for it to be hashed concurrently with its siblings.
+	ParallelHashingThreshold uint32 `toml:"parallel-hashing-threshold,omitempty"`
 }
 
 // RPCConfig is to marshal/unmarshal toml RPC config vars
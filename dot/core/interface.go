@@ -82,6 +82,11 @@ type EpochState interface {
 	GetCurrentEpoch() (uint64, error)
 }
 
+// TransactionIndexState is the interface for the storage of indexed transaction data
+type TransactionIndexState interface {
+	StoreIndexedTransactions(block *types.Block, ops []rtstorage.IndexOperation) error
+}
+
 // CodeSubstitutedState interface to handle storage of code substitute state
 type CodeSubstitutedState interface {
 	LoadCodeSubstitutedBlockHash() common.Hash
@@ -29,8 +29,7 @@ func (s *Service) validateTransaction(peerID peer.ID, head *types.Header, rt run
 	rt.SetContextStorage(ts)
 
 	// validate each transaction
-	externalExt := types.Extrinsic(append([]byte{byte(types.TxnExternal)}, tx...))
-	validity, err = rt.ValidateTransaction(externalExt)
+	validity, err = rt.ValidateTransaction(types.TxnExternal, tx, head.Hash())
 	if err != nil {
 		if errors.Is(err, runtime.ErrInvalidTransaction) {
 			s.net.ReportPeer(peerset.ReputationChange{
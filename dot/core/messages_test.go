@@ -64,9 +64,11 @@ type mockSetContextStorage struct {
 }
 
 type mockValidateTxn struct {
-	input    types.Extrinsic
-	validity *transaction.Validity
-	err      error
+	source    types.TransactionSource
+	input     types.Extrinsic
+	blockHash common.Hash
+	validity  *transaction.Validity
+	err       error
 }
 
 type mockRuntime struct {
@@ -256,8 +258,10 @@ type args struct {
 				runtime:           runtimeMock2,
 				setContextStorage: &mockSetContextStorage{trieState: &storage.TrieState{}},
 				validateTxn: &mockValidateTxn{
-					input: types.Extrinsic(append([]byte{byte(types.TxnExternal)}, testExtrinsic[0]...)),
-					err:   runtime.ErrInvalidTransaction,
+					source:    types.TxnExternal,
+					input:     testExtrinsic[0],
+					blockHash: testEmptyHeader.Hash(),
+					err:       runtime.ErrInvalidTransaction,
 				},
 			},
 			args: args{
@@ -303,8 +307,10 @@ type args struct {
 				runtime:           runtimeMock3,
 				setContextStorage: &mockSetContextStorage{trieState: &storage.TrieState{}},
 				validateTxn: &mockValidateTxn{
-					input:    types.Extrinsic(append([]byte{byte(types.TxnExternal)}, testExtrinsic[0]...)),
-					validity: &transaction.Validity{Propagate: true},
+					source:    types.TxnExternal,
+					input:     testExtrinsic[0],
+					blockHash: testEmptyHeader.Hash(),
+					validity:  &transaction.Validity{Propagate: true},
 				},
 			},
 			args: args{
@@ -358,7 +364,8 @@ type args struct {
 			if tt.mockRuntime != nil {
 				rt := tt.mockRuntime.runtime
 				rt.On("SetContextStorage", tt.mockRuntime.setContextStorage.trieState)
-				rt.On("ValidateTransaction", tt.mockRuntime.validateTxn.input).
+				rt.On("ValidateTransaction", tt.mockRuntime.validateTxn.source,
+					tt.mockRuntime.validateTxn.input, tt.mockRuntime.validateTxn.blockHash).
 					Return(tt.mockRuntime.validateTxn.validity, tt.mockRuntime.validateTxn.err)
 			}
 
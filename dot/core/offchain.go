@@ -0,0 +1,212 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/life"
+	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
+	"github.com/ChainSafe/gossamer/lib/transaction"
+)
+
+const (
+	// DefaultOffchainWorkerConcurrency is the default maximum number of offchain workers running at once.
+	DefaultOffchainWorkerConcurrency = 4
+	// DefaultOffchainWorkerTimeout is the default duration after which an offchain worker times out.
+	DefaultOffchainWorkerTimeout = time.Minute
+)
+
+var errOffchainWorkerTimeout = errors.New("offchain worker timed out")
+
+// instanceFunc creates a runtime instance, to run an offchain worker or to trace a block.
+type instanceFunc func(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error)
+
+// newInstanceFunc returns the function creating runtime
+// instances with the wasm interpreter of the given name.
+func newInstanceFunc(wasmInterpreter string) instanceFunc {
+	if wasmInterpreter == life.Name {
+		return newLifeInstance
+	}
+	return newWasmerInstance
+}
+
+func newWasmerInstance(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+	instance, err := wasmer.NewInstance(code, &wasmer.Config{
+		InstanceConfig: cfg,
+		Imports:        wasmer.ImportsNodeRuntime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+func newLifeInstance(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+	instance, err := life.NewInstance(code, &life.Config{
+		InstanceConfig: cfg,
+		Resolver:       new(life.Resolver),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// offchainWorkers runs the offchain worker of the runtime on imported best blocks,
+// each in a new runtime instance, if the node is a validator and is synced.
+type offchainWorkers struct {
+	blockState   BlockState
+	storageState StorageState
+	net          Network
+	// transactions is the transaction pool the transactions submitted
+	// by the offchain workers are routed to.
+	transactions runtime.TransactionState
+	newInstance  instanceFunc
+	// slots holds a value for each running offchain worker.
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+func newOffchainWorkers(blockState BlockState, storageState StorageState, net Network,
+	transactions runtime.TransactionState, wasmInterpreter string, concurrency uint,
+	timeout time.Duration) *offchainWorkers {
+	if concurrency == 0 {
+		concurrency = DefaultOffchainWorkerConcurrency
+	}
+
+	if timeout == 0 {
+		timeout = DefaultOffchainWorkerTimeout
+	}
+
+	return &offchainWorkers{
+		blockState:   blockState,
+		storageState: storageState,
+		net:          net,
+		transactions: transactions,
+		newInstance:  newInstanceFunc(wasmInterpreter),
+		slots:        make(chan struct{}, concurrency),
+		timeout:      timeout,
+	}
+}
+
+// schedule starts the offchain worker for the block with the given header in the background.
+// The worker is not run if the maximum number of offchain workers are already running.
+func (o *offchainWorkers) schedule(header *types.Header) {
+	hash := header.Hash()
+
+	rt, err := o.blockState.GetRuntime(&hash)
+	if err != nil {
+		logger.Warnf("failed to get runtime to run offchain worker for block %s: %s", hash, err)
+		return
+	}
+
+	if !rt.Validator() || hash != o.blockState.BestBlockHash() || !o.net.IsSynced() {
+		return
+	}
+
+	select {
+	case o.slots <- struct{}{}:
+	default:
+		logger.Debugf("not running offchain worker for block %s: %d offchain workers already running",
+			hash, cap(o.slots))
+		return
+	}
+
+	go func() {
+		err := o.run(header, rt)
+		if err != nil {
+			logger.Warnf("offchain worker failed for block %s: %s", hash, err)
+		}
+	}()
+}
+
+// run runs the offchain worker for the block with the given header, in a new instance of
+// the given block runtime. It must be called holding a slot, which it releases once the
+// worker is done. Since the wasm execution cannot be interrupted, a worker running past
+// the timeout keeps running in the background until it returns, still holding its slot
+// so the maximum number of offchain workers running is enforced, and run returns
+// errOffchainWorkerTimeout.
+func (o *offchainWorkers) run(header *types.Header, rt runtime.Instance) error {
+	releaseSlot := func() { <-o.slots }
+
+	instance, err := o.newWorkerInstance(header, rt)
+	if err != nil {
+		releaseSlot()
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer releaseSlot()
+		defer instance.Stop()
+		done <- instance.OffchainWorker(header)
+	}()
+
+	timer := time.NewTimer(o.timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w: after %s", errOffchainWorkerTimeout, o.timeout)
+	}
+}
+
+// newWorkerInstance creates a runtime instance running the code of the block with the given
+// header, with the node services of the given block runtime and the block state.
+func (o *offchainWorkers) newWorkerInstance(header *types.Header, rt runtime.Instance) (
+	instance runtime.Instance, err error) {
+	ts, err := o.storageState.TrieState(&header.StateRoot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get trie state: %w", err)
+	}
+
+	code := ts.LoadCode()
+	if len(code) == 0 {
+		return nil, ErrEmptyRuntimeCode
+	}
+
+	cfg := runtime.InstanceConfig{
+		Storage:     ts,
+		Keystore:    rt.Keystore(),
+		NodeStorage: rt.NodeStorage(),
+		Network:     rt.NetworkService(),
+		Transaction: o.transactions,
+		CodeHash:    rt.GetCodeHash(),
+		Role:        4,
+	}
+
+	instance, err = o.newInstance(code, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create runtime instance: %w", err)
+	}
+
+	return instance, nil
+}
+
+// offchainTransactionPool routes the transactions submitted by the offchain
+// workers to the transaction pool, validating them as local transactions.
+type offchainTransactionPool struct {
+	submit func(source types.TransactionSource, ext types.Extrinsic) error
+}
+
+// AddToPool validates the extrinsic of the transaction given at the best block, adds it to
+// the transaction pool and gossips it. It returns the hash of the extrinsic, or the zero
+// hash if the transaction is invalid. The validity of the transaction given is ignored.
+func (p *offchainTransactionPool) AddToPool(vt *transaction.ValidTransaction) common.Hash {
+	err := p.submit(types.TxnLocal, vt.Extrinsic)
+	if err != nil {
+		logger.Warnf("failed to submit transaction from offchain worker: %s", err)
+		return common.Hash{}
+	}
+
+	return vt.Extrinsic.Hash()
+}
@@ -0,0 +1,351 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/life"
+	mocksruntime "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
+	"github.com/ChainSafe/gossamer/lib/transaction"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newOffchainTestTrieState(t *testing.T, code []byte) *rtstorage.TrieState {
+	t.Helper()
+	ts, err := rtstorage.NewTrieState(trie.NewEmptyTrie())
+	require.NoError(t, err)
+	if code != nil {
+		ts.Set(common.CodeKey, code)
+	}
+	return ts
+}
+
+func newOffchainTestRuntime(validator bool) *mocksruntime.Instance {
+	rt := new(mocksruntime.Instance)
+	rt.On("Validator").Return(validator)
+	rt.On("Keystore").Return(&keystore.GlobalKeystore{})
+	rt.On("NodeStorage").Return(runtime.NodeStorage{})
+	rt.On("NetworkService").Return(new(runtime.TestRuntimeNetwork))
+	rt.On("GetCodeHash").Return(common.Hash{1})
+	return rt
+}
+
+func Test_newOffchainWorkers(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		wasmInterpreter string
+		newInstance     instanceFunc
+	}{
+		"default": {
+			newInstance: newWasmerInstance,
+		},
+		"wasmer": {
+			wasmInterpreter: wasmer.Name,
+			newInstance:     newWasmerInstance,
+		},
+		"life": {
+			wasmInterpreter: life.Name,
+			newInstance:     newLifeInstance,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			workers := newOffchainWorkers(nil, nil, nil, nil, testCase.wasmInterpreter, 2, time.Second)
+
+			assert.Equal(t, reflect.ValueOf(testCase.newInstance).Pointer(),
+				reflect.ValueOf(workers.newInstance).Pointer())
+			assert.Equal(t, 2, cap(workers.slots))
+			assert.Equal(t, time.Second, workers.timeout)
+		})
+	}
+}
+
+func Test_offchainWorkers_schedule(t *testing.T) {
+	t.Parallel()
+
+	header := &types.Header{Number: 1}
+	hash := header.Hash()
+
+	t.Run("get runtime error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(nil, errTestDummyError)
+
+		workers := newOffchainWorkers(blockState, nil, nil, nil, "", 1, 0)
+		workers.schedule(header)
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("not a validator", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(newOffchainTestRuntime(false), nil)
+
+		workers := newOffchainWorkers(blockState, nil, nil, nil, "", 1, 0)
+		workers.schedule(header)
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("not the best block", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(newOffchainTestRuntime(true), nil)
+		blockState.EXPECT().BestBlockHash().Return(common.Hash{2})
+
+		workers := newOffchainWorkers(blockState, nil, nil, nil, "", 1, 0)
+		workers.schedule(header)
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("not synced", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(newOffchainTestRuntime(true), nil)
+		blockState.EXPECT().BestBlockHash().Return(hash)
+		net := NewMockNetwork(ctrl)
+		net.EXPECT().IsSynced().Return(false)
+
+		workers := newOffchainWorkers(blockState, nil, net, nil, "", 1, 0)
+		workers.schedule(header)
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("workers at capacity", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(newOffchainTestRuntime(true), nil)
+		blockState.EXPECT().BestBlockHash().Return(hash)
+		net := NewMockNetwork(ctrl)
+		net.EXPECT().IsSynced().Return(true)
+
+		workers := newOffchainWorkers(blockState, nil, net, nil, "", 1, 0)
+		workers.slots <- struct{}{}
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			t.Error("offchain worker should not be run")
+			return nil, nil
+		}
+		workers.schedule(header)
+		assert.Len(t, workers.slots, 1)
+	})
+
+	t.Run("run worker", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&hash).Return(newOffchainTestRuntime(true), nil)
+		blockState.EXPECT().BestBlockHash().Return(hash)
+		net := NewMockNetwork(ctrl)
+		net.EXPECT().IsSynced().Return(true)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(newOffchainTestTrieState(t, []byte{1}), nil)
+
+		stopped := make(chan struct{})
+		instance := new(mocksruntime.Instance)
+		instance.On("OffchainWorker", header).Return(nil).Once()
+		instance.On("Stop").Run(func(_ mock.Arguments) { close(stopped) }).Once()
+
+		workers := newOffchainWorkers(blockState, storageState, net, nil, "", 1, 0)
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			return instance, nil
+		}
+		workers.schedule(header)
+
+		<-stopped
+		instance.AssertExpectations(t)
+	})
+}
+
+func Test_offchainWorkers_run(t *testing.T) {
+	t.Parallel()
+
+	header := &types.Header{Number: 1, StateRoot: common.Hash{3}}
+
+	t.Run("trie state error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(nil, errTestDummyError)
+
+		workers := newOffchainWorkers(nil, storageState, nil, nil, "", 1, 0)
+		workers.slots <- struct{}{}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.ErrorIs(t, err, errTestDummyError)
+		assert.EqualError(t, err, "cannot get trie state: test dummy error")
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("empty code", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(newOffchainTestTrieState(t, nil), nil)
+
+		workers := newOffchainWorkers(nil, storageState, nil, nil, "", 1, 0)
+		workers.slots <- struct{}{}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.ErrorIs(t, err, ErrEmptyRuntimeCode)
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("instance configuration", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		ts := newOffchainTestTrieState(t, []byte{1})
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(ts, nil)
+		transactions := &offchainTransactionPool{}
+
+		workers := newOffchainWorkers(nil, storageState, nil, transactions, "", 1, 0)
+		workers.slots <- struct{}{}
+		workers.newInstance = func(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+			assert.Equal(t, []byte{1}, code)
+			assert.Same(t, ts, cfg.Storage)
+			assert.Same(t, transactions, cfg.Transaction)
+			assert.Equal(t, common.Hash{1}, cfg.CodeHash)
+			assert.Equal(t, byte(4), cfg.Role)
+			return nil, errTestDummyError
+		}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.EqualError(t, err, "cannot create runtime instance: test dummy error")
+		assert.Empty(t, workers.slots)
+	})
+
+	t.Run("worker error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(newOffchainTestTrieState(t, []byte{1}), nil)
+
+		instance := new(mocksruntime.Instance)
+		instance.On("OffchainWorker", header).Return(errTestDummyError).Once()
+		instance.On("Stop").Once()
+
+		workers := newOffchainWorkers(nil, storageState, nil, nil, "", 1, 0)
+		workers.slots <- struct{}{}
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			return instance, nil
+		}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.ErrorIs(t, err, errTestDummyError)
+
+		// the slot is released once the instance is stopped
+		workers.slots <- struct{}{}
+		instance.AssertExpectations(t)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(newOffchainTestTrieState(t, []byte{1}), nil)
+
+		release := make(chan time.Time)
+		stopped := make(chan struct{})
+		instance := new(mocksruntime.Instance)
+		instance.On("OffchainWorker", header).WaitUntil(release).Return(nil).Once()
+		instance.On("Stop").Run(func(_ mock.Arguments) { close(stopped) }).Once()
+
+		workers := newOffchainWorkers(nil, storageState, nil, nil, "", 1, time.Millisecond)
+		workers.slots <- struct{}{}
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			return instance, nil
+		}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.ErrorIs(t, err, errOffchainWorkerTimeout)
+		assert.EqualError(t, err, "offchain worker timed out: after 1ms")
+
+		// the slot of the runaway worker is released once its instance is stopped
+		assert.Len(t, workers.slots, 1)
+		close(release)
+		<-stopped
+		require.Eventually(t, func() bool { return len(workers.slots) == 0 }, time.Second, time.Millisecond)
+		instance.AssertExpectations(t)
+	})
+
+	t.Run("timed out worker holds its slot", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		storageState := NewMockStorageState(ctrl)
+		storageState.EXPECT().TrieState(&header.StateRoot).Return(newOffchainTestTrieState(t, []byte{1}), nil)
+
+		release := make(chan time.Time)
+		stopped := make(chan struct{})
+		instance := new(mocksruntime.Instance)
+		instance.On("OffchainWorker", header).WaitUntil(release).Return(nil).Once()
+		instance.On("Stop").Run(func(_ mock.Arguments) { close(stopped) }).Once()
+
+		nextHeader := &types.Header{Number: 2}
+		nextHash := nextHeader.Hash()
+		blockState := NewMockBlockState(ctrl)
+		blockState.EXPECT().GetRuntime(&nextHash).Return(newOffchainTestRuntime(true), nil)
+		blockState.EXPECT().BestBlockHash().Return(nextHash)
+		net := NewMockNetwork(ctrl)
+		net.EXPECT().IsSynced().Return(true)
+
+		workers := newOffchainWorkers(blockState, storageState, net, nil, "", 1, time.Millisecond)
+		workers.slots <- struct{}{}
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			return instance, nil
+		}
+		err := workers.run(header, newOffchainTestRuntime(true))
+		assert.ErrorIs(t, err, errOffchainWorkerTimeout)
+
+		// the offchain worker of the next block is not run while the timed out worker runs
+		workers.newInstance = func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+			t.Error("offchain worker should not be run")
+			return nil, nil
+		}
+		workers.schedule(nextHeader)
+		assert.Len(t, workers.slots, 1)
+
+		close(release)
+		<-stopped
+		require.Eventually(t, func() bool { return len(workers.slots) == 0 }, time.Second, time.Millisecond)
+		instance.AssertExpectations(t)
+	})
+}
+
+func Test_offchainTransactionPool_AddToPool(t *testing.T) {
+	t.Parallel()
+
+	ext := types.Extrinsic{1, 2, 3}
+	vt := transaction.NewValidTransaction(ext, &transaction.Validity{})
+
+	pool := &offchainTransactionPool{
+		submit: func(source types.TransactionSource, submitted types.Extrinsic) error {
+			assert.Equal(t, types.TxnLocal, source)
+			assert.Equal(t, ext, submitted)
+			return nil
+		},
+	}
+	assert.Equal(t, ext.Hash(), pool.AddToPool(vt))
+
+	pool.submit = func(types.TransactionSource, types.Extrinsic) error {
+		return errTestDummyError
+	}
+	assert.Equal(t, common.Hash{}, pool.AddToPool(vt))
+}
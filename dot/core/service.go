@@ -9,6 +9,7 @@
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/types"
@@ -22,6 +23,7 @@
 	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
 	"github.com/ChainSafe/gossamer/lib/services"
 	"github.com/ChainSafe/gossamer/lib/transaction"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	cscale "github.com/centrifuge/go-substrate-rpc-client/v3/scale"
 	ctypes "github.com/centrifuge/go-substrate-rpc-client/v3/types"
 )
@@ -52,10 +54,18 @@ type Service struct {
 	transactionState TransactionState
 	net              Network
 
+	// transactionIndexState stores the data indexed by the runtime, it is optional
+	transactionIndexState TransactionIndexState
+
 	// map of code substitutions keyed by block hash
 	codeSubstitute       map[common.Hash]string
 	codeSubstitutedState CodeSubstitutedState
 
+	// offchainWorkers runs the offchain workers on imported blocks, it is optional
+	offchainWorkers *offchainWorkers
+	// newInstance creates the runtime instances the blocks are traced on
+	newInstance instanceFunc
+
 	// Keystore
 	keys *keystore.GlobalKeystore
 }
@@ -72,8 +82,20 @@ type Config struct {
 	Keystore         *keystore.GlobalKeystore
 	Runtime          runtime.Instance
 
+	TransactionIndexState TransactionIndexState
+
 	CodeSubstitutes      map[common.Hash]string
 	CodeSubstitutedState CodeSubstitutedState
+
+	// WasmInterpreter is the name of the wasm interpreter the offchain workers
+	// are run and the blocks are traced with, wasmer is used if it is empty.
+	WasmInterpreter string
+	// OffchainWorkerConcurrency is the maximum number of offchain workers running at once,
+	// DefaultOffchainWorkerConcurrency is used if it is 0.
+	OffchainWorkerConcurrency uint
+	// OffchainWorkerTimeout is the duration after which an offchain worker times out,
+	// DefaultOffchainWorkerTimeout is used if it is 0.
+	OffchainWorkerTimeout time.Duration
 }
 
 // NewService returns a new core service that connects the runtime, BABE
@@ -116,8 +138,15 @@ func NewService(cfg *Config) (*Service, error) {
 		blockAddCh:           blockAddCh,
 		codeSubstitute:       cfg.CodeSubstitutes,
 		codeSubstitutedState: cfg.CodeSubstitutedState,
+
+		transactionIndexState: cfg.TransactionIndexState,
+		newInstance:           newInstanceFunc(cfg.WasmInterpreter),
 	}
 
+	srv.offchainWorkers = newOffchainWorkers(cfg.BlockState, cfg.StorageState, cfg.Network,
+		&offchainTransactionPool{submit: srv.submitExtrinsic}, cfg.WasmInterpreter,
+		cfg.OffchainWorkerConcurrency, cfg.OffchainWorkerTimeout)
+
 	return srv, nil
 }
 
@@ -212,10 +241,18 @@ func (s *Service) handleBlock(block *types.Block, state *rtstorage.TrieState) er
 	logger.Debugf("imported block %s and stored state trie with root %s",
 		block.Header.Hash(), state.MustRoot())
 
+	if s.transactionIndexState != nil {
+		err = s.transactionIndexState.StoreIndexedTransactions(block, state.TransactionIndexOperations())
+		if err != nil {
+			return fmt.Errorf("failed to store indexed transactions: %w", err)
+		}
+	}
+
 	rt, err := s.blockState.GetRuntime(&block.Header.ParentHash)
 	if err != nil {
 		return err
 	}
+	rt.SetContextStorage(state)
 
 	// check for runtime changes
 	if err := s.blockState.HandleRuntimeChanges(state, rt, block.Header.Hash()); err != nil {
@@ -313,6 +350,10 @@ func (s *Service) handleBlocksAsync() {
 			}
 
 			s.maintainTransactionPool(block)
+
+			if s.offchainWorkers != nil {
+				s.offchainWorkers.schedule(&block.Header)
+			}
 		case <-s.ctx.Done():
 			return
 		}
@@ -347,7 +388,8 @@ func (s *Service) handleChainReorg(prev, curr common.Hash) error {
 	}
 
 	// Check transaction validation on the best block.
-	rt, err := s.blockState.GetRuntime(nil)
+	bestBlockHash := s.blockState.BestBlockHash()
+	rt, err := s.blockState.GetRuntime(&bestBlockHash)
 	if err != nil {
 		return err
 	}
@@ -356,6 +398,12 @@ func (s *Service) handleChainReorg(prev, curr common.Hash) error {
 		return ErrNilRuntime
 	}
 
+	ts, err := s.storageState.TrieState(nil)
+	if err != nil {
+		return fmt.Errorf("cannot get best block state: %w", err)
+	}
+	rt.SetContextStorage(ts)
+
 	// for each block in the previous chain, re-add its extrinsics back into the pool
 	for _, hash := range subchain {
 		body, err := s.blockState.GetBlockBody(hash)
@@ -376,10 +424,7 @@ func (s *Service) handleChainReorg(prev, curr common.Hash) error {
 				continue
 			}
 
-			externalExt := make(types.Extrinsic, 0, 1+len(ext))
-			externalExt = append(externalExt, byte(types.TxnExternal))
-			externalExt = append(externalExt, ext...)
-			txv, err := rt.ValidateTransaction(externalExt)
+			txv, err := rt.ValidateTransaction(types.TxnExternal, ext, bestBlockHash)
 			if err != nil {
 				logger.Debugf("failed to validate transaction for extrinsic %s: %s", ext, err)
 				continue
@@ -406,13 +451,13 @@ func (s *Service) maintainTransactionPool(block *types.Block) {
 	txs := s.transactionState.PendingInPool()
 	for _, tx := range txs {
 		// get the best block corresponding runtime
-		rt, err := s.blockState.GetRuntime(nil)
+		rt, err := s.bestBlockRuntime()
 		if err != nil {
 			logger.Warnf("failed to get runtime to re-validate transactions in pool: %s", err)
 			continue
 		}
 
-		txnValidity, err := rt.ValidateTransaction(tx.Extrinsic)
+		txnValidity, err := rt.ValidateTransaction(types.TxnExternal, tx.Extrinsic, s.blockState.BestBlockHash())
 		if err != nil {
 			s.transactionState.RemoveExtrinsic(tx.Extrinsic)
 			continue
@@ -451,7 +496,7 @@ func (s *Service) HasKey(pubKeyStr, keystoreType string) (bool, error) {
 
 // DecodeSessionKeys executes the runtime DecodeSessionKeys and return the scale encoded keys
 func (s *Service) DecodeSessionKeys(enc []byte) ([]byte, error) {
-	rt, err := s.blockState.GetRuntime(nil)
+	rt, err := s.bestBlockRuntime()
 	if err != nil {
 		return nil, err
 	}
@@ -459,6 +504,23 @@ func (s *Service) DecodeSessionKeys(enc []byte) ([]byte, error) {
 	return rt.DecodeSessionKeys(enc)
 }
 
+// bestBlockRuntime returns the runtime of the best block,
+// with its storage set to the state of the best block.
+func (s *Service) bestBlockRuntime() (rt runtime.Instance, err error) {
+	rt, err = s.blockState.GetRuntime(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := s.storageState.TrieState(nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get best block state: %w", err)
+	}
+
+	rt.SetContextStorage(ts)
+	return rt, nil
+}
+
 // GetRuntimeVersion gets the current RuntimeVersion
 func (s *Service) GetRuntimeVersion(bhash *common.Hash) (runtime.Version, error) {
 	var stateRootHash *common.Hash
@@ -492,6 +554,13 @@ func (s *Service) HandleSubmittedExtrinsic(ext types.Extrinsic) error {
 		return nil
 	}
 
+	// the transaction source is External
+	return s.submitExtrinsic(types.TxnExternal, ext)
+}
+
+// submitExtrinsic validates the extrinsic from the given source at the best block,
+// adds it to the transaction pool and gossips it.
+func (s *Service) submitExtrinsic(source types.TransactionSource, ext types.Extrinsic) error {
 	if s.transactionState.Exists(ext) {
 		return nil
 	}
@@ -515,9 +584,7 @@ func (s *Service) HandleSubmittedExtrinsic(ext types.Extrinsic) error {
 	}
 
 	rt.SetContextStorage(ts)
-	// the transaction source is External
-	externalExt := types.Extrinsic(append([]byte{byte(types.TxnExternal)}, ext...))
-	txv, err := rt.ValidateTransaction(externalExt)
+	txv, err := rt.ValidateTransaction(source, ext, bestBlockHash)
 	if err != nil {
 		return err
 	}
@@ -616,8 +683,9 @@ func (s *Service) tryQueryStorage(block common.Hash, keys ...string) (QueryKeyVa
 }
 
 // GetReadProofAt will return an array with the proofs for the keys passed as params
-// based on the block hash passed as param as well, if block hash is nil then the current state will take place
-func (s *Service) GetReadProofAt(block common.Hash, keys [][]byte) (
+// based on the block hash passed as param as well, if block hash is nil then the current state will take place.
+// If compact is true, the proof is encoded in the compact format of Substrate.
+func (s *Service) GetReadProofAt(block common.Hash, keys [][]byte, compact bool) (
 	hash common.Hash, proofForKeys [][]byte, err error) {
 	if block.IsEmpty() {
 		block = s.blockState.BestBlockHash()
@@ -633,5 +701,75 @@ func (s *Service) GetReadProofAt(block common.Hash, keys [][]byte) (
 		return hash, nil, err
 	}
 
+	if compact {
+		proofForKeys, err = trie.EncodeCompactProof(proofForKeys, stateRoot[:])
+		if err != nil {
+			return hash, nil, fmt.Errorf("cannot encode compact proof: %w", err)
+		}
+	}
+
 	return block, proofForKeys, nil
 }
+
+// TraceBlock executes the block with the given hash on the state of its parent, and
+// returns the storage accesses made by the runtime. If key prefixes are given, only
+// the accesses to keys starting with one of the prefixes are returned. It also returns
+// the hash of the parent block.
+func (s *Service) TraceBlock(hash common.Hash, keyPrefixes [][]byte) (
+	parentHash common.Hash, events []rtstorage.TraceEvent, err error) {
+	block, err := s.blockState.GetBlockByHash(hash)
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot get block: %w", err)
+	}
+
+	parentHash = block.Header.ParentHash
+	stateRoot, err := s.storageState.GetStateRootFromBlock(&parentHash)
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot get parent state root: %w", err)
+	}
+
+	ts, err := s.storageState.TrieState(stateRoot)
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot get parent trie state: %w", err)
+	}
+
+	code := ts.LoadCode()
+	if len(code) == 0 {
+		return parentHash, nil, ErrEmptyRuntimeCode
+	}
+
+	// the runtime of the parent block is not kept once it is finalised, so the block
+	// is executed on a new instance running the code of the parent state, with the
+	// node services of the best block runtime.
+	rt, err := s.blockState.GetRuntime(nil)
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot get best block runtime: %w", err)
+	}
+
+	instance, err := s.newInstance(code, runtime.InstanceConfig{
+		Storage:     ts,
+		Keystore:    rt.Keystore(),
+		NodeStorage: rt.NodeStorage(),
+		Network:     rt.NetworkService(),
+	})
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot create runtime instance: %w", err)
+	}
+	defer instance.Stop()
+
+	tracer := rtstorage.NewTracer()
+	ts.SetTracer(tracer)
+
+	_, err = instance.ExecuteBlock(block)
+	if err != nil {
+		return parentHash, nil, fmt.Errorf("cannot execute block %d: %w", block.Header.Number, err)
+	}
+
+	for _, event := range tracer.Events() {
+		if event.HasKeyPrefix(keyPrefixes) {
+			events = append(events, event)
+		}
+	}
+
+	return parentHash, events, nil
+}
@@ -6,6 +6,7 @@
 package core
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"os"
@@ -415,7 +416,7 @@ func TestHandleChainReorg_WithReorg_Transactions(t *testing.T) {
 	rt, err := s.blockState.GetRuntime(&bhash)
 	require.NoError(t, err)
 
-	validity, err := rt.ValidateTransaction(tx)
+	validity, err := rt.ValidateTransaction(types.TxnExternal, tx, bhash)
 	require.NoError(t, err)
 
 	// get common ancestor
@@ -692,6 +693,42 @@ func TestService_HandleRuntimeChanges(t *testing.T) {
 	require.Equal(t, v.SpecVersion(), updatedSpecVersion)
 }
 
+func TestService_HandleRuntimeChanges_HeapPages(t *testing.T) {
+	s := NewTestService(t, nil)
+
+	hash := s.blockState.BestBlockHash() // genesisHash
+	parentRt, err := s.blockState.GetRuntime(&hash)
+	require.NoError(t, err)
+	require.Equal(t, runtime.DefaultHeapPages, parentRt.GetHeapPages())
+
+	ts, err := s.storageState.TrieState(nil) // Pass genesis root
+	require.NoError(t, err)
+
+	const heapPages = uint64(4096)
+	encodedHeapPages := make([]byte, 8)
+	binary.LittleEndian.PutUint64(encodedHeapPages, heapPages)
+	ts.Set(common.HeapPagesKey, encodedHeapPages)
+
+	newBlock := &types.Block{
+		Header: types.Header{
+			ParentHash: hash,
+			Number:     1,
+			Digest:     types.NewDigest(),
+		},
+		Body: *types.NewBody([]types.Extrinsic{[]byte("Heap pages update")}),
+	}
+	bhash := newBlock.Header.Hash()
+
+	err = s.blockState.HandleRuntimeChanges(ts, parentRt, bhash)
+	require.NoError(t, err)
+
+	rt, err := s.blockState.GetRuntime(&bhash)
+	require.NoError(t, err)
+	require.NotEqual(t, parentRt, rt)
+	require.Equal(t, heapPages, rt.GetHeapPages())
+	require.Equal(t, parentRt.GetCodeHash(), rt.GetCodeHash())
+}
+
 func TestService_HandleCodeSubstitutes(t *testing.T) {
 	s := NewTestService(t, nil)
 
@@ -30,6 +30,7 @@
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -57,7 +58,7 @@ func generateTestCentrifugeMetadata(t *testing.T) *ctypes.Metadata {
 	return meta
 }
 
-func generateExtrinsic(t *testing.T) (extrinsic, externalExtrinsic types.Extrinsic, body *types.Body) {
+func generateExtrinsic(t *testing.T) (extrinsic types.Extrinsic, body *types.Body) {
 	t.Helper()
 	meta := generateTestCentrifugeMetadata(t)
 
@@ -111,9 +112,8 @@ func generateExtrinsic(t *testing.T) (extrinsic, externalExtrinsic types.Extrins
 	require.NoError(t, err)
 
 	encExt := []types.Extrinsic{extEnc.Bytes()}
-	testExternalExt := types.Extrinsic(append([]byte{byte(types.TxnExternal)}, encExt[0]...))
 	testUnencryptedBody := types.NewBody(encExt)
-	return encExt[0], testExternalExt, testUnencryptedBody
+	return encExt[0], testUnencryptedBody
 }
 
 func Test_Service_StorageRoot(t *testing.T) {
@@ -393,6 +393,7 @@ func Test_Service_handleBlock(t *testing.T) {
 		mockBlockState := NewMockBlockState(ctrl)
 		mockBlockState.EXPECT().AddBlock(&block).Return(blocktree.ErrBlockExists)
 		mockBlockState.EXPECT().GetRuntime(&block.Header.ParentHash).Return(runtimeMock, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		mockBlockState.EXPECT().HandleRuntimeChanges(trieState, runtimeMock, block.Header.Hash()).
 			Return(errTestDummyError)
 
@@ -420,6 +421,7 @@ func Test_Service_handleBlock(t *testing.T) {
 		mockBlockState := NewMockBlockState(ctrl)
 		mockBlockState.EXPECT().AddBlock(&block).Return(blocktree.ErrBlockExists)
 		mockBlockState.EXPECT().GetRuntime(&block.Header.ParentHash).Return(runtimeMock, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		mockBlockState.EXPECT().HandleRuntimeChanges(trieState, runtimeMock, block.Header.Hash()).Return(nil)
 
 		service := &Service{
@@ -480,6 +482,7 @@ func Test_Service_HandleBlockProduced(t *testing.T) {
 		mockBlockState := NewMockBlockState(ctrl)
 		mockBlockState.EXPECT().AddBlock(&block).Return(blocktree.ErrBlockExists)
 		mockBlockState.EXPECT().GetRuntime(&block.Header.ParentHash).Return(runtimeMock, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		mockBlockState.EXPECT().HandleRuntimeChanges(trieState, runtimeMock, block.Header.Hash()).Return(nil)
 		mockNetwork := NewMockNetwork(ctrl)
 		mockNetwork.EXPECT().GossipMessage(msg)
@@ -517,15 +520,22 @@ func Test_Service_maintainTransactionPool(t *testing.T) {
 
 		ctrl := gomock.NewController(t)
 		runtimeMock := new(mocksruntime.Instance)
-		runtimeMock.On("ValidateTransaction", types.Extrinsic{21}).Return(nil, errTestDummyError)
+		runtimeMock.On("ValidateTransaction", types.TxnExternal, types.Extrinsic{21}, common.Hash{1}).
+			Return(nil, errTestDummyError)
 		mockTxnState := NewMockTransactionState(ctrl)
 		mockTxnState.EXPECT().RemoveExtrinsic(types.Extrinsic{21}).Times(2)
 		mockTxnState.EXPECT().PendingInPool().Return([]*transaction.ValidTransaction{vt})
 		mockBlockState := NewMockBlockState(ctrl)
 		mockBlockState.EXPECT().GetRuntime(nil).Return(runtimeMock, nil)
+		mockBlockState.EXPECT().BestBlockHash().Return(common.Hash{1})
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		service := &Service{
 			transactionState: mockTxnState,
 			blockState:       mockBlockState,
+			storageState:     mockStorageState,
 		}
 		service.maintainTransactionPool(&block)
 	})
@@ -552,7 +562,7 @@ func Test_Service_maintainTransactionPool(t *testing.T) {
 
 		ctrl := gomock.NewController(t)
 		runtimeMock := new(mocksruntime.Instance)
-		runtimeMock.On("ValidateTransaction", types.Extrinsic{21}).
+		runtimeMock.On("ValidateTransaction", types.TxnExternal, types.Extrinsic{21}, common.Hash{1}).
 			Return(&transaction.Validity{Propagate: true}, nil)
 		mockTxnState := NewMockTransactionState(ctrl)
 		mockTxnState.EXPECT().RemoveExtrinsic(types.Extrinsic{21})
@@ -561,9 +571,15 @@ func Test_Service_maintainTransactionPool(t *testing.T) {
 		mockTxnState.EXPECT().RemoveExtrinsicFromPool(types.Extrinsic{21})
 		mockBlockStateOk := NewMockBlockState(ctrl)
 		mockBlockStateOk.EXPECT().GetRuntime(nil).Return(runtimeMock, nil)
+		mockBlockStateOk.EXPECT().BestBlockHash().Return(common.Hash{1})
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		service := &Service{
 			transactionState: mockTxnState,
 			blockState:       mockBlockStateOk,
+			storageState:     mockStorageState,
 		}
 		service.maintainTransactionPool(&block)
 	})
@@ -641,15 +657,20 @@ func Test_Service_handleBlocksAsync(t *testing.T) {
 
 		ctrl := gomock.NewController(t)
 		runtimeMock := new(mocksruntime.Instance)
-		runtimeMock.On("ValidateTransaction", types.Extrinsic{21}).Return(nil, errTestDummyError)
+		runtimeMock.On("ValidateTransaction", types.TxnExternal, types.Extrinsic{21}, common.Hash{}).
+			Return(nil, errTestDummyError)
 		mockBlockState := NewMockBlockState(ctrl)
-		mockBlockState.EXPECT().BestBlockHash().Return(common.Hash{}).Times(2)
+		mockBlockState.EXPECT().BestBlockHash().Return(common.Hash{}).Times(3)
 		mockBlockState.EXPECT().HighestCommonAncestor(common.Hash{}, block.Header.Hash()).
 			Return(common.Hash{}, errTestDummyError)
 		mockBlockState.EXPECT().GetRuntime(nil).Return(runtimeMock, nil)
 		mockTxnStateErr := NewMockTransactionState(ctrl)
 		mockTxnStateErr.EXPECT().RemoveExtrinsic(types.Extrinsic{21}).Times(2)
 		mockTxnStateErr.EXPECT().PendingInPool().Return([]*transaction.ValidTransaction{vt})
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		blockAddChan := make(chan *types.Block)
 		go func() {
 			blockAddChan <- &block
@@ -657,6 +678,7 @@ func Test_Service_handleBlocksAsync(t *testing.T) {
 		}()
 		service := &Service{
 			blockState:       mockBlockState,
+			storageState:     mockStorageState,
 			transactionState: mockTxnStateErr,
 			blockAddCh:       blockAddChan,
 			ctx:              context.Background(),
@@ -681,7 +703,7 @@ func TestService_handleChainReorg(t *testing.T) {
 	testSubChain := []common.Hash{testPrevHash, testCurrentHash, testAncestorHash}
 
 	// A valid extrinsic is needed since it will be validated in handleChainReorg
-	ext, externExt, body := generateExtrinsic(t)
+	ext, body := generateExtrinsic(t)
 	testValidity := &transaction.Validity{Propagate: true}
 	vtx := transaction.NewValidTransaction(ext, testValidity)
 
@@ -759,7 +781,8 @@ func TestService_handleChainReorg(t *testing.T) {
 		mockBlockState.EXPECT().HighestCommonAncestor(testPrevHash, testCurrentHash).
 			Return(testAncestorHash, nil)
 		mockBlockState.EXPECT().SubChain(testAncestorHash, testPrevHash).Return(testSubChain, nil)
-		mockBlockState.EXPECT().GetRuntime(nil).Return(nil, errDummyErr)
+		mockBlockState.EXPECT().BestBlockHash().Return(testCurrentHash)
+		mockBlockState.EXPECT().GetRuntime(&testCurrentHash).Return(nil, errDummyErr)
 
 		service := &Service{
 			blockState: mockBlockState,
@@ -775,13 +798,19 @@ func TestService_handleChainReorg(t *testing.T) {
 		mockBlockState.EXPECT().HighestCommonAncestor(testPrevHash, testCurrentHash).
 			Return(testAncestorHash, nil)
 		mockBlockState.EXPECT().SubChain(testAncestorHash, testPrevHash).Return(testSubChain, nil)
-		mockBlockState.EXPECT().GetRuntime(nil).Return(runtimeMockErr, nil)
+		mockBlockState.EXPECT().BestBlockHash().Return(testCurrentHash)
+		mockBlockState.EXPECT().GetRuntime(&testCurrentHash).Return(runtimeMockErr, nil)
 		mockBlockState.EXPECT().GetBlockBody(testCurrentHash).Return(nil, errDummyErr)
 		mockBlockState.EXPECT().GetBlockBody(testAncestorHash).Return(body, nil)
-		runtimeMockErr.On("ValidateTransaction", externExt).Return(nil, errTestDummyError)
+		runtimeMockErr.On("ValidateTransaction", types.TxnExternal, ext, testCurrentHash).Return(nil, errTestDummyError)
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMockErr.On("SetContextStorage", trieState)
 
 		service := &Service{
-			blockState: mockBlockState,
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
 		}
 		execTest(t, service, testPrevHash, testCurrentHash, nil)
 	})
@@ -794,16 +823,22 @@ func TestService_handleChainReorg(t *testing.T) {
 		mockBlockState.EXPECT().HighestCommonAncestor(testPrevHash, testCurrentHash).
 			Return(testAncestorHash, nil)
 		mockBlockState.EXPECT().SubChain(testAncestorHash, testPrevHash).Return(testSubChain, nil)
-		mockBlockState.EXPECT().GetRuntime(nil).Return(runtimeMockOk, nil)
+		mockBlockState.EXPECT().BestBlockHash().Return(testCurrentHash)
+		mockBlockState.EXPECT().GetRuntime(&testCurrentHash).Return(runtimeMockOk, nil)
 		mockBlockState.EXPECT().GetBlockBody(testCurrentHash).Return(nil, errDummyErr)
 		mockBlockState.EXPECT().GetBlockBody(testAncestorHash).Return(body, nil)
-		runtimeMockOk.On("ValidateTransaction", externExt).
+		runtimeMockOk.On("ValidateTransaction", types.TxnExternal, ext, testCurrentHash).
 			Return(testValidity, nil)
 		mockTxnStateOk := NewMockTransactionState(ctrl)
 		mockTxnStateOk.EXPECT().AddToPool(vtx).Return(common.Hash{})
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMockOk.On("SetContextStorage", trieState)
 
 		service := &Service{
 			blockState:       mockBlockState,
+			storageState:     mockStorageState,
 			transactionState: mockTxnStateOk,
 		}
 		execTest(t, service, testPrevHash, testCurrentHash, nil)
@@ -941,8 +976,13 @@ func TestService_DecodeSessionKeys(t *testing.T) {
 		runtimeMock.On("DecodeSessionKeys", testEncKeys).Return(testEncKeys, nil)
 		mockBlockState := NewMockBlockState(ctrl)
 		mockBlockState.EXPECT().GetRuntime(nil).Return(runtimeMock, nil)
+		trieState := &rtstorage.TrieState{}
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().TrieState(nil).Return(trieState, nil)
+		runtimeMock.On("SetContextStorage", trieState)
 		service := &Service{
-			blockState: mockBlockState,
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
 		}
 		execTest(t, service, testEncKeys, testEncKeys, nil)
 	})
@@ -1049,7 +1089,6 @@ func TestServiceGetRuntimeVersion(t *testing.T) {
 func TestServiceHandleSubmittedExtrinsic(t *testing.T) {
 	t.Parallel()
 	ext := types.Extrinsic{}
-	externalExt := types.Extrinsic(append([]byte{byte(types.TxnExternal)}, ext...))
 	execTest := func(t *testing.T, s *Service, ext types.Extrinsic, expErr error) {
 		err := s.HandleSubmittedExtrinsic(ext)
 		assert.ErrorIs(t, err, expErr)
@@ -1123,7 +1162,8 @@ func TestServiceHandleSubmittedExtrinsic(t *testing.T) {
 		mockTxnState.EXPECT().Exists(types.Extrinsic{})
 
 		runtimeMockErr.On("SetContextStorage", &rtstorage.TrieState{})
-		runtimeMockErr.On("ValidateTransaction", externalExt).Return(nil, errDummyErr)
+		runtimeMockErr.On("ValidateTransaction", types.TxnExternal, types.Extrinsic{}, common.Hash{}).
+			Return(nil, errDummyErr)
 		service := &Service{
 			storageState:     mockStorageState,
 			transactionState: mockTxnState,
@@ -1142,7 +1182,7 @@ func TestServiceHandleSubmittedExtrinsic(t *testing.T) {
 		mockBlockState.EXPECT().BestBlockHash().Return(common.Hash{})
 		mockBlockState.EXPECT().GetRuntime(&common.Hash{}).Return(runtimeMock, nil).MaxTimes(2)
 		runtimeMock.On("SetContextStorage", &rtstorage.TrieState{})
-		runtimeMock.On("ValidateTransaction", externalExt).
+		runtimeMock.On("ValidateTransaction", types.TxnExternal, types.Extrinsic{}, common.Hash{}).
 			Return(&transaction.Validity{Propagate: true}, nil)
 
 		mockStorageState := NewMockStorageState(ctrl)
@@ -1327,12 +1367,12 @@ func TestService_QueryStorage(t *testing.T) {
 
 func TestService_GetReadProofAt(t *testing.T) {
 	t.Parallel()
-	execTest := func(t *testing.T, s *Service, block common.Hash, keys [][]byte,
-		expHash common.Hash, expProofForKeys [][]byte, expErr error) {
-		resHash, resProofForKeys, err := s.GetReadProofAt(block, keys)
+	execTest := func(t *testing.T, s *Service, block common.Hash, keys [][]byte, compact bool,
+		expHash common.Hash, expProofForKeys [][]byte, expErr error, expErrMessage string) {
+		resHash, resProofForKeys, err := s.GetReadProofAt(block, keys, compact)
 		assert.ErrorIs(t, err, expErr)
 		if expErr != nil {
-			assert.EqualError(t, err, expErr.Error())
+			assert.EqualError(t, err, expErrMessage)
 		}
 		assert.Equal(t, expHash, resHash)
 		assert.Equal(t, expProofForKeys, resProofForKeys)
@@ -1347,7 +1387,7 @@ func TestService_GetReadProofAt(t *testing.T) {
 		service := &Service{
 			blockState: mockBlockState,
 		}
-		execTest(t, service, common.Hash{}, nil, common.Hash{}, nil, errDummyErr)
+		execTest(t, service, common.Hash{}, nil, false, common.Hash{}, nil, errDummyErr, errDummyErr.Error())
 	})
 
 	t.Run("generate trie proof error", func(t *testing.T) {
@@ -1363,7 +1403,7 @@ func TestService_GetReadProofAt(t *testing.T) {
 			blockState:   mockBlockState,
 			storageState: mockStorageState,
 		}
-		execTest(t, service, common.Hash{}, [][]byte{{1}}, common.Hash{}, nil, errDummyErr)
+		execTest(t, service, common.Hash{}, [][]byte{{1}}, false, common.Hash{}, nil, errDummyErr, errDummyErr.Error())
 	})
 
 	t.Run("happy path", func(t *testing.T) {
@@ -1379,6 +1419,181 @@ func TestService_GetReadProofAt(t *testing.T) {
 			blockState:   mockBlockState,
 			storageState: mockStorageState,
 		}
-		execTest(t, service, common.Hash{}, [][]byte{{1}}, common.Hash{2}, [][]byte{{2}}, nil)
+		execTest(t, service, common.Hash{}, [][]byte{{1}}, false, common.Hash{2}, [][]byte{{2}}, nil, "")
+	})
+
+	t.Run("compact proof error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().BestBlockHash().Return(common.Hash{2})
+		mockBlockState.EXPECT().GetBlockStateRoot(common.Hash{2}).Return(common.Hash{3}, nil)
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().GenerateTrieProof(common.Hash{3}, [][]byte{{1}}).
+			Return([][]byte{{2}}, nil)
+		service := &Service{
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
+		}
+		const expErrMessage = "cannot encode compact proof: cannot encode state trie: " +
+			"root node not found in proof: for hash " +
+			"0x0300000000000000000000000000000000000000000000000000000000000000"
+		execTest(t, service, common.Hash{}, [][]byte{{1}}, true, common.Hash{}, nil,
+			trie.ErrRootNotInProof, expErrMessage)
+	})
+
+	t.Run("compact proof", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		// leaf node with key 0x01 and a value of 40 bytes
+		leafEncoding := append([]byte{0x42, 0x01, 40 << 2}, bytes.Repeat([]byte{9}, 40)...)
+		stateRoot, err := common.Blake2bHash(leafEncoding)
+		require.NoError(t, err)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().GetBlockStateRoot(common.Hash{2}).Return(stateRoot, nil)
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().GenerateTrieProof(stateRoot, [][]byte{{1}}).
+			Return([][]byte{leafEncoding}, nil)
+		service := &Service{
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
+		}
+		execTest(t, service, common.Hash{2}, [][]byte{{1}}, true, common.Hash{2}, [][]byte{leafEncoding}, nil, "")
+	})
+}
+
+func TestService_TraceBlock(t *testing.T) {
+	t.Parallel()
+
+	block := &types.Block{
+		Header: types.Header{ParentHash: common.Hash{1}, Number: 2},
+	}
+	parentStateRoot := common.Hash{3}
+	code := []byte{4}
+
+	newParentTrieState := func(t *testing.T) *rtstorage.TrieState {
+		t.Helper()
+		ts, err := rtstorage.NewTrieState(nil)
+		require.NoError(t, err)
+		ts.Set(common.CodeKey, code)
+		return ts
+	}
+
+	newBestRuntime := func() *mocksruntime.Instance {
+		bestRuntime := new(mocksruntime.Instance)
+		bestRuntime.On("Keystore").Return((*keystore.GlobalKeystore)(nil))
+		bestRuntime.On("NodeStorage").Return(runtime.NodeStorage{})
+		bestRuntime.On("NetworkService").Return(nil)
+		return bestRuntime
+	}
+
+	t.Run("get block error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().GetBlockByHash(common.Hash{2}).Return(nil, errDummyErr)
+		service := &Service{
+			blockState: mockBlockState,
+		}
+
+		parentHash, events, err := service.TraceBlock(common.Hash{2}, nil)
+		assert.ErrorIs(t, err, errDummyErr)
+		assert.EqualError(t, err, "cannot get block: dummy error for testing")
+		assert.Equal(t, common.Hash{}, parentHash)
+		assert.Nil(t, events)
+	})
+
+	t.Run("empty parent code", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		ts, err := rtstorage.NewTrieState(nil)
+		require.NoError(t, err)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().GetBlockByHash(common.Hash{2}).Return(block, nil)
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().GetStateRootFromBlock(&common.Hash{1}).Return(&parentStateRoot, nil)
+		mockStorageState.EXPECT().TrieState(&parentStateRoot).Return(ts, nil)
+		service := &Service{
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
+		}
+
+		parentHash, events, err := service.TraceBlock(common.Hash{2}, nil)
+		assert.ErrorIs(t, err, ErrEmptyRuntimeCode)
+		assert.Equal(t, common.Hash{1}, parentHash)
+		assert.Nil(t, events)
+	})
+
+	t.Run("execute block error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		ts := newParentTrieState(t)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().GetBlockByHash(common.Hash{2}).Return(block, nil)
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().GetStateRootFromBlock(&common.Hash{1}).Return(&parentStateRoot, nil)
+		mockStorageState.EXPECT().TrieState(&parentStateRoot).Return(ts, nil)
+		mockBlockState.EXPECT().GetRuntime(nil).Return(newBestRuntime(), nil)
+		instance := new(mocksruntime.Instance)
+		instance.On("ExecuteBlock", block).Return(nil, errDummyErr)
+		instance.On("Stop")
+		service := &Service{
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
+			newInstance: func([]byte, runtime.InstanceConfig) (runtime.Instance, error) {
+				return instance, nil
+			},
+		}
+
+		parentHash, events, err := service.TraceBlock(common.Hash{2}, nil)
+		assert.ErrorIs(t, err, errDummyErr)
+		assert.EqualError(t, err, "cannot execute block 2: dummy error for testing")
+		assert.Equal(t, common.Hash{1}, parentHash)
+		assert.Nil(t, events)
+		instance.AssertExpectations(t)
+	})
+
+	t.Run("parent block below the finalised head", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		ts := newParentTrieState(t)
+		mockBlockState := NewMockBlockState(ctrl)
+		mockBlockState.EXPECT().GetBlockByHash(common.Hash{2}).Return(block, nil)
+		mockStorageState := NewMockStorageState(ctrl)
+		mockStorageState.EXPECT().GetStateRootFromBlock(&common.Hash{1}).Return(&parentStateRoot, nil)
+		mockStorageState.EXPECT().TrieState(&parentStateRoot).Return(ts, nil)
+		// the runtime of the finalised parent block is not in the block tree,
+		// so only the best block runtime is used, for its node services.
+		mockBlockState.EXPECT().GetRuntime(nil).Return(newBestRuntime(), nil)
+		instance := new(mocksruntime.Instance)
+		instance.On("ExecuteBlock", block).Return(nil, nil).Run(func(mock.Arguments) {
+			ts.TraceCall(runtime.CoreExecuteBlock)
+			ts.Set([]byte("ab"), []byte{1, 2})
+			_ = ts.Get([]byte("cd"))
+			ts.TraceCall("")
+		})
+		instance.On("Stop")
+		service := &Service{
+			blockState:   mockBlockState,
+			storageState: mockStorageState,
+			newInstance: func(instanceCode []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+				assert.Equal(t, code, instanceCode)
+				assert.Same(t, ts, cfg.Storage)
+				return instance, nil
+			},
+		}
+
+		parentHash, events, err := service.TraceBlock(common.Hash{2}, [][]byte{[]byte("a")})
+		require.NoError(t, err)
+		assert.Equal(t, common.Hash{1}, parentHash)
+		valueSize := uint32(2)
+		expected := []rtstorage.TraceEvent{{
+			Call:      runtime.CoreExecuteBlock,
+			Operation: rtstorage.TraceSet,
+			Key:       []byte("ab"),
+			ValueSize: &valueSize,
+		}}
+		assert.Equal(t, expected, events)
+		instance.AssertExpectations(t)
 	})
 }
@@ -64,6 +64,8 @@ type Config struct {
 	BlockState         BlockState
 	Syncer             Syncer
 	TransactionHandler TransactionHandler
+	// TransactionIndex serves indexed transaction data to peers, it is optional
+	TransactionIndex TransactionIndex
 
 	// Used to specify the address broadcasted to other peers, and avoids using pubip.Get
 	PublicIP string
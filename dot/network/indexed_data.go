@@ -0,0 +1,218 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/peerset"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+
+	libp2pnetwork "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+var (
+	// maxIndexedDataRequestHashes is the maximum number of hashes in a single IndexedDataRequestMessage
+	maxIndexedDataRequestHashes = 128
+	indexedDataRequestTimeout   = time.Second * 5
+
+	errTooManyIndexedDataHashes = errors.New("too many hashes in indexed data request")
+	errIndexedDataTooLarge      = errors.New("indexed data too large for a response")
+)
+
+var (
+	_ Message = &IndexedDataRequestMessage{}
+	_ Message = &IndexedDataResponseMessage{}
+)
+
+// IndexedDataRequestMessage is sent to request indexed transaction data by content hash.
+// Like a bitswap want-list, the peer answers with the entries it has and omits the others.
+// The entries which do not fit in the response after the first one are omitted as well,
+// and can be requested again.
+type IndexedDataRequestMessage struct {
+	Hashes []common.Hash
+}
+
+// SubProtocol returns the indexed data sub-protocol
+func (*IndexedDataRequestMessage) SubProtocol() string {
+	return indexedDataID
+}
+
+// Encode returns the SCALE encoded IndexedDataRequestMessage
+func (m *IndexedDataRequestMessage) Encode() ([]byte, error) {
+	return scale.Marshal(*m)
+}
+
+// Decode decodes the SCALE encoded input into the IndexedDataRequestMessage
+func (m *IndexedDataRequestMessage) Decode(in []byte) error {
+	return scale.Unmarshal(in, m)
+}
+
+// String formats an IndexedDataRequestMessage as a string
+func (m *IndexedDataRequestMessage) String() string {
+	return fmt.Sprintf("IndexedDataRequestMessage Hashes=%v", m.Hashes)
+}
+
+// IndexedDataEntry is a piece of indexed transaction data along with its content hash
+type IndexedDataEntry struct {
+	Hash common.Hash
+	Data []byte
+}
+
+// IndexedDataResponseMessage is sent in response to an IndexedDataRequestMessage
+type IndexedDataResponseMessage struct {
+	Entries []IndexedDataEntry
+}
+
+// SubProtocol returns the indexed data sub-protocol
+func (*IndexedDataResponseMessage) SubProtocol() string {
+	return indexedDataID
+}
+
+// Encode returns the SCALE encoded IndexedDataResponseMessage
+func (m *IndexedDataResponseMessage) Encode() ([]byte, error) {
+	return scale.Marshal(*m)
+}
+
+// Decode decodes the SCALE encoded input into the IndexedDataResponseMessage
+func (m *IndexedDataResponseMessage) Decode(in []byte) error {
+	return scale.Unmarshal(in, m)
+}
+
+// String formats an IndexedDataResponseMessage as a string
+func (m *IndexedDataResponseMessage) String() string {
+	return fmt.Sprintf("IndexedDataResponseMessage NumEntries=%d", len(m.Entries))
+}
+
+// DoIndexedDataRequest requests indexed transaction data from the given peer.
+// Entries which are not returned by the peer are not available from it.
+func (s *Service) DoIndexedDataRequest(to peer.ID, req *IndexedDataRequestMessage) (
+	*IndexedDataResponseMessage, error) {
+	if len(req.Hashes) > maxIndexedDataRequestHashes {
+		return nil, fmt.Errorf("%w: %d > %d", errTooManyIndexedDataHashes, len(req.Hashes), maxIndexedDataRequestHashes)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, indexedDataRequestTimeout)
+	defer cancel()
+
+	stream, err := s.host.p2pHost.NewStream(ctx, to, s.host.protocolID+indexedDataID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	if err = s.host.writeToStream(stream, req); err != nil {
+		return nil, err
+	}
+
+	// the response can be as large as a block response
+	buf := make([]byte, maxBlockResponseSize)
+	n, err := readStream(stream, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("read stream error: %w", err)
+	}
+
+	if n == 0 {
+		return nil, fmt.Errorf("received empty message")
+	}
+
+	resp := new(IndexedDataResponseMessage)
+	err = resp.Decode(buf[:n])
+	if err != nil {
+		s.host.cm.peerSetHandler.ReportPeer(peerset.ReputationChange{
+			Value:  peerset.BadMessageValue,
+			Reason: peerset.BadMessageReason,
+		}, to)
+		return nil, fmt.Errorf("failed to decode indexed data response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// handleIndexedDataStream handles streams with the <protocol-id>/indexed-data/1 protocol ID
+func (s *Service) handleIndexedDataStream(stream libp2pnetwork.Stream) {
+	if stream == nil {
+		return
+	}
+
+	s.readStream(stream, decodeIndexedDataMessage, s.handleIndexedDataMessage)
+}
+
+func decodeIndexedDataMessage(in []byte, _ peer.ID, _ bool) (Message, error) {
+	msg := new(IndexedDataRequestMessage)
+	err := msg.Decode(in)
+	return msg, err
+}
+
+// handleIndexedDataMessage handles inbound indexed data requests
+func (s *Service) handleIndexedDataMessage(stream libp2pnetwork.Stream, msg Message) error {
+	if msg == nil {
+		return nil
+	}
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	req, ok := msg.(*IndexedDataRequestMessage)
+	if !ok {
+		return nil
+	}
+
+	resp, err := s.createIndexedDataResponse(req)
+	if err != nil {
+		logger.Debugf("cannot create response for indexed data request: %s", err)
+		return nil
+	}
+
+	if err = s.host.writeToStream(stream, resp); err != nil {
+		logger.Debugf("failed to send IndexedDataResponse message to peer %s: %s", stream.Conn().RemotePeer(), err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) createIndexedDataResponse(req *IndexedDataRequestMessage) (*IndexedDataResponseMessage, error) {
+	if len(req.Hashes) > maxIndexedDataRequestHashes {
+		return nil, fmt.Errorf("%w: %d > %d", errTooManyIndexedDataHashes, len(req.Hashes), maxIndexedDataRequestHashes)
+	}
+
+	resp := &IndexedDataResponseMessage{}
+	var size uint64
+	for _, hash := range req.Hashes {
+		data, err := s.transactionIndex.GetIndexedTransaction(hash)
+		if err != nil {
+			logger.Tracef("indexed data %s not available: %s", hash, err)
+			continue
+		}
+
+		// keep the response under the maximum message size a peer accepts,
+		// accounting for the length prefix of the data
+		size += uint64(len(hash) + len(data) + 8)
+		if size > maxBlockResponseSize {
+			if len(resp.Entries) == 0 {
+				return nil, fmt.Errorf("%w: %s has %d bytes", errIndexedDataTooLarge, hash, len(data))
+			}
+			logger.Debugf("omitting indexed data %s and the following ones, "+
+				"since the response would be larger than %d bytes", hash, maxBlockResponseSize)
+			break
+		}
+
+		resp.Entries = append(resp.Entries, IndexedDataEntry{
+			Hash: hash,
+			Data: data,
+		})
+	}
+
+	return resp, nil
+}
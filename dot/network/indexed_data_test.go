@@ -0,0 +1,165 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package network
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type mapTransactionIndex map[common.Hash][]byte
+
+func (m mapTransactionIndex) GetIndexedTransaction(hash common.Hash) ([]byte, error) {
+	data, has := m[hash]
+	if !has {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func TestDecodeIndexedDataMessage(t *testing.T) {
+	t.Parallel()
+
+	req := &IndexedDataRequestMessage{
+		Hashes: []common.Hash{{1}, {2}},
+	}
+	enc, err := req.Encode()
+	require.NoError(t, err)
+
+	msg, err := decodeIndexedDataMessage(enc, peer.ID("noot"), true)
+	require.NoError(t, err)
+	require.Equal(t, req, msg)
+}
+
+func TestIndexedDataResponseMessage_EncodeDecode(t *testing.T) {
+	t.Parallel()
+
+	resp := &IndexedDataResponseMessage{
+		Entries: []IndexedDataEntry{
+			{Hash: common.Hash{1}, Data: []byte("data")},
+		},
+	}
+	enc, err := resp.Encode()
+	require.NoError(t, err)
+
+	res := new(IndexedDataResponseMessage)
+	err = res.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, resp, res)
+}
+
+func TestService_createIndexedDataResponse(t *testing.T) {
+	t.Parallel()
+
+	s := &Service{
+		transactionIndex: mapTransactionIndex{
+			{1}: []byte("first"),
+			{3}: []byte("third"),
+		},
+	}
+
+	resp, err := s.createIndexedDataResponse(&IndexedDataRequestMessage{
+		Hashes: []common.Hash{{1}, {2}, {3}},
+	})
+	require.NoError(t, err)
+
+	expected := &IndexedDataResponseMessage{
+		Entries: []IndexedDataEntry{
+			{Hash: common.Hash{1}, Data: []byte("first")},
+			{Hash: common.Hash{3}, Data: []byte("third")},
+		},
+	}
+	require.Equal(t, expected, resp)
+
+	_, err = s.createIndexedDataResponse(&IndexedDataRequestMessage{
+		Hashes: make([]common.Hash, maxIndexedDataRequestHashes+1),
+	})
+	require.ErrorIs(t, err, errTooManyIndexedDataHashes)
+}
+
+func TestService_createIndexedDataResponse_size(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte{1}, int(maxBlockResponseSize)/2)
+	s := &Service{
+		transactionIndex: mapTransactionIndex{
+			{1}: large,
+			{2}: large,
+			{3}: bytes.Repeat([]byte{3}, int(maxBlockResponseSize)),
+		},
+	}
+
+	// the entries which do not fit after the first one are omitted
+	resp, err := s.createIndexedDataResponse(&IndexedDataRequestMessage{
+		Hashes: []common.Hash{{1}, {2}},
+	})
+	require.NoError(t, err)
+	expected := &IndexedDataResponseMessage{
+		Entries: []IndexedDataEntry{{Hash: common.Hash{1}, Data: large}},
+	}
+	require.Equal(t, expected, resp)
+
+	// the first entry is never omitted
+	_, err = s.createIndexedDataResponse(&IndexedDataRequestMessage{
+		Hashes: []common.Hash{{3}, {1}},
+	})
+	require.ErrorIs(t, err, errIndexedDataTooLarge)
+}
+
+func TestService_DoIndexedDataRequest(t *testing.T) {
+	t.Parallel()
+
+	transactionIndex := mapTransactionIndex{
+		{1}: []byte("first"),
+		// larger than the maximum size of the other messages
+		{2}: bytes.Repeat([]byte{2}, maxMessageSize+1),
+		{3}: []byte("third"),
+	}
+
+	nodeA := createTestService(t, &Config{
+		BasePath:    t.TempDir(),
+		Port:        availablePort(t),
+		NoBootstrap: true,
+		NoMDNS:      true,
+	})
+	nodeA.noGossip = true
+
+	nodeB := createTestService(t, &Config{
+		BasePath:         t.TempDir(),
+		Port:             availablePort(t),
+		NoBootstrap:      true,
+		NoMDNS:           true,
+		TransactionIndex: transactionIndex,
+	})
+	nodeB.noGossip = true
+
+	addrInfoB := nodeB.host.addrInfo()
+	err := nodeA.host.connect(addrInfoB)
+	// retry connect if "failed to dial" error
+	if failedToDial(err) {
+		time.Sleep(TestBackoffTimeout)
+		err = nodeA.host.connect(addrInfoB)
+	}
+	require.NoError(t, err)
+
+	resp, err := nodeA.DoIndexedDataRequest(addrInfoB.ID, &IndexedDataRequestMessage{
+		Hashes: []common.Hash{{1}, {2}, {4}, {3}},
+	})
+	require.NoError(t, err)
+
+	expected := &IndexedDataResponseMessage{
+		Entries: []IndexedDataEntry{
+			{Hash: common.Hash{1}, Data: transactionIndex[common.Hash{1}]},
+			{Hash: common.Hash{2}, Data: transactionIndex[common.Hash{2}]},
+			{Hash: common.Hash{3}, Data: transactionIndex[common.Hash{3}]},
+		},
+	}
+	require.Equal(t, expected, resp)
+}
@@ -33,6 +33,7 @@
 	lightID         = "/light/2"
 	blockAnnounceID = "/block-announces/1"
 	transactionsID  = "/transactions/1"
+	indexedDataID   = "/indexed-data/1"
 
 	maxMessageSize = 1024 * 64 // 64kb for now
 )
@@ -120,6 +121,7 @@ type Service struct {
 	blockState         BlockState
 	syncer             Syncer
 	transactionHandler TransactionHandler
+	transactionIndex   TransactionIndex
 
 	// Configuration options
 	noBootstrap bool
@@ -197,6 +199,7 @@ func NewService(cfg *Config) (*Service, error) {
 		gossip:                 newGossip(),
 		blockState:             cfg.BlockState,
 		transactionHandler:     cfg.TransactionHandler,
+		transactionIndex:       cfg.TransactionIndex,
 		noBootstrap:            cfg.NoBootstrap,
 		noMDNS:                 cfg.NoMDNS,
 		syncer:                 cfg.Syncer,
@@ -240,6 +243,9 @@ func (s *Service) Start() error {
 
 	s.host.registerStreamHandler(s.host.protocolID+syncID, s.handleSyncStream)
 	s.host.registerStreamHandler(s.host.protocolID+lightID, s.handleLightStream)
+	if s.transactionIndex != nil {
+		s.host.registerStreamHandler(s.host.protocolID+indexedDataID, s.handleIndexedDataStream)
+	}
 
 	// register block announce protocol
 	err := s.RegisterNotificationsProtocol(
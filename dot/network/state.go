@@ -44,6 +44,11 @@ type TransactionHandler interface {
 	TransactionsCount() int
 }
 
+// TransactionIndex is the interface used by the indexed data sub-protocol
+type TransactionIndex interface {
+	GetIndexedTransaction(hash common.Hash) ([]byte, error)
+}
+
 // PeerSetHandler is the interface used by the connection manager to handle peerset.
 type PeerSetHandler interface {
 	Start(context.Context)
@@ -195,15 +195,16 @@ func readStream(stream libp2pnetwork.Stream, bufPointer *[]byte) (int, error) {
 		return 0, nil // msg length of 0 is allowed, for example transactions handshake
 	}
 
+	if length > maxBlockResponseSize {
+		logger.Warnf("received message with size %d greater than maxBlockResponseSize, closing stream", length)
+		return 0, fmt.Errorf("message size greater than maximum: got %d", length)
+	}
+
 	if length > uint64(len(buf)) {
 		extraBytes := int(length) - len(buf)
 		*bufPointer = append(buf, make([]byte, extraBytes)...) // TODO #2288 use bytes.Buffer instead
 		logger.Warnf("received message with size %d greater than allocated message buffer size %d", length, len(buf))
-	}
-
-	if length > maxBlockResponseSize {
-		logger.Warnf("received message with size %d greater than maxBlockResponseSize, closing stream", length)
-		return 0, fmt.Errorf("message size greater than maximum: got %d", length)
+		buf = *bufPointer
 	}
 
 	tot = 0
@@ -32,6 +32,7 @@
 	"github.com/ChainSafe/gossamer/lib/grandpa"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
 	"github.com/ChainSafe/gossamer/lib/services"
 	"github.com/ChainSafe/gossamer/lib/utils"
 )
@@ -149,6 +150,13 @@ func (*nodeBuilder) initNode(cfg *Config) error {
 		return fmt.Errorf("failed to create trie from genesis: %w", err)
 	}
 
+	// the genesis state root is computed with the state trie version of the genesis runtime
+	stateVersion, err := genesisStateVersion(t, cfg.Log.RuntimeLvl)
+	if err != nil {
+		return fmt.Errorf("failed to get genesis state version: %w", err)
+	}
+	t.SetVersion(stateVersion)
+
 	// create genesis block from trie
 	header, err := genesis.NewGenesisBlockFromTrie(t)
 	if err != nil {
@@ -490,6 +498,14 @@ func (n *Node) Stop() {
 func (n *nodeBuilder) loadRuntime(cfg *Config, ns *runtime.NodeStorage,
 	stateSrvc *state.Service, ks *keystore.GlobalKeystore,
 	net *network.Service) error {
+	if cfg.Core.WasmInterpreter == wasmer.Name {
+		cache, err := wasmer.NewCache(filepath.Join(cfg.Global.BasePath, "runtime-cache"), wasmer.DefaultCacheMaxSize)
+		if err != nil {
+			return fmt.Errorf("cannot create compiled runtime cache: %w", err)
+		}
+		wasmer.SetDefaultCache(cache)
+	}
+
 	blocks := stateSrvc.Block.GetNonFinalisedBlocks()
 	runtimeCode := make(map[string]runtime.Instance)
 	for i := range blocks {
@@ -63,6 +63,11 @@
 	// BadJustificationReason is used when peer send invalid justification.
 	BadJustificationReason = "Bad justification"
 
+	// BadBlockValue is used when peer sends an invalid block.
+	BadBlockValue Reputation = -(1 << 29)
+	// BadBlockReason is used when peer sends an invalid block.
+	BadBlockReason = "Bad block"
+
 	// GenesisMismatch is used when peer has a different genesis
 	GenesisMismatch Reputation = math.MinInt32
 	// GenesisMismatchReason used when a peer has a different genesis
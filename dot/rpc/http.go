@@ -42,6 +42,7 @@ type HTTPServerConfig struct {
 	SystemAPI           modules.SystemAPI
 	SyncStateAPI        modules.SyncStateAPI
 	SyncAPI             modules.SyncAPI
+	TransactionIndexAPI modules.TransactionIndexAPI
 	NodeStorage         *runtime.NodeStorage
 	RPC                 bool
 	RPCExternal         bool
@@ -119,7 +120,9 @@ func (h *HTTPServer) RegisterModules(mods []string) {
 		case "syncstate":
 			srvc = modules.NewSyncStateModule(h.serverConfig.SyncStateAPI)
 		case "payment":
-			srvc = modules.NewPaymentModule(h.serverConfig.BlockAPI)
+			srvc = modules.NewPaymentModule(h.serverConfig.BlockAPI, h.serverConfig.StorageAPI)
+		case "transactionIndex":
+			srvc = modules.NewTransactionIndexModule(h.serverConfig.TransactionIndexAPI)
 		default:
 			h.logger.Warn("Unrecognised module: " + mod)
 			continue
@@ -13,6 +13,7 @@
 	"github.com/ChainSafe/gossamer/lib/genesis"
 	"github.com/ChainSafe/gossamer/lib/grandpa"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/lib/trie"
 )
@@ -27,7 +28,9 @@ type StorageAPI interface {
 	GetStorageByBlockHash(bhash *common.Hash, key []byte) ([]byte, error)
 	Entries(root *common.Hash) (map[string][]byte, error)
 	GetStateRootFromBlock(bhash *common.Hash) (*common.Hash, error)
-	GetKeysWithPrefix(root *common.Hash, prefix []byte) ([][]byte, error)
+	TrieState(root *common.Hash) (*rtstorage.TrieState, error)
+	GenerateChildTrieProof(stateRoot common.Hash, keyToChild []byte, keys [][]byte) ([][]byte, error)
+	NewIterator(root *common.Hash, options trie.IteratorOptions) (*trie.Iterator, error)
 	RegisterStorageObserver(observer state.Observer)
 	UnregisterStorageObserver(observer state.Observer)
 }
@@ -103,7 +106,8 @@ type CoreAPI interface {
 	GetMetadata(bhash *common.Hash) ([]byte, error)
 	QueryStorage(from, to common.Hash, keys ...string) (map[common.Hash]core.QueryKeyValueChanges, error)
 	DecodeSessionKeys(enc []byte) ([]byte, error)
-	GetReadProofAt(block common.Hash, keys [][]byte) (common.Hash, [][]byte, error)
+	GetReadProofAt(block common.Hash, keys [][]byte, compact bool) (common.Hash, [][]byte, error)
+	TraceBlock(hash common.Hash, keyPrefixes [][]byte) (common.Hash, []rtstorage.TraceEvent, error)
 }
 
 //go:generate mockery --name RPCAPI --structname RPCAPI --case underscore --keeptree
@@ -153,6 +157,13 @@ type SyncStateAPI interface {
 	GenSyncSpec(raw bool) (*genesis.Genesis, error)
 }
 
+//go:generate mockery --name TransactionIndexAPI --structname TransactionIndexAPI --case underscore --keeptree
+
+// TransactionIndexAPI is the interface to retrieve indexed transaction data
+type TransactionIndexAPI interface {
+	GetIndexedTransaction(hash common.Hash) ([]byte, error)
+}
+
 //go:generate mockgen -destination=mock_sync_api_test.go -package $GOPACKAGE . SyncAPI
 
 // SyncAPI is the interface to interact with the sync service
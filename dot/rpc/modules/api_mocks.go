@@ -9,6 +9,7 @@
 	"github.com/ChainSafe/gossamer/lib/common"
 	runtimemocks "github.com/ChainSafe/gossamer/lib/runtime/mocks"
 	"github.com/ChainSafe/gossamer/lib/transaction"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,7 +24,10 @@ func NewMockStorageAPI() *modulesmocks.StorageAPI {
 	m.On("RegisterStorageObserver", mock.Anything)
 	m.On("UnregisterStorageObserver", mock.Anything)
 	m.On("GetStateRootFromBlock", mock.AnythingOfType("*common.Hash")).Return(nil, nil)
-	m.On("GetKeysWithPrefix", mock.AnythingOfType("*common.Hash"), mock.AnythingOfType("[]uint8")).Return(nil, nil)
+	m.On("NewIterator", mock.AnythingOfType("*common.Hash"), mock.AnythingOfType("trie.IteratorOptions")).
+		Return(func(*common.Hash, trie.IteratorOptions) *trie.Iterator {
+			return trie.NewEmptyTrie().NewIterator(trie.IteratorOptions{})
+		}, nil)
 	return m
 }
 
@@ -79,6 +83,7 @@ func NewMockVersion() *runtimemocks.Version {
 	m.On("SpecVersion").Return(uint32(0))
 	m.On("ImplVersion").Return(uint32(0))
 	m.On("TransactionVersion").Return(uint32(0))
+	m.On("StateVersion").Return(uint8(0))
 	m.On("APIItems").Return(nil)
 	return m
 }
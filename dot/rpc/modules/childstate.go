@@ -4,9 +4,12 @@
 package modules
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
 
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
 )
 
 // GetKeysRequest represents the request to retrieve the keys of a child storage
@@ -37,6 +40,18 @@ type GetChildStorageRequest struct {
 	Hash     *common.Hash
 }
 
+// ChildStateGetReadProofRequest holds the fields of the request to
+// retrieve the proof of keys in a child storage
+type ChildStateGetReadProofRequest struct {
+	// ChildStorageKey is the hex encoded key of the child storage, which can
+	// be prefixed with the child storage key prefix as done by Substrate.
+	ChildStorageKey string
+	Keys            []string
+	Hash            *common.Hash
+	// Compact is true to return the proof in the compact format of Substrate.
+	Compact bool
+}
+
 // ChildStateModule is the module responsible to implement all the childstate RPC calls
 type ChildStateModule struct {
 	storageAPI StorageAPI
@@ -166,3 +181,58 @@ func (cs *ChildStateModule) GetStorage(
 
 	return nil
 }
+
+// GetReadProof returns the proof of the keys in a child storage, which includes
+// the proof of the child storage root in the state of the block.
+func (cs *ChildStateModule) GetReadProof(
+	_ *http.Request, req *ChildStateGetReadProofRequest, res *StateGetReadProofResponse) error {
+	keyToChild, err := common.HexToBytes(req.ChildStorageKey)
+	if err != nil {
+		return err
+	}
+	keyToChild = bytes.TrimPrefix(keyToChild, trie.ChildStorageKeyPrefix)
+
+	keys := make([][]byte, len(req.Keys))
+	for i, hexKey := range req.Keys {
+		keys[i], err = common.HexToBytes(hexKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	var hash common.Hash
+	if req.Hash == nil {
+		hash = cs.blockAPI.BestBlockHash()
+	} else {
+		hash = *req.Hash
+	}
+
+	stateRoot, err := cs.storageAPI.GetStateRootFromBlock(&hash)
+	if err != nil {
+		return err
+	}
+
+	proof, err := cs.storageAPI.GenerateChildTrieProof(*stateRoot, keyToChild, keys)
+	if err != nil {
+		return err
+	}
+
+	if req.Compact {
+		proof, err = trie.EncodeCompactProof(proof, stateRoot[:])
+		if err != nil {
+			return fmt.Errorf("cannot encode compact proof: %w", err)
+		}
+	}
+
+	hexProof := make([]string, len(proof))
+	for i, encoding := range proof {
+		hexProof[i] = common.BytesToHex(encoding)
+	}
+
+	*res = StateGetReadProofResponse{
+		At:    hash,
+		Proof: hexProof,
+	}
+
+	return nil
+}
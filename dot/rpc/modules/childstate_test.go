@@ -8,6 +8,7 @@
 	"net/http"
 	"testing"
 
+	"github.com/ChainSafe/chaindb"
 	apimocks "github.com/ChainSafe/gossamer/dot/rpc/modules/mocks"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/genesis"
@@ -527,3 +528,135 @@ type args struct {
 		})
 	}
 }
+
+func TestChildStateModule_GetReadProof(t *testing.T) {
+	tr, sr := createTestTrieState(t)
+
+	db, err := chaindb.NewBadgerDB(&chaindb.Config{
+		InMemory: true,
+		DataDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	err = tr.Store(db)
+	require.NoError(t, err)
+
+	keyToChild := []byte(":child_storage_key")
+	keys := [][]byte{[]byte(":child_first"), []byte(":another_child")}
+	proof, err := trie.GenerateChildProof(sr[:], keyToChild, keys, db)
+	require.NoError(t, err)
+
+	hexProof := make([]string, len(proof))
+	for i, encoding := range proof {
+		hexProof[i] = common.BytesToHex(encoding)
+	}
+
+	compactProof, err := trie.EncodeCompactProof(proof, sr[:])
+	require.NoError(t, err)
+	hexCompactProof := make([]string, len(compactProof))
+	for i, encoding := range compactProof {
+		hexCompactProof[i] = common.BytesToHex(encoding)
+	}
+
+	hash := common.MustHexToHash("0x3aa96b0149b6ca3688878bdbd19464448624136398e3ce45b9e755d3ab61355a")
+	mockBlockAPI := new(apimocks.BlockAPI)
+	mockBlockAPI.On("BestBlockHash").Return(hash)
+
+	mockStorageAPI := new(apimocks.StorageAPI)
+	mockStorageAPI.On("GetStateRootFromBlock", &hash).Return(&sr, nil)
+	mockStorageAPI.On("GenerateChildTrieProof", sr, keyToChild, keys).Return(proof, nil)
+
+	mockErrorStorageAPI1 := new(apimocks.StorageAPI)
+	mockErrorStorageAPI1.On("GetStateRootFromBlock", &hash).Return(&sr, nil)
+	mockErrorStorageAPI1.On("GenerateChildTrieProof", sr, keyToChild, [][]byte{}).
+		Return(nil, errors.New("GenerateChildTrieProof error"))
+
+	mockErrorStorageAPI2 := new(apimocks.StorageAPI)
+	mockErrorStorageAPI2.On("GetStateRootFromBlock", &hash).Return(nil, errors.New("GetStateRootFromBlock error"))
+
+	tests := map[string]struct {
+		storageAPI StorageAPI
+		req        *ChildStateGetReadProofRequest
+		expErr     string
+		exp        StateGetReadProofResponse
+	}{
+		"best block": {
+			storageAPI: mockStorageAPI,
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: common.BytesToHex(keyToChild),
+				Keys:            []string{common.BytesToHex(keys[0]), common.BytesToHex(keys[1])},
+			},
+			exp: StateGetReadProofResponse{At: hash, Proof: hexProof},
+		},
+		"prefixed child storage key": {
+			storageAPI: mockStorageAPI,
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: common.BytesToHex(append(append([]byte{}, trie.ChildStorageKeyPrefix...),
+					keyToChild...)),
+				Keys: []string{common.BytesToHex(keys[0]), common.BytesToHex(keys[1])},
+				Hash: &hash,
+			},
+			exp: StateGetReadProofResponse{At: hash, Proof: hexProof},
+		},
+		"compact proof": {
+			storageAPI: mockStorageAPI,
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: common.BytesToHex(keyToChild),
+				Keys:            []string{common.BytesToHex(keys[0]), common.BytesToHex(keys[1])},
+				Hash:            &hash,
+				Compact:         true,
+			},
+			exp: StateGetReadProofResponse{At: hash, Proof: hexCompactProof},
+		},
+		"invalid child storage key": {
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: "0xzz",
+			},
+			expErr: "encoding/hex: invalid byte: U+007A 'z': 0xzz",
+		},
+		"GenerateChildTrieProof error": {
+			storageAPI: mockErrorStorageAPI1,
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: common.BytesToHex(keyToChild),
+				Keys:            []string{},
+				Hash:            &hash,
+			},
+			expErr: "GenerateChildTrieProof error",
+		},
+		"GetStateRootFromBlock error": {
+			storageAPI: mockErrorStorageAPI2,
+			req: &ChildStateGetReadProofRequest{
+				ChildStorageKey: common.BytesToHex(keyToChild),
+				Hash:            &hash,
+			},
+			expErr: "GetStateRootFromBlock error",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			cs := NewChildStateModule(tt.storageAPI, mockBlockAPI)
+
+			var res StateGetReadProofResponse
+			err := cs.GetReadProof(nil, tt.req, &res)
+			if tt.expErr != "" {
+				assert.EqualError(t, err, tt.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.exp, res)
+		})
+	}
+
+	// the proofs returned prove the child storage keys in the state
+	decodedProof, err := trie.DecodeCompactProof(compactProof, sr[:])
+	require.NoError(t, err)
+	for _, proof := range [][][]byte{proof, decodedProof} {
+		ok, err := trie.VerifyChildProof(proof, sr[:], keyToChild, []trie.Pair{
+			{Key: keys[0], Value: []byte(":child_first_value")},
+			{Key: keys[1], Value: []byte("value")},
+		})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
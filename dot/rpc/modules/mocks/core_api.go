@@ -12,6 +12,8 @@
 
 	runtime "github.com/ChainSafe/gossamer/lib/runtime"
 
+	storage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+
 	types "github.com/ChainSafe/gossamer/dot/types"
 )
 
@@ -66,13 +68,13 @@ func (_m *CoreAPI) GetMetadata(bhash *common.Hash) ([]byte, error) {
 	return r0, r1
 }
 
-// GetReadProofAt provides a mock function with given fields: block, keys
-func (_m *CoreAPI) GetReadProofAt(block common.Hash, keys [][]byte) (common.Hash, [][]byte, error) {
-	ret := _m.Called(block, keys)
+// GetReadProofAt provides a mock function with given fields: block, keys, compact
+func (_m *CoreAPI) GetReadProofAt(block common.Hash, keys [][]byte, compact bool) (common.Hash, [][]byte, error) {
+	ret := _m.Called(block, keys, compact)
 
 	var r0 common.Hash
-	if rf, ok := ret.Get(0).(func(common.Hash, [][]byte) common.Hash); ok {
-		r0 = rf(block, keys)
+	if rf, ok := ret.Get(0).(func(common.Hash, [][]byte, bool) common.Hash); ok {
+		r0 = rf(block, keys, compact)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(common.Hash)
@@ -80,8 +82,8 @@ func (_m *CoreAPI) GetReadProofAt(block common.Hash, keys [][]byte) (common.Hash
 	}
 
 	var r1 [][]byte
-	if rf, ok := ret.Get(1).(func(common.Hash, [][]byte) [][]byte); ok {
-		r1 = rf(block, keys)
+	if rf, ok := ret.Get(1).(func(common.Hash, [][]byte, bool) [][]byte); ok {
+		r1 = rf(block, keys, compact)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([][]byte)
@@ -89,8 +91,8 @@ func (_m *CoreAPI) GetReadProofAt(block common.Hash, keys [][]byte) (common.Hash
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(common.Hash, [][]byte) error); ok {
-		r2 = rf(block, keys)
+	if rf, ok := ret.Get(2).(func(common.Hash, [][]byte, bool) error); ok {
+		r2 = rf(block, keys, compact)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -199,3 +201,35 @@ func (_m *CoreAPI) QueryStorage(from common.Hash, to common.Hash, keys ...string
 
 	return r0, r1
 }
+
+// TraceBlock provides a mock function with given fields: hash, keyPrefixes
+func (_m *CoreAPI) TraceBlock(hash common.Hash, keyPrefixes [][]byte) (common.Hash, []storage.TraceEvent, error) {
+	ret := _m.Called(hash, keyPrefixes)
+
+	var r0 common.Hash
+	if rf, ok := ret.Get(0).(func(common.Hash, [][]byte) common.Hash); ok {
+		r0 = rf(hash, keyPrefixes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(common.Hash)
+		}
+	}
+
+	var r1 []storage.TraceEvent
+	if rf, ok := ret.Get(1).(func(common.Hash, [][]byte) []storage.TraceEvent); ok {
+		r1 = rf(hash, keyPrefixes)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]storage.TraceEvent)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(common.Hash, [][]byte) error); ok {
+		r2 = rf(hash, keyPrefixes)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
@@ -8,6 +8,8 @@
 
 	state "github.com/ChainSafe/gossamer/dot/state"
 
+	storage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+
 	trie "github.com/ChainSafe/gossamer/lib/trie"
 )
 
@@ -39,13 +41,13 @@ func (_m *StorageAPI) Entries(root *common.Hash) (map[string][]byte, error) {
 	return r0, r1
 }
 
-// GetKeysWithPrefix provides a mock function with given fields: root, prefix
-func (_m *StorageAPI) GetKeysWithPrefix(root *common.Hash, prefix []byte) ([][]byte, error) {
-	ret := _m.Called(root, prefix)
+// GenerateChildTrieProof provides a mock function with given fields: stateRoot, keyToChild, keys
+func (_m *StorageAPI) GenerateChildTrieProof(stateRoot common.Hash, keyToChild []byte, keys [][]byte) ([][]byte, error) {
+	ret := _m.Called(stateRoot, keyToChild, keys)
 
 	var r0 [][]byte
-	if rf, ok := ret.Get(0).(func(*common.Hash, []byte) [][]byte); ok {
-		r0 = rf(root, prefix)
+	if rf, ok := ret.Get(0).(func(common.Hash, []byte, [][]byte) [][]byte); ok {
+		r0 = rf(stateRoot, keyToChild, keys)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([][]byte)
@@ -53,8 +55,8 @@ func (_m *StorageAPI) GetKeysWithPrefix(root *common.Hash, prefix []byte) ([][]b
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*common.Hash, []byte) error); ok {
-		r1 = rf(root, prefix)
+	if rf, ok := ret.Get(1).(func(common.Hash, []byte, [][]byte) error); ok {
+		r1 = rf(stateRoot, keyToChild, keys)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -177,11 +179,57 @@ func (_m *StorageAPI) GetStorageFromChild(root *common.Hash, keyToChild []byte,
 	return r0, r1
 }
 
+// NewIterator provides a mock function with given fields: root, options
+func (_m *StorageAPI) NewIterator(root *common.Hash, options trie.IteratorOptions) (*trie.Iterator, error) {
+	ret := _m.Called(root, options)
+
+	var r0 *trie.Iterator
+	if rf, ok := ret.Get(0).(func(*common.Hash, trie.IteratorOptions) *trie.Iterator); ok {
+		r0 = rf(root, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*trie.Iterator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*common.Hash, trie.IteratorOptions) error); ok {
+		r1 = rf(root, options)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterStorageObserver provides a mock function with given fields: observer
 func (_m *StorageAPI) RegisterStorageObserver(observer state.Observer) {
 	_m.Called(observer)
 }
 
+// TrieState provides a mock function with given fields: root
+func (_m *StorageAPI) TrieState(root *common.Hash) (*storage.TrieState, error) {
+	ret := _m.Called(root)
+
+	var r0 *storage.TrieState
+	if rf, ok := ret.Get(0).(func(*common.Hash) *storage.TrieState); ok {
+		r0 = rf(root)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*storage.TrieState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*common.Hash) error); ok {
+		r1 = rf(root)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UnregisterStorageObserver provides a mock function with given fields: observer
 func (_m *StorageAPI) UnregisterStorageObserver(observer state.Observer) {
 	_m.Called(observer)
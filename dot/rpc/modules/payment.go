@@ -26,13 +26,15 @@ type PaymentQueryInfoResponse struct {
 
 // PaymentModule holds all the RPC implementation of polkadot payment rpc api
 type PaymentModule struct {
-	blockAPI BlockAPI
+	blockAPI   BlockAPI
+	storageAPI StorageAPI
 }
 
 // NewPaymentModule returns a pointer to PaymentModule
-func NewPaymentModule(blockAPI BlockAPI) *PaymentModule {
+func NewPaymentModule(blockAPI BlockAPI, storageAPI StorageAPI) *PaymentModule {
 	return &PaymentModule{
-		blockAPI: blockAPI,
+		blockAPI:   blockAPI,
+		storageAPI: storageAPI,
 	}
 }
 
@@ -55,6 +57,17 @@ func (p *PaymentModule) QueryInfo(_ *http.Request, req *PaymentQueryInfoRequest,
 		return err
 	}
 
+	stateRoot, err := p.storageAPI.GetStateRootFromBlock(&hash)
+	if err != nil {
+		return err
+	}
+
+	ts, err := p.storageAPI.TrieState(stateRoot)
+	if err != nil {
+		return err
+	}
+	r.SetContextStorage(ts)
+
 	encQueryInfo, err := r.PaymentQueryInfo(ext)
 	if err != nil {
 		return err
@@ -13,6 +13,7 @@
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
 	mocksruntime "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 
 	"github.com/stretchr/testify/assert"
@@ -51,7 +52,16 @@ func TestPaymentModule_QueryInfo(t *testing.T) {
 	runtimeErrorMock.On("PaymentQueryInfo", common.MustHexToBytes("0x0000")).
 		Return(nil, errors.New("PaymentQueryInfo error"))
 
-	paymentModule := NewPaymentModule(blockAPIMock)
+	stateRoot := common.Hash{3}
+	trieState := &rtstorage.TrieState{}
+	storageAPIMock := new(mocks.StorageAPI)
+	storageAPIMock.On("GetStateRootFromBlock", &testHash).Return(&stateRoot, nil)
+	storageAPIMock.On("TrieState", &stateRoot).Return(trieState, nil)
+	for _, rt := range []*mocksruntime.Instance{runtimeMock, runtimeMock2, runtimeErrorMock} {
+		rt.On("SetContextStorage", trieState)
+	}
+
+	paymentModule := NewPaymentModule(blockAPIMock, storageAPIMock)
 	type fields struct {
 		blockAPI BlockAPI
 	}
@@ -152,7 +162,8 @@ type args struct {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &PaymentModule{
-				blockAPI: tt.fields.blockAPI,
+				blockAPI:   tt.fields.blockAPI,
+				storageAPI: storageAPIMock,
 			}
 			res := PaymentQueryInfoResponse{}
 			err := p.QueryInfo(tt.args.in0, tt.args.req, &res)
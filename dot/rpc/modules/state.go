@@ -8,10 +8,13 @@
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
@@ -19,6 +22,8 @@
 type StateGetReadProofRequest struct {
 	Keys []string
 	Hash common.Hash
+	// Compact is true to return the proof in the compact format of Substrate.
+	Compact bool
 }
 
 // StateCallRequest holds json fields
@@ -77,6 +82,17 @@ type StateStorageQueryRangeRequest struct {
 	EndBlock   common.Hash `json:"block"`
 }
 
+// StateTraceBlockRequest holds json fields
+type StateTraceBlockRequest struct {
+	Block common.Hash `json:"block" validate:"required"`
+	// Targets is accepted for compatibility, only the storage accesses of the runtime are traced.
+	Targets *string `json:"targets"`
+	// StorageKeys is a comma separated list of hex encoded key prefixes the storage accesses traced must match.
+	StorageKeys *string `json:"storageKeys"`
+	// Methods is a comma separated list of the runtime functions traced.
+	Methods *string `json:"methods"`
+}
+
 // StateStorageKeysQuery field to store storage keys
 type StateStorageKeysQuery [][]byte
 
@@ -131,6 +147,49 @@ type StorageChangeSetResponse struct {
 // StorageKey is the key for the storage
 type StorageKey []byte
 
+// StateTraceBlockResponse holds either the trace of the block or the error tracing it
+type StateTraceBlockResponse struct {
+	BlockTrace *BlockTrace `json:"blockTrace,omitempty"`
+	TraceError *TraceError `json:"traceError,omitempty"`
+}
+
+// BlockTrace is the trace of the execution of a block
+type BlockTrace struct {
+	BlockHash      common.Hash  `json:"blockHash"`
+	ParentHash     common.Hash  `json:"parentHash"`
+	TracingTargets string       `json:"tracingTargets"`
+	StorageKeys    string       `json:"storageKeys"`
+	Methods        string       `json:"methods"`
+	Spans          []TraceSpan  `json:"spans"`
+	Events         []TraceEvent `json:"events"`
+}
+
+// TraceError is the error tracing a block
+type TraceError struct {
+	Error string `json:"error"`
+}
+
+// TraceSpan is a runtime call made while executing a block
+type TraceSpan struct {
+	ID       uint64  `json:"id"`
+	ParentID *uint64 `json:"parentId"`
+	Name     string  `json:"name"`
+	Target   string  `json:"target"`
+	Wasm     bool    `json:"wasm"`
+}
+
+// TraceEvent is a storage access made while executing a block
+type TraceEvent struct {
+	Target   string         `json:"target"`
+	Data     TraceEventData `json:"data"`
+	ParentID *uint64        `json:"parentId"`
+}
+
+// TraceEventData holds the values of a trace event
+type TraceEventData struct {
+	StringValues map[string]string `json:"stringValues"`
+}
+
 // StateRuntimeVersionResponse is the runtime version response
 type StateRuntimeVersionResponse struct {
 	SpecName           string        `json:"specName"`
@@ -172,43 +231,25 @@ func (sm *StateModule) GetPairs(_ *http.Request, req *StatePairRequest, res *Sta
 		}
 	}
 
-	if req.Prefix == nil || *req.Prefix == "" || *req.Prefix == "0x" {
-		pairs, err := sm.storageAPI.Entries(stateRootHash)
+	var prefix []byte
+	if req.Prefix != nil && *req.Prefix != "" && *req.Prefix != "0x" {
+		prefix, err = common.HexToBytes(*req.Prefix)
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot convert hex prefix %s to bytes: %w", *req.Prefix, err)
 		}
-
-		for k, v := range pairs {
-			*res = append(*res, []string{common.BytesToHex([]byte(k)), common.BytesToHex(v)})
-		}
-		return nil
 	}
 
-	reqBytes, err := common.HexToBytes(*req.Prefix)
-	if err != nil {
-		return fmt.Errorf("cannot convert hex prefix %s to bytes: %w", *req.Prefix, err)
-	}
-	keys, err := sm.storageAPI.GetKeysWithPrefix(stateRootHash, reqBytes)
+	iterator, err := sm.storageAPI.NewIterator(stateRootHash, trie.IteratorOptions{Prefix: prefix})
 	if err != nil {
 		return err
 	}
 
-	if len(keys) == 0 {
-		*res = []interface{}{}
-		return nil
-	}
-
-	*res = make([]interface{}, len(keys))
-	for i, key := range keys {
-		val, err := sm.storageAPI.GetStorage(stateRootHash, key)
-		if err != nil {
-			return err
-		}
-
-		(*res)[i] = []string{common.BytesToHex(key), common.BytesToHex(val)}
+	*res = []interface{}{}
+	for iterator.Next() {
+		*res = append(*res, []string{common.BytesToHex(iterator.Key()), common.BytesToHex(iterator.Value())})
 	}
 
-	return nil
+	return iterator.Err()
 }
 
 // Call isn't implemented properly yet.
@@ -227,24 +268,27 @@ func (sm *StateModule) GetKeysPaged(_ *http.Request, req *StateStorageKeyRequest
 	if err != nil {
 		return err
 	}
-	keys, err := sm.storageAPI.GetKeysWithPrefix(req.Block, hPrefix)
+	var afterKey []byte
+	if req.AfterKey != "" {
+		afterKey, err = common.HexToBytes(req.AfterKey)
+		if err != nil {
+			return fmt.Errorf("cannot convert hex after key %s to bytes: %w", req.AfterKey, err)
+		}
+	}
+
+	iterator, err := sm.storageAPI.NewIterator(req.Block, trie.IteratorOptions{
+		Prefix:     hPrefix,
+		StartAfter: afterKey,
+	})
 	if err != nil {
 		return fmt.Errorf("cannot get keys with prefix %s: %w", hPrefix, err)
 	}
-	resCount := uint32(0)
-	for _, k := range keys {
-		fKey := fmt.Sprintf("0x%x", k)
-		if strings.Compare(fKey, req.AfterKey) == 1 {
-			// sm.storageAPI.Keys sorts keys in lexicographical order, so we know that keys where strings.Compare = 1
-			//  are after the requested after key.
-			if resCount >= req.Qty {
-				break
-			}
-			*res = append(*res, fKey)
-			resCount++
-		}
+
+	for resCount := uint32(0); resCount < req.Qty && iterator.Next(); resCount++ {
+		*res = append(*res, common.BytesToHex(iterator.Key()))
 	}
-	return err
+
+	return iterator.Err()
 }
 
 // GetMetadata calls runtime Metadata_metadata function
@@ -273,7 +317,7 @@ func (sm *StateModule) GetReadProof(
 		keys[i] = bKey
 	}
 
-	block, proofs, err := sm.coreAPI.GetReadProofAt(req.Hash, keys)
+	block, proofs, err := sm.coreAPI.GetReadProofAt(req.Hash, keys, req.Compact)
 	if err != nil {
 		return err
 	}
@@ -291,6 +335,114 @@ func (sm *StateModule) GetReadProof(
 	return nil
 }
 
+// TraceBlock re-executes the given block on the state of its parent, and returns the storage
+// accesses made by the runtime. Storage keys and methods optionally filter the accesses
+// returned by key prefix and runtime function.
+func (sm *StateModule) TraceBlock(
+	_ *http.Request, req *StateTraceBlockRequest, res *StateTraceBlockResponse) error {
+	var targets, storageKeys, methods string
+	if req.Targets != nil {
+		targets = *req.Targets
+	}
+	if req.StorageKeys != nil {
+		storageKeys = *req.StorageKeys
+	}
+	if req.Methods != nil {
+		methods = *req.Methods
+	}
+
+	var keyPrefixes [][]byte
+	for _, hexPrefix := range splitTraceFilter(storageKeys) {
+		prefix, err := common.HexToBytes(hexPrefix)
+		if err != nil {
+			return fmt.Errorf("cannot convert hex storage key prefix %s to bytes: %w", hexPrefix, err)
+		}
+		keyPrefixes = append(keyPrefixes, prefix)
+	}
+
+	parentHash, events, err := sm.coreAPI.TraceBlock(req.Block, keyPrefixes)
+	if err != nil {
+		*res = StateTraceBlockResponse{
+			TraceError: &TraceError{Error: err.Error()},
+		}
+		return nil
+	}
+
+	blockTrace := &BlockTrace{
+		BlockHash:      req.Block,
+		ParentHash:     parentHash,
+		TracingTargets: targets,
+		StorageKeys:    storageKeys,
+		Methods:        methods,
+		Spans:          []TraceSpan{},
+		Events:         []TraceEvent{},
+	}
+
+	tracedMethods := make(map[string]struct{})
+	for _, method := range splitTraceFilter(methods) {
+		tracedMethods[method] = struct{}{}
+	}
+
+	spanIDs := make(map[string]uint64)
+	for _, event := range events {
+		if _, traced := tracedMethods[event.Call]; len(tracedMethods) > 0 && !traced {
+			continue
+		}
+
+		var parentID *uint64
+		if event.Call != "" {
+			id, ok := spanIDs[event.Call]
+			if !ok {
+				id = uint64(len(spanIDs) + 1)
+				spanIDs[event.Call] = id
+				blockTrace.Spans = append(blockTrace.Spans, TraceSpan{
+					ID:     id,
+					Name:   event.Call,
+					Target: "runtime",
+					Wasm:   true,
+				})
+			}
+			parentID = &id
+		}
+
+		blockTrace.Events = append(blockTrace.Events, TraceEvent{
+			Target:   "state",
+			Data:     TraceEventData{StringValues: traceEventValues(event)},
+			ParentID: parentID,
+		})
+	}
+
+	*res = StateTraceBlockResponse{BlockTrace: blockTrace}
+	return nil
+}
+
+// splitTraceFilter returns the comma separated values of the trace filter given.
+func splitTraceFilter(filter string) (values []string) {
+	for _, value := range strings.Split(filter, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func traceEventValues(event rtstorage.TraceEvent) map[string]string {
+	values := map[string]string{
+		"message": string(event.Operation),
+	}
+	if event.Key != nil {
+		values["key"] = common.BytesToHex(event.Key)
+	}
+	if event.ChildKey != nil {
+		values["childKey"] = common.BytesToHex(event.ChildKey)
+	}
+	if event.ValueSize != nil {
+		values["valueSize"] = strconv.FormatUint(uint64(*event.ValueSize), 10)
+	}
+	return values
+}
+
 // GetRuntimeVersion Get the runtime version at a given block.
 //  If no block hash is provided, the latest version gets returned.
 func (sm *StateModule) GetRuntimeVersion(
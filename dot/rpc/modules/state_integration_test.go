@@ -469,7 +469,7 @@ func TestStateModule_GetKeysPaged(t *testing.T) {
 func TestGetReadProof_WhenCoreAPIReturnsError(t *testing.T) {
 	coreAPIMock := new(mocks.CoreAPI)
 	coreAPIMock.
-		On("GetReadProofAt", mock.AnythingOfType("common.Hash"), mock.AnythingOfType("[][]uint8")).
+		On("GetReadProofAt", mock.AnythingOfType("common.Hash"), mock.AnythingOfType("[][]uint8"), false).
 		Return(common.Hash{}, nil, errors.New("mocked error"))
 
 	sm := new(StateModule)
@@ -488,7 +488,7 @@ func TestGetReadProof_WhenReturnsProof(t *testing.T) {
 
 	coreAPIMock := new(mocks.CoreAPI)
 	coreAPIMock.
-		On("GetReadProofAt", mock.AnythingOfType("common.Hash"), mock.AnythingOfType("[][]uint8")).
+		On("GetReadProofAt", mock.AnythingOfType("common.Hash"), mock.AnythingOfType("[][]uint8"), false).
 		Return(expectedBlock, mockedProof, nil)
 
 	sm := new(StateModule)
@@ -25,56 +25,40 @@
 	testdata "github.com/ChainSafe/gossamer/dot/rpc/modules/test_data"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestStateModuleGetPairs(t *testing.T) {
 	str := "0x01"
+	invalidPrefix := "a"
+	noKeyPrefix := "0x02"
 	hash := common.MustHexToHash("0x3aa96b0149b6ca3688878bdbd19464448624136398e3ce45b9e755d3ab61355a")
-	m := make(map[string][]byte)
-	m["a"] = []byte{21, 22}
-	m["b"] = []byte{23, 24}
+	tr := trie.NewEmptyTrie()
+	tr.Put([]byte("a"), []byte{21, 22})
+	tr.Put([]byte("b"), []byte{23, 24})
+	tr.Put([]byte{1}, []byte{21})
+	tr.Put([]byte{1, 2}, []byte{22})
+	newIterator := func(_ *common.Hash, options trie.IteratorOptions) *trie.Iterator {
+		return tr.NewIterator(options)
+	}
 
 	mockStorageAPI := new(mocks.StorageAPI)
 	mockStorageAPI.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPI.On("Entries", &hash).Return(m, nil)
-	mockStorageAPI.On("GetKeysWithPrefix", &hash, common.MustHexToBytes(str)).Return([][]byte{{1}, {1}}, nil)
-	mockStorageAPI.On("GetStorage", &hash, []byte{1}).Return([]byte{21}, nil)
-
-	mockStorageAPINil := new(mocks.StorageAPI)
-	mockStorageAPINil.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPINil.On("Entries", &hash).Return(m, nil)
-
-	mockStorageAPIGetKeysEmpty := new(mocks.StorageAPI)
-	mockStorageAPIGetKeysEmpty.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPIGetKeysEmpty.On("Entries", &hash).Return(m, nil)
-	mockStorageAPIGetKeysEmpty.On("GetKeysWithPrefix", &hash, common.MustHexToBytes(str)).Return([][]byte{}, nil)
+	mockStorageAPI.On("NewIterator", &hash, mock.AnythingOfType("trie.IteratorOptions")).Return(newIterator, nil)
 
-	mockStorageAPIGetKeysErr := new(mocks.StorageAPI)
-	mockStorageAPIGetKeysErr.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPIGetKeysErr.On("Entries", &hash).Return(m, nil)
-	mockStorageAPIGetKeysErr.On("GetKeysWithPrefix", &hash, common.MustHexToBytes(str)).
-		Return(nil, errors.New("GetKeysWithPrefix Err"))
-
-	mockStorageAPIEntriesErr := new(mocks.StorageAPI)
-	mockStorageAPIEntriesErr.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPIEntriesErr.On("Entries", &hash).Return(nil, errors.New("entries Err"))
+	mockStorageAPIIteratorErr := new(mocks.StorageAPI)
+	mockStorageAPIIteratorErr.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
+	mockStorageAPIIteratorErr.On("NewIterator", &hash, trie.IteratorOptions{}).
+		Return(nil, errors.New("NewIterator Err"))
 
 	mockStorageAPIErr := new(mocks.StorageAPI)
 	mockStorageAPIErr.On("GetStateRootFromBlock", &hash).Return(nil, errors.New("GetStateRootFromBlock Err"))
 
-	mockStorageAPIGetStorageErr := new(mocks.StorageAPI)
-	mockStorageAPIGetStorageErr.On("GetStateRootFromBlock", &hash).Return(&hash, nil)
-	mockStorageAPIGetStorageErr.On("Entries", &hash).Return(m, nil)
-	mockStorageAPIGetStorageErr.On("GetKeysWithPrefix", &hash, common.MustHexToBytes(str)).Return([][]byte{{2}, {2}}, nil)
-	mockStorageAPIGetStorageErr.On("GetStorage", &hash, []byte{2}).Return(nil, errors.New("GetStorage Err"))
-
-	var expRes StatePairResponse
-	for k, v := range m {
-		expRes = append(expRes, []string{common.BytesToHex([]byte(k)), common.BytesToHex(v)})
-	}
 	type fields struct {
 		networkAPI NetworkAPI
 		storageAPI StorageAPI
@@ -99,28 +83,34 @@ type args struct {
 					Bhash: &hash,
 				},
 			},
+			exp:    StatePairResponse{},
 			expErr: errors.New("GetStateRootFromBlock Err"),
 		},
 		{
 			name:   "Nil Prefix OK",
-			fields: fields{nil, mockStorageAPINil, nil},
+			fields: fields{nil, mockStorageAPI, nil},
 			args: args{
 				req: &StatePairRequest{
 					Bhash: &hash,
 				},
 			},
-			exp: expRes,
+			exp: StatePairResponse{
+				[]string{"0x01", "0x15"},
+				[]string{"0x0102", "0x16"},
+				[]string{"0x61", "0x1516"},
+				[]string{"0x62", "0x1718"},
+			},
 		},
 		{
-			name:   "Nil Prefix Err",
-			fields: fields{nil, mockStorageAPIEntriesErr, nil},
+			name:   "NewIterator Error",
+			fields: fields{nil, mockStorageAPIIteratorErr, nil},
 			args: args{
 				req: &StatePairRequest{
 					Bhash: &hash,
 				},
 			},
-			exp:    []interface{}{},
-			expErr: errors.New("entries Err"),
+			exp:    StatePairResponse{},
+			expErr: errors.New("NewIterator Err"),
 		},
 		{
 			name:   "OK Case",
@@ -131,37 +121,26 @@ type args struct {
 					Bhash:  &hash,
 				},
 			},
-			exp: StatePairResponse{[]string{"0x01", "0x15"}, []string{"0x01", "0x15"}},
-		},
-		{
-			name:   "GetKeysWithPrefix Error",
-			fields: fields{nil, mockStorageAPIGetKeysErr, nil},
-			args: args{
-				req: &StatePairRequest{
-					Prefix: &str,
-					Bhash:  &hash,
-				},
-			},
-			expErr: errors.New("GetKeysWithPrefix Err"),
+			exp: StatePairResponse{[]string{"0x01", "0x15"}, []string{"0x0102", "0x16"}},
 		},
 		{
-			name:   "GetStorage Error",
-			fields: fields{nil, mockStorageAPIGetStorageErr, nil},
+			name:   "Invalid Prefix Error",
+			fields: fields{nil, mockStorageAPI, nil},
 			args: args{
 				req: &StatePairRequest{
-					Prefix: &str,
+					Prefix: &invalidPrefix,
 					Bhash:  &hash,
 				},
 			},
-			exp:    StatePairResponse{interface{}(nil), interface{}(nil)},
-			expErr: errors.New("GetStorage Err"),
+			exp:    StatePairResponse{},
+			expErr: errors.New("cannot convert hex prefix a to bytes: could not byteify non 0x prefixed string: a"),
 		},
 		{
-			name:   "GetKeysWithPrefix Empty",
-			fields: fields{nil, mockStorageAPIGetKeysEmpty, nil},
+			name:   "No Key With Prefix",
+			fields: fields{nil, mockStorageAPI, nil},
 			args: args{
 				req: &StatePairRequest{
-					Prefix: &str,
+					Prefix: &noKeyPrefix,
 					Bhash:  &hash,
 				},
 			},
@@ -182,23 +161,28 @@ type args struct {
 			} else {
 				assert.NoError(t, err)
 			}
-			assert.ElementsMatch(t, tt.exp, res)
+			assert.Equal(t, tt.exp, res)
 		})
 	}
 }
 
 func TestStateModuleGetKeysPaged(t *testing.T) {
-	mockStorageAPI := new(mocks.StorageAPI)
-	mockStorageAPI.On("GetKeysWithPrefix", (*common.Hash)(nil), common.MustHexToBytes("0x")).
-		Return([][]byte{{1}, {2}}, nil)
+	tr := trie.NewEmptyTrie()
+	tr.Put([]byte{1}, []byte{1})
+	tr.Put([]byte{1, 1, 1}, []byte{1})
+	tr.Put([]byte{1, 1, 2}, []byte{1})
+	tr.Put([]byte{2}, []byte{1})
+	newIterator := func(_ *common.Hash, options trie.IteratorOptions) *trie.Iterator {
+		return tr.NewIterator(options)
+	}
 
-	mockStorageAPI2 := new(mocks.StorageAPI)
-	mockStorageAPI2.On("GetKeysWithPrefix", (*common.Hash)(nil), common.MustHexToBytes("0x")).
-		Return([][]byte{{1, 1, 1}, {1, 1, 1}}, nil)
+	mockStorageAPI := new(mocks.StorageAPI)
+	mockStorageAPI.On("NewIterator", (*common.Hash)(nil), mock.AnythingOfType("trie.IteratorOptions")).
+		Return(newIterator, nil)
 
 	mockStorageAPIErr := new(mocks.StorageAPI)
-	mockStorageAPIErr.On("GetKeysWithPrefix", (*common.Hash)(nil), common.MustHexToBytes("0x")).
-		Return(nil, errors.New("GetKeysWithPrefix Err"))
+	mockStorageAPIErr.On("NewIterator", (*common.Hash)(nil), mock.AnythingOfType("trie.IteratorOptions")).
+		Return(nil, errors.New("NewIterator Err"))
 
 	type fields struct {
 		networkAPI NetworkAPI
@@ -228,7 +212,7 @@ type args struct {
 		},
 		{
 			name:   "ResCount break",
-			fields: fields{nil, mockStorageAPI2, nil},
+			fields: fields{nil, mockStorageAPI, nil},
 			args: args{
 				req: &StateStorageKeyRequest{
 					Qty:      1,
@@ -238,14 +222,26 @@ type args struct {
 			exp: StateStorageKeysResponse{"0x010101"},
 		},
 		{
-			name:   "GetKeysWithPrefix Error",
+			name:   "Prefix and after key",
+			fields: fields{nil, mockStorageAPI, nil},
+			args: args{
+				req: &StateStorageKeyRequest{
+					Prefix:   "0x01",
+					Qty:      10,
+					AfterKey: "0x010101",
+				},
+			},
+			exp: StateStorageKeysResponse{"0x010102"},
+		},
+		{
+			name:   "NewIterator Error",
 			fields: fields{nil, mockStorageAPIErr, nil},
 			args: args{
 				req: &StateStorageKeyRequest{
 					AfterKey: "0x01",
 				},
 			},
-			expErr: errors.New("cannot get keys with prefix : GetKeysWithPrefix Err"),
+			expErr: errors.New("cannot get keys with prefix : NewIterator Err"),
 		},
 		{
 			name:   "Request Prefix Error",
@@ -258,6 +254,16 @@ type args struct {
 			},
 			expErr: errors.New("could not byteify non 0x prefixed string: a"),
 		},
+		{
+			name:   "Request After Key Error",
+			fields: fields{nil, mockStorageAPI, nil},
+			args: args{
+				req: &StateStorageKeyRequest{
+					AfterKey: "a",
+				},
+			},
+			expErr: errors.New("cannot convert hex after key a to bytes: could not byteify non 0x prefixed string: a"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -366,10 +372,11 @@ func TestStateModuleGetReadProof(t *testing.T) {
 	}
 
 	mockCoreAPI := new(mocks.CoreAPI)
-	mockCoreAPI.On("GetReadProofAt", hash, expKeys).Return(hash, [][]byte{{1, 1, 1}, {1, 1, 1}}, nil)
+	mockCoreAPI.On("GetReadProofAt", hash, expKeys, false).Return(hash, [][]byte{{1, 1, 1}, {1, 1, 1}}, nil)
+	mockCoreAPI.On("GetReadProofAt", hash, expKeys, true).Return(hash, [][]byte{{1, 1}}, nil)
 
 	mockCoreAPIErr := new(mocks.CoreAPI)
-	mockCoreAPIErr.On("GetReadProofAt", hash, expKeys).Return(nil, nil, errors.New("GetReadProofAt Error"))
+	mockCoreAPIErr.On("GetReadProofAt", hash, expKeys, false).Return(nil, nil, errors.New("GetReadProofAt Error"))
 
 	type fields struct {
 		networkAPI NetworkAPI
@@ -403,6 +410,21 @@ type args struct {
 				Proof: []string{"0x010101", "0x010101"},
 			},
 		},
+		{
+			name:   "compact proof",
+			fields: fields{nil, nil, mockCoreAPI},
+			args: args{
+				req: &StateGetReadProofRequest{
+					Keys:    keys,
+					Hash:    hash,
+					Compact: true,
+				},
+			},
+			exp: StateGetReadProofResponse{
+				At:    hash,
+				Proof: []string{"0x0101"},
+			},
+		},
 		{
 			name:   "GetReadProofAt Error",
 			fields: fields{nil, nil, mockCoreAPIErr},
@@ -445,6 +467,104 @@ type args struct {
 	}
 }
 
+func TestStateModuleTraceBlock(t *testing.T) {
+	hash := common.Hash{2}
+	parentHash := common.Hash{1}
+	valueSize := uint32(3)
+	events := []rtstorage.TraceEvent{
+		{Operation: rtstorage.TraceGet, Key: []byte{1}},
+		{Call: runtime.CoreExecuteBlock, Operation: rtstorage.TraceSet, Key: []byte{1, 2}, ValueSize: &valueSize},
+		{Call: runtime.CoreExecuteBlock, Operation: rtstorage.TraceClear, ChildKey: []byte{3}, Key: []byte{1}},
+		{Call: "BlockBuilder_finalize_block", Operation: rtstorage.TraceNextKey, Key: []byte{1, 3}},
+	}
+	prefixes := [][]byte{{1}, {3}}
+
+	mockCoreAPI := new(mocks.CoreAPI)
+	mockCoreAPI.On("TraceBlock", hash, prefixes).Return(parentHash, events, nil)
+	mockCoreAPIErr := new(mocks.CoreAPI)
+	mockCoreAPIErr.On("TraceBlock", hash, [][]byte(nil)).
+		Return(common.Hash{}, nil, errors.New("cannot get block: not found"))
+
+	storageKeys := "0x01, 0x03"
+	methods := runtime.CoreExecuteBlock
+	executeBlockSpan, finalizeBlockSpan := uint64(1), uint64(2)
+
+	tests := map[string]struct {
+		coreAPI CoreAPI
+		req     *StateTraceBlockRequest
+		exp     StateTraceBlockResponse
+		expErr  string
+	}{
+		"all methods": {
+			coreAPI: mockCoreAPI,
+			req:     &StateTraceBlockRequest{Block: hash, StorageKeys: &storageKeys},
+			exp: StateTraceBlockResponse{BlockTrace: &BlockTrace{
+				BlockHash:   hash,
+				ParentHash:  parentHash,
+				StorageKeys: storageKeys,
+				Spans: []TraceSpan{
+					{ID: 1, Name: runtime.CoreExecuteBlock, Target: "runtime", Wasm: true},
+					{ID: 2, Name: "BlockBuilder_finalize_block", Target: "runtime", Wasm: true},
+				},
+				Events: []TraceEvent{
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "get", "key": "0x01"}}},
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "set", "key": "0x0102", "valueSize": "3"}}, ParentID: &executeBlockSpan},
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "clear", "childKey": "0x03", "key": "0x01"}}, ParentID: &executeBlockSpan},
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "next_key", "key": "0x0103"}}, ParentID: &finalizeBlockSpan},
+				},
+			}},
+		},
+		"methods filter": {
+			coreAPI: mockCoreAPI,
+			req:     &StateTraceBlockRequest{Block: hash, StorageKeys: &storageKeys, Methods: &methods},
+			exp: StateTraceBlockResponse{BlockTrace: &BlockTrace{
+				BlockHash:   hash,
+				ParentHash:  parentHash,
+				StorageKeys: storageKeys,
+				Methods:     methods,
+				Spans: []TraceSpan{
+					{ID: 1, Name: runtime.CoreExecuteBlock, Target: "runtime", Wasm: true},
+				},
+				Events: []TraceEvent{
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "set", "key": "0x0102", "valueSize": "3"}}, ParentID: &executeBlockSpan},
+					{Target: "state", Data: TraceEventData{StringValues: map[string]string{
+						"message": "clear", "childKey": "0x03", "key": "0x01"}}, ParentID: &executeBlockSpan},
+				},
+			}},
+		},
+		"trace error": {
+			coreAPI: mockCoreAPIErr,
+			req:     &StateTraceBlockRequest{Block: hash},
+			exp: StateTraceBlockResponse{
+				TraceError: &TraceError{Error: "cannot get block: not found"},
+			},
+		},
+		"invalid storage key": {
+			req: &StateTraceBlockRequest{Block: hash, StorageKeys: &methods},
+			expErr: "cannot convert hex storage key prefix Core_execute_block to bytes: " +
+				"could not byteify non 0x prefixed string: Core_execute_block",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sm := &StateModule{coreAPI: tt.coreAPI}
+			res := StateTraceBlockResponse{}
+			err := sm.TraceBlock(nil, tt.req, &res)
+			if tt.expErr != "" {
+				assert.EqualError(t, err, tt.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.exp, res)
+		})
+	}
+}
+
 func TestStateModuleGetRuntimeVersion(t *testing.T) {
 	hash := common.MustHexToHash("0x3aa96b0149b6ca3688878bdbd19464448624136398e3ce45b9e755d3ab61355a")
 	testAPIItem := runtime.APIItem{
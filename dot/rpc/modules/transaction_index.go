@@ -0,0 +1,44 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// TransactionIndexDataRequest represents the request to retrieve indexed transaction data
+type TransactionIndexDataRequest struct {
+	Hash common.Hash
+}
+
+// TransactionIndexModule is an RPC module to retrieve the transaction data indexed by the runtime
+type TransactionIndexModule struct {
+	transactionIndexAPI TransactionIndexAPI
+}
+
+// NewTransactionIndexModule creates an instance of TransactionIndexModule given TransactionIndexAPI.
+func NewTransactionIndexModule(api TransactionIndexAPI) *TransactionIndexModule {
+	return &TransactionIndexModule{
+		transactionIndexAPI: api,
+	}
+}
+
+// GetData returns the hex encoded indexed transaction data with the given content hash.
+// The response is empty if the data is not indexed or was pruned.
+func (tm *TransactionIndexModule) GetData(_ *http.Request, req *TransactionIndexDataRequest,
+	res *StringResponse) error {
+	data, err := tm.transactionIndexAPI.GetIndexedTransaction(req.Hash)
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	*res = StringResponse(common.BytesToHex(data))
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package modules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/rpc/modules/mocks"
+	"github.com/ChainSafe/gossamer/lib/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionIndexModule_GetData(t *testing.T) {
+	data := []byte("indexed data")
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name   string
+		hash   common.Hash
+		data   []byte
+		err    error
+		expErr error
+		exp    StringResponse
+	}{
+		{
+			name: "indexed data",
+			hash: common.Hash{1},
+			data: data,
+			exp:  StringResponse(common.BytesToHex(data)),
+		},
+		{
+			name: "data not found",
+			hash: common.Hash{2},
+			err:  chaindb.ErrKeyNotFound,
+		},
+		{
+			name:   "database error",
+			hash:   common.Hash{3},
+			err:    errTest,
+			expErr: errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := new(mocks.TransactionIndexAPI)
+			api.On("GetIndexedTransaction", tt.hash).Return(tt.data, tt.err)
+
+			module := NewTransactionIndexModule(api)
+			var res StringResponse
+			err := module.GetData(nil, &TransactionIndexDataRequest{Hash: tt.hash}, &res)
+			assert.ErrorIs(t, err, tt.expErr)
+			assert.Equal(t, tt.exp, res)
+			api.AssertExpectations(t)
+		})
+	}
+}
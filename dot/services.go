@@ -31,7 +31,9 @@
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/runtime/life"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/lib/utils"
 )
 
@@ -59,6 +61,13 @@ func (nodeBuilder) createStateService(cfg *Config) (*state.Service, error) {
 		Path:     cfg.Global.BasePath,
 		LogLevel: cfg.Log.StateLvl,
 		Metrics:  metrics.NewIntervalConfig(cfg.Global.PublishMetrics),
+
+		RuntimePoolSize: uint(cfg.Core.RuntimePoolSize),
+		TrieCacheSize:   uint(cfg.Core.TrieCacheSize) * 1024 * 1024,
+	}
+
+	if cfg.Core.ParallelHashingThreshold != 0 {
+		trie.SetParallelHashingThreshold(cfg.Core.ParallelHashingThreshold)
 	}
 
 	stateSrvc := state.NewService(config)
@@ -108,6 +117,13 @@ func createRuntime(cfg *Config, ns runtime.NodeStorage, st *state.Service,
 	runtime.Instance, error) {
 	logger.Info("creating runtime with interpreter " + cfg.Core.WasmInterpreter + "...")
 
+	// the code hash is the hash of the code stored in the state,
+	// even if the code is substituted below.
+	codeHash, err := common.Blake2bHash(code)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash runtime code: %w", err)
+	}
+
 	// check if code substitute is in use, if so replace code
 	codeSubHash := st.Base.LoadCodeSubstitutedBlockHash()
 
@@ -127,11 +143,6 @@ func createRuntime(cfg *Config, ns runtime.NodeStorage, st *state.Service,
 		return nil, err
 	}
 
-	codeHash, err := st.Storage.LoadCodeHash(nil)
-	if err != nil {
-		return nil, err
-	}
-
 	var rt runtime.Instance
 	switch cfg.Core.WasmInterpreter {
 	case wasmer.Name:
@@ -176,6 +187,32 @@ func createRuntime(cfg *Config, ns runtime.NodeStorage, st *state.Service,
 	return rt, nil
 }
 
+// genesisStateVersion returns the state trie version of the runtime
+// stored in the given genesis trie, as found in its runtime version.
+func genesisStateVersion(t *trie.Trie, logLvl log.Level) (version trie.Version, err error) {
+	ts, err := rtstorage.NewTrieState(t.Snapshot())
+	if err != nil {
+		return 0, fmt.Errorf("cannot create trie state: %w", err)
+	}
+
+	rtCfg := &wasmer.Config{}
+	rtCfg.Storage = ts
+	rtCfg.LogLvl = logLvl
+
+	rt, err := wasmer.NewRuntimeFromGenesis(rtCfg)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create genesis runtime: %w", err)
+	}
+	defer rt.Stop()
+
+	runtimeVersion, err := rt.Version()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get genesis runtime version: %w", err)
+	}
+
+	return trie.ParseVersion(uint32(runtimeVersion.StateVersion()))
+}
+
 func asAuthority(authority bool) string {
 	if authority {
 		return " as authority"
@@ -266,6 +303,12 @@ func (nodeBuilder) createCoreService(cfg *Config, ks *keystore.GlobalKeystore,
 		Network:              net,
 		CodeSubstitutes:      codeSubs,
 		CodeSubstitutedState: st.Base,
+
+		TransactionIndexState: st.TransactionIndex,
+
+		WasmInterpreter:           cfg.Core.WasmInterpreter,
+		OffchainWorkerConcurrency: uint(cfg.Core.OffchainWorkerConcurrency),
+		OffchainWorkerTimeout:     cfg.Core.OffchainWorkerTimeout,
 	}
 
 	// create new core service
@@ -313,6 +356,7 @@ func (nodeBuilder) createNetworkService(cfg *Config, stateSrvc *state.Service,
 		Telemetry:         telemetryMailer,
 		PublicDNS:         cfg.Network.PublicDNS,
 		Metrics:           metrics.NewIntervalConfig(cfg.Global.PublishMetrics),
+		TransactionIndex:  stateSrvc.TransactionIndex,
 	}
 
 	networkSrvc, err := network.NewService(&networkConfig)
@@ -363,6 +407,7 @@ func (nodeBuilder) createRPCService(params rpcServiceSettings) (*rpc.HTTPServer,
 		RPCAPI:              rpcService,
 		SyncStateAPI:        syncStateSrvc,
 		SyncAPI:             params.syncer,
+		TransactionIndexAPI: params.state.TransactionIndex,
 		SystemAPI:           params.system,
 		RPC:                 params.config.RPC.Enabled,
 		RPCExternal:         params.config.RPC.External,
@@ -399,6 +444,12 @@ func (nodeBuilder) createGRANDPAService(cfg *Config, st *state.Service, dh *dige
 		return nil, err
 	}
 
+	ts, err := st.Storage.TrieState(nil)
+	if err != nil {
+		return nil, err
+	}
+	rt.SetContextStorage(ts)
+
 	ad, err := rt.GrandpaAuthorities()
 	if err != nil {
 		return nil, err
@@ -575,19 +575,40 @@ func (bs *BlockState) HandleRuntimeChanges(newState *rtstorage.TrieState,
 		return err
 	}
 
+	heapPages, err := runtime.GetHeapPages(newState)
+	if err != nil {
+		return err
+	}
+
 	codeHash := rt.GetCodeHash()
-	if bytes.Equal(codeHash[:], currCodeHash[:]) {
+	codeChanged := !bytes.Equal(codeHash[:], currCodeHash[:])
+	heapPagesChanged := heapPages != rt.GetHeapPages()
+	if !codeChanged && !heapPagesChanged {
 		bs.StoreRuntime(bHash, rt)
 		return nil
 	}
 
-	logger.Infof("🔄 detected runtime code change, upgrading with block %s from previous code hash %s to new code hash %s...", //nolint:lll
-		bHash, codeHash, currCodeHash)
 	code := newState.LoadCode()
 	if len(code) == 0 {
 		return errors.New("new :code is empty")
 	}
 
+	if !codeChanged {
+		logger.Infof("🔄 detected runtime heap pages change, upgrading with block %s from previous heap pages %d to new heap pages %d...", //nolint:lll
+			bHash, rt.GetHeapPages(), heapPages)
+
+		instance, err := bs.newRuntimeInstance(newState, rt, code, currCodeHash)
+		if err != nil {
+			return err
+		}
+
+		bs.StoreRuntime(bHash, instance)
+		return nil
+	}
+
+	logger.Infof("🔄 detected runtime code change, upgrading with block %s from previous code hash %s to new code hash %s...", //nolint:lll
+		bHash, codeHash, currCodeHash)
+
 	codeSubBlockHash := bs.baseState.LoadCodeSubstitutedBlockHash()
 
 	if !codeSubBlockHash.Equal(common.Hash{}) {
@@ -609,21 +630,7 @@ func (bs *BlockState) HandleRuntimeChanges(newState *rtstorage.TrieState,
 			bHash, codeHash, previousVersion.SpecVersion(), currCodeHash, newVersion.SpecVersion())
 	}
 
-	rtCfg := &wasmer.Config{
-		Imports: wasmer.ImportsNodeRuntime,
-	}
-
-	rtCfg.Storage = newState
-	rtCfg.Keystore = rt.Keystore()
-	rtCfg.NodeStorage = rt.NodeStorage()
-	rtCfg.Network = rt.NetworkService()
-	rtCfg.CodeHash = currCodeHash
-
-	if rt.Validator() {
-		rtCfg.Role = 4
-	}
-
-	instance, err := wasmer.NewInstance(code, rtCfg)
+	instance, err := bs.newRuntimeInstance(newState, rt, code, currCodeHash)
 	if err != nil {
 		return err
 	}
@@ -643,6 +650,27 @@ func (bs *BlockState) HandleRuntimeChanges(newState *rtstorage.TrieState,
 	return nil
 }
 
+// newRuntimeInstance creates a new runtime instance running the given code on the given state,
+// with the same keystore, node storage, network and role as the given runtime instance.
+func (*BlockState) newRuntimeInstance(newState *rtstorage.TrieState, rt runtime.Instance,
+	code []byte, codeHash common.Hash) (runtime.Instance, error) {
+	rtCfg := &wasmer.Config{
+		Imports: wasmer.ImportsNodeRuntime,
+	}
+
+	rtCfg.Storage = newState
+	rtCfg.Keystore = rt.Keystore()
+	rtCfg.NodeStorage = rt.NodeStorage()
+	rtCfg.Network = rt.NetworkService()
+	rtCfg.CodeHash = codeHash
+
+	if rt.Validator() {
+		rtCfg.Role = 4
+	}
+
+	return wasmer.NewInstance(code, rtCfg)
+}
+
 // GetRuntime gets the runtime for the corresponding block hash.
 func (bs *BlockState) GetRuntime(hash *common.Hash) (runtime.Instance, error) {
 	if hash == nil {
@@ -107,7 +107,7 @@ func (p *OfflinePruner) SetBloomFilter() (err error) {
 	}
 
 	latestBlockNum := header.Number
-	keys := make(map[common.Hash]struct{})
+	var nodeHashes uint
 
 	logger.Infof("Latest block number is %d", latestBlockNum)
 
@@ -123,24 +123,29 @@ func (p *OfflinePruner) SetBloomFilter() (err error) {
 			return err
 		}
 
-		tr.PopulateNodeHashes(tr.RootNode(), keys)
+		iterator := tr.NewNodeIterator()
+		for iterator.Next() {
+			err = p.bloom.put(iterator.Hash())
+			if err != nil {
+				return err
+			}
+			nodeHashes++
+		}
 
-		// get parent header of current block
-		header, err = p.blockState.GetHeader(header.ParentHash)
+		err = iterator.Err()
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot iterate over nodes of trie with root %s: %w", header.StateRoot, err)
 		}
-		blockNum = header.Number
-	}
 
-	for key := range keys {
-		err = p.bloom.put(key.ToBytes())
+		// get parent header of current block
+		header, err = p.blockState.GetHeader(header.ParentHash)
 		if err != nil {
 			return err
 		}
+		blockNum = header.Number
 	}
 
-	logger.Infof("Total keys added in bloom filter: %d", len(keys))
+	logger.Infof("Total keys added in bloom filter: %d", nodeHashes)
 	return nil
 }
 
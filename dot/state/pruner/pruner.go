@@ -173,6 +173,11 @@ func (p *FullNode) addDeathRow(jr *journalRecord, blockNum int64) {
 	// add deleted keys from journal to death index
 	deletedKeys := make(map[common.Hash]int64, len(jr.deletedHashesSet))
 	for k := range jr.deletedHashesSet {
+		if _, inserted := jr.insertedHashesSet[k]; inserted {
+			// the key is deleted and inserted back by the block,
+			// for example the value of a node stored by hash.
+			continue
+		}
 		p.deathIndex[k] = blockNum
 		deletedKeys[k] = blockNum
 	}
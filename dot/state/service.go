@@ -37,9 +37,15 @@ type Service struct {
 	Grandpa     *GrandpaState
 	closeCh     chan interface{}
 
+	TransactionIndex *TransactionIndexState
+
 	PrunerCfg pruner.Config
 	Telemetry telemetry.Client
 
+	transactionIndexRetention uint
+	runtimePoolSize           uint
+	trieCacheSize             uint
+
 	// Below are for testing only.
 	BabeThresholdNumerator   uint64
 	BabeThresholdDenominator uint64
@@ -52,6 +58,18 @@ type Config struct {
 	PrunerCfg pruner.Config
 	Telemetry telemetry.Client
 	Metrics   metrics.IntervalConfig
+
+	// TransactionIndexRetention is the number of blocks indexed transaction data is kept for.
+	// DefaultTransactionIndexRetention is used if it is 0.
+	TransactionIndexRetention uint
+
+	// RuntimePoolSize is the maximum number of runtime instances per runtime code.
+	// runtime.DefaultPoolSize is used if it is 0.
+	RuntimePoolSize uint
+
+	// TrieCacheSize is the maximum size in bytes of the cache of trie nodes
+	// decoded from the database. trie.DefaultNodeCacheSize is used if it is 0.
+	TrieCacheSize uint
 }
 
 // NewService create a new instance of Service
@@ -68,6 +86,10 @@ func NewService(config Config) *Service {
 		closeCh:   make(chan interface{}),
 		PrunerCfg: config.PrunerCfg,
 		Telemetry: config.Telemetry,
+
+		transactionIndexRetention: config.TransactionIndexRetention,
+		runtimePoolSize:           config.RuntimePoolSize,
+		trieCacheSize:             config.TrieCacheSize,
 	}
 }
 
@@ -113,6 +135,12 @@ func (s *Service) Start() error {
 		return nil
 	}
 
+	trieCacheSize := s.trieCacheSize
+	if trieCacheSize == 0 {
+		trieCacheSize = trie.DefaultNodeCacheSize
+	}
+	trie.SetNodeCacheSize(int(trieCacheSize))
+
 	tries, err := NewTries(trie.NewEmptyTrie())
 	if err != nil {
 		return fmt.Errorf("cannot create tries: %w", err)
@@ -123,6 +151,7 @@ func (s *Service) Start() error {
 	if err != nil {
 		return fmt.Errorf("failed to create block state: %w", err)
 	}
+	s.Block.bt.SetRuntimePoolSize(s.runtimePoolSize)
 
 	// retrieve latest header
 	bestHeader, err := s.Block.GetHighestFinalisedHeader()
@@ -144,12 +173,17 @@ func (s *Service) Start() error {
 		return fmt.Errorf("failed to create storage state: %w", err)
 	}
 
-	// load current storage state trie into memory
+	// load current storage state trie
 	_, err = s.Storage.LoadFromDB(stateRoot)
 	if err != nil {
 		return fmt.Errorf("failed to load storage trie from database: %w", err)
 	}
 
+	err = s.Storage.rebuildSnapshot(stateRoot)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild flat state snapshot: %w", err)
+	}
+
 	// create transaction queue
 	s.Transaction = NewTransactionState(s.Telemetry)
 
@@ -164,6 +198,8 @@ func (s *Service) Start() error {
 		return fmt.Errorf("failed to create grandpa state: %w", err)
 	}
 
+	s.TransactionIndex = NewTransactionIndexState(s.db, s.transactionIndexRetention)
+
 	num, _ := s.Block.BestBlockNumber()
 	logger.Infof(
 		"created state service with head %s, highest number %d and genesis hash %s",
@@ -190,6 +226,7 @@ func (s *Service) Rewind(toBlock uint) error {
 	}
 
 	s.Block.bt = blocktree.NewBlockTreeFromRoot(&root.Header)
+	s.Block.bt.SetRuntimePoolSize(s.runtimePoolSize)
 
 	header, err := s.Block.BestBlockHeader()
 	if err != nil {
@@ -253,6 +290,11 @@ func (s *Service) Rewind(toBlock uint) error {
 func (s *Service) Stop() error {
 	close(s.closeCh)
 
+	// the flat state snapshot rebuild must end before the database is closed.
+	if s.Storage != nil {
+		s.Storage.snapshotRebuild.Wait()
+	}
+
 	hash, err := s.Block.GetHighestFinalisedHash()
 	if err != nil {
 		return err
@@ -0,0 +1,369 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+const (
+	// entriesPrefix is the prefix of the database table
+	// holding the keys and values of the disk layer.
+	entriesPrefix = "flatkv"
+	// maxBatchSize is the approximate size in bytes of the keys and values
+	// written to the database at once when the disk layer is rebuilt.
+	maxBatchSize = 4 * 1024 * 1024
+)
+
+// rootKey is the database key of the state root of the disk layer.
+var rootKey = []byte("flatroot")
+
+// DefaultMaxLayers is the default maximum number of diff layers of a snapshot.
+const DefaultMaxLayers = 1024
+
+var (
+	// ErrRootNotCovered is returned when a state root is neither the state root
+	// of the disk layer nor the state root of a diff layer above it.
+	ErrRootNotCovered = errors.New("state root not covered by snapshot")
+
+	// ErrTooManyLayers is returned when adding a diff layer to
+	// a snapshot already holding its maximum number of diff layers.
+	ErrTooManyLayers = errors.New("too many diff layers")
+)
+
+// Snapshot is a flat key-value view of the state trie at recent state roots,
+// so values can be read without traversing the trie. Its disk layer holds the
+// keys and values of the state trie at a single state root, usually the one of
+// the latest finalised block, in a database table. Its diff layers hold in memory
+// the changes of the state trie for the state roots of the blocks above it.
+// The child tries are not part of the snapshot.
+type Snapshot struct {
+	db        chaindb.Database
+	entries   chaindb.Database
+	maxLayers int
+
+	mutex    sync.RWMutex
+	diskRoot common.Hash
+	layers   map[common.Hash]*diffLayer
+}
+
+// diffLayer holds the changes of the state trie from its parent state root.
+type diffLayer struct {
+	parentRoot common.Hash
+	// changes maps the keys changed to their new value,
+	// which is nil if the key is deleted.
+	changes map[string][]byte
+}
+
+// New returns a snapshot with its disk layer stored in the database
+// given, and with at most the maximum number of diff layers given.
+func New(db chaindb.Database, maxLayers int) (snapshot *Snapshot, err error) {
+	snapshot = &Snapshot{
+		db:        db,
+		entries:   chaindb.NewTable(db, entriesPrefix),
+		maxLayers: maxLayers,
+		layers:    make(map[common.Hash]*diffLayer),
+	}
+
+	root, err := db.Get(rootKey)
+	switch {
+	case errors.Is(err, chaindb.ErrKeyNotFound):
+		// the disk layer is not built, or was not fully written
+	case err != nil:
+		return nil, fmt.Errorf("cannot get state root of disk layer: %w", err)
+	default:
+		snapshot.diskRoot = common.BytesToHash(root)
+	}
+
+	return snapshot, nil
+}
+
+// Root returns the state root of the disk layer,
+// which is the zero hash if the disk layer is not built.
+func (s *Snapshot) Root() (root common.Hash) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.diskRoot
+}
+
+// Covers returns true if the state root given is covered by the snapshot.
+func (s *Snapshot) Covers(root common.Hash) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.covers(root)
+}
+
+func (s *Snapshot) covers(root common.Hash) bool {
+	if s.diskRoot.IsEmpty() {
+		return false
+	}
+
+	for root != s.diskRoot {
+		layer, ok := s.layers[root]
+		if !ok {
+			return false
+		}
+		root = layer.parentRoot
+	}
+	return true
+}
+
+// Update adds a diff layer for the state root given, holding the changes given
+// of the state trie from the parent state root given, which must be covered by
+// the snapshot. The changes of the child tries are ignored.
+func (s *Snapshot) Update(parentRoot, root common.Hash, changes []trie.Change) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.covers(root) {
+		return nil
+	}
+
+	if !s.covers(parentRoot) {
+		return fmt.Errorf("%w: parent state root %s", ErrRootNotCovered, parentRoot)
+	}
+
+	if len(s.layers) >= s.maxLayers {
+		return fmt.Errorf("%w: %d", ErrTooManyLayers, s.maxLayers)
+	}
+
+	layer := &diffLayer{
+		parentRoot: parentRoot,
+		changes:    make(map[string][]byte, len(changes)),
+	}
+	for _, change := range changes {
+		if change.KeyToChild != nil {
+			continue
+		}
+		layer.changes[string(change.Key)] = change.NewValue
+	}
+	s.layers[root] = layer
+
+	return nil
+}
+
+// Get returns the value at the key given in the state trie with the state
+// root given, and true if the state root is covered by the snapshot. The
+// value is nil if the key is not in the state trie.
+func (s *Snapshot) Get(root common.Hash, key []byte) (value []byte, covered bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.diskRoot.IsEmpty() {
+		return nil, false, nil
+	}
+
+	for root != s.diskRoot {
+		layer, ok := s.layers[root]
+		if !ok {
+			return nil, false, nil
+		}
+
+		value, ok = layer.changes[string(key)]
+		if ok {
+			return value, true, nil
+		}
+		root = layer.parentRoot
+	}
+
+	value, err = s.entries.Get(key)
+	switch {
+	case errors.Is(err, chaindb.ErrKeyNotFound):
+		return nil, true, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("cannot get value from disk layer: %w", err)
+	case value == nil:
+		// the database returns nil for empty values
+		return []byte{}, true, nil
+	default:
+		return value, true, nil
+	}
+}
+
+// Flatten writes the changes of the diff layers up to the one with the
+// state root given to the disk layer, which then has this state root.
+// The diff layers written are removed, as are the diff layers which do not
+// descend from the state root given, since they are on pruned forks.
+func (s *Snapshot) Flatten(root common.Hash) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if root == s.diskRoot {
+		return nil
+	} else if !s.covers(root) {
+		return fmt.Errorf("%w: %s", ErrRootNotCovered, root)
+	}
+
+	var layers []*diffLayer
+	for current := root; current != s.diskRoot; current = s.layers[current].parentRoot {
+		layers = append(layers, s.layers[current])
+	}
+
+	// the state root of the disk layer is removed while its entries
+	// are written, so it is rebuilt if they are not all written.
+	err = s.db.Del(rootKey)
+	if err != nil {
+		return fmt.Errorf("cannot delete state root of disk layer: %w", err)
+	}
+
+	batch := s.entries.NewBatch()
+	// the changes of the oldest layers are written first,
+	// so they are overridden by the changes of the newest layers.
+	for i := len(layers) - 1; i >= 0; i-- {
+		for key, value := range layers[i].changes {
+			if value == nil {
+				err = batch.Del([]byte(key))
+			} else {
+				err = batch.Put([]byte(key), value)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot write change to batch: %w", err)
+			}
+		}
+	}
+
+	err = batch.Flush()
+	if err != nil {
+		return fmt.Errorf("cannot write changes to disk layer: %w", err)
+	}
+
+	err = s.db.Put(rootKey, root[:])
+	if err != nil {
+		return fmt.Errorf("cannot put state root of disk layer: %w", err)
+	}
+
+	s.diskRoot = root
+	s.removeDetachedLayers()
+	return nil
+}
+
+// Rebuild rebuilds the disk layer with the keys and values of the iterator
+// given, over the state trie with the state root given. The state roots are
+// not covered by the disk layer while it is rebuilt. The diff layers which
+// do not descend from the state root given are removed.
+func (s *Snapshot) Rebuild(root common.Hash, iterator *trie.Iterator) (err error) {
+	s.mutex.Lock()
+	s.diskRoot = common.Hash{}
+	s.mutex.Unlock()
+
+	err = s.db.Del(rootKey)
+	if err != nil {
+		return fmt.Errorf("cannot delete state root of disk layer: %w", err)
+	}
+
+	err = s.clearEntries()
+	if err != nil {
+		return fmt.Errorf("cannot clear disk layer: %w", err)
+	}
+
+	batch := s.entries.NewBatch()
+	batchSize := 0
+	for iterator.Next() {
+		err = batch.Put(iterator.Key(), iterator.Value())
+		if err != nil {
+			return fmt.Errorf("cannot write entry to batch: %w", err)
+		}
+
+		batchSize += len(iterator.Key()) + len(iterator.Value())
+		if batchSize >= maxBatchSize {
+			err = batch.Flush()
+			if err != nil {
+				return fmt.Errorf("cannot write entries to disk layer: %w", err)
+			}
+			batch.Reset()
+			batchSize = 0
+		}
+	}
+
+	err = iterator.Err()
+	if err != nil {
+		return fmt.Errorf("cannot iterate over state trie: %w", err)
+	}
+
+	err = batch.Flush()
+	if err != nil {
+		return fmt.Errorf("cannot write entries to disk layer: %w", err)
+	}
+
+	err = s.db.Put(rootKey, root[:])
+	if err != nil {
+		return fmt.Errorf("cannot put state root of disk layer: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.diskRoot = root
+	s.removeDetachedLayers()
+	return nil
+}
+
+// clearEntries deletes all the entries of the disk layer from the database.
+func (s *Snapshot) clearEntries() (err error) {
+	// the table iterator reads the first key of the database
+	// without checking there is one, so it cannot be used if it is empty.
+	databaseIterator := s.db.NewIterator()
+	empty := !databaseIterator.Next()
+	databaseIterator.Release()
+	if empty {
+		return nil
+	}
+
+	// the table iterator only returns the keys with the prefix of the
+	// entries table, without this prefix, as the table batch expects them.
+	iterator := s.entries.NewIterator()
+	defer iterator.Release()
+
+	batch := s.entries.NewBatch()
+	batchSize := 0
+	for iterator.Next() {
+		key := iterator.Key()
+		err = batch.Del(key)
+		if err != nil {
+			return fmt.Errorf("cannot delete entry in batch: %w", err)
+		}
+
+		batchSize += len(key)
+		if batchSize >= maxBatchSize {
+			err = batch.Flush()
+			if err != nil {
+				return fmt.Errorf("cannot delete entries: %w", err)
+			}
+			batch.Reset()
+			batchSize = 0
+		}
+	}
+
+	return batch.Flush()
+}
+
+// removeDetachedLayers removes the diff layers which
+// do not descend from the state root of the disk layer.
+func (s *Snapshot) removeDetachedLayers() {
+	attached := map[common.Hash]bool{s.diskRoot: true}
+	var isAttached func(root common.Hash) bool
+	isAttached = func(root common.Hash) bool {
+		result, ok := attached[root]
+		if ok {
+			return result
+		}
+
+		layer, ok := s.layers[root]
+		result = ok && isAttached(layer.parentRoot)
+		attached[root] = result
+		return result
+	}
+
+	delete(s.layers, s.diskRoot)
+	for root := range s.layers {
+		if !isAttached(root) {
+			delete(s.layers, root)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package snapshot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) chaindb.Database {
+	t.Helper()
+
+	db, err := chaindb.NewBadgerDB(&chaindb.Config{
+		InMemory: true,
+		DataDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := db.Close()
+		assert.NoError(t, err)
+	})
+	return db
+}
+
+// addBlock adds a diff layer to the snapshot given for the state trie given
+// modified by the function given, and returns the modified state trie.
+func addBlock(t *testing.T, snapshot *Snapshot, parent *trie.Trie, modify func(tr *trie.Trie)) *trie.Trie {
+	t.Helper()
+
+	tr := parent.Snapshot()
+	modify(tr)
+
+	changes, err := trie.Diff(parent, tr)
+	require.NoError(t, err)
+	err = snapshot.Update(parent.MustHash(), tr.MustHash(), changes)
+	require.NoError(t, err)
+	return tr
+}
+
+// assertSnapshotEntries asserts the values of the keys of both tries
+// given are the same in the snapshot as in the second trie given.
+func assertSnapshotEntries(t *testing.T, snapshot *Snapshot, other, tr *trie.Trie) {
+	t.Helper()
+
+	keys := make(map[string]struct{})
+	for key := range other.Entries() {
+		keys[key] = struct{}{}
+	}
+	for key := range tr.Entries() {
+		keys[key] = struct{}{}
+	}
+
+	for key := range keys {
+		value, covered, err := snapshot.Get(tr.MustHash(), []byte(key))
+		require.NoError(t, err)
+		require.True(t, covered)
+		assert.Equal(t, tr.Get([]byte(key)), value, "key %q", key)
+	}
+}
+
+func Test_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+	snapshot, err := New(db, DefaultMaxLayers)
+	require.NoError(t, err)
+	assert.Equal(t, common.Hash{}, snapshot.Root())
+
+	genesis := trie.NewEmptyTrie()
+	for i := 0; i < 100; i++ {
+		genesis.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value %02d", i)))
+	}
+	genesis.Put([]byte("empty"), []byte{})
+
+	_, covered, err := snapshot.Get(genesis.MustHash(), []byte("key00"))
+	require.NoError(t, err)
+	assert.False(t, covered)
+
+	err = snapshot.Rebuild(genesis.MustHash(), genesis.NewIterator(trie.IteratorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, genesis.MustHash(), snapshot.Root())
+	assertSnapshotEntries(t, snapshot, genesis, genesis)
+
+	block1 := addBlock(t, snapshot, genesis, func(tr *trie.Trie) {
+		tr.Put([]byte("key00"), []byte("modified in block 1"))
+		tr.Delete([]byte("key01"))
+		tr.Put([]byte("added"), []byte("added in block 1"))
+	})
+	block2 := addBlock(t, snapshot, block1, func(tr *trie.Trie) {
+		tr.Put([]byte("key00"), []byte("modified in block 2"))
+		tr.Put([]byte("key01"), []byte("added back in block 2"))
+		tr.Delete([]byte("added"))
+	})
+	fork := addBlock(t, snapshot, genesis, func(tr *trie.Trie) {
+		tr.Put([]byte("key02"), []byte("modified in fork"))
+	})
+
+	assertSnapshotEntries(t, snapshot, genesis, block1)
+	assertSnapshotEntries(t, snapshot, genesis, block2)
+	assertSnapshotEntries(t, snapshot, genesis, fork)
+
+	err = snapshot.Flatten(block1.MustHash())
+	require.NoError(t, err)
+	assert.Equal(t, block1.MustHash(), snapshot.Root())
+	assertSnapshotEntries(t, snapshot, genesis, block1)
+	assertSnapshotEntries(t, snapshot, genesis, block2)
+
+	// the state roots below the disk layer and on pruned forks are not covered
+	assert.False(t, snapshot.Covers(genesis.MustHash()))
+	assert.False(t, snapshot.Covers(fork.MustHash()))
+	assert.Len(t, snapshot.layers, 1)
+
+	err = snapshot.Flatten(fork.MustHash())
+	assert.ErrorIs(t, err, ErrRootNotCovered)
+
+	// the disk layer is kept in the database
+	snapshot, err = New(db, DefaultMaxLayers)
+	require.NoError(t, err)
+	assert.Equal(t, block1.MustHash(), snapshot.Root())
+	assertSnapshotEntries(t, snapshot, genesis, block1)
+
+	// rebuilding the disk layer removes the entries of the previous one
+	err = snapshot.Rebuild(fork.MustHash(), fork.NewIterator(trie.IteratorOptions{}))
+	require.NoError(t, err)
+	assertSnapshotEntries(t, snapshot, block1, fork)
+}
+
+func Test_Snapshot_Update(t *testing.T) {
+	t.Parallel()
+
+	snapshot, err := New(newTestDB(t), 2)
+	require.NoError(t, err)
+
+	genesis := trie.NewEmptyTrie()
+	genesis.Put([]byte("key"), []byte("value"))
+
+	err = snapshot.Update(genesis.MustHash(), common.Hash{1}, nil)
+	assert.ErrorIs(t, err, ErrRootNotCovered)
+
+	err = snapshot.Rebuild(genesis.MustHash(), genesis.NewIterator(trie.IteratorOptions{}))
+	require.NoError(t, err)
+
+	err = snapshot.Update(genesis.MustHash(), common.Hash{1}, []trie.Change{
+		{KeyToChild: []byte("child"), Key: []byte("key"), Kind: trie.Added, NewValue: []byte("child value")},
+	})
+	require.NoError(t, err)
+	err = snapshot.Update(common.Hash{1}, common.Hash{2}, nil)
+	require.NoError(t, err)
+	// the diff layer of a state root already covered is not added again
+	err = snapshot.Update(common.Hash{1}, common.Hash{2}, nil)
+	require.NoError(t, err)
+
+	err = snapshot.Update(common.Hash{2}, common.Hash{3}, nil)
+	assert.ErrorIs(t, err, ErrTooManyLayers)
+
+	// the changes of child tries are not in the snapshot
+	value, covered, err := snapshot.Get(common.Hash{2}, []byte("key"))
+	require.NoError(t, err)
+	assert.True(t, covered)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func Test_Snapshot_clearEntries(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+	snapshot, err := New(db, DefaultMaxLayers)
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "flatk", "flatkw", "z"} {
+		err = db.Put([]byte(key), []byte("value"))
+		require.NoError(t, err)
+	}
+	for _, key := range []string{"", "key1", "key2"} {
+		err = snapshot.entries.Put([]byte(key), []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = snapshot.clearEntries()
+	require.NoError(t, err)
+
+	var keys []string
+	iterator := db.NewIterator()
+	defer iterator.Release()
+	for iterator.Next() {
+		keys = append(keys, string(iterator.Key()))
+	}
+	assert.Equal(t, []string{"a", "flatk", "flatkw", "z"}, keys)
+}
@@ -10,6 +10,7 @@
 
 	"github.com/ChainSafe/chaindb"
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/state/snapshot"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
 	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
@@ -39,6 +40,15 @@ type StorageState struct {
 	changedLock  sync.RWMutex
 	observerList []Observer
 	pruner       pruner.Pruner
+
+	// snapshot is the flat key-value view of the state tries
+	// of the highest finalised block and of the blocks above it.
+	snapshot *snapshot.Snapshot
+	// snapshotRebuilding is true while the snapshot is rebuilt in
+	// the background, and snapshotRebuild waits for this rebuild.
+	snapshotMutex      sync.Mutex
+	snapshotRebuilding bool
+	snapshotRebuild    sync.WaitGroup
 }
 
 // NewStorageState creates a new StorageState backed by the given block state
@@ -62,12 +72,18 @@ func NewStorageState(db chaindb.Database, blockState *BlockState,
 		p = &pruner.ArchiveNode{}
 	}
 
+	flatSnapshot, err := snapshot.New(db, snapshot.DefaultMaxLayers)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create flat state snapshot: %w", err)
+	}
+
 	return &StorageState{
 		blockState:   blockState,
 		tries:        tries,
 		db:           storageTable,
 		observerList: []Observer{},
 		pruner:       p,
+		snapshot:     flatSnapshot,
 	}, nil
 }
 
@@ -101,7 +117,14 @@ func (s *StorageState) StoreTrie(ts *rtstorage.TrieState, header *types.Header)
 		return err
 	}
 
-	go s.notifyAll(root)
+	if header != nil {
+		err := s.updateSnapshot(root, ts.Trie(), header)
+		if err != nil {
+			logger.Debugf("cannot update flat state snapshot for block %s: %s", header.Hash(), err)
+		}
+	}
+
+	go s.notifyAll(root, header)
 	return nil
 }
 
@@ -135,14 +158,18 @@ func (s *StorageState) TrieState(root *common.Hash) (*rtstorage.TrieState, error
 		return nil, err
 	}
 
+	if s.snapshot.Covers(*root) {
+		next.SetFlatState(s.snapshot, *root)
+	}
+
 	logger.Tracef("returning trie with root %s to be modified", root)
 	return next, nil
 }
 
-// LoadFromDB loads an encoded trie from the DB where the key is `root`
+// LoadFromDB loads the trie with the given root from the database.
+// The nodes of the trie are loaded from the database only when traversed.
 func (s *StorageState) LoadFromDB(root common.Hash) (*trie.Trie, error) {
-	t := trie.NewEmptyTrie()
-	err := t.Load(s.db, root)
+	t, err := trie.NewLazyTrie(s.db, root)
 	if err != nil {
 		return nil, err
 	}
@@ -191,10 +218,16 @@ func (s *StorageState) GetStorage(root *common.Hash, key []byte) ([]byte, error)
 		root = &sr
 	}
 
+	value, covered, err := s.snapshot.Get(*root, key)
+	if err != nil {
+		return nil, err
+	} else if covered {
+		return value, nil
+	}
+
 	t := s.tries.get(*root)
 	if t != nil {
-		val := t.Get(key)
-		return val, nil
+		return t.GetWithError(key)
 	}
 
 	return trie.GetFromDB(s.db, *root, key)
@@ -265,6 +298,17 @@ func (s *StorageState) GetKeysWithPrefix(root *common.Hash, prefix []byte) ([][]
 	return tr.GetKeysWithPrefix(prefix), nil
 }
 
+// NewIterator returns an iterator over the keys and values of the trie with the given
+// state root (or best block state root if root is nil), in the range of the options given.
+func (s *StorageState) NewIterator(root *common.Hash, options trie.IteratorOptions) (*trie.Iterator, error) {
+	tr, err := s.loadTrie(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.NewIterator(options), nil
+}
+
 // GetStorageChild returns a child trie, if it exists
 func (s *StorageState) GetStorageChild(root *common.Hash, keyToChild []byte) (*trie.Trie, error) {
 	tr, err := s.loadTrie(root)
@@ -304,3 +348,10 @@ func (s *StorageState) LoadCodeHash(hash *common.Hash) (common.Hash, error) {
 func (s *StorageState) GenerateTrieProof(stateRoot common.Hash, keys [][]byte) ([][]byte, error) {
 	return trie.GenerateProof(stateRoot[:], keys, s.db)
 }
+
+// GenerateChildTrieProof returns the proofs related to the keys in the child trie at the
+// child trie key given, including the proof of the child trie root on the state root trie
+func (s *StorageState) GenerateChildTrieProof(stateRoot common.Hash, keyToChild []byte,
+	keys [][]byte) ([][]byte, error) {
+	return trie.GenerateChildProof(stateRoot[:], keyToChild, keys, s.db)
+}
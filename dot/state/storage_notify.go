@@ -4,11 +4,14 @@
 package state
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"strings"
 
+	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
 )
 
 // KeyValue struct to hold key value pairs
@@ -57,7 +60,8 @@ func (s *StorageState) RegisterStorageObserver(o Observer) {
 		return
 	}
 	go func() {
-		if err := s.notifyObserver(sr, o); err != nil {
+		allEntries := func() ([]KeyValue, error) { return s.stateEntries(sr) }
+		if err := s.notifyObserver(sr, o, allEntries); err != nil {
 			logger.Warnf("failed to notify storage subscriptions: %s", err)
 		}
 	}()
@@ -69,46 +73,78 @@ func (s *StorageState) UnregisterStorageObserver(o Observer) {
 	s.observerList = s.removeFromSlice(s.observerList, o)
 }
 
-func (s *StorageState) notifyAll(root common.Hash) {
+// notifyAll notifies the observers of the state with the given root, for
+// the block header given. Observers without filter are sent the changes
+// since the state of the parent block, or all the entries of the state if
+// the header is nil or if the state of the parent block is unknown.
+func (s *StorageState) notifyAll(root common.Hash, header *types.Header) {
 	s.changedLock.RLock()
 	defer s.changedLock.RUnlock()
-	for _, observer := range s.observerList {
-		err := s.notifyObserver(root, observer)
+
+	var parentRoot *common.Hash
+	if header != nil && len(s.observerList) > 0 {
+		parentHeader, err := s.blockState.GetHeader(header.ParentHash)
 		if err != nil {
-			logger.Warnf("failed to notify storage subscriptions: %s", err)
+			logger.Debugf("cannot get parent header of block %s: %s", header.Hash(), err)
+		} else {
+			parentRoot = &parentHeader.StateRoot
 		}
 	}
-}
 
-func (s *StorageState) notifyObserver(root common.Hash, o Observer) error {
-	t, err := s.TrieState(&root)
-	if err != nil {
-		return err
+	// the changes sent to the observers without filter are computed
+	// once, when notifying the first observer without filter.
+	var (
+		changes         []KeyValue
+		changesErr      error
+		changesComputed bool
+	)
+	unfilteredChanges := func() ([]KeyValue, error) {
+		if !changesComputed {
+			if parentRoot != nil {
+				changes, changesErr = s.stateChanges(*parentRoot, root)
+			} else {
+				changes, changesErr = s.stateEntries(root)
+			}
+			changesComputed = true
+		}
+		return changes, changesErr
 	}
 
-	if t == nil {
-		return errTrieDoesNotExist(root)
+	for _, observer := range s.observerList {
+		err := s.notifyObserver(root, observer, unfilteredChanges)
+		if err != nil {
+			logger.Warnf("failed to notify storage subscriptions: %s", err)
+		}
 	}
+}
 
+// notifyObserver notifies the observer given of the state with the given root.
+// An observer without filter is sent the changes returned by the function given,
+// and an observer with a filter is sent the values of its keys which changed.
+func (s *StorageState) notifyObserver(root common.Hash, o Observer,
+	unfilteredChanges func() ([]KeyValue, error)) (err error) {
 	subRes := &SubscriptionResult{
 		Hash: root,
 	}
-	if len(o.GetFilter()) == 0 {
-		// no filter, so send all changes
-		ent := t.TrieEntries()
-		for k, v := range ent {
-			if k != ":code" {
-				// currently we're ignoring :code since this is a lot of data
-				kv := &KeyValue{
-					Key:   common.MustHexToBytes(fmt.Sprintf("0x%x", k)),
-					Value: v,
-				}
-				subRes.Changes = append(subRes.Changes, *kv)
-			}
+
+	filter := o.GetFilter()
+	if len(filter) == 0 {
+		subRes.Changes, err = unfilteredChanges()
+		if err != nil {
+			return err
 		}
 	} else {
+		t, err := s.TrieState(&root)
+		if err != nil {
+			return err
+		}
+
+		if t == nil {
+			return errTrieDoesNotExist(root)
+		}
+
 		// filter result to include only interested keys
-		for k, cachedValue := range o.GetFilter() {
+		for k, cachedValue := range filter {
 			value := t.Get(common.MustHexToBytes(k))
 			if !reflect.DeepEqual(cachedValue, value) {
 				kv := &KeyValue{
@@ -116,7 +152,7 @@ func (s *StorageState) notifyObserver(root common.Hash, o Observer) error {
 					Value: value,
 				}
 				subRes.Changes = append(subRes.Changes, *kv)
-				o.GetFilter()[k] = value
+				filter[k] = value
 			}
 		}
 	}
@@ -131,6 +167,67 @@ func (s *StorageState) notifyObserver(root common.Hash, o Observer) error {
 	return nil
 }
 
+// stateEntries returns all the entries of the state trie with
+// the root given, excluding :code, as changes to send to observers.
+func (s *StorageState) stateEntries(root common.Hash) (entries []KeyValue, err error) {
+	t, err := s.TrieState(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	if t == nil {
+		return nil, errTrieDoesNotExist(root)
+	}
+
+	for k, v := range t.TrieEntries() {
+		if k == ":code" {
+			// currently we're ignoring :code since this is a lot of data
+			continue
+		}
+
+		entries = append(entries, KeyValue{
+			Key:   []byte(k),
+			Value: v,
+		})
+	}
+
+	return entries, nil
+}
+
+// stateChanges returns the keys of the state trie, excluding :code, changed from the
+// state with the parent root given to the state with the root given, with their new
+// value which is nil for deleted keys.
+func (s *StorageState) stateChanges(parentRoot, root common.Hash) (changes []KeyValue, err error) {
+	parentTrie, err := s.loadTrie(&parentRoot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load parent state trie: %w", err)
+	}
+
+	stateTrie, err := s.loadTrie(&root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load state trie: %w", err)
+	}
+
+	trieChanges, err := trie.Diff(parentTrie, stateTrie)
+	if err != nil {
+		return nil, fmt.Errorf("cannot diff state tries: %w", err)
+	}
+
+	for _, change := range trieChanges {
+		if change.KeyToChild != nil || bytes.Equal(change.Key, codeKey) {
+			// currently we're ignoring :code since this is a lot of data
+			continue
+		}
+
+		changes = append(changes, KeyValue{
+			Key:   change.Key,
+			Value: change.NewValue,
+		})
+	}
+
+	return changes, nil
+}
+
 func (s *StorageState) removeFromSlice(observerList []Observer, observerToRemove Observer) []Observer {
 	s.changedLock.Lock()
 	defer s.changedLock.Unlock()
@@ -12,7 +12,9 @@
 	"time"
 
 	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -52,6 +54,79 @@ func TestStorageState_RegisterStorageObserver(t *testing.T) {
 	mockobs.AssertCalled(t, "Update", expectedResult)
 }
 
+func TestStorageState_RegisterStorageObserver_changesSinceParent(t *testing.T) {
+	ss := newTestStorageState(t, newTriesEmpty())
+
+	parentTrieState, err := ss.TrieState(&trie.EmptyHash)
+	require.NoError(t, err)
+	parentTrieState.Set([]byte("unchanged"), []byte("value"))
+	parentTrieState.Set([]byte("modified"), []byte("old value"))
+	parentTrieState.Set([]byte("deleted"), []byte("value"))
+	err = ss.StoreTrie(parentTrieState, nil)
+	require.NoError(t, err)
+
+	parentHeader := &types.Header{
+		ParentHash: testGenesisHeader.Hash(),
+		Number:     1,
+		StateRoot:  parentTrieState.MustRoot(),
+		Digest:     createPrimaryBABEDigest(t),
+	}
+	err = ss.blockState.AddBlock(&types.Block{Header: *parentHeader, Body: types.Body{}})
+	require.NoError(t, err)
+
+	// the changes are computed once and shared by the observers
+	results := make(chan *SubscriptionResult, 2)
+	var mocks []*MockObserver
+	for i := 0; i < 2; i++ {
+		mockobs := &MockObserver{}
+		mockobs.On("Update", mock.AnythingOfType("*state.SubscriptionResult")).
+			Run(func(args mock.Arguments) {
+				result := args.Get(0).(*SubscriptionResult)
+				if result.Hash != parentHeader.StateRoot {
+					results <- result
+				}
+			})
+		mockobs.On("GetID").Return(uint(10 + i))
+		mockobs.On("GetFilter").Return(map[string][]byte{})
+		mocks = append(mocks, mockobs)
+
+		ss.RegisterStorageObserver(mockobs)
+		defer ss.UnregisterStorageObserver(mockobs)
+	}
+
+	ts, err := ss.TrieState(&parentHeader.StateRoot)
+	require.NoError(t, err)
+	ts.Set([]byte("modified"), []byte("new value"))
+	ts.Set([]byte("added"), []byte("value"))
+	ts.Delete([]byte("deleted"))
+
+	header := &types.Header{
+		ParentHash: parentHeader.Hash(),
+		Number:     2,
+		StateRoot:  ts.MustRoot(),
+		Digest:     createPrimaryBABEDigest(t),
+	}
+	err = ss.StoreTrie(ts, header)
+	require.NoError(t, err)
+
+	expectedResult := &SubscriptionResult{
+		Hash: ts.MustRoot(),
+		Changes: []KeyValue{
+			{Key: []byte("added"), Value: []byte("value")},
+			{Key: []byte("deleted")},
+			{Key: []byte("modified"), Value: []byte("new value")},
+		},
+	}
+
+	time.Sleep(time.Millisecond * 250)
+	for _, mockobs := range mocks {
+		mockobs.AssertCalled(t, "Update", expectedResult)
+	}
+
+	first, second := <-results, <-results
+	require.Same(t, &first.Changes[0], &second.Changes[0])
+}
+
 func TestStorageState_RegisterStorageObserver_Multi(t *testing.T) {
 	ss := newTestStorageState(t, newTriesEmpty())
 	ts, err := ss.TrieState(nil)
@@ -0,0 +1,169 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/dot/state/snapshot"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+// updateSnapshot adds the changes of the state trie given, with the given root, from
+// the state of the parent block of the block header given to the flat state snapshot.
+// It then flattens the snapshot up to the state of the highest finalised block, or
+// rebuilds it in the background at this state if the snapshot does not cover it.
+func (s *StorageState) updateSnapshot(root common.Hash, t *trie.Trie, header *types.Header) error {
+	if s.isSnapshotRebuilding() {
+		return nil
+	}
+
+	finalisedHeader, err := s.blockState.GetHighestFinalisedHeader()
+	if err != nil {
+		return fmt.Errorf("cannot get highest finalised header: %w", err)
+	}
+
+	parentHeader, err := s.blockState.GetHeader(header.ParentHash)
+	if err != nil {
+		return fmt.Errorf("cannot get parent header: %w", err)
+	}
+
+	covered, err := s.coverState(parentHeader, finalisedHeader.Number)
+	if err != nil {
+		return fmt.Errorf("cannot cover parent state: %w", err)
+	}
+
+	if covered {
+		err = s.addSnapshotLayer(parentHeader.StateRoot, root, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.snapshot.Flatten(finalisedHeader.StateRoot)
+	if errors.Is(err, snapshot.ErrRootNotCovered) {
+		logger.Warnf("flat state snapshot does not cover finalised state root %s, "+
+			"rebuilding it in the background", finalisedHeader.StateRoot)
+		s.rebuildSnapshotInBackground(finalisedHeader.StateRoot)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("cannot flatten snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// coverState adds to the flat state snapshot the diff layers of the state of the
+// block with the header given and of its ancestors, down to the first ancestor with
+// its state covered by the snapshot. It returns false if no ancestor above the block
+// with the finalised number given is covered, for example after a restart with
+// unfinalised blocks or after a rebuild of the snapshot.
+func (s *StorageState) coverState(header *types.Header, finalisedNumber uint) (covered bool, err error) {
+	var uncovered []*types.Header
+	for !s.snapshot.Covers(header.StateRoot) {
+		if header.Number <= finalisedNumber || len(uncovered) >= snapshot.DefaultMaxLayers {
+			return false, nil
+		}
+
+		uncovered = append(uncovered, header)
+		header, err = s.blockState.GetHeader(header.ParentHash)
+		if err != nil {
+			return false, fmt.Errorf("cannot get parent header: %w", err)
+		}
+	}
+
+	// the oldest states are added first, so the parent state of each one is covered.
+	for i := len(uncovered) - 1; i >= 0; i-- {
+		root := uncovered[i].StateRoot
+		t, err := s.loadTrie(&root)
+		if err != nil {
+			return false, fmt.Errorf("cannot load state trie: %w", err)
+		}
+
+		err = s.addSnapshotLayer(header.StateRoot, root, t)
+		if err != nil {
+			return false, err
+		}
+		header = uncovered[i]
+	}
+
+	return true, nil
+}
+
+// addSnapshotLayer adds the changes of the state trie given, with the given root,
+// from the parent state root given to the flat state snapshot.
+func (s *StorageState) addSnapshotLayer(parentRoot, root common.Hash, t *trie.Trie) error {
+	parentTrie, err := s.loadTrie(&parentRoot)
+	if err != nil {
+		return fmt.Errorf("cannot load parent state trie: %w", err)
+	}
+
+	changes, err := trie.Diff(parentTrie, t)
+	if err != nil {
+		return fmt.Errorf("cannot diff state tries: %w", err)
+	}
+
+	err = s.snapshot.Update(parentRoot, root, changes)
+	if err != nil {
+		return fmt.Errorf("cannot update snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StorageState) isSnapshotRebuilding() bool {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+	return s.snapshotRebuilding
+}
+
+// rebuildSnapshotInBackground rebuilds the flat state snapshot from the state trie
+// with the given root in a goroutine, unless the snapshot is already being rebuilt.
+func (s *StorageState) rebuildSnapshotInBackground(root common.Hash) {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+	if s.snapshotRebuilding {
+		return
+	}
+	s.snapshotRebuilding = true
+
+	s.snapshotRebuild.Add(1)
+	go func() {
+		defer s.snapshotRebuild.Done()
+
+		err := s.rebuildSnapshot(root)
+		if err != nil {
+			logger.Errorf("cannot rebuild flat state snapshot: %s", err)
+		}
+
+		s.snapshotMutex.Lock()
+		defer s.snapshotMutex.Unlock()
+		s.snapshotRebuilding = false
+	}()
+}
+
+// rebuildSnapshot rebuilds the flat state snapshot from the state trie with
+// the given root, if the snapshot disk layer is not already at this root.
+func (s *StorageState) rebuildSnapshot(root common.Hash) error {
+	if s.snapshot.Root() == root {
+		return nil
+	}
+
+	t, err := s.loadTrie(&root)
+	if err != nil {
+		return fmt.Errorf("cannot load state trie: %w", err)
+	}
+
+	logger.Infof("rebuilding flat state snapshot at state root %s...", root)
+	err = s.snapshot.Rebuild(root, t.NewIterator(trie.IteratorOptions{}))
+	if err != nil {
+		return fmt.Errorf("cannot rebuild snapshot: %w", err)
+	}
+	logger.Infof("rebuilt flat state snapshot at state root %s", root)
+
+	return nil
+}
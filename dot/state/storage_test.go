@@ -8,6 +8,7 @@
 	"time"
 
 	"github.com/ChainSafe/gossamer/dot/state/pruner"
+	"github.com/ChainSafe/gossamer/dot/state/snapshot"
 	"github.com/ChainSafe/gossamer/dot/telemetry"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/trie/node"
@@ -222,3 +223,133 @@ func TestGetStorageChildAndGetStorageFromChild(t *testing.T) {
 
 	require.Equal(t, []byte("voila"), value)
 }
+
+func TestStorage_snapshot(t *testing.T) {
+	storage := newTestStorageState(t, newTriesEmpty())
+	err := storage.rebuildSnapshot(trie.EmptyHash)
+	require.NoError(t, err)
+
+	ts, err := storage.TrieState(&trie.EmptyHash)
+	require.NoError(t, err)
+	ts.Set([]byte("key1"), []byte("value1"))
+	ts.Set([]byte("key2"), []byte("value2"))
+
+	header1 := &types.Header{
+		ParentHash: testGenesisHeader.Hash(),
+		Number:     1,
+		StateRoot:  ts.MustRoot(),
+		Digest:     createPrimaryBABEDigest(t),
+	}
+	err = storage.StoreTrie(ts, header1)
+	require.NoError(t, err)
+	err = storage.blockState.AddBlock(&types.Block{Header: *header1, Body: types.Body{}})
+	require.NoError(t, err)
+
+	// the state of block 1 is in a diff layer
+	value, covered, err := storage.snapshot.Get(header1.StateRoot, []byte("key1"))
+	require.NoError(t, err)
+	require.True(t, covered)
+	require.Equal(t, []byte("value1"), value)
+
+	err = storage.blockState.SetFinalisedHash(header1.Hash(), 1, 0)
+	require.NoError(t, err)
+
+	ts, err = storage.TrieState(&header1.StateRoot)
+	require.NoError(t, err)
+	ts.Set([]byte("key1"), []byte("modified"))
+	ts.Delete([]byte("key2"))
+
+	header2 := &types.Header{
+		ParentHash: header1.Hash(),
+		Number:     2,
+		StateRoot:  ts.MustRoot(),
+		Digest:     createPrimaryBABEDigest(t),
+	}
+	err = storage.StoreTrie(ts, header2)
+	require.NoError(t, err)
+
+	// the state of the finalised block 1 is flattened to the disk layer
+	require.Equal(t, header1.StateRoot, storage.snapshot.Root())
+
+	for _, root := range []common.Hash{header1.StateRoot, header2.StateRoot} {
+		require.True(t, storage.snapshot.Covers(root))
+
+		tr, err := storage.LoadFromDB(root)
+		require.NoError(t, err)
+		for _, key := range [][]byte{[]byte("key1"), []byte("key2"), []byte("key3")} {
+			value, err := storage.GetStorage(&root, key)
+			require.NoError(t, err)
+			require.Equal(t, tr.Get(key), value)
+		}
+	}
+}
+
+func TestStorage_snapshot_rebuild(t *testing.T) {
+	db := NewInMemoryDB(t)
+	tries := newTriesEmpty()
+	storage, err := NewStorageState(db, newTestBlockState(t, testGenesisHeader, tries), tries, pruner.Config{})
+	require.NoError(t, err)
+	err = storage.rebuildSnapshot(trie.EmptyHash)
+	require.NoError(t, err)
+
+	parentHeader := testGenesisHeader
+	addBlock := func(key string) *types.Header {
+		ts, err := storage.TrieState(&parentHeader.StateRoot)
+		require.NoError(t, err)
+		ts.Set([]byte(key), []byte("value"))
+
+		header := &types.Header{
+			ParentHash: parentHeader.Hash(),
+			Number:     parentHeader.Number + 1,
+			StateRoot:  ts.MustRoot(),
+			Digest:     createPrimaryBABEDigest(t),
+		}
+		err = storage.StoreTrie(ts, header)
+		require.NoError(t, err)
+		err = storage.blockState.AddBlock(&types.Block{Header: *header, Body: types.Body{}})
+		require.NoError(t, err)
+
+		parentHeader = header
+		return header
+	}
+
+	restart := func() {
+		flatSnapshot, err := snapshot.New(db, snapshot.DefaultMaxLayers)
+		require.NoError(t, err)
+		storage.snapshot = flatSnapshot
+	}
+
+	header1 := addBlock("key1")
+	require.True(t, storage.snapshot.Covers(header1.StateRoot))
+
+	// the diff layers are lost on restart, and the states of the unfinalised
+	// blocks are covered again when the next block is added.
+	restart()
+	require.False(t, storage.snapshot.Covers(header1.StateRoot))
+	header2 := addBlock("key2")
+	require.True(t, storage.snapshot.Covers(header1.StateRoot))
+	require.True(t, storage.snapshot.Covers(header2.StateRoot))
+
+	// the finalised state is not covered, so the snapshot is rebuilt at this state
+	restart()
+	err = storage.blockState.SetFinalisedHash(header2.Hash(), 1, 0)
+	require.NoError(t, err)
+	header3 := addBlock("key3")
+	storage.snapshotRebuild.Wait()
+	require.Equal(t, header2.StateRoot, storage.snapshot.Root())
+	require.False(t, storage.snapshot.Covers(header3.StateRoot))
+
+	// the states of the blocks above the rebuilt state are covered on the next block
+	header4 := addBlock("key4")
+	for _, root := range []common.Hash{header3.StateRoot, header4.StateRoot} {
+		require.True(t, storage.snapshot.Covers(root))
+
+		tr, err := storage.LoadFromDB(root)
+		require.NoError(t, err)
+		for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+			value, err := storage.GetStorage(&root, []byte(key))
+			require.NoError(t, err)
+			require.Equal(t, tr.Get([]byte(key)), value)
+		}
+	}
+}
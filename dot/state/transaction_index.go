@@ -0,0 +1,262 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// DefaultTransactionIndexRetention is the default number of blocks indexed transaction data is kept for.
+// It matches the default storage period of pallet-transaction-storage.
+const DefaultTransactionIndexRetention uint = 100800
+
+var (
+	transactionIndexPrefix = "txindex"
+	indexedDataPrefix      = []byte("data")
+	indexedRefsPrefix      = []byte("refs")
+	indexedAtBlockPrefix   = []byte("block")
+)
+
+// TransactionIndexState stores the extrinsic data indexed by the runtime, keyed by its content hash.
+// Every block indexing or renewing some data holds a reference to it, which is released once
+// the block is older than the retention period. The data is deleted when it is no longer referenced.
+type TransactionIndexState struct {
+	db        chaindb.Database
+	retention uint
+	lock      sync.Mutex
+}
+
+// NewTransactionIndexState returns a new TransactionIndexState which keeps indexed data
+// for the given number of blocks. If retention is 0, DefaultTransactionIndexRetention is used.
+func NewTransactionIndexState(db chaindb.Database, retention uint) *TransactionIndexState {
+	if retention == 0 {
+		retention = DefaultTransactionIndexRetention
+	}
+
+	return &TransactionIndexState{
+		db:        chaindb.NewTable(db, transactionIndexPrefix),
+		retention: retention,
+	}
+}
+
+func indexedDataKey(hash common.Hash) []byte {
+	return append(indexedDataPrefix, hash.ToBytes()...)
+}
+
+func indexedRefsKey(hash common.Hash) []byte {
+	return append(indexedRefsPrefix, hash.ToBytes()...)
+}
+
+func indexedAtBlockKey(number uint) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(number))
+	return append(indexedAtBlockPrefix, buf...)
+}
+
+// GetIndexedTransaction returns the indexed data with the given content hash
+func (s *TransactionIndexState) GetIndexedTransaction(hash common.Hash) ([]byte, error) {
+	return s.db.Get(indexedDataKey(hash))
+}
+
+// HasIndexedTransaction returns whether indexed data with the given content hash is stored
+func (s *TransactionIndexState) HasIndexedTransaction(hash common.Hash) (bool, error) {
+	return s.db.Has(indexedDataKey(hash))
+}
+
+// StoreIndexedTransactions applies the transaction index operations recorded while executing
+// the given block, then prunes the data whose retention period ended with this block.
+func (s *TransactionIndexState) StoreIndexedTransactions(block *types.Block,
+	ops []rtstorage.IndexOperation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(ops) > 0 {
+		err := s.applyIndexOperations(block, ops)
+		if err != nil {
+			return fmt.Errorf("cannot apply transaction index operations: %w", err)
+		}
+	}
+
+	if block.Header.Number <= s.retention {
+		return nil
+	}
+
+	err := s.prune(block.Header.Number - s.retention)
+	if err != nil {
+		return fmt.Errorf("cannot prune indexed transactions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TransactionIndexState) applyIndexOperations(block *types.Block, ops []rtstorage.IndexOperation) error {
+	refs := make(map[common.Hash]uint32)
+	getRefs := func(hash common.Hash) (uint32, error) {
+		if count, has := refs[hash]; has {
+			return count, nil
+		}
+		return s.getRefs(hash)
+	}
+
+	batch := s.db.NewBatch()
+	var referenced []common.Hash
+	for _, op := range ops {
+		if int(op.Extrinsic) >= len(block.Body) {
+			logger.Warnf("ignoring transaction index operation for extrinsic %d of block %s with %d extrinsics",
+				op.Extrinsic, block.Header.Hash(), len(block.Body))
+			continue
+		}
+
+		count, err := getRefs(op.Hash)
+		if err != nil {
+			return err
+		}
+
+		if op.Renew {
+			if count == 0 {
+				logger.Warnf("ignoring renewal of unknown indexed transaction %s", op.Hash)
+				continue
+			}
+		} else {
+			ext := block.Body[op.Extrinsic]
+			if int(op.Size) > len(ext) {
+				logger.Warnf("ignoring indexing of %d bytes of extrinsic %d of block %s with size %d",
+					op.Size, op.Extrinsic, block.Header.Hash(), len(ext))
+				continue
+			}
+
+			if count == 0 {
+				err = batch.Put(indexedDataKey(op.Hash), ext[len(ext)-int(op.Size):])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		refs[op.Hash] = count + 1
+		referenced = append(referenced, op.Hash)
+	}
+
+	for hash, count := range refs {
+		err := batch.Put(indexedRefsKey(hash), encodeRefs(count))
+		if err != nil {
+			return err
+		}
+	}
+
+	// blocks on different forks at the same height share the list of references
+	atBlock, err := s.getReferencedAtBlock(block.Header.Number)
+	if err != nil {
+		return err
+	}
+
+	enc, err := scale.Marshal(append(atBlock, referenced...))
+	if err != nil {
+		return err
+	}
+
+	err = batch.Put(indexedAtBlockKey(block.Header.Number), enc)
+	if err != nil {
+		return err
+	}
+
+	return batch.Flush()
+}
+
+// prune releases the references held by the blocks with the given number
+func (s *TransactionIndexState) prune(number uint) error {
+	referenced, err := s.getReferencedAtBlock(number)
+	if err != nil {
+		return err
+	}
+
+	if len(referenced) == 0 {
+		return nil
+	}
+
+	refs := make(map[common.Hash]uint32)
+	for _, hash := range referenced {
+		count, has := refs[hash]
+		if !has {
+			count, err = s.getRefs(hash)
+			if err != nil {
+				return err
+			}
+		}
+
+		if count > 0 {
+			count--
+		}
+		refs[hash] = count
+	}
+
+	batch := s.db.NewBatch()
+	for hash, count := range refs {
+		if count > 0 {
+			err = batch.Put(indexedRefsKey(hash), encodeRefs(count))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = batch.Del(indexedRefsKey(hash)); err != nil {
+			return err
+		}
+
+		if err = batch.Del(indexedDataKey(hash)); err != nil {
+			return err
+		}
+	}
+
+	err = batch.Del(indexedAtBlockKey(number))
+	if err != nil {
+		return err
+	}
+
+	return batch.Flush()
+}
+
+func (s *TransactionIndexState) getRefs(hash common.Hash) (uint32, error) {
+	enc, err := s.db.Get(indexedRefsKey(hash))
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(enc), nil
+}
+
+func (s *TransactionIndexState) getReferencedAtBlock(number uint) ([]common.Hash, error) {
+	enc, err := s.db.Get(indexedAtBlockKey(number))
+	if errors.Is(err, chaindb.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var referenced []common.Hash
+	err = scale.Unmarshal(enc, &referenced)
+	if err != nil {
+		return nil, err
+	}
+
+	return referenced, nil
+}
+
+func encodeRefs(count uint32) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, count)
+	return buf
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package state
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndexedBlock(number uint, body ...types.Extrinsic) *types.Block {
+	return &types.Block{
+		Header: types.Header{
+			Number: number,
+			Digest: types.NewDigest(),
+		},
+		Body: body,
+	}
+}
+
+func TestTransactionIndexState_StoreIndexedTransactions(t *testing.T) {
+	s := NewTransactionIndexState(NewInMemoryDB(t), 10)
+
+	data := []byte("indexed data")
+	hash, err := common.Blake2bHash(data)
+	require.NoError(t, err)
+
+	block := newTestIndexedBlock(1, types.Extrinsic{1, 2, 3}, append([]byte{4, 5}, data...))
+	ops := []rtstorage.IndexOperation{
+		{Extrinsic: 1, Hash: hash, Size: uint32(len(data))},
+		// out of range operations are ignored
+		{Extrinsic: 2, Hash: common.Hash{1}, Size: 1},
+		{Extrinsic: 0, Hash: common.Hash{2}, Size: 4},
+		{Extrinsic: 0, Hash: common.Hash{3}, Renew: true},
+	}
+
+	err = s.StoreIndexedTransactions(block, ops)
+	require.NoError(t, err)
+
+	res, err := s.GetIndexedTransaction(hash)
+	require.NoError(t, err)
+	require.Equal(t, data, res)
+
+	for _, missing := range []common.Hash{{1}, {2}, {3}} {
+		has, err := s.HasIndexedTransaction(missing)
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+
+	count, err := s.getRefs(hash)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), count)
+}
+
+func TestTransactionIndexState_Prune(t *testing.T) {
+	const retention = 3
+	s := NewTransactionIndexState(NewInMemoryDB(t), retention)
+
+	dataA := []byte("a")
+	dataB := []byte("b")
+	hashA := common.Hash{0xa}
+	hashB := common.Hash{0xb}
+
+	err := s.StoreIndexedTransactions(newTestIndexedBlock(1, dataA, dataB), []rtstorage.IndexOperation{
+		{Extrinsic: 0, Hash: hashA, Size: 1},
+		{Extrinsic: 1, Hash: hashB, Size: 1},
+	})
+	require.NoError(t, err)
+
+	// renew hashA at block 2, so it is kept until block 2 is pruned
+	err = s.StoreIndexedTransactions(newTestIndexedBlock(2, types.Extrinsic{}), []rtstorage.IndexOperation{
+		{Extrinsic: 0, Hash: hashA, Renew: true},
+	})
+	require.NoError(t, err)
+
+	count, err := s.getRefs(hashA)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), count)
+
+	err = s.StoreIndexedTransactions(newTestIndexedBlock(3), nil)
+	require.NoError(t, err)
+
+	// block 1 is pruned
+	err = s.StoreIndexedTransactions(newTestIndexedBlock(4), nil)
+	require.NoError(t, err)
+
+	_, err = s.GetIndexedTransaction(hashB)
+	require.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+
+	res, err := s.GetIndexedTransaction(hashA)
+	require.NoError(t, err)
+	require.Equal(t, dataA, res)
+
+	// block 2 is pruned
+	err = s.StoreIndexedTransactions(newTestIndexedBlock(5), nil)
+	require.NoError(t, err)
+
+	_, err = s.GetIndexedTransaction(hashA)
+	require.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+
+	count, err = s.getRefs(hashA)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -8,6 +8,7 @@
 
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 type blockQueue struct {
@@ -15,6 +16,8 @@ type blockQueue struct {
 	cap    int
 	ch     chan *types.BlockData
 	blocks map[common.Hash]*types.BlockData
+	// peers are the peers the blocks in the queue were received from, if known.
+	peers map[common.Hash]peer.ID
 }
 
 // newBlockQueue initialises a queue of *types.BlockData with the given capacity.
@@ -23,13 +26,23 @@ func newBlockQueue(cap int) *blockQueue {
 		cap:    cap,
 		ch:     make(chan *types.BlockData, cap),
 		blocks: make(map[common.Hash]*types.BlockData),
+		peers:  make(map[common.Hash]peer.ID),
 	}
 }
 
 // push pushes an item into the queue. it blocks if the queue is at capacity.
 func (q *blockQueue) push(bd *types.BlockData) {
+	q.pushFrom(bd, "")
+}
+
+// pushFrom pushes an item received from the given peer into the queue, where the
+// peer is empty if it is unknown. it blocks if the queue is at capacity.
+func (q *blockQueue) pushFrom(bd *types.BlockData, from peer.ID) {
 	q.Lock()
 	q.blocks[bd.Hash] = bd
+	if from != "" {
+		q.peers[bd.Hash] = from
+	}
 	q.Unlock()
 
 	q.ch <- bd
@@ -37,11 +50,20 @@ func (q *blockQueue) push(bd *types.BlockData) {
 
 // pop pops an item from the queue. it blocks if the queue is empty.
 func (q *blockQueue) pop() *types.BlockData {
-	bd := <-q.ch
+	bd, _ := q.popWithPeer()
+	return bd
+}
+
+// popWithPeer pops an item from the queue along with the peer it was received from,
+// which is empty if it is unknown. it blocks if the queue is empty.
+func (q *blockQueue) popWithPeer() (bd *types.BlockData, from peer.ID) {
+	bd = <-q.ch
 	q.Lock()
 	delete(q.blocks, bd.Hash)
+	from = q.peers[bd.Hash]
+	delete(q.peers, bd.Hash)
 	q.Unlock()
-	return bd
+	return bd, from
 }
 
 func (q *blockQueue) has(hash common.Hash) bool {
@@ -0,0 +1,35 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_blockQueue_peers(t *testing.T) {
+	t.Parallel()
+
+	queue := newBlockQueue(2)
+	first := &types.BlockData{Hash: common.Hash{1}}
+	second := &types.BlockData{Hash: common.Hash{2}}
+
+	queue.pushFrom(first, peer.ID("alice"))
+	queue.push(second)
+	assert.True(t, queue.has(first.Hash))
+
+	bd, from := queue.popWithPeer()
+	assert.Equal(t, first, bd)
+	assert.Equal(t, peer.ID("alice"), from)
+	assert.False(t, queue.has(first.Hash))
+
+	bd, from = queue.popWithPeer()
+	assert.Equal(t, second, bd)
+	assert.Empty(t, from)
+	assert.Empty(t, queue.peers)
+}
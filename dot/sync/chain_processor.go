@@ -8,10 +8,14 @@
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/ChainSafe/gossamer/dot/peerset"
 	"github.com/ChainSafe/gossamer/dot/telemetry"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/blocktree"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
 )
 
 // ChainProcessor processes ready blocks.
@@ -39,13 +43,15 @@ type chainProcessor struct {
 	babeVerifier       BabeVerifier
 	finalityGadget     FinalityGadget
 	blockImportHandler BlockImportHandler
+	network            Network
 	telemetry          telemetry.Client
 }
 
 func newChainProcessor(readyBlocks *blockQueue, pendingBlocks DisjointBlockSet,
 	blockState BlockState, storageState StorageState,
 	transactionState TransactionState, babeVerifier BabeVerifier,
-	finalityGadget FinalityGadget, blockImportHandler BlockImportHandler, telemetry telemetry.Client) *chainProcessor {
+	finalityGadget FinalityGadget, blockImportHandler BlockImportHandler, network Network,
+	telemetry telemetry.Client) *chainProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &chainProcessor{
@@ -59,6 +65,7 @@ func newChainProcessor(readyBlocks *blockQueue, pendingBlocks DisjointBlockSet,
 		babeVerifier:       babeVerifier,
 		finalityGadget:     finalityGadget,
 		blockImportHandler: blockImportHandler,
+		network:            network,
 		telemetry:          telemetry,
 	}
 }
@@ -79,12 +86,19 @@ func (s *chainProcessor) processReadyBlocks() {
 		default:
 		}
 
-		bd := s.readyBlocks.pop()
+		bd, from := s.readyBlocks.popWithPeer()
 		if bd == nil {
 			continue
 		}
 
 		if err := s.processBlockData(bd); err != nil {
+			if errors.Is(err, errFatalInherentError) && from != "" {
+				s.network.ReportPeer(peerset.ReputationChange{
+					Value:  peerset.BadBlockValue,
+					Reason: peerset.BadBlockReason,
+				}, from)
+			}
+
 			// depending on the error, we might want to save this block for later
 			if !errors.Is(err, errFailedToGetParent) {
 				logger.Errorf("block data processing for block with hash %s failed: %s", bd.Hash, err)
@@ -244,8 +258,18 @@ func (s *chainProcessor) handleBlock(block *types.Block) error {
 		return err
 	}
 
+	err = runtime.SetStorageStateVersion(rt, ts)
+	if err != nil {
+		return fmt.Errorf("cannot set state version of block %d: %w", block.Header.Number, err)
+	}
+
 	rt.SetContextStorage(ts)
 
+	err = checkInherents(rt, ts, block, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to check inherents of block %d: %w", block.Header.Number, err)
+	}
+
 	_, err = rt.ExecuteBlock(block)
 	if err != nil {
 		return fmt.Errorf("failed to execute block %d: %w", block.Header.Number, err)
@@ -266,6 +290,51 @@ func (s *chainProcessor) handleBlock(block *types.Block) error {
 	return nil
 }
 
+// checkInherents checks the inherents of the given block with BlockBuilder_check_inherents,
+// against the inherent data made of the given timestamp and the BABE slot of the block.
+// Changes made to the storage of the parent block by the runtime call are discarded.
+// It returns errFatalInherentError if an inherent is invalid.
+func checkInherents(rt runtime.Instance, ts *rtstorage.TrieState, block *types.Block, now time.Time) error {
+	slot, err := types.GetSlotFromHeader(&block.Header)
+	if err != nil {
+		return fmt.Errorf("cannot get slot from header: %w", err)
+	}
+
+	inherentData := types.NewInherentsData()
+	err = inherentData.SetInt64Inherent(types.Timstap0, uint64(now.UnixMilli()))
+	if err != nil {
+		return err
+	}
+
+	err = inherentData.SetInt64Inherent(types.Babeslot, slot)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := inherentData.Encode()
+	if err != nil {
+		return err
+	}
+
+	ts.BeginStorageTransaction()
+	result, err := rt.CheckInherents(block, encoded)
+	ts.RollbackStorageTransaction()
+	if err != nil {
+		return err
+	}
+
+	if result.FatalError {
+		return fmt.Errorf("%w: %s", errFatalInherentError, result)
+	}
+
+	if !result.Okay {
+		logger.Warnf("block number %d with hash %s has invalid inherents: %s",
+			block.Header.Number, block.Header.Hash(), result)
+	}
+
+	return nil
+}
+
 func (s *chainProcessor) handleJustification(header *types.Header, justification []byte) {
 	if len(justification) == 0 || header == nil {
 		return
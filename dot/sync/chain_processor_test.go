@@ -0,0 +1,114 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sync
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	mocksruntime "github.com/ChainSafe/gossamer/lib/runtime/mocks"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkInherents(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+	now := time.UnixMilli(6001)
+
+	preDigest, err := types.NewBabeSecondaryPlainPreDigest(0, 2).ToPreRuntimeDigest()
+	require.NoError(t, err)
+	block := types.NewBlock(*types.NewEmptyHeader(), types.Body{{1}})
+	block.Header.Number = 1
+	require.NoError(t, block.Header.Digest.Add(*preDigest))
+
+	// inherent data entries are encoded in map order, so each entry is matched separately
+	var inherentEntries [][]byte
+	for key, value := range map[string]uint64{"timstap0": 6001, "babeslot": 2} {
+		inherentData := types.NewInherentsData()
+		require.NoError(t, inherentData.SetInt64Inherent([]byte(key), value))
+		encoded, err := inherentData.Encode()
+		require.NoError(t, err)
+		inherentEntries = append(inherentEntries, encoded[1:])
+	}
+	matchInherentData := mock.MatchedBy(func(data []byte) bool {
+		return len(data) == 1+len(inherentEntries[0])+len(inherentEntries[1]) && data[0] == 2<<2 &&
+			bytes.Contains(data, inherentEntries[0]) && bytes.Contains(data, inherentEntries[1])
+	})
+
+	testCases := map[string]struct {
+		block      types.Block
+		result     *runtime.CheckInherentsResult
+		runtimeErr error
+		errWrapped error
+		errMsg     string
+	}{
+		"no pre-digest": {
+			block:  types.NewBlock(*types.NewEmptyHeader(), types.Body{}),
+			errMsg: "cannot get slot from header: chain head missing digest",
+		},
+		"runtime error": {
+			block:      block,
+			runtimeErr: errTest,
+			errWrapped: errTest,
+			errMsg:     "test error",
+		},
+		"fatal error": {
+			block: block,
+			result: &runtime.CheckInherentsResult{
+				FatalError: true,
+				Errors:     map[[8]byte][]byte{{'t', 'i', 'm', 's', 't', 'a', 'p', '0'}: {1}},
+			},
+			errWrapped: errFatalInherentError,
+			errMsg:     "fatal inherent error: okay=false fatal=true errors=[timstap0: 0x01]",
+		},
+		"non fatal error": {
+			block:  block,
+			result: &runtime.CheckInherentsResult{Errors: map[[8]byte][]byte{}},
+		},
+		"okay": {
+			block:  block,
+			result: &runtime.CheckInherentsResult{Okay: true},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ts, err := rtstorage.NewTrieState(trie.NewEmptyTrie())
+			require.NoError(t, err)
+
+			rt := new(mocksruntime.Instance)
+			if testCase.result != nil || testCase.runtimeErr != nil {
+				rt.On("CheckInherents", &testCase.block, matchInherentData).
+					Run(func(mock.Arguments) { ts.Set([]byte("key"), []byte("value")) }).
+					Return(testCase.result, testCase.runtimeErr).Once()
+			}
+
+			err = checkInherents(rt, ts, &testCase.block, now)
+			if testCase.errMsg != "" {
+				if testCase.errWrapped != nil {
+					assert.ErrorIs(t, err, testCase.errWrapped)
+				}
+				assert.EqualError(t, err, testCase.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			// storage changes made by the runtime call are discarded
+			assert.Nil(t, ts.Get([]byte("key")))
+			rt.AssertExpectations(t)
+		})
+	}
+}
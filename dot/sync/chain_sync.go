@@ -706,13 +706,15 @@ func (cs *chainSync) doSync(req *network.BlockRequestMessage, peersTried map[pee
 	// response was validated! place into ready block queue
 	for _, bd := range resp.BlockData {
 		// block is ready to be processed!
-		cs.handleReadyBlock(bd)
+		cs.handleReadyBlock(bd, who)
 	}
 
 	return nil
 }
 
-func (cs *chainSync) handleReadyBlock(bd *types.BlockData) {
+// handleReadyBlock places the given block data, received from the given peer if it is not empty,
+// and its descendants in the pending block set into the ready queue.
+func (cs *chainSync) handleReadyBlock(bd *types.BlockData, from peer.ID) {
 	if cs.readyBlocks.has(bd.Hash) {
 		logger.Tracef("ignoring block %s in response, already in ready queue", bd.Hash)
 		return
@@ -756,7 +758,10 @@ func (cs *chainSync) handleReadyBlock(bd *types.BlockData) {
 	ready := []*types.BlockData{bd}
 	ready = cs.pendingBlocks.getReadyDescendants(bd.Hash, ready)
 
-	for _, rb := range ready {
+	cs.pendingBlocks.removeBlock(bd.Hash)
+	cs.readyBlocks.pushFrom(bd, from)
+
+	for _, rb := range ready[1:] {
 		cs.pendingBlocks.removeBlock(rb.Hash)
 		cs.readyBlocks.push(rb)
 	}
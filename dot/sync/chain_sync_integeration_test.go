@@ -753,7 +753,7 @@ func TestHandleReadyBlock(t *testing.T) {
 	}
 	cs.pendingBlocks.addBlock(block2NotDescendant)
 
-	cs.handleReadyBlock(block1.ToBlockData())
+	cs.handleReadyBlock(block1.ToBlockData(), "")
 
 	require.False(t, cs.pendingBlocks.hasBlock(header1.Hash()))
 	require.False(t, cs.pendingBlocks.hasBlock(header2.Hash()))
@@ -47,6 +47,9 @@
 	errFailedToGetParent            = errors.New("failed to get parent header")
 	errStartAndEndMismatch          = errors.New("request start and end hash are not on the same chain")
 	errFailedToGetDescendant        = errors.New("failed to find descendant block")
+
+	// chainProcessor errors
+	errFatalInherentError = errors.New("fatal inherent error")
 )
 
 // ErrNilChannel is returned if a channel is nil
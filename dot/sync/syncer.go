@@ -87,7 +87,7 @@ func NewService(cfg *Config) (*Service, error) {
 	chainSync := newChainSync(csCfg)
 	chainProcessor := newChainProcessor(readyBlocks, pendingBlocks,
 		cfg.BlockState, cfg.StorageState, cfg.TransactionState,
-		cfg.BabeVerifier, cfg.FinalityGadget, cfg.BlockImportHandler, cfg.Telemetry)
+		cfg.BabeVerifier, cfg.FinalityGadget, cfg.BlockImportHandler, cfg.Network, cfg.Telemetry)
 
 	return &Service{
 		blockState:     cfg.BlockState,
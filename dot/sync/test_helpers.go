@@ -67,8 +67,7 @@ func BuildBlock(t *testing.T, instance runtime.Instance, parent *types.Header, e
 		// validate and apply extrinsic
 		var ret []byte
 
-		externalExt := types.Extrinsic(append([]byte{byte(types.TxnExternal)}, ext...))
-		_, err = instance.ValidateTransaction(externalExt)
+		_, err = instance.ValidateTransaction(types.TxnExternal, ext, parent.Hash())
 		require.NoError(t, err)
 
 		ret, err = instance.ApplyExtrinsic(ext)
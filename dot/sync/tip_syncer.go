@@ -9,11 +9,12 @@
 	"github.com/ChainSafe/gossamer/dot/network"
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 var _ workHandler = &tipSyncer{}
 
-type handleReadyBlockFunc func(*types.BlockData)
+type handleReadyBlockFunc func(bd *types.BlockData, from peer.ID)
 
 // tipSyncer handles workers when syncing at the tip of the chain
 type tipSyncer struct {
@@ -202,7 +203,7 @@ func (s *tipSyncer) handleTick() ([]*worker, error) {
 		if has || s.readyBlocks.has(block.header.ParentHash) {
 			// block is ready, as parent is known!
 			// also, move any pendingBlocks that are descendants of this block to the ready blocks queue
-			s.handleReadyBlock(block.toBlockData())
+			s.handleReadyBlock(block.toBlockData(), "")
 			continue
 		}
 
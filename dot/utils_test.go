@@ -136,6 +136,11 @@ type args struct {
 grandpa_authority = false
 wasm_interpreter = ""
 grandpa_interval = 0
+runtime_pool_size = 0
+trie_cache_size = 0
+offchain_worker_concurrency = 0
+offchain_worker_timeout = 0
+parallel_hashing_threshold = 0
 
 [network]
 port = 0
@@ -20,6 +20,11 @@ type Branch struct {
 	Key      []byte
 	Children [16]Node
 	Value    []byte
+	// HashedValue is true when Value is the blake2b hash of the
+	// actual value, as decoded from a node encoded with a trie
+	// version storing large values by hash. It is reset once the
+	// actual value is loaded from the database or the proof.
+	HashedValue bool
 	// Dirty is true when the branch differs
 	// from the node stored in the database.
 	Dirty      bool
@@ -34,7 +39,9 @@ type Branch struct {
 	// Statistics
 
 	// Descendants is the number of descendant nodes for
-	// this particular node.
+	// this particular node. For a branch decoded from the
+	// database on its own, as done for lazy tries, it only
+	// counts its direct children and is a lower bound.
 	Descendants uint32
 }
 
@@ -9,6 +9,7 @@
 	"hash"
 	"io"
 	"runtime"
+	"sync/atomic"
 
 	"github.com/ChainSafe/gossamer/internal/trie/codec"
 	"github.com/ChainSafe/gossamer/internal/trie/pools"
@@ -19,12 +20,12 @@
 // ScaleEncodeHash hashes the node (blake2b sum on encoded value)
 // and then SCALE encodes it. This is used to encode children
 // nodes of branches.
-func (b *Branch) ScaleEncodeHash() (encoding []byte, err error) {
+func (b *Branch) ScaleEncodeHash(maxInlineValue int) (encoding []byte, err error) {
 	buffer := pools.DigestBuffers.Get().(*bytes.Buffer)
 	buffer.Reset()
 	defer pools.DigestBuffers.Put(buffer)
 
-	err = b.hash(buffer)
+	err = b.hash(buffer, maxInlineValue)
 	if err != nil {
 		return nil, fmt.Errorf("cannot hash branch: %w", err)
 	}
@@ -37,12 +38,12 @@ func (b *Branch) ScaleEncodeHash() (encoding []byte, err error) {
 	return encoding, nil
 }
 
-func (b *Branch) hash(digestBuffer io.Writer) (err error) {
+func (b *Branch) hash(digestBuffer io.Writer, maxInlineValue int) (err error) {
 	encodingBuffer := pools.EncodingBuffers.Get().(*bytes.Buffer)
 	encodingBuffer.Reset()
 	defer pools.EncodingBuffers.Put(encodingBuffer)
 
-	err = b.Encode(encodingBuffer)
+	err = b.Encode(encodingBuffer, maxInlineValue)
 	if err != nil {
 		return fmt.Errorf("cannot encode leaf: %w", err)
 	}
@@ -75,8 +76,9 @@ func (b *Branch) hash(digestBuffer io.Writer) (err error) {
 }
 
 // Encode encodes a branch with the encoding specified at the top of this package
-// to the buffer given.
-func (b *Branch) Encode(buffer Buffer) (err error) {
+// to the buffer given. The value of the branch and the values of its children
+// are hashed if they are larger than maxInlineValue.
+func (b *Branch) Encode(buffer Buffer, maxInlineValue int) (err error) {
 	if !b.Dirty && b.Encoding != nil {
 		_, err = buffer.Write(b.Encoding)
 		if err != nil {
@@ -85,7 +87,7 @@ func (b *Branch) Encode(buffer Buffer) (err error) {
 		return nil
 	}
 
-	err = b.encodeHeader(buffer)
+	err = b.encodeHeader(buffer, maxInlineValue)
 	if err != nil {
 		return fmt.Errorf("cannot encode header: %w", err)
 	}
@@ -103,18 +105,13 @@ func (b *Branch) Encode(buffer Buffer) (err error) {
 	}
 
 	if b.Value != nil {
-		bytes, err := scale.Marshal(b.Value)
+		err = encodeValue(b.Value, b.HashedValue, maxInlineValue, buffer)
 		if err != nil {
-			return fmt.Errorf("cannot scale encode value: %w", err)
-		}
-
-		_, err = buffer.Write(bytes)
-		if err != nil {
-			return fmt.Errorf("cannot write encoded value to buffer: %w", err)
+			return err
 		}
 	}
 
-	err = encodeChildrenOpportunisticParallel(b.Children, buffer)
+	err = encodeChildrenOpportunisticParallel(b.Children, maxInlineValue, buffer)
 	if err != nil {
 		return fmt.Errorf("cannot encode children of branch: %w", err)
 	}
@@ -128,14 +125,15 @@ type encodingAsyncResult struct {
 	err    error
 }
 
-func runEncodeChild(child Node, index int,
+func runEncodeChild(child Node, index, maxInlineValue int,
 	results chan<- encodingAsyncResult, rateLimit <-chan struct{}) {
-	buffer := pools.EncodingBuffers.Get().(*bytes.Buffer)
+	// the scale encoded hash of a child is at most 33 bytes long.
+	buffer := pools.DigestBuffers.Get().(*bytes.Buffer)
 	buffer.Reset()
 	// buffer is put back in the pool after processing its
 	// data in the select block below.
 
-	err := encodeChild(child, buffer)
+	err := encodeChild(child, maxInlineValue, buffer)
 
 	results <- encodingAsyncResult{
 		index:  index,
@@ -153,32 +151,87 @@ func runEncodeChild(child Node, index int,
 
 var parallelEncodingRateLimit = make(chan struct{}, parallelLimit)
 
+// DefaultParallelEncodingThreshold is the default minimum number of nodes
+// to encode in the subtrie of a branch to encode it in parallel with its siblings.
+const DefaultParallelEncodingThreshold = 64
+
+var parallelEncodingThreshold uint32 = DefaultParallelEncodingThreshold
+
+// SetParallelEncodingThreshold sets the minimum number of nodes to encode
+// in the subtrie of a branch to encode it in parallel with its siblings.
+func SetParallelEncodingThreshold(nodes uint32) {
+	atomic.StoreUint32(&parallelEncodingThreshold, nodes)
+}
+
+// encodeInParallel returns true if the child given is a branch with at
+// least the parallel encoding threshold number of nodes to encode in its
+// subtrie, so encoding it outweighs the cost of a goroutine.
+// The nodes are counted instead of relying on the branch descendants count,
+// since the latter is not accurate for branches decoded from a lazy trie.
+func encodeInParallel(child Node) bool {
+	branch, ok := child.(*Branch)
+	if !ok || branch == nil {
+		return false
+	}
+
+	threshold := atomic.LoadUint32(&parallelEncodingThreshold)
+	return countNodesToEncode(branch, threshold) >= threshold
+}
+
+// countNodesToEncode returns the number of nodes not already encoded
+// in the subtrie rooted at the node given, stopping to count once the
+// limit given is reached. Already encoded nodes are not traversed.
+func countNodesToEncode(n Node, limit uint32) (count uint32) {
+	switch n := n.(type) {
+	case *Leaf:
+		if n == nil || (!n.Dirty && n.Encoding != nil) {
+			return 0
+		}
+		return 1
+	case *Branch:
+		if n == nil || (!n.Dirty && n.Encoding != nil) {
+			return 0
+		}
+		count = 1
+		for _, child := range n.Children {
+			if count >= limit {
+				break
+			}
+			count += countNodesToEncode(child, limit-count)
+		}
+		return count
+	default:
+		return 0
+	}
+}
+
 // encodeChildrenOpportunisticParallel encodes children in parallel eventually.
-// Leaves are encoded in a blocking way, and branches are encoded in separate
-// goroutines IF they are less than the parallelLimit number of goroutines already
-// running. This is designed to limit the total number of goroutines in order to
-// avoid using too much memory on the stack.
-func encodeChildrenOpportunisticParallel(children [16]Node, buffer io.Writer) (err error) {
+// Leaves and small or already encoded branches are encoded in a blocking way,
+// and other branches are encoded in separate goroutines IF they are less than
+// the parallelLimit number of goroutines already running. This is designed to
+// limit the total number of goroutines in order to avoid using too much memory
+// on the stack. The children encodings are written in order, so the encoding
+// is the same as if the children were encoded sequentially.
+func encodeChildrenOpportunisticParallel(children [16]Node, maxInlineValue int, buffer io.Writer) (err error) {
 	// Buffered channels since children might be encoded in this
 	// goroutine or another one.
 	resultsCh := make(chan encodingAsyncResult, ChildrenCapacity)
 
 	for i, child := range children {
-		if isNodeNil(child) || child.Type() == LeafType {
-			runEncodeChild(child, i, resultsCh, nil)
+		if !encodeInParallel(child) {
+			runEncodeChild(child, i, maxInlineValue, resultsCh, nil)
 			continue
 		}
 
-		// Branch child
 		select {
 		case parallelEncodingRateLimit <- struct{}{}:
 			// We have a goroutine available to encode
 			// the branch in parallel.
-			go runEncodeChild(child, i, resultsCh, parallelEncodingRateLimit)
+			go runEncodeChild(child, i, maxInlineValue, resultsCh, parallelEncodingRateLimit)
 		default:
 			// we reached the maximum parallel goroutines
 			// so encode this branch in this goroutine
-			runEncodeChild(child, i, resultsCh, nil)
+			runEncodeChild(child, i, maxInlineValue, resultsCh, nil)
 		}
 	}
 
@@ -206,7 +259,7 @@ func encodeChildrenOpportunisticParallel(children [16]Node, buffer io.Writer) (e
 				}
 			}
 
-			pools.EncodingBuffers.Put(resultBuffers[currentIndex])
+			pools.DigestBuffers.Put(resultBuffers[currentIndex])
 			resultBuffers[currentIndex] = nil
 
 			currentIndex++
@@ -217,15 +270,15 @@ func encodeChildrenOpportunisticParallel(children [16]Node, buffer io.Writer) (e
 		if buffer == nil { // already emptied and put back in pool
 			continue
 		}
-		pools.EncodingBuffers.Put(buffer)
+		pools.DigestBuffers.Put(buffer)
 	}
 
 	return err
 }
 
-func encodeChildrenSequentially(children [16]Node, buffer io.Writer) (err error) {
+func encodeChildrenSequentially(children [16]Node, maxInlineValue int, buffer io.Writer) (err error) {
 	for i, child := range children {
-		err = encodeChild(child, buffer)
+		err = encodeChild(child, maxInlineValue, buffer)
 		if err != nil {
 			return fmt.Errorf("cannot encode child at index %d: %w", i, err)
 		}
@@ -245,12 +298,12 @@ func isNodeNil(n Node) (isNil bool) {
 	return isNil
 }
 
-func encodeChild(child Node, buffer io.Writer) (err error) {
+func encodeChild(child Node, maxInlineValue int, buffer io.Writer) (err error) {
 	if isNodeNil(child) {
 		return nil
 	}
 
-	scaleEncodedChild, err := child.ScaleEncodeHash()
+	scaleEncodedChild, err := child.ScaleEncodeHash(maxInlineValue)
 	if err != nil {
 		return fmt.Errorf("failed to hash and scale encode child: %w", err)
 	}
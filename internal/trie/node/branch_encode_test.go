@@ -43,7 +43,7 @@ func Test_Branch_ScaleEncodeHash(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			encoding, err := testCase.branch.ScaleEncodeHash()
+			encoding, err := testCase.branch.ScaleEncodeHash(NoMaxInlineValue)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -129,7 +129,7 @@ func Test_Branch_hash(t *testing.T) {
 			digestBuffer.EXPECT().Write(testCase.write.written).
 				Return(testCase.write.n, testCase.write.err)
 
-			err := testCase.branch.hash(digestBuffer)
+			err := testCase.branch.hash(digestBuffer, NoMaxInlineValue)
 
 			if testCase.errWrapped != nil {
 				assert.ErrorIs(t, err, testCase.errWrapped)
@@ -251,7 +251,7 @@ func Test_Branch_Encode(t *testing.T) {
 				},
 			},
 			wrappedErr: errTest,
-			errMessage: "cannot write encoded value to buffer: test error",
+			errMessage: "cannot write scale encoded value to buffer: test error",
 		},
 		"buffer write error for children encoding": {
 			branch: &Branch{
@@ -336,7 +336,7 @@ func Test_Branch_Encode(t *testing.T) {
 				previousCall = call
 			}
 
-			err := testCase.branch.Encode(buffer)
+			err := testCase.branch.Encode(buffer, NoMaxInlineValue)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -358,7 +358,7 @@ func Benchmark_encodeChildrenOpportunisticParallel(b *testing.B) {
 
 	b.Run("", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_ = encodeChildrenOpportunisticParallel(children, io.Discard)
+			_ = encodeChildrenOpportunisticParallel(children, NoMaxInlineValue, io.Discard)
 		}
 	})
 }
@@ -377,16 +377,150 @@ func populateChildren(valueSize, depth int) (children [16]Node) {
 	}
 
 	for i := range children {
-		children[i] = &Branch{
+		branch := &Branch{
 			Key:      someValue,
 			Value:    someValue,
 			Children: populateChildren(valueSize, depth-1),
 		}
+		for _, child := range branch.Children {
+			branch.Descendants++
+			if childBranch, ok := child.(*Branch); ok {
+				branch.Descendants += childBranch.Descendants
+			}
+		}
+		children[i] = branch
 	}
 
 	return children
 }
 
+func Test_encodeInParallel(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		child    Node
+		parallel bool
+	}{
+		"nil node": {},
+		"nil branch": {
+			child: (*Branch)(nil),
+		},
+		"leaf": {
+			child: &Leaf{Key: []byte{1}},
+		},
+		"branch below threshold": {
+			child: &Branch{
+				Dirty:    true,
+				Children: populateChildren(1, 0),
+			},
+		},
+		"encoded branch": {
+			child: &Branch{
+				Encoding: []byte{1},
+				Children: populateChildren(1, 1),
+			},
+		},
+		"dirty branch": {
+			child: &Branch{
+				Dirty:    true,
+				Encoding: []byte{1},
+				Children: populateChildren(1, 1),
+			},
+			parallel: true,
+		},
+		"branch not encoded": {
+			child: &Branch{
+				Children: populateChildren(1, 1),
+			},
+			parallel: true,
+		},
+		"branch with encoded children": {
+			child: &Branch{
+				Dirty:       true,
+				Descendants: DefaultParallelEncodingThreshold,
+				Children: func() (children [16]Node) {
+					for i := range children {
+						children[i] = &Branch{
+							Encoding:    []byte{1},
+							Descendants: DefaultParallelEncodingThreshold,
+						}
+					}
+					return children
+				}(),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			parallel := encodeInParallel(testCase.child)
+
+			assert.Equal(t, testCase.parallel, parallel)
+		})
+	}
+}
+
+func Test_countNodesToEncode(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		node  Node
+		limit uint32
+		count uint32
+	}{
+		"nil node": {
+			limit: 10,
+		},
+		"nil leaf": {
+			node:  (*Leaf)(nil),
+			limit: 10,
+		},
+		"encoded leaf": {
+			node:  &Leaf{Encoding: []byte{1}},
+			limit: 10,
+		},
+		"dirty leaf": {
+			node:  &Leaf{Dirty: true, Encoding: []byte{1}},
+			limit: 10,
+			count: 1,
+		},
+		"branch": {
+			node: &Branch{
+				Children: [16]Node{
+					&Leaf{},
+					&Leaf{Encoding: []byte{1}},
+					&Branch{
+						Children: [16]Node{&Leaf{}},
+					},
+				},
+			},
+			limit: 10,
+			count: 4,
+		},
+		"limit reached": {
+			node: &Branch{
+				Children: populateChildren(1, 1),
+			},
+			limit: 20,
+			count: 20,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			count := countNodesToEncode(testCase.node, testCase.limit)
+
+			assert.Equal(t, testCase.count, count)
+		})
+	}
+}
+
 func Test_encodeChildrenOpportunisticParallel(t *testing.T) {
 	t.Parallel()
 
@@ -492,7 +626,7 @@ func Test_encodeChildrenOpportunisticParallel(t *testing.T) {
 				previousCall = call
 			}
 
-			err := encodeChildrenOpportunisticParallel(testCase.children, buffer)
+			err := encodeChildrenOpportunisticParallel(testCase.children, NoMaxInlineValue, buffer)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -506,26 +640,17 @@ func Test_encodeChildrenOpportunisticParallel(t *testing.T) {
 	t.Run("opportunist parallel branch encoding", func(t *testing.T) {
 		t.Parallel()
 
-		var children [16]Node
-		for i := range children {
-			children[i] = &Branch{}
-		}
+		children := populateChildren(1, 2)
 
 		buffer := bytes.NewBuffer(nil)
 
-		err := encodeChildrenOpportunisticParallel(children, buffer)
+		err := encodeChildrenOpportunisticParallel(children, NoMaxInlineValue, buffer)
 
 		require.NoError(t, err)
-		expectedBytes := []byte{
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0,
-			0xc, 0x80, 0x0, 0x0, 0xc, 0x80, 0x0, 0x0}
-		assert.Equal(t, expectedBytes, buffer.Bytes())
+		expected := bytes.NewBuffer(nil)
+		err = encodeChildrenSequentially(children, NoMaxInlineValue, expected)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Bytes(), buffer.Bytes())
 	})
 }
 
@@ -617,7 +742,7 @@ func Test_encodeChildrenSequentially(t *testing.T) {
 				previousCall = call
 			}
 
-			err := encodeChildrenSequentially(testCase.children, buffer)
+			err := encodeChildrenSequentially(testCase.children, NoMaxInlineValue, buffer)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -712,7 +837,7 @@ func Test_encodeChild(t *testing.T) {
 					Return(testCase.write.n, testCase.write.err)
 			}
 
-			err := encodeChild(testCase.child, buffer)
+			err := encodeChild(testCase.child, NoMaxInlineValue, buffer)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
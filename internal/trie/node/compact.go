@@ -0,0 +1,324 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// CompactEscapeHeader is the byte prefixed to the compact encoding of a node
+// whose value stored by hash is omitted, in which case the value follows the
+// node in the compact proof. It matches the compact proofs of Substrate.
+const CompactEscapeHeader byte = 0x01
+
+var (
+	ErrValueNotHashed        = errors.New("value is not stored by hash")
+	ErrChildNotHashed        = errors.New("child is not referenced by hash")
+	ErrCompactValueNotEmpty  = errors.New("omitted value is not empty")
+	ErrOmittedChildHashEmpty = errors.New("hash of omitted child is not given")
+	ErrOmittedValueHashEmpty = errors.New("hash of omitted value is not given")
+	ErrTrailingBytes         = errors.New("trailing bytes after node encoding")
+)
+
+// encodingLayout holds the offsets of the parts of a node encoding,
+// so the encoding can be rewritten without decoding and encoding
+// the node again.
+type encodingLayout struct {
+	variant          variant
+	partialKeyLength int
+	// headerEnd is the offset of the end of the header,
+	// including the extra partial key length bytes.
+	headerEnd int
+	// valueStart and valueEnd are the offsets of the value, which is SCALE
+	// encoded if it is inlined, or the hash of the value if it is hashed.
+	// They are equal if the node has no value.
+	valueStart, valueEnd int
+	// children holds the offsets of the SCALE encoded references to the
+	// children and of their data, which are all zero for an absent child.
+	children [ChildrenCapacity]struct{ start, dataStart, end int }
+}
+
+// decodeLayout returns the layout of the node encoding given.
+func decodeLayout(encoding []byte) (layout encodingLayout, err error) {
+	if len(encoding) == 0 {
+		return layout, ErrReadHeaderByte
+	}
+
+	reader := bytes.NewReader(encoding[1:])
+	offset := func() int { return len(encoding) - reader.Len() }
+
+	header := encoding[0]
+	layout.variant = decodeVariant(header)
+	switch layout.variant {
+	case leafVariant, leafWithHashedValueVariant,
+		branchVariant, branchWithValueVariant, branchWithHashedValueVariant:
+	default:
+		return layout, fmt.Errorf("%w: %d", ErrUnknownNodeType, Type(header>>nodeHeaderShift))
+	}
+
+	keyLengthMask := layout.variant.partialKeyLengthHeaderMask()
+	partialKeyLength := int(header & keyLengthMask)
+	if header&keyLengthMask == keyLengthMask {
+		for {
+			nextKeyLength, err := reader.ReadByte()
+			if err != nil {
+				return layout, fmt.Errorf("%w: %s", ErrReadKeyLength, err)
+			}
+			partialKeyLength += int(nextKeyLength)
+			if nextKeyLength < 0xff {
+				break
+			}
+		}
+	}
+	layout.partialKeyLength = partialKeyLength
+	layout.headerEnd = offset()
+
+	keyLength := int64(partialKeyLength/2 + partialKeyLength%2)
+	if keyLength > int64(reader.Len()) {
+		return layout, fmt.Errorf("%w: %d bytes are left instead of %d",
+			ErrReadKeyData, reader.Len(), keyLength)
+	}
+	_, _ = reader.Seek(keyLength, io.SeekCurrent)
+
+	var childrenBitmap uint16
+	isBranch := layout.variant == branchVariant ||
+		layout.variant == branchWithValueVariant ||
+		layout.variant == branchWithHashedValueVariant
+	if isBranch {
+		bitmap := make([]byte, 2)
+		_, err = io.ReadFull(reader, bitmap)
+		if err != nil {
+			return layout, fmt.Errorf("%w: %s", ErrReadChildrenBitmap, err)
+		}
+		childrenBitmap = uint16(bitmap[0]) | uint16(bitmap[1])<<8
+	}
+
+	layout.valueStart = offset()
+	switch layout.variant {
+	case leafVariant, branchWithValueVariant:
+		_, err = skipScaleBytes(reader)
+		if err != nil {
+			return layout, fmt.Errorf("%w: %s", ErrDecodeValue, err)
+		}
+	case leafWithHashedValueVariant, branchWithHashedValueVariant:
+		_, err = decodeHashedValue(reader)
+		if err != nil {
+			return layout, err
+		}
+	}
+	layout.valueEnd = offset()
+
+	for i := 0; i < ChildrenCapacity; i++ {
+		if (childrenBitmap>>i)&1 != 1 {
+			continue
+		}
+
+		start := offset()
+		length, err := skipScaleBytes(reader)
+		if err != nil {
+			return layout, fmt.Errorf("%w: at index %d: %s", ErrDecodeChildHash, i, err)
+		}
+		layout.children[i].start = start
+		layout.children[i].end = offset()
+		layout.children[i].dataStart = layout.children[i].end - length
+	}
+
+	if reader.Len() > 0 {
+		return layout, fmt.Errorf("%w: %d bytes", ErrTrailingBytes, reader.Len())
+	}
+
+	return layout, nil
+}
+
+// skipScaleBytes moves the reader given after the SCALE encoded
+// byte slice it starts with, and returns the length of the slice.
+func skipScaleBytes(reader *bytes.Reader) (length int, err error) {
+	var scaleLength uint
+	err = scale.NewDecoder(reader).Decode(&scaleLength)
+	if err != nil {
+		return 0, err
+	} else if scaleLength > uint(reader.Len()) {
+		return 0, fmt.Errorf("%w: %d bytes are left instead of %d",
+			io.ErrUnexpectedEOF, reader.Len(), scaleLength)
+	}
+
+	length = int(scaleLength)
+	_, _ = reader.Seek(int64(length), io.SeekCurrent)
+	return length, nil
+}
+
+// hasHashedValue returns true if the value of the node is stored by hash.
+func (l encodingLayout) hasHashedValue() bool {
+	return l.variant == leafWithHashedValueVariant ||
+		l.variant == branchWithHashedValueVariant
+}
+
+// inlineValueVariant returns the variant of the node with
+// its value inlined instead of stored by hash.
+func (l encodingLayout) inlineValueVariant() variant {
+	if l.variant == leafWithHashedValueVariant {
+		return leafVariant
+	}
+	return branchWithValueVariant
+}
+
+// hashedValueVariant returns the variant of the node with
+// its value stored by hash instead of inlined.
+func (l encodingLayout) hashedValueVariant() variant {
+	if l.variant == leafVariant {
+		return leafWithHashedValueVariant
+	}
+	return branchWithHashedValueVariant
+}
+
+// childReference returns the reference to the child at the index given,
+// without its SCALE length prefix, or nil if there is no child at the index.
+func (l encodingLayout) childReference(encoding []byte, index int) (reference []byte) {
+	child := l.children[index]
+	if child.start == child.end {
+		return nil
+	}
+	return encoding[child.dataStart:child.end]
+}
+
+// DecodeReferences returns the hash of the value of the node encoded given
+// if it is stored by hash, and the references to its children, which are nil
+// for absent children, the hash of the child or the encoding of the child if
+// it is inlined. For a compact encoding, the value hash is nil if the value is
+// omitted, and the references to the children omitted are empty and not nil.
+func DecodeReferences(encoding []byte) (valueHash []byte, children [ChildrenCapacity][]byte, err error) {
+	if len(encoding) > 0 && encoding[0] == CompactEscapeHeader {
+		encoding = encoding[1:]
+	}
+
+	layout, err := decodeLayout(encoding)
+	if err != nil {
+		return nil, children, err
+	}
+
+	if layout.hasHashedValue() {
+		valueHash = encoding[layout.valueStart:layout.valueEnd]
+	}
+
+	for i := range children {
+		children[i] = layout.childReference(encoding, i)
+	}
+
+	return valueHash, children, nil
+}
+
+// CompactValueOmitted returns true if the compact node encoding
+// given omits its value, which then follows it in the compact proof.
+func CompactValueOmitted(compactEncoding []byte) bool {
+	return len(compactEncoding) > 0 && compactEncoding[0] == CompactEscapeHeader
+}
+
+// EncodeCompact returns the compact encoding of the node encoded given,
+// where the references to the children at the indexes set in omittedChildren
+// are replaced with empty references, and where the value stored by hash is
+// replaced with an empty inlined value if omitValue is true, in which case
+// the compact encoding is prefixed with the CompactEscapeHeader byte.
+func EncodeCompact(encoding []byte, omittedChildren [ChildrenCapacity]bool,
+	omitValue bool) (compactEncoding []byte, err error) {
+	layout, err := decodeLayout(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode node encoding: %w", err)
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, len(encoding)+1))
+
+	if omitValue {
+		if !layout.hasHashedValue() {
+			return nil, ErrValueNotHashed
+		}
+
+		buffer.WriteByte(CompactEscapeHeader)
+		err = encodeHeader(layout.inlineValueVariant(), layout.partialKeyLength, buffer)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode header: %w", err)
+		}
+		buffer.Write(encoding[layout.headerEnd:layout.valueStart])
+		const emptyValue = 0x00 // SCALE encoding of an empty byte slice
+		buffer.WriteByte(emptyValue)
+	} else {
+		buffer.Write(encoding[:layout.valueEnd])
+	}
+
+	for i, child := range layout.children {
+		if !omittedChildren[i] {
+			buffer.Write(encoding[child.start:child.end])
+			continue
+		}
+
+		reference := layout.childReference(encoding, i)
+		if len(reference) != hashedValueLength {
+			return nil, fmt.Errorf("%w: at index %d", ErrChildNotHashed, i)
+		}
+		const emptyReference = 0x00 // SCALE encoding of an empty byte slice
+		buffer.WriteByte(emptyReference)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// DecodeCompact returns the node encoding of the compact node encoding given,
+// where the empty references to the omitted children are replaced with the
+// children hashes given, and where the omitted value, if any, is replaced
+// with the value hash given.
+func DecodeCompact(compactEncoding []byte, childrenHashes [ChildrenCapacity][]byte,
+	valueHash []byte) (encoding []byte, err error) {
+	valueOmitted := CompactValueOmitted(compactEncoding)
+	if valueOmitted {
+		compactEncoding = compactEncoding[1:]
+	}
+
+	layout, err := decodeLayout(compactEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode compact node encoding: %w", err)
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, len(compactEncoding)+ChildrenCapacity*hashedValueLength))
+
+	if valueOmitted {
+		if layout.hasHashedValue() || layout.valueEnd-layout.valueStart != 1 {
+			return nil, ErrCompactValueNotEmpty
+		} else if len(valueHash) != hashedValueLength {
+			return nil, ErrOmittedValueHashEmpty
+		}
+
+		err = encodeHeader(layout.hashedValueVariant(), layout.partialKeyLength, buffer)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode header: %w", err)
+		}
+		buffer.Write(compactEncoding[layout.headerEnd:layout.valueStart])
+		buffer.Write(valueHash)
+	} else {
+		buffer.Write(compactEncoding[:layout.valueEnd])
+	}
+
+	for i, child := range layout.children {
+		reference := layout.childReference(compactEncoding, i)
+		if reference == nil || len(reference) > 0 {
+			buffer.Write(compactEncoding[child.start:child.end])
+			continue
+		}
+
+		if len(childrenHashes[i]) != hashedValueLength {
+			return nil, fmt.Errorf("%w: at index %d", ErrOmittedChildHashEmpty, i)
+		}
+
+		encodedHash, err := scale.Marshal(childrenHashes[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot scale encode hash of child at index %d: %w", i, err)
+		}
+		buffer.Write(encodedHash)
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package node
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestNode(t *testing.T, n Node, maxInlineValue int) (encoding []byte) {
+	t.Helper()
+	buffer := bytes.NewBuffer(nil)
+	err := n.Encode(buffer, maxInlineValue)
+	require.NoError(t, err)
+	return buffer.Bytes()
+}
+
+func Test_DecodeReferences(t *testing.T) {
+	t.Parallel()
+
+	childHash := bytes.Repeat([]byte{0xcc}, 32)
+	valueHash := bytes.Repeat([]byte{0xdd}, 32)
+	inlineLeaf := &Leaf{Key: []byte{2}, Value: []byte{3}}
+
+	testCases := map[string]struct {
+		encoding   []byte
+		valueHash  []byte
+		children   [ChildrenCapacity][]byte
+		errWrapped error
+		errMessage string
+	}{
+		"empty encoding": {
+			errWrapped: ErrReadHeaderByte,
+			errMessage: "cannot read header byte",
+		},
+		"leaf": {
+			encoding: []byte{0x41, 0x01, 0x04, 0x02},
+		},
+		"leaf with hashed value": {
+			encoding:  append([]byte{0x21, 0x01}, valueHash...),
+			valueHash: valueHash,
+		},
+		"branch with hashed value and children": {
+			encoding: encodeTestNode(t, &Branch{
+				Key:         []byte{1},
+				Value:       valueHash,
+				HashedValue: true,
+				Children: [ChildrenCapacity]Node{
+					1: &Leaf{HashDigest: childHash},
+					5: inlineLeaf,
+				},
+			}, NoMaxInlineValue),
+			valueHash: valueHash,
+			children: [ChildrenCapacity][]byte{
+				1: childHash,
+				5: {0x41, 0x02, 0x04, 0x03},
+			},
+		},
+		"compact branch": {
+			encoding: []byte{0x80, 0x02, 0x00, 0x00},
+			children: [ChildrenCapacity][]byte{
+				1: {},
+			},
+		},
+		"trailing bytes": {
+			encoding:   []byte{0x41, 0x01, 0x04, 0x02, 0xff},
+			errWrapped: ErrTrailingBytes,
+			errMessage: "trailing bytes after node encoding: 1 bytes",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			valueHash, children, err := DecodeReferences(testCase.encoding)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+			assert.Equal(t, testCase.valueHash, valueHash)
+			assert.Equal(t, testCase.children, children)
+		})
+	}
+}
+
+func Test_EncodeCompact_DecodeCompact(t *testing.T) {
+	t.Parallel()
+
+	childHashA := bytes.Repeat([]byte{0xaa}, 32)
+	childHashB := bytes.Repeat([]byte{0xbb}, 32)
+	valueHash := bytes.Repeat([]byte{0xdd}, 32)
+	longKey := bytes.Repeat([]byte{1}, 70)
+
+	testCases := map[string]struct {
+		node            Node
+		omittedChildren [ChildrenCapacity]bool
+		omitValue       bool
+		compactEncoding []byte
+	}{
+		"leaf": {
+			node:            &Leaf{Key: []byte{1}, Value: []byte{2}},
+			compactEncoding: []byte{0x41, 0x01, 0x04, 0x02},
+		},
+		"leaf with omitted value": {
+			node:            &Leaf{Key: []byte{1}, Value: valueHash, HashedValue: true},
+			omitValue:       true,
+			compactEncoding: []byte{CompactEscapeHeader, 0x41, 0x01, 0x00},
+		},
+		"leaf with long key and omitted value": {
+			node:      &Leaf{Key: longKey, Value: valueHash, HashedValue: true},
+			omitValue: true,
+			compactEncoding: append(append([]byte{CompactEscapeHeader, 0x7f, 70 - 63},
+				bytes.Repeat([]byte{0x11}, 35)...), 0x00),
+		},
+		"branch with omitted children": {
+			node: &Branch{
+				Key: []byte{1},
+				Children: [ChildrenCapacity]Node{
+					0:  &Leaf{HashDigest: childHashA},
+					3:  &Leaf{Key: []byte{2}, Value: []byte{3}},
+					15: &Leaf{HashDigest: childHashB},
+				},
+			},
+			omittedChildren: [ChildrenCapacity]bool{15: true},
+			compactEncoding: append(append([]byte{0x81, 0x01, 0x09, 0x80, 0x80},
+				childHashA...), 0x10, 0x41, 0x02, 0x04, 0x03, 0x00),
+		},
+		"branch with omitted value and children": {
+			node: &Branch{
+				Key:         []byte{},
+				Value:       valueHash,
+				HashedValue: true,
+				Children: [ChildrenCapacity]Node{
+					0: &Leaf{HashDigest: childHashA},
+					1: &Leaf{HashDigest: childHashB},
+				},
+			},
+			omittedChildren: [ChildrenCapacity]bool{0: true, 1: true},
+			omitValue:       true,
+			compactEncoding: []byte{CompactEscapeHeader, 0xc0, 0x03, 0x00, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			encoding := encodeTestNode(t, testCase.node, NoMaxInlineValue)
+
+			compactEncoding, err := EncodeCompact(encoding, testCase.omittedChildren, testCase.omitValue)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.compactEncoding, compactEncoding)
+			assert.Equal(t, testCase.omitValue, CompactValueOmitted(compactEncoding))
+
+			var childrenHashes [ChildrenCapacity][]byte
+			_, children, err := DecodeReferences(encoding)
+			require.NoError(t, err)
+			for i, omitted := range testCase.omittedChildren {
+				if omitted {
+					childrenHashes[i] = children[i]
+				}
+			}
+			var valueHash []byte
+			if testCase.omitValue {
+				valueHash = testCase.node.GetValue()
+			}
+
+			decodedEncoding, err := DecodeCompact(compactEncoding, childrenHashes, valueHash)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, decodedEncoding)
+		})
+	}
+}
+
+func Test_EncodeCompact_errors(t *testing.T) {
+	t.Parallel()
+
+	leafEncoding := []byte{0x41, 0x01, 0x04, 0x02}
+
+	_, err := EncodeCompact(leafEncoding, [ChildrenCapacity]bool{}, true)
+	assert.ErrorIs(t, err, ErrValueNotHashed)
+
+	branchEncoding := encodeTestNode(t, &Branch{
+		Key: []byte{1},
+		Children: [ChildrenCapacity]Node{
+			&Leaf{Key: []byte{2}, Value: []byte{3}},
+		},
+	}, NoMaxInlineValue)
+	_, err = EncodeCompact(branchEncoding, [ChildrenCapacity]bool{true}, false)
+	assert.ErrorIs(t, err, ErrChildNotHashed)
+	assert.EqualError(t, err, "child is not referenced by hash: at index 0")
+}
+
+func Test_DecodeCompact_errors(t *testing.T) {
+	t.Parallel()
+
+	compactBranch := []byte{0x80, 0x02, 0x00, 0x00}
+	_, err := DecodeCompact(compactBranch, [ChildrenCapacity][]byte{}, nil)
+	assert.ErrorIs(t, err, ErrOmittedChildHashEmpty)
+	assert.EqualError(t, err, "hash of omitted child is not given: at index 1")
+
+	compactLeaf := []byte{CompactEscapeHeader, 0x41, 0x01, 0x00}
+	_, err = DecodeCompact(compactLeaf, [ChildrenCapacity][]byte{}, nil)
+	assert.ErrorIs(t, err, ErrOmittedValueHashEmpty)
+
+	compactLeaf = []byte{CompactEscapeHeader, 0x41, 0x01, 0x04, 0x02}
+	_, err = DecodeCompact(compactLeaf, [ChildrenCapacity][]byte{}, make([]byte, 32))
+	assert.ErrorIs(t, err, ErrCompactValueNotEmpty)
+}
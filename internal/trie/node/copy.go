@@ -87,6 +87,7 @@ func (b *Branch) Copy(settings CopySettings) Node {
 	if settings.CopyValue && b.Value != nil {
 		cpy.Value = make([]byte, len(b.Value))
 		copy(cpy.Value, b.Value)
+		cpy.HashedValue = b.HashedValue
 	}
 
 	if settings.CopyCached {
@@ -120,6 +121,7 @@ func (l *Leaf) Copy(settings CopySettings) Node {
 	if settings.CopyValue && l.Value != nil {
 		cpy.Value = make([]byte, len(l.Value))
 		copy(cpy.Value, l.Value)
+		cpy.HashedValue = l.HashedValue
 	}
 
 	if settings.CopyCached {
@@ -19,6 +19,7 @@
 	ErrNodeTypeIsNotABranch = errors.New("node type is not a branch")
 	ErrNodeTypeIsNotALeaf   = errors.New("node type is not a leaf")
 	ErrDecodeValue          = errors.New("cannot decode value")
+	ErrReadHashedValue      = errors.New("cannot read hashed value")
 	ErrReadChildrenBitmap   = errors.New("cannot read children bitmap")
 	ErrDecodeChildHash      = errors.New("cannot decode child hash")
 )
@@ -36,22 +37,21 @@ func Decode(reader io.Reader) (n Node, err error) {
 	}
 	header := oneByteBuf[0]
 
-	nodeType := Type(header >> 6)
-	switch nodeType {
-	case LeafType:
+	switch decodeVariant(header) {
+	case leafVariant, leafWithHashedValueVariant:
 		n, err = decodeLeaf(reader, header)
 		if err != nil {
 			return nil, fmt.Errorf("cannot decode leaf: %w", err)
 		}
 		return n, nil
-	case BranchType, BranchWithValueType:
+	case branchVariant, branchWithValueVariant, branchWithHashedValueVariant:
 		n, err = decodeBranch(reader, header)
 		if err != nil {
 			return nil, fmt.Errorf("cannot decode branch: %w", err)
 		}
 		return n, nil
 	default:
-		return nil, fmt.Errorf("%w: %d", ErrUnknownNodeType, nodeType)
+		return nil, fmt.Errorf("%w: %d", ErrUnknownNodeType, Type(header>>nodeHeaderShift))
 	}
 }
 
@@ -61,17 +61,17 @@ func Decode(reader io.Reader) (n Node, err error) {
 // children are known to be with an empty leaf. The children nodes hashes are then used to
 // find other values using the persistent database.
 func decodeBranch(reader io.Reader, header byte) (branch *Branch, err error) {
-	nodeType := Type(header >> 6)
-	switch nodeType {
-	case BranchType, BranchWithValueType:
+	v := decodeVariant(header)
+	switch v {
+	case branchVariant, branchWithValueVariant, branchWithHashedValueVariant:
 	default:
-		return nil, fmt.Errorf("%w: %d", ErrNodeTypeIsNotABranch, nodeType)
+		return nil, fmt.Errorf("%w: %d", ErrNodeTypeIsNotABranch, Type(header>>nodeHeaderShift))
 	}
 
 	branch = new(Branch)
 
-	keyLen := header & keyLenOffset
-	branch.Key, err = decodeKey(reader, keyLen)
+	keyLengthMask := v.partialKeyLengthHeaderMask()
+	branch.Key, err = decodeKey(reader, header&keyLengthMask, keyLengthMask)
 	if err != nil {
 		return nil, fmt.Errorf("cannot decode key: %w", err)
 	}
@@ -84,7 +84,8 @@ func decodeBranch(reader io.Reader, header byte) (branch *Branch, err error) {
 
 	sd := scale.NewDecoder(reader)
 
-	if nodeType == BranchWithValueType {
+	switch v {
+	case branchWithValueVariant:
 		var value []byte
 		// branch w/ value
 		err := sd.Decode(&value)
@@ -92,6 +93,12 @@ func decodeBranch(reader io.Reader, header byte) (branch *Branch, err error) {
 			return nil, fmt.Errorf("%w: %s", ErrDecodeValue, err)
 		}
 		branch.Value = value
+	case branchWithHashedValueVariant:
+		branch.Value, err = decodeHashedValue(reader)
+		if err != nil {
+			return nil, err
+		}
+		branch.HashedValue = true
 	}
 
 	for i := 0; i < 16; i++ {
@@ -132,21 +139,32 @@ func decodeBranch(reader io.Reader, header byte) (branch *Branch, err error) {
 
 // decodeLeaf reads and decodes from a reader with the encoding specified in lib/trie/node/encode_doc.go.
 func decodeLeaf(reader io.Reader, header byte) (leaf *Leaf, err error) {
-	nodeType := Type(header >> 6)
-	if nodeType != LeafType {
-		return nil, fmt.Errorf("%w: %d", ErrNodeTypeIsNotALeaf, nodeType)
+	v := decodeVariant(header)
+	switch v {
+	case leafVariant, leafWithHashedValueVariant:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrNodeTypeIsNotALeaf, Type(header>>nodeHeaderShift))
 	}
 
 	leaf = &Leaf{
 		Dirty: true,
 	}
 
-	keyLen := header & keyLenOffset
-	leaf.Key, err = decodeKey(reader, keyLen)
+	keyLengthMask := v.partialKeyLengthHeaderMask()
+	leaf.Key, err = decodeKey(reader, header&keyLengthMask, keyLengthMask)
 	if err != nil {
 		return nil, fmt.Errorf("cannot decode key: %w", err)
 	}
 
+	if v == leafWithHashedValueVariant {
+		leaf.Value, err = decodeHashedValue(reader)
+		if err != nil {
+			return nil, err
+		}
+		leaf.HashedValue = true
+		return leaf, nil
+	}
+
 	sd := scale.NewDecoder(reader)
 	var value []byte
 	err = sd.Decode(&value)
@@ -160,3 +178,13 @@ func decodeLeaf(reader io.Reader, header byte) (leaf *Leaf, err error) {
 
 	return leaf, nil
 }
+
+// decodeHashedValue reads the 32 bytes hash of a value stored by hash.
+func decodeHashedValue(reader io.Reader) (hash []byte, err error) {
+	hash = make([]byte, hashedValueLength)
+	_, err = io.ReadFull(reader, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrReadHashedValue, err)
+	}
+	return hash, nil
+}
@@ -74,6 +74,32 @@ func Test_Decode(t *testing.T) {
 				Dirty: true,
 			},
 		},
+		"leaf with hashed value success": {
+			reader: bytes.NewReader(
+				concatByteSlices([][]byte{
+					{
+						33, // node type 001 (leaf with hashed value) and key length 1
+						9,  // key data
+					},
+					repeatBytes(32, 7), // hashed value
+				}),
+			),
+			n: &Leaf{
+				Key:         []byte{9},
+				Value:       repeatBytes(32, 7),
+				HashedValue: true,
+				Dirty:       true,
+			},
+		},
+		"leaf with hashed value read error": {
+			reader: bytes.NewReader([]byte{
+				33, // node type 001 (leaf with hashed value) and key length 1
+				9,  // key data
+				7,  // truncated hashed value
+			}),
+			errWrapped: ErrReadHashedValue,
+			errMessage: "cannot decode leaf: cannot read hashed value: unexpected EOF",
+		},
 		"branch decoding error": {
 			reader: bytes.NewReader([]byte{
 				129, // node type 2 (branch without value) and key length 1
@@ -95,6 +121,24 @@ func Test_Decode(t *testing.T) {
 				Dirty: true,
 			},
 		},
+		"branch with hashed value success": {
+			reader: bytes.NewReader(
+				concatByteSlices([][]byte{
+					{
+						17,   // node type 0001 (branch with hashed value) and key length 1
+						9,    // key data
+						0, 0, // no children bitmap
+					},
+					repeatBytes(32, 7), // hashed value
+				}),
+			),
+			n: &Branch{
+				Key:         []byte{9},
+				Value:       repeatBytes(32, 7),
+				HashedValue: true,
+				Dirty:       true,
+			},
+		},
 		"branch with two inlined children": {
 			reader: bytes.NewReader(
 				[]byte{
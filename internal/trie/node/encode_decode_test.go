@@ -7,10 +7,17 @@
 	"bytes"
 	"testing"
 
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func blake2bHash(t *testing.T, b []byte) (hash []byte) {
+	digest, err := common.Blake2bHash(b)
+	require.NoError(t, err)
+	return digest[:]
+}
+
 func Test_Branch_Encode_Decode(t *testing.T) {
 	t.Parallel()
 
@@ -117,7 +124,7 @@ func Test_Branch_Encode_Decode(t *testing.T) {
 
 			buffer := bytes.NewBuffer(nil)
 
-			err := testCase.branchToEncode.Encode(buffer)
+			err := testCase.branchToEncode.Encode(buffer, NoMaxInlineValue)
 			require.NoError(t, err)
 
 			oneBuffer := make([]byte, 1)
@@ -132,3 +139,89 @@ func Test_Branch_Encode_Decode(t *testing.T) {
 		})
 	}
 }
+
+func Test_Encode_Decode_HashedValue(t *testing.T) {
+	t.Parallel()
+
+	const maxInlineValue = 32
+	largeValue := bytes.Repeat([]byte{1}, maxInlineValue+1)
+	largeValueHash := blake2bHash(t, largeValue)
+
+	testCases := map[string]struct {
+		nodeToEncode Node
+		nodeDecoded  Node
+	}{
+		"leaf with inlined value": {
+			nodeToEncode: &Leaf{
+				Key:   []byte{1},
+				Value: largeValue[:maxInlineValue],
+			},
+			nodeDecoded: &Leaf{
+				Key:   []byte{1},
+				Value: largeValue[:maxInlineValue],
+				Dirty: true,
+			},
+		},
+		"leaf with hashed value": {
+			nodeToEncode: &Leaf{
+				Key:   []byte{1},
+				Value: largeValue,
+			},
+			nodeDecoded: &Leaf{
+				Key:         []byte{1},
+				Value:       largeValueHash,
+				HashedValue: true,
+				Dirty:       true,
+			},
+		},
+		"leaf with value already hashed": {
+			nodeToEncode: &Leaf{
+				Key:         []byte{1},
+				Value:       largeValueHash,
+				HashedValue: true,
+			},
+			nodeDecoded: &Leaf{
+				Key:         []byte{1},
+				Value:       largeValueHash,
+				HashedValue: true,
+				Dirty:       true,
+			},
+		},
+		"branch with hashed value": {
+			nodeToEncode: &Branch{
+				Key:   []byte{1},
+				Value: largeValue,
+				Children: [16]Node{
+					&Leaf{Key: []byte{2}, Value: []byte{3}},
+				},
+			},
+			nodeDecoded: &Branch{
+				Key:         []byte{1},
+				Value:       largeValueHash,
+				HashedValue: true,
+				Children: [16]Node{
+					&Leaf{Key: []byte{2}, Value: []byte{3}, Dirty: true},
+				},
+				Descendants: 1,
+				Dirty:       true,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := bytes.NewBuffer(nil)
+
+			err := testCase.nodeToEncode.Encode(buffer, maxInlineValue)
+			require.NoError(t, err)
+
+			decoded, err := Decode(buffer)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.nodeDecoded, decoded)
+		})
+	}
+}
@@ -26,3 +26,13 @@
 // `Extra partial key length` is included if len(key) > 63 and consists of the remaining key length
 // `Partial Key` is the leaf's key
 // `Value` is the leaf's SCALE encoded value
+//
+// Hashed values:
+// Starting with the state trie version 1, values larger than 32 bytes are not inlined in
+// their node. Instead the node header indicates the value is hashed and the `Value` is the
+// 32 bytes blake2b hash of the value, not SCALE encoded. The value itself is stored separately.
+// `NodeHeader` is then a byte such that:
+// most significant three bits of `NodeHeader`: 001 for a leaf with a hashed value,
+// least significant five bits of `NodeHeader`: if len(key) > 30, 0x1f, otherwise len(key)
+// most significant four bits of `NodeHeader`: 0001 for a branch with a hashed value,
+// least significant four bits of `NodeHeader`: if len(key) > 14, 0x0f, otherwise len(key)
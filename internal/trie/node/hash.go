@@ -5,11 +5,27 @@
 
 import (
 	"bytes"
+	"fmt"
+	"hash"
 
 	"github.com/ChainSafe/gossamer/internal/trie/pools"
-	"github.com/ChainSafe/gossamer/lib/common"
 )
 
+// hashEncoding returns the blake2b 256 hash digest of the
+// encoding given, using a hasher from the hashers pool.
+func hashEncoding(encoding []byte) (digest []byte, err error) {
+	hasher := pools.Hashers.Get().(hash.Hash)
+	hasher.Reset()
+	defer pools.Hashers.Put(hasher)
+
+	_, err = hasher.Write(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash encoding: %w", err)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
 // SetEncodingAndHash sets the encoding and hash slices
 // given to the branch. Note it does not copy them, so beware.
 func (b *Branch) SetEncodingAndHash(enc, hash []byte) {
@@ -29,7 +45,8 @@ func (b *Branch) GetHash() []byte {
 // the blake2b hash digest of the encoding of the branch.
 // If the encoding is less than 32 bytes, the hash returned
 // is the encoding and not the hash of the encoding.
-func (b *Branch) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
+// Values larger than maxInlineValue are hashed in the encoding.
+func (b *Branch) EncodeAndHash(isRoot bool, maxInlineValue int) (encoding, hash []byte, err error) {
 	if !b.Dirty && b.Encoding != nil && b.HashDigest != nil {
 		return b.Encoding, b.HashDigest, nil
 	}
@@ -38,7 +55,7 @@ func (b *Branch) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
 	buffer.Reset()
 	defer pools.EncodingBuffers.Put(buffer)
 
-	err = b.Encode(buffer)
+	err = b.Encode(buffer, maxInlineValue)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -57,11 +74,10 @@ func (b *Branch) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
 	}
 
 	// Note: using the sync.Pool's buffer is useful here.
-	hashArray, err := common.Blake2bHash(buffer.Bytes())
+	b.HashDigest, err = hashEncoding(buffer.Bytes())
 	if err != nil {
 		return nil, nil, err
 	}
-	b.HashDigest = hashArray[:]
 	hash = b.HashDigest // no need to copy
 
 	return encoding, hash, nil
@@ -86,7 +102,8 @@ func (l *Leaf) GetHash() []byte {
 // the blake2b hash digest of the encoding of the leaf.
 // If the encoding is less than 32 bytes, the hash returned
 // is the encoding and not the hash of the encoding.
-func (l *Leaf) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
+// Values larger than maxInlineValue are hashed in the encoding.
+func (l *Leaf) EncodeAndHash(isRoot bool, maxInlineValue int) (encoding, hash []byte, err error) {
 	if !l.IsDirty() && l.Encoding != nil && l.HashDigest != nil {
 		return l.Encoding, l.HashDigest, nil
 	}
@@ -95,7 +112,7 @@ func (l *Leaf) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
 	buffer.Reset()
 	defer pools.EncodingBuffers.Put(buffer)
 
-	err = l.Encode(buffer)
+	err = l.Encode(buffer, maxInlineValue)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -116,12 +133,10 @@ func (l *Leaf) EncodeAndHash(isRoot bool) (encoding, hash []byte, err error) {
 	}
 
 	// Note: using the sync.Pool's buffer is useful here.
-	hashArray, err := common.Blake2bHash(buffer.Bytes())
+	l.HashDigest, err = hashEncoding(buffer.Bytes())
 	if err != nil {
 		return nil, nil, err
 	}
-
-	l.HashDigest = hashArray[:]
 	hash = l.HashDigest // no need to copy
 
 	return encoding, hash, nil
@@ -7,8 +7,23 @@
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func Test_hashEncoding(t *testing.T) {
+	t.Parallel()
+
+	digest, err := hashEncoding([]byte{1, 2, 3})
+
+	require.NoError(t, err)
+	expectedDigest := []byte{
+		0x11, 0xc0, 0xe7, 0x9b, 0x71, 0xc3, 0x97, 0x6c,
+		0xcd, 0xc, 0x2, 0xd1, 0x31, 0xe, 0x25, 0x16,
+		0xc0, 0x8e, 0xdc, 0x9d, 0x8b, 0x6f, 0x57, 0xcc,
+		0xd6, 0x80, 0xd6, 0x3a, 0x4d, 0x8e, 0x72, 0xda}
+	assert.Equal(t, expectedDigest, digest)
+}
+
 func Test_Branch_SetEncodingAndHash(t *testing.T) {
 	t.Parallel()
 
@@ -138,7 +153,7 @@ func Test_Branch_EncodeAndHash(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			encoding, hash, err := testCase.branch.EncodeAndHash(testCase.isRoot)
+			encoding, hash, err := testCase.branch.EncodeAndHash(testCase.isRoot, NoMaxInlineValue)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if testCase.errWrapped != nil {
@@ -279,7 +294,7 @@ func Test_Leaf_EncodeAndHash(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			encoding, hash, err := testCase.leaf.EncodeAndHash(testCase.isRoot)
+			encoding, hash, err := testCase.leaf.EncodeAndHash(testCase.isRoot, NoMaxInlineValue)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if testCase.errWrapped != nil {
@@ -7,62 +7,107 @@
 	"io"
 )
 
-const (
-	keyLenOffset    = 0x3f
-	nodeHeaderShift = 6
-)
+const nodeHeaderShift = 6
 
-// encodeHeader creates the encoded header for the branch.
-func (b *Branch) encodeHeader(writer io.Writer) (err error) {
-	var header byte
-	if b.Value == nil {
-		header = byte(BranchType) << nodeHeaderShift
-	} else {
-		header = byte(BranchWithValueType) << nodeHeaderShift
+// variant is a node variant as encoded in the first bits of the node header.
+type variant struct {
+	bits byte
+	mask byte
+}
+
+// Node variants, see the encoding specified at the top of this package.
+var (
+	leafVariant = variant{ // leaf 01
+		bits: 0b0100_0000,
+		mask: 0b1100_0000,
+	}
+	branchVariant = variant{ // branch 10
+		bits: 0b1000_0000,
+		mask: 0b1100_0000,
+	}
+	branchWithValueVariant = variant{ // branch 11
+		bits: 0b1100_0000,
+		mask: 0b1100_0000,
+	}
+	leafWithHashedValueVariant = variant{ // leaf with hashed value 001
+		bits: 0b0010_0000,
+		mask: 0b1110_0000,
 	}
+	branchWithHashedValueVariant = variant{ // branch with hashed value 0001
+		bits: 0b0001_0000,
+		mask: 0b1111_0000,
+	}
+	invalidVariant = variant{}
+)
 
-	if len(b.Key) >= keyLenOffset {
-		header = header | keyLenOffset
-		_, err = writer.Write([]byte{header})
-		if err != nil {
-			return err
-		}
+// variants is the list of node variants, ordered by decreasing mask size
+// so the first variant matching a header byte is the right one.
+var variants = []variant{
+	leafVariant,
+	branchVariant,
+	branchWithValueVariant,
+	leafWithHashedValueVariant,
+	branchWithHashedValueVariant,
+}
 
-		err = encodeKeyLength(len(b.Key), writer)
-		if err != nil {
-			return err
-		}
-	} else {
-		header = header | byte(len(b.Key))
-		_, err = writer.Write([]byte{header})
-		if err != nil {
-			return err
+// partialKeyLengthHeaderMask returns the mask of the bits
+// of the header byte used to encode the partial key length.
+func (v variant) partialKeyLengthHeaderMask() byte {
+	return ^v.mask
+}
+
+// decodeVariant returns the node variant of the header byte given,
+// or the invalid variant if no variant matches.
+func decodeVariant(header byte) variant {
+	for _, v := range variants {
+		if header&v.mask == v.bits {
+			return v
 		}
 	}
+	return invalidVariant
+}
 
-	return nil
+// encodeHeader creates the encoded header for the branch.
+func (b *Branch) encodeHeader(writer io.Writer, maxInlineValue int) (err error) {
+	v := branchVariant
+	switch {
+	case b.Value == nil:
+	case valueIsHashed(b.Value, b.HashedValue, maxInlineValue):
+		v = branchWithHashedValueVariant
+	default:
+		v = branchWithValueVariant
+	}
+
+	return encodeHeader(v, len(b.Key), writer)
 }
 
 // encodeHeader creates the encoded header for the leaf.
-func (l *Leaf) encodeHeader(writer io.Writer) (err error) {
-	header := byte(LeafType) << nodeHeaderShift
+func (l *Leaf) encodeHeader(writer io.Writer, maxInlineValue int) (err error) {
+	v := leafVariant
+	if valueIsHashed(l.Value, l.HashedValue, maxInlineValue) {
+		v = leafWithHashedValueVariant
+	}
 
-	if len(l.Key) < 63 {
-		header |= byte(len(l.Key))
+	return encodeHeader(v, len(l.Key), writer)
+}
+
+// encodeHeader writes the header byte of the node variant given,
+// followed by the extra partial key length bytes if needed.
+func encodeHeader(v variant, partialKeyLength int, writer io.Writer) (err error) {
+	header := v.bits
+	keyLengthMask := int(v.partialKeyLengthHeaderMask())
+
+	if partialKeyLength < keyLengthMask {
+		header |= byte(partialKeyLength)
 		_, err = writer.Write([]byte{header})
 		return err
 	}
 
-	header |= keyLenOffset
+	header |= byte(keyLengthMask)
 	_, err = writer.Write([]byte{header})
 	if err != nil {
 		return err
 	}
 
-	err = encodeKeyLength(len(l.Key), writer)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return encodeKeyLength(partialKeyLength-keyLengthMask, writer)
 }
@@ -4,10 +4,12 @@
 package node
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_Branch_encodeHeader(t *testing.T) {
@@ -120,7 +122,7 @@ func Test_Branch_encodeHeader(t *testing.T) {
 				previousCall = call
 			}
 
-			err := testCase.branch.encodeHeader(writer)
+			err := testCase.branch.encodeHeader(writer, NoMaxInlineValue)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if testCase.errWrapped != nil {
@@ -234,7 +236,7 @@ func Test_Leaf_encodeHeader(t *testing.T) {
 				previousCall = call
 			}
 
-			err := testCase.leaf.encodeHeader(writer)
+			err := testCase.leaf.encodeHeader(writer, NoMaxInlineValue)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if testCase.errWrapped != nil {
@@ -243,3 +245,102 @@ func Test_Leaf_encodeHeader(t *testing.T) {
 		})
 	}
 }
+
+func Test_encodeHeader(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		variant          variant
+		partialKeyLength int
+		encoding         []byte
+	}{
+		"leaf with short key": {
+			variant:          leafVariant,
+			partialKeyLength: 30,
+			encoding:         []byte{0x5e},
+		},
+		"leaf with hashed value and short key": {
+			variant:          leafWithHashedValueVariant,
+			partialKeyLength: 30,
+			encoding:         []byte{0x3e},
+		},
+		"leaf with hashed value and key of length 31": {
+			variant:          leafWithHashedValueVariant,
+			partialKeyLength: 31,
+			encoding:         []byte{0x3f, 0x0},
+		},
+		"branch with hashed value and short key": {
+			variant:          branchWithHashedValueVariant,
+			partialKeyLength: 14,
+			encoding:         []byte{0x1e},
+		},
+		"branch with hashed value and long key": {
+			variant:          branchWithHashedValueVariant,
+			partialKeyLength: 15 + 255 + 1,
+			encoding:         []byte{0x1f, 0xff, 0x1},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			buffer := bytes.NewBuffer(nil)
+
+			err := encodeHeader(testCase.variant, testCase.partialKeyLength, buffer)
+
+			require.NoError(t, err)
+			assert.Equal(t, testCase.encoding, buffer.Bytes())
+		})
+	}
+}
+
+func Test_decodeVariant(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header  byte
+		variant variant
+	}{
+		"empty": {
+			header:  0x00,
+			variant: invalidVariant,
+		},
+		"leaf": {
+			header:  0x7f,
+			variant: leafVariant,
+		},
+		"branch": {
+			header:  0x80,
+			variant: branchVariant,
+		},
+		"branch with value": {
+			header:  0xc1,
+			variant: branchWithValueVariant,
+		},
+		"leaf with hashed value": {
+			header:  0x3f,
+			variant: leafWithHashedValueVariant,
+		},
+		"branch with hashed value": {
+			header:  0x1f,
+			variant: branchWithHashedValueVariant,
+		},
+		"compact encoding escape": {
+			header:  0x01,
+			variant: invalidVariant,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			v := decodeVariant(testCase.header)
+
+			assert.Equal(t, testCase.variant, v)
+		})
+	}
+}
@@ -49,10 +49,9 @@ func (l *Leaf) SetKey(key []byte) {
 	ErrReadKeyData      = errors.New("cannot read key data")
 )
 
-// encodeKeyLength encodes the key length.
+// encodeKeyLength encodes the part of the key length which
+// does not fit in the partial key length bits of the header byte.
 func encodeKeyLength(keyLength int, writer io.Writer) (err error) {
-	keyLength -= 63
-
 	if keyLength >= int(maxPartialKeySize) {
 		return fmt.Errorf("%w: %d",
 			ErrPartialKeyTooBig, keyLength)
@@ -77,12 +76,13 @@ func encodeKeyLength(keyLength int, writer io.Writer) (err error) {
 	return nil
 }
 
-// decodeKey decodes a key from a reader.
-func decodeKey(reader io.Reader, keyLengthByte byte) (b []byte, err error) {
-	keyLength := int(keyLengthByte)
+// decodeKey decodes a key from a reader, given the partial key length bits
+// of the header byte and their mask.
+func decodeKey(reader io.Reader, partialKeyLengthHeader, partialKeyLengthHeaderMask byte) (b []byte, err error) {
+	keyLength := int(partialKeyLengthHeader)
 
-	if keyLengthByte == keyLenOffset {
-		// partial key longer than 63, read next bytes for rest of pk len
+	if partialKeyLengthHeader == partialKeyLengthHeaderMask {
+		// partial key longer than the mask, read next bytes for rest of pk len
 		buffer := pools.SingleByteBuffers.Get().(*bytes.Buffer)
 		defer pools.SingleByteBuffers.Put(buffer)
 		oneByteBuf := buffer.Bytes()
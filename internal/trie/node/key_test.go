@@ -71,7 +71,7 @@ func Test_encodeKeyLength(t *testing.T) {
 		errMessage string
 	}{
 		"length equal to maximum": {
-			keyLength:  int(maxPartialKeySize) + 63,
+			keyLength:  int(maxPartialKeySize),
 			errWrapped: ErrPartialKeyTooBig,
 			errMessage: "partial key length cannot be " +
 				"larger than or equal to 2^16: 65535",
@@ -79,7 +79,7 @@ func Test_encodeKeyLength(t *testing.T) {
 		"zero length": {
 			writes: []writeCall{
 				{
-					written: []byte{0xc1},
+					written: []byte{0x00},
 				},
 			},
 		},
@@ -87,7 +87,7 @@ func Test_encodeKeyLength(t *testing.T) {
 			keyLength: 1,
 			writes: []writeCall{
 				{
-					written: []byte{0xc2},
+					written: []byte{0x01},
 				},
 			},
 		},
@@ -95,7 +95,7 @@ func Test_encodeKeyLength(t *testing.T) {
 			keyLength: 1,
 			writes: []writeCall{
 				{
-					written: []byte{0xc2},
+					written: []byte{0x01},
 					err:     errTest,
 				},
 			},
@@ -103,7 +103,7 @@ func Test_encodeKeyLength(t *testing.T) {
 			errMessage: errTest.Error(),
 		},
 		"error at first byte write": {
-			keyLength: 255 + 100 + 63,
+			keyLength: 255 + 100,
 			writes: []writeCall{
 				{
 					written: []byte{255},
@@ -114,7 +114,7 @@ func Test_encodeKeyLength(t *testing.T) {
 			errMessage: errTest.Error(),
 		},
 		"error at last byte write": {
-			keyLength: 255 + 100 + 63,
+			keyLength: 255 + 100,
 			writes: []writeCall{
 				{
 					written: []byte{255},
@@ -166,7 +166,7 @@ func Test_encodeKeyLength(t *testing.T) {
 		// mock writer since it's too slow, so we use
 		// an actual buffer.
 
-		const keyLength = int(maxPartialKeySize) + 62
+		const keyLength = int(maxPartialKeySize) - 1
 		const expectedEncodingLength = 257
 		expectedBytes := make([]byte, expectedEncodingLength)
 		for i := 0; i < len(expectedBytes)-1; i++ {
@@ -322,7 +322,7 @@ func Test_decodeKey(t *testing.T) {
 				previousCall = call
 			}
 
-			b, err := decodeKey(reader, testCase.keyLength)
+			b, err := decodeKey(reader, testCase.keyLength, 0x3f)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if err != nil {
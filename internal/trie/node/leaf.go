@@ -16,6 +16,11 @@ type Leaf struct {
 	// Partial key bytes in nibbles (0 to f in hexadecimal)
 	Key   []byte
 	Value []byte
+	// HashedValue is true when Value is the blake2b hash of the
+	// actual value, as decoded from a node encoded with a trie
+	// version storing large values by hash. It is reset once the
+	// actual value is loaded from the database or the proof.
+	HashedValue bool
 	// Dirty is true when the leaf differs
 	// from the node stored in the database.
 	Dirty      bool
@@ -38,6 +43,14 @@ func NewLeaf(key, value []byte, dirty bool, generation uint64) *Leaf {
 	}
 }
 
+// IsStub returns true if the leaf is a stub standing for a child node
+// of a decoded branch, which is only known by its hash digest and has
+// to be decoded from the database or a proof to be used.
+func (l *Leaf) IsStub() bool {
+	return !l.Dirty && l.Encoding == nil && l.HashDigest != nil &&
+		l.Key == nil && l.Value == nil
+}
+
 // Type returns LeafType.
 func (l *Leaf) Type() Type {
 	return LeafType
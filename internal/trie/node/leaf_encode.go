@@ -17,7 +17,8 @@
 // Encode encodes a leaf to the buffer given.
 // The encoding has the following format:
 // NodeHeader | Extra partial key length | Partial Key | Value
-func (l *Leaf) Encode(buffer Buffer) (err error) {
+// The value is hashed if it is larger than maxInlineValue.
+func (l *Leaf) Encode(buffer Buffer, maxInlineValue int) (err error) {
 	if !l.Dirty && l.Encoding != nil {
 		_, err = buffer.Write(l.Encoding)
 		if err != nil {
@@ -26,7 +27,7 @@ func (l *Leaf) Encode(buffer Buffer) (err error) {
 		return nil
 	}
 
-	err = l.encodeHeader(buffer)
+	err = l.encodeHeader(buffer, maxInlineValue)
 	if err != nil {
 		return fmt.Errorf("cannot encode header: %w", err)
 	}
@@ -37,14 +38,9 @@ func (l *Leaf) Encode(buffer Buffer) (err error) {
 		return fmt.Errorf("cannot write LE key to buffer: %w", err)
 	}
 
-	encodedValue, err := scale.Marshal(l.Value) // TODO scale encoder to write to buffer
+	err = encodeValue(l.Value, l.HashedValue, maxInlineValue, buffer)
 	if err != nil {
-		return fmt.Errorf("cannot scale marshal value: %w", err)
-	}
-
-	_, err = buffer.Write(encodedValue)
-	if err != nil {
-		return fmt.Errorf("cannot write scale encoded value to buffer: %w", err)
+		return err
 	}
 
 	// TODO remove this copying since it defeats the purpose of `buffer`
@@ -57,12 +53,12 @@ func (l *Leaf) Encode(buffer Buffer) (err error) {
 // ScaleEncodeHash hashes the node (blake2b sum on encoded value)
 // and then SCALE encodes it. This is used to encode children
 // nodes of branches.
-func (l *Leaf) ScaleEncodeHash() (encoding []byte, err error) {
+func (l *Leaf) ScaleEncodeHash(maxInlineValue int) (encoding []byte, err error) {
 	buffer := pools.DigestBuffers.Get().(*bytes.Buffer)
 	buffer.Reset()
 	defer pools.DigestBuffers.Put(buffer)
 
-	err = l.hash(buffer)
+	err = l.hash(buffer, maxInlineValue)
 	if err != nil {
 		return nil, fmt.Errorf("cannot hash leaf: %w", err)
 	}
@@ -74,12 +70,22 @@ func (l *Leaf) ScaleEncodeHash() (encoding []byte, err error) {
 	return scEncChild, nil
 }
 
-func (l *Leaf) hash(writer io.Writer) (err error) {
+func (l *Leaf) hash(writer io.Writer, maxInlineValue int) (err error) {
+	if l.IsStub() {
+		// the hash digest of a stub is the hash of the node it stands
+		// for, or its encoding if the node is inlined in its parent.
+		_, err = writer.Write(l.HashDigest)
+		if err != nil {
+			return fmt.Errorf("cannot write hash digest of stub leaf to buffer: %w", err)
+		}
+		return nil
+	}
+
 	encodingBuffer := pools.EncodingBuffers.Get().(*bytes.Buffer)
 	encodingBuffer.Reset()
 	defer pools.EncodingBuffers.Put(encodingBuffer)
 
-	err = l.Encode(encodingBuffer)
+	err = l.Encode(encodingBuffer, maxInlineValue)
 	if err != nil {
 		return fmt.Errorf("cannot encode leaf: %w", err)
 	}
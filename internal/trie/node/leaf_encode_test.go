@@ -145,7 +145,7 @@ func Test_Leaf_Encode(t *testing.T) {
 				buffer.EXPECT().Bytes().Return(testCase.bufferBytes)
 			}
 
-			err := testCase.leaf.Encode(buffer)
+			err := testCase.leaf.Encode(buffer, NoMaxInlineValue)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -178,7 +178,7 @@ func Test_Leaf_ScaleEncodeHash(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			b, err := testCase.leaf.ScaleEncodeHash()
+			b, err := testCase.leaf.ScaleEncodeHash(NoMaxInlineValue)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -202,6 +202,38 @@ func Test_Leaf_hash(t *testing.T) {
 		wrappedErr error
 		errMessage string
 	}{
+		"stub leaf buffer write error": {
+			leaf: &Leaf{
+				HashDigest: []byte{1, 2, 3},
+			},
+			writeCall: true,
+			write: writeCall{
+				written: []byte{1, 2, 3},
+				err:     errTest,
+			},
+			wrappedErr: errTest,
+			errMessage: "cannot write hash digest of stub leaf to buffer: " +
+				"test error",
+		},
+		"stub leaf success": {
+			leaf: &Leaf{
+				HashDigest: []byte{
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+				},
+			},
+			writeCall: true,
+			write: writeCall{
+				written: []byte{
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+					1, 2, 3, 4, 5, 6, 7, 8,
+				},
+			},
+		},
 		"small leaf buffer write error": {
 			leaf: &Leaf{
 				Encoding: []byte{1, 2, 3},
@@ -283,7 +315,7 @@ func Test_Leaf_hash(t *testing.T) {
 					Return(testCase.write.n, testCase.write.err)
 			}
 
-			err := testCase.leaf.hash(writer)
+			err := testCase.leaf.hash(writer, NoMaxInlineValue)
 
 			if testCase.wrappedErr != nil {
 				assert.ErrorIs(t, err, testCase.wrappedErr)
@@ -33,6 +33,41 @@ func Test_NewLeaf(t *testing.T) {
 	assert.Equal(t, expectedLeaf, leaf)
 }
 
+func Test_Leaf_IsStub(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		leaf *Leaf
+		stub bool
+	}{
+		"empty leaf": {
+			leaf: &Leaf{},
+		},
+		"stub leaf": {
+			leaf: &Leaf{HashDigest: []byte{1}},
+			stub: true,
+		},
+		"dirty leaf": {
+			leaf: &Leaf{HashDigest: []byte{1}, Dirty: true},
+		},
+		"leaf with encoding": {
+			leaf: &Leaf{HashDigest: []byte{1}, Encoding: []byte{2}},
+		},
+		"leaf with key": {
+			leaf: &Leaf{HashDigest: []byte{1}, Key: []byte{2}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.stub, testCase.leaf.IsStub())
+		})
+	}
+}
+
 func Test_Leaf_Type(t *testing.T) {
 	t.Parallel()
 
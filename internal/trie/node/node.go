@@ -7,9 +7,9 @@
 
 // Node is a node in the trie and can be a leaf or a branch.
 type Node interface {
-	Encode(buffer Buffer) (err error) // TODO change to io.Writer
-	EncodeAndHash(isRoot bool) (encoding []byte, hash []byte, err error)
-	ScaleEncodeHash() (encoding []byte, err error)
+	Encode(buffer Buffer, maxInlineValue int) (err error) // TODO change to io.Writer
+	EncodeAndHash(isRoot bool, maxInlineValue int) (encoding []byte, hash []byte, err error)
+	ScaleEncodeHash(maxInlineValue int) (encoding []byte, err error)
 	IsDirty() bool
 	SetDirty(dirty bool)
 	SetKey(key []byte)
@@ -14,6 +14,12 @@ func (b *Branch) AddDescendants(n uint32) {
 }
 
 // SubDescendants subtracts descendant nodes count from the node stats.
+// The count saturates at zero, since the descendants of a branch decoded
+// from a lazy trie are only counted once they are loaded.
 func (b *Branch) SubDescendants(n uint32) {
+	if n > b.Descendants {
+		b.Descendants = 0
+		return
+	}
 	b.Descendants -= n
 }
@@ -57,4 +57,8 @@ func Test_Branch_SubDescendants(t *testing.T) {
 	}
 
 	assert.Equal(t, expected, branch)
+
+	// the count saturates at zero
+	branch.SubDescendants(finalDescendants + 1)
+	assert.Equal(t, uint32(0), branch.Descendants)
 }
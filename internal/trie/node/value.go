@@ -3,6 +3,22 @@
 
 package node
 
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// NoMaxInlineValue is the maximum inline value size to use
+// so values are never hashed, as for the state trie version 0.
+const NoMaxInlineValue = math.MaxInt
+
+// hashedValueLength is the length of a hashed value in a node encoding.
+const hashedValueLength = 32
+
 // GetValue returns the value of the branch.
 // Note it does not copy the byte slice so modifying the returned
 // byte slice will modify the byte slice of the branch.
@@ -16,3 +32,56 @@ func (b *Branch) GetValue() (value []byte) {
 func (l *Leaf) GetValue() (value []byte) {
 	return l.Value
 }
+
+// valueIsHashed returns true if the value is to be encoded by its hash,
+// either because it is already its hash or because it is larger
+// than the maximum inline value size given.
+func valueIsHashed(value []byte, hashedValue bool, maxInlineValue int) bool {
+	return hashedValue || len(value) > maxInlineValue
+}
+
+// encodeValue writes the value to the writer, either SCALE encoded
+// or as its blake2b hash if it is hashed.
+func encodeValue(value []byte, hashedValue bool, maxInlineValue int, writer io.Writer) (err error) {
+	if !valueIsHashed(value, hashedValue, maxInlineValue) {
+		encodedValue, err := scale.Marshal(value) // TODO scale encoder to write to buffer
+		if err != nil {
+			return fmt.Errorf("cannot scale marshal value: %w", err)
+		}
+
+		_, err = writer.Write(encodedValue)
+		if err != nil {
+			return fmt.Errorf("cannot write scale encoded value to buffer: %w", err)
+		}
+		return nil
+	}
+
+	if !hashedValue {
+		hash, err := common.Blake2bHash(value)
+		if err != nil {
+			return fmt.Errorf("cannot hash value: %w", err)
+		}
+		value = hash[:]
+	}
+
+	_, err = writer.Write(value)
+	if err != nil {
+		return fmt.Errorf("cannot write hashed value to buffer: %w", err)
+	}
+	return nil
+}
+
+// EncodingHasHashedValue returns true if the node encoding
+// given stores the value of the node by its hash.
+func EncodingHasHashedValue(encoding []byte) bool {
+	if len(encoding) == 0 {
+		return false
+	}
+
+	switch decodeVariant(encoding[0]) {
+	case leafWithHashedValueVariant, branchWithHashedValueVariant:
+		return true
+	default:
+		return false
+	}
+}
@@ -28,3 +28,39 @@ func Test_Leaf_GetValue(t *testing.T) {
 	value := leaf.GetValue()
 	assert.Equal(t, []byte{2}, value)
 }
+
+func Test_EncodingHasHashedValue(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		encoding    []byte
+		hashedValue bool
+	}{
+		"empty encoding": {},
+		"leaf": {
+			encoding: []byte{0x41, 0x1, 0x4, 0x1},
+		},
+		"branch with value": {
+			encoding: []byte{0xc1, 0x1, 0x0, 0x0, 0x4, 0x1},
+		},
+		"leaf with hashed value": {
+			encoding:    []byte{0x21, 0x1},
+			hashedValue: true,
+		},
+		"branch with hashed value": {
+			encoding:    []byte{0x11, 0x1, 0x0, 0x0},
+			hashedValue: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hashedValue := EncodingHasHashedValue(testCase.encoding)
+
+			assert.Equal(t, testCase.hashedValue, hashedValue)
+		})
+	}
+}
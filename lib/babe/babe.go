@@ -15,6 +15,7 @@
 	"github.com/ChainSafe/gossamer/dot/types"
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
+	"github.com/ChainSafe/gossamer/lib/runtime"
 
 	ethmetrics "github.com/ethereum/go-ethereum/metrics"
 )
@@ -531,6 +532,11 @@ func (b *Service) handleSlot(epoch, slotNum uint64,
 		return err
 	}
 
+	err = runtime.SetStorageStateVersion(rt, ts)
+	if err != nil {
+		return fmt.Errorf("cannot set state version: %w", err)
+	}
+
 	rt.SetContextStorage(ts)
 
 	block, err := b.buildBlock(parent, currentSlot, rt, authorityIndex, preRuntimeDigest)
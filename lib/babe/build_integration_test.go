@@ -211,7 +211,7 @@ func TestApplyExtrinsic(t *testing.T) {
 
 	extHex := runtime.NewTestExtrinsic(t, rt, parentHash, parentHash, 0, "System.remark", []byte{0xab, 0xcd})
 	extBytes := common.MustHexToBytes(extHex)
-	_, err = rt.ValidateTransaction(append([]byte{byte(types.TxnExternal)}, extBytes...))
+	_, err = rt.ValidateTransaction(types.TxnExternal, extBytes, parentHash)
 	require.NoError(t, err)
 
 	digest2 := types.NewDigest()
@@ -300,7 +300,7 @@ func TestBuildAndApplyExtrinsic(t *testing.T) {
 	encoder := cscale.NewEncoder(&extEnc)
 	ext.Encode(*encoder)
 
-	txVal, err := rt.ValidateTransaction(append([]byte{byte(types.TxnLocal)}, extEnc.Bytes()...))
+	txVal, err := rt.ValidateTransaction(types.TxnLocal, extEnc.Bytes(), common.Hash(genHash))
 	require.NoError(t, err)
 
 	vtx := transaction.NewValidTransaction(extEnc.Bytes(), txVal)
@@ -405,12 +405,20 @@ func (bt *BlockTree) DeepCopy() *BlockTree {
 	return btCopy
 }
 
-// StoreRuntime stores the runtime for corresponding block hash.
+// StoreRuntime stores the runtime for corresponding block hash. The runtime instance
+// is added to the runtime instance pool of its code hash and heap pages.
 func (bt *BlockTree) StoreRuntime(hash common.Hash, in runtime.Instance) {
 	bt.runtimes.set(hash, in)
 }
 
-// GetBlockRuntime returns block runtime for corresponding block hash.
+// SetRuntimePoolSize sets the maximum number of runtime instances of each
+// runtime pool created, where 0 means runtime.DefaultPoolSize.
+func (bt *BlockTree) SetRuntimePoolSize(size uint) {
+	bt.runtimes.setPoolSize(size)
+}
+
+// GetBlockRuntime returns a handle on the runtime instance pool for the corresponding block hash.
+// Runtime calls made with different handles can run concurrently on different instances.
 func (bt *BlockTree) GetBlockRuntime(hash common.Hash) (runtime.Instance, error) {
 	ins := bt.runtimes.get(hash)
 	if ins == nil {
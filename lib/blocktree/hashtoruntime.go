@@ -6,34 +6,133 @@
 import (
 	"sync"
 
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 )
 
+// poolKey identifies the runtime instances which can be shared between blocks.
+type poolKey struct {
+	codeHash  common.Hash
+	heapPages uint64
+}
+
+// runtimePool is a pool of runtime instances used by one or more blocks.
+type runtimePool struct {
+	key        poolKey
+	pool       *runtime.Pool
+	references uint
+}
+
 type hashToRuntime struct {
 	mutex   sync.RWMutex
-	mapping map[Hash]runtime.Instance
+	mapping map[Hash]*runtimePool
+	pools   map[poolKey]*runtimePool
+	// runtimePools maps each runtime pool in use to its single runtimePool,
+	// so the references to a pool are all counted in the same place.
+	runtimePools map[*runtime.Pool]*runtimePool
+	// poolSize is the maximum number of instances of each
+	// runtime pool, where 0 means runtime.DefaultPoolSize.
+	poolSize uint
 }
 
 func newHashToRuntime() *hashToRuntime {
 	return &hashToRuntime{
-		mapping: make(map[Hash]runtime.Instance),
+		mapping:      make(map[Hash]*runtimePool),
+		pools:        make(map[poolKey]*runtimePool),
+		runtimePools: make(map[*runtime.Pool]*runtimePool),
 	}
 }
 
+// get returns a new handle on the runtime instance pool of the block hash,
+// or nil if there is no runtime for the block hash.
 func (h *hashToRuntime) get(hash Hash) (instance runtime.Instance) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
-	return h.mapping[hash]
+	rp, ok := h.mapping[hash]
+	if !ok {
+		return nil
+	}
+	return rp.pool.Instance()
 }
 
+// set sets the runtime of the block hash. If the instance is a pooled instance,
+// its pool is used for the block hash, unless the pool is no longer in use in which
+// case the pool of instances with the same code hash and heap pages is used if any.
+// Otherwise the instance is added to the pool of instances with the same code hash
+// and heap pages, created if needed.
 func (h *hashToRuntime) set(hash Hash, instance runtime.Instance) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
-	h.mapping[hash] = instance
+
+	key := poolKey{
+		codeHash:  instance.GetCodeHash(),
+		heapPages: instance.GetHeapPages(),
+	}
+
+	var rp *runtimePool
+	if pooled, ok := instance.(*runtime.PooledInstance); ok {
+		rp = h.runtimePools[pooled.Pool()]
+		if rp == nil {
+			rp = h.pools[key]
+		}
+		if rp == nil {
+			rp = h.newRuntimePool(key, pooled.Pool())
+		}
+	} else {
+		rp = h.pools[key]
+		if rp == nil {
+			rp = h.newRuntimePool(key, runtime.NewPool(instance, h.poolSize))
+		} else {
+			rp.pool.Add(instance)
+		}
+	}
+
+	// the reference is added before removing the previous mapping,
+	// so the pool is not stopped if the block hash already uses it.
+	rp.references++
+	h.deleteMapping(hash)
+	h.mapping[hash] = rp
+}
+
+// newRuntimePool registers the runtime pool given as the pool of instances
+// with the pool key given. It must be called with the mutex locked.
+func (h *hashToRuntime) newRuntimePool(key poolKey, pool *runtime.Pool) (rp *runtimePool) {
+	rp = &runtimePool{key: key, pool: pool}
+	h.pools[key] = rp
+	h.runtimePools[pool] = rp
+	return rp
 }
 
 func (h *hashToRuntime) delete(hash Hash) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
+	h.deleteMapping(hash)
+}
+
+// deleteMapping removes the block hash mapping, and removes and stops its runtime
+// pool if no other block hash uses it. It must be called with the mutex locked.
+func (h *hashToRuntime) deleteMapping(hash Hash) {
+	rp, ok := h.mapping[hash]
+	if !ok {
+		return
+	}
+
 	delete(h.mapping, hash)
+	rp.references--
+	if rp.references > 0 {
+		return
+	}
+
+	if h.pools[rp.key] == rp {
+		delete(h.pools, rp.key)
+	}
+	delete(h.runtimePools, rp.pool)
+	rp.pool.Stop()
+}
+
+// setPoolSize sets the maximum number of instances of the runtime pools created.
+func (h *hashToRuntime) setPoolSize(size uint) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.poolSize = size
 }
@@ -11,7 +11,9 @@
 
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_newHashToRuntime(t *testing.T) {
@@ -20,145 +22,129 @@ func Test_newHashToRuntime(t *testing.T) {
 	hti := newHashToRuntime()
 
 	expected := &hashToRuntime{
-		mapping: make(map[Hash]runtime.Instance),
+		mapping:      make(map[Hash]*runtimePool),
+		pools:        make(map[poolKey]*runtimePool),
+		runtimePools: make(map[*runtime.Pool]*runtimePool),
 	}
 	assert.Equal(t, expected, hti)
 }
 
 //go:generate mockgen -destination=mock_instance_test.go -package $GOPACKAGE github.com/ChainSafe/gossamer/lib/runtime Instance
 
+// newTestInstance returns a mock runtime instance with the given code hash and heap pages.
+func newTestInstance(ctrl *gomock.Controller, codeHash common.Hash, heapPages uint64) *MockInstance {
+	instance := NewMockInstance(ctrl)
+	instance.EXPECT().GetCodeHash().Return(codeHash).AnyTimes()
+	instance.EXPECT().GetHeapPages().Return(heapPages).AnyTimes()
+	return instance
+}
+
 func Test_hashToRuntime_get(t *testing.T) {
 	t.Parallel()
 
-	testCases := map[string]struct {
-		htr      *hashToRuntime
-		hash     Hash
-		instance runtime.Instance
-	}{
-		"hash does not exist": {
-			htr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{4, 5, 6}: NewMockInstance(nil),
-				},
-			},
-			hash: common.Hash{1, 2, 3},
-		},
-		"hash exists": {
-			htr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{1, 2, 3}: NewMockInstance(nil),
-				},
-			},
-			hash:     common.Hash{1, 2, 3},
-			instance: NewMockInstance(nil),
+	ctrl := gomock.NewController(t)
+	pool := runtime.NewPool(NewMockInstance(ctrl), 1)
+	rp := &runtimePool{pool: pool, references: 1}
+
+	htr := &hashToRuntime{
+		mapping: map[Hash]*runtimePool{
+			{1, 2, 3}: rp,
 		},
 	}
 
-	for name, testCase := range testCases {
-		testCase := testCase
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+	instance := htr.get(common.Hash{4, 5, 6})
+	assert.Nil(t, instance)
 
-			instance := testCase.htr.get(testCase.hash)
+	instance = htr.get(common.Hash{1, 2, 3})
+	require.IsType(t, &runtime.PooledInstance{}, instance)
+	assert.Same(t, pool, instance.(*runtime.PooledInstance).Pool())
 
-			assert.Equal(t, testCase.instance, instance)
-		})
-	}
+	// each call returns a different handle on the pool
+	other := htr.get(common.Hash{1, 2, 3})
+	assert.NotSame(t, instance, other)
 }
 
 func Test_hashToRuntime_set(t *testing.T) {
 	t.Parallel()
 
-	testCases := map[string]struct {
-		initialHtr  *hashToRuntime
-		hash        Hash
-		instance    runtime.Instance
-		expectedHtr *hashToRuntime
-	}{
-		"set new instance": {
-			initialHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{},
-			},
-			hash:     common.Hash{1, 2, 3},
-			instance: NewMockInstance(nil),
-			expectedHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{1, 2, 3}: NewMockInstance(nil),
-				},
-			},
-		},
-		"override instance": {
-			initialHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{1, 2, 3}: NewMockInstance(nil),
-				},
-			},
-			hash:     common.Hash{1, 2, 3},
-			instance: nil,
-			expectedHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{1, 2, 3}: nil,
-				},
-			},
-		},
-	}
+	ctrl := gomock.NewController(t)
 
-	for name, testCase := range testCases {
-		testCase := testCase
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+	key := poolKey{codeHash: common.Hash{1}, heapPages: 2048}
+	otherKey := poolKey{codeHash: common.Hash{2}, heapPages: 2048}
 
-			htr := testCase.initialHtr
-
-			htr.set(testCase.hash, testCase.instance)
-
-			assert.Equal(t, testCase.expectedHtr, htr)
-		})
-	}
+	htr := newHashToRuntime()
+	htr.setPoolSize(1)
+
+	// first instance creates a pool
+	first := newTestInstance(ctrl, key.codeHash, key.heapPages)
+	htr.set(common.Hash{1}, first)
+	rp := htr.pools[key]
+	require.NotNil(t, rp)
+	assert.Equal(t, key, rp.key)
+	assert.Equal(t, uint(1), rp.references)
+	assert.Same(t, rp, htr.mapping[common.Hash{1}])
+
+	// an instance with the same code hash and heap pages is added to the pool,
+	// and stopped since the pool of a non cloner instance is full.
+	second := newTestInstance(ctrl, key.codeHash, key.heapPages)
+	second.EXPECT().Stop()
+	htr.set(common.Hash{2}, second)
+	assert.Same(t, rp, htr.mapping[common.Hash{2}])
+	assert.Equal(t, uint(2), rp.references)
+
+	// a pooled instance uses its pool
+	htr.set(common.Hash{3}, htr.get(common.Hash{1}))
+	assert.Same(t, rp, htr.mapping[common.Hash{3}])
+	assert.Equal(t, uint(3), rp.references)
+
+	// overriding the runtime of a block hash releases its previous pool
+	other := newTestInstance(ctrl, otherKey.codeHash, otherKey.heapPages)
+	htr.set(common.Hash{3}, other)
+	otherRp := htr.pools[otherKey]
+	require.NotNil(t, otherRp)
+	assert.Same(t, otherRp, htr.mapping[common.Hash{3}])
+	assert.Equal(t, uint(1), otherRp.references)
+	assert.Equal(t, uint(2), rp.references)
+	assert.Len(t, htr.pools, 2)
+
+	// setting the same runtime again for a block hash keeps its pool
+	htr.set(common.Hash{3}, htr.get(common.Hash{3}))
+	assert.Same(t, otherRp, htr.mapping[common.Hash{3}])
+	assert.Equal(t, uint(1), otherRp.references)
 }
 
 func Test_hashToRuntime_delete(t *testing.T) {
 	t.Parallel()
 
-	testCases := map[string]struct {
-		initialHtr  *hashToRuntime
-		hash        common.Hash
-		expectedHtr *hashToRuntime
-	}{
-		"hash does not exist": {
-			initialHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{},
-			},
-			hash: common.Hash{1, 2, 3},
-			expectedHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{},
-			},
-		},
-		"hash deleted": {
-			initialHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{
-					{1, 2, 3}: NewMockInstance(nil),
-				},
-			},
-			hash: common.Hash{1, 2, 3},
-			expectedHtr: &hashToRuntime{
-				mapping: map[Hash]runtime.Instance{},
-			},
-		},
-	}
-
-	for name, testCase := range testCases {
-		testCase := testCase
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+	ctrl := gomock.NewController(t)
 
-			htr := testCase.initialHtr
-
-			htr.delete(testCase.hash)
-
-			assert.Equal(t, testCase.expectedHtr, htr)
-		})
-	}
+	key := poolKey{codeHash: common.Hash{1}, heapPages: 2048}
+	htr := newHashToRuntime()
+	instance := newTestInstance(ctrl, key.codeHash, key.heapPages)
+	htr.set(common.Hash{1}, instance)
+	handle := htr.get(common.Hash{1})
+	htr.set(common.Hash{2}, handle)
+
+	htr.delete(common.Hash{3})
+	assert.Len(t, htr.mapping, 2)
+
+	htr.delete(common.Hash{1})
+	assert.Len(t, htr.mapping, 1)
+	require.Contains(t, htr.pools, key)
+	assert.Equal(t, uint(1), htr.pools[key].references)
+
+	// the pool is stopped once no block hash uses it
+	instance.EXPECT().Stop()
+	htr.delete(common.Hash{2})
+	assert.Empty(t, htr.mapping)
+	assert.Empty(t, htr.pools)
+	assert.Empty(t, htr.runtimePools)
+
+	// a handle on the stopped pool uses the pool with the same key
+	other := newTestInstance(ctrl, key.codeHash, key.heapPages)
+	htr.set(common.Hash{1}, other)
+	htr.set(common.Hash{2}, handle)
+	assert.Same(t, htr.mapping[common.Hash{1}], htr.mapping[common.Hash{2}])
 }
 
 func Test_hashToRuntime_threadSafety(t *testing.T) {
@@ -193,9 +179,11 @@ func Test_hashToRuntime_threadSafety(t *testing.T) {
 		}
 	}
 
+	ctrl := gomock.NewController(t)
 	htr := newHashToRuntime()
 	hash := common.Hash{1, 2, 3}
-	instance := NewMockInstance(nil)
+	instance := newTestInstance(ctrl, common.Hash{1}, 2048)
+	instance.EXPECT().Stop().AnyTimes()
 
 	for i := 0; i < parallelism; i++ {
 		go runInLoop(func() {
@@ -5,8 +5,8 @@
 
 import (
 	"errors"
+	"runtime"
 	"sync"
-	"time"
 
 	"github.com/ChainSafe/gossamer/internal/log"
 )
@@ -16,7 +16,7 @@
 // SigVerifyFunc verifies a signature given a public key and a message
 type SigVerifyFunc func(pubkey, sig, msg []byte) (err error)
 
-// SignatureInfo ...
+// SignatureInfo holds a signature to verify along with its public key, message and verification function
 type SignatureInfo struct {
 	PubKey     []byte
 	Sign       []byte
@@ -24,15 +24,16 @@ type SignatureInfo struct {
 	VerifyFunc SigVerifyFunc
 }
 
-// SignatureVerifier ...
+// SignatureVerifier verifies batches of signatures in the background, using a pool of workers.
 type SignatureVerifier struct {
-	batch   []*SignatureInfo
-	init    bool // Indicates whether the batch processing is started.
-	invalid bool // Set to true if any signature verification fails.
-	logger  log.LeveledLogger
-	closeCh chan struct{}
+	batch    []*SignatureInfo
+	init     bool // Indicates whether the batch processing is started.
+	invalid  bool // Set to true if any signature verification fails.
+	finished bool // Set to true once Finish is called, the workers exit when the batch is empty.
+	workers  int
+	logger   log.LeveledLogger
+	cond     *sync.Cond
 	sync.RWMutex
-	sync.Once
 	sync.WaitGroup
 }
 
@@ -41,77 +42,109 @@ type SignatureVerifier struct {
 // Finish() is called to stop the verification process.
 // Signatures can be added to the batch using Add().
 func NewSignatureVerifier(logger log.LeveledLogger) *SignatureVerifier {
-	return &SignatureVerifier{
+	sv := &SignatureVerifier{
 		batch:   make([]*SignatureInfo, 0),
-		init:    false,
-		invalid: false,
+		workers: runtime.NumCPU(),
 		logger:  logger,
-		RWMutex: sync.RWMutex{},
-		closeCh: make(chan struct{}),
 	}
+	sv.cond = sync.NewCond(&sv.RWMutex)
+	return sv
 }
 
 // Start signature verification in batch.
 func (sv *SignatureVerifier) Start() {
-	// Update the init state.
 	sv.Lock()
+	defer sv.Unlock()
+
+	if sv.init {
+		sv.logger.Error("[ext_crypto_start_batch_verify_version_1]: batch verification already started")
+		return
+	}
+
 	sv.init = true
-	sv.Unlock()
+	sv.WaitGroup.Add(sv.workers)
+	for i := 0; i < sv.workers; i++ {
+		go sv.work()
+	}
+}
 
-	sv.WaitGroup.Add(1)
-
-	go func() {
-		defer sv.Done()
-		for {
-			select {
-			case <-sv.closeCh:
-				return
-			default:
-				signature := sv.Remove()
-				if signature == nil {
-					continue
-				}
-				err := signature.VerifyFunc(signature.PubKey, signature.Sign, signature.Msg)
-				if err != nil {
-					sv.logger.Errorf("[ext_crypto_start_batch_verify_version_1]: %s", err)
-					sv.Invalid()
-					return
-				}
-			}
+// work verifies the signatures of the batch until it is empty and Finish is called,
+// or until a signature fails to verify.
+func (sv *SignatureVerifier) work() {
+	defer sv.Done()
+	for {
+		signature := sv.next()
+		if signature == nil {
+			return
 		}
-	}()
+
+		err := signature.VerifyFunc(signature.PubKey, signature.Sign, signature.Msg)
+		if err != nil {
+			sv.logger.Errorf("[ext_crypto_start_batch_verify_version_1]: %s", err)
+			sv.Invalid()
+			return
+		}
+	}
 }
 
-// IsStarted ...
+// next waits for the next signature of the batch, it returns nil if there is none left to verify.
+func (sv *SignatureVerifier) next() *SignatureInfo {
+	sv.Lock()
+	defer sv.Unlock()
+
+	for len(sv.batch) == 0 && !sv.finished && !sv.invalid {
+		sv.cond.Wait()
+	}
+
+	if sv.invalid || len(sv.batch) == 0 {
+		return nil
+	}
+
+	signature := sv.batch[0]
+	sv.batch = sv.batch[1:]
+	return signature
+}
+
+// IsStarted returns whether batch verification is started
 func (sv *SignatureVerifier) IsStarted() bool {
 	sv.RLock()
 	defer sv.RUnlock()
 	return sv.init
 }
 
-// IsInvalid ...
+// IsInvalid returns whether a signature of the batch failed to verify
 func (sv *SignatureVerifier) IsInvalid() bool {
 	sv.RLock()
 	defer sv.RUnlock()
 	return sv.invalid
 }
 
-// Invalid ...
+// Invalid marks the batch as invalid, which stops its verification
 func (sv *SignatureVerifier) Invalid() {
-	sv.RLock()
-	defer sv.RUnlock()
+	sv.Lock()
+	defer sv.Unlock()
 	sv.invalid = true
+	sv.batch = nil
+	sv.cond.Broadcast()
 }
 
-// Add ...
+// Add adds a signature to the batch. The signature, public key and message are copied,
+// since they usually point into the runtime memory which may be reused before verification.
 func (sv *SignatureVerifier) Add(s *SignatureInfo) {
-	if sv.IsInvalid() {
+	sv.Lock()
+	defer sv.Unlock()
+
+	if sv.invalid {
 		return
 	}
 
-	sv.Lock()
-	defer sv.Unlock()
-	sv.batch = append(sv.batch, s)
+	sv.batch = append(sv.batch, &SignatureInfo{
+		PubKey:     append([]byte{}, s.PubKey...),
+		Sign:       append([]byte{}, s.Sign...),
+		Msg:        append([]byte{}, s.Msg...),
+		VerifyFunc: s.VerifyFunc,
+	})
+	sv.cond.Signal()
 }
 
 // Remove returns the first signature from the batch. Returns nil if batch is empty.
@@ -122,7 +155,7 @@ func (sv *SignatureVerifier) Remove() *SignatureInfo {
 		return nil
 	}
 	sign := sv.batch[0]
-	sv.batch = sv.batch[1:len(sv.batch)]
+	sv.batch = sv.batch[1:]
 	return sign
 }
 
@@ -133,22 +166,24 @@ func (sv *SignatureVerifier) Reset() {
 	sv.init = false
 	sv.batch = make([]*SignatureInfo, 0)
 	sv.invalid = false
-	sv.closeCh = make(chan struct{})
+	sv.finished = false
 }
 
 // Finish waits till batch is finished. Returns true if all the signatures are valid, Otherwise returns false.
+// The verifier is then reset, so a new batch can be started.
 func (sv *SignatureVerifier) Finish() bool {
-	for {
-		time.Sleep(100 * time.Millisecond)
-		sv.Lock()
-		if sv.invalid || len(sv.batch) == 0 {
-			close(sv.closeCh)
-			sv.Unlock()
-			break
-		}
-		sv.RUnlock()
+	sv.Lock()
+	if !sv.init {
+		sv.Unlock()
+		sv.logger.Error("[ext_crypto_finish_batch_verify_version_1]: batch verification not started")
+		return false
 	}
-	// Wait till start function to finish and then reset it.
+
+	sv.finished = true
+	sv.cond.Broadcast()
+	sv.Unlock()
+
+	// Wait till the workers have verified the batch and then reset it.
 	sv.Wait()
 	isInvalid := sv.IsInvalid()
 	sv.Reset()
@@ -124,3 +124,48 @@ func TestVerifySignature(t *testing.T) {
 	}
 
 }
+
+func TestSignatureVerifier_ManySignatures(t *testing.T) {
+	t.Parallel()
+
+	keypair, err := sr25519.GenerateKeypair()
+	require.NoError(t, err)
+
+	signVerify := crypto.NewSignatureVerifier(log.New(log.SetWriter(io.Discard)))
+
+	// the verifier is reused for each batch
+	for _, invalidIndex := range []int{-1, 99, -1} {
+		signVerify.Start()
+
+		for i := 0; i < 100; i++ {
+			message := []byte{byte(i)}
+			sig, err := keypair.Sign(message)
+			require.NoError(t, err)
+
+			if i == invalidIndex {
+				message = []byte("other message")
+			}
+
+			// the input is copied, so it can be modified once added
+			info := &crypto.SignatureInfo{
+				PubKey:     keypair.Public().Encode(),
+				Sign:       sig,
+				Msg:        message,
+				VerifyFunc: sr25519.VerifySignatureDeprecated,
+			}
+			signVerify.Add(info)
+			info.Sign[0]++
+		}
+
+		ok := signVerify.Finish()
+		require.Equal(t, invalidIndex == -1, ok)
+		require.False(t, signVerify.IsStarted())
+	}
+}
+
+func TestSignatureVerifier_FinishNotStarted(t *testing.T) {
+	t.Parallel()
+
+	signVerify := crypto.NewSignatureVerifier(log.New(log.SetWriter(io.Discard)))
+	require.False(t, signVerify.Finish())
+}
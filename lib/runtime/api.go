@@ -0,0 +1,354 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/transaction"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Names of the runtime APIs, whose versions are listed in the API items of the runtime version.
+const (
+	CoreAPI                   = "Core"
+	BlockBuilderAPI           = "BlockBuilder"
+	TaggedTransactionQueueAPI = "TaggedTransactionQueue"
+	OffchainWorkerAPI         = "OffchainWorkerApi"
+	SessionKeysAPI            = "SessionKeys"
+	TransactionPaymentAPI     = "TransactionPaymentApi"
+)
+
+// ErrAPINotImplemented is returned when calling a runtime API the runtime does not implement
+var ErrAPINotImplemented = errors.New("runtime API not implemented")
+
+// APIID returns the identifier of the runtime API with the given name,
+// which is the 64-bit blake2b hash of the name.
+func APIID(name string) (id [8]byte) {
+	h, err := blake2b.New(len(id), nil)
+	if err != nil {
+		panic(err) // only fails for an invalid size or key
+	}
+	_, _ = h.Write([]byte(name))
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// APIVersion returns the version of the runtime API with the given name found
+// in the API items given, and false if the runtime does not implement the API.
+func APIVersion(apiItems []APIItem, name string) (version uint32, ok bool) {
+	id := APIID(name)
+	for _, apiItem := range apiItems {
+		if apiItem.Name == id {
+			return apiItem.Ver, true
+		}
+	}
+	return 0, false
+}
+
+// requireAPIVersion returns the version of the runtime API with the given name,
+// and an error if the runtime does not implement the API.
+func requireAPIVersion(apiItems []APIItem, name string) (version uint32, err error) {
+	version, ok := APIVersion(apiItems, name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrAPINotImplemented, name)
+	}
+	return version, nil
+}
+
+// DecodeVersion decodes the runtime version returned by Core_version. The
+// transaction version is only encoded from version 3 of the Core API, and the
+// state version from version 4. If the runtime does not list the Core API, the
+// transaction version is decoded if present.
+func DecodeVersion(encoded []byte) (Version, error) {
+	var info legacyVersionData
+	decoder := scale.NewDecoder(bytes.NewReader(encoded))
+	err := decoder.Decode(&info)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := NewLegacyVersionData(info.SpecName, info.ImplName, info.AuthoringVersion,
+		info.SpecVersion, info.ImplVersion, info.APIItems)
+
+	coreVersion, ok := APIVersion(info.APIItems, CoreAPI)
+	if ok && coreVersion < 3 {
+		return legacy, nil
+	}
+
+	var transactionVersion uint32
+	err = decoder.Decode(&transactionVersion)
+	if !ok && errors.Is(err, io.EOF) {
+		return legacy, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot decode transaction version: %w", err)
+	}
+
+	version := NewVersionData(info.SpecName, info.ImplName, info.AuthoringVersion,
+		info.SpecVersion, info.ImplVersion, info.APIItems, transactionVersion)
+	if coreVersion >= coreVersionWithStateVersion {
+		var stateVersion uint8
+		err = decoder.Decode(&stateVersion)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode state version: %w", err)
+		}
+		version.SetStateVersion(stateVersion)
+	}
+
+	return version, nil
+}
+
+// InitializeBlockFunction returns the exported runtime function initialising a
+// block, which is named Core_initialise_block before version 2 of the Core API.
+func InitializeBlockFunction(apiItems []APIItem) (function string, err error) {
+	version, err := requireAPIVersion(apiItems, CoreAPI)
+	if err != nil {
+		return "", err
+	}
+
+	if version < 2 {
+		return "Core_initialise_block", nil
+	}
+	return CoreInitializeBlock, nil
+}
+
+// FinalizeBlockFunction returns the exported runtime function finalising a block, which
+// is named BlockBuilder_finalise_block before version 3 of the BlockBuilder API.
+func FinalizeBlockFunction(apiItems []APIItem) (function string, err error) {
+	version, err := requireAPIVersion(apiItems, BlockBuilderAPI)
+	if err != nil {
+		return "", err
+	}
+
+	if version < 3 {
+		return "BlockBuilder_finalise_block", nil
+	}
+	return BlockBuilderFinalizeBlock, nil
+}
+
+// EncodeValidateTransactionArgs encodes the arguments of TaggedTransactionQueue_validate_transaction.
+// Version 1 of the API only takes the transaction, version 2 takes the source of the transaction
+// before it, and version 3 also takes the hash of the block the transaction is validated at.
+func EncodeValidateTransactionArgs(apiItems []APIItem, source types.TransactionSource,
+	tx types.Extrinsic, blockHash common.Hash) ([]byte, error) {
+	version, err := requireAPIVersion(apiItems, TaggedTransactionQueueAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case version < 2:
+		return tx, nil
+	case version < 3:
+		return append([]byte{byte(source)}, tx...), nil
+	default:
+		args := make([]byte, 0, 1+len(tx)+len(blockHash))
+		args = append(args, byte(source))
+		args = append(args, tx...)
+		return append(args, blockHash[:]...), nil
+	}
+}
+
+// DecodeValidateTransactionResult decodes the transaction validity returned by
+// TaggedTransactionQueue_validate_transaction, or the validity error.
+func DecodeValidateTransactionResult(result []byte) (*transaction.Validity, error) {
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: empty result", ErrCannotValidateTx)
+	}
+
+	if result[0] != 0 {
+		return nil, NewValidateTransactionError(result)
+	}
+
+	validity := transaction.NewValidity(0, [][]byte{{}}, [][]byte{{}}, 0, false)
+	err := scale.Unmarshal(result[1:], validity)
+	return validity, err
+}
+
+// EncodeOffchainWorkerArgs encodes the arguments of OffchainWorkerApi_offchain_worker, which
+// is the block number for version 1 of the API, and the block header from version 2.
+func EncodeOffchainWorkerArgs(apiItems []APIItem, header *types.Header) ([]byte, error) {
+	version, err := requireAPIVersion(apiItems, OffchainWorkerAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < 2 {
+		return scale.Marshal(uint32(header.Number))
+	}
+	return scale.Marshal(*header)
+}
+
+// CheckDecodeSessionKeys returns an error if the runtime does not implement the
+// SessionKeys API, and so cannot decode session keys.
+func CheckDecodeSessionKeys(apiItems []APIItem) error {
+	_, err := requireAPIVersion(apiItems, SessionKeysAPI)
+	return err
+}
+
+// EncodePaymentQueryInfoArgs encodes the arguments of TransactionPaymentApi_query_info,
+// which are the extrinsic followed by its length.
+func EncodePaymentQueryInfoArgs(ext []byte) ([]byte, error) {
+	encLen, err := scale.Marshal(uint32(len(ext)))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]byte, 0, len(ext)+len(encLen))
+	args = append(args, ext...)
+	return append(args, encLen...), nil
+}
+
+// dispatchInfo is the dispatch info returned from version 2 of the TransactionPaymentApi,
+// where the weight is made of a compact encoded reference time and proof size.
+type dispatchInfo struct {
+	RefTime    uint
+	ProofSize  uint
+	Class      uint8
+	PartialFee *scale.Uint128
+}
+
+// DecodePaymentQueryInfo decodes the dispatch info returned by TransactionPaymentApi_query_info.
+// The weight is a 64-bit integer before version 2 of the API, and its reference time from version 2.
+func DecodePaymentQueryInfo(apiItems []APIItem, encoded []byte) (*types.TransactionPaymentQueryInfo, error) {
+	version, err := requireAPIVersion(apiItems, TransactionPaymentAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < 2 {
+		info := new(types.TransactionPaymentQueryInfo)
+		err = scale.Unmarshal(encoded, info)
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+
+	var info dispatchInfo
+	err = scale.Unmarshal(encoded, &info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TransactionPaymentQueryInfo{
+		Weight:     uint64(info.RefTime),
+		Class:      int(info.Class),
+		PartialFee: info.PartialFee,
+	}, nil
+}
+
+// CheckInherentsResult is the result of BlockBuilder_check_inherents
+type CheckInherentsResult struct {
+	// Okay is true if all the inherents of the block are valid.
+	Okay bool
+	// FatalError is true if one of the errors is fatal, in which case the block is invalid.
+	FatalError bool
+	// Errors are the SCALE encoded errors of the invalid inherents, keyed by inherent identifier.
+	Errors map[[8]byte][]byte
+}
+
+func (r *CheckInherentsResult) String() string {
+	identifiers := make([]string, 0, len(r.Errors))
+	for identifier := range r.Errors {
+		identifiers = append(identifiers, string(identifier[:]))
+	}
+	sort.Strings(identifiers)
+
+	errs := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		var key [8]byte
+		copy(key[:], identifier)
+		errs[i] = fmt.Sprintf("%s: %s", identifier, common.BytesToHex(r.Errors[key]))
+	}
+
+	return fmt.Sprintf("okay=%t fatal=%t errors=[%s]", r.Okay, r.FatalError, strings.Join(errs, ", "))
+}
+
+// EncodeCheckInherentsArgs encodes the arguments of BlockBuilder_check_inherents, which are
+// the block without its seal digest followed by the SCALE encoded inherent data.
+func EncodeCheckInherentsArgs(block *types.Block, inherentData []byte) ([]byte, error) {
+	unsealed := types.Block{
+		Header: block.Header,
+		Body:   block.Body,
+	}
+	unsealed.Header.Digest = types.NewDigest()
+	for _, item := range block.Header.Digest.Types {
+		if _, ok := item.Value().(types.SealDigest); ok {
+			continue
+		}
+
+		err := unsealed.Header.Digest.Add(item.Value())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encodedBlock, err := unsealed.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encodedBlock, inherentData...), nil
+}
+
+// DecodeCheckInherentsResult decodes the result returned by BlockBuilder_check_inherents.
+func DecodeCheckInherentsResult(encoded []byte) (*CheckInherentsResult, error) {
+	result := new(CheckInherentsResult)
+	reader := bytes.NewReader(encoded)
+	decoder := scale.NewDecoder(reader)
+	err := decoder.Decode(&result.Okay)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode okay: %w", err)
+	}
+
+	err = decoder.Decode(&result.FatalError)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode fatal error: %w", err)
+	}
+
+	var length uint
+	err = decoder.Decode(&length)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode errors length: %w", err)
+	}
+
+	result.Errors = make(map[[8]byte][]byte)
+	for i := uint(0); i < length; i++ {
+		var identifier [8]byte
+		err = decoder.Decode(&identifier)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode identifier of error %d: %w", i, err)
+		}
+
+		// the error is read from the reader, since the
+		// decoder fails to decode empty bytes at the end
+		var errLength uint
+		err = decoder.Decode(&errLength)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode length of error %d: %w", i, err)
+		}
+
+		if errLength > uint(reader.Len()) {
+			return nil, fmt.Errorf("cannot decode error %d: %w", i, io.ErrUnexpectedEOF)
+		}
+
+		inherentErr := make([]byte, errLength)
+		_, err = io.ReadFull(reader, inherentErr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode error %d: %w", i, err)
+		}
+		result.Errors[identifier] = inherentErr
+	}
+
+	return result, nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPIItems(versions map[string]uint32) (apiItems []APIItem) {
+	for name, version := range versions {
+		apiItems = append(apiItems, APIItem{Name: APIID(name), Ver: version})
+	}
+	return apiItems
+}
+
+func Test_APIID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, [8]byte{0xdf, 0x6a, 0xcb, 0x68, 0x99, 0x07, 0x60, 0x9b}, APIID(CoreAPI))
+	assert.Equal(t, [8]byte{0x37, 0xe3, 0x97, 0xfc, 0x7c, 0x91, 0xf5, 0xe4}, APIID("Metadata"))
+}
+
+func Test_APIVersion(t *testing.T) {
+	t.Parallel()
+
+	apiItems := newAPIItems(map[string]uint32{CoreAPI: 4})
+
+	version, ok := APIVersion(apiItems, CoreAPI)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(4), version)
+
+	_, ok = APIVersion(apiItems, BlockBuilderAPI)
+	assert.False(t, ok)
+}
+
+func Test_DecodeVersion(t *testing.T) {
+	t.Parallel()
+
+	encodeLegacy := func(apiItems []APIItem) []byte {
+		encoded, err := NewLegacyVersionData([]byte("a"), []byte("b"), 1, 2, 3, apiItems).Encode()
+		require.NoError(t, err)
+		return encoded
+	}
+
+	testCases := map[string]struct {
+		encoded  []byte
+		expected Version
+		errMsg   string
+	}{
+		"core v2 ignores trailing bytes": {
+			encoded: append(encodeLegacy(newAPIItems(map[string]uint32{CoreAPI: 2})), 5, 0, 0, 0),
+			expected: NewLegacyVersionData([]byte("a"), []byte("b"), 1, 2, 3,
+				newAPIItems(map[string]uint32{CoreAPI: 2})),
+		},
+		"core v3 with transaction version": {
+			encoded: append(encodeLegacy(newAPIItems(map[string]uint32{CoreAPI: 3})), 5, 0, 0, 0),
+			expected: NewVersionData([]byte("a"), []byte("b"), 1, 2, 3,
+				newAPIItems(map[string]uint32{CoreAPI: 3}), 5),
+		},
+		"core v3 missing transaction version": {
+			encoded: encodeLegacy(newAPIItems(map[string]uint32{CoreAPI: 3})),
+			errMsg:  "cannot decode transaction version: EOF",
+		},
+		"core v4 with state version": {
+			encoded: append(encodeLegacy(newAPIItems(map[string]uint32{CoreAPI: 4})), 5, 0, 0, 0, 1),
+			expected: func() Version {
+				version := NewVersionData([]byte("a"), []byte("b"), 1, 2, 3,
+					newAPIItems(map[string]uint32{CoreAPI: 4}), 5)
+				version.SetStateVersion(1)
+				return version
+			}(),
+		},
+		"no core API without transaction version": {
+			encoded:  encodeLegacy(nil),
+			expected: NewLegacyVersionData([]byte("a"), []byte("b"), 1, 2, 3, nil),
+		},
+		"no core API with transaction version": {
+			encoded:  append(encodeLegacy(nil), 5, 0, 0, 0),
+			expected: NewVersionData([]byte("a"), []byte("b"), 1, 2, 3, nil, 5),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			version, err := DecodeVersion(testCase.encoded)
+			if testCase.errMsg != "" {
+				assert.EqualError(t, err, testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, version)
+		})
+	}
+}
+
+func Test_InitializeBlockFunction(t *testing.T) {
+	t.Parallel()
+
+	function, err := InitializeBlockFunction(newAPIItems(map[string]uint32{CoreAPI: 1}))
+	require.NoError(t, err)
+	assert.Equal(t, "Core_initialise_block", function)
+
+	function, err = InitializeBlockFunction(newAPIItems(map[string]uint32{CoreAPI: 4}))
+	require.NoError(t, err)
+	assert.Equal(t, CoreInitializeBlock, function)
+
+	_, err = InitializeBlockFunction(nil)
+	assert.ErrorIs(t, err, ErrAPINotImplemented)
+	assert.EqualError(t, err, "runtime API not implemented: Core")
+}
+
+func Test_FinalizeBlockFunction(t *testing.T) {
+	t.Parallel()
+
+	function, err := FinalizeBlockFunction(newAPIItems(map[string]uint32{BlockBuilderAPI: 2}))
+	require.NoError(t, err)
+	assert.Equal(t, "BlockBuilder_finalise_block", function)
+
+	function, err = FinalizeBlockFunction(newAPIItems(map[string]uint32{BlockBuilderAPI: 5}))
+	require.NoError(t, err)
+	assert.Equal(t, BlockBuilderFinalizeBlock, function)
+}
+
+func Test_EncodeValidateTransactionArgs(t *testing.T) {
+	t.Parallel()
+
+	tx := types.Extrinsic{1, 2}
+	blockHash := common.Hash{3}
+
+	testCases := map[string]struct {
+		version uint32
+		args    []byte
+	}{
+		"v1": {
+			version: 1,
+			args:    []byte{1, 2},
+		},
+		"v2": {
+			version: 2,
+			args:    []byte{byte(types.TxnExternal), 1, 2},
+		},
+		"v3": {
+			version: 3,
+			args:    append([]byte{byte(types.TxnExternal), 1, 2}, blockHash[:]...),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			apiItems := newAPIItems(map[string]uint32{TaggedTransactionQueueAPI: testCase.version})
+			args, err := EncodeValidateTransactionArgs(apiItems, types.TxnExternal, tx, blockHash)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.args, args)
+		})
+	}
+}
+
+func Test_EncodeOffchainWorkerArgs(t *testing.T) {
+	t.Parallel()
+
+	header := types.NewEmptyHeader()
+	header.Number = 7
+
+	args, err := EncodeOffchainWorkerArgs(newAPIItems(map[string]uint32{OffchainWorkerAPI: 1}), header)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{7, 0, 0, 0}, args)
+
+	args, err = EncodeOffchainWorkerArgs(newAPIItems(map[string]uint32{OffchainWorkerAPI: 2}), header)
+	require.NoError(t, err)
+	expected, err := scale.Marshal(*header)
+	require.NoError(t, err)
+	assert.Equal(t, expected, args)
+
+	_, err = EncodeOffchainWorkerArgs(nil, header)
+	assert.ErrorIs(t, err, ErrAPINotImplemented)
+}
+
+func Test_DecodePaymentQueryInfo(t *testing.T) {
+	t.Parallel()
+
+	fee := scale.MustNewUint128(big.NewInt(9))
+	expected := &types.TransactionPaymentQueryInfo{
+		Weight:     1000,
+		Class:      1,
+		PartialFee: fee,
+	}
+
+	encoded, err := scale.Marshal(*expected)
+	require.NoError(t, err)
+	info, err := DecodePaymentQueryInfo(newAPIItems(map[string]uint32{TransactionPaymentAPI: 1}), encoded)
+	require.NoError(t, err)
+	assert.Equal(t, expected, info)
+
+	encoded, err = scale.Marshal(dispatchInfo{RefTime: 1000, ProofSize: 64, Class: 1, PartialFee: fee})
+	require.NoError(t, err)
+	info, err = DecodePaymentQueryInfo(newAPIItems(map[string]uint32{TransactionPaymentAPI: 2}), encoded)
+	require.NoError(t, err)
+	assert.Equal(t, expected, info)
+}
+
+func Test_EncodeCheckInherentsArgs(t *testing.T) {
+	t.Parallel()
+
+	preDigest, err := types.NewBabeSecondaryPlainPreDigest(0, 1).ToPreRuntimeDigest()
+	require.NoError(t, err)
+
+	unsealed := types.NewBlock(*types.NewEmptyHeader(), types.Body{{1, 2}})
+	unsealed.Header.Number = 1
+	require.NoError(t, unsealed.Header.Digest.Add(*preDigest))
+
+	sealed := types.NewBlock(*types.NewEmptyHeader(), types.Body{{1, 2}})
+	sealed.Header.Number = 1
+	require.NoError(t, sealed.Header.Digest.Add(*preDigest, types.SealDigest{
+		ConsensusEngineID: types.BabeEngineID,
+		Data:              []byte{3},
+	}))
+
+	inherentData := []byte{4, 5}
+	args, err := EncodeCheckInherentsArgs(&sealed, inherentData)
+	require.NoError(t, err)
+	assert.Equal(t, append(unsealed.MustEncode(), inherentData...), args)
+
+	// the seal of the block given is kept
+	assert.Len(t, sealed.Header.Digest.Types, 2)
+}
+
+func Test_DecodeCheckInherentsResult(t *testing.T) {
+	t.Parallel()
+
+	result, err := DecodeCheckInherentsResult([]byte{1, 0, 0})
+	require.NoError(t, err)
+	assert.Equal(t, &CheckInherentsResult{Okay: true, Errors: map[[8]byte][]byte{}}, result)
+	assert.Equal(t, "okay=true fatal=false errors=[]", result.String())
+
+	encoded := []byte{0, 1, 8}
+	encoded = append(encoded, []byte("timstap0")...)
+	encoded = append(encoded, 4, 1)
+	encoded = append(encoded, []byte("babeslot")...)
+	encoded = append(encoded, 0)
+	result, err = DecodeCheckInherentsResult(encoded)
+	require.NoError(t, err)
+	expected := &CheckInherentsResult{
+		FatalError: true,
+		Errors: map[[8]byte][]byte{
+			{'t', 'i', 'm', 's', 't', 'a', 'p', '0'}: {1},
+			{'b', 'a', 'b', 'e', 's', 'l', 'o', 't'}: {},
+		},
+	}
+	assert.Equal(t, expected, result)
+	assert.Equal(t, "okay=false fatal=true errors=[babeslot: 0x, timstap0: 0x01]", result.String())
+
+	_, err = DecodeCheckInherentsResult(encoded[:len(encoded)-1])
+	assert.EqualError(t, err, "cannot decode length of error 1: EOF")
+
+	_, err = DecodeCheckInherentsResult(encoded[:12])
+	assert.EqualError(t, err, "cannot decode error 0: unexpected EOF")
+}
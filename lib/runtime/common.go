@@ -3,6 +3,13 @@
 
 package runtime
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
 // Int64ToPointerAndSize converts an int64 into a int32 pointer and a int32 length
 func Int64ToPointerAndSize(in int64) (ptr, length int32) {
 	return int32(in), int32(in >> 32)
@@ -12,3 +19,19 @@ func Int64ToPointerAndSize(in int64) (ptr, length int32) {
 func PointerAndSizeToInt64(ptr, size int32) int64 {
 	return int64(ptr) | (int64(size) << 32)
 }
+
+// DecompressWasm decompresses a Wasm blob that may or may not be compressed with zstd
+// ref: https://github.com/paritytech/substrate/blob/master/primitives/maybe-compressed-blob/src/lib.rs
+func DecompressWasm(code []byte) ([]byte, error) {
+	compressionFlag := []byte{82, 188, 83, 118, 70, 219, 142, 5}
+	if !bytes.HasPrefix(code, compressionFlag) {
+		return code, nil
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return decoder.DecodeAll(code[len(compressionFlag):], nil)
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressWasm(t *testing.T) {
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		in       []byte
+		expected []byte
+		msg      string
+	}{
+		{
+			[]byte{82, 188, 83, 118, 70, 219, 142},
+			[]byte{82, 188, 83, 118, 70, 219, 142},
+			"partial compression flag",
+		},
+		{
+			[]byte{82, 188, 83, 118, 70, 219, 142, 6},
+			[]byte{82, 188, 83, 118, 70, 219, 142, 6},
+			"wrong compression flag",
+		},
+		{
+			[]byte{82, 188, 83, 118, 70, 219, 142, 6, 221},
+			[]byte{82, 188, 83, 118, 70, 219, 142, 6, 221},
+			"wrong compression flag with data",
+		},
+		{
+			append([]byte{82, 188, 83, 118, 70, 219, 142, 5}, encoder.EncodeAll([]byte("compressed"), nil)...),
+			[]byte("compressed"),
+			"compressed data",
+		},
+	}
+
+	for _, test := range cases {
+		actual, err := DecompressWasm(test.in)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, actual)
+	}
+}
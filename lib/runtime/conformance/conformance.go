@@ -0,0 +1,323 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+// Package conformance replays runtime calls through the wasmer and the life runtime
+// executors, and reports where they differ. Since life is an interpreter, it serves
+// as a reference executor to debug consensus issues without JIT involvement.
+package conformance
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/life"
+	"github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/runtime/wasmer"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+// Call is a runtime call made on each executor.
+type Call struct {
+	// Name describes the call in the report.
+	Name string
+	Run  func(instance runtime.Instance) ([]byte, error)
+}
+
+// ExecCall returns a call of the given exported runtime function with the given data.
+func ExecCall(function string, data []byte) Call {
+	return Call{
+		Name: function,
+		Run: func(instance runtime.Instance) ([]byte, error) {
+			return instance.Exec(function, data)
+		},
+	}
+}
+
+// ExecuteBlockCall returns a call executing the given block.
+func ExecuteBlockCall(block *types.Block) Call {
+	return Call{
+		Name: fmt.Sprintf("%s block #%d (%s)", runtime.CoreExecuteBlock, block.Header.Number, block.Header.Hash()),
+		Run: func(instance runtime.Instance) ([]byte, error) {
+			return instance.ExecuteBlock(block)
+		},
+	}
+}
+
+// DifferenceKind is the kind of a difference between the executors.
+type DifferenceKind string
+
+const (
+	// ReturnValue is a difference of the data returned by the runtime.
+	ReturnValue DifferenceKind = "return value"
+	// Error is a difference of the error returned by the call.
+	Error DifferenceKind = "error"
+	// HostCall is a difference of the storage host calls made by the runtime.
+	HostCall DifferenceKind = "host call"
+	// StorageWrite is a difference of the storage writes made by the runtime.
+	StorageWrite DifferenceKind = "storage write"
+	// StorageEntry is a difference of the storage entries after the call.
+	StorageEntry DifferenceKind = "storage entry"
+)
+
+// Difference is a difference between the executors found for a call.
+type Difference struct {
+	Call   string
+	Kind   DifferenceKind
+	Wasmer string
+	Life   string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s differs: wasmer %q, life %q", d.Call, d.Kind, d.Wasmer, d.Life)
+}
+
+// Report is the result of running calls through both executors.
+type Report struct {
+	Calls       int
+	Differences []Difference
+}
+
+// Conforms returns true if the executors did not differ.
+func (r *Report) Conforms() bool {
+	return len(r.Differences) == 0
+}
+
+func (r *Report) String() string {
+	if r.Conforms() {
+		return fmt.Sprintf("%d calls, no difference", r.Calls)
+	}
+
+	lines := make([]string, 0, len(r.Differences)+1)
+	lines = append(lines, fmt.Sprintf("%d calls, %d differences:", r.Calls, len(r.Differences)))
+	for _, difference := range r.Differences {
+		lines = append(lines, difference.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// executor creates runtime instances for the code and configuration given.
+type executor func(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error)
+
+func newWasmerInstance(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+	return wasmer.NewInstance(code, &wasmer.Config{
+		InstanceConfig: cfg,
+		Imports:        wasmer.ImportsNodeRuntime,
+	})
+}
+
+func newLifeInstance(code []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+	return life.NewInstance(code, &life.Config{InstanceConfig: cfg})
+}
+
+// Harness runs runtime calls through the wasmer and life executors.
+type Harness struct {
+	code   []byte
+	config runtime.InstanceConfig
+	wasmer executor
+	life   executor
+}
+
+// NewHarness returns a harness running the given runtime code. The storage
+// of the configuration is ignored, each run is given its own storage.
+func NewHarness(code []byte, cfg runtime.InstanceConfig) *Harness {
+	return &Harness{
+		code:   code,
+		config: cfg,
+		wasmer: newWasmerInstance,
+		life:   newLifeInstance,
+	}
+}
+
+// ReplayBlocks executes the blocks one after the other on the given state,
+// and reports the differences between the executors.
+func (h *Harness) ReplayBlocks(state *trie.Trie, blocks []*types.Block) (*Report, error) {
+	calls := make([]Call, len(blocks))
+	for i, block := range blocks {
+		calls[i] = ExecuteBlockCall(block)
+	}
+	return h.Run(state, calls)
+}
+
+// Run makes the calls one after the other on the given state, and reports
+// the return values, errors, storage host calls, storage writes and storage
+// entries differing between the executors. The state given is not modified.
+func (h *Harness) Run(state *trie.Trie, calls []Call) (*Report, error) {
+	wasmerRun, err := h.newRun(h.wasmer, state)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create wasmer instance: %w", err)
+	}
+	defer wasmerRun.instance.Stop()
+
+	lifeRun, err := h.newRun(h.life, state)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create life instance: %w", err)
+	}
+	defer lifeRun.instance.Stop()
+
+	report := &Report{Calls: len(calls)}
+	for _, call := range calls {
+		wasmerResult := wasmerRun.call(call)
+		lifeResult := lifeRun.call(call)
+		report.Differences = append(report.Differences, compare(call.Name, wasmerResult, lifeResult)...)
+		report.Differences = append(report.Differences,
+			compareEntries(call.Name, wasmerRun.state.TrieEntries(), lifeRun.state.TrieEntries())...)
+	}
+
+	return report, nil
+}
+
+// run is a runtime instance run by the harness with its own storage.
+type run struct {
+	instance runtime.Instance
+	state    *storage.TrieState
+	storage  *recordingStorage
+}
+
+func (h *Harness) newRun(newInstance executor, state *trie.Trie) (*run, error) {
+	ts, err := storage.NewTrieState(state.Snapshot())
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trie state: %w", err)
+	}
+
+	r := &run{
+		state:   ts,
+		storage: newRecordingStorage(ts),
+	}
+
+	cfg := h.config
+	cfg.Storage = r.storage
+	r.instance, err = newInstance(h.code, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// result is the result of a call on one executor.
+type result struct {
+	value     []byte
+	err       error
+	hostCalls []string
+	writes    []string
+}
+
+func (r *run) call(call Call) (res result) {
+	r.storage.reset()
+	r.instance.SetContextStorage(r.storage)
+	res.value, res.err = call.Run(r.instance)
+	res.hostCalls = r.storage.hostCalls()
+	res.writes = r.storage.writes()
+	return res
+}
+
+func compare(name string, wasmerResult, lifeResult result) (differences []Difference) {
+	// the error messages of the executors differ, only the call failing is compared.
+	if (wasmerResult.err == nil) != (lifeResult.err == nil) {
+		differences = append(differences, Difference{
+			Call:   name,
+			Kind:   Error,
+			Wasmer: errorString(wasmerResult.err),
+			Life:   errorString(lifeResult.err),
+		})
+	}
+
+	if !bytes.Equal(wasmerResult.value, lifeResult.value) {
+		differences = append(differences, Difference{
+			Call:   name,
+			Kind:   ReturnValue,
+			Wasmer: fmt.Sprintf("0x%x", wasmerResult.value),
+			Life:   fmt.Sprintf("0x%x", lifeResult.value),
+		})
+	}
+
+	if difference, ok := compareOperations(name, StorageWrite, wasmerResult.writes, lifeResult.writes); ok {
+		differences = append(differences, difference)
+	}
+
+	if difference, ok := compareOperations(name, HostCall, wasmerResult.hostCalls, lifeResult.hostCalls); ok {
+		differences = append(differences, difference)
+	}
+
+	return differences
+}
+
+// compareOperations returns the first difference between the operations,
+// and true if the operations differ.
+func compareOperations(name string, kind DifferenceKind, wasmerOperations, lifeOperations []string) (
+	difference Difference, differ bool) {
+	length := len(wasmerOperations)
+	if len(lifeOperations) > length {
+		length = len(lifeOperations)
+	}
+
+	for i := 0; i < length; i++ {
+		wasmerOperation, lifeOperation := "<none>", "<none>"
+		if i < len(wasmerOperations) {
+			wasmerOperation = wasmerOperations[i]
+		}
+		if i < len(lifeOperations) {
+			lifeOperation = lifeOperations[i]
+		}
+
+		if wasmerOperation != lifeOperation {
+			return Difference{
+				Call:   fmt.Sprintf("%s %s #%d", name, kind, i),
+				Kind:   kind,
+				Wasmer: wasmerOperation,
+				Life:   lifeOperation,
+			}, true
+		}
+	}
+
+	return difference, false
+}
+
+// compareEntries returns the differences between the storage entries, sorted by key.
+func compareEntries(name string, wasmerEntries, lifeEntries map[string][]byte) (differences []Difference) {
+	keys := make([]string, 0, len(wasmerEntries))
+	for key := range wasmerEntries {
+		keys = append(keys, key)
+	}
+	for key := range lifeEntries {
+		if _, ok := wasmerEntries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		wasmerValue, wasmerOk := wasmerEntries[key]
+		lifeValue, lifeOk := lifeEntries[key]
+		if wasmerOk == lifeOk && bytes.Equal(wasmerValue, lifeValue) {
+			continue
+		}
+
+		differences = append(differences, Difference{
+			Call:   fmt.Sprintf("%s key 0x%x", name, key),
+			Kind:   StorageEntry,
+			Wasmer: entryString(wasmerValue, wasmerOk),
+			Life:   entryString(lifeValue, lifeOk),
+		})
+	}
+
+	return differences
+}
+
+func entryString(value []byte, ok bool) string {
+	if !ok {
+		return "<none>"
+	}
+	return fmt.Sprintf("0x%x", value)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package conformance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInstance is a runtime instance setting the value
+// given at the key given, and returning the previous value.
+type testInstance struct {
+	runtime.Instance // nil, only the methods below are implemented
+	storage          runtime.Storage
+	// value, if not nil, is set instead of the value given.
+	value []byte
+	err   error
+}
+
+func (ti *testInstance) SetContextStorage(s runtime.Storage) {
+	ti.storage = s
+}
+
+func (ti *testInstance) Exec(function string, data []byte) ([]byte, error) {
+	if ti.err != nil {
+		return nil, ti.err
+	}
+
+	key := []byte(function)
+	previous := ti.storage.Get(key)
+	value := data
+	if ti.value != nil {
+		value = ti.value
+	}
+	ti.storage.Set(key, value)
+	return previous, nil
+}
+
+func (*testInstance) Stop() {}
+
+func newTestExecutor(instance *testInstance) executor {
+	return func(_ []byte, cfg runtime.InstanceConfig) (runtime.Instance, error) {
+		instance.storage = cfg.Storage
+		return instance, nil
+	}
+}
+
+func Test_Harness_Run(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		wasmer      *testInstance
+		life        *testInstance
+		differences []Difference
+	}{
+		"no difference": {
+			wasmer: &testInstance{},
+			life:   &testInstance{},
+		},
+		"storage write difference": {
+			wasmer: &testInstance{},
+			life:   &testInstance{value: []byte{9}},
+			differences: []Difference{
+				{Call: "a storage write #0", Kind: StorageWrite, Wasmer: "Set(0x61, 0x02)", Life: "Set(0x61, 0x09)"},
+				{Call: "a host call #1", Kind: HostCall, Wasmer: "Set(0x61, 0x02)", Life: "Set(0x61, 0x09)"},
+				{Call: "a key 0x61", Kind: StorageEntry, Wasmer: "0x02", Life: "0x09"},
+				{Call: "a", Kind: ReturnValue, Wasmer: "0x02", Life: "0x09"},
+				{Call: "a storage write #0", Kind: StorageWrite, Wasmer: "Set(0x61, 0x03)", Life: "Set(0x61, 0x09)"},
+				{Call: "a host call #0", Kind: HostCall, Wasmer: "Get(0x61) = 0x02", Life: "Get(0x61) = 0x09"},
+				{Call: "a key 0x61", Kind: StorageEntry, Wasmer: "0x03", Life: "0x09"},
+			},
+		},
+		"error difference": {
+			wasmer: &testInstance{},
+			life:   &testInstance{err: errTest},
+			differences: []Difference{
+				{Call: "a", Kind: Error, Wasmer: "<nil>", Life: "test error"},
+				{Call: "a", Kind: ReturnValue, Wasmer: "0x01", Life: "0x"},
+				{Call: "a storage write #0", Kind: StorageWrite, Wasmer: "Set(0x61, 0x02)", Life: "<none>"},
+				{Call: "a host call #0", Kind: HostCall, Wasmer: "Get(0x61) = 0x01", Life: "<none>"},
+				{Call: "a key 0x61", Kind: StorageEntry, Wasmer: "0x02", Life: "0x01"},
+				{Call: "a", Kind: Error, Wasmer: "<nil>", Life: "test error"},
+				{Call: "a", Kind: ReturnValue, Wasmer: "0x02", Life: "0x"},
+				{Call: "a storage write #0", Kind: StorageWrite, Wasmer: "Set(0x61, 0x03)", Life: "<none>"},
+				{Call: "a host call #0", Kind: HostCall, Wasmer: "Get(0x61) = 0x02", Life: "<none>"},
+				{Call: "a key 0x61", Kind: StorageEntry, Wasmer: "0x03", Life: "0x01"},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			state := trie.NewEmptyTrie()
+			state.Put([]byte("a"), []byte{1})
+
+			harness := NewHarness(nil, runtime.InstanceConfig{})
+			harness.wasmer = newTestExecutor(testCase.wasmer)
+			harness.life = newTestExecutor(testCase.life)
+
+			report, err := harness.Run(state, []Call{
+				ExecCall("a", []byte{2}),
+				ExecCall("a", []byte{3}),
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, 2, report.Calls)
+			assert.Equal(t, testCase.differences, report.Differences)
+			assert.Equal(t, len(testCase.differences) == 0, report.Conforms())
+
+			// the state given is left untouched
+			assert.Equal(t, map[string][]byte{"a": {1}}, state.Entries())
+		})
+	}
+}
+
+func Test_recordingStorage(t *testing.T) {
+	t.Parallel()
+
+	state, err := storage.NewTrieState(nil)
+	require.NoError(t, err)
+	state.Set([]byte("a"), []byte{1})
+
+	s := newRecordingStorage(state)
+	s.Set([]byte("b"), []byte{2})
+	_ = s.Get([]byte("a"))
+	_ = s.ClearPrefix([]byte("b"))
+	s.BeginStorageTransaction()
+	s.Delete([]byte("a"))
+	s.RollbackStorageTransaction()
+
+	assert.Equal(t, []string{
+		"Set(0x62, 0x02)",
+		"Get(0x61) = 0x01",
+		"ClearPrefix(0x62) = <nil>",
+		"BeginStorageTransaction()",
+		"Delete(0x61)",
+		"RollbackStorageTransaction()",
+	}, s.hostCalls())
+	assert.Equal(t, []string{
+		"Set(0x62, 0x02)",
+		"ClearPrefix(0x62) = <nil>",
+		"BeginStorageTransaction()",
+		"Delete(0x61)",
+		"RollbackStorageTransaction()",
+	}, s.writes())
+	assert.Equal(t, []byte{1}, state.Get([]byte("a")))
+
+	s.reset()
+	assert.Empty(t, s.hostCalls())
+}
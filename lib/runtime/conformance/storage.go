@@ -0,0 +1,202 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package conformance
+
+import (
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+var _ runtime.Storage = (*recordingStorage)(nil)
+
+// operation is a storage operation made by a runtime host function.
+type operation struct {
+	description string
+	write       bool
+}
+
+// recordingStorage is a runtime storage recording
+// the operations made on the storage it wraps.
+type recordingStorage struct {
+	storage    runtime.Storage
+	operations []operation
+}
+
+func newRecordingStorage(storage runtime.Storage) *recordingStorage {
+	return &recordingStorage{storage: storage}
+}
+
+// reset removes the operations recorded.
+func (s *recordingStorage) reset() {
+	s.operations = nil
+}
+
+// hostCalls returns the descriptions of all the operations recorded.
+func (s *recordingStorage) hostCalls() (descriptions []string) {
+	descriptions = make([]string, len(s.operations))
+	for i, op := range s.operations {
+		descriptions[i] = op.description
+	}
+	return descriptions
+}
+
+// writes returns the descriptions of the operations recorded modifying the storage.
+func (s *recordingStorage) writes() (descriptions []string) {
+	for _, op := range s.operations {
+		if op.write {
+			descriptions = append(descriptions, op.description)
+		}
+	}
+	return descriptions
+}
+
+func (s *recordingStorage) read(format string, args ...interface{}) {
+	s.operations = append(s.operations, operation{description: fmt.Sprintf(format, args...)})
+}
+
+func (s *recordingStorage) write(format string, args ...interface{}) {
+	s.operations = append(s.operations, operation{description: fmt.Sprintf(format, args...), write: true})
+}
+
+func (s *recordingStorage) Set(key, value []byte) {
+	s.write("Set(0x%x, 0x%x)", key, value)
+	s.storage.Set(key, value)
+}
+
+func (s *recordingStorage) Get(key []byte) []byte {
+	value := s.storage.Get(key)
+	s.read("Get(0x%x) = 0x%x", key, value)
+	return value
+}
+
+func (s *recordingStorage) Root() (common.Hash, error) {
+	root, err := s.storage.Root()
+	s.read("Root() = %s, %v", root, err)
+	return root, err
+}
+
+func (s *recordingStorage) SetVersion(version trie.Version) {
+	s.write("SetVersion(%s)", version)
+	s.storage.SetVersion(version)
+}
+
+func (s *recordingStorage) SetChild(keyToChild []byte, child *trie.Trie) error {
+	err := s.storage.SetChild(keyToChild, child)
+	s.write("SetChild(0x%x) = %v", keyToChild, err)
+	return err
+}
+
+func (s *recordingStorage) SetChildStorage(keyToChild, key, value []byte) error {
+	err := s.storage.SetChildStorage(keyToChild, key, value)
+	s.write("SetChildStorage(0x%x, 0x%x, 0x%x) = %v", keyToChild, key, value, err)
+	return err
+}
+
+func (s *recordingStorage) GetChildStorage(keyToChild, key []byte) ([]byte, error) {
+	value, err := s.storage.GetChildStorage(keyToChild, key)
+	s.read("GetChildStorage(0x%x, 0x%x) = 0x%x, %v", keyToChild, key, value, err)
+	return value, err
+}
+
+func (s *recordingStorage) Delete(key []byte) {
+	s.write("Delete(0x%x)", key)
+	s.storage.Delete(key)
+}
+
+func (s *recordingStorage) DeleteChild(keyToChild []byte) {
+	s.write("DeleteChild(0x%x)", keyToChild)
+	s.storage.DeleteChild(keyToChild)
+}
+
+func (s *recordingStorage) DeleteChildLimit(keyToChild []byte, limit *[]byte) (
+	deleted uint32, all bool, err error) {
+	deleted, all, err = s.storage.DeleteChildLimit(keyToChild, limit)
+	limitString := "nil"
+	if limit != nil {
+		limitString = fmt.Sprintf("0x%x", *limit)
+	}
+	s.write("DeleteChildLimit(0x%x, %s) = %d, %t, %v", keyToChild, limitString, deleted, all, err)
+	return deleted, all, err
+}
+
+func (s *recordingStorage) ClearChildStorage(keyToChild, key []byte) error {
+	err := s.storage.ClearChildStorage(keyToChild, key)
+	s.write("ClearChildStorage(0x%x, 0x%x) = %v", keyToChild, key, err)
+	return err
+}
+
+func (s *recordingStorage) NextKey(key []byte) []byte {
+	next := s.storage.NextKey(key)
+	s.read("NextKey(0x%x) = 0x%x", key, next)
+	return next
+}
+
+func (s *recordingStorage) ClearPrefixInChild(keyToChild, prefix []byte) error {
+	err := s.storage.ClearPrefixInChild(keyToChild, prefix)
+	s.write("ClearPrefixInChild(0x%x, 0x%x) = %v", keyToChild, prefix, err)
+	return err
+}
+
+func (s *recordingStorage) GetChildNextKey(keyToChild, key []byte) ([]byte, error) {
+	next, err := s.storage.GetChildNextKey(keyToChild, key)
+	s.read("GetChildNextKey(0x%x, 0x%x) = 0x%x, %v", keyToChild, key, next, err)
+	return next, err
+}
+
+func (s *recordingStorage) GetChild(keyToChild []byte) (*trie.Trie, error) {
+	child, err := s.storage.GetChild(keyToChild)
+	s.read("GetChild(0x%x) = %v", keyToChild, err)
+	return child, err
+}
+
+func (s *recordingStorage) ClearPrefix(prefix []byte) error {
+	err := s.storage.ClearPrefix(prefix)
+	s.write("ClearPrefix(0x%x) = %v", prefix, err)
+	return err
+}
+
+func (s *recordingStorage) ClearPrefixLimit(prefix []byte, limit uint32) (deleted uint32, all bool) {
+	deleted, all = s.storage.ClearPrefixLimit(prefix, limit)
+	s.write("ClearPrefixLimit(0x%x, %d) = %d, %t", prefix, limit, deleted, all)
+	return deleted, all
+}
+
+func (s *recordingStorage) BeginStorageTransaction() {
+	s.write("BeginStorageTransaction()")
+	s.storage.BeginStorageTransaction()
+}
+
+func (s *recordingStorage) CommitStorageTransaction() {
+	s.write("CommitStorageTransaction()")
+	s.storage.CommitStorageTransaction()
+}
+
+func (s *recordingStorage) RollbackStorageTransaction() {
+	s.write("RollbackStorageTransaction()")
+	s.storage.RollbackStorageTransaction()
+}
+
+func (s *recordingStorage) LoadCode() []byte {
+	code := s.storage.LoadCode()
+	s.read("LoadCode() = %d bytes", len(code))
+	return code
+}
+
+func (s *recordingStorage) IndexTransaction(extrinsic, size uint32, hash common.Hash) {
+	s.write("IndexTransaction(%d, %d, %s)", extrinsic, size, hash)
+	s.storage.IndexTransaction(extrinsic, size, hash)
+}
+
+func (s *recordingStorage) RenewTransaction(extrinsic uint32, hash common.Hash) {
+	s.write("RenewTransaction(%d, %s)", extrinsic, hash)
+	s.storage.RenewTransaction(extrinsic, hash)
+}
+
+// ClearTransactionIndexOperations is not recorded since it is not called by host functions.
+func (s *recordingStorage) ClearTransactionIndexOperations() {
+	s.storage.ClearTransactionIndexOperations()
+}
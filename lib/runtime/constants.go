@@ -68,10 +68,14 @@
 	BlockBuilderApplyExtrinsic = "BlockBuilder_apply_extrinsic"
 	// BlockBuilderFinalizeBlock is the runtime API call BlockBuilder_finalize_block
 	BlockBuilderFinalizeBlock = "BlockBuilder_finalize_block"
+	// BlockBuilderCheckInherents is the runtime API call BlockBuilder_check_inherents
+	BlockBuilderCheckInherents = "BlockBuilder_check_inherents"
 	// DecodeSessionKeys is the runtime API call SessionKeys_decode_session_keys
 	DecodeSessionKeys = "SessionKeys_decode_session_keys"
 	// TransactionPaymentAPIQueryInfo returns information of a given extrinsic
 	TransactionPaymentAPIQueryInfo = "TransactionPaymentApi_query_info"
+	// OffchainWorker is the runtime API call OffchainWorkerApi_offchain_worker
+	OffchainWorker = "OffchainWorkerApi_offchain_worker"
 )
 
 // GrandpaAuthoritiesKey is the location of GRANDPA authority data
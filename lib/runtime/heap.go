@@ -0,0 +1,139 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+// DefaultHeapPages is the number of heap pages given to the runtime
+// when the :heappages storage key is not set.
+const DefaultHeapPages = uint64(2048)
+
+// heapBaseExport is the name of the global exported by runtimes
+// holding the offset at which their heap starts.
+const heapBaseExport = "__heap_base"
+
+var (
+	// ErrHeapPagesEncoding is returned when the value at :heappages is not a little endian u64.
+	ErrHeapPagesEncoding = errors.New("heap pages value is not 8 bytes long")
+	// ErrHeapBaseNotValid is returned when the __heap_base global of a runtime is not valid.
+	ErrHeapBaseNotValid = errors.New("__heap_base global is not valid")
+)
+
+// GetHeapPages returns the number of heap pages stored at :heappages in the
+// given storage, or DefaultHeapPages if the storage is nil or the key is not set.
+func GetHeapPages(storage Storage) (heapPages uint64, err error) {
+	if storage == nil {
+		return DefaultHeapPages, nil
+	}
+
+	encoded := storage.Get(common.HeapPagesKey)
+	if encoded == nil {
+		return DefaultHeapPages, nil
+	}
+
+	if len(encoded) != 8 {
+		return 0, fmt.Errorf("%w: 0x%x", ErrHeapPagesEncoding, encoded)
+	}
+
+	return binary.LittleEndian.Uint64(encoded), nil
+}
+
+// ModuleMemory holds the memory layout declared by a runtime Wasm module.
+type ModuleMemory struct {
+	// HeapBase is the value of the __heap_base global exported by the module,
+	// or DefaultHeapBase if the module does not export it.
+	HeapBase uint32
+	// InitialPages is the initial number of pages of the memory of the module.
+	InitialPages uint32
+	// MaximumPages is the maximum number of pages of the memory of
+	// the module, and is 0 if the module does not declare a maximum.
+	MaximumPages uint32
+}
+
+// GetModuleMemory decodes the given Wasm code and returns the memory layout it declares,
+// from its imported or defined memory and its exported __heap_base global.
+func GetModuleMemory(code []byte) (moduleMemory ModuleMemory, err error) {
+	module, err := wasm.DecodeModule(bytes.NewReader(code))
+	if err != nil {
+		return moduleMemory, fmt.Errorf("cannot decode wasm module: %w", err)
+	}
+
+	moduleMemory.HeapBase = DefaultHeapBase
+
+	var importedGlobals int
+	if module.Import != nil {
+		for _, entry := range module.Import.Entries {
+			switch importType := entry.Type.(type) {
+			case wasm.MemoryImport:
+				moduleMemory.InitialPages, moduleMemory.MaximumPages = memoryLimits(importType.Type.Limits)
+			case wasm.GlobalVarImport:
+				importedGlobals++
+			}
+		}
+	}
+
+	if module.Memory != nil && len(module.Memory.Entries) > 0 {
+		moduleMemory.InitialPages, moduleMemory.MaximumPages = memoryLimits(module.Memory.Entries[0].Limits)
+	}
+
+	if module.Export == nil {
+		return moduleMemory, nil
+	}
+
+	export, ok := module.Export.Entries[heapBaseExport]
+	if !ok || export.Kind != wasm.ExternalGlobal {
+		return moduleMemory, nil
+	}
+
+	globalIndex := int(export.Index) - importedGlobals
+	if module.Global == nil || globalIndex < 0 || globalIndex >= len(module.Global.Globals) {
+		return moduleMemory, fmt.Errorf("%w: global index %d is not defined in the module",
+			ErrHeapBaseNotValid, export.Index)
+	}
+
+	value, err := module.ExecInitExpr(module.Global.Globals[globalIndex].Init)
+	if err != nil {
+		return moduleMemory, fmt.Errorf("%w: %s", ErrHeapBaseNotValid, err)
+	}
+
+	heapBase, ok := value.(int32)
+	if !ok {
+		return moduleMemory, fmt.Errorf("%w: value %v is not an i32", ErrHeapBaseNotValid, value)
+	}
+	moduleMemory.HeapBase = uint32(heapBase)
+
+	return moduleMemory, nil
+}
+
+// Pages returns the number of pages the memory of the module should have
+// to give the given number of heap pages to the runtime.
+// The number of pages is capped to the maximum pages of the module memory.
+func (m ModuleMemory) Pages(heapPages uint64) (pages uint32) {
+	total := uint64(m.InitialPages) + heapPages
+	if m.MaximumPages != 0 && total > uint64(m.MaximumPages) {
+		return m.MaximumPages
+	}
+
+	const maxWasmPages = 65536
+	if total > maxWasmPages {
+		return maxWasmPages
+	}
+	return uint32(total)
+}
+
+func memoryLimits(limits wasm.ResizableLimits) (initial, maximum uint32) {
+	const maximumIsSet = 1
+	if limits.Flags&maximumIsSet != 0 {
+		maximum = limits.Maximum
+	}
+	return limits.Initial, maximum
+}
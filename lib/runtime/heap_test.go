@@ -0,0 +1,166 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetHeapPages(t *testing.T) {
+	t.Parallel()
+
+	newStorage := func(heapPages []byte) Storage {
+		ts, err := storage.NewTrieState(nil)
+		require.NoError(t, err)
+		if heapPages != nil {
+			ts.Set(common.HeapPagesKey, heapPages)
+		}
+		return ts
+	}
+
+	testCases := map[string]struct {
+		storage    Storage
+		heapPages  uint64
+		errWrapped error
+	}{
+		"nil storage": {
+			heapPages: DefaultHeapPages,
+		},
+		"heap pages not set": {
+			storage:   newStorage(nil),
+			heapPages: DefaultHeapPages,
+		},
+		"heap pages set": {
+			storage:   newStorage([]byte{0x00, 0x10, 0, 0, 0, 0, 0, 0}),
+			heapPages: 4096,
+		},
+		"bad heap pages encoding": {
+			storage:    newStorage([]byte{1, 2}),
+			errWrapped: ErrHeapPagesEncoding,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			heapPages, err := GetHeapPages(testCase.storage)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			assert.Equal(t, testCase.heapPages, heapPages)
+		})
+	}
+}
+
+// wasmModule returns a hand assembled Wasm module made of the given sections.
+// All sections are short enough for their lengths to fit in a single LEB128 byte.
+func wasmModule(sections ...[]byte) (module []byte) {
+	module = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, section := range sections {
+		module = append(module, section[0], byte(len(section)-1))
+		module = append(module, section[1:]...)
+	}
+	return module
+}
+
+func Test_GetModuleMemory(t *testing.T) {
+	t.Parallel()
+
+	memoryImport := []byte{0x02, // import section
+		0x01,                // 1 import
+		0x03, 'e', 'n', 'v', // module
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', // field
+		0x02, 0x00, 0x14, // memory with 20 initial pages
+	}
+	memoryDefinition := []byte{0x05, // memory section
+		0x01,                   // 1 memory
+		0x01, 0x11, 0x80, 0x20, // 17 initial pages and 4096 maximum pages
+	}
+	heapBaseGlobal := []byte{0x06, // global section
+		0x01,                                           // 1 global
+		0x7f, 0x00, 0x41, 0x80, 0x80, 0xc0, 0x00, 0x0b, // immutable i32 1048576
+	}
+	heapBaseExport := []byte{0x07, // export section
+		0x01, // 1 export
+		0x0b, '_', '_', 'h', 'e', 'a', 'p', '_', 'b', 'a', 's', 'e',
+		0x03, 0x00, // global 0
+	}
+	badHeapBaseExport := []byte{0x07, // export section
+		0x01, // 1 export
+		0x0b, '_', '_', 'h', 'e', 'a', 'p', '_', 'b', 'a', 's', 'e',
+		0x03, 0x01, // global 1
+	}
+
+	testCases := map[string]struct {
+		code         []byte
+		moduleMemory ModuleMemory
+		errWrapped   error
+		errMessage   string
+	}{
+		"not a wasm module": {
+			code:       []byte{1, 2, 3},
+			errWrapped: io.ErrUnexpectedEOF,
+			errMessage: "cannot decode wasm module: unexpected EOF",
+		},
+		"imported memory and heap base": {
+			code: wasmModule(memoryImport, heapBaseGlobal, heapBaseExport),
+			moduleMemory: ModuleMemory{
+				HeapBase:     1048576,
+				InitialPages: 20,
+			},
+		},
+		"defined memory without heap base": {
+			code: wasmModule(memoryDefinition),
+			moduleMemory: ModuleMemory{
+				HeapBase:     DefaultHeapBase,
+				InitialPages: 17,
+				MaximumPages: 4096,
+			},
+		},
+		"heap base global not defined": {
+			code: wasmModule(memoryImport, heapBaseGlobal, badHeapBaseExport),
+			moduleMemory: ModuleMemory{
+				HeapBase:     DefaultHeapBase,
+				InitialPages: 20,
+			},
+			errWrapped: ErrHeapBaseNotValid,
+			errMessage: "__heap_base global is not valid: global index 1 is not defined in the module",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			moduleMemory, err := GetModuleMemory(testCase.code)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errMessage != "" {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+			assert.Equal(t, testCase.moduleMemory, moduleMemory)
+		})
+	}
+}
+
+func Test_ModuleMemory_Pages(t *testing.T) {
+	t.Parallel()
+
+	moduleMemory := ModuleMemory{InitialPages: 20}
+	assert.Equal(t, uint32(2068), moduleMemory.Pages(DefaultHeapPages))
+
+	moduleMemory.MaximumPages = 1000
+	assert.Equal(t, uint32(1000), moduleMemory.Pages(DefaultHeapPages))
+
+	moduleMemory.MaximumPages = 0
+	assert.Equal(t, uint32(65536), moduleMemory.Pages(1<<40))
+}
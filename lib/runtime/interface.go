@@ -26,11 +26,13 @@ type Instance interface {
 	SetContextStorage(s Storage) // used to set the TrieState before a runtime call
 
 	GetCodeHash() common.Hash
+	GetHeapPages() uint64
 	Version() (Version, error)
 	Metadata() ([]byte, error)
 	BabeConfiguration() (*types.BabeConfiguration, error)
 	GrandpaAuthorities() ([]types.Authority, error)
-	ValidateTransaction(e types.Extrinsic) (*transaction.Validity, error)
+	ValidateTransaction(source types.TransactionSource, tx types.Extrinsic,
+		blockHash common.Hash) (*transaction.Validity, error)
 	InitializeBlock(header *types.Header) error
 	InherentExtrinsics(data []byte) ([]byte, error)
 	ApplyExtrinsic(data types.Extrinsic) ([]byte, error)
@@ -38,12 +40,11 @@ type Instance interface {
 	ExecuteBlock(block *types.Block) ([]byte, error)
 	DecodeSessionKeys(enc []byte) ([]byte, error)
 	PaymentQueryInfo(ext []byte) (*types.TransactionPaymentQueryInfo, error)
-
-	CheckInherents() // TODO: use this in block verification process (#1873)
+	OffchainWorker(header *types.Header) error
+	CheckInherents(block *types.Block, inherentData []byte) (*CheckInherentsResult, error)
 
 	// parameters and return values for these are undefined in the spec
 	RandomSeed()
-	OffchainWorker()
 	GenerateSessionKeys()
 }
 
@@ -52,6 +53,7 @@ type Storage interface {
 	Set(key []byte, value []byte)
 	Get(key []byte) []byte
 	Root() (common.Hash, error)
+	SetVersion(version trie.Version)
 	SetChild(keyToChild []byte, child *trie.Trie) error
 	SetChildStorage(keyToChild, key, value []byte) error
 	GetChildStorage(keyToChild, key []byte) ([]byte, error)
@@ -69,6 +71,15 @@ type Storage interface {
 	CommitStorageTransaction()
 	RollbackStorageTransaction()
 	LoadCode() []byte
+	IndexTransaction(extrinsic, size uint32, hash common.Hash)
+	RenewTransaction(extrinsic uint32, hash common.Hash)
+	ClearTransactionIndexOperations()
+}
+
+// CallTracer is implemented by storages tracing the storage accesses made by the
+// runtime, which are told the exported runtime function being called.
+type CallTracer interface {
+	TraceCall(function string)
 }
 
 // BasicNetwork interface for functions used by runtime network state function
@@ -4,31 +4,36 @@
 package life
 
 import (
-	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
-// ValidateTransaction runs the extrinsic through runtime function
-// TaggedTransactionQueue_validate_transaction and returns *Validity
-func (in *Instance) ValidateTransaction(e types.Extrinsic) (*transaction.Validity, error) {
-	ret, err := in.Exec(runtime.TaggedTransactionQueueValidateTransaction, e)
+// ValidateTransaction runs the extrinsic received from the given source through the runtime
+// function TaggedTransactionQueue_validate_transaction, at the block with the given hash whose
+// state is the context storage, and returns *Validity
+func (in *Instance) ValidateTransaction(source types.TransactionSource, tx types.Extrinsic,
+	blockHash common.Hash) (*transaction.Validity, error) {
+	apiItems, err := in.apiItems()
 	if err != nil {
 		return nil, err
 	}
 
-	if ret[0] != 0 {
-		return nil, runtime.NewValidateTransactionError(ret)
+	args, err := runtime.EncodeValidateTransactionArgs(apiItems, source, tx, blockHash)
+	if err != nil {
+		return nil, err
 	}
 
-	v := transaction.NewValidity(0, [][]byte{{}}, [][]byte{{}}, 0, false)
-	err = scale.Unmarshal(ret[1:], v)
-	return v, err
+	ret, err := in.Exec(runtime.TaggedTransactionQueueValidateTransaction, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.DecodeValidateTransactionResult(ret)
 }
 
 // Version calls runtime function Core_Version
@@ -38,22 +43,35 @@ func (in *Instance) Version() (runtime.Version, error) {
 		return nil, err
 	}
 
-	version := &runtime.VersionData{}
-	err = version.Decode(res)
-	// error comes from scale now, so do a string check
+	version, err := runtime.DecodeVersion(res)
 	if err != nil {
-		if strings.Contains(err.Error(), "EOF") {
-			// kusama seems to use the legacy version format
-			lversion := &runtime.LegacyVersionData{}
-			err = lversion.Decode(res)
-			return lversion, err
-		}
 		return nil, err
 	}
 
+	in.mu.Lock()
+	in.version = version
+	in.mu.Unlock()
 	return version, nil
 }
 
+// apiItems returns the runtime APIs implemented by the instance code, calling
+// Core_version if the version of the code was not retrieved yet.
+func (in *Instance) apiItems() ([]runtime.APIItem, error) {
+	in.mu.Lock()
+	version := in.version
+	in.mu.Unlock()
+
+	if version == nil {
+		var err error
+		version, err = in.Version()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get runtime version: %w", err)
+		}
+	}
+
+	return version.APIItems(), nil
+}
+
 // Metadata calls runtime function Metadata_metadata
 func (in *Instance) Metadata() ([]byte, error) {
 	return in.Exec(runtime.Metadata, []byte{})
@@ -98,7 +116,17 @@ func (in *Instance) InitializeBlock(header *types.Header) error {
 		return fmt.Errorf("cannot encode header: %w", err)
 	}
 
-	_, err = in.Exec(runtime.CoreInitializeBlock, encodedHeader)
+	apiItems, err := in.apiItems()
+	if err != nil {
+		return err
+	}
+
+	function, err := runtime.InitializeBlockFunction(apiItems)
+	if err != nil {
+		return err
+	}
+
+	_, err = in.Exec(function, encodedHeader)
 	return err
 }
 
@@ -114,7 +142,17 @@ func (in *Instance) ApplyExtrinsic(data types.Extrinsic) ([]byte, error) {
 
 // FinalizeBlock calls runtime API function BlockBuilder_finalize_block
 func (in *Instance) FinalizeBlock() (*types.Header, error) {
-	data, err := in.Exec(runtime.BlockBuilderFinalizeBlock, []byte{})
+	apiItems, err := in.apiItems()
+	if err != nil {
+		return nil, err
+	}
+
+	function, err := runtime.FinalizeBlockFunction(apiItems)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := in.Exec(function, []byte{})
 	if err != nil {
 		return nil, err
 	}
@@ -161,16 +199,71 @@ func (in *Instance) ExecuteBlock(block *types.Block) ([]byte, error) {
 
 // DecodeSessionKeys decodes the given public session keys. Returns a list of raw public keys including their key type.
 func (in *Instance) DecodeSessionKeys(enc []byte) ([]byte, error) {
+	apiItems, err := in.apiItems()
+	if err != nil {
+		return nil, err
+	}
+
+	err = runtime.CheckDecodeSessionKeys(apiItems)
+	if err != nil {
+		return nil, err
+	}
+
 	return in.Exec(runtime.DecodeSessionKeys, enc)
 }
 
 // PaymentQueryInfo returns information of a given extrinsic
-func (*Instance) PaymentQueryInfo([]byte) (*types.TransactionPaymentQueryInfo, error) {
-	// TODO: implement the payment query info (see issue #1892)
-	return nil, errors.New("not implemented yet")
+func (in *Instance) PaymentQueryInfo(ext []byte) (*types.TransactionPaymentQueryInfo, error) {
+	apiItems, err := in.apiItems()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := runtime.EncodePaymentQueryInfoArgs(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	resBytes, err := in.Exec(runtime.TransactionPaymentAPIQueryInfo, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.DecodePaymentQueryInfo(apiItems, resBytes)
+}
+
+// OffchainWorker calls runtime API function OffchainWorkerApi_offchain_worker
+// for the block with the given header
+func (in *Instance) OffchainWorker(header *types.Header) error {
+	apiItems, err := in.apiItems()
+	if err != nil {
+		return err
+	}
+
+	args, err := runtime.EncodeOffchainWorkerArgs(apiItems, header)
+	if err != nil {
+		return err
+	}
+
+	_, err = in.Exec(runtime.OffchainWorker, args)
+	return err
+}
+
+// CheckInherents calls runtime API function BlockBuilder_check_inherents
+// with the block given and the SCALE encoded inherent data.
+func (in *Instance) CheckInherents(block *types.Block, inherentData []byte) (*runtime.CheckInherentsResult, error) {
+	args, err := runtime.EncodeCheckInherentsArgs(block, inherentData)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := in.Exec(runtime.BlockBuilderCheckInherents, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.DecodeCheckInherentsResult(ret)
 }
 
-func (in *Instance) CheckInherents()      {} //nolint:revive
 func (in *Instance) RandomSeed()          {} //nolint:revive
-func (in *Instance) OffchainWorker()      {} //nolint:revive
 func (in *Instance) GenerateSessionKeys() {} //nolint:revive
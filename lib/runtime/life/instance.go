@@ -9,12 +9,16 @@
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/crypto"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/offchain"
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
+	"github.com/ChainSafe/gossamer/lib/trie"
 
 	"github.com/perlin-network/life/exec"
 	wasm_validation "github.com/perlin-network/life/wasm-validation"
@@ -26,28 +30,52 @@
 // Check that runtime interfaces are satisfied
 var (
 	_      runtime.Instance = (*Instance)(nil)
+	_      runtime.Cloner   = (*Instance)(nil)
+	_      runtime.Memory   = (*Memory)(nil)
 	logger                  = log.NewFromGlobal(
 		log.AddContext("pkg", "runtime"),
 		log.AddContext("component", "perlin/life"),
 	)
-	ctx *runtime.Context
 )
 
 // Config represents a life configuration
 type Config struct {
 	runtime.InstanceConfig
+	// Resolver resolves the imports of the runtime. If it is nil or a *Resolver,
+	// a new *Resolver running the host functions with the instance context is used.
 	Resolver exec.ImportResolver
 }
 
 // Instance is a runtime life instance
 type Instance struct {
-	vm *exec.VirtualMachine
-	mu sync.Mutex
+	vm       *exec.VirtualMachine
+	ctx      *runtime.Context
+	resolver exec.ImportResolver
+	codeHash common.Hash
+	// code is the decompressed wasm code of the instance, kept to clone it.
+	code []byte
+	// heapPages is the number of heap pages given to the runtime,
+	// as found at :heappages when the instance was set up.
+	heapPages uint64
+	// version is the version of the code, used to dispatch the
+	// runtime API calls. It is nil until Core_version is called.
+	version runtime.Version
+	mu      sync.Mutex
+}
+
+// GetHeapPages returns the number of heap pages given to the runtime
+func (in *Instance) GetHeapPages() uint64 {
+	return in.heapPages
 }
 
 // GetCodeHash returns code hash of the runtime
-func (*Instance) GetCodeHash() common.Hash {
-	return common.Hash{}
+func (in *Instance) GetCodeHash() common.Hash {
+	return in.codeHash
+}
+
+// GetContext returns the context of the instance
+func (in *Instance) GetContext() *runtime.Context {
+	return in.ctx
 }
 
 // NewRuntimeFromGenesis creates a runtime instance from the genesis data
@@ -61,7 +89,18 @@ func NewRuntimeFromGenesis(cfg *Config) (runtime.Instance, error) {
 		return nil, fmt.Errorf("cannot find :code in state")
 	}
 
-	cfg.Resolver = new(Resolver)
+	cfg.Resolver = nil
+	return NewInstance(code, cfg)
+}
+
+// NewInstanceFromTrie returns a new runtime instance with the code provided in the given trie
+func NewInstanceFromTrie(t *trie.Trie, cfg *Config) (*Instance, error) {
+	code := t.Get(common.CodeKey)
+	if len(code) == 0 {
+		return nil, fmt.Errorf("cannot find :code in trie")
+	}
+
+	cfg.Resolver = nil
 	return NewInstance(code, cfg)
 }
 
@@ -80,7 +119,7 @@ func NewInstanceFromFile(fp string, cfg *Config) (*Instance, error) {
 	return NewInstance(bytes, cfg)
 }
 
-// NewInstance ...
+// NewInstance instantiates a runtime from raw wasm bytecode
 func NewInstance(code []byte, cfg *Config) (*Instance, error) {
 	if len(code) == 0 {
 		return nil, errors.New("code is empty")
@@ -88,78 +127,203 @@ func NewInstance(code []byte, cfg *Config) (*Instance, error) {
 
 	logger.Patch(log.SetLevel(cfg.LogLvl))
 
-	vmCfg := exec.VMConfig{
-		DefaultMemoryPages: 23,
+	runtimeCtx := &runtime.Context{
+		Storage:         cfg.Storage,
+		Keystore:        cfg.Keystore,
+		Validator:       cfg.Role == byte(4),
+		NodeStorage:     cfg.NodeStorage,
+		Network:         cfg.Network,
+		Transaction:     cfg.Transaction,
+		SigVerifier:     crypto.NewSignatureVerifier(logger),
+		OffchainHTTPSet: offchain.NewHTTPSet(),
+		Sandbox:         sandbox.NewStore(),
+	}
+
+	codeHash := cfg.CodeHash
+	if codeHash == (common.Hash{}) {
+		var err error
+		codeHash, err = common.Blake2bHash(code)
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash code: %w", err)
+		}
 	}
 
-	instance, err := exec.NewVirtualMachine(code, vmCfg, cfg.Resolver, nil)
+	inst := &Instance{
+		ctx:      runtimeCtx,
+		resolver: newResolver(cfg.Resolver, runtimeCtx),
+		codeHash: codeHash,
+	}
+
+	err := inst.setupInstanceVM(code)
 	if err != nil {
 		return nil, err
 	}
 
-	memory := &Memory{
-		memory: instance.Memory,
+	logger.Debugf("creating new runtime instance with context: %v", runtimeCtx)
+	return inst, nil
+}
+
+// newResolver returns the resolver given, or a new *Resolver for the context
+// if the resolver given is nil or a *Resolver bound to another context.
+func newResolver(resolver exec.ImportResolver, ctx *runtime.Context) exec.ImportResolver {
+	if _, ok := resolver.(*Resolver); resolver != nil && !ok {
+		return resolver
 	}
+	return &Resolver{ctx: ctx}
+}
 
-	// TODO: use __heap_base (#1874)
-	allocator := runtime.NewAllocator(memory, 0)
+// Clone returns a new instance running the same code with the same number of heap
+// pages, sharing the node services of the instance but with its own memory and
+// context. The storage of the clone is nil and must be set before calling it.
+func (in *Instance) Clone() (runtime.Instance, error) {
+	in.mu.Lock()
+	code, heapPages := in.code, in.heapPages
+	in.mu.Unlock()
+
+	ctx := &runtime.Context{
+		Keystore:        in.ctx.Keystore,
+		Validator:       in.ctx.Validator,
+		NodeStorage:     in.ctx.NodeStorage,
+		Network:         in.ctx.Network,
+		Transaction:     in.ctx.Transaction,
+		SigVerifier:     crypto.NewSignatureVerifier(logger),
+		OffchainHTTPSet: offchain.NewHTTPSet(),
+		Sandbox:         sandbox.NewStore(),
+	}
 
-	runtimeCtx := &runtime.Context{
-		Storage:     cfg.Storage,
-		Allocator:   allocator,
-		Keystore:    cfg.Keystore,
-		Validator:   cfg.Role == byte(4),
-		NodeStorage: cfg.NodeStorage,
-		Network:     cfg.Network,
-		Transaction: cfg.Transaction,
-		SigVerifier: crypto.NewSignatureVerifier(logger),
+	clone := &Instance{
+		ctx:      ctx,
+		resolver: newResolver(in.resolver, ctx),
+		codeHash: in.codeHash,
 	}
 
-	logger.Debugf("creating new runtime instance with context: %v", runtimeCtx)
+	err := clone.instantiate(code, heapPages)
+	if err != nil {
+		return nil, fmt.Errorf("cannot instantiate clone: %w", err)
+	}
 
-	inst := &Instance{
-		vm: instance,
+	in.mu.Lock()
+	clone.version = in.version
+	in.mu.Unlock()
+
+	return clone, nil
+}
+
+// setupInstanceVM instantiates the given code, with a memory sized to give the
+// runtime the number of heap pages stored at :heappages in the context storage.
+func (in *Instance) setupInstanceVM(code []byte) error {
+	code, err := runtime.DecompressWasm(code)
+	if err != nil {
+		return fmt.Errorf("cannot decompress WASM code: %w", err)
 	}
 
-	ctx = runtimeCtx
-	return inst, nil
+	heapPages, err := runtime.GetHeapPages(in.ctx.Storage)
+	if err != nil {
+		return fmt.Errorf("cannot get heap pages: %w", err)
+	}
+
+	return in.instantiate(code, heapPages)
+}
+
+// instantiate instantiates the given decompressed code with a memory sized to give
+// the runtime the heap pages given, and sets up the allocator to start at the heap
+// base exported by the code.
+func (in *Instance) instantiate(code []byte, heapPages uint64) error {
+	moduleMemory, err := runtime.GetModuleMemory(code)
+	if err != nil {
+		return fmt.Errorf("cannot get memory of wasm module: %w", err)
+	}
+	pages := moduleMemory.Pages(heapPages)
+
+	vmCfg := exec.VMConfig{
+		// size of the memory imported by newer runtimes
+		DefaultMemoryPages: int(pages),
+		MaxMemoryPages:     int(moduleMemory.MaximumPages),
+	}
+
+	vm, err := exec.NewVirtualMachine(code, vmCfg, in.resolver, nil)
+	if err != nil {
+		return err
+	}
+
+	memory := &Memory{vm: vm}
+	if currentPages := memory.Length() / runtime.PageSize; currentPages < pages {
+		// the runtime defines its own memory, grow it to give the runtime its heap pages.
+		err = memory.Grow(pages - currentPages)
+		if err != nil {
+			return fmt.Errorf("cannot grow memory by %d pages: %w", pages-currentPages, err)
+		}
+	}
+
+	in.vm = vm
+	in.code = code
+	in.heapPages = heapPages
+	in.version = nil
+	in.ctx.Allocator = runtime.NewAllocator(memory, moduleMemory.HeapBase)
+	in.ctx.Supervisor = &sandboxSupervisor{vm: vm, ctx: in.ctx}
+	return nil
 }
 
 // Memory is a thin wrapper around life's memory to support
 // Gossamer runtime.Memory interface
 type Memory struct {
-	memory []byte
+	vm *exec.VirtualMachine
 }
 
 // Data returns the memory's data
 func (m *Memory) Data() []byte {
-	return m.memory
+	return m.vm.Memory
 }
 
 // Length returns the memory's length
 func (m *Memory) Length() uint32 {
-	return uint32(len(m.memory))
+	return uint32(len(m.vm.Memory))
 }
 
-// Grow ...
+// Grow grows the memory by the given number of pages
 func (m *Memory) Grow(numPages uint32) error {
-	m.memory = append(m.memory, make([]byte, runtime.PageSize*numPages)...)
+	maxPages := m.vm.Config.MaxMemoryPages
+	if pages := int(m.Length()/runtime.PageSize + numPages); maxPages != 0 && pages > maxPages {
+		return fmt.Errorf("cannot grow memory to %d pages, maximum is %d pages", pages, maxPages)
+	}
+
+	m.vm.Memory = append(m.vm.Memory, make([]byte, runtime.PageSize*numPages)...)
 	return nil
 }
 
-// UpdateRuntimeCode ...
-func (*Instance) UpdateRuntimeCode(_ []byte) error {
-	return errors.New("unimplemented")
+// UpdateRuntimeCode updates the runtime instance to run the given code
+func (in *Instance) UpdateRuntimeCode(code []byte) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return in.setupInstanceVM(code)
 }
 
-// CheckRuntimeVersion ...
-func (*Instance) CheckRuntimeVersion(_ []byte) (runtime.Version, error) {
-	return nil, errors.New("unimplemented")
+// CheckRuntimeVersion calculates runtime Version for runtime blob passed in
+func (in *Instance) CheckRuntimeVersion(code []byte) (runtime.Version, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	// copy the context so the allocator of the instance is left untouched.
+	ctx := *in.ctx
+	tmp := &Instance{
+		ctx:      &ctx,
+		resolver: newResolver(in.resolver, &ctx),
+	}
+
+	err := tmp.setupInstanceVM(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return tmp.Version()
 }
 
 // SetContextStorage sets the runtime's storage. It should be set before calls to the below functions.
-func (*Instance) SetContextStorage(s runtime.Storage) {
-	ctx.Storage = s
+func (in *Instance) SetContextStorage(s runtime.Storage) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.ctx.Storage = s
 }
 
 // Exec calls the given function with the given data
@@ -167,11 +331,16 @@ func (in *Instance) Exec(function string, data []byte) ([]byte, error) {
 	in.mu.Lock()
 	defer in.mu.Unlock()
 
-	ptr, err := ctx.Allocator.Allocate(uint32(len(data)))
+	if in.ctx.Storage == nil {
+		return nil, runtime.ErrNilStorage
+	}
+
+	ptr, err := in.ctx.Allocator.Allocate(uint32(len(data)))
 	if err != nil {
 		return nil, err
 	}
-	defer ctx.Allocator.Clear()
+	defer in.ctx.Allocator.Clear()
+	defer in.ctx.Sandbox.Reset()
 
 	copy(in.vm.Memory[ptr:ptr+uint32(len(data))], data)
 
@@ -180,9 +349,17 @@ func (in *Instance) Exec(function string, data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("could not find exported function %s", function)
 	}
 
+	if tracer, ok := in.ctx.Storage.(runtime.CallTracer); ok {
+		tracer.TraceCall(function)
+		defer tracer.TraceCall("")
+	}
+
+	memoryLength := len(in.vm.Memory)
+	start := time.Now()
 	ret, err := in.vm.Run(fnc, int64(ptr), int64(len(data)))
+	runtime.ObserveExportCall(function, time.Since(start), uint32(len(in.vm.Memory)-memoryLength))
 	if err != nil {
-		fmt.Println(in.vm.StackTrace)
+		logger.Debugf("runtime stack trace: %s", in.vm.StackTrace)
 		return nil, err
 	}
 
@@ -194,21 +371,21 @@ func (in *Instance) Exec(function string, data []byte) ([]byte, error) {
 func (*Instance) Stop() {}
 
 // NodeStorage to get reference to runtime node service
-func (*Instance) NodeStorage() runtime.NodeStorage {
-	return ctx.NodeStorage
+func (in *Instance) NodeStorage() runtime.NodeStorage {
+	return in.ctx.NodeStorage
 }
 
 // NetworkService to get referernce to runtime network service
-func (*Instance) NetworkService() runtime.BasicNetwork {
-	return ctx.Network
+func (in *Instance) NetworkService() runtime.BasicNetwork {
+	return in.ctx.Network
 }
 
 // Validator returns the context's Validator
-func (*Instance) Validator() bool {
-	return ctx.Validator
+func (in *Instance) Validator() bool {
+	return in.ctx.Validator
 }
 
 // Keystore to get reference to runtime keystore
-func (*Instance) Keystore() *keystore.GlobalKeystore {
-	return ctx.Keystore
+func (in *Instance) Keystore() *keystore.GlobalKeystore {
+	return in.ctx.Keystore
 }
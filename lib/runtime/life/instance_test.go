@@ -0,0 +1,45 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package life
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/perlin-network/life/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Memory_Grow(t *testing.T) {
+	t.Parallel()
+
+	vm := &exec.VirtualMachine{
+		Config: exec.VMConfig{MaxMemoryPages: 3},
+		Memory: make([]byte, runtime.PageSize),
+	}
+	memory := &Memory{vm: vm}
+
+	err := memory.Grow(2)
+	require.NoError(t, err)
+	// the memory of the virtual machine is grown
+	assert.Len(t, vm.Memory, 3*runtime.PageSize)
+	assert.Equal(t, uint32(3*runtime.PageSize), memory.Length())
+
+	err = memory.Grow(1)
+	assert.EqualError(t, err, "cannot grow memory to 4 pages, maximum is 3 pages")
+	assert.Len(t, vm.Memory, 3*runtime.PageSize)
+}
+
+func Test_newResolver(t *testing.T) {
+	t.Parallel()
+
+	ctx := &runtime.Context{}
+
+	resolver := newResolver(nil, ctx)
+	assert.Equal(t, &Resolver{ctx: ctx}, resolver)
+
+	resolver = newResolver(&Resolver{ctx: &runtime.Context{}}, ctx)
+	assert.Same(t, ctx, resolver.(*Resolver).ctx)
+}
@@ -4,12 +4,15 @@
 package life
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
+	"math/rand"
+	"reflect"
+	"time"
 
+	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
 	rtype "github.com/ChainSafe/gossamer/lib/common/types"
 	"github.com/ChainSafe/gossamer/lib/crypto"
@@ -17,109 +20,222 @@
 	"github.com/ChainSafe/gossamer/lib/crypto/secp256k1"
 	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/offchain"
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
+	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 	"github.com/perlin-network/life/exec"
 )
 
-// Resolver resolves the imports for life
-type Resolver struct{} // TODO: move context inside resolver (#1875)
+// Resolver resolves the imports for life, the host functions are
+// run with the runtime context of the instance the resolver belongs to.
+type Resolver struct {
+	ctx *runtime.Context
+}
+
+// ResolveFunc returns the host function with the given name. The host functions
+// are the same as the ones given to wasmer runtime instances, and the calls to the
+// host functions of the families measured are measured.
+func (*Resolver) ResolveFunc(module, field string) exec.FunctionImport {
+	hostFunction := resolveFunc(module, field)
+
+	family, ok := runtime.HostFunctionFamilyOf(field)
+	if !ok {
+		return hostFunction
+	}
 
-// ResolveFunc ...
-func (*Resolver) ResolveFunc(module, field string) exec.FunctionImport { //nolint:gocyclo
+	return func(vm *exec.VirtualMachine) int64 {
+		defer runtime.StartHostFunction(family)()
+		return hostFunction(vm)
+	}
+}
+
+func resolveFunc(module, field string) exec.FunctionImport { //nolint:gocyclo
 	switch module {
 	case "env":
 		switch field {
-		case "ext_logging_log_version_1":
-			return ext_logging_log_version_1
-		case "ext_misc_print_utf8_version_1":
-			return ext_misc_print_utf8_version_1
-		case "ext_misc_print_hex_version_1":
-			return ext_misc_print_hex_version_1
-		case "ext_allocator_malloc_version_1":
-			return ext_allocator_malloc_version_1
 		case "ext_allocator_free_version_1":
 			return ext_allocator_free_version_1
-		case "ext_hashing_blake2_256_version_1":
-			return ext_hashing_blake2_256_version_1
-		case "ext_hashing_twox_128_version_1":
-			return ext_hashing_twox_128_version_1
-		case "ext_storage_get_version_1":
-			return ext_storage_get_version_1
-		case "ext_storage_set_version_1":
-			return ext_storage_set_version_1
-		case "ext_storage_next_key_version_1":
-			return ext_storage_next_key_version_1
-		case "ext_hashing_twox_64_version_1":
-			return ext_hashing_twox_64_version_1
-		case "ext_storage_clear_version_1":
-			return ext_storage_clear_version_1
-		case "ext_storage_clear_prefix_version_1":
-			return ext_storage_clear_prefix_version_1
-		case "ext_storage_read_version_1":
-			return ext_storage_read_version_1
-		case "ext_storage_append_version_1":
-			return ext_storage_append_version_1
-		case "ext_trie_blake2_256_ordered_root_version_1":
-			return ext_trie_blake2_256_ordered_root_version_1
-		case "ext_storage_root_version_1":
-			return ext_storage_root_version_1
-		case "ext_storage_changes_root_version_1":
-			return ext_storage_changes_root_version_1
-		case "ext_crypto_start_batch_verify_version_1":
-			return ext_crypto_start_batch_verify_version_1
-		case "ext_crypto_finish_batch_verify_version_1":
-			return ext_crypto_finish_batch_verify_version_1
-		case "ext_offchain_index_set_version_1":
-			return ext_offchain_index_set_version_1
-		case "ext_storage_exists_version_1":
-			return ext_storage_exists_version_1
-		case "ext_default_child_storage_set_version_1":
-			return ext_default_child_storage_set_version_1
-		case "ext_default_child_storage_get_version_1":
-			return ext_default_child_storage_get_version_1
-		case "ext_default_child_storage_read_version_1":
-			return ext_default_child_storage_read_version_1
-		case "ext_default_child_storage_clear_version_1":
-			return ext_default_child_storage_clear_version_1
-		case "ext_default_child_storage_storage_kill_version_1":
-			return ext_default_child_storage_storage_kill_version_1
-		case "ext_default_child_storage_exists_version_1":
-			return ext_default_child_storage_exists_version_1
-		case "ext_default_child_storage_clear_prefix_version_1":
-			return ext_default_child_storage_clear_prefix_version_1
-		case "ext_default_child_storage_root_version_1":
-			return ext_default_child_storage_root_version_1
-		case "ext_default_child_storage_next_key_version_1":
-			return ext_default_child_storage_next_key_version_1
-		case "ext_crypto_ed25519_public_keys_version_1":
-			return ext_crypto_ed25519_public_keys_version_1
+		case "ext_allocator_malloc_version_1":
+			return ext_allocator_malloc_version_1
+		case "ext_crypto_ecdsa_verify_version_2":
+			return ext_crypto_ecdsa_verify_version_2
 		case "ext_crypto_ed25519_generate_version_1":
 			return ext_crypto_ed25519_generate_version_1
+		case "ext_crypto_ed25519_public_keys_version_1":
+			return ext_crypto_ed25519_public_keys_version_1
 		case "ext_crypto_ed25519_sign_version_1":
 			return ext_crypto_ed25519_sign_version_1
 		case "ext_crypto_ed25519_verify_version_1":
 			return ext_crypto_ed25519_verify_version_1
-		case "ext_crypto_sr25519_public_keys_version_1":
-			return ext_crypto_sr25519_public_keys_version_1
+		case "ext_crypto_finish_batch_verify_version_1":
+			return ext_crypto_finish_batch_verify_version_1
+		case "ext_crypto_secp256k1_ecdsa_recover_compressed_version_1":
+			return ext_crypto_secp256k1_ecdsa_recover_compressed_version_1
+		case "ext_crypto_secp256k1_ecdsa_recover_compressed_version_2":
+			return ext_crypto_secp256k1_ecdsa_recover_compressed_version_2
+		case "ext_crypto_secp256k1_ecdsa_recover_version_1":
+			return ext_crypto_secp256k1_ecdsa_recover_version_1
+		case "ext_crypto_secp256k1_ecdsa_recover_version_2":
+			return ext_crypto_secp256k1_ecdsa_recover_version_2
 		case "ext_crypto_sr25519_generate_version_1":
 			return ext_crypto_sr25519_generate_version_1
+		case "ext_crypto_sr25519_public_keys_version_1":
+			return ext_crypto_sr25519_public_keys_version_1
 		case "ext_crypto_sr25519_sign_version_1":
 			return ext_crypto_sr25519_sign_version_1
 		case "ext_crypto_sr25519_verify_version_1":
 			return ext_crypto_sr25519_verify_version_1
-		case "ext_crypto_secp256k1_ecdsa_recover_version_1":
-			return ext_crypto_secp256k1_ecdsa_recover_version_1
+		case "ext_crypto_sr25519_verify_version_2":
+			return ext_crypto_sr25519_verify_version_2
+		case "ext_crypto_start_batch_verify_version_1":
+			return ext_crypto_start_batch_verify_version_1
+		case "ext_default_child_storage_clear_prefix_version_1":
+			return ext_default_child_storage_clear_prefix_version_1
+		case "ext_default_child_storage_clear_version_1":
+			return ext_default_child_storage_clear_version_1
+		case "ext_default_child_storage_exists_version_1":
+			return ext_default_child_storage_exists_version_1
+		case "ext_default_child_storage_get_version_1":
+			return ext_default_child_storage_get_version_1
+		case "ext_default_child_storage_next_key_version_1":
+			return ext_default_child_storage_next_key_version_1
+		case "ext_default_child_storage_read_version_1":
+			return ext_default_child_storage_read_version_1
+		case "ext_default_child_storage_root_version_1":
+			return ext_default_child_storage_root_version_1
+		case "ext_default_child_storage_root_version_2":
+			return ext_default_child_storage_root_version_2
+		case "ext_default_child_storage_set_version_1":
+			return ext_default_child_storage_set_version_1
+		case "ext_default_child_storage_storage_kill_version_1":
+			return ext_default_child_storage_storage_kill_version_1
+		case "ext_default_child_storage_storage_kill_version_2":
+			return ext_default_child_storage_storage_kill_version_2
+		case "ext_default_child_storage_storage_kill_version_3":
+			return ext_default_child_storage_storage_kill_version_3
+		case "ext_hashing_blake2_128_version_1":
+			return ext_hashing_blake2_128_version_1
+		case "ext_hashing_blake2_256_version_1":
+			return ext_hashing_blake2_256_version_1
 		case "ext_hashing_keccak_256_version_1":
 			return ext_hashing_keccak_256_version_1
 		case "ext_hashing_sha2_256_version_1":
 			return ext_hashing_sha2_256_version_1
-		case "ext_hashing_blake2_128_version_1":
-			return ext_hashing_blake2_128_version_1
+		case "ext_hashing_twox_128_version_1":
+			return ext_hashing_twox_128_version_1
 		case "ext_hashing_twox_256_version_1":
 			return ext_hashing_twox_256_version_1
+		case "ext_hashing_twox_64_version_1":
+			return ext_hashing_twox_64_version_1
+		case "ext_logging_log_version_1":
+			return ext_logging_log_version_1
+		case "ext_logging_max_level_version_1":
+			return ext_logging_max_level_version_1
+		case "ext_misc_print_hex_version_1":
+			return ext_misc_print_hex_version_1
+		case "ext_misc_print_num_version_1":
+			return ext_misc_print_num_version_1
+		case "ext_misc_print_utf8_version_1":
+			return ext_misc_print_utf8_version_1
+		case "ext_misc_runtime_version_version_1":
+			return ext_misc_runtime_version_version_1
+		case "ext_offchain_http_request_add_header_version_1":
+			return ext_offchain_http_request_add_header_version_1
+		case "ext_offchain_http_request_start_version_1":
+			return ext_offchain_http_request_start_version_1
+		case "ext_offchain_http_request_write_body_version_1":
+			return ext_offchain_http_request_write_body_version_1
+		case "ext_offchain_http_response_headers_version_1":
+			return ext_offchain_http_response_headers_version_1
+		case "ext_offchain_http_response_read_body_version_1":
+			return ext_offchain_http_response_read_body_version_1
+		case "ext_offchain_http_response_wait_version_1":
+			return ext_offchain_http_response_wait_version_1
+		case "ext_offchain_index_set_version_1":
+			return ext_offchain_index_set_version_1
+		case "ext_offchain_is_validator_version_1":
+			return ext_offchain_is_validator_version_1
+		case "ext_offchain_local_storage_clear_version_1":
+			return ext_offchain_local_storage_clear_version_1
+		case "ext_offchain_local_storage_compare_and_set_version_1":
+			return ext_offchain_local_storage_compare_and_set_version_1
+		case "ext_offchain_local_storage_get_version_1":
+			return ext_offchain_local_storage_get_version_1
+		case "ext_offchain_local_storage_set_version_1":
+			return ext_offchain_local_storage_set_version_1
+		case "ext_offchain_network_state_version_1":
+			return ext_offchain_network_state_version_1
+		case "ext_offchain_random_seed_version_1":
+			return ext_offchain_random_seed_version_1
+		case "ext_offchain_sleep_until_version_1":
+			return ext_offchain_sleep_until_version_1
+		case "ext_offchain_submit_transaction_version_1":
+			return ext_offchain_submit_transaction_version_1
+		case "ext_offchain_timestamp_version_1":
+			return ext_offchain_timestamp_version_1
+		case "ext_sandbox_get_global_val_version_1":
+			return ext_sandbox_get_global_val_version_1
+		case "ext_sandbox_instance_teardown_version_1":
+			return ext_sandbox_instance_teardown_version_1
+		case "ext_sandbox_instantiate_version_1":
+			return ext_sandbox_instantiate_version_1
+		case "ext_sandbox_invoke_version_1":
+			return ext_sandbox_invoke_version_1
+		case "ext_sandbox_memory_get_version_1":
+			return ext_sandbox_memory_get_version_1
+		case "ext_sandbox_memory_new_version_1":
+			return ext_sandbox_memory_new_version_1
+		case "ext_sandbox_memory_set_version_1":
+			return ext_sandbox_memory_set_version_1
+		case "ext_sandbox_memory_teardown_version_1":
+			return ext_sandbox_memory_teardown_version_1
+		case "ext_storage_append_version_1":
+			return ext_storage_append_version_1
+		case "ext_storage_changes_root_version_1":
+			return ext_storage_changes_root_version_1
+		case "ext_storage_clear_prefix_version_1":
+			return ext_storage_clear_prefix_version_1
+		case "ext_storage_clear_prefix_version_2":
+			return ext_storage_clear_prefix_version_2
+		case "ext_storage_clear_version_1":
+			return ext_storage_clear_version_1
+		case "ext_storage_commit_transaction_version_1":
+			return ext_storage_commit_transaction_version_1
+		case "ext_storage_exists_version_1":
+			return ext_storage_exists_version_1
+		case "ext_storage_get_version_1":
+			return ext_storage_get_version_1
+		case "ext_storage_next_key_version_1":
+			return ext_storage_next_key_version_1
+		case "ext_storage_read_version_1":
+			return ext_storage_read_version_1
+		case "ext_storage_rollback_transaction_version_1":
+			return ext_storage_rollback_transaction_version_1
+		case "ext_storage_root_version_1":
+			return ext_storage_root_version_1
+		case "ext_storage_root_version_2":
+			return ext_storage_root_version_2
+		case "ext_storage_set_version_1":
+			return ext_storage_set_version_1
+		case "ext_storage_start_transaction_version_1":
+			return ext_storage_start_transaction_version_1
+		case "ext_transaction_index_index_version_1":
+			return ext_transaction_index_index_version_1
+		case "ext_transaction_index_renew_version_1":
+			return ext_transaction_index_renew_version_1
+		case "ext_trie_blake2_256_ordered_root_version_1":
+			return ext_trie_blake2_256_ordered_root_version_1
+		case "ext_trie_blake2_256_ordered_root_version_2":
+			return ext_trie_blake2_256_ordered_root_version_2
 		case "ext_trie_blake2_256_root_version_1":
 			return ext_trie_blake2_256_root_version_1
+		case "ext_trie_blake2_256_root_version_2":
+			return ext_trie_blake2_256_root_version_2
+		case "ext_trie_blake2_256_verify_proof_version_1":
+			return ext_trie_blake2_256_verify_proof_version_1
 		default:
 			panic(fmt.Errorf("unknown import resolved: %s", field))
 		}
@@ -133,1228 +249,2452 @@ func (*Resolver) ResolveGlobal(_, _ string) int64 {
 	panic("we're not resolving global variables for now")
 }
 
+// getContext returns the runtime context of the instance run by the virtual machine.
+func getContext(vm *exec.VirtualMachine) *runtime.Context {
+	return vm.ImportResolver.(*Resolver).ctx
+}
+
 func ext_logging_log_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
 	level := int32(vm.GetCurrentFrame().Locals[0])
 	targetData := vm.GetCurrentFrame().Locals[1]
 	msgData := vm.GetCurrentFrame().Locals[2]
 
-	target := asMemorySlice(vm.Memory, targetData)
-	msg := asMemorySlice(vm.Memory, msgData)
+	target := string(asMemorySlice(vm, targetData))
+	msg := string(asMemorySlice(vm, msgData))
 
 	switch int(level) {
 	case 0:
-		logger.Criticalf("target=%s message=%s", string(target), string(msg))
+		logger.Critical("target=" + target + " message=" + msg)
 	case 1:
-		logger.Warnf("target=%s message=%s", string(target), string(msg))
+		logger.Warn("target=" + target + " message=" + msg)
 	case 2:
-		logger.Infof("target=%s message=%s", string(target), string(msg))
+		logger.Info("target=" + target + " message=" + msg)
 	case 3:
-		logger.Debugf("target=%s message=%s", string(target), string(msg))
+		logger.Debug("target=" + target + " message=" + msg)
 	case 4:
-		logger.Tracef("target=%s message=%s", string(target), string(msg))
+		logger.Trace("target=" + target + " message=" + msg)
 	default:
-		logger.Errorf("level=%d target=%s message=%s", level, string(target), string(msg))
+		logger.Errorf("level=%d target=%s message=%s", int(level), target, msg)
 	}
-
 	return 0
 }
 
-func ext_misc_print_utf8_version_1(vm *exec.VirtualMachine) int64 {
+func ext_logging_max_level_version_1(_ *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	data := asMemorySlice(vm.Memory, dataSpan)
-	logger.Debugf("utf8 data: 0x%x", data)
-	return 0
+	return 4
 }
 
-func ext_misc_print_hex_version_1(vm *exec.VirtualMachine) int64 {
+func ext_transaction_index_index_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	data := asMemorySlice(vm.Memory, dataSpan)
-	logger.Debugf("data is: 0x%x", data)
-	return 0
-}
-
-func ext_allocator_malloc_version_1(vm *exec.VirtualMachine) int64 {
-	size := uint32(vm.GetCurrentFrame().Locals[0])
-	logger.Tracef("executing with size %d...", size)
+	extrinsic := int32(vm.GetCurrentFrame().Locals[0])
+	size := int32(vm.GetCurrentFrame().Locals[1])
+	contextHash := int32(vm.GetCurrentFrame().Locals[2])
 
-	// Allocate memory
-	res, err := ctx.Allocator.Allocate(size)
-	if err != nil {
-		logger.Errorf("[ext_allocator_malloc_version_1]: %s", err)
-		panic(err)
-	}
+	runtimeCtx := getContext(vm)
 
-	return int64(res)
+	hash := common.BytesToHash(vm.Memory[contextHash : contextHash+32])
+	runtimeCtx.Storage.IndexTransaction(uint32(extrinsic), uint32(size), hash)
+	return 0
 }
 
-func ext_allocator_free_version_1(vm *exec.VirtualMachine) int64 {
-	addr := uint32(vm.GetCurrentFrame().Locals[0])
-	logger.Tracef("executing at address %d...", addr)
+func ext_transaction_index_renew_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	extrinsic := int32(vm.GetCurrentFrame().Locals[0])
+	contextHash := int32(vm.GetCurrentFrame().Locals[1])
 
-	// Deallocate memory
-	err := ctx.Allocator.Deallocate(addr)
-	if err != nil {
-		logger.Errorf("[ext_allocator_free_version_1]: %s", err)
-		panic(err)
-	}
+	runtimeCtx := getContext(vm)
 
+	hash := common.BytesToHash(vm.Memory[contextHash : contextHash+32])
+	runtimeCtx.Storage.RenewTransaction(uint32(extrinsic), hash)
 	return 0
 }
 
-func ext_hashing_blake2_256_version_1(vm *exec.VirtualMachine) int64 {
+// sandboxReturnCode converts a sandbox return code to the int32 the runtime expects
+func sandboxReturnCode(code uint32) int64 {
+	return int64(int32(code))
+}
+
+func ext_sandbox_get_global_val_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	dataSpan := vm.GetCurrentFrame().Locals[0]
+	instanceIdx := int32(vm.GetCurrentFrame().Locals[0])
+	nameSpan := vm.GetCurrentFrame().Locals[1]
 
-	data := asMemorySlice(vm.Memory, dataSpan)
+	runtimeCtx := getContext(vm)
+	name := string(asMemorySlice(vm, nameSpan))
 
-	hash, err := common.Blake2bHash(data)
+	var enc []byte
+	inst, err := runtimeCtx.Sandbox.Instance(uint32(instanceIdx))
 	if err != nil {
-		logger.Errorf("[ext_hashing_blake2_256_version_1]: %s", err)
-		return 0
+		logger.Errorf("failed to get sandbox instance: %s", err)
+		enc = []byte{0}
+	} else if value, ok := inst.GetGlobal(name); !ok {
+		enc = []byte{0}
+	} else {
+		vdt := sandbox.NewValue()
+		err = vdt.Set(value)
+		if err == nil {
+			enc, err = scale.Marshal(vdt)
+		}
+		if err != nil {
+			logger.Errorf("failed to encode global value: %s", err)
+			return 0
+		}
+		enc = append([]byte{1}, enc...)
 	}
 
-	logger.Debugf("data is 0x%x and hash is 0x%x", data, hash)
-
-	out, err := toWasmMemorySized(vm.Memory, hash[:], 32)
+	ret, err := toWasmMemory(vm, enc)
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
+		logger.Errorf("failed to allocate memory: %s", err)
 		return 0
 	}
 
-	return int64(out)
+	return ret
 }
 
-func ext_hashing_twox_128_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_instance_teardown_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	data := asMemorySlice(vm.Memory, dataSpan)
+	instanceIdx := int32(vm.GetCurrentFrame().Locals[0])
 
-	hash, err := common.Twox128Hash(data)
+	runtimeCtx := getContext(vm)
+
+	err := runtimeCtx.Sandbox.TeardownInstance(uint32(instanceIdx))
 	if err != nil {
-		logger.Errorf("[ext_hashing_twox_128_version_1]: %s", err)
-		return 0
+		logger.Errorf("failed to teardown sandbox instance: %s", err)
 	}
+	return 0
+}
 
-	logger.Debugf("data is 0x%x and hash is 0x%x", data, hash)
+func ext_sandbox_instantiate_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	dispatchThunk := int32(vm.GetCurrentFrame().Locals[0])
+	wasmCodeSpan := vm.GetCurrentFrame().Locals[1]
+	envDefSpan := vm.GetCurrentFrame().Locals[2]
+	statePtr := int32(vm.GetCurrentFrame().Locals[3])
 
-	out, err := toWasmMemorySized(vm.Memory, hash, 16)
-	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
-		return 0
+	runtimeCtx := getContext(vm)
+
+	// copy the inputs, since the supervisor memory may grow while the start function runs
+	code := append([]byte{}, asMemorySlice(vm, wasmCodeSpan)...)
+	envDef := append([]byte{}, asMemorySlice(vm, envDefSpan)...)
+
+	idx, err := runtimeCtx.Sandbox.Instantiate(uint32(dispatchThunk), code, envDef, uint32(statePtr),
+		runtimeCtx.Supervisor)
+	if errors.Is(err, sandbox.ErrExecution) {
+		logger.Errorf("failed to run sandbox instance start function: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	} else if err != nil {
+		logger.Errorf("failed to instantiate sandbox module: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrModule)
 	}
 
-	return int64(out)
+	return int64(idx)
 }
 
-func ext_hashing_twox_64_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_invoke_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	data := asMemorySlice(vm.Memory, dataSpan)
+	instanceIdx := int32(vm.GetCurrentFrame().Locals[0])
+	exportNameSpan := vm.GetCurrentFrame().Locals[1]
+	argsSpan := vm.GetCurrentFrame().Locals[2]
+	returnValPtr := int32(vm.GetCurrentFrame().Locals[3])
+	returnValLen := int32(vm.GetCurrentFrame().Locals[4])
+	statePtr := int32(vm.GetCurrentFrame().Locals[5])
 
-	hash, err := common.Twox64(data)
+	runtimeCtx := getContext(vm)
+
+	exportName := string(asMemorySlice(vm, exportNameSpan))
+
+	inst, err := runtimeCtx.Sandbox.Instance(uint32(instanceIdx))
 	if err != nil {
-		logger.Errorf("[ext_hashing_twox_64_version_1]: %s", err)
-		return 0
+		logger.Errorf("failed to get sandbox instance: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	args, err := sandbox.DecodeValues(asMemorySlice(vm, argsSpan))
+	if err != nil {
+		logger.Errorf("failed to decode sandbox invocation arguments: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
 	}
 
-	logger.Debugf("data is 0x%x and hash is 0x%x", data, hash)
+	res, err := inst.Invoke(exportName, args, uint32(statePtr), runtimeCtx.Supervisor)
+	if err != nil {
+		logger.Errorf("failed to invoke %s on sandbox instance %d: %s", exportName, instanceIdx, err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
 
-	out, err := toWasmMemorySized(vm.Memory, hash, 8)
+	enc, err := sandbox.EncodeReturnValue(res)
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
-		return 0
+		logger.Errorf("failed to encode sandbox return value: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
 	}
 
-	return int64(out)
+	if len(enc) > int(uint32(returnValLen)) {
+		logger.Errorf("return value of %d bytes does not fit in buffer of %d bytes", len(enc), uint32(returnValLen))
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	// the memory is loaded after the invocation, since it may have grown in the meantime
+	ptr := uint64(uint32(returnValPtr))
+	if ptr+uint64(len(enc)) > uint64(len(vm.Memory)) {
+		logger.Errorf("return value pointer 0x%x is out of bounds", ptr)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	copy(vm.Memory[ptr:], enc)
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
-func ext_storage_get_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_memory_get_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	storage := ctx.Storage
+	memoryIdx := int32(vm.GetCurrentFrame().Locals[0])
+	offset := int32(vm.GetCurrentFrame().Locals[1])
+	bufPtr := int32(vm.GetCurrentFrame().Locals[2])
+	bufLen := int32(vm.GetCurrentFrame().Locals[3])
 
-	key := asMemorySlice(vm.Memory, keySpan)
-	logger.Debugf("key: 0x%x", key)
+	runtimeCtx := getContext(vm)
 
-	value := storage.Get(key)
-	logger.Debugf("value: 0x%x", value)
+	mem, err := runtimeCtx.Sandbox.Memory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	start, end := uint64(uint32(bufPtr)), uint64(uint32(bufPtr))+uint64(uint32(bufLen))
+	if end > uint64(len(vm.Memory)) {
+		logger.Errorf("buffer [0x%x, 0x%x) is out of bounds of supervisor memory", start, end)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
 
-	valueSpan, err := toWasmMemoryOptional(vm.Memory, value)
+	err = mem.Get(uint32(offset), vm.Memory[start:end])
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
-		ptr, _ := toWasmMemoryOptional(vm.Memory, nil)
-		return ptr
+		logger.Debugf("failed to read sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
 	}
 
-	return valueSpan
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
-func ext_storage_set_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_memory_new_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	valueSpan := vm.GetCurrentFrame().Locals[1]
-	storage := ctx.Storage
+	initial := int32(vm.GetCurrentFrame().Locals[0])
+	maximum := int32(vm.GetCurrentFrame().Locals[1])
 
-	key := asMemorySlice(vm.Memory, keySpan)
-	value := asMemorySlice(vm.Memory, valueSpan)
+	runtimeCtx := getContext(vm)
 
-	logger.Infof("key 0x%x and value 0x%x", key, value)
+	idx, err := runtimeCtx.Sandbox.NewMemory(uint32(initial), uint32(maximum))
+	if err != nil {
+		logger.Errorf("failed to create sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrModule)
+	}
 
-	cp := make([]byte, len(value))
-	copy(cp, value)
-	storage.Set(key, cp)
-	return 0
+	return int64(idx)
 }
 
-func ext_storage_next_key_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_memory_set_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	storage := ctx.Storage
+	memoryIdx := int32(vm.GetCurrentFrame().Locals[0])
+	offset := int32(vm.GetCurrentFrame().Locals[1])
+	valPtr := int32(vm.GetCurrentFrame().Locals[2])
+	valLen := int32(vm.GetCurrentFrame().Locals[3])
 
-	key := asMemorySlice(vm.Memory, keySpan)
+	runtimeCtx := getContext(vm)
 
-	next := storage.NextKey(key)
-	logger.Debugf("key is 0x%x and next is 0x%x", key, next)
+	mem, err := runtimeCtx.Sandbox.Memory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	start, end := uint64(uint32(valPtr)), uint64(uint32(valPtr))+uint64(uint32(valLen))
+	if end > uint64(len(vm.Memory)) {
+		logger.Errorf("value [0x%x, 0x%x) is out of bounds of supervisor memory", start, end)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
 
-	nextSpan, err := toWasmMemoryOptional(vm.Memory, next)
+	err = mem.Set(uint32(offset), vm.Memory[start:end])
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
-		return 0
+		logger.Debugf("failed to write sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
 	}
 
-	return nextSpan
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
-func ext_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
+func ext_sandbox_memory_teardown_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	storage := ctx.Storage
+	memoryIdx := int32(vm.GetCurrentFrame().Locals[0])
 
-	key := asMemorySlice(vm.Memory, keySpan)
+	runtimeCtx := getContext(vm)
 
-	logger.Debugf("key: 0x%x", key)
-	storage.Delete(key)
+	err := runtimeCtx.Sandbox.TeardownMemory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to teardown sandbox memory: %s", err)
+	}
+	return 0
+}
+
+func ext_crypto_ed25519_generate_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+	seedSpan := vm.GetCurrentFrame().Locals[1]
+
+	runtimeCtx := getContext(vm)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+	seedBytes := asMemorySlice(vm, seedSpan)
+
+	var seed *[]byte
+	err := scale.Unmarshal(seedBytes, &seed)
+	if err != nil {
+		logger.Warnf("cannot generate key: %s", err)
+		return 0
+	}
+
+	var kp crypto.Keypair
+
+	if seed != nil {
+		kp, err = ed25519.NewKeypairFromMnenomic(string(*seed), "")
+	} else {
+		kp, err = ed25519.GenerateKeypair()
+	}
+
+	if err != nil {
+		logger.Warnf("cannot generate key: %s", err)
+		return 0
+	}
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id 0x%x: %s", id, err)
+		return 0
+	}
+
+	err = ks.Insert(kp)
+	if err != nil {
+		logger.Warnf("failed to insert key: %s", err)
+		return 0
+	}
+
+	ret, err := toWasmMemorySized(vm, kp.Public().Encode(), 32)
+	if err != nil {
+		logger.Warnf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	logger.Debug("generated ed25519 keypair with public key: " + kp.Public().Hex())
+	return int64(ret)
+}
+
+func ext_crypto_ed25519_public_keys_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+
+	runtimeCtx := getContext(vm)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id 0x%x: %s", id, err)
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	if ks.Type() != crypto.Ed25519Type && ks.Type() != crypto.UnknownType {
+		logger.Warnf(
+			"error for id 0x%x: keystore type is %s and not the expected ed25519",
+			id, ks.Type())
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	keys := ks.PublicKeys()
+
+	var encodedKeys []byte
+	for _, key := range keys {
+		encodedKeys = append(encodedKeys, key.Encode()...)
+	}
+
+	prefix, err := scale.Marshal(big.NewInt(int64(len(keys))))
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	ret, err := toWasmMemory(vm, append(prefix, encodedKeys...))
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		ret, _ = toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	return ret
+}
+
+func ext_crypto_ed25519_sign_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+	key := int32(vm.GetCurrentFrame().Locals[1])
+	msg := vm.GetCurrentFrame().Locals[2]
+
+	runtimeCtx := getContext(vm)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+
+	pubKeyData := vm.Memory[key : key+32]
+	pubKey, err := ed25519.NewPublicKey(pubKeyData)
+	if err != nil {
+		logger.Errorf("failed to get public keys: %s", err)
+		return 0
+	}
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id 0x%x: %s", id, err)
+		ret, _ := toWasmMemoryOptional(vm, nil)
+		return ret
+	}
+
+	var ret int64
+	signingKey := ks.GetKeypair(pubKey)
+	if signingKey == nil {
+		logger.Error("could not find public key " + pubKey.Hex() + " in keystore")
+		ret, err = toWasmMemoryOptional(vm, nil)
+		if err != nil {
+			logger.Errorf("failed to allocate memory: %s", err)
+			return 0
+		}
+		return ret
+	}
+
+	sig, err := signingKey.Sign(asMemorySlice(vm, msg))
+	if err != nil {
+		logger.Error("could not sign message")
+	}
+
+	ret, err = toWasmMemoryFixedSizeOptional(vm, sig)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	return ret
+}
+
+func ext_crypto_ed25519_verify_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := vm.GetCurrentFrame().Locals[1]
+	key := int32(vm.GetCurrentFrame().Locals[2])
+
+	sigVerifier := getContext(vm).SigVerifier
+
+	signature := vm.Memory[sig : sig+64]
+	message := asMemorySlice(vm, msg)
+	pubKeyData := vm.Memory[key : key+32]
+
+	pubKey, err := ed25519.NewPublicKey(pubKeyData)
+	if err != nil {
+		logger.Error("failed to create public key")
+		return 0
+	}
+
+	if sigVerifier.IsStarted() {
+		signature := crypto.SignatureInfo{
+			PubKey:     pubKey.Encode(),
+			Sign:       signature,
+			Msg:        message,
+			VerifyFunc: ed25519.VerifySignature,
+		}
+		sigVerifier.Add(&signature)
+		return 1
+	}
+
+	if ok, err := pubKey.Verify(message, signature); err != nil || !ok {
+		logger.Error("failed to verify")
+		return 0
+	}
+
+	logger.Debug("verified ed25519 signature")
+	return 1
+}
+
+func ext_crypto_secp256k1_ecdsa_recover_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := int32(vm.GetCurrentFrame().Locals[1])
+
+	// msg must be the 32-byte hash of the message to be signed.
+	// sig must be a 65-byte compact ECDSA signature containing the
+	// recovery id as the last element
+	message := vm.Memory[msg : msg+32]
+	signature := vm.Memory[sig : sig+65]
+
+	pub, err := secp256k1.RecoverPublicKey(message, signature)
+	if err != nil {
+		logger.Errorf("failed to recover public key: %s", err)
+		var ret int64
+		ret, err = toWasmMemoryResult(vm, nil)
+		if err != nil {
+			logger.Errorf("failed to allocate memory: %s", err)
+			return 0
+		}
+		return ret
+	}
+
+	logger.Debugf(
+		"recovered public key of length %d: 0x%x",
+		len(pub), pub)
+
+	ret, err := toWasmMemoryResult(vm, pub[1:])
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	return ret
+}
+
+func ext_crypto_secp256k1_ecdsa_recover_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	return ext_crypto_secp256k1_ecdsa_recover_version_1(vm)
+}
+
+func ext_crypto_ecdsa_verify_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := vm.GetCurrentFrame().Locals[1]
+	key := int32(vm.GetCurrentFrame().Locals[2])
+
+	sigVerifier := getContext(vm).SigVerifier
+
+	message := asMemorySlice(vm, msg)
+	signature := vm.Memory[sig : sig+64]
+	pubKey := vm.Memory[key : key+33]
+
+	pub := new(secp256k1.PublicKey)
+	err := pub.Decode(pubKey)
+	if err != nil {
+		logger.Errorf("failed to decode public key: %s", err)
+		return int64(0)
+	}
+
+	logger.Debugf("pub=%s, message=0x%x, signature=0x%x",
+		pub.Hex(), fmt.Sprintf("0x%x", message), fmt.Sprintf("0x%x", signature))
+
+	hash, err := common.Blake2bHash(message)
+	if err != nil {
+		logger.Errorf("failed to hash message: %s", err)
+		return int64(0)
+	}
+
+	if sigVerifier.IsStarted() {
+		signature := crypto.SignatureInfo{
+			PubKey:     pub.Encode(),
+			Sign:       signature,
+			Msg:        hash[:],
+			VerifyFunc: secp256k1.VerifySignature,
+		}
+		sigVerifier.Add(&signature)
+		return int64(1)
+	}
+
+	if ok, err := pub.Verify(hash[:], signature); err != nil || !ok {
+		logger.Errorf("failed to validate signature: %s", err)
+		return int64(0)
+	}
+
+	logger.Debug("validated signature")
+	return int64(1)
+}
+
+func ext_crypto_secp256k1_ecdsa_recover_compressed_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := int32(vm.GetCurrentFrame().Locals[1])
+
+	// msg must be the 32-byte hash of the message to be signed.
+	// sig must be a 65-byte compact ECDSA signature containing the
+	// recovery id as the last element
+	message := vm.Memory[msg : msg+32]
+	signature := vm.Memory[sig : sig+65]
+
+	cpub, err := secp256k1.RecoverPublicKeyCompressed(message, signature)
+	if err != nil {
+		logger.Errorf("failed to recover public key: %s", err)
+		ret, _ := toWasmMemoryResult(vm, nil)
+		return ret
+	}
+
+	logger.Debugf(
+		"recovered public key of length %d: 0x%x",
+		len(cpub), cpub)
+
+	ret, err := toWasmMemoryResult(vm, cpub)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	return ret
+}
+
+func ext_crypto_secp256k1_ecdsa_recover_compressed_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	return ext_crypto_secp256k1_ecdsa_recover_compressed_version_1(vm)
+}
+
+func ext_crypto_sr25519_generate_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+	seedSpan := vm.GetCurrentFrame().Locals[1]
+
+	runtimeCtx := getContext(vm)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+	seedBytes := asMemorySlice(vm, seedSpan)
+
+	var seed *[]byte
+	err := scale.Unmarshal(seedBytes, &seed)
+	if err != nil {
+		logger.Warnf("cannot generate key: %s", err)
+		return 0
+	}
+
+	var kp crypto.Keypair
+	if seed != nil {
+		kp, err = sr25519.NewKeypairFromMnenomic(string(*seed), "")
+	} else {
+		kp, err = sr25519.GenerateKeypair()
+	}
+
+	if err != nil {
+		logger.Tracef("cannot generate key: %s", err)
+		panic(err)
+	}
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id "+common.BytesToHex(id)+": %s", err)
+		return 0
+	}
+
+	err = ks.Insert(kp)
+	if err != nil {
+		logger.Warnf("failed to insert key: %s", err)
+		return 0
+	}
+
+	ret, err := toWasmMemorySized(vm, kp.Public().Encode(), 32)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	logger.Debug("generated sr25519 keypair with public key: " + kp.Public().Hex())
+	return int64(ret)
+}
+
+func ext_crypto_sr25519_public_keys_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+
+	runtimeCtx := getContext(vm)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id "+common.BytesToHex(id)+": %s", err)
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	if ks.Type() != crypto.Sr25519Type && ks.Type() != crypto.UnknownType {
+		logger.Warnf(
+			"keystore type for id 0x%x is %s and not expected sr25519",
+			id, ks.Type())
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	keys := ks.PublicKeys()
+
+	var encodedKeys []byte
+	for _, key := range keys {
+		encodedKeys = append(encodedKeys, key.Encode()...)
+	}
+
+	prefix, err := scale.Marshal(big.NewInt(int64(len(keys))))
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		ret, _ := toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	ret, err := toWasmMemory(vm, append(prefix, encodedKeys...))
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		ret, _ = toWasmMemory(vm, []byte{0})
+		return ret
+	}
+
+	return ret
+}
+
+func ext_crypto_sr25519_sign_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	keyTypeID := int32(vm.GetCurrentFrame().Locals[0])
+	key := int32(vm.GetCurrentFrame().Locals[1])
+	msg := vm.GetCurrentFrame().Locals[2]
+	runtimeCtx := getContext(vm)
+
+	emptyRet, _ := toWasmMemoryOptional(vm, nil)
+
+	id := vm.Memory[keyTypeID : keyTypeID+4]
+
+	ks, err := runtimeCtx.Keystore.GetKeystore(id)
+	if err != nil {
+		logger.Warnf("error for id 0x%x: %s", id, err)
+		return emptyRet
+	}
+
+	var ret int64
+	pubKey, err := sr25519.NewPublicKey(vm.Memory[key : key+32])
+	if err != nil {
+		logger.Errorf("failed to get public key: %s", err)
+		return emptyRet
+	}
+
+	signingKey := ks.GetKeypair(pubKey)
+	if signingKey == nil {
+		logger.Error("could not find public key " + pubKey.Hex() + " in keystore")
+		return emptyRet
+	}
+
+	msgData := asMemorySlice(vm, msg)
+	sig, err := signingKey.Sign(msgData)
+	if err != nil {
+		logger.Errorf("could not sign message: %s", err)
+		return emptyRet
+	}
+
+	ret, err = toWasmMemoryFixedSizeOptional(vm, sig)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return emptyRet
+	}
+
+	return ret
+}
+
+func ext_crypto_sr25519_verify_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := vm.GetCurrentFrame().Locals[1]
+	key := int32(vm.GetCurrentFrame().Locals[2])
+
+	sigVerifier := getContext(vm).SigVerifier
+
+	message := asMemorySlice(vm, msg)
+	signature := vm.Memory[sig : sig+64]
+
+	pub, err := sr25519.NewPublicKey(vm.Memory[key : key+32])
+	if err != nil {
+		logger.Error("invalid sr25519 public key")
+		return 0
+	}
+
+	logger.Debugf(
+		"pub=%s message=0x%x signature=0x%x",
+		pub.Hex(), message, signature)
+
+	if sigVerifier.IsStarted() {
+		signature := crypto.SignatureInfo{
+			PubKey:     pub.Encode(),
+			Sign:       signature,
+			Msg:        message,
+			VerifyFunc: sr25519.VerifySignatureDeprecated,
+		}
+		sigVerifier.Add(&signature)
+		return 1
+	}
+
+	if ok, err := pub.VerifyDeprecated(message, signature); err != nil || !ok {
+		logger.Debugf("failed to validate signature: %s", err)
+		return 0
+	}
+
+	logger.Debug("verified sr25519 signature")
+	return 1
+}
+
+func ext_crypto_sr25519_verify_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	sig := int32(vm.GetCurrentFrame().Locals[0])
+	msg := vm.GetCurrentFrame().Locals[1]
+	key := int32(vm.GetCurrentFrame().Locals[2])
+
+	sigVerifier := getContext(vm).SigVerifier
+
+	message := asMemorySlice(vm, msg)
+	signature := vm.Memory[sig : sig+64]
+
+	pub, err := sr25519.NewPublicKey(vm.Memory[key : key+32])
+	if err != nil {
+		logger.Error("invalid sr25519 public key")
+		return 0
+	}
+
+	logger.Debugf(
+		"pub=%s; message=0x%x; signature=0x%x",
+		pub.Hex(), message, signature)
+
+	if sigVerifier.IsStarted() {
+		signature := crypto.SignatureInfo{
+			PubKey:     pub.Encode(),
+			Sign:       signature,
+			Msg:        message,
+			VerifyFunc: sr25519.VerifySignature,
+		}
+		sigVerifier.Add(&signature)
+		return 1
+	}
+
+	if ok, err := pub.Verify(message, signature); err != nil || !ok {
+		logger.Errorf("failed to validate signature: %s", err)
+		return 0
+	}
+
+	logger.Debug("validated signature")
+	return int64(1)
+}
+
+func ext_crypto_start_batch_verify_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+
+	sigVerifier := getContext(vm).SigVerifier
+	sigVerifier.Start()
+	return 0
+}
+
+func ext_crypto_finish_batch_verify_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+
+	sigVerifier := getContext(vm).SigVerifier
+
+	if !sigVerifier.Finish() {
+		logger.Error("failed to verify batch of signatures")
+		return 0
+	}
+
+	return 1
+}
+
+func ext_trie_blake2_256_root_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+	return trieRoot(vm, dataSpan, trie.V0)
+}
+
+func ext_trie_blake2_256_root_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+	version := int32(vm.GetCurrentFrame().Locals[1])
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return trieRoot(vm, dataSpan, stateVersion)
+}
+
+// trieRoot computes the root hash of the trie built from the SCALE encoded
+// (key, value) tuples at dataSpan, using the given state trie version.
+func trieRoot(vm *exec.VirtualMachine, dataSpan int64, version trie.Version) int64 {
+	runtimeCtx := getContext(vm)
+	data := asMemorySlice(vm, dataSpan)
+
+	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
+
+	type kv struct {
+		Key, Value []byte
+	}
+
+	// this function is expecting an array of (key, value) tuples
+	var kvs []kv
+	if err := scale.Unmarshal(data, &kvs); err != nil {
+		logger.Errorf("failed scale decoding data: %s", err)
+		return 0
+	}
+
+	for _, kv := range kvs {
+		t.Put(kv.Key, kv.Value)
+	}
+
+	// allocate memory for value and copy value to memory
+	ptr, err := runtimeCtx.Allocator.Allocate(32)
+	if err != nil {
+		logger.Errorf("failed allocating: %s", err)
+		return 0
+	}
+
+	hash, err := t.Hash()
+	if err != nil {
+		logger.Errorf("failed computing trie Merkle root hash: %s", err)
+		return 0
+	}
+
+	logger.Debugf("root hash is %s", hash)
+	copy(vm.Memory[ptr:ptr+32], hash[:])
+	return int64(ptr)
+}
+
+func ext_trie_blake2_256_ordered_root_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+	return trieOrderedRoot(vm, dataSpan, trie.V0)
+}
+
+func ext_trie_blake2_256_ordered_root_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+	version := int32(vm.GetCurrentFrame().Locals[1])
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return trieOrderedRoot(vm, dataSpan, stateVersion)
+}
+
+// trieOrderedRoot computes the root hash of the trie built from the SCALE encoded
+// values at dataSpan, keyed by their compact encoded index, using the given state
+// trie version.
+func trieOrderedRoot(vm *exec.VirtualMachine, dataSpan int64, version trie.Version) int64 {
+	runtimeCtx := getContext(vm)
+	data := asMemorySlice(vm, dataSpan)
+
+	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
+	var values [][]byte
+	err := scale.Unmarshal(data, &values)
+	if err != nil {
+		logger.Errorf("failed scale decoding data: %s", err)
+		return 0
+	}
+
+	for i, val := range values {
+		key, err := scale.Marshal(big.NewInt(int64(i)))
+		if err != nil {
+			logger.Errorf("failed scale encoding value index %d: %s", i, err)
+			return 0
+		}
+		logger.Tracef(
+			"put key=0x%x and value=0x%x",
+			key, val)
+
+		t.Put(key, val)
+	}
+
+	// allocate memory for value and copy value to memory
+	ptr, err := runtimeCtx.Allocator.Allocate(32)
+	if err != nil {
+		logger.Errorf("failed allocating: %s", err)
+		return 0
+	}
+
+	hash, err := t.Hash()
+	if err != nil {
+		logger.Errorf("failed computing trie Merkle root hash: %s", err)
+		return 0
+	}
+
+	logger.Debugf("root hash is %s", hash)
+	copy(vm.Memory[ptr:ptr+32], hash[:])
+	return int64(ptr)
+}
+
+func ext_trie_blake2_256_verify_proof_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	rootSpan := int32(vm.GetCurrentFrame().Locals[0])
+	proofSpan := vm.GetCurrentFrame().Locals[1]
+	keySpan := vm.GetCurrentFrame().Locals[2]
+	valueSpan := vm.GetCurrentFrame().Locals[3]
+
+	toDecProofs := asMemorySlice(vm, proofSpan)
+	var decProofs [][]byte
+	err := scale.Unmarshal(toDecProofs, &decProofs)
+	if err != nil {
+		logger.Errorf("[ext_trie_blake2_256_verify_proof_version_1]: %s", err)
+		return int64(0)
+	}
+
+	key := asMemorySlice(vm, keySpan)
+	value := asMemorySlice(vm, valueSpan)
+
+	trieRoot := vm.Memory[rootSpan : rootSpan+32]
+
+	exists, err := trie.VerifyProof(decProofs, trieRoot, []trie.Pair{{Key: key, Value: value}})
+	if err != nil {
+		logger.Errorf("[ext_trie_blake2_256_verify_proof_version_1]: %s", err)
+		return int64(0)
+	}
+
+	var result int64
+	if exists {
+		result = 1
+	}
+
+	return result
+}
+
+func ext_misc_print_hex_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+
+	data := asMemorySlice(vm, dataSpan)
+	logger.Debugf("data: 0x%x", data)
 	return 0
 }
 
-func ext_storage_clear_prefix_version_1(vm *exec.VirtualMachine) int64 {
+func ext_misc_print_num_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	data := vm.GetCurrentFrame().Locals[0]
+
+	logger.Debugf("num: %d", data)
+	return 0
+}
+
+func ext_misc_print_utf8_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+
+	data := asMemorySlice(vm, dataSpan)
+	logger.Debug("utf8: " + string(data))
+	return 0
+}
+
+func ext_misc_runtime_version_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+
+	data := asMemorySlice(vm, dataSpan)
+
+	cfg := &Config{}
+	cfg.LogLvl = log.DoNotChange
+	cfg.Storage, _ = rtstorage.NewTrieState(nil)
+
+	instance, err := NewInstance(data, cfg)
+	if err != nil {
+		logger.Errorf("failed to create instance: %s", err)
+		return 0
+	}
+
+	version, err := instance.Version()
+	if err != nil {
+		logger.Errorf("failed to get runtime version: %s", err)
+		out, _ := toWasmMemoryOptional(vm, nil)
+		return out
+	}
+
+	encodedData, err := version.Encode()
+	if err != nil {
+		logger.Errorf("failed to encode result: %s", err)
+		return 0
+	}
+
+	out, err := toWasmMemoryOptional(vm, encodedData)
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
+	}
+
+	return out
+}
+
+func ext_default_child_storage_read_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	key := vm.GetCurrentFrame().Locals[1]
+	valueOut := vm.GetCurrentFrame().Locals[2]
+	offset := int32(vm.GetCurrentFrame().Locals[3])
+
+	storage := getContext(vm).Storage
+
+	value, err := storage.GetChildStorage(asMemorySlice(vm, childStorageKey), asMemorySlice(vm, key))
+	if err != nil {
+		logger.Errorf("failed to get child storage: %s", err)
+		return 0
+	}
+
+	valueBuf, valueLen := runtime.Int64ToPointerAndSize(valueOut)
+	copy(vm.Memory[valueBuf:valueBuf+valueLen], value[offset:])
+
+	size := uint32(len(value[offset:]))
+	sizeBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sizeBuf, size)
+
+	sizeSpan, err := toWasmMemoryOptional(vm, sizeBuf)
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
+	}
+
+	return sizeSpan
+}
+
+func ext_default_child_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	keySpan := vm.GetCurrentFrame().Locals[1]
+
+	ctx := getContext(vm)
 	storage := ctx.Storage
-	prefixSpan := vm.GetCurrentFrame().Locals[0]
 
-	prefix := asMemorySlice(vm.Memory, prefixSpan)
-	logger.Debugf("prefix: 0x%x", prefix)
+	keyToChild := asMemorySlice(vm, childStorageKey)
+	key := asMemorySlice(vm, keySpan)
 
-	err := storage.ClearPrefix(prefix)
+	err := storage.ClearChildStorage(keyToChild, key)
 	if err != nil {
-		logger.Errorf("[ext_storage_clear_prefix_version_1]: %s", err)
+		logger.Errorf("failed to clear child storage: %s", err)
 	}
+	return 0
+}
+
+func ext_default_child_storage_clear_prefix_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	prefixSpan := vm.GetCurrentFrame().Locals[1]
+
+	ctx := getContext(vm)
+	storage := ctx.Storage
 
-	// sanity check
-	next := storage.NextKey(prefix)
-	if len(next) >= len(prefix) && bytes.Equal(prefix, next[:len(prefix)]) {
-		panic("did not clear prefix")
+	keyToChild := asMemorySlice(vm, childStorageKey)
+	prefix := asMemorySlice(vm, prefixSpan)
+
+	err := storage.ClearPrefixInChild(keyToChild, prefix)
+	if err != nil {
+		logger.Errorf("failed to clear prefix in child: %s", err)
 	}
+	return 0
+}
+
+func ext_default_child_storage_exists_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	key := vm.GetCurrentFrame().Locals[1]
+
+	storage := getContext(vm).Storage
 
+	child, err := storage.GetChildStorage(asMemorySlice(vm, childStorageKey), asMemorySlice(vm, key))
+	if err != nil {
+		logger.Errorf("failed to get child from child storage: %s", err)
+		return 0
+	}
+	if child != nil {
+		return 1
+	}
 	return 0
 }
 
-func ext_storage_exists_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
+func ext_default_child_storage_get_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	key := vm.GetCurrentFrame().Locals[1]
+
+	storage := getContext(vm).Storage
+
+	child, err := storage.GetChildStorage(asMemorySlice(vm, childStorageKey), asMemorySlice(vm, key))
+	if err != nil {
+		logger.Errorf("failed to get child from child storage: %s", err)
+		return 0
+	}
+
+	value, err := toWasmMemoryOptional(vm, child)
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
+	}
+
+	return value
+}
+
+func ext_default_child_storage_next_key_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	key := vm.GetCurrentFrame().Locals[1]
+
+	storage := getContext(vm).Storage
+
+	child, err := storage.GetChildNextKey(asMemorySlice(vm, childStorageKey), asMemorySlice(vm, key))
+	if err != nil {
+		logger.Errorf("failed to get child's next key: %s", err)
+		return 0
+	}
+
+	value, err := toWasmMemoryOptional(vm, child)
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
+	}
+
+	return value
+}
+
+func ext_default_child_storage_root_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	return defaultChildStorageRoot(vm, childStorageKey, trie.V0)
+}
+
+func ext_default_child_storage_root_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	version := int32(vm.GetCurrentFrame().Locals[1])
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return defaultChildStorageRoot(vm, childStorageKey, stateVersion)
+}
+
+// defaultChildStorageRoot returns the root hash of the child trie
+// at the given key, computed using the given state trie version.
+func defaultChildStorageRoot(vm *exec.VirtualMachine, childStorageKey int64, version trie.Version) int64 {
+	storage := getContext(vm).Storage
+
+	child, err := storage.GetChild(asMemorySlice(vm, childStorageKey))
+	if err != nil {
+		logger.Errorf("failed to retrieve child: %s", err)
+		return 0
+	}
+
+	child.SetVersion(version)
+
+	childRoot, err := child.Hash()
+	if err != nil {
+		logger.Errorf("failed to encode child root: %s", err)
+		return 0
+	}
+
+	root, err := toWasmMemoryOptional(vm, childRoot[:])
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
+	}
+
+	return root
+}
+
+func ext_default_child_storage_set_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
+	keySpan := vm.GetCurrentFrame().Locals[1]
+	valueSpan := vm.GetCurrentFrame().Locals[2]
+
+	ctx := getContext(vm)
 	storage := ctx.Storage
 
-	key := asMemorySlice(vm.Memory, keySpan)
+	childStorageKey := asMemorySlice(vm, childStorageKeySpan)
+	key := asMemorySlice(vm, keySpan)
+	value := asMemorySlice(vm, valueSpan)
 
-	val := storage.Get(key)
-	if len(val) == 0 {
+	cp := make([]byte, len(value))
+	copy(cp, value)
+
+	err := storage.SetChildStorage(childStorageKey, key, cp)
+	if err != nil {
+		logger.Errorf("failed to set value in child storage: %s", err)
+		return 0
+	}
+	return 0
+}
+
+func ext_default_child_storage_storage_kill_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
+
+	ctx := getContext(vm)
+	storage := ctx.Storage
+
+	childStorageKey := asMemorySlice(vm, childStorageKeySpan)
+	storage.DeleteChild(childStorageKey)
+	return 0
+}
+
+func ext_default_child_storage_storage_kill_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
+	lim := vm.GetCurrentFrame().Locals[1]
+
+	ctx := getContext(vm)
+	storage := ctx.Storage
+	childStorageKey := asMemorySlice(vm, childStorageKeySpan)
+
+	limitBytes := asMemorySlice(vm, lim)
+
+	var limit *[]byte
+	err := scale.Unmarshal(limitBytes, &limit)
+	if err != nil {
+		logger.Warnf("cannot generate limit: %s", err)
 		return 0
 	}
 
+	_, all, err := storage.DeleteChildLimit(childStorageKey, limit)
+	if err != nil {
+		logger.Warnf("cannot get child storage: %s", err)
+	}
+
+	if all {
+		return 1
+	}
+
+	return 0
+}
+
+type noneRemain uint32
+type someRemain uint32
+
+func (noneRemain) Index() uint {
+	return 0
+}
+func (someRemain) Index() uint {
 	return 1
 }
 
-func ext_storage_read_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	valueOut := vm.GetCurrentFrame().Locals[1]
-	offset := int32(vm.GetCurrentFrame().Locals[2])
+func ext_default_child_storage_storage_kill_version_3(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
+	lim := vm.GetCurrentFrame().Locals[1]
+	ctx := getContext(vm)
 	storage := ctx.Storage
-	memory := vm.Memory
+	childStorageKey := asMemorySlice(vm, childStorageKeySpan)
 
-	key := asMemorySlice(memory, keySpan)
-	value := storage.Get(key)
-	logger.Debugf("key 0x%x and value 0x%x", key, value)
+	limitBytes := asMemorySlice(vm, lim)
 
-	if value == nil {
-		ret, _ := toWasmMemoryOptional(memory, nil)
-		return ret
+	var limit *[]byte
+	err := scale.Unmarshal(limitBytes, &limit)
+	if err != nil {
+		logger.Warnf("cannot generate limit: %s", err)
 	}
 
-	var size uint32
+	deleted, all, err := storage.DeleteChildLimit(childStorageKey, limit)
+	if err != nil {
+		logger.Warnf("cannot get child storage: %s", err)
+		return int64(0)
+	}
+
+	vdt, err := scale.NewVaryingDataType(noneRemain(0), someRemain(0))
+	if err != nil {
+		logger.Warnf("cannot create new varying data type: %s", err)
+	}
+
+	if all {
+		err = vdt.Set(noneRemain(deleted))
+	} else {
+		err = vdt.Set(someRemain(deleted))
+	}
+	if err != nil {
+		logger.Warnf("cannot set varying data type: %s", err)
+		return int64(0)
+	}
 
-	if int(offset) > len(value) {
-		size = uint32(0)
-	} else {
-		size = uint32(len(value[offset:]))
-		valueBuf, valueLen := runtime.Int64ToPointerAndSize(valueOut)
-		copy(memory[valueBuf:valueBuf+valueLen], value[offset:])
+	encoded, err := scale.Marshal(vdt)
+	if err != nil {
+		logger.Warnf("problem marshaling varying data type: %s", err)
+		return int64(0)
 	}
 
-	sizeSpan, err := toWasmMemoryOptionalUint32(memory, &size)
+	out, err := toWasmMemoryOptional(vm, encoded)
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
+		logger.Warnf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return sizeSpan
+	return out
 }
 
-func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
-	nextLength := big.NewInt(1)
-	var valueRes []byte
-
-	// this function assumes the item in storage is a SCALE encoded array of items
-	// the valueToAppend is a new item, so it appends the item and increases the length prefix by 1
-	valueCurr := storage.Get(key)
-
-	if len(valueCurr) == 0 {
-		valueRes = valueToAppend
-	} else {
-		var currLength *big.Int
-		err := scale.Unmarshal(valueCurr, &currLength)
-		if err != nil {
-			logger.Tracef("item in storage is not SCALE encoded, overwriting at key 0x%x", key)
-			storage.Set(key, append([]byte{4}, valueToAppend...))
-			return nil
-		}
-
-		lengthBytes, err := scale.Marshal(currLength)
-		if err != nil {
-			return err
-		}
-		// append new item, pop off number of bytes required for length encoding,
-		// since we're not using old scale.Decoder
-		valueRes = append(valueCurr[len(lengthBytes):], valueToAppend...)
-
-		// increase length by 1
-		nextLength = big.NewInt(0).Add(currLength, big.NewInt(1))
-	}
+func ext_allocator_free_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	addr := int32(vm.GetCurrentFrame().Locals[0])
+	runtimeCtx := getContext(vm)
 
-	lengthEnc, err := scale.Marshal(nextLength)
+	// Deallocate memory
+	err := runtimeCtx.Allocator.Deallocate(uint32(addr))
 	if err != nil {
-		logger.Tracef("failed to encode new length: %s", err)
-		return err
+		logger.Errorf("failed to free memory: %s", err)
 	}
-
-	// append new length prefix to start of items array
-	lengthEnc = append(lengthEnc, valueRes...)
-	logger.Debugf("resulting value: 0x%x", lengthEnc)
-	storage.Set(key, lengthEnc)
-	return nil
+	return 0
 }
 
-func ext_storage_append_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	storage := ctx.Storage
-	keySpan := vm.GetCurrentFrame().Locals[0]
-	valueSpan := vm.GetCurrentFrame().Locals[1]
+func ext_allocator_malloc_version_1(vm *exec.VirtualMachine) int64 {
+	size := int32(vm.GetCurrentFrame().Locals[0])
+	logger.Tracef("executing with size %d...", int64(size))
 
-	key := asMemorySlice(vm.Memory, keySpan)
-	logger.Debugf("key 0x%x", key)
-	valueAppend := asMemorySlice(vm.Memory, valueSpan)
+	ctx := getContext(vm)
 
-	err := storageAppend(storage, key, valueAppend)
+	// Allocate memory
+	res, err := ctx.Allocator.Allocate(uint32(size))
 	if err != nil {
-		logger.Errorf("[ext_storage_append_version_1]: %s", err)
+		logger.Criticalf("failed to allocate memory: %s", err)
+		panic(err)
 	}
 
-	return 0
+	return int64(res)
 }
 
-func ext_trie_blake2_256_ordered_root_version_1(vm *exec.VirtualMachine) int64 {
+func ext_hashing_blake2_128_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
 	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
-	data := asMemorySlice(memory, dataSpan)
 
-	t := trie.NewEmptyTrie()
-	var v [][]byte
-	err := scale.Unmarshal(data, &v)
+	data := asMemorySlice(vm, dataSpan)
+
+	hash, err := common.Blake2b128(data)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("[ext_hashing_blake2_128_version_1]: %s", err)
 		return 0
 	}
 
-	for i, val := range v {
-		key, err := scale.Marshal(big.NewInt(int64(i)))
-		if err != nil {
-			logger.Errorf("[ext_blake2_256_enumerated_trie_root]: %s", err)
-			return 0
-		}
-		logger.Tracef("key 0x%x and value 0x%x", key, val)
+	logger.Debugf(
+		"data 0x%x has hash 0x%x",
+		data, hash)
 
-		t.Put(key, val)
+	out, err := toWasmMemorySized(vm, hash, 16)
+	if err != nil {
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
 	}
 
-	// allocate memory for value and copy value to memory
-	ptr, err := ctx.Allocator.Allocate(32)
+	return int64(out)
+}
+
+func ext_hashing_blake2_256_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+
+	data := asMemorySlice(vm, dataSpan)
+
+	hash, err := common.Blake2bHash(data)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("[ext_hashing_blake2_256_version_1]: %s", err)
 		return 0
 	}
 
-	hash, err := t.Hash()
+	logger.Debugf("data 0x%x has hash %s", data, hash)
+
+	out, err := toWasmMemorySized(vm, hash[:], 32)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	logger.Debugf("root hash: %s", hash)
-	copy(memory[ptr:ptr+32], hash[:])
-	return int64(ptr)
+	return int64(out)
 }
 
-func ext_storage_root_version_1(vm *exec.VirtualMachine) int64 {
+func ext_hashing_keccak_256_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	storage := ctx.Storage
+	dataSpan := vm.GetCurrentFrame().Locals[0]
 
-	root, err := storage.Root()
+	data := asMemorySlice(vm, dataSpan)
+
+	hash, err := common.Keccak256(data)
 	if err != nil {
-		logger.Errorf("failed to get storage root: %s", err)
+		logger.Errorf("[ext_hashing_keccak_256_version_1]: %s", err)
 		return 0
 	}
 
-	logger.Debugf("root hash: %s", root)
+	logger.Debugf("data 0x%x has hash %s", data, hash)
 
-	rootSpan, err := toWasmMemory(vm.Memory, root[:])
+	out, err := toWasmMemorySized(vm, hash[:], 32)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return rootSpan
+	return int64(out)
 }
 
-func ext_storage_changes_root_version_1(vm *exec.VirtualMachine) int64 {
+func ext_hashing_sha2_256_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	logger.Debug("returning None")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+
+	data := asMemorySlice(vm, dataSpan)
+	hash := common.Sha256(data)
+
+	logger.Debugf("data 0x%x has hash %s", data, hash)
 
-	rootSpan, err := toWasmMemoryOptional(vm.Memory, nil)
+	out, err := toWasmMemorySized(vm, hash[:], 32)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return rootSpan
-}
-
-func ext_crypto_start_batch_verify_version_1(_ *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	return 0
-}
-
-func ext_crypto_finish_batch_verify_version_1(_ *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	return 1
+	return int64(out)
 }
 
-func ext_offchain_index_set_version_1(_ *exec.VirtualMachine) int64 {
+func ext_hashing_twox_256_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
-	return 0
-}
+	dataSpan := vm.GetCurrentFrame().Locals[0]
 
-func ext_default_child_storage_set_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-	storage := ctx.Storage
-	memory := vm.Memory
+	data := asMemorySlice(vm, dataSpan)
 
-	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
-	childStorageKey := asMemorySlice(memory, childStorageKeySpan)
-	keySpan := vm.GetCurrentFrame().Locals[1]
-	key := asMemorySlice(memory, keySpan)
-	valueSpan := vm.GetCurrentFrame().Locals[2]
-	value := asMemorySlice(memory, valueSpan)
+	hash, err := common.Twox256(data)
+	if err != nil {
+		logger.Errorf("[ext_hashing_twox_256_version_1]: %s", err)
+		return 0
+	}
 
-	cp := make([]byte, len(value))
-	copy(cp, value)
+	logger.Debugf("data 0x%x has hash %s", data, hash)
 
-	err := storage.SetChildStorage(childStorageKey, key, cp)
+	out, err := toWasmMemorySized(vm, hash[:], 32)
 	if err != nil {
-		logger.Errorf("failed to set value in child storage: %s", err)
+		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return 0
+	return int64(out)
 }
 
-func ext_default_child_storage_get_version_1(vm *exec.VirtualMachine) int64 {
+func ext_hashing_twox_128_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
+	data := asMemorySlice(vm, dataSpan)
 
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	key := vm.GetCurrentFrame().Locals[1]
-	storage := ctx.Storage
-	memory := vm.Memory
-
-	child, err := storage.GetChildStorage(asMemorySlice(memory, childStorageKey), asMemorySlice(memory, key))
+	hash, err := common.Twox128Hash(data)
 	if err != nil {
-		logger.Errorf("failed to get child from child storage: %s", err)
+		logger.Errorf("[ext_hashing_twox_128_version_1]: %s", err)
 		return 0
 	}
 
-	value, err := toWasmMemoryOptional(memory, child)
+	logger.Debugf(
+		"data 0x%x hash hash 0x%x",
+		data, hash)
+
+	out, err := toWasmMemorySized(vm, hash, 16)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return value
+	return int64(out)
 }
 
-func ext_default_child_storage_read_version_1(vm *exec.VirtualMachine) int64 {
+func ext_hashing_twox_64_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	dataSpan := vm.GetCurrentFrame().Locals[0]
 
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	key := vm.GetCurrentFrame().Locals[1]
-	valueOut := vm.GetCurrentFrame().Locals[2]
-	offset := vm.GetCurrentFrame().Locals[3]
-	storage := ctx.Storage
-	memory := vm.Memory
+	data := asMemorySlice(vm, dataSpan)
 
-	value, err := storage.GetChildStorage(asMemorySlice(memory, childStorageKey), asMemorySlice(memory, key))
+	hash, err := common.Twox64(data)
 	if err != nil {
-		logger.Errorf("failed to get child storage: %s", err)
+		logger.Errorf("[ext_hashing_twox_64_version_1]: %s", err)
 		return 0
 	}
 
-	valueBuf, valueLen := runtime.Int64ToPointerAndSize(valueOut)
-	copy(memory[valueBuf:valueBuf+valueLen], value[offset:])
-
-	size := uint32(len(value[offset:]))
-	sizeBuf := make([]byte, 4)
-	binary.LittleEndian.PutUint32(sizeBuf, size)
+	logger.Debugf(
+		"data 0x%x has hash 0x%x",
+		data, hash)
 
-	sizeSpan, err := toWasmMemoryOptional(memory, sizeBuf)
+	out, err := toWasmMemorySized(vm, hash, 8)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return sizeSpan
+	return int64(out)
 }
 
-func ext_default_child_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Debug("executing...")
-
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	keySpan := vm.GetCurrentFrame().Locals[1]
-	memory := vm.Memory
-	storage := ctx.Storage
+func ext_offchain_index_set_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	valueSpan := vm.GetCurrentFrame().Locals[1]
+	runtimeCtx := getContext(vm)
 
-	keyToChild := asMemorySlice(memory, childStorageKey)
-	key := asMemorySlice(memory, keySpan)
+	storageKey := asMemorySlice(vm, keySpan)
+	newValue := asMemorySlice(vm, valueSpan)
+	cp := make([]byte, len(newValue))
+	copy(cp, newValue)
 
-	err := storage.ClearChildStorage(keyToChild, key)
+	err := runtimeCtx.NodeStorage.BaseDB.Put(storageKey, cp)
 	if err != nil {
-		logger.Errorf("failed to clear child storage: %s", err)
+		logger.Errorf("failed to set value in raw storage: %s", err)
 	}
 	return 0
 }
 
-func ext_default_child_storage_storage_kill_version_1(vm *exec.VirtualMachine) int64 {
+func ext_offchain_local_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	kind := int32(vm.GetCurrentFrame().Locals[0])
+	key := vm.GetCurrentFrame().Locals[1]
+	runtimeCtx := getContext(vm)
 
-	childStorageKeySpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
-	storage := ctx.Storage
+	storageKey := asMemorySlice(vm, key)
 
-	childStorageKey := asMemorySlice(memory, childStorageKeySpan)
-	storage.DeleteChild(childStorageKey)
+	kindInt := binary.LittleEndian.Uint32(vm.Memory[kind : kind+4])
+
+	var err error
+
+	switch runtime.NodeStorageType(kindInt) {
+	case runtime.NodeStorageTypePersistent:
+		err = runtimeCtx.NodeStorage.PersistentStorage.Del(storageKey)
+	case runtime.NodeStorageTypeLocal:
+		err = runtimeCtx.NodeStorage.LocalStorage.Del(storageKey)
+	}
+
+	if err != nil {
+		logger.Errorf("failed to clear value from storage: %s", err)
+	}
 	return 0
 }
 
-func ext_default_child_storage_exists_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+func ext_offchain_is_validator_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
 
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
+	runtimeCtx := getContext(vm)
+	if runtimeCtx.Validator {
+		return 1
+	}
+	return 0
+}
+
+func ext_offchain_local_storage_compare_and_set_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	kind := int32(vm.GetCurrentFrame().Locals[0])
 	key := vm.GetCurrentFrame().Locals[1]
-	storage := ctx.Storage
-	memory := vm.Memory
+	oldValue := vm.GetCurrentFrame().Locals[2]
+	newValue := vm.GetCurrentFrame().Locals[3]
+
+	runtimeCtx := getContext(vm)
+
+	storageKey := asMemorySlice(vm, key)
+
+	var storedValue []byte
+	var err error
+
+	switch runtime.NodeStorageType(kind) {
+	case runtime.NodeStorageTypePersistent:
+		storedValue, err = runtimeCtx.NodeStorage.PersistentStorage.Get(storageKey)
+	case runtime.NodeStorageTypeLocal:
+		storedValue, err = runtimeCtx.NodeStorage.LocalStorage.Get(storageKey)
+	}
 
-	child, err := storage.GetChildStorage(asMemorySlice(memory, childStorageKey), asMemorySlice(memory, key))
 	if err != nil {
-		logger.Errorf("failed to get child from child storage: %s", err)
+		logger.Errorf("failed to get value from storage: %s", err)
 		return 0
 	}
-	if child != nil {
-		return 1
+
+	oldVal := asMemorySlice(vm, oldValue)
+	newVal := asMemorySlice(vm, newValue)
+	if reflect.DeepEqual(storedValue, oldVal) {
+		cp := make([]byte, len(newVal))
+		copy(cp, newVal)
+		err = runtimeCtx.NodeStorage.LocalStorage.Put(storageKey, cp)
+		if err != nil {
+			logger.Errorf("failed to set value in storage: %s", err)
+			return 0
+		}
 	}
-	return 0
+
+	return 1
 }
 
-func ext_default_child_storage_clear_prefix_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+func ext_offchain_local_storage_get_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	kind := int32(vm.GetCurrentFrame().Locals[0])
+	key := vm.GetCurrentFrame().Locals[1]
 
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	prefixSpan := vm.GetCurrentFrame().Locals[1]
-	storage := ctx.Storage
-	memory := vm.Memory
+	runtimeCtx := getContext(vm)
+	storageKey := asMemorySlice(vm, key)
 
-	keyToChild := asMemorySlice(memory, childStorageKey)
-	prefix := asMemorySlice(memory, prefixSpan)
+	var res []byte
+	var err error
+
+	switch runtime.NodeStorageType(kind) {
+	case runtime.NodeStorageTypePersistent:
+		res, err = runtimeCtx.NodeStorage.PersistentStorage.Get(storageKey)
+	case runtime.NodeStorageTypeLocal:
+		res, err = runtimeCtx.NodeStorage.LocalStorage.Get(storageKey)
+	}
 
-	err := storage.ClearPrefixInChild(keyToChild, prefix)
 	if err != nil {
-		logger.Errorf("failed to clear prefix in child: %s", err)
+		logger.Errorf("failed to get value from storage: %s", err)
 	}
-	return 0
+	// allocate memory for value and copy value to memory
+	ptr, err := toWasmMemoryOptional(vm, res)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+	return ptr
 }
 
-func ext_default_child_storage_root_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+func ext_offchain_local_storage_set_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	kind := int32(vm.GetCurrentFrame().Locals[0])
+	key := vm.GetCurrentFrame().Locals[1]
+	value := vm.GetCurrentFrame().Locals[2]
 
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
-	storage := ctx.Storage
+	runtimeCtx := getContext(vm)
+	storageKey := asMemorySlice(vm, key)
+	newValue := asMemorySlice(vm, value)
+	cp := make([]byte, len(newValue))
+	copy(cp, newValue)
+
+	var err error
+	switch runtime.NodeStorageType(kind) {
+	case runtime.NodeStorageTypePersistent:
+		err = runtimeCtx.NodeStorage.PersistentStorage.Put(storageKey, cp)
+	case runtime.NodeStorageTypeLocal:
+		err = runtimeCtx.NodeStorage.LocalStorage.Put(storageKey, cp)
+	}
+
+	if err != nil {
+		logger.Errorf("failed to set value in storage: %s", err)
+	}
+	return 0
+}
 
-	child, err := storage.GetChild(asMemorySlice(memory, childStorageKey))
-	if err != nil {
-		logger.Errorf("failed to retrieve child: %s", err)
+func ext_offchain_network_state_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	runtimeCtx := getContext(vm)
+	if runtimeCtx.Network == nil {
 		return 0
 	}
 
-	childRoot, err := child.Hash()
+	nsEnc, err := scale.Marshal(runtimeCtx.Network.NetworkState())
 	if err != nil {
-		logger.Errorf("failed to encode child root: %s", err)
+		logger.Errorf("failed at encoding network state: %s", err)
 		return 0
 	}
 
-	root, err := toWasmMemoryOptional(memory, childRoot[:])
+	// copy network state length to memory writtenOut location
+	nsEncLen := uint32(len(nsEnc))
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, nsEncLen)
+
+	// allocate memory for value and copy value to memory
+	ptr, err := toWasmMemorySized(vm, nsEnc, nsEncLen)
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
+		logger.Errorf("failed to allocate memory: %s", err)
 		return 0
 	}
 
-	return root
+	return int64(ptr)
 }
 
-func ext_default_child_storage_next_key_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-
-	childStorageKey := vm.GetCurrentFrame().Locals[0]
-	key := vm.GetCurrentFrame().Locals[1]
-	memory := vm.Memory
-	storage := ctx.Storage
+func ext_offchain_random_seed_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
 
-	child, err := storage.GetChildNextKey(asMemorySlice(memory, childStorageKey), asMemorySlice(memory, key))
+	seed := make([]byte, 32)
+	_, err := rand.Read(seed)
 	if err != nil {
-		logger.Errorf("failed to get child's next key: %s", err)
-		return 0
+		logger.Errorf("failed to generate random seed: %s", err)
 	}
-
-	value, err := toWasmMemoryOptional(memory, child)
+	ptr, err := toWasmMemorySized(vm, seed, 32)
 	if err != nil {
-		logger.Errorf("failed to allocate: %s", err)
-		return 0
+		logger.Errorf("failed to allocate memory: %s", err)
 	}
-
-	return value
+	return int64(ptr)
 }
 
-func ext_crypto_ed25519_public_keys_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+func ext_offchain_submit_transaction_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	data := vm.GetCurrentFrame().Locals[0]
 
-	id := memory[keyTypeID : keyTypeID+4]
+	extBytes := asMemorySlice(vm, data)
 
-	ks, err := ctx.Keystore.GetKeystore(id)
+	var extrinsic []byte
+	err := scale.Unmarshal(extBytes, &extrinsic)
 	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
-	}
-
-	if ks.Type() != crypto.Ed25519Type && ks.Type() != crypto.UnknownType {
-		logger.Warnf(
-			"keystore type for id 0x%x is %s and not the expected ed25519",
-			id, ks.Type())
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
+		logger.Errorf("failed to decode extrinsic data: %s", err)
 	}
 
-	keys := ks.PublicKeys()
+	// validate the transaction
+	txv := transaction.NewValidity(0, [][]byte{{}}, [][]byte{{}}, 0, false)
+	vtx := transaction.NewValidTransaction(extrinsic, txv)
 
-	var encodedKeys []byte
-	for _, key := range keys {
-		encodedKeys = append(encodedKeys, key.Encode()...)
-	}
+	runtimeCtx := getContext(vm)
+	runtimeCtx.Transaction.AddToPool(vtx)
 
-	prefix, err := scale.Marshal(big.NewInt(int64(len(keys))))
+	ptr, err := toWasmMemoryOptional(vm, nil)
 	if err != nil {
 		logger.Errorf("failed to allocate memory: %s", err)
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
 	}
+	return ptr
+}
 
-	ret, err := toWasmMemory(memory, append(prefix, encodedKeys...))
-	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		ret, _ = toWasmMemory(memory, []byte{0})
-		return ret
-	}
+func ext_offchain_timestamp_version_1(_ *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
 
-	return ret
+	now := time.Now().UnixMilli()
+	return int64(now)
 }
 
-func ext_crypto_ed25519_generate_version_1(vm *exec.VirtualMachine) int64 {
+func ext_offchain_sleep_until_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	deadline := vm.GetCurrentFrame().Locals[0]
 
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	seedSpan := vm.GetCurrentFrame().Locals[1]
-	memory := vm.Memory
+	dur := time.Until(time.UnixMilli(deadline))
+	if dur > 0 {
+		time.Sleep(dur)
+	}
+	return 0
+}
 
-	id := memory[keyTypeID : keyTypeID+4]
-	seedBytes := asMemorySlice(memory, seedSpan)
+func ext_offchain_http_request_start_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	methodSpan := vm.GetCurrentFrame().Locals[0]
+	uriSpan := vm.GetCurrentFrame().Locals[1]
 
-	var seed *[]byte
-	err := scale.Unmarshal(seedBytes, &seed)
-	if err != nil {
-		logger.Warnf("cannot generate key: %s", err)
-		return 0
-	}
+	runtimeCtx := getContext(vm)
 
-	var kp crypto.Keypair
+	httpMethod := asMemorySlice(vm, methodSpan)
+	uri := asMemorySlice(vm, uriSpan)
 
-	if seed != nil {
-		kp, err = ed25519.NewKeypairFromMnenomic(string(*seed), "")
-	} else {
-		kp, err = ed25519.GenerateKeypair()
-	}
+	result := scale.NewResult(int16(0), nil)
 
+	reqID, err := runtimeCtx.OffchainHTTPSet.StartRequest(string(httpMethod), string(uri))
 	if err != nil {
-		logger.Warnf("cannot generate key: %s", err)
-		return 0
+		// StartRequest error already was logged
+		logger.Errorf("failed to start request: %s", err)
+		err = result.Set(scale.Err, nil)
+	} else {
+		err = result.Set(scale.OK, reqID)
 	}
 
-	ks, err := ctx.Keystore.GetKeystore(id)
+	// note: just check if an error occurs while setting the result data
 	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		return 0
+		logger.Errorf("failed to set the result data: %s", err)
+		return int64(0)
 	}
 
-	err = ks.Insert(kp)
+	enc, err := scale.Marshal(result)
 	if err != nil {
-		logger.Warnf("failed to insert key: %s", err)
-		return 0
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return int64(0)
 	}
 
-	ret, err := toWasmMemorySized(memory, kp.Public().Encode(), 32)
+	ptr, err := toWasmMemory(vm, enc)
 	if err != nil {
-		logger.Warnf("failed to allocate memory: %s", err)
-		return 0
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
 	}
 
-	logger.Debug(
-		"generated ed25519 keypair with resulting public key: " +
-			kp.Public().Hex())
-	return int64(ret)
+	return ptr
 }
 
-func ext_crypto_ed25519_sign_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+func ext_offchain_http_request_add_header_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	reqID := int32(vm.GetCurrentFrame().Locals[0])
+	nameSpan := vm.GetCurrentFrame().Locals[1]
+	valueSpan := vm.GetCurrentFrame().Locals[2]
 
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	key := vm.GetCurrentFrame().Locals[1]
-	msg := vm.GetCurrentFrame().Locals[2]
-	memory := vm.Memory
+	name := asMemorySlice(vm, nameSpan)
+	value := asMemorySlice(vm, valueSpan)
 
-	id := memory[keyTypeID : keyTypeID+4]
+	runtimeCtx := getContext(vm)
+	offchainReq := runtimeCtx.OffchainHTTPSet.Get(int16(reqID))
 
-	pubKeyData := memory[key : key+32]
-	pubKey, err := ed25519.NewPublicKey(pubKeyData)
+	result := scale.NewResult(nil, nil)
+	resultMode := scale.OK
+
+	err := offchainReq.AddHeader(string(name), string(value))
 	if err != nil {
-		logger.Errorf("failed to get public keys: %s", err)
-		return 0
+		logger.Errorf("failed to add request header: %s", err)
+		resultMode = scale.Err
 	}
 
-	ks, err := ctx.Keystore.GetKeystore(id)
+	err = result.Set(resultMode, nil)
 	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		ret, _ := toWasmMemoryOptional(memory, nil)
-		return ret
+		logger.Errorf("failed to set the result data: %s", err)
+		return int64(0)
 	}
 
-	var ret int64
-	signingKey := ks.GetKeypair(pubKey)
-	if signingKey == nil {
-		logger.Error("could not find public key " + pubKey.Hex() + " in keystore")
-		ret, err = toWasmMemoryOptional(memory, nil)
-		if err != nil {
-			logger.Errorf("failed to allocate memory: %s", err)
-			return 0
-		}
-		return ret
+	enc, err := scale.Marshal(result)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return int64(0)
 	}
 
-	sig, err := signingKey.Sign(asMemorySlice(memory, msg))
+	ptr, err := toWasmMemory(vm, enc)
 	if err != nil {
-		logger.Error("could not sign message")
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
 	}
 
-	ret, err = toWasmMemoryFixedSizeOptional(memory, sig)
+	return ptr
+}
+
+// decodeDeadline decodes the SCALE encoded optional deadline, in milliseconds since the UNIX epoch,
+// passed to the offchain HTTP host functions. A nil deadline means waiting indefinitely.
+func decodeDeadline(enc []byte) (*time.Time, error) {
+	var millis *uint64
+	err := scale.Unmarshal(enc, &millis)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		return 0
+		return nil, err
 	}
 
-	return ret
+	if millis == nil {
+		return nil, nil
+	}
+
+	deadline := time.UnixMilli(int64(*millis))
+	return &deadline, nil
 }
 
-func ext_crypto_ed25519_verify_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+// httpErrorResult sets the result to the offchain.HTTPError matching the given error
+func httpErrorResult(result *scale.Result, err error) error {
+	httpErr := offchain.HTTPErrorIO
+	errors.As(err, &httpErr)
+	return result.Set(scale.Err, httpErr)
+}
 
-	sig := vm.GetCurrentFrame().Locals[0]
-	msg := vm.GetCurrentFrame().Locals[1]
-	key := vm.GetCurrentFrame().Locals[2]
-	memory := vm.Memory
-	sigVerifier := ctx.SigVerifier
+func ext_offchain_http_request_write_body_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	reqID := int32(vm.GetCurrentFrame().Locals[0])
+	chunkSpan := vm.GetCurrentFrame().Locals[1]
+	deadlineSpan := vm.GetCurrentFrame().Locals[2]
 
-	signature := memory[sig : sig+64]
-	message := asMemorySlice(memory, msg)
-	pubKeyData := memory[key : key+32]
+	runtimeCtx := getContext(vm)
 
-	pubKey, err := ed25519.NewPublicKey(pubKeyData)
+	result := scale.NewResult(nil, offchain.HTTPError(0))
+
+	deadline, err := decodeDeadline(asMemorySlice(vm, deadlineSpan))
 	if err != nil {
-		logger.Error("failed to create public key")
-		return 0
+		logger.Errorf("failed to decode deadline: %s", err)
+		err = result.Set(scale.Err, offchain.HTTPErrorInvalid)
+	} else {
+		chunk := asMemorySlice(vm, chunkSpan)
+		err = runtimeCtx.OffchainHTTPSet.WriteBody(int16(reqID), chunk, deadline)
+		if err != nil {
+			logger.Debugf("failed to write request body: %s", err)
+			err = httpErrorResult(&result, err)
+		} else {
+			err = result.Set(scale.OK, nil)
+		}
 	}
 
-	if sigVerifier.IsStarted() {
-		signature := crypto.SignatureInfo{
-			PubKey:     pubKey.Encode(),
-			Sign:       signature,
-			Msg:        message,
-			VerifyFunc: ed25519.VerifySignature,
-		}
-		sigVerifier.Add(&signature)
-		return 1
+	if err != nil {
+		logger.Errorf("failed to set the result data: %s", err)
+		return int64(0)
 	}
 
-	if ok, err := pubKey.Verify(message, signature); err != nil || !ok {
-		logger.Error("failed to verify")
-		return 0
+	enc, err := scale.Marshal(result)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return int64(0)
 	}
 
-	logger.Debug("verified ed25519 signature")
-	return 1
-}
+	ptr, err := toWasmMemory(vm, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
+	}
 
-func ext_crypto_sr25519_public_keys_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+	return ptr
+}
 
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+func ext_offchain_http_response_wait_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	idsSpan := vm.GetCurrentFrame().Locals[0]
+	deadlineSpan := vm.GetCurrentFrame().Locals[1]
 
-	id := memory[keyTypeID : keyTypeID+4]
+	runtimeCtx := getContext(vm)
 
-	ks, err := ctx.Keystore.GetKeystore(id)
+	var ids []int16
+	err := scale.Unmarshal(asMemorySlice(vm, idsSpan), &ids)
 	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
+		logger.Errorf("failed to decode request ids: %s", err)
+		return int64(0)
 	}
 
-	if ks.Type() != crypto.Sr25519Type && ks.Type() != crypto.UnknownType {
-		logger.Warnf(
-			"keystore type for id 0x%x is %s and not the expected sr25519",
-			id, ks.Type())
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
+	deadline, err := decodeDeadline(asMemorySlice(vm, deadlineSpan))
+	if err != nil {
+		logger.Errorf("failed to decode deadline: %s", err)
+		return int64(0)
 	}
 
-	keys := ks.PublicKeys()
-
-	var encodedKeys []byte
-	for _, key := range keys {
-		encodedKeys = append(encodedKeys, key.Encode()...)
+	statuses := scale.NewVaryingDataTypeSlice(offchain.NewHTTPRequestStatus())
+	err = statuses.Add(runtimeCtx.OffchainHTTPSet.Wait(ids, deadline)...)
+	if err != nil {
+		logger.Errorf("failed to set the request statuses: %s", err)
+		return int64(0)
 	}
 
-	prefix, err := scale.Marshal(big.NewInt(int64(len(keys))))
+	enc, err := scale.Marshal(statuses)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		ret, _ := toWasmMemory(memory, []byte{0})
-		return ret
+		logger.Errorf("failed to scale marshal the request statuses: %s", err)
+		return int64(0)
 	}
 
-	ret, err := toWasmMemory(memory, append(prefix, encodedKeys...))
+	ptr, err := toWasmMemory(vm, enc)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		ret, _ = toWasmMemory(memory, []byte{0})
-		return ret
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
 	}
 
-	return ret
+	return ptr
 }
 
-func ext_crypto_sr25519_generate_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
-
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	seedSpan := vm.GetCurrentFrame().Locals[1]
-	memory := vm.Memory
+func ext_offchain_http_response_headers_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	reqID := int32(vm.GetCurrentFrame().Locals[0])
 
-	id := memory[keyTypeID : keyTypeID+4]
-	seedBytes := asMemorySlice(memory, seedSpan)
+	runtimeCtx := getContext(vm)
 
-	var seed *[]byte
-	err := scale.Unmarshal(seedBytes, &seed)
+	headers := runtimeCtx.OffchainHTTPSet.ResponseHeaders(int16(reqID))
+	enc, err := scale.Marshal(headers)
 	if err != nil {
-		logger.Warnf("cannot generate key: %s", err)
-		return 0
+		logger.Errorf("failed to scale marshal the response headers: %s", err)
+		return int64(0)
 	}
 
-	var kp crypto.Keypair
-	if seed != nil {
-		kp, err = sr25519.NewKeypairFromMnenomic(string(*seed), "")
-	} else {
-		kp, err = sr25519.GenerateKeypair()
+	ptr, err := toWasmMemory(vm, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
 	}
 
+	return ptr
+}
+
+func ext_offchain_http_response_read_body_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	reqID := int32(vm.GetCurrentFrame().Locals[0])
+	bufferSpan := vm.GetCurrentFrame().Locals[1]
+	deadlineSpan := vm.GetCurrentFrame().Locals[2]
+
+	runtimeCtx := getContext(vm)
+
+	result := scale.NewResult(uint32(0), offchain.HTTPError(0))
+
+	deadline, err := decodeDeadline(asMemorySlice(vm, deadlineSpan))
 	if err != nil {
-		logger.Tracef("cannot generate key: %s", err)
-		panic(err)
+		logger.Errorf("failed to decode deadline: %s", err)
+		err = result.Set(scale.Err, offchain.HTTPErrorInvalid)
+	} else {
+		buffer := asMemorySlice(vm, bufferSpan)
+		var n int
+		n, err = runtimeCtx.OffchainHTTPSet.ReadBody(int16(reqID), buffer, deadline)
+		if err != nil {
+			logger.Debugf("failed to read response body: %s", err)
+			err = httpErrorResult(&result, err)
+		} else {
+			err = result.Set(scale.OK, uint32(n))
+		}
 	}
 
-	ks, err := ctx.Keystore.GetKeystore(id)
 	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		return 0
+		logger.Errorf("failed to set the result data: %s", err)
+		return int64(0)
 	}
 
-	err = ks.Insert(kp)
+	enc, err := scale.Marshal(result)
 	if err != nil {
-		logger.Warnf("failed to insert key: %s", err)
-		return 0
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return int64(0)
 	}
 
-	ret, err := toWasmMemorySized(memory, kp.Public().Encode(), 32)
+	ptr, err := toWasmMemory(vm, enc)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		return 0
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return int64(0)
 	}
 
-	logger.Debug(
-		"generated sr25519 keypair with resulting public key: " +
-			kp.Public().Hex())
-	return int64(ret)
+	return ptr
 }
 
-func ext_crypto_sr25519_sign_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Trace("executing...")
+func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
+	nextLength := big.NewInt(1)
+	var valueRes []byte
 
-	keyTypeID := vm.GetCurrentFrame().Locals[0]
-	key := vm.GetCurrentFrame().Locals[1]
-	msg := vm.GetCurrentFrame().Locals[2]
-	memory := vm.Memory
+	// this function assumes the item in storage is a SCALE encoded array of items
+	// the valueToAppend is a new item, so it appends the item and increases the length prefix by 1
+	valueCurr := storage.Get(key)
 
-	emptyRet, _ := toWasmMemoryOptional(memory, nil)
+	if len(valueCurr) == 0 {
+		valueRes = valueToAppend
+	} else {
+		var currLength *big.Int
+		err := scale.Unmarshal(valueCurr, &currLength)
+		if err != nil {
+			logger.Tracef(
+				"item in storage is not SCALE encoded, overwriting at key 0x%x", key)
+			storage.Set(key, append([]byte{4}, valueToAppend...))
+			return nil
+		}
 
-	id := memory[keyTypeID : keyTypeID+4]
+		lengthBytes, err := scale.Marshal(currLength)
+		if err != nil {
+			return err
+		}
+		// append new item, pop off number of bytes required for length encoding,
+		// since we're not using old scale.Decoder
+		valueRes = append(valueCurr[len(lengthBytes):], valueToAppend...)
 
-	ks, err := ctx.Keystore.GetKeystore(id)
-	if err != nil {
-		logger.Warnf("error for id 0x%x: %s", id, err)
-		return emptyRet
+		// increase length by 1
+		nextLength = big.NewInt(0).Add(currLength, big.NewInt(1))
 	}
 
-	var ret int64
-	pubKey, err := sr25519.NewPublicKey(memory[key : key+32])
+	lengthEnc, err := scale.Marshal(nextLength)
 	if err != nil {
-		logger.Errorf("failed to get public key: %s", err)
-		return emptyRet
+		logger.Tracef("failed to encode new length: %s", err)
+		return err
 	}
 
-	signingKey := ks.GetKeypair(pubKey)
-	if signingKey == nil {
-		logger.Error("could not find public key " + pubKey.Hex() + " in keystore")
-		return emptyRet
-	}
+	// append new length prefix to start of items array
+	lengthEnc = append(lengthEnc, valueRes...)
+	logger.Debugf("resulting value: 0x%x", lengthEnc)
+	storage.Set(key, lengthEnc)
+	return nil
+}
+
+func ext_storage_append_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	valueSpan := vm.GetCurrentFrame().Locals[1]
+	ctx := getContext(vm)
+	storage := ctx.Storage
+
+	key := asMemorySlice(vm, keySpan)
+	valueAppend := asMemorySlice(vm, valueSpan)
+	logger.Debugf(
+		"will append value 0x%x to values at key 0x%x",
+		valueAppend, key)
 
-	msgData := asMemorySlice(memory, msg)
-	sig, err := signingKey.Sign(msgData)
+	cp := make([]byte, len(valueAppend))
+	copy(cp, valueAppend)
+
+	err := storageAppend(storage, key, cp)
 	if err != nil {
-		logger.Errorf("could not sign message: %s", err)
-		return emptyRet
+		logger.Errorf("[ext_storage_append_version_1]: %s", err)
 	}
+	return 0
+}
+
+func ext_storage_changes_root_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	logger.Debug("returning None")
 
-	ret, err = toWasmMemoryFixedSizeOptional(memory, sig)
+	rootSpan, err := toWasmMemoryOptional(vm, nil)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		return emptyRet
+		logger.Errorf("failed to allocate: %s", err)
+		return 0
 	}
 
-	return ret
+	return rootSpan
 }
 
-func ext_crypto_sr25519_verify_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_clear_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	ctx := getContext(vm)
+	storage := ctx.Storage
 
-	sig := vm.GetCurrentFrame().Locals[0]
-	msg := vm.GetCurrentFrame().Locals[1]
-	key := vm.GetCurrentFrame().Locals[2]
-	memory := vm.Memory
-	sigVerifier := ctx.SigVerifier
-
-	message := asMemorySlice(memory, msg)
-	signature := memory[sig : sig+64]
+	key := asMemorySlice(vm, keySpan)
 
-	pub, err := sr25519.NewPublicKey(memory[key : key+32])
-	if err != nil {
-		logger.Error("invalid sr25519 public key")
-		return 0
-	}
+	logger.Debugf("key: 0x%x", key)
+	storage.Delete(key)
+	return 0
+}
 
-	logger.Debugf(
-		"pub=%s; message=0x%x; signature=0x%x",
-		pub.Hex(), message, signature)
+func ext_storage_clear_prefix_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	prefixSpan := vm.GetCurrentFrame().Locals[0]
+	ctx := getContext(vm)
+	storage := ctx.Storage
 
-	if sigVerifier.IsStarted() {
-		signature := crypto.SignatureInfo{
-			PubKey:     pub.Encode(),
-			Sign:       signature,
-			Msg:        message,
-			VerifyFunc: sr25519.VerifySignature,
-		}
-		sigVerifier.Add(&signature)
-		return 1
-	}
+	prefix := asMemorySlice(vm, prefixSpan)
+	logger.Debugf("prefix: 0x%x", prefix)
 
-	if ok, err := pub.VerifyDeprecated(message, signature); err != nil || !ok {
-		logger.Debugf("failed to validate signature: %s", err)
-		// this fails at block 3876, however based on discussions this seems to be expected
-		return 1
+	err := storage.ClearPrefix(prefix)
+	if err != nil {
+		logger.Errorf("[ext_storage_clear_prefix_version_1]: %s", err)
 	}
-
-	logger.Debug("verified sr25519 signature")
-	return 1
+	return 0
 }
 
-func ext_crypto_secp256k1_ecdsa_recover_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_clear_prefix_version_2(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	prefixSpan := vm.GetCurrentFrame().Locals[0]
+	lim := vm.GetCurrentFrame().Locals[1]
 
-	sig := vm.GetCurrentFrame().Locals[0]
-	msg := vm.GetCurrentFrame().Locals[1]
-	memory := vm.Memory
+	ctx := getContext(vm)
+	storage := ctx.Storage
 
-	// msg must be the 32-byte hash of the message to be signed.
-	// sig must be a 65-byte compact ECDSA signature containing the
-	// recovery id as the last element
-	message := memory[msg : msg+32]
-	signature := memory[sig : sig+65]
+	prefix := asMemorySlice(vm, prefixSpan)
+	logger.Debugf("prefix: 0x%x", prefix)
+
+	limitBytes := asMemorySlice(vm, lim)
 
-	if signature[64] == 27 {
-		signature[64] = 0
+	var limit []byte
+	err := scale.Unmarshal(limitBytes, &limit)
+	if err != nil {
+		logger.Warnf("[ext_storage_clear_prefix_version_2]: cannot generate limit: %s", err)
+		ret, _ := toWasmMemory(vm, nil)
+		return ret
 	}
 
-	if signature[64] == 28 {
-		signature[64] = 1
+	if len(limit) == 0 {
+		// limit is None, set limit to max
+		limit = []byte{0xff, 0xff, 0xff, 0xff}
 	}
 
-	pub, err := secp256k1.RecoverPublicKey(message, signature)
+	limitUint := binary.LittleEndian.Uint32(limit)
+	numRemoved, all := storage.ClearPrefixLimit(prefix, limitUint)
+	encBytes, err := toKillStorageResultEnum(all, numRemoved)
 	if err != nil {
-		logger.Errorf("failed to recover public key: %s", err)
-		var ret int64
-		ret, err = toWasmMemoryResult(memory, nil)
-		if err != nil {
-			logger.Errorf("failed to allocate memory: %s", err)
-			return 0
-		}
+		logger.Errorf("failed to allocate memory: %s", err)
+		ret, _ := toWasmMemory(vm, nil)
 		return ret
 	}
 
-	logger.Debugf(
-		"recovered public key of length %d: 0x%x",
-		len(pub), pub)
-
-	ret, err := toWasmMemoryResult(memory, pub[1:])
+	valueSpan, err := toWasmMemory(vm, encBytes)
 	if err != nil {
-		logger.Errorf("failed to allocate memory: %s", err)
-		return 0
+		logger.Errorf("failed to allocate: %s", err)
+		ptr, _ := toWasmMemory(vm, nil)
+		return ptr
 	}
 
-	return ret
+	return valueSpan
 }
 
-func ext_hashing_keccak_256_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_exists_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	storage := getContext(vm).Storage
 
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
-
-	data := asMemorySlice(memory, dataSpan)
+	key := asMemorySlice(vm, keySpan)
+	logger.Debugf("key: 0x%x", key)
 
-	hash, err := common.Keccak256(data)
-	if err != nil {
-		logger.Errorf("[ext_hashing_keccak_256_version_1]: %s", err)
-		return 0
+	val := storage.Get(key)
+	if len(val) > 0 {
+		return 1
 	}
 
-	logger.Debugf("data 0x%x has hash %s", data, hash)
+	return 0
+}
+
+func ext_storage_get_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+
+	storage := getContext(vm).Storage
+
+	key := asMemorySlice(vm, keySpan)
+	logger.Debugf("key: 0x%x", key)
+
+	value := storage.Get(key)
+	logger.Debugf("value: 0x%x", value)
 
-	out, err := toWasmMemorySized(memory, hash[:], 32)
+	valueSpan, err := toWasmMemoryOptional(vm, value)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
-		return 0
+		ptr, _ := toWasmMemoryOptional(vm, nil)
+		return ptr
 	}
 
-	return int64(out)
+	return valueSpan
 }
 
-func ext_hashing_sha2_256_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_next_key_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
 
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+	storage := getContext(vm).Storage
 
-	data := asMemorySlice(memory, dataSpan)
-	hash := common.Sha256(data)
+	key := asMemorySlice(vm, keySpan)
 
-	logger.Debugf("data 0x%x hash hash %x", data, hash)
+	next := storage.NextKey(key)
+	logger.Debugf(
+		"key: 0x%x; next key 0x%x",
+		key, next)
 
-	out, err := toWasmMemorySized(memory, hash[:], 32)
+	nextSpan, err := toWasmMemoryOptional(vm, next)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return int64(out)
+	return nextSpan
 }
 
-func ext_hashing_blake2_128_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_read_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	valueOut := vm.GetCurrentFrame().Locals[1]
+	offset := int32(vm.GetCurrentFrame().Locals[2])
 
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+	storage := getContext(vm).Storage
 
-	data := asMemorySlice(memory, dataSpan)
+	key := asMemorySlice(vm, keySpan)
+	value := storage.Get(key)
+	logger.Debugf(
+		"key 0x%x has value 0x%x",
+		key, value)
 
-	hash, err := common.Blake2b128(data)
-	if err != nil {
-		logger.Errorf("[ext_hashing_blake2_128_version_1]: %s", err)
-		return 0
+	if value == nil {
+		ret, _ := toWasmMemoryOptional(vm, nil)
+		return ret
 	}
 
-	logger.Debugf("data 0x%x has hash 0x%x", data, hash)
+	var size uint32
+
+	if int(offset) > len(value) {
+		size = uint32(0)
+	} else {
+		size = uint32(len(value[offset:]))
+		valueBuf, valueLen := runtime.Int64ToPointerAndSize(valueOut)
+		copy(vm.Memory[valueBuf:valueBuf+valueLen], value[offset:])
+	}
 
-	out, err := toWasmMemorySized(memory, hash, 16)
+	sizeSpan, err := toWasmMemoryOptionalUint32(vm, &size)
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return int64(out)
+	return sizeSpan
 }
 
-func ext_hashing_twox_256_version_1(vm *exec.VirtualMachine) int64 {
+func ext_storage_root_version_1(vm *exec.VirtualMachine) int64 {
 	logger.Trace("executing...")
+	return storageRoot(vm, trie.V0)
+}
 
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+func ext_storage_root_version_2(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	version := int32(vm.GetCurrentFrame().Locals[0])
 
-	data := asMemorySlice(memory, dataSpan)
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
 
-	hash, err := common.Twox256(data)
+	return storageRoot(vm, stateVersion)
+}
+
+// storageRoot returns the root hash of the storage,
+// computed using the given state trie version.
+func storageRoot(vm *exec.VirtualMachine, version trie.Version) int64 {
+	storage := getContext(vm).Storage
+
+	storage.SetVersion(version)
+	root, err := storage.Root()
 	if err != nil {
-		logger.Errorf("[ext_hashing_twox_256_version_1]: %s", err)
+		logger.Errorf("failed to get storage root: %s", err)
 		return 0
 	}
 
-	logger.Debugf("data 0x%x has hash %s", data, hash)
+	logger.Debugf("root hash is: %s", root)
 
-	out, err := toWasmMemorySized(memory, hash[:], 32)
+	rootSpan, err := toWasmMemory(vm, root[:])
 	if err != nil {
 		logger.Errorf("failed to allocate: %s", err)
 		return 0
 	}
 
-	return int64(out)
+	return rootSpan
 }
 
-func ext_trie_blake2_256_root_version_1(vm *exec.VirtualMachine) int64 {
-	logger.Debug("executing...")
+func ext_storage_set_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Trace("executing...")
+	keySpan := vm.GetCurrentFrame().Locals[0]
+	valueSpan := vm.GetCurrentFrame().Locals[1]
 
-	dataSpan := vm.GetCurrentFrame().Locals[0]
-	memory := vm.Memory
+	ctx := getContext(vm)
+	storage := ctx.Storage
 
-	data := asMemorySlice(memory, dataSpan)
+	key := asMemorySlice(vm, keySpan)
+	value := asMemorySlice(vm, valueSpan)
 
-	t := trie.NewEmptyTrie()
+	cp := make([]byte, len(value))
+	copy(cp, value)
 
-	// this function is expecting an array of (key, value) tuples
-	type kv struct {
-		Key, Value []byte
-	}
+	logger.Debugf(
+		"key 0x%x has value 0x%x",
+		key, value)
+	storage.Set(key, cp)
+	return 0
+}
 
-	var kvs []kv
-	if err := scale.Unmarshal(data, &kvs); err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
-		return 0
-	}
+func ext_storage_start_transaction_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	getContext(vm).Storage.BeginStorageTransaction()
+	return 0
+}
 
-	for _, kv := range kvs {
-		t.Put(kv.Key, kv.Value)
-	}
+func ext_storage_rollback_transaction_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("executing...")
+	getContext(vm).Storage.RollbackStorageTransaction()
+	return 0
+}
 
-	// allocate memory for value and copy value to memory
-	ptr, err := ctx.Allocator.Allocate(32)
-	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
-		return 0
-	}
+func ext_storage_commit_transaction_version_1(vm *exec.VirtualMachine) int64 {
+	logger.Debug("[ext_storage_commit_transaction_version_1] executing...")
+	getContext(vm).Storage.CommitStorageTransaction()
+	return 0
+}
 
-	hash, err := t.Hash()
+// Convert 64bit wasm span descriptor to Go memory slice
+func asMemorySlice(vm *exec.VirtualMachine, span int64) []byte {
+	ptr, size := runtime.Int64ToPointerAndSize(span)
+	return vm.Memory[ptr : ptr+size]
+}
+
+// Copy a byte slice to wasm memory and return the resulting 64bit span descriptor
+func toWasmMemory(vm *exec.VirtualMachine, data []byte) (int64, error) {
+	allocator := getContext(vm).Allocator
+	size := uint32(len(data))
+
+	out, err := allocator.Allocate(size)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
-		return 0
+		return 0, err
 	}
 
-	logger.Debugf("root hash: %s", hash)
-	copy(memory[ptr:ptr+32], hash[:])
-	return int64(ptr)
-}
+	if uint32(len(vm.Memory)) < out+size {
+		panic(fmt.Sprintf("length of memory is less than expected, want %d have %d", out+size, len(vm.Memory)))
+	}
 
-// Convert 64bit wasm span descriptor to Go memory slice
-func asMemorySlice(memory []byte, span int64) []byte {
-	ptr, size := runtime.Int64ToPointerAndSize(span)
-	return memory[ptr : ptr+size]
+	copy(vm.Memory[out:out+size], data)
+	return runtime.PointerAndSizeToInt64(int32(out), int32(size)), nil
 }
 
 // Copy a byte slice of a fixed size to wasm memory and return resulting pointer
-func toWasmMemorySized(memory, data []byte, size uint32) (uint32, error) {
+func toWasmMemorySized(vm *exec.VirtualMachine, data []byte, size uint32) (uint32, error) {
 	if int(size) != len(data) {
 		return 0, errors.New("internal byte array size missmatch")
 	}
 
-	allocator := ctx.Allocator
+	allocator := getContext(vm).Allocator
+
 	out, err := allocator.Allocate(size)
 	if err != nil {
 		return 0, err
 	}
 
-	copy(memory[out:out+size], data)
+	copy(vm.Memory[out:out+size], data)
+
 	return out, nil
 }
 
 // Wraps slice in optional.Bytes and copies result to wasm memory. Returns resulting 64bit span descriptor
-func toWasmMemoryOptional(memory, data []byte) (int64, error) {
+func toWasmMemoryOptional(vm *exec.VirtualMachine, data []byte) (int64, error) {
 	var opt *[]byte
 	if data != nil {
-		opt = &data
+		temp := data
+		opt = &temp
 	}
 
 	enc, err := scale.Marshal(opt)
@@ -1362,25 +2702,28 @@ func toWasmMemoryOptional(memory, data []byte) (int64, error) {
 		return 0, err
 	}
 
-	return toWasmMemory(memory, enc)
+	return toWasmMemory(vm, enc)
 }
 
-// Copy a byte slice to wasm memory and return the resulting 64bit span descriptor
-func toWasmMemory(memory, data []byte) (int64, error) {
-	allocator := ctx.Allocator
-	size := uint32(len(data))
+// Wraps slice in Result type and copies result to wasm memory. Returns resulting 64bit span descriptor
+func toWasmMemoryResult(vm *exec.VirtualMachine, data []byte) (int64, error) {
+	var res *rtype.Result
+	if len(data) == 0 {
+		res = rtype.NewResult(byte(1), nil)
+	} else {
+		res = rtype.NewResult(byte(0), data)
+	}
 
-	out, err := allocator.Allocate(size)
+	enc, err := res.Encode()
 	if err != nil {
 		return 0, err
 	}
 
-	copy(memory[out:out+size], data)
-	return runtime.PointerAndSizeToInt64(int32(out), int32(size)), nil
+	return toWasmMemory(vm, enc)
 }
 
 // Wraps slice in optional and copies result to wasm memory. Returns resulting 64bit span descriptor
-func toWasmMemoryOptionalUint32(memory []byte, data *uint32) (int64, error) {
+func toWasmMemoryOptionalUint32(vm *exec.VirtualMachine, data *uint32) (int64, error) {
 	var opt *uint32
 	if data != nil {
 		temp := *data
@@ -1391,33 +2734,37 @@ func toWasmMemoryOptionalUint32(memory []byte, data *uint32) (int64, error) {
 	if err != nil {
 		return int64(0), err
 	}
-	return toWasmMemory(memory, enc)
+	return toWasmMemory(vm, enc)
 }
 
-// Wraps slice in optional.FixedSizeBytes and copies result to wasm memory. Returns resulting 64bit span descriptor
-func toWasmMemoryFixedSizeOptional(memory, data []byte) (int64, error) {
-	var opt [64]byte
-	copy(opt[:], data[:])
-	enc, err := scale.Marshal(&opt)
+// toKillStorageResult returns enum encoded value
+func toKillStorageResultEnum(allRemoved bool, numRemoved uint32) ([]byte, error) {
+	var b, sbytes []byte
+	sbytes, err := scale.Marshal(numRemoved)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return toWasmMemory(memory, enc)
-}
 
-// Wraps slice in Result type and copies result to wasm memory. Returns resulting 64bit span descriptor
-func toWasmMemoryResult(memory, data []byte) (int64, error) {
-	var res *rtype.Result
-	if len(data) == 0 {
-		res = rtype.NewResult(byte(1), nil)
+	if allRemoved {
+		// No key remains in the child trie.
+		b = append(b, byte(0))
 	} else {
-		res = rtype.NewResult(byte(0), data)
+		// At least one key still resides in the child trie due to the supplied limit.
+		b = append(b, byte(1))
 	}
 
-	enc, err := res.Encode()
+	b = append(b, sbytes...)
+
+	return b, err
+}
+
+// Wraps slice in optional.FixedSizeBytes and copies result to wasm memory. Returns resulting 64bit span descriptor
+func toWasmMemoryFixedSizeOptional(vm *exec.VirtualMachine, data []byte) (int64, error) {
+	var opt [64]byte
+	copy(opt[:], data)
+	enc, err := scale.Marshal(&opt)
 	if err != nil {
 		return 0, err
 	}
-
-	return toWasmMemory(memory, enc)
+	return toWasmMemory(vm, enc)
 }
@@ -0,0 +1,94 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package life
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/perlin-network/life/exec"
+)
+
+var (
+	errDispatchThunkNotFound  = errors.New("dispatch thunk not found in function table")
+	errDispatchThunkSignature = errors.New("dispatch thunk signature mismatch")
+	errOutOfBoundsResult      = errors.New("dispatch thunk result out of memory bounds")
+)
+
+// sandboxSupervisor routes calls made by sandboxed guests to the functions
+// they imported from the supervisor runtime.
+type sandboxSupervisor struct {
+	vm  *exec.VirtualMachine
+	ctx *runtime.Context
+}
+
+// Dispatch implements sandbox.Supervisor. The dispatch thunk is an entry of the runtime's
+// indirect function table. The arguments are allocated in the runtime memory for the
+// call, and the result allocated by the runtime is copied and freed, as Substrate does.
+func (s *sandboxSupervisor) Dispatch(dispatchThunk uint32, args []byte, state, index uint32) ([]byte, error) {
+	if uint64(dispatchThunk) >= uint64(len(s.vm.Table)) {
+		return nil, fmt.Errorf("%w: thunk %d, function %d", errDispatchThunkNotFound, dispatchThunk, index)
+	}
+
+	functionID := int(s.vm.Table[dispatchThunk])
+	code := s.vm.FunctionCode[functionID]
+	if code.NumParams != 4 || code.NumReturns != 1 {
+		return nil, fmt.Errorf("%w: thunk %d has %d parameters and %d results",
+			errDispatchThunkSignature, dispatchThunk, code.NumParams, code.NumReturns)
+	}
+
+	allocator := s.ctx.Allocator
+	argsPtr, err := allocator.Allocate(uint32(len(args)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate arguments: %w", err)
+	}
+	copy(s.vm.Memory[argsPtr:], args)
+
+	ret, err := s.call(functionID, int64(argsPtr), int64(len(args)), int64(state), int64(index))
+	// the arguments are freed even if the call failed, so trapped calls do not leak them
+	deallocErr := allocator.Deallocate(argsPtr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot call dispatch thunk %d: %w", dispatchThunk, err)
+	} else if deallocErr != nil {
+		return nil, fmt.Errorf("cannot free arguments: %w", deallocErr)
+	}
+
+	// the pointer is in the upper half of the result and the length in the lower half
+	span := uint64(ret)
+	resultPtr, resultLen := uint32(span>>32), uint32(span)
+
+	if uint64(resultPtr)+uint64(resultLen) > uint64(len(s.vm.Memory)) {
+		return nil, fmt.Errorf("%w: pointer 0x%x, length %d", errOutOfBoundsResult, resultPtr, resultLen)
+	}
+	result := append([]byte{}, s.vm.Memory[resultPtr:resultPtr+resultLen]...)
+
+	err = allocator.Deallocate(resultPtr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot free result: %w", err)
+	}
+
+	return result, nil
+}
+
+// call runs the function with the given ID while the virtual machine is suspended in a
+// host function. life cannot run a function while another one is running, so the call
+// stack of the suspended function is set aside, and restored once the function returns.
+func (s *sandboxSupervisor) call(functionID int, params ...int64) (ret int64, err error) {
+	vm := s.vm
+	callStack, currentFrame := vm.CallStack, vm.CurrentFrame
+	numValueSlots, delegate := vm.NumValueSlots, vm.Delegate
+
+	vm.CallStack = make([]exec.Frame, len(callStack))
+	vm.CurrentFrame = -1
+	vm.Delegate = nil
+
+	defer func() {
+		vm.CallStack, vm.CurrentFrame = callStack, currentFrame
+		vm.NumValueSlots, vm.Delegate = numValueSlots, delegate
+		vm.Exited, vm.ExitError = false, nil
+	}()
+
+	return vm.Run(functionID, params...)
+}
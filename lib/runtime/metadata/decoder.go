@@ -0,0 +1,122 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// ErrTrailingBytes is returned when bytes are left after decoding
+var ErrTrailingBytes = errors.New("trailing bytes")
+
+// decoder decodes the SCALE encoded metadata field by field.
+type decoder struct {
+	reader  *bytes.Reader
+	decoder *scale.Decoder
+}
+
+func newDecoder(reader *bytes.Reader) *decoder {
+	return &decoder{
+		reader:  reader,
+		decoder: scale.NewDecoder(reader),
+	}
+}
+
+func (d *decoder) decode(dst interface{}) error {
+	return d.decoder.Decode(dst)
+}
+
+// remaining returns the number of bytes left to decode.
+func (d *decoder) remaining() int {
+	return d.reader.Len()
+}
+
+func (d *decoder) readByte() (b byte, err error) {
+	err = d.decode(&b)
+	return b, err
+}
+
+func (d *decoder) readString() (s string, err error) {
+	err = d.decode(&s)
+	return s, err
+}
+
+func (d *decoder) readStrings() (s []string, err error) {
+	err = d.decode(&s)
+	return s, err
+}
+
+func (d *decoder) readBytes() (b []byte, err error) {
+	err = d.decode(&b)
+	return b, err
+}
+
+// readLength reads a compact encoded length.
+func (d *decoder) readLength() (length uint, err error) {
+	err = d.decode(&length)
+	return length, err
+}
+
+// readTypeID reads a compact encoded type identifier.
+func (d *decoder) readTypeID() (id TypeID, err error) {
+	var n uint
+	err = d.decode(&n)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint32 {
+		return 0, fmt.Errorf("type id %d overflows 32 bits", n)
+	}
+	return TypeID(n), nil
+}
+
+// readOption reads the prefix of an optional value,
+// and returns true if the value is present.
+func (d *decoder) readOption() (some bool, err error) {
+	b, err := d.readByte()
+	if err != nil {
+		return false, err
+	}
+
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid option prefix: %d", b)
+	}
+}
+
+// readOptionString reads an optional string, which is empty if not present.
+func (d *decoder) readOptionString() (s string, err error) {
+	some, err := d.readOption()
+	if err != nil || !some {
+		return "", err
+	}
+	return d.readString()
+}
+
+// decodeVec decodes a compact length prefixed sequence of elements
+// decoded one after the other with the given function.
+func decodeVec[T any](d *decoder, decodeElement func(d *decoder) (T, error)) (elements []T, err error) {
+	length, err := d.readLength()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode length: %w", err)
+	}
+
+	for i := uint(0); i < length; i++ {
+		element, err := decodeElement(d)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// ErrTooManyKeys is returned when building a storage key with more keys than the storage entry has
+var ErrTooManyKeys = errors.New("too many keys")
+
+// StorageHasher is the hasher of a storage map key
+type StorageHasher uint8
+
+// Storage hashers, in the order of their encoding
+const (
+	HasherBlake2128 StorageHasher = iota
+	HasherBlake2256
+	HasherBlake2128Concat
+	HasherTwox128
+	HasherTwox256
+	HasherTwox64Concat
+	HasherIdentity
+)
+
+func (h StorageHasher) String() string {
+	switch h {
+	case HasherBlake2128:
+		return "Blake2_128"
+	case HasherBlake2256:
+		return "Blake2_256"
+	case HasherBlake2128Concat:
+		return "Blake2_128Concat"
+	case HasherTwox128:
+		return "Twox128"
+	case HasherTwox256:
+		return "Twox256"
+	case HasherTwox64Concat:
+		return "Twox64Concat"
+	case HasherIdentity:
+		return "Identity"
+	default:
+		return fmt.Sprintf("StorageHasher(%d)", uint8(h))
+	}
+}
+
+// Hash hashes the SCALE encoded key given.
+func (h StorageHasher) Hash(key []byte) (hashed []byte, err error) {
+	switch h {
+	case HasherBlake2128:
+		return common.Blake2b128(key)
+	case HasherBlake2256:
+		hash, err := common.Blake2bHash(key)
+		return hash[:], err
+	case HasherBlake2128Concat:
+		hashed, err = common.Blake2b128(key)
+		return append(hashed, key...), err
+	case HasherTwox128:
+		return common.Twox128Hash(key)
+	case HasherTwox256:
+		hash, err := common.Twox256(key)
+		return hash[:], err
+	case HasherTwox64Concat:
+		hashed, err = common.Twox64(key)
+		return append(hashed, key...), err
+	case HasherIdentity:
+		return append([]byte{}, key...), nil
+	default:
+		return nil, fmt.Errorf("invalid storage hasher: %d", uint8(h))
+	}
+}
+
+func decodeStorageHasher(d *decoder) (hasher StorageHasher, err error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode hasher: %w", err)
+	}
+
+	hasher = StorageHasher(b)
+	if hasher > HasherIdentity {
+		return 0, fmt.Errorf("invalid hasher: %d", b)
+	}
+	return hasher, nil
+}
+
+// Key returns the storage key of the entry with the given name, for the SCALE encoded map keys
+// given. Fewer keys than the hashers of the entry can be given to build a key prefix.
+func (s *Storage) Key(name string, keys ...[]byte) (storageKey []byte, err error) {
+	entry, ok := s.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("storage entry %s not found in %s", name, s.Prefix)
+	}
+
+	if len(keys) > len(entry.Hashers) {
+		return nil, fmt.Errorf("%w: %d keys for %d hashers of %s %s",
+			ErrTooManyKeys, len(keys), len(entry.Hashers), s.Prefix, name)
+	}
+
+	storageKey, err = common.Twox128Hash([]byte(s.Prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	hashedName, err := common.Twox128Hash([]byte(name))
+	if err != nil {
+		return nil, err
+	}
+	storageKey = append(storageKey, hashedName...)
+
+	for i, key := range keys {
+		hashed, err := entry.Hashers[i].Hash(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash key %d: %w", i, err)
+		}
+		storageKey = append(storageKey, hashed...)
+	}
+
+	return storageKey, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StorageHasher_Hash(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("System")
+
+	testCases := map[StorageHasher]string{
+		HasherBlake2128:       "0x789f1c09383940a7773420432ffd084a",
+		HasherBlake2256:       "0xf72e3d99d040a28fb747e589243f3ecb618430419f121d07f6aa40e7a30a894b",
+		HasherBlake2128Concat: "0x789f1c09383940a7773420432ffd084a53797374656d",
+		HasherTwox128:         "0x26aa394eea5630e07c48ae0c9558cef7",
+		HasherTwox256:         "0x26aa394eea5630e07c48ae0c9558cef714355510e01e85b83bb4d561945dad84",
+		HasherTwox64Concat:    "0x26aa394eea5630e053797374656d",
+		HasherIdentity:        "0x53797374656d",
+	}
+
+	for hasher, expected := range testCases {
+		hasher, expected := hasher, expected
+		t.Run(hasher.String(), func(t *testing.T) {
+			t.Parallel()
+
+			hashed, err := hasher.Hash(key)
+			require.NoError(t, err)
+			assert.Equal(t, expected, common.BytesToHex(hashed))
+		})
+	}
+}
+
+func Test_Storage_Key(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := DecodeOpaque(readTestMetadata(t, "polkadot_9100_v13.meta.gz"))
+	require.NoError(t, err)
+	system, ok := metadata.Pallet("System")
+	require.True(t, ok)
+
+	const accountPrefix = "0x26aa394eea5630e07c48ae0c9558cef7b99d880ec681799c0cf30e8886371da9"
+
+	key, err := system.Storage.Key("Account")
+	require.NoError(t, err)
+	assert.Equal(t, accountPrefix, common.BytesToHex(key))
+
+	accountID := make([]byte, 32)
+	key, err = system.Storage.Key("Account", accountID)
+	require.NoError(t, err)
+	hashed, err := HasherBlake2128Concat.Hash(accountID)
+	require.NoError(t, err)
+	assert.Equal(t, accountPrefix+common.BytesToHex(hashed)[2:], common.BytesToHex(key))
+
+	_, err = system.Storage.Key("Account", accountID, accountID)
+	assert.ErrorIs(t, err, ErrTooManyKeys)
+	assert.EqualError(t, err, "too many keys: 2 keys for 1 hashers of System Account")
+
+	_, err = system.Storage.Key("Missing")
+	assert.EqualError(t, err, "storage entry Missing not found in System")
+}
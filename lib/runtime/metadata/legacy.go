@@ -0,0 +1,338 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"fmt"
+)
+
+// storage entry types of the metadata versions 12 and 13
+const (
+	legacyStoragePlain byte = iota
+	legacyStorageMap
+	legacyStorageDoubleMap
+	// legacyStorageNMap is only found from version 13.
+	legacyStorageNMap
+)
+
+// decodeLegacy decodes the metadata versions 12 and 13, where types are referenced by name.
+func decodeLegacy(d *decoder, version uint8) (metadata *Metadata, err error) {
+	metadata = &Metadata{Version: version}
+
+	metadata.Pallets, err = decodeVec(d, func(d *decoder) (Pallet, error) {
+		return decodeLegacyModule(d, version)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode modules: %w", err)
+	}
+
+	metadata.Extrinsic.Version, err = d.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode extrinsic version: %w", err)
+	}
+
+	identifiers, err := d.readStrings()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode signed extensions: %w", err)
+	}
+	for _, identifier := range identifiers {
+		metadata.Extrinsic.SignedExtensions = append(metadata.Extrinsic.SignedExtensions,
+			SignedExtension{Identifier: identifier})
+	}
+
+	return metadata, nil
+}
+
+func decodeLegacyModule(d *decoder, version uint8) (pallet Pallet, err error) {
+	pallet.Name, err = d.readString()
+	if err != nil {
+		return pallet, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	some, err := d.readOption()
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode storage: %w", pallet.Name, err)
+	}
+	if some {
+		pallet.Storage, err = decodeLegacyStorage(d, version)
+		if err != nil {
+			return pallet, fmt.Errorf("module %s: cannot decode storage: %w", pallet.Name, err)
+		}
+	}
+
+	some, err = d.readOption()
+	if err == nil && some {
+		pallet.Calls, err = decodeVec(d, decodeLegacyCall)
+	}
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode calls: %w", pallet.Name, err)
+	}
+
+	some, err = d.readOption()
+	if err == nil && some {
+		pallet.Events, err = decodeVec(d, decodeLegacyEvent)
+	}
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode events: %w", pallet.Name, err)
+	}
+
+	pallet.Constants, err = decodeVec(d, decodeLegacyConstant)
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode constants: %w", pallet.Name, err)
+	}
+
+	pallet.Errors, err = decodeVec(d, decodeLegacyError)
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode errors: %w", pallet.Name, err)
+	}
+
+	pallet.Index, err = d.readByte()
+	if err != nil {
+		return pallet, fmt.Errorf("module %s: cannot decode index: %w", pallet.Name, err)
+	}
+
+	// calls, events and errors are indexed by their position
+	for _, variants := range [][]Variant{pallet.Calls, pallet.Events, pallet.Errors} {
+		for i := range variants {
+			variants[i].Index = uint8(i)
+		}
+	}
+
+	return pallet, nil
+}
+
+func decodeLegacyStorage(d *decoder, version uint8) (storage *Storage, err error) {
+	storage = new(Storage)
+	storage.Prefix, err = d.readString()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode prefix: %w", err)
+	}
+
+	storage.Entries, err = decodeVec(d, func(d *decoder) (StorageEntry, error) {
+		return decodeLegacyStorageEntry(d, version)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode entries: %w", err)
+	}
+
+	return storage, nil
+}
+
+func decodeLegacyStorageEntry(d *decoder, version uint8) (entry StorageEntry, err error) {
+	entry.Name, err = d.readString()
+	if err != nil {
+		return entry, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	entry.Modifier, err = decodeStorageModifier(d)
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: %w", entry.Name, err)
+	}
+
+	err = decodeLegacyStorageEntryType(d, version, &entry)
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: %w", entry.Name, err)
+	}
+
+	entry.Default, err = d.readBytes()
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: cannot decode default value: %w", entry.Name, err)
+	}
+
+	entry.Docs, err = d.readStrings()
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: cannot decode docs: %w", entry.Name, err)
+	}
+
+	return entry, nil
+}
+
+// decodeLegacyStorageEntryType decodes the hashers, key types and
+// value type of the storage entry into the given storage entry.
+func decodeLegacyStorageEntryType(d *decoder, version uint8, entry *StorageEntry) (err error) {
+	entryType, err := d.readByte()
+	if err != nil {
+		return fmt.Errorf("cannot decode entry type: %w", err)
+	}
+
+	if entryType == legacyStorageNMap && version < 13 {
+		return fmt.Errorf("invalid storage entry type: %d", entryType)
+	}
+
+	switch entryType {
+	case legacyStoragePlain:
+		entry.Value.Name, err = d.readString()
+	case legacyStorageMap:
+		var hasher StorageHasher
+		hasher, err = decodeStorageHasher(d)
+		if err != nil {
+			return err
+		}
+		entry.Hashers = []StorageHasher{hasher}
+		entry.Keys, entry.Value, err = decodeLegacyTypeRefs(d, 1)
+		if err != nil {
+			return err
+		}
+		var unused bool
+		err = d.decode(&unused)
+	case legacyStorageDoubleMap:
+		var hasher StorageHasher
+		hasher, err = decodeStorageHasher(d)
+		if err != nil {
+			return err
+		}
+		entry.Keys, entry.Value, err = decodeLegacyTypeRefs(d, 2)
+		if err != nil {
+			return err
+		}
+		var key2Hasher StorageHasher
+		key2Hasher, err = decodeStorageHasher(d)
+		entry.Hashers = []StorageHasher{hasher, key2Hasher}
+	case legacyStorageNMap:
+		err = decodeLegacyNMap(d, entry)
+	default:
+		return fmt.Errorf("invalid storage entry type: %d", entryType)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot decode entry type: %w", err)
+	}
+
+	return nil
+}
+
+func decodeLegacyNMap(d *decoder, entry *StorageEntry) (err error) {
+	keys, err := d.readStrings()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		entry.Keys = append(entry.Keys, TypeRef{Name: key})
+	}
+
+	entry.Hashers, err = decodeVec(d, decodeStorageHasher)
+	if err != nil {
+		return err
+	}
+
+	if len(entry.Hashers) != len(entry.Keys) {
+		return fmt.Errorf("%d hashers for %d keys", len(entry.Hashers), len(entry.Keys))
+	}
+
+	entry.Value.Name, err = d.readString()
+	return err
+}
+
+// decodeLegacyTypeRefs decodes the given number of key type names followed by the value type name.
+func decodeLegacyTypeRefs(d *decoder, keys int) (keyRefs []TypeRef, valueRef TypeRef, err error) {
+	for i := 0; i < keys; i++ {
+		var key string
+		key, err = d.readString()
+		if err != nil {
+			return nil, valueRef, err
+		}
+		keyRefs = append(keyRefs, TypeRef{Name: key})
+	}
+
+	valueRef.Name, err = d.readString()
+	return keyRefs, valueRef, err
+}
+
+func decodeLegacyCall(d *decoder) (call Variant, err error) {
+	call.Name, err = d.readString()
+	if err != nil {
+		return call, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	call.Fields, err = decodeVec(d, func(d *decoder) (field Field, err error) {
+		field.Name, err = d.readString()
+		if err != nil {
+			return field, err
+		}
+		field.Type.Name, err = d.readString()
+		return field, err
+	})
+	if err != nil {
+		return call, fmt.Errorf("call %s: cannot decode arguments: %w", call.Name, err)
+	}
+
+	call.Docs, err = d.readStrings()
+	if err != nil {
+		return call, fmt.Errorf("call %s: cannot decode docs: %w", call.Name, err)
+	}
+
+	return call, nil
+}
+
+func decodeLegacyEvent(d *decoder) (event Variant, err error) {
+	event.Name, err = d.readString()
+	if err != nil {
+		return event, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	arguments, err := d.readStrings()
+	if err != nil {
+		return event, fmt.Errorf("event %s: cannot decode arguments: %w", event.Name, err)
+	}
+	for _, argument := range arguments {
+		event.Fields = append(event.Fields, Field{Type: TypeRef{Name: argument}})
+	}
+
+	event.Docs, err = d.readStrings()
+	if err != nil {
+		return event, fmt.Errorf("event %s: cannot decode docs: %w", event.Name, err)
+	}
+
+	return event, nil
+}
+
+func decodeLegacyConstant(d *decoder) (constant Constant, err error) {
+	constant.Name, err = d.readString()
+	if err != nil {
+		return constant, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	constant.Type.Name, err = d.readString()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode type: %w", constant.Name, err)
+	}
+
+	constant.Value, err = d.readBytes()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode value: %w", constant.Name, err)
+	}
+
+	constant.Docs, err = d.readStrings()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode docs: %w", constant.Name, err)
+	}
+
+	return constant, nil
+}
+
+func decodeLegacyError(d *decoder) (e Variant, err error) {
+	e.Name, err = d.readString()
+	if err != nil {
+		return e, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	e.Docs, err = d.readStrings()
+	if err != nil {
+		return e, fmt.Errorf("error %s: cannot decode docs: %w", e.Name, err)
+	}
+
+	return e, nil
+}
+
+func decodeStorageModifier(d *decoder) (modifier StorageModifier, err error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode modifier: %w", err)
+	}
+
+	modifier = StorageModifier(b)
+	if modifier > StorageDefault {
+		return 0, fmt.Errorf("invalid modifier: %d", b)
+	}
+	return modifier, nil
+}
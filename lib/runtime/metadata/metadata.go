@@ -0,0 +1,226 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+// Package metadata decodes the metadata of a runtime, from version 12 to version 14,
+// describing its pallets with their storage entries, calls, events, errors and constants.
+// From version 14, the types used by the runtime are described in a portable type registry,
+// which can be used to decode storage values and extrinsics dynamically.
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// magicNumber is "meta" read as a little endian 32-bit integer,
+// which prefixes the encoded metadata.
+const magicNumber uint32 = 0x6174656d
+
+var (
+	// ErrMagicNumber is returned when the metadata is not prefixed with the metadata magic number
+	ErrMagicNumber = errors.New("metadata magic number mismatch")
+	// ErrVersionNotSupported is returned when decoding a metadata version which is not supported
+	ErrVersionNotSupported = errors.New("metadata version not supported")
+)
+
+// Metadata is the metadata of a runtime
+type Metadata struct {
+	Version uint8
+	// Types is the portable type registry, and is nil before version 14.
+	Types     *Registry
+	Pallets   []Pallet
+	Extrinsic Extrinsic
+}
+
+// Pallet returns the pallet with the given name, and false if it is not found.
+func (m *Metadata) Pallet(name string) (pallet *Pallet, ok bool) {
+	for i := range m.Pallets {
+		if m.Pallets[i].Name == name {
+			return &m.Pallets[i], true
+		}
+	}
+	return nil, false
+}
+
+// PalletByIndex returns the pallet with the given index, and false if it is not found.
+func (m *Metadata) PalletByIndex(index uint8) (pallet *Pallet, ok bool) {
+	for i := range m.Pallets {
+		if m.Pallets[i].Index == index {
+			return &m.Pallets[i], true
+		}
+	}
+	return nil, false
+}
+
+// TypeRef is a reference to a type. Before version 14, types are only referenced by name,
+// and from version 14 by their identifier in the type registry, with an optional name.
+type TypeRef struct {
+	Name string
+	// ID is the identifier of the type in the type registry, and is only set from version 14.
+	ID TypeID
+}
+
+// Pallet is a pallet of the runtime, which is called a module before version 14
+type Pallet struct {
+	Name  string
+	Index uint8
+	// Storage is nil if the pallet has no storage.
+	Storage   *Storage
+	Calls     []Variant
+	Events    []Variant
+	Errors    []Variant
+	Constants []Constant
+}
+
+// Constant returns the constant with the given name, and false if it is not found.
+func (p *Pallet) Constant(name string) (constant *Constant, ok bool) {
+	for i := range p.Constants {
+		if p.Constants[i].Name == name {
+			return &p.Constants[i], true
+		}
+	}
+	return nil, false
+}
+
+// Variant is a call, an event or an error of a pallet, or a variant of an enum type
+type Variant struct {
+	Name   string
+	Index  uint8
+	Fields []Field
+	Docs   []string
+}
+
+// Field is a field of a variant or of a composite type
+type Field struct {
+	// Name is empty for unnamed fields.
+	Name string
+	Type TypeRef
+	Docs []string
+}
+
+// Constant is a constant of a pallet, with its SCALE encoded value
+type Constant struct {
+	Name  string
+	Type  TypeRef
+	Value []byte
+	Docs  []string
+}
+
+// Storage is the storage of a pallet
+type Storage struct {
+	Prefix  string
+	Entries []StorageEntry
+}
+
+// Entry returns the storage entry with the given name, and false if it is not found.
+func (s *Storage) Entry(name string) (entry *StorageEntry, ok bool) {
+	for i := range s.Entries {
+		if s.Entries[i].Name == name {
+			return &s.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// StorageModifier defines the value returned for a storage entry which is not set
+type StorageModifier uint8
+
+const (
+	// StorageOptional returns no value for entries which are not set
+	StorageOptional StorageModifier = iota
+	// StorageDefault returns the default value for entries which are not set
+	StorageDefault
+)
+
+func (m StorageModifier) String() string {
+	switch m {
+	case StorageOptional:
+		return "Optional"
+	case StorageDefault:
+		return "Default"
+	default:
+		return fmt.Sprintf("StorageModifier(%d)", uint8(m))
+	}
+}
+
+// StorageEntry is a storage value or a storage map of a pallet
+type StorageEntry struct {
+	Name     string
+	Modifier StorageModifier
+	// Hashers are the hashers of the keys, and is empty for storage values.
+	Hashers []StorageHasher
+	// Keys are the types of the keys, one per hasher.
+	Keys  []TypeRef
+	Value TypeRef
+	// Default is the SCALE encoded default value.
+	Default []byte
+	Docs    []string
+}
+
+// Extrinsic is the metadata of the extrinsics
+type Extrinsic struct {
+	// Type is the type of the extrinsics, and is only set from version 14.
+	Type             TypeRef
+	Version          uint8
+	SignedExtensions []SignedExtension
+}
+
+// SignedExtension is a signed extension of the extrinsics
+type SignedExtension struct {
+	Identifier string
+	// Type and AdditionalSigned are only set from version 14.
+	Type             TypeRef
+	AdditionalSigned TypeRef
+}
+
+// Decode decodes the metadata prefixed with the metadata magic number.
+func Decode(encoded []byte) (metadata *Metadata, err error) {
+	d := newDecoder(bytes.NewReader(encoded))
+
+	var magic uint32
+	err = d.decode(&magic)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode magic number: %w", err)
+	}
+	if magic != magicNumber {
+		return nil, fmt.Errorf("%w: 0x%08x", ErrMagicNumber, magic)
+	}
+
+	version, err := d.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode version: %w", err)
+	}
+
+	switch version {
+	case 12, 13:
+		metadata, err = decodeLegacy(d, version)
+	case 14:
+		metadata, err = decodeV14(d)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotSupported, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode metadata version %d: %w", version, err)
+	}
+
+	if d.remaining() > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after metadata version %d",
+			ErrTrailingBytes, d.remaining(), version)
+	}
+
+	return metadata, nil
+}
+
+// DecodeOpaque decodes the opaque metadata returned by the Metadata_metadata
+// runtime call, which is the encoded metadata prefixed with its length.
+func DecodeOpaque(opaque []byte) (metadata *Metadata, err error) {
+	var encoded []byte
+	err = scale.Unmarshal(opaque, &encoded)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode opaque metadata: %w", err)
+	}
+	return Decode(encoded)
+}
@@ -0,0 +1,344 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readTestMetadata reads the gzipped opaque metadata test file with the given name.
+func readTestMetadata(t *testing.T, name string) []byte {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("test_data", name))
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	opaque, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return opaque
+}
+
+// encode concatenates the SCALE encoding of the values given,
+// where raw bytes are appended as they are.
+func encode(t *testing.T, values ...interface{}) (encoded []byte) {
+	t.Helper()
+
+	for _, value := range values {
+		if raw, ok := value.(rawBytes); ok {
+			encoded = append(encoded, raw...)
+			continue
+		}
+		b, err := scale.Marshal(value)
+		require.NoError(t, err)
+		encoded = append(encoded, b...)
+	}
+	return encoded
+}
+
+type rawBytes []byte
+
+// some encodes the prefix of a present optional value.
+var some = rawBytes{1}
+
+// none encodes an absent optional value.
+var none = rawBytes{0}
+
+// encodeType encodes a type of the type registry without type parameters and docs.
+func encodeType(t *testing.T, id uint, path []string, def ...interface{}) rawBytes {
+	t.Helper()
+	values := append([]interface{}{id, path, uint(0)}, def...)
+	values = append(values, []string(nil))
+	return encode(t, values...)
+}
+
+// encodeField encodes a field of a composite or variant type without docs.
+func encodeField(t *testing.T, name string, id uint, typeName string) rawBytes {
+	t.Helper()
+	values := []interface{}{none}
+	if name != "" {
+		values = []interface{}{some, name}
+	}
+	values = append(values, id, some, typeName, []string(nil))
+	return encode(t, values...)
+}
+
+// newTestMetadataV14 returns a version 14 metadata of a balances pallet.
+// The version 14 metadata of a real runtime is decoded in the wasmer package tests,
+// with the metadata returned by the Polkadot v0.9.17 runtime downloaded for them.
+func newTestMetadataV14(t *testing.T) []byte {
+	t.Helper()
+
+	types := []interface{}{
+		uint(13),
+		// u8
+		encodeType(t, 0, nil, rawBytes{5, 3}),
+		// [u8; 32]
+		encodeType(t, 1, nil, rawBytes{3}, uint32(32), uint(0)),
+		// AccountId32
+		encodeType(t, 2, []string{"sp_core", "crypto", "AccountId32"}, rawBytes{0}, uint(1),
+			encodeField(t, "", 1, "[u8; 32]")),
+		// u128
+		encodeType(t, 3, nil, rawBytes{5, 7}),
+		// AccountData
+		encodeType(t, 4, []string{"pallet_balances", "AccountData"}, rawBytes{0}, uint(2),
+			encodeField(t, "free", 3, "Balance"), encodeField(t, "reserved", 3, "Balance")),
+		// Call
+		encodeType(t, 5, []string{"pallet_balances", "pallet", "Call"}, rawBytes{1}, uint(1),
+			"transfer", uint(2), encodeField(t, "dest", 2, "AccountId"),
+			encodeField(t, "value", 6, "Compact<Balance>"), uint8(0), []string{"Transfer some balance."}),
+		// Compact<u128>
+		encodeType(t, 6, nil, rawBytes{6}, uint(3)),
+		// Event
+		encodeType(t, 7, []string{"pallet_balances", "pallet", "Event"}, rawBytes{1}, uint(1),
+			"Transfer", uint(3), encodeField(t, "", 2, "AccountId"), encodeField(t, "", 2, "AccountId"),
+			encodeField(t, "", 3, "Balance"), uint8(2), []string(nil)),
+		// Error
+		encodeType(t, 8, []string{"pallet_balances", "pallet", "Error"}, rawBytes{1}, uint(1),
+			"InsufficientBalance", uint(0), uint8(0), []string(nil)),
+		// (AccountId32, u32)
+		encodeType(t, 9, nil, rawBytes{4}, []uint{2, 10}),
+		// u32
+		encodeType(t, 10, nil, rawBytes{5, 5}),
+		// Vec<u8>
+		encodeType(t, 11, nil, rawBytes{2}, uint(0)),
+		// str
+		encodeType(t, 12, nil, rawBytes{5, 2}),
+	}
+
+	pallet := []interface{}{
+		"Balances",
+		// storage
+		some, "Balances", uint(3),
+		"TotalIssuance", uint8(StorageDefault), rawBytes{0}, uint(3), make([]byte, 16), []string{"Total issuance."},
+		"Account", uint8(StorageDefault), rawBytes{1}, []uint8{uint8(HasherBlake2128Concat)}, uint(2), uint(4),
+		make([]byte, 32), []string(nil),
+		"Locks", uint8(StorageOptional), rawBytes{1},
+		[]uint8{uint8(HasherBlake2128Concat), uint8(HasherTwox64Concat)}, uint(9), uint(11),
+		[]byte(nil), []string(nil),
+		// calls
+		some, uint(5),
+		// events
+		some, uint(7),
+		// constants
+		uint(1), "ExistentialDeposit", uint(3), make([]byte, 16), []string(nil),
+		// errors
+		some, uint(8),
+		// index
+		uint8(5),
+	}
+
+	values := []interface{}{magicNumber, uint8(14)}
+	values = append(values, types...)
+	values = append(values, uint(1))
+	values = append(values, pallet...)
+	values = append(values,
+		// extrinsic
+		uint(11), uint8(4), uint(1), "CheckNonce", uint(10), uint(12),
+		// runtime type
+		uint(12))
+	return encode(t, values...)
+}
+
+func Test_Decode_V14(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := Decode(newTestMetadataV14(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(14), metadata.Version)
+	assert.Equal(t, 13, metadata.Types.Len())
+	assert.Equal(t, Extrinsic{
+		Type:    TypeRef{ID: 11},
+		Version: 4,
+		SignedExtensions: []SignedExtension{{
+			Identifier:       "CheckNonce",
+			Type:             TypeRef{ID: 10},
+			AdditionalSigned: TypeRef{ID: 12},
+		}},
+	}, metadata.Extrinsic)
+
+	expectedPallet := Pallet{
+		Name:  "Balances",
+		Index: 5,
+		Storage: &Storage{
+			Prefix: "Balances",
+			Entries: []StorageEntry{{
+				Name:     "TotalIssuance",
+				Modifier: StorageDefault,
+				Value:    TypeRef{ID: 3},
+				Default:  make([]byte, 16),
+				Docs:     []string{"Total issuance."},
+			}, {
+				Name:     "Account",
+				Modifier: StorageDefault,
+				Hashers:  []StorageHasher{HasherBlake2128Concat},
+				Keys:     []TypeRef{{ID: 2}},
+				Value:    TypeRef{ID: 4},
+				Default:  make([]byte, 32),
+			}, {
+				Name:     "Locks",
+				Modifier: StorageOptional,
+				Hashers:  []StorageHasher{HasherBlake2128Concat, HasherTwox64Concat},
+				Keys:     []TypeRef{{ID: 2}, {ID: 10}},
+				Value:    TypeRef{ID: 11},
+				Default:  []byte{},
+			}},
+		},
+		Calls: []Variant{{
+			Name: "transfer",
+			Fields: []Field{
+				{Name: "dest", Type: TypeRef{Name: "AccountId", ID: 2}},
+				{Name: "value", Type: TypeRef{Name: "Compact<Balance>", ID: 6}},
+			},
+			Docs: []string{"Transfer some balance."},
+		}},
+		Events: []Variant{{
+			Name:  "Transfer",
+			Index: 2,
+			Fields: []Field{
+				{Type: TypeRef{Name: "AccountId", ID: 2}},
+				{Type: TypeRef{Name: "AccountId", ID: 2}},
+				{Type: TypeRef{Name: "Balance", ID: 3}},
+			},
+		}},
+		Errors: []Variant{{Name: "InsufficientBalance"}},
+		Constants: []Constant{{
+			Name:  "ExistentialDeposit",
+			Type:  TypeRef{ID: 3},
+			Value: make([]byte, 16),
+		}},
+	}
+	assert.Equal(t, []Pallet{expectedPallet}, metadata.Pallets)
+
+	pallet, ok := metadata.PalletByIndex(5)
+	require.True(t, ok)
+	assert.Equal(t, "Balances", pallet.Name)
+
+	accountID, err := metadata.Types.Type(2)
+	require.NoError(t, err)
+	assert.Equal(t, "sp_core::crypto::AccountId32", accountID.PathString())
+	assert.Equal(t, TypeDefComposite, accountID.Def.Kind)
+
+	accountData, ok := metadata.Types.TypeByPath("pallet_balances::AccountData")
+	require.True(t, ok)
+	assert.Equal(t, TypeID(4), accountData.ID)
+
+	_, err = metadata.Types.Type(13)
+	assert.ErrorIs(t, err, ErrTypeNotFound)
+}
+
+func Test_Decode_V12(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := DecodeOpaque(readTestMetadata(t, "node_runtime_v12.meta.gz"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(12), metadata.Version)
+	assert.Nil(t, metadata.Types)
+	assert.Len(t, metadata.Pallets, 31)
+	assert.Equal(t, uint8(4), metadata.Extrinsic.Version)
+	assert.Equal(t, SignedExtension{Identifier: "CheckSpecVersion"}, metadata.Extrinsic.SignedExtensions[0])
+
+	system, ok := metadata.Pallet("System")
+	require.True(t, ok)
+	account, ok := system.Storage.Entry("Account")
+	require.True(t, ok)
+	assert.Equal(t, []StorageHasher{HasherBlake2128Concat}, account.Hashers)
+	assert.Equal(t, []TypeRef{{Name: "T::AccountId"}}, account.Keys)
+	assert.Equal(t, TypeRef{Name: "AccountInfo<T::Index, T::AccountData>"}, account.Value)
+
+	balances, ok := metadata.Pallet("Balances")
+	require.True(t, ok)
+	assert.Equal(t, uint8(6), balances.Index)
+	assert.Equal(t, Variant{
+		Name: "transfer",
+		Fields: []Field{
+			{Name: "dest", Type: TypeRef{Name: "<T::Lookup as StaticLookup>::Source"}},
+			{Name: "value", Type: TypeRef{Name: "Compact<T::Balance>"}},
+		},
+		Docs: balances.Calls[0].Docs,
+	}, balances.Calls[0])
+	assert.Equal(t, uint8(1), balances.Calls[1].Index)
+
+	existentialDeposit, ok := balances.Constant("ExistentialDeposit")
+	require.True(t, ok)
+	assert.Equal(t, "T::Balance", existentialDeposit.Type.Name)
+	assert.Len(t, existentialDeposit.Value, 16)
+}
+
+func Test_Decode_V13(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := DecodeOpaque(readTestMetadata(t, "polkadot_9100_v13.meta.gz"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(13), metadata.Version)
+	assert.Len(t, metadata.Pallets, 30)
+
+	staking, ok := metadata.Pallet("Staking")
+	require.True(t, ok)
+	erasStakers, ok := staking.Storage.Entry("ErasStakers")
+	require.True(t, ok)
+	assert.Equal(t, []StorageHasher{HasherTwox64Concat, HasherTwox64Concat}, erasStakers.Hashers)
+	assert.Equal(t, []TypeRef{{Name: "EraIndex"}, {Name: "T::AccountId"}}, erasStakers.Keys)
+}
+
+func Test_Decode_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		encoded []byte
+		errMsg  string
+		errWrap error
+	}{
+		"empty": {
+			errMsg: "cannot decode magic number: EOF",
+		},
+		"bad magic number": {
+			encoded: []byte{1, 2, 3, 4, 14},
+			errMsg:  "metadata magic number mismatch: 0x04030201",
+			errWrap: ErrMagicNumber,
+		},
+		"unsupported version": {
+			encoded: []byte{'m', 'e', 't', 'a', 11},
+			errMsg:  "metadata version not supported: 11",
+			errWrap: ErrVersionNotSupported,
+		},
+		"truncated": {
+			encoded: newTestMetadataV14(t)[:100],
+			errMsg: "cannot decode metadata version 14: cannot decode type registry: " +
+				"element 4: type 4: cannot decode type parameters: cannot decode length: EOF",
+		},
+		"trailing bytes": {
+			encoded: append(newTestMetadataV14(t), 0),
+			errMsg:  "trailing bytes: 1 bytes after metadata version 14",
+			errWrap: ErrTrailingBytes,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Decode(testCase.encoded)
+			assert.EqualError(t, err, testCase.errMsg)
+			if testCase.errWrap != nil {
+				assert.ErrorIs(t, err, testCase.errWrap)
+			}
+		})
+	}
+}
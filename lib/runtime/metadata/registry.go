@@ -0,0 +1,342 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTypeNotFound is returned when a type identifier is not found in the type registry
+var ErrTypeNotFound = errors.New("type not found")
+
+// TypeID is the identifier of a type in the type registry
+type TypeID uint32
+
+// Registry is the portable type registry of the metadata, from version 14
+type Registry struct {
+	types map[TypeID]*Type
+	// ids are the type identifiers in the order of the registry.
+	ids []TypeID
+}
+
+// Len returns the number of types in the registry.
+func (r *Registry) Len() int {
+	return len(r.ids)
+}
+
+// Type returns the type with the given identifier.
+func (r *Registry) Type(id TypeID) (*Type, error) {
+	t, ok := r.types[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrTypeNotFound, id)
+	}
+	return t, nil
+}
+
+// TypeByPath returns the first type with the given path, such as
+// sp_runtime::generic::digest::Digest, and false if it is not found.
+func (r *Registry) TypeByPath(path string) (t *Type, ok bool) {
+	for _, id := range r.ids {
+		t = r.types[id]
+		if t.PathString() == path {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Type is a type of the type registry
+type Type struct {
+	ID TypeID
+	// Path is the path of the type, such as [sp_core crypto AccountId32],
+	// and is empty for primitives, sequences, arrays, tuples and compacts.
+	Path   []string
+	Params []TypeParam
+	Def    TypeDef
+	Docs   []string
+}
+
+// PathString returns the path of the type joined with ::
+func (t *Type) PathString() string {
+	return strings.Join(t.Path, "::")
+}
+
+// TypeParam is a generic type parameter of a type
+type TypeParam struct {
+	Name string
+	// Type is nil if the type parameter is not used by the type.
+	Type *TypeID
+}
+
+// TypeDefKind is the kind of a type definition
+type TypeDefKind uint8
+
+const (
+	// TypeDefComposite is a struct or a tuple struct
+	TypeDefComposite TypeDefKind = iota
+	// TypeDefVariant is an enum
+	TypeDefVariant
+	// TypeDefSequence is a variable length sequence
+	TypeDefSequence
+	// TypeDefArray is a fixed length array
+	TypeDefArray
+	// TypeDefTuple is a tuple
+	TypeDefTuple
+	// TypeDefPrimitive is a primitive type
+	TypeDefPrimitive
+	// TypeDefCompact is a compact encoded integer
+	TypeDefCompact
+	// TypeDefBitSequence is a sequence of bits
+	TypeDefBitSequence
+)
+
+func (k TypeDefKind) String() string {
+	switch k {
+	case TypeDefComposite:
+		return "Composite"
+	case TypeDefVariant:
+		return "Variant"
+	case TypeDefSequence:
+		return "Sequence"
+	case TypeDefArray:
+		return "Array"
+	case TypeDefTuple:
+		return "Tuple"
+	case TypeDefPrimitive:
+		return "Primitive"
+	case TypeDefCompact:
+		return "Compact"
+	case TypeDefBitSequence:
+		return "BitSequence"
+	default:
+		return fmt.Sprintf("TypeDefKind(%d)", uint8(k))
+	}
+}
+
+// TypeDef is the definition of a type. Only the fields of its kind are set.
+type TypeDef struct {
+	Kind TypeDefKind
+	// Fields are the fields of a composite type.
+	Fields []Field
+	// Variants are the variants of a variant type.
+	Variants []Variant
+	// Elem is the element type of a sequence, an array or a compact type.
+	Elem TypeID
+	// Len is the length of an array type.
+	Len uint32
+	// Tuple are the element types of a tuple type.
+	Tuple     []TypeID
+	Primitive Primitive
+	// BitStore and BitOrder are the store and order types of a bit sequence type.
+	BitStore TypeID
+	BitOrder TypeID
+}
+
+// Primitive is a primitive type
+type Primitive uint8
+
+// Primitive types, in the order of their encoding
+const (
+	PrimitiveBool Primitive = iota
+	PrimitiveChar
+	PrimitiveStr
+	PrimitiveU8
+	PrimitiveU16
+	PrimitiveU32
+	PrimitiveU64
+	PrimitiveU128
+	PrimitiveU256
+	PrimitiveI8
+	PrimitiveI16
+	PrimitiveI32
+	PrimitiveI64
+	PrimitiveI128
+	PrimitiveI256
+)
+
+var primitiveNames = [...]string{
+	"bool", "char", "str",
+	"u8", "u16", "u32", "u64", "u128", "u256",
+	"i8", "i16", "i32", "i64", "i128", "i256",
+}
+
+func (p Primitive) String() string {
+	if int(p) < len(primitiveNames) {
+		return primitiveNames[p]
+	}
+	return fmt.Sprintf("Primitive(%d)", uint8(p))
+}
+
+// variants returns the variants of the variant type with the given identifier.
+func (r *Registry) variants(id TypeID) ([]Variant, error) {
+	t, err := r.Type(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Def.Kind != TypeDefVariant {
+		return nil, fmt.Errorf("type %d is a %s and not a variant", id, t.Def.Kind)
+	}
+	return t.Def.Variants, nil
+}
+
+func decodeRegistry(d *decoder) (registry *Registry, err error) {
+	types, err := decodeVec(d, decodeType)
+	if err != nil {
+		return nil, err
+	}
+
+	registry = &Registry{
+		types: make(map[TypeID]*Type, len(types)),
+		ids:   make([]TypeID, len(types)),
+	}
+	for i := range types {
+		t := &types[i]
+		if _, ok := registry.types[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate type id %d", t.ID)
+		}
+		registry.types[t.ID] = t
+		registry.ids[i] = t.ID
+	}
+
+	return registry, nil
+}
+
+func decodeType(d *decoder) (t Type, err error) {
+	t.ID, err = d.readTypeID()
+	if err != nil {
+		return t, fmt.Errorf("cannot decode id: %w", err)
+	}
+
+	t.Path, err = d.readStrings()
+	if err != nil {
+		return t, fmt.Errorf("type %d: cannot decode path: %w", t.ID, err)
+	}
+
+	t.Params, err = decodeVec(d, decodeTypeParam)
+	if err != nil {
+		return t, fmt.Errorf("type %d: cannot decode type parameters: %w", t.ID, err)
+	}
+
+	t.Def, err = decodeTypeDef(d)
+	if err != nil {
+		return t, fmt.Errorf("type %d: cannot decode definition: %w", t.ID, err)
+	}
+
+	t.Docs, err = d.readStrings()
+	if err != nil {
+		return t, fmt.Errorf("type %d: cannot decode docs: %w", t.ID, err)
+	}
+
+	return t, nil
+}
+
+func decodeTypeParam(d *decoder) (param TypeParam, err error) {
+	param.Name, err = d.readString()
+	if err != nil {
+		return param, err
+	}
+
+	some, err := d.readOption()
+	if err != nil || !some {
+		return param, err
+	}
+
+	id, err := d.readTypeID()
+	if err != nil {
+		return param, err
+	}
+	param.Type = &id
+	return param, nil
+}
+
+func decodeTypeDef(d *decoder) (def TypeDef, err error) {
+	kind, err := d.readByte()
+	if err != nil {
+		return def, err
+	}
+
+	def.Kind = TypeDefKind(kind)
+	switch def.Kind {
+	case TypeDefComposite:
+		def.Fields, err = decodeVec(d, decodeField)
+	case TypeDefVariant:
+		def.Variants, err = decodeVec(d, decodeVariant)
+	case TypeDefSequence, TypeDefCompact:
+		def.Elem, err = d.readTypeID()
+	case TypeDefArray:
+		err = d.decode(&def.Len)
+		if err == nil {
+			def.Elem, err = d.readTypeID()
+		}
+	case TypeDefTuple:
+		def.Tuple, err = decodeVec(d, (*decoder).readTypeID)
+	case TypeDefPrimitive:
+		var primitive byte
+		primitive, err = d.readByte()
+		def.Primitive = Primitive(primitive)
+		if err == nil && def.Primitive > PrimitiveI256 {
+			err = fmt.Errorf("invalid primitive: %d", primitive)
+		}
+	case TypeDefBitSequence:
+		def.BitStore, err = d.readTypeID()
+		if err == nil {
+			def.BitOrder, err = d.readTypeID()
+		}
+	default:
+		return def, fmt.Errorf("invalid type definition kind: %d", kind)
+	}
+
+	return def, err
+}
+
+func decodeField(d *decoder) (field Field, err error) {
+	field.Name, err = d.readOptionString()
+	if err != nil {
+		return field, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	field.Type.ID, err = d.readTypeID()
+	if err != nil {
+		return field, fmt.Errorf("cannot decode type: %w", err)
+	}
+
+	field.Type.Name, err = d.readOptionString()
+	if err != nil {
+		return field, fmt.Errorf("cannot decode type name: %w", err)
+	}
+
+	field.Docs, err = d.readStrings()
+	if err != nil {
+		return field, fmt.Errorf("cannot decode docs: %w", err)
+	}
+
+	return field, nil
+}
+
+func decodeVariant(d *decoder) (variant Variant, err error) {
+	variant.Name, err = d.readString()
+	if err != nil {
+		return variant, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	variant.Fields, err = decodeVec(d, decodeField)
+	if err != nil {
+		return variant, fmt.Errorf("variant %s: cannot decode fields: %w", variant.Name, err)
+	}
+
+	variant.Index, err = d.readByte()
+	if err != nil {
+		return variant, fmt.Errorf("variant %s: cannot decode index: %w", variant.Name, err)
+	}
+
+	variant.Docs, err = d.readStrings()
+	if err != nil {
+		return variant, fmt.Errorf("variant %s: cannot decode docs: %w", variant.Name, err)
+	}
+
+	return variant, nil
+}
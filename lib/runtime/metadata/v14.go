@@ -0,0 +1,255 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"fmt"
+)
+
+// storage entry types of the metadata version 14
+const (
+	storagePlain byte = iota
+	storageMap
+)
+
+// decodeV14 decodes the metadata version 14, where types are
+// referenced by their identifier in the portable type registry.
+func decodeV14(d *decoder) (metadata *Metadata, err error) {
+	metadata = &Metadata{Version: 14}
+
+	metadata.Types, err = decodeRegistry(d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode type registry: %w", err)
+	}
+
+	metadata.Pallets, err = decodeVec(d, func(d *decoder) (Pallet, error) {
+		return decodePallet(d, metadata.Types)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode pallets: %w", err)
+	}
+
+	metadata.Extrinsic, err = decodeExtrinsic(d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode extrinsic: %w", err)
+	}
+
+	// the runtime type is not kept
+	_, err = d.readTypeID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode runtime type: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func decodePallet(d *decoder, registry *Registry) (pallet Pallet, err error) {
+	pallet.Name, err = d.readString()
+	if err != nil {
+		return pallet, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	some, err := d.readOption()
+	if err == nil && some {
+		pallet.Storage, err = decodeStorage(d, registry)
+	}
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode storage: %w", pallet.Name, err)
+	}
+
+	pallet.Calls, err = decodePalletVariants(d, registry)
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode calls: %w", pallet.Name, err)
+	}
+
+	pallet.Events, err = decodePalletVariants(d, registry)
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode events: %w", pallet.Name, err)
+	}
+
+	pallet.Constants, err = decodeVec(d, decodeConstant)
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode constants: %w", pallet.Name, err)
+	}
+
+	pallet.Errors, err = decodePalletVariants(d, registry)
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode errors: %w", pallet.Name, err)
+	}
+
+	pallet.Index, err = d.readByte()
+	if err != nil {
+		return pallet, fmt.Errorf("pallet %s: cannot decode index: %w", pallet.Name, err)
+	}
+
+	return pallet, nil
+}
+
+// decodePalletVariants decodes the optional variant type of the calls, events or errors
+// of a pallet, and returns the variants of the type found in the registry.
+func decodePalletVariants(d *decoder, registry *Registry) (variants []Variant, err error) {
+	some, err := d.readOption()
+	if err != nil || !some {
+		return nil, err
+	}
+
+	id, err := d.readTypeID()
+	if err != nil {
+		return nil, err
+	}
+
+	return registry.variants(id)
+}
+
+func decodeStorage(d *decoder, registry *Registry) (storage *Storage, err error) {
+	storage = new(Storage)
+	storage.Prefix, err = d.readString()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode prefix: %w", err)
+	}
+
+	storage.Entries, err = decodeVec(d, func(d *decoder) (StorageEntry, error) {
+		return decodeStorageEntry(d, registry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode entries: %w", err)
+	}
+
+	return storage, nil
+}
+
+func decodeStorageEntry(d *decoder, registry *Registry) (entry StorageEntry, err error) {
+	entry.Name, err = d.readString()
+	if err != nil {
+		return entry, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	entry.Modifier, err = decodeStorageModifier(d)
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: %w", entry.Name, err)
+	}
+
+	err = decodeStorageEntryType(d, registry, &entry)
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: cannot decode entry type: %w", entry.Name, err)
+	}
+
+	entry.Default, err = d.readBytes()
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: cannot decode default value: %w", entry.Name, err)
+	}
+
+	entry.Docs, err = d.readStrings()
+	if err != nil {
+		return entry, fmt.Errorf("entry %s: cannot decode docs: %w", entry.Name, err)
+	}
+
+	return entry, nil
+}
+
+// decodeStorageEntryType decodes the hashers, key types and value type of the storage
+// entry into the given storage entry. The key type of maps with more than one hasher is
+// a tuple, whose element types are set as the key types.
+func decodeStorageEntryType(d *decoder, registry *Registry, entry *StorageEntry) (err error) {
+	entryType, err := d.readByte()
+	if err != nil {
+		return err
+	}
+
+	switch entryType {
+	case storagePlain:
+		entry.Value.ID, err = d.readTypeID()
+		return err
+	case storageMap:
+	default:
+		return fmt.Errorf("invalid storage entry type: %d", entryType)
+	}
+
+	entry.Hashers, err = decodeVec(d, decodeStorageHasher)
+	if err != nil {
+		return fmt.Errorf("cannot decode hashers: %w", err)
+	}
+
+	key, err := d.readTypeID()
+	if err != nil {
+		return fmt.Errorf("cannot decode key type: %w", err)
+	}
+
+	entry.Value.ID, err = d.readTypeID()
+	if err != nil {
+		return fmt.Errorf("cannot decode value type: %w", err)
+	}
+
+	if len(entry.Hashers) == 1 {
+		entry.Keys = []TypeRef{{ID: key}}
+		return nil
+	}
+
+	keyType, err := registry.Type(key)
+	if err != nil {
+		return fmt.Errorf("cannot get key type: %w", err)
+	}
+	if keyType.Def.Kind != TypeDefTuple || len(keyType.Def.Tuple) != len(entry.Hashers) {
+		return fmt.Errorf("key type %d is not a tuple of %d elements", key, len(entry.Hashers))
+	}
+
+	for _, id := range keyType.Def.Tuple {
+		entry.Keys = append(entry.Keys, TypeRef{ID: id})
+	}
+	return nil
+}
+
+func decodeConstant(d *decoder) (constant Constant, err error) {
+	constant.Name, err = d.readString()
+	if err != nil {
+		return constant, fmt.Errorf("cannot decode name: %w", err)
+	}
+
+	constant.Type.ID, err = d.readTypeID()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode type: %w", constant.Name, err)
+	}
+
+	constant.Value, err = d.readBytes()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode value: %w", constant.Name, err)
+	}
+
+	constant.Docs, err = d.readStrings()
+	if err != nil {
+		return constant, fmt.Errorf("constant %s: cannot decode docs: %w", constant.Name, err)
+	}
+
+	return constant, nil
+}
+
+func decodeExtrinsic(d *decoder) (extrinsic Extrinsic, err error) {
+	extrinsic.Type.ID, err = d.readTypeID()
+	if err != nil {
+		return extrinsic, fmt.Errorf("cannot decode type: %w", err)
+	}
+
+	extrinsic.Version, err = d.readByte()
+	if err != nil {
+		return extrinsic, fmt.Errorf("cannot decode version: %w", err)
+	}
+
+	extrinsic.SignedExtensions, err = decodeVec(d, func(d *decoder) (extension SignedExtension, err error) {
+		extension.Identifier, err = d.readString()
+		if err != nil {
+			return extension, err
+		}
+		extension.Type.ID, err = d.readTypeID()
+		if err != nil {
+			return extension, err
+		}
+		extension.AdditionalSigned.ID, err = d.readTypeID()
+		return extension, err
+	})
+	if err != nil {
+		return extrinsic, fmt.Errorf("cannot decode signed extensions: %w", err)
+	}
+
+	return extrinsic, nil
+}
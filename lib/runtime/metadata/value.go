@@ -0,0 +1,268 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+var (
+	// ErrBitSequenceNotSupported is returned when decoding a value of a bit sequence type
+	ErrBitSequenceNotSupported = errors.New("bit sequence decoding not supported")
+	// ErrMaxDepth is returned when decoding a value nested deeper than maxDepth
+	ErrMaxDepth = errors.New("maximum value depth reached")
+)
+
+// maxDepth is the maximum nesting of decoded values, which bounds the recursion
+// of recursive types such as calls wrapping calls.
+const maxDepth = 256
+
+// VariantValue is a decoded value of a variant type
+type VariantValue struct {
+	Name  string
+	Index uint8
+	// Value is the value of the variant fields, decoded as a composite value.
+	Value interface{}
+}
+
+// DecodeValue decodes the SCALE encoded value of the type with the given identifier.
+// Values are decoded as follows:
+//   - bool, str, u8 to u64 and i8 to i64 primitives as the Go type of the same name
+//   - char primitives as rune, and 128 and 256 bit integers as *big.Int
+//   - composite values as map[string]interface{} if their fields are named, and as
+//     []interface{} otherwise
+//   - variant values as VariantValue
+//   - sequences and arrays of u8 as []byte, and as []interface{} otherwise
+//   - tuples as []interface{}, and compact integers as *big.Int
+//
+// Bit sequences are not supported.
+func (r *Registry) DecodeValue(id TypeID, encoded []byte) (value interface{}, err error) {
+	d := newDecoder(bytes.NewReader(encoded))
+	value, err = r.decodeValue(d, id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.remaining() > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after value of type %d", ErrTrailingBytes, d.remaining(), id)
+	}
+	return value, nil
+}
+
+func (r *Registry) decodeValue(d *decoder, id TypeID, depth int) (value interface{}, err error) {
+	if depth == maxDepth {
+		return nil, fmt.Errorf("%w: decoding type %d", ErrMaxDepth, id)
+	}
+
+	t, err := r.Type(id)
+	if err != nil {
+		return nil, err
+	}
+
+	def := t.Def
+	switch def.Kind {
+	case TypeDefComposite:
+		return r.decodeFields(d, def.Fields, depth)
+	case TypeDefVariant:
+		return r.decodeVariantValue(d, id, def.Variants, depth)
+	case TypeDefSequence:
+		length, err := d.readLength()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode sequence length: %w", err)
+		}
+		return r.decodeElements(d, def.Elem, length, depth)
+	case TypeDefArray:
+		return r.decodeElements(d, def.Elem, uint(def.Len), depth)
+	case TypeDefTuple:
+		values := make([]interface{}, len(def.Tuple))
+		for i, elem := range def.Tuple {
+			values[i], err = r.decodeValue(d, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case TypeDefPrimitive:
+		return decodePrimitive(d, def.Primitive)
+	case TypeDefCompact:
+		compact := big.NewInt(0)
+		err = d.decode(&compact)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode compact: %w", err)
+		}
+		return compact, nil
+	case TypeDefBitSequence:
+		return nil, fmt.Errorf("%w: type %d", ErrBitSequenceNotSupported, id)
+	default:
+		return nil, fmt.Errorf("invalid type definition kind: %d", def.Kind)
+	}
+}
+
+// decodeFields decodes the values of the given fields as a map if they are named,
+// and as a slice otherwise.
+func (r *Registry) decodeFields(d *decoder, fields []Field, depth int) (value interface{}, err error) {
+	if len(fields) > 0 && fields[0].Name != "" {
+		values := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			values[field.Name], err = r.decodeValue(d, field.Type.ID, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+		return values, nil
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i], err = r.decodeValue(d, field.Type.ID, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return values, nil
+}
+
+func (r *Registry) decodeVariantValue(d *decoder, id TypeID, variants []Variant,
+	depth int) (value VariantValue, err error) {
+	index, err := d.readByte()
+	if err != nil {
+		return value, fmt.Errorf("cannot decode variant index: %w", err)
+	}
+
+	for _, variant := range variants {
+		if variant.Index != index {
+			continue
+		}
+
+		fields, err := r.decodeFields(d, variant.Fields, depth)
+		if err != nil {
+			return value, fmt.Errorf("variant %s: %w", variant.Name, err)
+		}
+
+		return VariantValue{
+			Name:  variant.Name,
+			Index: index,
+			Value: fields,
+		}, nil
+	}
+
+	return value, fmt.Errorf("variant index %d not found in type %d", index, id)
+}
+
+// decodeElements decodes the given number of elements of the given type, as bytes
+// for u8 elements and as a slice otherwise.
+func (r *Registry) decodeElements(d *decoder, elem TypeID, length uint, depth int) (
+	value interface{}, err error) {
+	elemType, err := r.Type(elem)
+	if err != nil {
+		return nil, err
+	}
+
+	if elemType.Def.Kind == TypeDefPrimitive && elemType.Def.Primitive == PrimitiveU8 {
+		if length > uint(d.remaining()) {
+			return nil, fmt.Errorf("cannot decode %d bytes: %d bytes left", length, d.remaining())
+		}
+		b := make([]byte, length)
+		_, err = d.reader.Read(b)
+		return b, err
+	}
+
+	var values []interface{}
+	for i := uint(0); i < length; i++ {
+		value, err := r.decodeValue(d, elem, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func decodePrimitive(d *decoder, primitive Primitive) (value interface{}, err error) {
+	switch primitive {
+	case PrimitiveBool:
+		var v bool
+		err = d.decode(&v)
+		value = v
+	case PrimitiveChar:
+		var v uint32
+		err = d.decode(&v)
+		if err == nil && !utf8.ValidRune(rune(v)) {
+			err = fmt.Errorf("invalid char: %d", v)
+		}
+		value = rune(v)
+	case PrimitiveStr:
+		value, err = d.readString()
+	case PrimitiveU8:
+		value, err = d.readByte()
+	case PrimitiveU16:
+		var v uint16
+		err = d.decode(&v)
+		value = v
+	case PrimitiveU32:
+		var v uint32
+		err = d.decode(&v)
+		value = v
+	case PrimitiveU64:
+		var v uint64
+		err = d.decode(&v)
+		value = v
+	case PrimitiveI8:
+		var v int8
+		err = d.decode(&v)
+		value = v
+	case PrimitiveI16:
+		var v int16
+		err = d.decode(&v)
+		value = v
+	case PrimitiveI32:
+		var v int32
+		err = d.decode(&v)
+		value = v
+	case PrimitiveI64:
+		var v int64
+		err = d.decode(&v)
+		value = v
+	case PrimitiveU128, PrimitiveI128:
+		value, err = decodeBigInt(d, 16, primitive == PrimitiveI128)
+	case PrimitiveU256, PrimitiveI256:
+		value, err = decodeBigInt(d, 32, primitive == PrimitiveI256)
+	default:
+		return nil, fmt.Errorf("invalid primitive: %d", primitive)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", primitive, err)
+	}
+	return value, nil
+}
+
+// decodeBigInt decodes a little endian integer of the given size,
+// which is in two's complement if it is signed.
+func decodeBigInt(d *decoder, size int, signed bool) (value *big.Int, err error) {
+	if d.remaining() < size {
+		return nil, fmt.Errorf("%d bytes left", d.remaining())
+	}
+
+	b := make([]byte, size)
+	_, err = d.reader.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// reverse to big endian
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	value = new(big.Int).SetBytes(b)
+	if signed && b[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(size*8)))
+	}
+	return value, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metadata
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry_DecodeValue(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := Decode(newTestMetadataV14(t))
+	require.NoError(t, err)
+	registry := metadata.Types
+
+	accountID := make([]byte, 32)
+	accountID[0] = 1
+
+	testCases := map[string]struct {
+		id      TypeID
+		encoded []byte
+		value   interface{}
+		errWrap error
+		errMsg  string
+	}{
+		"u32": {
+			id:      10,
+			encoded: []byte{1, 2, 0, 0},
+			value:   uint32(0x0201),
+		},
+		"str": {
+			id:      12,
+			encoded: encode(t, "abc"),
+			value:   "abc",
+		},
+		"unnamed composite of bytes": {
+			id:      2,
+			encoded: accountID,
+			value:   []interface{}{accountID},
+		},
+		"named composite": {
+			id:      4,
+			encoded: append(append([]byte{1}, make([]byte, 15)...), append([]byte{2}, make([]byte, 15)...)...),
+			value: map[string]interface{}{
+				"free":     big.NewInt(1),
+				"reserved": big.NewInt(2),
+			},
+		},
+		"variant": {
+			id:      5,
+			encoded: append(append([]byte{0}, accountID...), 0x04),
+			value: VariantValue{
+				Name: "transfer",
+				Value: map[string]interface{}{
+					"dest":  []interface{}{accountID},
+					"value": big.NewInt(1),
+				},
+			},
+		},
+		"variant index not found": {
+			id:      5,
+			encoded: []byte{1},
+			errMsg:  "variant index 1 not found in type 5",
+		},
+		"tuple": {
+			id:      9,
+			encoded: append(append([]byte{}, accountID...), 7, 0, 0, 0),
+			value:   []interface{}{[]interface{}{accountID}, uint32(7)},
+		},
+		"sequence of bytes": {
+			id:      11,
+			encoded: []byte{8, 1, 2},
+			value:   []byte{1, 2},
+		},
+		"sequence too long": {
+			id:      11,
+			encoded: []byte{12, 1, 2},
+			errMsg:  "cannot decode 3 bytes: 2 bytes left",
+		},
+		"compact": {
+			id:      6,
+			encoded: []byte{0xfe, 0xff, 0xff, 0xff},
+			value:   big.NewInt(1<<30 - 1),
+		},
+		"trailing bytes": {
+			id:      10,
+			encoded: []byte{1, 2, 0, 0, 0},
+			errWrap: ErrTrailingBytes,
+			errMsg:  "trailing bytes: 1 bytes after value of type 10",
+		},
+		"type not found": {
+			id:      13,
+			errWrap: ErrTypeNotFound,
+			errMsg:  "type not found: 13",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := registry.DecodeValue(testCase.id, testCase.encoded)
+			if testCase.errMsg != "" {
+				assert.EqualError(t, err, testCase.errMsg)
+				if testCase.errWrap != nil {
+					assert.ErrorIs(t, err, testCase.errWrap)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.value, value)
+		})
+	}
+}
+
+func Test_decodeBigInt(t *testing.T) {
+	t.Parallel()
+
+	minusTwo := make([]byte, 16)
+	for i := range minusTwo {
+		minusTwo[i] = 0xff
+	}
+	minusTwo[0] = 0xfe
+
+	testCases := map[string]struct {
+		encoded []byte
+		signed  bool
+		value   *big.Int
+	}{
+		"unsigned": {
+			encoded: minusTwo,
+			value:   new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(2)),
+		},
+		"signed negative": {
+			encoded: minusTwo,
+			signed:  true,
+			value:   big.NewInt(-2),
+		},
+		"signed positive": {
+			encoded: append([]byte{2}, make([]byte, 15)...),
+			signed:  true,
+			value:   big.NewInt(2),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := newDecoder(bytes.NewReader(testCase.encoded))
+			value, err := decodeBigInt(d, 16, testCase.signed)
+			require.NoError(t, err)
+			assert.Equal(t, 0, testCase.value.Cmp(value), "%s != %s", testCase.value, value)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HostFunctionSampleRate is the number of calls to the host functions of a family
+// for each call timed, since host functions are called too often to time every call.
+const HostFunctionSampleRate = 64
+
+var (
+	exportCallsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gossamer_runtime_calls",
+		Name:      "total",
+		Help:      "total number of calls to each exported runtime function",
+	}, []string{"function"})
+	exportCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gossamer_runtime_calls",
+		Name:      "duration_seconds",
+		Help:      "duration of the calls to each exported runtime function",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"function"})
+	exportCallMemoryGrowth = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gossamer_runtime_calls",
+		Name:      "memory_growth_bytes",
+		Help:      "growth of the runtime memory during the calls to each exported runtime function",
+		Buckets:   prometheus.ExponentialBuckets(PageSize, 4, 8),
+	}, []string{"function"})
+
+	hostFunctionCallsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gossamer_runtime_host_functions",
+		Name:      "calls_total",
+		Help:      "total number of calls to the host functions of each family",
+	}, []string{"family"})
+	hostFunctionCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gossamer_runtime_host_functions",
+		Name:      "sampled_duration_seconds",
+		Help:      "duration of a sample of the calls to the host functions of each family",
+		Buckets:   prometheus.ExponentialBuckets(0.000001, 4, 10),
+	}, []string{"family"})
+)
+
+// ObserveExportCall records the duration of a call to the exported runtime
+// function given, and the growth of the runtime memory during the call.
+func ObserveExportCall(function string, duration time.Duration, memoryGrowth uint32) {
+	exportCallsCounter.WithLabelValues(function).Inc()
+	exportCallDuration.WithLabelValues(function).Observe(duration.Seconds())
+	exportCallMemoryGrowth.WithLabelValues(function).Observe(float64(memoryGrowth))
+}
+
+// HostFunctionFamily is a family of host functions measured together
+type HostFunctionFamily uint8
+
+// Host function families measured
+const (
+	HostFunctionStorage HostFunctionFamily = iota
+	HostFunctionCrypto
+	HostFunctionHashing
+	HostFunctionAllocator
+	HostFunctionOffchain
+	numHostFunctionFamilies
+)
+
+var hostFunctionFamilyPrefixes = [numHostFunctionFamilies][]string{
+	HostFunctionStorage:   {"ext_storage_", "ext_default_child_storage_"},
+	HostFunctionCrypto:    {"ext_crypto_"},
+	HostFunctionHashing:   {"ext_hashing_"},
+	HostFunctionAllocator: {"ext_allocator_"},
+	HostFunctionOffchain:  {"ext_offchain_"},
+}
+
+func (f HostFunctionFamily) String() string {
+	switch f {
+	case HostFunctionStorage:
+		return "storage"
+	case HostFunctionCrypto:
+		return "crypto"
+	case HostFunctionHashing:
+		return "hashing"
+	case HostFunctionAllocator:
+		return "allocator"
+	case HostFunctionOffchain:
+		return "offchain"
+	default:
+		return "unknown"
+	}
+}
+
+// HostFunctionFamilyOf returns the family of the host function with the given
+// name, and false if the host function does not belong to a measured family.
+func HostFunctionFamilyOf(name string) (family HostFunctionFamily, ok bool) {
+	for family, prefixes := range hostFunctionFamilyPrefixes {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return HostFunctionFamily(family), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// hostFunctionMetrics are the metrics of a host function family, resolved
+// once so measuring host function calls does not look up the metrics.
+type hostFunctionMetrics struct {
+	calls    prometheus.Counter
+	duration prometheus.Observer
+	// count is the number of calls, used to sample the calls timed.
+	count uint32
+}
+
+var hostFunctions [numHostFunctionFamilies]*hostFunctionMetrics
+
+func init() {
+	for family := range hostFunctions {
+		label := HostFunctionFamily(family).String()
+		hostFunctions[family] = &hostFunctionMetrics{
+			calls:    hostFunctionCallsCounter.WithLabelValues(label),
+			duration: hostFunctionCallDuration.WithLabelValues(label),
+		}
+	}
+}
+
+func noop() {}
+
+// StartHostFunction counts a call to a host function of the given family, and returns the
+// function to call once the host function returns, which records the duration of the call
+// if the call is sampled. It is meant to be deferred at the start of the host function:
+//
+//	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
+func StartHostFunction(family HostFunctionFamily) (done func()) {
+	metrics := hostFunctions[family]
+	metrics.calls.Inc()
+	if atomic.AddUint32(&metrics.count, 1)%HostFunctionSampleRate != 0 {
+		return noop
+	}
+
+	start := time.Now()
+	return func() {
+		metrics.duration.Observe(time.Since(start).Seconds())
+	}
+}
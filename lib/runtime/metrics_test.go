@@ -0,0 +1,84 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HostFunctionFamilyOf(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		family HostFunctionFamily
+		ok     bool
+	}{
+		"ext_storage_get_version_1":                  {family: HostFunctionStorage, ok: true},
+		"ext_default_child_storage_get_version_1":    {family: HostFunctionStorage, ok: true},
+		"ext_crypto_sr25519_verify_version_2":        {family: HostFunctionCrypto, ok: true},
+		"ext_hashing_blake2_256_version_1":           {family: HostFunctionHashing, ok: true},
+		"ext_allocator_malloc_version_1":             {family: HostFunctionAllocator, ok: true},
+		"ext_offchain_local_storage_get_version_1":   {family: HostFunctionOffchain, ok: true},
+		"ext_misc_print_utf8_version_1":              {},
+		"ext_trie_blake2_256_ordered_root_version_1": {},
+	}
+
+	for name, testCase := range testCases {
+		name, testCase := name, testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			family, ok := HostFunctionFamilyOf(name)
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.family, family)
+		})
+	}
+}
+
+func Test_HostFunctionFamily_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "storage", HostFunctionStorage.String())
+	assert.Equal(t, "offchain", HostFunctionOffchain.String())
+	assert.Equal(t, "unknown", numHostFunctionFamilies.String())
+}
+
+func Test_StartHostFunction(t *testing.T) {
+	// not parallel since it counts the calls made to the global metrics
+
+	metrics := hostFunctions[HostFunctionHashing]
+	sampleCount := func() uint64 {
+		var metric dto.Metric
+		err := metrics.duration.(prometheus.Histogram).Write(&metric)
+		require.NoError(t, err)
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	calls := testutil.ToFloat64(metrics.calls)
+	samples := sampleCount()
+
+	for i := 0; i < 2*HostFunctionSampleRate; i++ {
+		StartHostFunction(HostFunctionHashing)()
+	}
+
+	assert.Equal(t, calls+2*HostFunctionSampleRate, testutil.ToFloat64(metrics.calls))
+	assert.Equal(t, samples+2, sampleCount())
+}
+
+func Test_ObserveExportCall(t *testing.T) {
+	t.Parallel()
+
+	const function = "Test_observe_export_call"
+	ObserveExportCall(function, time.Millisecond, 2*PageSize)
+	ObserveExportCall(function, time.Millisecond, 0)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(exportCallsCounter.WithLabelValues(function)))
+}
@@ -66,9 +66,27 @@ func (_m *Instance) BabeConfiguration() (*types.BabeConfiguration, error) {
 	return r0, r1
 }
 
-// CheckInherents provides a mock function with given fields:
-func (_m *Instance) CheckInherents() {
-	_m.Called()
+// CheckInherents provides a mock function with given fields: block, inherentData
+func (_m *Instance) CheckInherents(block *types.Block, inherentData []byte) (*runtime.CheckInherentsResult, error) {
+	ret := _m.Called(block, inherentData)
+
+	var r0 *runtime.CheckInherentsResult
+	if rf, ok := ret.Get(0).(func(*types.Block, []byte) *runtime.CheckInherentsResult); ok {
+		r0 = rf(block, inherentData)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*runtime.CheckInherentsResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.Block, []byte) error); ok {
+		r1 = rf(block, inherentData)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // CheckRuntimeVersion provides a mock function with given fields: _a0
@@ -207,6 +225,20 @@ func (_m *Instance) GetCodeHash() common.Hash {
 	return r0
 }
 
+// GetHeapPages provides a mock function with given fields:
+func (_m *Instance) GetHeapPages() uint64 {
+	ret := _m.Called()
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
 // GrandpaAuthorities provides a mock function with given fields:
 func (_m *Instance) GrandpaAuthorities() ([]types.Authority, error) {
 	ret := _m.Called()
@@ -336,9 +368,18 @@ func (_m *Instance) NodeStorage() runtime.NodeStorage {
 	return r0
 }
 
-// OffchainWorker provides a mock function with given fields:
-func (_m *Instance) OffchainWorker() {
-	_m.Called()
+// OffchainWorker provides a mock function with given fields: header
+func (_m *Instance) OffchainWorker(header *types.Header) error {
+	ret := _m.Called(header)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.Header) error); ok {
+		r0 = rf(header)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // PaymentQueryInfo provides a mock function with given fields: ext
@@ -393,13 +434,13 @@ func (_m *Instance) UpdateRuntimeCode(_a0 []byte) error {
 	return r0
 }
 
-// ValidateTransaction provides a mock function with given fields: e
-func (_m *Instance) ValidateTransaction(e types.Extrinsic) (*transaction.Validity, error) {
-	ret := _m.Called(e)
+// ValidateTransaction provides a mock function with given fields: source, tx, blockHash
+func (_m *Instance) ValidateTransaction(source types.TransactionSource, tx types.Extrinsic, blockHash common.Hash) (*transaction.Validity, error) {
+	ret := _m.Called(source, tx, blockHash)
 
 	var r0 *transaction.Validity
-	if rf, ok := ret.Get(0).(func(types.Extrinsic) *transaction.Validity); ok {
-		r0 = rf(e)
+	if rf, ok := ret.Get(0).(func(types.TransactionSource, types.Extrinsic, common.Hash) *transaction.Validity); ok {
+		r0 = rf(source, tx, blockHash)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*transaction.Validity)
@@ -407,8 +448,8 @@ func (_m *Instance) ValidateTransaction(e types.Extrinsic) (*transaction.Validit
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(types.Extrinsic) error); ok {
-		r1 = rf(e)
+	if rf, ok := ret.Get(1).(func(types.TransactionSource, types.Extrinsic, common.Hash) error); ok {
+		r1 = rf(source, tx, blockHash)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -4,19 +4,18 @@
 package offchain
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
-)
-
-type contextKey string
+	"time"
 
-const (
-	waitingKey contextKey = "waiting"
-	invalidKey contextKey = "invalid"
+	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
 const maxConcurrentRequests = 1000
@@ -60,16 +59,37 @@ func (b requestIDBuffer) put(i int16) error {
 	}
 }
 
-// Request holds the request object and update the invalid and waiting status whenever
-// the request starts or is waiting to be read
+// readResult is the outcome of a read of the response body
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// Request holds the request object along with the state of its body, its response
+// and the reading of the response body
 type Request struct {
 	Request *http.Request
+
+	body       bytes.Buffer
+	invalid    bool // set once the body is being written, headers can no longer be added
+	dispatched bool // set once the request is sent, the body can no longer be written
+	cancel     context.CancelFunc
+
+	done     chan struct{} // closed once the response or an error is received
+	response *http.Response
+	err      error
+
+	// lock guards the fields below, since the response body can be read
+	// without holding the lock of the request set while waiting for data.
+	lock        *sync.Mutex
+	pendingRead chan readResult // set while a read of the response body is in flight
+	unread      []byte          // data read from the response body not returned yet
+	eof         bool
 }
 
 // AddHeader adds a new HTTP header into request property, only if request is valid
 func (r *Request) AddHeader(name, value string) error {
-	invalid, ok := r.Request.Context().Value(invalidKey).(bool)
-	if ok && invalid {
+	if r.invalid {
 		return errRequestInvalid
 	}
 
@@ -82,11 +102,89 @@ func (r *Request) AddHeader(name, value string) error {
 	return nil
 }
 
+// dispatch sends the request with the body written so far, unless it was already sent
+func (r *Request) dispatch(client *http.Client) {
+	if r.dispatched {
+		return
+	}
+
+	r.invalid = true
+	r.dispatched = true
+
+	if r.body.Len() > 0 {
+		body := r.body.Bytes()
+		r.Request.ContentLength = int64(len(body))
+		r.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	go func() {
+		r.response, r.err = client.Do(r.Request) //nolint:bodyclose
+		close(r.done)
+	}()
+}
+
+// received returns whether the response or an error was received
+func (r *Request) received() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// readBody reads the response body into buf, waiting for the data until the timeout fires.
+// It returns true once the whole body was read. The response must have been received without error.
+func (r *Request) readBody(buf []byte, timeout <-chan time.Time) (n int, finished bool, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.unread) == 0 && !r.eof {
+		if r.pendingRead == nil {
+			// the read happens in the background so it can outlive the deadline,
+			// its result is then returned by the next call
+			pendingRead := make(chan readResult, 1)
+			data := make([]byte, len(buf))
+			go func() {
+				n, err := r.response.Body.Read(data)
+				pendingRead <- readResult{data: data[:n], err: err}
+			}()
+			r.pendingRead = pendingRead
+		}
+
+		select {
+		case res := <-r.pendingRead:
+			r.pendingRead = nil
+			r.unread = res.data
+			if errors.Is(res.err, io.EOF) {
+				r.eof = true
+			} else if res.err != nil {
+				return 0, false, fmt.Errorf("%w: %s", HTTPErrorIO, res.err)
+			}
+		case <-timeout:
+			return 0, false, HTTPErrorDeadlineReached
+		}
+	}
+
+	n = copy(buf, r.unread)
+	r.unread = r.unread[n:]
+	return n, n == 0 && r.eof, nil
+}
+
+// close aborts the request and releases its response
+func (r *Request) close() {
+	r.cancel()
+	if r.received() && r.response != nil {
+		_ = r.response.Body.Close()
+	}
+}
+
 // HTTPSet holds a pool of concurrent http request calls
 type HTTPSet struct {
 	*sync.Mutex
 	reqs   map[int16]*Request
 	idBuff requestIDBuffer
+	client *http.Client
 }
 
 // NewHTTPSet creates a offchain http set that can be used
@@ -96,6 +194,7 @@ func NewHTTPSet() *HTTPSet {
 		new(sync.Mutex),
 		make(map[int16]*Request),
 		newIntBuffer(maxConcurrentRequests),
+		&http.Client{},
 	}
 }
 
@@ -114,32 +213,45 @@ func (p *HTTPSet) StartRequest(method, uri string) (int16, error) {
 		return 0, errRequestIDNotAvailable
 	}
 
-	req, err := http.NewRequest(method, uri, nil)
-	req.Header = make(http.Header)
-
-	ctx := context.WithValue(req.Context(), waitingKey, false)
-	ctx = context.WithValue(ctx, invalidKey, false)
-
-	req = req.WithContext(ctx)
-
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
 	if err != nil {
+		cancel()
+		_ = p.idBuff.put(id)
 		return 0, err
 	}
 
+	req.Header = make(http.Header)
+
 	p.reqs[id] = &Request{
 		Request: req,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		lock:    new(sync.Mutex),
 	}
 
 	return id, nil
 }
 
-// Remove just remove a expecific request from reqs
+// Remove aborts the request with the given id and frees the id
 func (p *HTTPSet) Remove(id int16) error {
 	p.Lock()
 	defer p.Unlock()
 
-	delete(p.reqs, id)
+	return p.remove(id)
+}
+
+func (p *HTTPSet) remove(id int16) error {
+	req, ok := p.reqs[id]
+	if !ok {
+		return errRequestInvalid
+	}
 
+	if req.cancel != nil {
+		req.close()
+	}
+
+	delete(p.reqs, id)
 	return p.idBuff.put(id)
 }
 
@@ -150,3 +262,165 @@ func (p *HTTPSet) Get(id int16) *Request {
 
 	return p.reqs[id]
 }
+
+// WriteBody writes a chunk of the body of the request with the given id. Writing an empty
+// chunk finishes the body and sends the request. Headers can no longer be added once
+// the body is being written, and the body can no longer be written once the request was sent.
+func (p *HTTPSet) WriteBody(id int16, chunk []byte, deadline *time.Time) error {
+	p.Lock()
+	defer p.Unlock()
+
+	req, ok := p.reqs[id]
+	if !ok || req.dispatched {
+		return HTTPErrorInvalid
+	}
+
+	if deadline != nil && !time.Now().Before(*deadline) {
+		return HTTPErrorDeadlineReached
+	}
+
+	if len(chunk) == 0 {
+		req.dispatch(p.client)
+		return nil
+	}
+
+	req.invalid = true
+	req.body.Write(chunk)
+	return nil
+}
+
+// Wait sends the requests with the given ids if they were not sent yet, and waits for their
+// responses until the deadline is reached, or indefinitely if the deadline is nil.
+// It returns the status of each request, requests which failed are removed.
+func (p *HTTPSet) Wait(ids []int16, deadline *time.Time) []scale.VaryingDataTypeValue {
+	p.Lock()
+	reqs := make([]*Request, len(ids))
+	for i, id := range ids {
+		req, ok := p.reqs[id]
+		if !ok {
+			continue
+		}
+
+		req.dispatch(p.client)
+		reqs[i] = req
+	}
+	p.Unlock()
+
+	timeout, stop := deadlineTimer(deadline)
+	defer stop()
+
+	expired := false
+	statuses := make([]scale.VaryingDataTypeValue, len(ids))
+	for i, req := range reqs {
+		if req == nil {
+			statuses[i] = StatusInvalid{}
+			continue
+		}
+
+		if !expired {
+			select {
+			case <-req.done:
+			case <-timeout:
+				expired = true
+			}
+		}
+
+		switch {
+		case !req.received():
+			statuses[i] = StatusDeadlineReached{}
+		case req.err != nil:
+			statuses[i] = StatusIOError{}
+			_ = p.Remove(ids[i])
+		default:
+			statuses[i] = StatusFinished(req.response.StatusCode)
+		}
+	}
+
+	return statuses
+}
+
+// ResponseHeaders returns the headers of the response to the request with the given id,
+// sorted by name. Nothing is returned if the request is invalid or the response was not received yet.
+func (p *HTTPSet) ResponseHeaders(id int16) []HTTPHeader {
+	p.Lock()
+	defer p.Unlock()
+
+	req, ok := p.reqs[id]
+	if !ok || !req.received() || req.err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(req.response.Header))
+	for name := range req.response.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers []HTTPHeader
+	for _, name := range names {
+		for _, value := range req.response.Header[name] {
+			headers = append(headers, HTTPHeader{
+				Name:  []byte(name),
+				Value: []byte(value),
+			})
+		}
+	}
+
+	return headers
+}
+
+// ReadBody reads the response body of the request with the given id into buf, waiting for the response
+// and the data until the deadline is reached, or indefinitely if the deadline is nil.
+// It returns the number of bytes read, 0 meaning the whole body was read. The request is removed
+// once its body was read or if an error other than the deadline being reached occurs.
+func (p *HTTPSet) ReadBody(id int16, buf []byte, deadline *time.Time) (int, error) {
+	p.Lock()
+	req, ok := p.reqs[id]
+	if ok {
+		req.dispatch(p.client)
+	}
+	p.Unlock()
+
+	if !ok {
+		return 0, HTTPErrorInvalid
+	}
+
+	timeout, stop := deadlineTimer(deadline)
+	defer stop()
+
+	select {
+	case <-req.done:
+	case <-timeout:
+		return 0, HTTPErrorDeadlineReached
+	}
+
+	if req.err != nil {
+		_ = p.Remove(id)
+		return 0, fmt.Errorf("%w: %s", HTTPErrorIO, req.err)
+	}
+
+	n, finished, err := req.readBody(buf, timeout)
+	if errors.Is(err, HTTPErrorDeadlineReached) {
+		return 0, err
+	} else if err != nil {
+		_ = p.Remove(id)
+		return 0, err
+	}
+
+	if finished {
+		_ = p.Remove(id)
+	}
+
+	return n, nil
+}
+
+// deadlineTimer returns a channel firing once the deadline is reached, and a function releasing
+// the timer. The channel never fires if the deadline is nil.
+func deadlineTimer(deadline *time.Time) (<-chan time.Time, func()) {
+	if deadline == nil {
+		return nil, func() {}
+	}
+
+	timer := time.NewTimer(time.Until(*deadline))
+	return timer.C, func() { timer.Stop() }
+}
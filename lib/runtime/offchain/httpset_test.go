@@ -4,10 +4,14 @@
 package offchain
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
 
 	"github.com/stretchr/testify/require"
 )
@@ -54,17 +58,13 @@ func TestHTTPSetGet(t *testing.T) {
 func TestOffchainRequest_AddHeader(t *testing.T) {
 	t.Parallel()
 
-	invalidCtx := context.WithValue(context.Background(), invalidKey, true)
-	invalidReq, err := http.NewRequestWithContext(invalidCtx, http.MethodGet, "http://test.com", nil)
-	require.NoError(t, err)
-
 	cases := map[string]struct {
 		offReq           Request
 		err              error
 		headerK, headerV string
 	}{
 		"should return invalid request": {
-			offReq: Request{invalidReq},
+			offReq: Request{Request: &http.Request{Header: make(http.Header)}, invalid: true},
 			err:    errRequestInvalid,
 		},
 		"should add header": {
@@ -101,3 +101,182 @@ func TestOffchainRequest_AddHeader(t *testing.T) {
 		})
 	}
 }
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPSet_RequestWithBody(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		w.Header().Set("X-Request-Header", r.Header.Get("X-Test"))
+		w.Header().Set("X-Request-Body", string(body))
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte("response body"))
+		require.NoError(t, err)
+	})
+
+	set := NewHTTPSet()
+	id, err := set.StartRequest(http.MethodPost, server.URL)
+	require.NoError(t, err)
+
+	err = set.Get(id).AddHeader("X-Test", "header")
+	require.NoError(t, err)
+
+	err = set.WriteBody(id, []byte("hello "), nil)
+	require.NoError(t, err)
+
+	// headers can no longer be added once the body is being written
+	err = set.Get(id).AddHeader("X-Late", "header")
+	require.ErrorIs(t, err, errRequestInvalid)
+
+	err = set.WriteBody(id, []byte("world"), nil)
+	require.NoError(t, err)
+
+	// an empty chunk finishes the body and sends the request
+	err = set.WriteBody(id, nil, nil)
+	require.NoError(t, err)
+
+	err = set.WriteBody(id, []byte("too late"), nil)
+	require.ErrorIs(t, err, HTTPErrorInvalid)
+
+	statuses := set.Wait([]int16{id}, nil)
+	require.Equal(t, []scale.VaryingDataTypeValue{StatusFinished(http.StatusCreated)}, statuses)
+
+	headers := set.ResponseHeaders(id)
+	require.Contains(t, headers, HTTPHeader{Name: []byte("X-Request-Header"), Value: []byte("header")})
+	require.Contains(t, headers, HTTPHeader{Name: []byte("X-Request-Body"), Value: []byte("hello world")})
+
+	var body []byte
+	buf := make([]byte, 4)
+	for {
+		n, err := set.ReadBody(id, buf, nil)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+		body = append(body, buf[:n]...)
+	}
+	require.Equal(t, []byte("response body"), body)
+
+	// the request is removed once its body was read
+	require.Nil(t, set.Get(id))
+	_, err = set.ReadBody(id, buf, nil)
+	require.ErrorIs(t, err, HTTPErrorInvalid)
+}
+
+func TestHTTPSet_Wait(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer close(release)
+
+	set := NewHTTPSet()
+	fastID, err := set.StartRequest(http.MethodGet, server.URL+"/fast")
+	require.NoError(t, err)
+	slowID, err := set.StartRequest(http.MethodGet, server.URL+"/slow")
+	require.NoError(t, err)
+	failingID, err := set.StartRequest(http.MethodGet, "http://127.0.0.1:0")
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	statuses := set.Wait([]int16{fastID, slowID, failingID, 999}, &deadline)
+
+	expected := []scale.VaryingDataTypeValue{
+		StatusFinished(http.StatusOK),
+		StatusDeadlineReached{},
+		StatusIOError{},
+		StatusInvalid{},
+	}
+	require.Equal(t, expected, statuses)
+
+	// failed requests are removed, requests past the deadline are kept
+	require.Nil(t, set.Get(failingID))
+	require.NotNil(t, set.Get(slowID))
+	require.Empty(t, set.ResponseHeaders(slowID))
+
+	_, err = set.ReadBody(slowID, make([]byte, 1), &deadline)
+	require.ErrorIs(t, err, HTTPErrorDeadlineReached)
+
+	err = set.WriteBody(slowID, []byte("body"), nil)
+	require.ErrorIs(t, err, HTTPErrorInvalid)
+}
+
+func TestHTTPSet_WriteBody_DeadlineReached(t *testing.T) {
+	t.Parallel()
+
+	set := NewHTTPSet()
+	id, err := set.StartRequest(http.MethodPost, defaultTestURI)
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(-time.Second)
+	err = set.WriteBody(id, []byte("body"), &deadline)
+	require.ErrorIs(t, err, HTTPErrorDeadlineReached)
+
+	err = set.WriteBody(999, []byte("body"), nil)
+	require.ErrorIs(t, err, HTTPErrorInvalid)
+}
+
+func TestHTTPSet_Remove(t *testing.T) {
+	t.Parallel()
+
+	set := NewHTTPSet()
+	id, err := set.StartRequest(http.MethodGet, defaultTestURI)
+	require.NoError(t, err)
+
+	err = set.Remove(id)
+	require.NoError(t, err)
+
+	err = set.Remove(id)
+	require.ErrorIs(t, err, errRequestInvalid)
+}
+
+func TestHTTPSet_ReadBody_concurrent(t *testing.T) {
+	t.Parallel()
+
+	responseBody := []byte("a response body read by concurrent calls")
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(responseBody)
+	})
+
+	set := NewHTTPSet()
+	id, err := set.StartRequest(http.MethodGet, server.URL)
+	require.NoError(t, err)
+
+	const readers = 4
+	bytesRead := make(chan int, readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			total := 0
+			buf := make([]byte, 3)
+			for {
+				n, err := set.ReadBody(id, buf, nil)
+				if err != nil || n == 0 {
+					// the request is removed once a reader read the whole body
+					bytesRead <- total
+					return
+				}
+				total += n
+			}
+		}()
+	}
+
+	total := 0
+	for i := 0; i < readers; i++ {
+		total += <-bytesRead
+	}
+	require.Equal(t, len(responseBody), total)
+}
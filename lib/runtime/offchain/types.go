@@ -0,0 +1,69 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package offchain
+
+import (
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// HTTPError is an error of the offchain HTTP host functions, its value is
+// the SCALE encoded sp_core::offchain::HttpError returned to the runtime.
+type HTTPError byte
+
+const (
+	// HTTPErrorDeadlineReached is returned when the action could not be completed before the deadline
+	HTTPErrorDeadlineReached HTTPError = 1
+	// HTTPErrorIO is returned when an IO error occurred while processing the request
+	HTTPErrorIO HTTPError = 2
+	// HTTPErrorInvalid is returned when the request id is invalid in this context
+	HTTPErrorInvalid HTTPError = 3
+)
+
+func (e HTTPError) Error() string {
+	switch e {
+	case HTTPErrorDeadlineReached:
+		return "deadline reached"
+	case HTTPErrorIO:
+		return "io error"
+	case HTTPErrorInvalid:
+		return "invalid request id"
+	default:
+		return "unknown http error"
+	}
+}
+
+// StatusDeadlineReached is the status of a request whose response was not received before the deadline
+type StatusDeadlineReached struct{}
+
+// Index returns VDT index
+func (StatusDeadlineReached) Index() uint { return 0 }
+
+// StatusIOError is the status of a request which failed, the request is then invalid
+type StatusIOError struct{}
+
+// Index returns VDT index
+func (StatusIOError) Index() uint { return 1 }
+
+// StatusInvalid is the status of an invalid request id
+type StatusInvalid struct{}
+
+// Index returns VDT index
+func (StatusInvalid) Index() uint { return 2 }
+
+// StatusFinished is the status of a request whose response was received, holding the response status code
+type StatusFinished uint16
+
+// Index returns VDT index
+func (StatusFinished) Index() uint { return 3 }
+
+// NewHTTPRequestStatus returns a new VaryingDataType to represent a sp_core::offchain::HttpRequestStatus
+func NewHTTPRequestStatus() scale.VaryingDataType {
+	return scale.MustNewVaryingDataType(StatusDeadlineReached{}, StatusIOError{}, StatusInvalid{}, StatusFinished(0))
+}
+
+// HTTPHeader is a header of an HTTP response
+type HTTPHeader struct {
+	Name  []byte
+	Value []byte
+}
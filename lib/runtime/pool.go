@@ -0,0 +1,480 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/dot/types"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/keystore"
+	"github.com/ChainSafe/gossamer/lib/transaction"
+)
+
+// DefaultPoolSize is the default maximum number of runtime instances in a pool.
+const DefaultPoolSize = 4
+
+var (
+	// ErrPooledInstanceUpdate is returned when trying to update the code of a pooled instance.
+	ErrPooledInstanceUpdate = errors.New("cannot update the code of a pooled runtime instance")
+
+	// ErrPooledInstanceNoStorage is returned when making a runtime call
+	// with a pooled instance handle on which no storage was set.
+	ErrPooledInstanceNoStorage = errors.New("no storage set on pooled runtime instance")
+
+	// ErrPoolStopped is returned when making a runtime call
+	// with a handle to a pool of runtime instances which is stopped.
+	ErrPoolStopped = errors.New("runtime instance pool is stopped")
+)
+
+var _ Instance = (*PooledInstance)(nil)
+
+// Cloner is implemented by runtime instances which can create
+// new instances running the same code with the same configuration.
+type Cloner interface {
+	Clone() (Instance, error)
+}
+
+// Pool is a pool of runtime instances running the same code, so runtime
+// calls can be run concurrently on different instances. Instances are
+// created on demand, cloning the first instance of the pool, up to the
+// size of the pool. If the first instance cannot be cloned, the pool only
+// holds this instance and runtime calls are run one after the other.
+type Pool struct {
+	template  Instance
+	size      int
+	instances chan Instance
+
+	mutex    sync.Mutex
+	members  map[Instance]struct{}
+	creating int // number of instances being cloned
+	// stopped is closed when the pool is stopped.
+	stopped chan struct{}
+}
+
+// NewPool creates a pool of at most size runtime instances running the
+// same code as the given instance, which is the first instance of the pool.
+// DefaultPoolSize is used if size is 0.
+func NewPool(instance Instance, size uint) *Pool {
+	if size == 0 {
+		size = DefaultPoolSize
+	}
+
+	if _, ok := instance.(Cloner); !ok {
+		size = 1
+	}
+
+	pool := &Pool{
+		template:  instance,
+		size:      int(size),
+		instances: make(chan Instance, size),
+		members:   map[Instance]struct{}{instance: {}},
+		stopped:   make(chan struct{}),
+	}
+	pool.instances <- instance
+
+	return pool
+}
+
+// Add adds the given instance, which must run the same code as the instances
+// of the pool, to the pool. The instance is stopped if the pool is full.
+func (p *Pool) Add(instance Instance) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.members[instance]; ok {
+		return
+	}
+
+	if p.isStopped() || len(p.members)+p.creating >= p.size {
+		instance.Stop()
+		return
+	}
+
+	p.members[instance] = struct{}{}
+	p.instances <- instance
+}
+
+// Instance returns a new handle to the pool implementing the runtime Instance
+// interface. Each runtime call on the handle checks out an instance of the pool,
+// sets its storage to the storage set on the handle and returns it to the pool.
+// The storage must be set on the handle before making runtime calls with it.
+func (p *Pool) Instance() *PooledInstance {
+	return &PooledInstance{
+		pool: p,
+	}
+}
+
+// checkout returns an idle instance of the pool, creating it if the pool is not full,
+// or waits for an instance to be returned to the pool. It returns ErrPoolStopped
+// if the pool is stopped.
+func (p *Pool) checkout() (instance Instance, err error) {
+	select {
+	case <-p.stopped:
+		return nil, ErrPoolStopped
+	case instance = <-p.instances:
+		return instance, nil
+	default:
+	}
+
+	p.mutex.Lock()
+	if len(p.members)+p.creating >= p.size {
+		p.mutex.Unlock()
+		select {
+		case <-p.stopped:
+			return nil, ErrPoolStopped
+		case instance = <-p.instances:
+			return instance, nil
+		}
+	}
+	p.creating++
+	p.mutex.Unlock()
+
+	instance, err = p.template.(Cloner).Clone()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.creating--
+	if err != nil {
+		return nil, fmt.Errorf("cannot create runtime instance: %w", err)
+	}
+
+	if p.isStopped() {
+		instance.Stop()
+		return nil, ErrPoolStopped
+	}
+	p.members[instance] = struct{}{}
+
+	return instance, nil
+}
+
+// put returns an instance checked out to the pool,
+// or stops it if the pool is stopped.
+func (p *Pool) put(instance Instance) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isStopped() {
+		delete(p.members, instance)
+		instance.Stop()
+		return
+	}
+
+	p.instances <- instance
+}
+
+// Stop stops the idle instances of the pool, and the instances in use once
+// they are returned to the pool. Runtime calls made with the handles to the
+// pool return ErrPoolStopped from then on.
+func (p *Pool) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isStopped() {
+		return
+	}
+	close(p.stopped)
+
+	for {
+		select {
+		case instance := <-p.instances:
+			delete(p.members, instance)
+			instance.Stop()
+		default:
+			return
+		}
+	}
+}
+
+// isStopped returns true if the pool is stopped.
+func (p *Pool) isStopped() bool {
+	select {
+	case <-p.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// PooledInstance is a handle to a pool of runtime instances.
+type PooledInstance struct {
+	pool *Pool
+
+	mutex   sync.RWMutex
+	storage Storage
+}
+
+// Pool returns the pool of the handle.
+func (pi *PooledInstance) Pool() *Pool {
+	return pi.pool
+}
+
+// checkout checks out an instance from the pool and sets its storage to the
+// storage set on the handle. It returns ErrPooledInstanceNoStorage if no storage
+// was set on the handle, so an instance never runs on the storage of another handle.
+func (pi *PooledInstance) checkout() (instance Instance, err error) {
+	pi.mutex.RLock()
+	storage := pi.storage
+	pi.mutex.RUnlock()
+
+	if storage == nil {
+		return nil, ErrPooledInstanceNoStorage
+	}
+
+	instance, err = pi.pool.checkout()
+	if err != nil {
+		return nil, err
+	}
+
+	instance.SetContextStorage(storage)
+	return instance, nil
+}
+
+// SetContextStorage sets the storage used by the runtime calls made with this handle.
+func (pi *PooledInstance) SetContextStorage(s Storage) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	pi.storage = s
+}
+
+// UpdateRuntimeCode returns an error since the instances
+// of a pool must all run the same code.
+func (*PooledInstance) UpdateRuntimeCode([]byte) error {
+	return ErrPooledInstanceUpdate
+}
+
+// CheckRuntimeVersion calculates runtime Version for runtime blob passed in
+func (pi *PooledInstance) CheckRuntimeVersion(code []byte) (Version, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.CheckRuntimeVersion(code)
+}
+
+// Stop does nothing, since the instances are owned by the pool.
+func (*PooledInstance) Stop() {}
+
+// NodeStorage returns the node storage of the instances of the pool
+func (pi *PooledInstance) NodeStorage() NodeStorage {
+	return pi.pool.template.NodeStorage()
+}
+
+// NetworkService returns the network service of the instances of the pool
+func (pi *PooledInstance) NetworkService() BasicNetwork {
+	return pi.pool.template.NetworkService()
+}
+
+// Keystore returns the keystore of the instances of the pool
+func (pi *PooledInstance) Keystore() *keystore.GlobalKeystore {
+	return pi.pool.template.Keystore()
+}
+
+// Validator returns true if the instances of the pool are validators
+func (pi *PooledInstance) Validator() bool {
+	return pi.pool.template.Validator()
+}
+
+// GetCodeHash returns the code hash of the instances of the pool
+func (pi *PooledInstance) GetCodeHash() common.Hash {
+	return pi.pool.template.GetCodeHash()
+}
+
+// GetHeapPages returns the number of heap pages of the instances of the pool
+func (pi *PooledInstance) GetHeapPages() uint64 {
+	return pi.pool.template.GetHeapPages()
+}
+
+// Exec calls the given function with the given data
+func (pi *PooledInstance) Exec(function string, data []byte) ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.Exec(function, data)
+}
+
+// Version calls runtime function Core_Version
+func (pi *PooledInstance) Version() (Version, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.Version()
+}
+
+// Metadata calls runtime function Metadata_metadata
+func (pi *PooledInstance) Metadata() ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.Metadata()
+}
+
+// BabeConfiguration gets the configuration data for BABE from the runtime
+func (pi *PooledInstance) BabeConfiguration() (*types.BabeConfiguration, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.BabeConfiguration()
+}
+
+// GrandpaAuthorities returns the genesis authorities from the runtime
+func (pi *PooledInstance) GrandpaAuthorities() ([]types.Authority, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.GrandpaAuthorities()
+}
+
+// ValidateTransaction runs the extrinsic received from the given source through the
+// runtime function TaggedTransactionQueue_validate_transaction and returns *Validity
+func (pi *PooledInstance) ValidateTransaction(source types.TransactionSource, tx types.Extrinsic,
+	blockHash common.Hash) (*transaction.Validity, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.ValidateTransaction(source, tx, blockHash)
+}
+
+// InitializeBlock calls runtime API function Core_initialise_block
+func (pi *PooledInstance) InitializeBlock(header *types.Header) error {
+	instance, err := pi.checkout()
+	if err != nil {
+		return err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.InitializeBlock(header)
+}
+
+// InherentExtrinsics calls runtime API function BlockBuilder_inherent_extrinsics
+func (pi *PooledInstance) InherentExtrinsics(data []byte) ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.InherentExtrinsics(data)
+}
+
+// ApplyExtrinsic calls runtime API function BlockBuilder_apply_extrinsic
+func (pi *PooledInstance) ApplyExtrinsic(data types.Extrinsic) ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.ApplyExtrinsic(data)
+}
+
+// FinalizeBlock calls runtime API function BlockBuilder_finalize_block
+func (pi *PooledInstance) FinalizeBlock() (*types.Header, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.FinalizeBlock()
+}
+
+// ExecuteBlock calls runtime function Core_execute_block
+func (pi *PooledInstance) ExecuteBlock(block *types.Block) ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.ExecuteBlock(block)
+}
+
+// DecodeSessionKeys decodes the given public session keys. Returns a list of raw public keys including their key type.
+func (pi *PooledInstance) DecodeSessionKeys(enc []byte) ([]byte, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.DecodeSessionKeys(enc)
+}
+
+// PaymentQueryInfo returns information of a given extrinsic
+func (pi *PooledInstance) PaymentQueryInfo(ext []byte) (*types.TransactionPaymentQueryInfo, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.PaymentQueryInfo(ext)
+}
+
+// CheckInherents calls runtime API function BlockBuilder_check_inherents
+func (pi *PooledInstance) CheckInherents(block *types.Block, inherentData []byte) (*CheckInherentsResult, error) {
+	instance, err := pi.checkout()
+	if err != nil {
+		return nil, err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.CheckInherents(block, inherentData)
+}
+
+// RandomSeed calls runtime API function RandomSeed
+func (pi *PooledInstance) RandomSeed() {
+	instance, err := pi.checkout()
+	if err != nil {
+		return
+	}
+	defer pi.pool.put(instance)
+
+	instance.RandomSeed()
+}
+
+// OffchainWorker calls runtime API function OffchainWorkerApi_offchain_worker
+func (pi *PooledInstance) OffchainWorker(header *types.Header) error {
+	instance, err := pi.checkout()
+	if err != nil {
+		return err
+	}
+	defer pi.pool.put(instance)
+
+	return instance.OffchainWorker(header)
+}
+
+// GenerateSessionKeys calls runtime API function SessionKeys_generate_session_keys
+func (pi *PooledInstance) GenerateSessionKeys() {
+	instance, err := pi.checkout()
+	if err != nil {
+		return
+	}
+	defer pi.pool.put(instance)
+
+	instance.GenerateSessionKeys()
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInstance is a runtime instance returning
+// its storage value at the key given to Exec.
+type testInstance struct {
+	Instance // nil, only the methods below are implemented
+	mutex    sync.Mutex
+	storage  Storage
+	stopped  bool
+	// execWait, if not nil, is waited on during Exec calls.
+	execWait *sync.WaitGroup
+	// execStarted, if not nil, is signaled at the start of Exec calls.
+	execStarted chan<- struct{}
+}
+
+func (ti *testInstance) SetContextStorage(s Storage) {
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+	ti.storage = s
+}
+
+func (ti *testInstance) Exec(_ string, data []byte) ([]byte, error) {
+	if ti.execStarted != nil {
+		ti.execStarted <- struct{}{}
+	}
+	if ti.execWait != nil {
+		ti.execWait.Wait()
+	}
+
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+	return ti.storage.Get(data), nil
+}
+
+func (ti *testInstance) Stop() {
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+	ti.stopped = true
+}
+
+// testClonerInstance is a test instance which can be cloned.
+type testClonerInstance struct {
+	*testInstance
+	clones *int32
+}
+
+func (tci *testClonerInstance) Clone() (Instance, error) {
+	atomic.AddInt32(tci.clones, 1)
+	return &testClonerInstance{
+		testInstance: &testInstance{
+			execWait:    tci.execWait,
+			execStarted: tci.execStarted,
+		},
+		clones: tci.clones,
+	}, nil
+}
+
+func newTestStorage(t *testing.T, value string) Storage {
+	t.Helper()
+	ts, err := storage.NewTrieState(nil)
+	require.NoError(t, err)
+	ts.Set([]byte("key"), []byte(value))
+	return ts
+}
+
+func Test_NewPool(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		instance Instance
+		size     uint
+		poolSize int
+	}{
+		"default size": {
+			instance: &testClonerInstance{testInstance: &testInstance{}},
+			poolSize: DefaultPoolSize,
+		},
+		"size set": {
+			instance: &testClonerInstance{testInstance: &testInstance{}},
+			size:     2,
+			poolSize: 2,
+		},
+		"instance not cloner": {
+			instance: &testInstance{},
+			size:     2,
+			poolSize: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := NewPool(testCase.instance, testCase.size)
+
+			assert.Equal(t, testCase.poolSize, pool.size)
+			assert.Equal(t, map[Instance]struct{}{testCase.instance: {}}, pool.members)
+			assert.Len(t, pool.instances, 1)
+		})
+	}
+}
+
+func Test_Pool_Add(t *testing.T) {
+	t.Parallel()
+
+	template := &testClonerInstance{testInstance: &testInstance{}}
+	pool := NewPool(template, 2)
+
+	pool.Add(template)
+	assert.Len(t, pool.members, 1)
+
+	second := &testInstance{}
+	pool.Add(second)
+	assert.Len(t, pool.members, 2)
+	assert.False(t, second.stopped)
+
+	third := &testInstance{}
+	pool.Add(third)
+	assert.Len(t, pool.members, 2)
+	assert.True(t, third.stopped)
+}
+
+func Test_Pool_Stop(t *testing.T) {
+	t.Parallel()
+
+	execWait := new(sync.WaitGroup)
+	execStarted := make(chan struct{})
+	inUse := &testInstance{execWait: execWait, execStarted: execStarted}
+	idle := &testInstance{}
+	pool := NewPool(inUse, 2)
+	pool.Add(idle)
+
+	execWait.Add(1)
+	handle := pool.Instance()
+	handle.SetContextStorage(newTestStorage(t, "value"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = handle.Exec("", []byte("key"))
+	}()
+	<-execStarted
+
+	// idle instances are stopped right away
+	pool.Stop()
+	assert.True(t, idle.stopped)
+	assert.Equal(t, map[Instance]struct{}{inUse: {}}, pool.members)
+
+	// instances in use are stopped once returned to the pool
+	execWait.Done()
+	<-done
+	assert.True(t, inUse.stopped)
+	assert.Empty(t, pool.members)
+
+	_, err := handle.Exec("", []byte("key"))
+	assert.ErrorIs(t, err, ErrPoolStopped)
+
+	added := &testInstance{}
+	pool.Add(added)
+	assert.True(t, added.stopped)
+	assert.Empty(t, pool.members)
+}
+
+func Test_PooledInstance_storageIsolation(t *testing.T) {
+	t.Parallel()
+
+	clones := int32(0)
+	pool := NewPool(&testClonerInstance{testInstance: &testInstance{}, clones: &clones}, 2)
+
+	first := pool.Instance()
+	first.SetContextStorage(newTestStorage(t, "first"))
+	second := pool.Instance()
+	second.SetContextStorage(newTestStorage(t, "second"))
+	// handles without storage never use the storage of another handle
+	third := pool.Instance()
+
+	value, err := first.Exec("", []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+
+	value, err = second.Exec("", []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), value)
+
+	value, err = third.Exec("", []byte("key"))
+	assert.ErrorIs(t, err, ErrPooledInstanceNoStorage)
+	assert.Nil(t, value)
+
+	// calls made one after the other reuse the same instance
+	assert.Equal(t, int32(0), clones)
+}
+
+func Test_PooledInstance_concurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	const size = 3
+	const calls = 2 * size
+
+	var execWait sync.WaitGroup
+	execWait.Add(1)
+	execStarted := make(chan struct{})
+	clones := int32(0)
+	template := &testClonerInstance{
+		testInstance: &testInstance{
+			execWait:    &execWait,
+			execStarted: execStarted,
+		},
+		clones: &clones,
+	}
+	pool := NewPool(template, size)
+
+	storage := newTestStorage(t, "value")
+	var callsDone sync.WaitGroup
+	callsDone.Add(calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			defer callsDone.Done()
+			handle := pool.Instance()
+			handle.SetContextStorage(storage)
+			value, err := handle.Exec("", []byte("key"))
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("value"), value)
+		}()
+	}
+
+	// wait for the pool instances to all run a call at the same time
+	for i := 0; i < size; i++ {
+		<-execStarted
+	}
+	execWait.Done()
+
+	// let the remaining calls run on the returned instances
+	for i := size; i < calls; i++ {
+		<-execStarted
+	}
+	callsDone.Wait()
+
+	assert.Equal(t, int32(size-1), clones)
+	assert.Len(t, pool.members, size)
+}
+
+func Test_PooledInstance_UpdateRuntimeCode(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(&testInstance{}, 0)
+
+	err := pool.Instance().UpdateRuntimeCode(nil)
+	assert.ErrorIs(t, err, ErrPooledInstanceUpdate)
+}
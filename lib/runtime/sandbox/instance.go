@@ -0,0 +1,390 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sandbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/go-interpreter/wagon/wasm"
+	"github.com/perlin-network/life/exec"
+)
+
+var (
+	errUnresolvedImport    = errors.New("unresolved import")
+	errUnsupportedImport   = errors.New("unsupported import kind")
+	errIncompatibleMemory  = errors.New("incompatible memory import")
+	errFunctionNotFound    = errors.New("exported function not found")
+	errSignatureMismatch   = errors.New("signature mismatch")
+	errInstanceBusy        = errors.New("instance is already executing")
+	errNoSupervisor        = errors.New("no supervisor to dispatch call to")
+	errUnexpectedHostValue = errors.New("unexpected value returned by supervisor")
+)
+
+// Supervisor is implemented by the executor of the runtime that created a sandbox.
+// It routes calls made by guests to functions they imported from the supervisor.
+type Supervisor interface {
+	// Dispatch calls the supervisor's dispatch thunk with the SCALE encoded arguments,
+	// the opaque state pointer and the index of the supervisor function to call.
+	// It returns the SCALE encoded Result<ReturnValue, HostError> produced by the thunk.
+	Dispatch(dispatchThunk uint32, args []byte, state, index uint32) ([]byte, error)
+}
+
+// guestFunc is a function imported by the guest from the supervisor
+type guestFunc struct {
+	index uint32
+	sig   *wasm.FunctionSig
+}
+
+// Instance is a guest Wasm module instantiated in the sandbox.
+// Guests are executed by the life interpreter, which allows arbitrary import signatures.
+type Instance struct {
+	vm            *exec.VirtualMachine
+	module        *wasm.Module
+	dispatchThunk uint32
+	funcs         map[string]guestFunc
+	memory        *Memory
+
+	running    bool
+	supervisor Supervisor
+	state      uint32
+}
+
+func importKey(module, field string) string {
+	return module + "\x00" + field
+}
+
+// newInstance instantiates the guest code, resolving its imports from the environment entries
+// and running its start function, if any.
+func newInstance(dispatchThunk uint32, code []byte, entries []EnvironmentEntry,
+	memories func(uint32) (*Memory, error), state uint32, supervisor Supervisor) (*Instance, error) {
+	module, err := wasm.ReadModule(bytes.NewReader(code), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode module: %w", err)
+	}
+
+	env := make(map[string]EnvironmentEntry, len(entries))
+	for _, entry := range entries {
+		env[importKey(string(entry.Module), string(entry.Field))] = entry
+	}
+
+	inst := &Instance{
+		module:        module,
+		dispatchThunk: dispatchThunk,
+		funcs:         make(map[string]guestFunc),
+	}
+
+	if module.Import != nil {
+		for _, imp := range module.Import.Entries {
+			key := importKey(imp.ModuleName, imp.FieldName)
+			entry, ok := env[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s.%s", errUnresolvedImport, imp.ModuleName, imp.FieldName)
+			}
+
+			switch typ := imp.Type.(type) {
+			case wasm.FuncImport:
+				if entry.Kind != ExternFunction {
+					return nil, fmt.Errorf("%w: %s.%s is not a function",
+						errUnresolvedImport, imp.ModuleName, imp.FieldName)
+				}
+				if module.Types == nil || int(typ.Type) >= len(module.Types.Entries) {
+					return nil, fmt.Errorf("%w: invalid type index %d", errSignatureMismatch, typ.Type)
+				}
+				inst.funcs[key] = guestFunc{
+					index: entry.Index,
+					sig:   &module.Types.Entries[typ.Type],
+				}
+			case wasm.MemoryImport:
+				if entry.Kind != ExternMemory {
+					return nil, fmt.Errorf("%w: %s.%s is not a memory",
+						errUnresolvedImport, imp.ModuleName, imp.FieldName)
+				}
+				mem, err := memories(entry.Index)
+				if err != nil {
+					return nil, err
+				}
+				err = checkMemoryLimits(mem, typ.Type.Limits)
+				if err != nil {
+					return nil, err
+				}
+				inst.memory = mem
+			default:
+				return nil, fmt.Errorf("%w: %s.%s", errUnsupportedImport, imp.ModuleName, imp.FieldName)
+			}
+		}
+	}
+
+	cfg := exec.VMConfig{
+		MaxMemoryPages: maxPages,
+	}
+
+	if inst.memory != nil {
+		cfg.DefaultMemoryPages = int(inst.memory.Pages())
+		cfg.MaxMemoryPages = int(inst.memory.Maximum())
+	} else if module.Memory != nil && len(module.Memory.Entries) > 0 {
+		limits := module.Memory.Entries[0].Limits
+		if limits.Flags&1 == 1 {
+			cfg.MaxMemoryPages = int(limits.Maximum)
+		}
+	}
+
+	vm, err := exec.NewVirtualMachine(code, cfg, inst, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot instantiate module: %w", err)
+	}
+	inst.vm = vm
+
+	if inst.memory != nil {
+		// life initialises data segments into a memory of its own, so copy them into the
+		// imported memory which is shared with the supervisor and other instances.
+		err = inst.initImportedMemory()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if module.Start != nil {
+		_, err = inst.run(int(module.Start.Index), nil, state, supervisor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return inst, nil
+}
+
+func checkMemoryLimits(mem *Memory, limits wasm.ResizableLimits) error {
+	if mem.Pages() < limits.Initial {
+		return fmt.Errorf("%w: memory has %d pages, module requires at least %d",
+			errIncompatibleMemory, mem.Pages(), limits.Initial)
+	}
+
+	if limits.Flags&1 == 1 && mem.Maximum() > limits.Maximum {
+		return fmt.Errorf("%w: memory maximum of %d pages exceeds module maximum of %d",
+			errIncompatibleMemory, mem.Maximum(), limits.Maximum)
+	}
+
+	return nil
+}
+
+func (in *Instance) initImportedMemory() error {
+	if in.module.Data != nil {
+		for _, segment := range in.module.Data.Entries {
+			offset, err := in.module.ExecInitExpr(segment.Offset)
+			if err != nil {
+				return fmt.Errorf("cannot evaluate data segment offset: %w", err)
+			}
+
+			off, ok := offset.(int32)
+			if !ok {
+				return fmt.Errorf("invalid data segment offset type %T", offset)
+			}
+
+			err = in.memory.Set(uint32(off), segment.Data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	in.vm.Memory = in.memory.data
+	return nil
+}
+
+// ResolveFunc implements exec.ImportResolver. All function imports have been checked when
+// the instance was created, so the supervisor index is always known here.
+func (in *Instance) ResolveFunc(module, field string) exec.FunctionImport {
+	fn, ok := in.funcs[importKey(module, field)]
+	if !ok {
+		panic(fmt.Errorf("%w: %s.%s", errUnresolvedImport, module, field))
+	}
+
+	return func(vm *exec.VirtualMachine) int64 {
+		frame := vm.GetCurrentFrame()
+
+		args := make([]scale.VaryingDataTypeValue, len(fn.sig.ParamTypes))
+		for i, typ := range fn.sig.ParamTypes {
+			arg, err := fromRaw(frame.Locals[i], typ)
+			if err != nil {
+				panic(err)
+			}
+			args[i] = arg
+		}
+
+		ret, err := in.dispatch(fn.index, args)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(fn.sig.ReturnTypes) == 0 {
+			if ret != nil {
+				panic(fmt.Errorf("%w: got %T for function without result", errUnexpectedHostValue, ret))
+			}
+			return 0
+		}
+
+		raw, err := toRaw(ret, fn.sig.ReturnTypes[0])
+		if err != nil {
+			panic(err)
+		}
+		return raw
+	}
+}
+
+// ResolveGlobal implements exec.ImportResolver. Global imports are rejected when the
+// instance is created, so this is never called.
+func (*Instance) ResolveGlobal(module, field string) int64 {
+	panic(fmt.Errorf("%w: %s.%s", errUnsupportedImport, module, field))
+}
+
+// dispatch forwards a call made by the guest to the supervisor function with the given index
+func (in *Instance) dispatch(index uint32, args []scale.VaryingDataTypeValue) (scale.VaryingDataTypeValue, error) {
+	if in.supervisor == nil {
+		return nil, errNoSupervisor
+	}
+
+	enc, err := EncodeValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// the supervisor may access the shared memory while handling the call
+	in.flushMemory()
+	res, err := in.supervisor.Dispatch(in.dispatchThunk, enc, in.state, index)
+	in.loadMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeHostResult(res)
+}
+
+// loadMemory points the interpreter at the current buffer of the imported memory
+func (in *Instance) loadMemory() {
+	if in.memory != nil {
+		in.vm.Memory = in.memory.data
+	}
+}
+
+// flushMemory stores the interpreter's buffer, which may have been grown, in the imported memory
+func (in *Instance) flushMemory() {
+	if in.memory != nil {
+		in.memory.data = in.vm.Memory
+	}
+}
+
+// funcSig returns the signature of the function with the given index in the function index space
+func (in *Instance) funcSig(index uint32) (*wasm.FunctionSig, error) {
+	var imported uint32
+	if in.module.Import != nil {
+		for _, imp := range in.module.Import.Entries {
+			typ, ok := imp.Type.(wasm.FuncImport)
+			if !ok {
+				continue
+			}
+			if imported == index {
+				return &in.module.Types.Entries[typ.Type], nil
+			}
+			imported++
+		}
+	}
+
+	local := index - imported
+	if in.module.Function == nil || int(local) >= len(in.module.Function.Types) {
+		return nil, fmt.Errorf("%w: function index %d", errFunctionNotFound, index)
+	}
+
+	return &in.module.Types.Entries[in.module.Function.Types[local]], nil
+}
+
+func (in *Instance) run(funcID int, params []int64, state uint32, supervisor Supervisor) (ret int64, err error) {
+	if in.running {
+		return 0, fmt.Errorf("%w: %s", ErrExecution, errInstanceBusy)
+	}
+
+	in.running = true
+	in.state = state
+	in.supervisor = supervisor
+	in.loadMemory()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrExecution, r)
+		}
+
+		in.flushMemory()
+		in.running = false
+		in.supervisor = nil
+
+		// reset the interpreter so that the instance can be invoked again after a trap
+		in.vm.ExitError = nil
+		in.vm.CurrentFrame = -1
+		in.vm.Exited = true
+	}()
+
+	ret, err = in.vm.Run(funcID, params...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrExecution, err)
+	}
+
+	return ret, nil
+}
+
+// Invoke calls the exported function with the given name and arguments. The state is passed
+// back to the supervisor for every call the guest makes to a supervisor function.
+// A nil value is returned for functions without a result.
+func (in *Instance) Invoke(name string, args []scale.VaryingDataTypeValue, state uint32,
+	supervisor Supervisor) (scale.VaryingDataTypeValue, error) {
+	funcID, ok := in.vm.GetFunctionExport(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: %s", ErrExecution, errFunctionNotFound, name)
+	}
+
+	sig, err := in.funcSig(uint32(funcID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExecution, err)
+	}
+
+	if len(args) != len(sig.ParamTypes) {
+		return nil, fmt.Errorf("%w: %s: got %d arguments, expected %d",
+			ErrExecution, errSignatureMismatch, len(args), len(sig.ParamTypes))
+	}
+
+	params := make([]int64, len(args))
+	for i, arg := range args {
+		params[i], err = toRaw(arg, sig.ParamTypes[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrExecution, err)
+		}
+	}
+
+	ret, err := in.run(funcID, params, state, supervisor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sig.ReturnTypes) == 0 {
+		return nil, nil
+	}
+
+	return fromRaw(ret, sig.ReturnTypes[0])
+}
+
+// GetGlobal returns the value of the exported global with the given name
+func (in *Instance) GetGlobal(name string) (scale.VaryingDataTypeValue, bool) {
+	id, ok := in.vm.GetGlobalExport(name)
+	if !ok || id >= len(in.vm.Globals) || id >= len(in.module.GlobalIndexSpace) {
+		return nil, false
+	}
+
+	value, err := fromRaw(in.vm.Globals[id], in.module.GlobalIndexSpace[id].Type.Type)
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
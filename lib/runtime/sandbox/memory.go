@@ -0,0 +1,80 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sandbox
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// PageSize is the size of a Wasm linear memory page in bytes
+	PageSize = 65536
+	// maxPages is the maximum number of pages addressable by a 32 bit linear memory
+	maxPages = 65536
+	// NoMaximum is passed as the maximum number of pages for memories without an upper bound
+	NoMaximum = math.MaxUint32
+)
+
+// Memory is a linear memory created by the supervisor, which can be read and written
+// by the supervisor and imported by guest instances.
+type Memory struct {
+	data    []byte
+	maximum uint32
+}
+
+// NewMemory creates a new zeroed memory of `initial` pages, which can grow up to `maximum` pages.
+// If maximum is NoMaximum, the memory can grow up to the 4GiB limit of 32 bit memories.
+func NewMemory(initial, maximum uint32) (*Memory, error) {
+	if maximum == NoMaximum {
+		maximum = maxPages
+	}
+
+	if maximum > maxPages {
+		return nil, fmt.Errorf("maximum of %d pages exceeds limit of %d pages", maximum, maxPages)
+	}
+
+	if initial > maximum {
+		return nil, fmt.Errorf("initial size of %d pages exceeds maximum of %d pages", initial, maximum)
+	}
+
+	return &Memory{
+		data:    make([]byte, uint64(initial)*PageSize),
+		maximum: maximum,
+	}, nil
+}
+
+// Pages returns the current size of the memory in pages
+func (m *Memory) Pages() uint32 {
+	return uint32(len(m.data) / PageSize)
+}
+
+// Maximum returns the maximum size of the memory in pages
+func (m *Memory) Maximum() uint32 {
+	return m.maximum
+}
+
+// Get copies len(buf) bytes starting at offset into buf
+func (m *Memory) Get(offset uint32, buf []byte) error {
+	end := uint64(offset) + uint64(len(buf))
+	if end > uint64(len(m.data)) {
+		return fmt.Errorf("%w: reading %d bytes at offset %d of memory of size %d",
+			ErrOutOfBounds, len(buf), offset, len(m.data))
+	}
+
+	copy(buf, m.data[offset:end])
+	return nil
+}
+
+// Set copies the given data into the memory starting at offset
+func (m *Memory) Set(offset uint32, data []byte) error {
+	end := uint64(offset) + uint64(len(data))
+	if end > uint64(len(m.data)) {
+		return fmt.Errorf("%w: writing %d bytes at offset %d of memory of size %d",
+			ErrOutOfBounds, len(data), offset, len(m.data))
+	}
+
+	copy(m.data[offset:end], data)
+	return nil
+}
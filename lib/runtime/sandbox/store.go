@@ -0,0 +1,156 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+)
+
+// Return codes of the ext_sandbox_* host functions, as expected by sp-sandbox
+const (
+	// ReturnOK is returned when the operation succeeded
+	ReturnOK uint32 = 0
+	// ReturnErrExecution is returned when the guest trapped
+	ReturnErrExecution uint32 = math.MaxUint32
+	// ReturnErrModule is returned when the guest module could not be instantiated
+	ReturnErrModule uint32 = math.MaxUint32 - 1
+	// ReturnErrOutOfBounds is returned when a memory access is out of bounds
+	ReturnErrOutOfBounds uint32 = math.MaxUint32 - 2
+)
+
+// ExternFunction and ExternMemory are the kinds of entities an environment definition can provide
+const (
+	ExternFunction byte = 1
+	ExternMemory   byte = 2
+)
+
+var (
+	// ErrExecution is returned when the execution of a guest traps
+	ErrExecution = errors.New("guest execution failed")
+	// ErrOutOfBounds is returned when a memory access is out of bounds
+	ErrOutOfBounds = errors.New("out of bounds memory access")
+
+	errInstanceNotFound = errors.New("sandbox instance not found")
+	errMemoryNotFound   = errors.New("sandbox memory not found")
+	errInvalidExtern    = errors.New("invalid extern entity kind")
+)
+
+// EnvironmentEntry is an entity the supervisor provides to a guest under a module and field name.
+// Its SCALE encoding matches sp-sandbox's (module_name, field_name, ExternEntity) entries.
+type EnvironmentEntry struct {
+	Module []byte
+	Field  []byte
+	Kind   byte
+	Index  uint32
+}
+
+// DecodeEnvironmentDefinition decodes a SCALE encoded sp-sandbox EnvironmentDefinition
+func DecodeEnvironmentDefinition(in []byte) ([]EnvironmentEntry, error) {
+	var entries []EnvironmentEntry
+	err := scale.Unmarshal(in, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Kind != ExternFunction && entry.Kind != ExternMemory {
+			return nil, fmt.Errorf("%w: %d", errInvalidExtern, entry.Kind)
+		}
+	}
+
+	return entries, nil
+}
+
+// Store holds the guest instances and memories created by a runtime instance.
+// Indices are never reused until the store is reset, so a torn down instance or
+// memory stays invalid.
+type Store struct {
+	instances []*Instance
+	memories  []*Memory
+}
+
+// NewStore returns an empty sandbox store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// NewMemory creates a new memory and returns its index
+func (s *Store) NewMemory(initial, maximum uint32) (uint32, error) {
+	mem, err := NewMemory(initial, maximum)
+	if err != nil {
+		return 0, err
+	}
+
+	s.memories = append(s.memories, mem)
+	return uint32(len(s.memories) - 1), nil
+}
+
+// Memory returns the memory with the given index
+func (s *Store) Memory(idx uint32) (*Memory, error) {
+	if int(idx) >= len(s.memories) || s.memories[idx] == nil {
+		return nil, fmt.Errorf("%w: %d", errMemoryNotFound, idx)
+	}
+
+	return s.memories[idx], nil
+}
+
+// TeardownMemory removes the memory with the given index
+func (s *Store) TeardownMemory(idx uint32) error {
+	if _, err := s.Memory(idx); err != nil {
+		return err
+	}
+
+	s.memories[idx] = nil
+	return nil
+}
+
+// Instantiate creates a guest instance from the given Wasm code and SCALE encoded environment
+// definition, and returns its index. Calls to supervisor functions made by the start function
+// are routed through the dispatch thunk with the given state.
+// An error wrapping ErrExecution is returned if the start function traps.
+func (s *Store) Instantiate(dispatchThunk uint32, code, envDef []byte, state uint32,
+	supervisor Supervisor) (uint32, error) {
+	entries, err := DecodeEnvironmentDefinition(envDef)
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode environment definition: %w", err)
+	}
+
+	inst, err := newInstance(dispatchThunk, code, entries, s.Memory, state, supervisor)
+	if err != nil {
+		return 0, err
+	}
+
+	s.instances = append(s.instances, inst)
+	return uint32(len(s.instances) - 1), nil
+}
+
+// Instance returns the guest instance with the given index
+func (s *Store) Instance(idx uint32) (*Instance, error) {
+	if int(idx) >= len(s.instances) || s.instances[idx] == nil {
+		return nil, fmt.Errorf("%w: %d", errInstanceNotFound, idx)
+	}
+
+	return s.instances[idx], nil
+}
+
+// TeardownInstance removes the guest instance with the given index
+func (s *Store) TeardownInstance(idx uint32) error {
+	if _, err := s.Instance(idx); err != nil {
+		return err
+	}
+
+	s.instances[idx] = nil
+	return nil
+}
+
+// Reset removes all the guest instances and memories. It is called at the end of each
+// call to the runtime, since the guests only live for the duration of a call, as in Substrate.
+func (s *Store) Reset() {
+	s.instances = nil
+	s.memories = nil
+}
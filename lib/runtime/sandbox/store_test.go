@@ -0,0 +1,430 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sandbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The guests below are hand assembled. All sections and vectors
+// are short enough for their lengths to fit in a single LEB128 byte.
+
+func wasmModule(sections ...[]byte) (module []byte) {
+	module = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, section := range sections {
+		module = append(module, section...)
+	}
+	return module
+}
+
+func wasmSection(id byte, items ...[]byte) []byte {
+	contents := wasmVec(items...)
+	return append([]byte{id, byte(len(contents))}, contents...)
+}
+
+func wasmVec(items ...[]byte) (vec []byte) {
+	vec = []byte{byte(len(items))}
+	for _, item := range items {
+		vec = append(vec, item...)
+	}
+	return vec
+}
+
+func wasmName(name string) []byte {
+	return append([]byte{byte(len(name))}, name...)
+}
+
+func wasmBody(code ...byte) []byte {
+	body := append([]byte{0x00}, code...) // no locals
+	body = append(body, 0x0b)
+	return append([]byte{byte(len(body))}, body...)
+}
+
+func concat(parts ...[]byte) (out []byte) {
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// guestWithMemory is
+// (module
+//   (import "env" "double" (func (param i32) (result i32)))
+//   (import "env" "memory" (memory 1))
+//   (func (export "add") (param i32 i32) (result i32) local.get 0 local.get 1 i32.add)
+//   (func (export "call_double") (param i32) (result i32) local.get 0 call 0)
+//   (func (export "store") (param i32 i32) local.get 0 local.get 1 i32.store)
+//   (func (export "trap") unreachable)
+//   (data (i32.const 0) "hello"))
+var guestWithMemory = wasmModule(
+	wasmSection(0x01,
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f},
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x00},
+		[]byte{0x60, 0x00, 0x00},
+	),
+	wasmSection(0x02,
+		concat(wasmName("env"), wasmName("double"), []byte{0x00, 0x01}),
+		concat(wasmName("env"), wasmName("memory"), []byte{0x02, 0x00, 0x01}),
+	),
+	wasmSection(0x03, []byte{0x00}, []byte{0x01}, []byte{0x02}, []byte{0x03}),
+	wasmSection(0x07,
+		concat(wasmName("add"), []byte{0x00, 0x01}),
+		concat(wasmName("call_double"), []byte{0x00, 0x02}),
+		concat(wasmName("store"), []byte{0x00, 0x03}),
+		concat(wasmName("trap"), []byte{0x00, 0x04}),
+	),
+	wasmSection(0x0a,
+		wasmBody(0x20, 0x00, 0x20, 0x01, 0x6a),
+		wasmBody(0x20, 0x00, 0x10, 0x00),
+		wasmBody(0x20, 0x00, 0x20, 0x01, 0x36, 0x02, 0x00),
+		wasmBody(0x00),
+	),
+	wasmSection(0x0b,
+		concat([]byte{0x00, 0x41, 0x00, 0x0b}, wasmName("hello")),
+	),
+)
+
+// guestWithStart is
+// (module
+//   (import "env" "tick" (func))
+//   (global (export "counter") (mut i32) (i32.const 7))
+//   (func global.get 0 i32.const 1 i32.add global.set 0 call 0)
+//   (start 1))
+var guestWithStart = wasmModule(
+	wasmSection(0x01, []byte{0x60, 0x00, 0x00}),
+	wasmSection(0x02, concat(wasmName("env"), wasmName("tick"), []byte{0x00, 0x00})),
+	wasmSection(0x03, []byte{0x00}),
+	wasmSection(0x06, []byte{0x7f, 0x01, 0x41, 0x07, 0x0b}),
+	wasmSection(0x07, concat(wasmName("counter"), []byte{0x03, 0x00})),
+	[]byte{0x08, 0x01, 0x01},
+	wasmSection(0x0a, wasmBody(0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00, 0x10, 0x00)),
+)
+
+type dispatchCall struct {
+	thunk, state, index uint32
+	args                []scale.VaryingDataTypeValue
+}
+
+type mockSupervisor struct {
+	calls  []dispatchCall
+	result func(args []scale.VaryingDataTypeValue) []byte
+	err    error
+}
+
+func (s *mockSupervisor) Dispatch(thunk uint32, args []byte, state, index uint32) ([]byte, error) {
+	values, err := DecodeValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.calls = append(s.calls, dispatchCall{thunk: thunk, state: state, index: index, args: values})
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.result(values), nil
+}
+
+func encodeEnv(t *testing.T, entries ...EnvironmentEntry) []byte {
+	t.Helper()
+	enc, err := scale.Marshal(entries)
+	require.NoError(t, err)
+	return enc
+}
+
+func newGuestWithMemory(t *testing.T) (*Store, uint32, uint32) {
+	t.Helper()
+
+	store := NewStore()
+	memIdx, err := store.NewMemory(1, NoMaximum)
+	require.NoError(t, err)
+
+	env := encodeEnv(t,
+		EnvironmentEntry{Module: []byte("env"), Field: []byte("double"), Kind: ExternFunction, Index: 42},
+		EnvironmentEntry{Module: []byte("env"), Field: []byte("memory"), Kind: ExternMemory, Index: memIdx},
+	)
+
+	instIdx, err := store.Instantiate(5, guestWithMemory, env, 99, nil)
+	require.NoError(t, err)
+	return store, instIdx, memIdx
+}
+
+func TestStore_Instantiate_dataSegmentsWrittenToImportedMemory(t *testing.T) {
+	t.Parallel()
+
+	store, _, memIdx := newGuestWithMemory(t)
+	mem, err := store.Memory(memIdx)
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+	err = mem.Get(0, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), buf)
+}
+
+func TestStore_Instantiate_errors(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	memIdx, err := store.NewMemory(1, NoMaximum)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		code []byte
+		env  []byte
+	}{
+		"invalid code": {
+			code: []byte{0x01, 0x02},
+			env:  encodeEnv(t),
+		},
+		"invalid environment": {
+			code: guestWithMemory,
+			env:  []byte{0xff},
+		},
+		"unresolved function": {
+			code: guestWithMemory,
+			env: encodeEnv(t,
+				EnvironmentEntry{Module: []byte("env"), Field: []byte("memory"), Kind: ExternMemory, Index: memIdx}),
+		},
+		"memory provided as function": {
+			code: guestWithMemory,
+			env: encodeEnv(t,
+				EnvironmentEntry{Module: []byte("env"), Field: []byte("double"), Kind: ExternFunction},
+				EnvironmentEntry{Module: []byte("env"), Field: []byte("memory"), Kind: ExternFunction}),
+		},
+		"unknown memory": {
+			code: guestWithMemory,
+			env: encodeEnv(t,
+				EnvironmentEntry{Module: []byte("env"), Field: []byte("double"), Kind: ExternFunction},
+				EnvironmentEntry{Module: []byte("env"), Field: []byte("memory"), Kind: ExternMemory, Index: 7}),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Instantiate(0, testCase.code, testCase.env, 0, nil)
+			require.Error(t, err)
+			assert.False(t, errors.Is(err, ErrExecution))
+		})
+	}
+}
+
+func TestStore_Instantiate_startFunction(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	env := encodeEnv(t, EnvironmentEntry{Module: []byte("env"), Field: []byte("tick"), Kind: ExternFunction, Index: 3})
+
+	supervisor := &mockSupervisor{
+		result: func([]scale.VaryingDataTypeValue) []byte { return []byte{0, 0} }, // Ok(ReturnValue::Unit)
+	}
+
+	idx, err := store.Instantiate(11, guestWithStart, env, 22, supervisor)
+	require.NoError(t, err)
+
+	require.Len(t, supervisor.calls, 1)
+	assert.Equal(t, dispatchCall{thunk: 11, state: 22, index: 3, args: []scale.VaryingDataTypeValue{}},
+		supervisor.calls[0])
+
+	inst, err := store.Instance(idx)
+	require.NoError(t, err)
+
+	value, ok := inst.GetGlobal("counter")
+	require.True(t, ok)
+	assert.Equal(t, I32(8), value)
+
+	_, ok = inst.GetGlobal("missing")
+	assert.False(t, ok)
+
+	supervisor.err = errors.New("host error")
+	_, err = store.Instantiate(11, guestWithStart, env, 22, supervisor)
+	assert.ErrorIs(t, err, ErrExecution)
+}
+
+func TestInstance_Invoke(t *testing.T) {
+	t.Parallel()
+
+	store, instIdx, memIdx := newGuestWithMemory(t)
+	inst, err := store.Instance(instIdx)
+	require.NoError(t, err)
+
+	res, err := inst.Invoke("add", []scale.VaryingDataTypeValue{I32(40), I32(2)}, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, I32(42), res)
+
+	res, err = inst.Invoke("store", []scale.VaryingDataTypeValue{I32(8), I32(0x04030201)}, 0, nil)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	mem, err := store.Memory(memIdx)
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	err = mem.Get(8, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, buf)
+
+	_, err = inst.Invoke("trap", nil, 0, nil)
+	assert.ErrorIs(t, err, ErrExecution)
+
+	// the instance stays usable after a trap
+	res, err = inst.Invoke("add", []scale.VaryingDataTypeValue{I32(1), I32(1)}, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, I32(2), res)
+
+	_, err = inst.Invoke("missing", nil, 0, nil)
+	assert.ErrorIs(t, err, ErrExecution)
+
+	_, err = inst.Invoke("add", []scale.VaryingDataTypeValue{I32(1)}, 0, nil)
+	assert.ErrorIs(t, err, ErrExecution)
+
+	_, err = inst.Invoke("add", []scale.VaryingDataTypeValue{I32(1), I64(1)}, 0, nil)
+	assert.ErrorIs(t, err, ErrExecution)
+}
+
+func TestInstance_Invoke_dispatch(t *testing.T) {
+	t.Parallel()
+
+	store, instIdx, _ := newGuestWithMemory(t)
+	inst, err := store.Instance(instIdx)
+	require.NoError(t, err)
+
+	supervisor := &mockSupervisor{
+		result: func(args []scale.VaryingDataTypeValue) []byte {
+			enc, err := EncodeReturnValue(args[0].(I32) * 2)
+			require.NoError(t, err)
+			return append([]byte{0}, enc...)
+		},
+	}
+
+	res, err := inst.Invoke("call_double", []scale.VaryingDataTypeValue{I32(21)}, 77, supervisor)
+	require.NoError(t, err)
+	assert.Equal(t, I32(42), res)
+	assert.Equal(t, []dispatchCall{{thunk: 5, state: 77, index: 42, args: []scale.VaryingDataTypeValue{I32(21)}}},
+		supervisor.calls)
+
+	// host error
+	supervisor.result = func([]scale.VaryingDataTypeValue) []byte { return []byte{1} }
+	_, err = inst.Invoke("call_double", []scale.VaryingDataTypeValue{I32(21)}, 77, supervisor)
+	assert.ErrorIs(t, err, ErrExecution)
+
+	// wrong return type
+	supervisor.result = func([]scale.VaryingDataTypeValue) []byte {
+		enc, err := EncodeReturnValue(I64(1))
+		require.NoError(t, err)
+		return append([]byte{0}, enc...)
+	}
+	_, err = inst.Invoke("call_double", []scale.VaryingDataTypeValue{I32(21)}, 77, supervisor)
+	assert.ErrorIs(t, err, ErrExecution)
+
+	// no supervisor
+	_, err = inst.Invoke("call_double", []scale.VaryingDataTypeValue{I32(21)}, 77, nil)
+	assert.ErrorIs(t, err, ErrExecution)
+}
+
+func TestStore_teardown(t *testing.T) {
+	t.Parallel()
+
+	store, instIdx, memIdx := newGuestWithMemory(t)
+
+	err := store.TeardownInstance(instIdx)
+	require.NoError(t, err)
+	_, err = store.Instance(instIdx)
+	assert.ErrorIs(t, err, errInstanceNotFound)
+	err = store.TeardownInstance(instIdx)
+	assert.ErrorIs(t, err, errInstanceNotFound)
+
+	err = store.TeardownMemory(memIdx)
+	require.NoError(t, err)
+	_, err = store.Memory(memIdx)
+	assert.ErrorIs(t, err, errMemoryNotFound)
+
+	// indices are not reused
+	newIdx, err := store.NewMemory(1, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, memIdx, newIdx)
+}
+
+func TestStore_Reset(t *testing.T) {
+	t.Parallel()
+
+	store, instIdx, memIdx := newGuestWithMemory(t)
+
+	store.Reset()
+	_, err := store.Instance(instIdx)
+	assert.ErrorIs(t, err, errInstanceNotFound)
+	_, err = store.Memory(memIdx)
+	assert.ErrorIs(t, err, errMemoryNotFound)
+
+	newIdx, err := store.NewMemory(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), newIdx)
+}
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewMemory(2, 1)
+	assert.Error(t, err)
+
+	_, err = NewMemory(1, maxPages+1)
+	assert.Error(t, err)
+
+	mem, err := NewMemory(1, NoMaximum)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), mem.Pages())
+	assert.Equal(t, uint32(maxPages), mem.Maximum())
+
+	err = mem.Set(PageSize-2, []byte{1, 2})
+	require.NoError(t, err)
+
+	buf := make([]byte, 2)
+	err = mem.Get(PageSize-2, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, buf)
+
+	err = mem.Set(PageSize-1, []byte{1, 2})
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+
+	err = mem.Get(PageSize-1, buf)
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+}
+
+func TestValues_encoding(t *testing.T) {
+	t.Parallel()
+
+	values := []scale.VaryingDataTypeValue{I32(-1), I64(2), F32(3), F64(4)}
+	enc, err := EncodeValues(values)
+	require.NoError(t, err)
+
+	expected := []byte{
+		16,
+		0, 0xff, 0xff, 0xff, 0xff,
+		1, 2, 0, 0, 0, 0, 0, 0, 0,
+		2, 3, 0, 0, 0,
+		3, 4, 0, 0, 0, 0, 0, 0, 0,
+	}
+	assert.Equal(t, expected, enc)
+
+	decoded, err := DecodeValues(enc)
+	require.NoError(t, err)
+	assert.Equal(t, values, decoded)
+
+	enc, err = EncodeReturnValue(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0}, enc)
+
+	enc, err = EncodeReturnValue(I64(-1))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, enc)
+
+	value, err := DecodeReturnValue(enc)
+	require.NoError(t, err)
+	assert.Equal(t, I64(-1), value)
+}
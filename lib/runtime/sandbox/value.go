@@ -0,0 +1,168 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+var errUnexpectedValueType = errors.New("unexpected value type")
+
+// I32 is a 32 bit integer Wasm value
+type I32 int32
+
+// Index returns VDT index
+func (I32) Index() uint { return 0 }
+
+// I64 is a 64 bit integer Wasm value
+type I64 int64
+
+// Index returns VDT index
+func (I64) Index() uint { return 1 }
+
+// F32 is a 32 bit float Wasm value, stored as its bit pattern
+type F32 uint32
+
+// Index returns VDT index
+func (F32) Index() uint { return 2 }
+
+// F64 is a 64 bit float Wasm value, stored as its bit pattern
+type F64 uint64
+
+// Index returns VDT index
+func (F64) Index() uint { return 3 }
+
+// NewValue returns a VaryingDataType representing a typed Wasm value,
+// as encoded by sp-sandbox.
+func NewValue() scale.VaryingDataType {
+	return scale.MustNewVaryingDataType(I32(0), I64(0), F32(0), F64(0))
+}
+
+// EncodeValues SCALE encodes a list of typed Wasm values.
+func EncodeValues(values []scale.VaryingDataTypeValue) ([]byte, error) {
+	vdts := scale.NewVaryingDataTypeSlice(NewValue())
+	err := vdts.Add(values...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scale.Marshal(vdts)
+}
+
+// DecodeValues decodes a SCALE encoded list of typed Wasm values.
+func DecodeValues(in []byte) ([]scale.VaryingDataTypeValue, error) {
+	vdts := scale.NewVaryingDataTypeSlice(NewValue())
+	err := scale.Unmarshal(in, &vdts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]scale.VaryingDataTypeValue, len(vdts.Types))
+	for i := range vdts.Types {
+		values[i] = vdts.Types[i].Value()
+	}
+	return values, nil
+}
+
+// EncodeReturnValue SCALE encodes the result of a guest function call.
+// A nil value is encoded as ReturnValue::Unit.
+func EncodeReturnValue(value scale.VaryingDataTypeValue) ([]byte, error) {
+	if value == nil {
+		return []byte{0}, nil
+	}
+
+	vdt := NewValue()
+	err := vdt.Set(value)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := scale.Marshal(vdt)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{1}, enc...), nil
+}
+
+// DecodeReturnValue decodes a SCALE encoded ReturnValue.
+// A nil value is returned for ReturnValue::Unit.
+func DecodeReturnValue(in []byte) (scale.VaryingDataTypeValue, error) {
+	if len(in) == 0 {
+		return nil, errors.New("empty return value")
+	}
+
+	switch in[0] {
+	case 0:
+		return nil, nil
+	case 1:
+		vdt := NewValue()
+		err := scale.Unmarshal(in[1:], &vdt)
+		if err != nil {
+			return nil, err
+		}
+		return vdt.Value(), nil
+	default:
+		return nil, fmt.Errorf("invalid return value variant %d", in[0])
+	}
+}
+
+// decodeHostResult decodes the SCALE encoded Result<ReturnValue, HostError>
+// returned by the supervisor's dispatch thunk.
+func decodeHostResult(in []byte) (scale.VaryingDataTypeValue, error) {
+	if len(in) == 0 {
+		return nil, errors.New("empty host result")
+	}
+
+	if in[0] != 0 {
+		return nil, errors.New("supervisor returned a host error")
+	}
+
+	return DecodeReturnValue(in[1:])
+}
+
+// toRaw converts a typed value into the raw representation used by the interpreter,
+// checking that it matches the expected Wasm type.
+func toRaw(value scale.VaryingDataTypeValue, expected wasm.ValueType) (int64, error) {
+	switch v := value.(type) {
+	case I32:
+		if expected == wasm.ValueTypeI32 {
+			return int64(v), nil
+		}
+	case I64:
+		if expected == wasm.ValueTypeI64 {
+			return int64(v), nil
+		}
+	case F32:
+		if expected == wasm.ValueTypeF32 {
+			return int64(v), nil
+		}
+	case F64:
+		if expected == wasm.ValueTypeF64 {
+			return int64(v), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: got %T, expected %s", errUnexpectedValueType, value, expected)
+}
+
+// fromRaw converts a raw interpreter value of the given Wasm type into a typed value.
+func fromRaw(raw int64, typ wasm.ValueType) (scale.VaryingDataTypeValue, error) {
+	switch typ {
+	case wasm.ValueTypeI32:
+		return I32(int32(raw)), nil
+	case wasm.ValueTypeI64:
+		return I64(raw), nil
+	case wasm.ValueTypeF32:
+		return F32(uint32(raw)), nil
+	case wasm.ValueTypeF64:
+		return F64(uint64(raw)), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnexpectedValueType, typ)
+	}
+}
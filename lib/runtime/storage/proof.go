@@ -0,0 +1,271 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+)
+
+var (
+	// ErrProofRecordingInTransaction is returned when starting to record a proof during a storage transaction
+	ErrProofRecordingInTransaction = errors.New("cannot record proof during a storage transaction")
+	// ErrProofRecordingStarted is returned when starting to record a proof while a proof is being recorded
+	ErrProofRecordingStarted = errors.New("proof recording already started")
+)
+
+// ProofRecorder records the trie nodes accessed on a trie state, including the nodes
+// of its child tries, to build a proof of the accesses against the state the trie
+// state had when the recording started.
+type ProofRecorder struct {
+	mutex sync.Mutex
+	// trie is the state trie when the recording started, which is never modified.
+	trie  *trie.Trie
+	root  common.Hash
+	nodes proofNodes
+	// err is the first error encountered while recording nodes.
+	err error
+}
+
+func newProofRecorder(t *trie.Trie) (recorder *ProofRecorder, err error) {
+	root, err := t.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash trie: %w", err)
+	}
+
+	return &ProofRecorder{
+		trie:  t,
+		root:  root,
+		nodes: make(proofNodes),
+	}, nil
+}
+
+// Root returns the state root hash the proof recorded is verified against.
+func (r *ProofRecorder) Root() common.Hash {
+	return r.root
+}
+
+// Proof returns the compact proof, as encoded by trie.EncodeCompactProof, of the trie
+// nodes and of the values stored by hash recorded so far. Nodes inlined in their parent
+// node are not part of the proof. The proof is empty if no node is recorded.
+func (r *ProofRecorder) Proof() (compactProof [][]byte, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	} else if len(r.nodes) == 0 {
+		return [][]byte{}, nil
+	}
+
+	proof := make([][]byte, 0, len(r.nodes))
+	for _, encoding := range r.nodes {
+		proof = append(proof, encoding)
+	}
+
+	compactProof, err = trie.EncodeCompactProof(proof, r.root[:])
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode compact proof: %w", err)
+	}
+	return compactProof, nil
+}
+
+// proofNodes maps the hashes of the trie nodes and values recorded to their encoding.
+type proofNodes map[string][]byte
+
+// Record records the encoding of a trie node or of a value stored by hash.
+func (p proofNodes) Record(hash, rawData []byte) {
+	if len(hash) != common.HashLength {
+		// the node is inlined in its parent
+		return
+	}
+	p[string(hash)] = rawData
+}
+
+// recordKey records the nodes on the path to the key given,
+// in the child trie at keyToChild if it is not nil.
+func (r *ProofRecorder) recordKey(keyToChild, key []byte) {
+	r.record(keyToChild, func(t *trie.Trie) error {
+		return t.RecordPath(key, r.nodes)
+	})
+}
+
+// recordDelete records the nodes on the path to the key given, and the node merged
+// with its parent branch when the key is deleted from the current trie given,
+// in the child trie at keyToChild if it is not nil.
+func (r *ProofRecorder) recordDelete(keyToChild, key []byte, current *trie.Trie) {
+	r.record(keyToChild, func(t *trie.Trie) error {
+		modified, err := getTrie(current, keyToChild)
+		if err != nil {
+			return err
+		} else if modified == nil {
+			return t.RecordPath(key, r.nodes)
+		}
+		return t.RecordDelete(key, modified, r.nodes)
+	})
+}
+
+// recordNextKey records the nodes on the paths to the key given and to the
+// key following it, in the child trie at keyToChild if it is not nil.
+func (r *ProofRecorder) recordNextKey(keyToChild, key []byte) {
+	r.record(keyToChild, func(t *trie.Trie) error {
+		err := t.RecordPath(key, r.nodes)
+		if err != nil {
+			return err
+		}
+
+		nextKey := t.NextKey(key)
+		if nextKey == nil {
+			return nil
+		}
+		return t.RecordPath(nextKey, r.nodes)
+	})
+}
+
+// recordPrefix records the nodes of the keys starting with the prefix given,
+// in the child trie at keyToChild if it is not nil.
+func (r *ProofRecorder) recordPrefix(keyToChild, prefix []byte) {
+	r.record(keyToChild, func(t *trie.Trie) error {
+		return t.RecordPrefix(prefix, r.nodes)
+	})
+}
+
+// recordClearPrefix records the nodes of the keys starting with the prefix given, and
+// the node merged with its parent branch when these keys are deleted from the current
+// trie given, in the child trie at keyToChild if it is not nil.
+func (r *ProofRecorder) recordClearPrefix(keyToChild, prefix []byte, current *trie.Trie) {
+	r.record(keyToChild, func(t *trie.Trie) error {
+		modified, err := getTrie(current, keyToChild)
+		if err != nil {
+			return err
+		} else if modified == nil {
+			return t.RecordPrefix(prefix, r.nodes)
+		}
+		return t.RecordClearPrefix(prefix, modified, r.nodes)
+	})
+}
+
+// getTrie returns the state trie given if keyToChild is nil,
+// and its child trie at keyToChild otherwise.
+func getTrie(stateTrie *trie.Trie, keyToChild []byte) (*trie.Trie, error) {
+	if keyToChild == nil {
+		return stateTrie, nil
+	}
+	return stateTrie.GetChild(keyToChild)
+}
+
+// record runs the record function given on the recorded state trie, or
+// on its child trie at keyToChild if it is not nil, after recording the
+// path to the child trie. Errors are kept to be returned by Proof.
+func (r *ProofRecorder) record(keyToChild []byte, record func(t *trie.Trie) error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	t := r.trie
+	if keyToChild != nil {
+		childKey := append(append([]byte{}, trie.ChildStorageKeyPrefix...), keyToChild...)
+		err := t.RecordPath(childKey, r.nodes)
+		if err != nil {
+			r.err = fmt.Errorf("cannot record path to child trie 0x%x: %w", keyToChild, err)
+			return
+		}
+
+		t, err = t.GetChild(keyToChild)
+		if err != nil || t == nil {
+			// the child trie was created after the recording started
+			return
+		}
+	}
+
+	err := record(t)
+	if err != nil {
+		r.err = fmt.Errorf("cannot record trie nodes: %w", err)
+	}
+}
+
+// StartProofRecording starts recording the trie nodes accessed on the trie state, to build a
+// proof of the accesses against its current state. It returns the proof recorder, which keeps
+// recording until StopProofRecording is called. It cannot be called during a storage transaction.
+func (s *TrieState) StartProofRecording() (recorder *ProofRecorder, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.oldTrie != nil {
+		return nil, ErrProofRecordingInTransaction
+	} else if s.recorder != nil {
+		return nil, ErrProofRecordingStarted
+	}
+
+	recorder, err = newProofRecorder(s.t)
+	if err != nil {
+		return nil, err
+	}
+
+	// modifications are done on a new version of the trie, so the recorded trie stays unchanged.
+	s.t = s.t.Snapshot()
+	s.recorder = recorder
+	return recorder, nil
+}
+
+// StopProofRecording stops the proof recording of the trie state, if any.
+func (s *TrieState) StopProofRecording() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.recorder = nil
+}
+
+// recordKey records the nodes on the path to the key given, in the child trie at
+// keyToChild if it is not nil, if a proof is being recorded.
+// It must be called with the lock held.
+func (s *TrieState) recordKey(keyToChild, key []byte) {
+	if s.recorder != nil {
+		s.recorder.recordKey(keyToChild, key)
+	}
+}
+
+// recordDelete records the nodes on the path to the key given and the node merged with
+// its parent branch once the key is deleted, in the child trie at keyToChild if it is
+// not nil, if a proof is being recorded. It must be called with the lock held, before
+// the key is deleted.
+func (s *TrieState) recordDelete(keyToChild, key []byte) {
+	if s.recorder != nil {
+		s.recorder.recordDelete(keyToChild, key, s.t)
+	}
+}
+
+// recordNextKey records the nodes on the paths to the key given and to the key
+// following it, in the child trie at keyToChild if it is not nil, if a proof is
+// being recorded. It must be called with the lock held.
+func (s *TrieState) recordNextKey(keyToChild, key []byte) {
+	if s.recorder != nil {
+		s.recorder.recordNextKey(keyToChild, key)
+	}
+}
+
+// recordPrefix records the nodes of the keys starting with the prefix given, in the
+// child trie at keyToChild if it is not nil, if a proof is being recorded.
+// It must be called with the lock held.
+func (s *TrieState) recordPrefix(keyToChild, prefix []byte) {
+	if s.recorder != nil {
+		s.recorder.recordPrefix(keyToChild, prefix)
+	}
+}
+
+// recordClearPrefix records the nodes of the keys starting with the prefix given and
+// the node merged with its parent branch once these keys are deleted, in the child
+// trie at keyToChild if it is not nil, if a proof is being recorded. It must be called
+// with the lock held, before the keys are deleted.
+func (s *TrieState) recordClearPrefix(keyToChild, prefix []byte) {
+	if s.recorder != nil {
+		s.recorder.recordClearPrefix(keyToChild, prefix, s.t)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProofTestTrie(t *testing.T) *trie.Trie {
+	t.Helper()
+
+	tr := trie.NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d padded to be hashed", i)))
+		tr.Put([]byte(fmt.Sprintf("other%02d", i)), []byte(fmt.Sprintf("other%02d padded to be hashed", i)))
+	}
+
+	child := trie.NewEmptyTrie()
+	for i := 0; i < 16; i++ {
+		child.Put([]byte(fmt.Sprintf("child%02d", i)), []byte(fmt.Sprintf("child value%02d padded to be hashed", i)))
+	}
+	err := tr.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	return tr
+}
+
+// loadProofTrie loads the state trie with the root given from the compact proof
+// given, with its child trie at keyToChild loaded as well if keyToChild is not nil.
+func loadProofTrie(t *testing.T, compactProof [][]byte, root, keyToChild []byte) *trie.Trie {
+	t.Helper()
+	proof, err := trie.DecodeCompactProof(compactProof, root)
+	require.NoError(t, err)
+	proofTrie := trie.NewEmptyTrie()
+	err = proofTrie.LoadFromProof(proof, root)
+	require.NoError(t, err)
+
+	if keyToChild == nil {
+		return proofTrie
+	}
+
+	childRoot := proofTrie.Get(append(append([]byte{}, trie.ChildStorageKeyPrefix...), keyToChild...))
+	require.NotNil(t, childRoot)
+	child := trie.NewEmptyTrie()
+	err = child.LoadFromProof(proof, childRoot)
+	require.NoError(t, err)
+	err = proofTrie.PutChild(keyToChild, child)
+	require.NoError(t, err)
+	return proofTrie
+}
+
+func TestTrieState_StartProofRecording(t *testing.T) {
+	t.Parallel()
+
+	original := newProofTestTrie(t)
+	ts, err := NewTrieState(original.DeepCopy())
+	require.NoError(t, err)
+	root := ts.MustRoot()
+
+	_ = ts.Get([]byte("other39"))
+
+	recorder, err := ts.StartProofRecording()
+	require.NoError(t, err)
+	assert.Equal(t, root, recorder.Root())
+
+	_, err = ts.StartProofRecording()
+	assert.ErrorIs(t, err, ErrProofRecordingStarted)
+
+	ts.Set([]byte("key01"), []byte("modified"))
+	assert.Equal(t, []byte("modified"), ts.Get([]byte("key01")))
+	assert.Equal(t, []byte("value03 padded to be hashed"), ts.Get([]byte("key03")))
+	assert.Nil(t, ts.Get([]byte("missing")))
+	ts.Delete([]byte("key05"))
+	assert.Equal(t, []byte("key11"), ts.NextKey([]byte("key10")))
+	err = ts.ClearPrefix([]byte("key2"))
+	require.NoError(t, err)
+	value, err := ts.GetChildStorage([]byte("child"), []byte("child07"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("child value07 padded to be hashed"), value)
+
+	ts.StopProofRecording()
+	_ = ts.Get([]byte("other40"))
+
+	proof, err := recorder.Proof()
+	require.NoError(t, err)
+
+	// the accesses made during the recording can be replayed on the proof
+	proofTrie := loadProofTrie(t, proof, root[:], []byte("child"))
+	for _, key := range []string{"key01", "key03", "missing", "key05", "key10", "key11"} {
+		assert.Equal(t, original.Get([]byte(key)), proofTrie.Get([]byte(key)), key)
+	}
+	assert.Equal(t, []byte("key11"), proofTrie.NextKey([]byte("key10")))
+	assert.Equal(t, original.GetKeysWithPrefix([]byte("key2")), proofTrie.GetKeysWithPrefix([]byte("key2")))
+
+	childProofTrie, err := proofTrie.GetChild([]byte("child"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("child value07 padded to be hashed"), childProofTrie.Get([]byte("child07")))
+
+	// the other accesses are not in the proof
+	assert.Nil(t, proofTrie.Get([]byte("other39")))
+	assert.Nil(t, proofTrie.Get([]byte("other40")))
+	assert.Nil(t, childProofTrie.Get([]byte("child08")))
+
+	// the recorded trie is not modified by the trie state
+	originalRoot, err := original.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, originalRoot, root)
+	assert.NotEqual(t, root, ts.MustRoot())
+}
+
+func TestTrieState_StartProofRecording_inTransaction(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestTrieState(t)
+	ts.BeginStorageTransaction()
+
+	_, err := ts.StartProofRecording()
+	assert.ErrorIs(t, err, ErrProofRecordingInTransaction)
+}
+
+func TestProofRecorder_Proof(t *testing.T) {
+	t.Parallel()
+
+	ts, err := NewTrieState(newProofTestTrie(t))
+	require.NoError(t, err)
+
+	recorder, err := ts.StartProofRecording()
+	require.NoError(t, err)
+
+	proof, err := recorder.Proof()
+	require.NoError(t, err)
+	assert.Empty(t, proof)
+
+	_ = ts.Get([]byte("key01"))
+	_ = ts.Get([]byte("key01"))
+	_ = ts.Get([]byte("key02"))
+
+	proof, err = recorder.Proof()
+	require.NoError(t, err)
+	require.NotEmpty(t, proof)
+
+	root := recorder.Root()
+	decodedProof, err := trie.DecodeCompactProof(proof, root[:])
+	require.NoError(t, err)
+
+	hashes := make(map[common.Hash]struct{}, len(decodedProof))
+	for _, encoding := range decodedProof {
+		hash, err := common.Blake2bHash(encoding)
+		require.NoError(t, err)
+		assert.NotContains(t, hashes, hash)
+		hashes[hash] = struct{}{}
+	}
+	assert.Contains(t, hashes, root)
+}
+
+func TestTrieState_StartProofRecording_deletes(t *testing.T) {
+	t.Parallel()
+
+	// values are at least 33 bytes long so their leaves are not inlined in their parent
+	value := func(i int) []byte {
+		return []byte(fmt.Sprintf("value %d padded to be at least 33 bytes long", i))
+	}
+
+	testCases := map[string]struct {
+		// keys are put in the child trie at keyToChild if it is not nil,
+		// in which case the state trie only has the key 0x1000 besides
+		// the child trie key, and in the state trie otherwise.
+		keys       [][]byte
+		keyToChild []byte
+		writes     func(ts *TrieState)
+	}{
+		"delete leaf leaving its parent with one child": {
+			keys: [][]byte{{0x10, 0x00}, {0x20, 0x00, 0x01}, {0x20, 0x00, 0x02}},
+			writes: func(ts *TrieState) {
+				ts.Delete([]byte{0x10, 0x00})
+			},
+		},
+		"delete value of branch with one child": {
+			keys: [][]byte{{0x10}, {0x10, 0x00, 0x01}, {0x10, 0x00, 0x02}, {0x20}},
+			writes: func(ts *TrieState) {
+				ts.Delete([]byte{0x10})
+			},
+		},
+		"delete leaves one by one": {
+			keys: [][]byte{{0x01}, {0x02}, {0x03}, {0x04, 0x01}, {0x04, 0x02}},
+			writes: func(ts *TrieState) {
+				ts.Delete([]byte{0x01})
+				ts.Delete([]byte{0x02})
+				ts.Delete([]byte{0x03})
+			},
+		},
+		"clear prefix leaving its parent with one child": {
+			keys: [][]byte{{0x10, 0x00}, {0x10, 0x01}, {0x20, 0x00, 0x01}, {0x20, 0x00, 0x02}},
+			writes: func(ts *TrieState) {
+				err := ts.ClearPrefix([]byte{0x10})
+				require.NoError(t, err)
+			},
+		},
+		"delete in child trie": {
+			keys:       [][]byte{{0x10, 0x00}, {0x20, 0x00, 0x01}, {0x20, 0x00, 0x02}},
+			keyToChild: []byte("child"),
+			writes: func(ts *TrieState) {
+				err := ts.ClearChildStorage([]byte("child"), []byte{0x10, 0x00})
+				require.NoError(t, err)
+			},
+		},
+		"clear prefix in child trie": {
+			keys:       [][]byte{{0x10, 0x00}, {0x10, 0x01}, {0x20, 0x00, 0x01}, {0x20, 0x00, 0x02}},
+			keyToChild: []byte("child"),
+			writes: func(ts *TrieState) {
+				err := ts.ClearPrefixInChild([]byte("child"), []byte{0x10})
+				require.NoError(t, err)
+			},
+		},
+		"delete child trie": {
+			keys:       [][]byte{{0x10, 0x00}},
+			keyToChild: []byte("child"),
+			writes: func(ts *TrieState) {
+				ts.DeleteChild([]byte("child"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tr := trie.NewEmptyTrie()
+			if testCase.keyToChild == nil {
+				for i, key := range testCase.keys {
+					tr.Put(key, value(i))
+				}
+			} else {
+				tr.Put([]byte{0x10, 0x00}, value(0))
+				child := trie.NewEmptyTrie()
+				for i, key := range testCase.keys {
+					child.Put(key, value(i))
+				}
+				err := tr.PutChild(testCase.keyToChild, child)
+				require.NoError(t, err)
+			}
+
+			ts, err := NewTrieState(tr)
+			require.NoError(t, err)
+			recorder, err := ts.StartProofRecording()
+			require.NoError(t, err)
+
+			testCase.writes(ts)
+
+			proof, err := recorder.Proof()
+			require.NoError(t, err)
+			root := recorder.Root()
+
+			// the writes replayed on the proof give the same state root
+			proofTrie := loadProofTrie(t, proof, root[:], testCase.keyToChild)
+			proofState, err := NewTrieState(proofTrie)
+			require.NoError(t, err)
+			testCase.writes(proofState)
+
+			assert.Equal(t, ts.MustRoot(), proofState.MustRoot())
+
+			if testCase.keyToChild == nil {
+				return
+			}
+
+			// the child trie root in the state trie is not updated by the deletes in the child trie
+			child, err := ts.GetChild(testCase.keyToChild)
+			if errors.Is(err, trie.ErrChildTrieDoesNotExist) {
+				return
+			}
+			require.NoError(t, err)
+			proofChild, err := proofState.GetChild(testCase.keyToChild)
+			require.NoError(t, err)
+			assert.Equal(t, child.MustHash(), proofChild.MustHash())
+		})
+	}
+}
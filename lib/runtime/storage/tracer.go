@@ -0,0 +1,134 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"bytes"
+	"sync"
+)
+
+// TraceOperation is the kind of a storage access traced
+type TraceOperation string
+
+const (
+	// TraceGet is a read of the value stored at a key
+	TraceGet TraceOperation = "get"
+	// TraceSet is a write of the value stored at a key
+	TraceSet TraceOperation = "set"
+	// TraceClear is a removal of the value stored at a key
+	TraceClear TraceOperation = "clear"
+	// TraceClearPrefix is a removal of the values stored at keys starting with a prefix
+	TraceClearPrefix TraceOperation = "clear_prefix"
+	// TraceClearChild is a removal of a child trie
+	TraceClearChild TraceOperation = "clear_child"
+	// TraceNextKey is a lookup of the key following a key
+	TraceNextKey TraceOperation = "next_key"
+)
+
+// TraceEvent is a storage access made by the runtime
+type TraceEvent struct {
+	// Call is the exported runtime function executing when the storage was accessed,
+	// and is empty for accesses made outside of a runtime call.
+	Call      string
+	Operation TraceOperation
+	// ChildKey is the key of the child trie accessed, and is nil for the main trie.
+	ChildKey []byte
+	// Key is the key accessed, or the prefix for clear_prefix operations.
+	Key []byte
+	// ValueSize is the size of the value read or written, and is nil if there is no value.
+	ValueSize *uint32
+}
+
+// HasKeyPrefix returns true if the key, or the child key for child trie accesses,
+// starts with one of the prefixes given, or if no prefix is given.
+func (e TraceEvent) HasKeyPrefix(prefixes [][]byte) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(e.Key, prefix) ||
+			(e.ChildKey != nil && bytes.HasPrefix(e.ChildKey, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tracer records the storage accesses made on the trie states it is set on
+type Tracer struct {
+	mutex  sync.Mutex
+	call   string
+	events []TraceEvent
+}
+
+// NewTracer returns a new storage tracer
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// SetCall sets the exported runtime function recorded with the following storage accesses
+func (t *Tracer) SetCall(function string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.call = function
+}
+
+// Events returns the storage accesses recorded so far
+func (t *Tracer) Events() []TraceEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	events := make([]TraceEvent, len(t.events))
+	copy(events, t.events)
+	return events
+}
+
+func (t *Tracer) record(operation TraceOperation, childKey, key, value []byte, hasValue bool) {
+	event := TraceEvent{
+		Operation: operation,
+		ChildKey:  copyBytes(childKey),
+		Key:       copyBytes(key),
+	}
+	if hasValue {
+		size := uint32(len(value))
+		event.ValueSize = &size
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	event.Call = t.call
+	t.events = append(t.events, event)
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// SetTracer sets the tracer recording the storage accesses made on the trie state.
+// Tracing is disabled if the tracer is nil.
+func (s *TrieState) SetTracer(tracer *Tracer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tracer = tracer
+}
+
+// TraceCall sets the exported runtime function recorded with the following
+// storage accesses, if tracing is enabled.
+func (s *TrieState) TraceCall(function string) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.tracer != nil {
+		s.tracer.SetCall(function)
+	}
+}
+
+// trace records a storage access if tracing is enabled. It must be called with the lock held.
+func (s *TrieState) trace(operation TraceOperation, childKey, key, value []byte, hasValue bool) {
+	if s.tracer != nil {
+		s.tracer.record(operation, childKey, key, value, hasValue)
+	}
+}
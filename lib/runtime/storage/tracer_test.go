@@ -0,0 +1,100 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieState_SetTracer(t *testing.T) {
+	ts := newTestTrieState(t)
+	ts.Set([]byte("untraced"), []byte{1})
+
+	tracer := NewTracer()
+	ts.SetTracer(tracer)
+
+	child := trie.NewEmptyTrie()
+	err := ts.SetChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	_ = ts.Get([]byte("untraced"))
+	ts.TraceCall("Core_execute_block")
+	ts.Set([]byte("key"), []byte{1, 2})
+	_ = ts.Get([]byte("missing"))
+	_ = ts.NextKey([]byte("key"))
+	ts.Delete([]byte("key"))
+	err = ts.ClearPrefix([]byte("k"))
+	require.NoError(t, err)
+	err = ts.SetChildStorage([]byte("child"), []byte("a"), []byte{3})
+	require.NoError(t, err)
+	_, err = ts.GetChildStorage([]byte("child"), []byte("a"))
+	require.NoError(t, err)
+	ts.DeleteChild([]byte("child"))
+	ts.TraceCall("")
+
+	ts.SetTracer(nil)
+	_ = ts.Get([]byte("key"))
+
+	one, two := uint32(1), uint32(2)
+	expected := []TraceEvent{
+		{Operation: TraceGet, Key: []byte("untraced"), ValueSize: &one},
+		{Call: "Core_execute_block", Operation: TraceSet, Key: []byte("key"), ValueSize: &two},
+		{Call: "Core_execute_block", Operation: TraceGet, Key: []byte("missing")},
+		{Call: "Core_execute_block", Operation: TraceNextKey, Key: []byte("key")},
+		{Call: "Core_execute_block", Operation: TraceClear, Key: []byte("key")},
+		{Call: "Core_execute_block", Operation: TraceClearPrefix, Key: []byte("k")},
+		{Call: "Core_execute_block", Operation: TraceSet, ChildKey: []byte("child"), Key: []byte("a"), ValueSize: &one},
+		{Call: "Core_execute_block", Operation: TraceGet, ChildKey: []byte("child"), Key: []byte("a"), ValueSize: &one},
+		{Call: "Core_execute_block", Operation: TraceClearChild, ChildKey: []byte("child")},
+	}
+	events := tracer.Events()
+	assert.Equal(t, expected, events)
+
+	// the returned events are a copy
+	events[0].Call = "modified"
+	assert.Equal(t, expected, tracer.Events())
+}
+
+func TestTraceEvent_HasKeyPrefix(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		event    TraceEvent
+		prefixes [][]byte
+		ok       bool
+	}{
+		"no prefix": {
+			event: TraceEvent{Key: []byte{1}},
+			ok:    true,
+		},
+		"key prefix": {
+			event:    TraceEvent{Key: []byte{1, 2}},
+			prefixes: [][]byte{{2}, {1}},
+			ok:       true,
+		},
+		"child key prefix": {
+			event:    TraceEvent{ChildKey: []byte{2, 3}, Key: []byte{1}},
+			prefixes: [][]byte{{2}},
+			ok:       true,
+		},
+		"no matching prefix": {
+			event:    TraceEvent{Key: []byte{1, 2}},
+			prefixes: [][]byte{{2}, {1, 3}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ok := testCase.event.HasKeyPrefix(testCase.prefixes)
+			assert.Equal(t, testCase.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// IndexOperation is a transaction index operation requested by the runtime while executing a block
+type IndexOperation struct {
+	// Extrinsic is the index of the extrinsic in the block body
+	Extrinsic uint32
+	// Hash is the content hash of the indexed data
+	Hash common.Hash
+	// Size is the number of bytes at the end of the extrinsic to index. It is unused for renewals.
+	Size uint32
+	// Renew is true if the operation renews data that was previously indexed
+	Renew bool
+}
+
+// IndexTransaction records that the last `size` bytes of the given extrinsic should be indexed under `hash`
+func (s *TrieState) IndexTransaction(extrinsic, size uint32, hash common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.indexOps = append(s.indexOps, IndexOperation{
+		Extrinsic: extrinsic,
+		Hash:      hash,
+		Size:      size,
+	})
+}
+
+// RenewTransaction records that the indexed data with the given hash should be retained for longer
+func (s *TrieState) RenewTransaction(extrinsic uint32, hash common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.indexOps = append(s.indexOps, IndexOperation{
+		Extrinsic: extrinsic,
+		Hash:      hash,
+		Renew:     true,
+	})
+}
+
+// TransactionIndexOperations returns the transaction index operations recorded so far
+func (s *TrieState) TransactionIndexOperations() []IndexOperation {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	ops := make([]IndexOperation, len(s.indexOps))
+	copy(ops, s.indexOps)
+	return ops
+}
+
+// ClearTransactionIndexOperations removes the transaction index operations recorded so far,
+// so only the operations requested while executing the next block are recorded.
+func (s *TrieState) ClearTransactionIndexOperations() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.indexOps = nil
+	s.oldIndexOps = nil
+}
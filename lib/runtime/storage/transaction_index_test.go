@@ -0,0 +1,57 @@
+// Copyright 2021 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieState_TransactionIndexOperations(t *testing.T) {
+	ts := newTestTrieState(t)
+	require.Empty(t, ts.TransactionIndexOperations())
+
+	hashA := common.Hash{1}
+	hashB := common.Hash{2}
+	ts.IndexTransaction(1, 32, hashA)
+	ts.RenewTransaction(2, hashB)
+
+	expected := []IndexOperation{
+		{Extrinsic: 1, Hash: hashA, Size: 32},
+		{Extrinsic: 2, Hash: hashB, Renew: true},
+	}
+	ops := ts.TransactionIndexOperations()
+	require.Equal(t, expected, ops)
+
+	// the returned operations are a copy
+	ops[0].Size = 0
+	require.Equal(t, expected, ts.TransactionIndexOperations())
+}
+
+func TestTrieState_TransactionIndexOperations_storageTransactions(t *testing.T) {
+	ts := newTestTrieState(t)
+	hashA := common.Hash{1}
+	hashB := common.Hash{2}
+	ts.IndexTransaction(1, 32, hashA)
+
+	// the operations requested in a rolled back storage transaction are removed
+	ts.BeginStorageTransaction()
+	ts.RenewTransaction(2, hashB)
+	ts.RollbackStorageTransaction()
+	require.Equal(t, []IndexOperation{{Extrinsic: 1, Hash: hashA, Size: 32}}, ts.TransactionIndexOperations())
+
+	ts.BeginStorageTransaction()
+	ts.RenewTransaction(2, hashB)
+	ts.CommitStorageTransaction()
+	expected := []IndexOperation{
+		{Extrinsic: 1, Hash: hashA, Size: 32},
+		{Extrinsic: 2, Hash: hashB, Renew: true},
+	}
+	require.Equal(t, expected, ts.TransactionIndexOperations())
+
+	ts.ClearTransactionIndexOperations()
+	require.Empty(t, ts.TransactionIndexOperations())
+}
@@ -18,6 +18,24 @@ type TrieState struct {
 	t       *trie.Trie
 	oldTrie *trie.Trie // this is the trie before BeginStorageTransaction is called. set to nil if it isn't called
 	lock    sync.RWMutex
+
+	indexOps    []IndexOperation // transaction index operations requested by the runtime
+	oldIndexOps []IndexOperation // indexOps before BeginStorageTransaction is called
+	tracer      *Tracer          // records the storage accesses if not nil
+	recorder    *ProofRecorder   // records the trie nodes accessed if not nil
+
+	// flatState is read from for the values of the keys whose trie nodes are not
+	// loaded, at the state root flatRoot of the trie when flatState was set.
+	flatState FlatState
+	flatRoot  common.Hash
+}
+
+// FlatState is a flat key-value view of state tries, used to read values
+// without decoding trie nodes from the database.
+type FlatState interface {
+	// Get returns the value at the key given in the state trie with the state
+	// root given, and false if the state root is not covered by the flat state.
+	Get(root common.Hash, key []byte) (value []byte, covered bool, err error)
 }
 
 // NewTrieState returns a new TrieState with the given trie
@@ -33,6 +51,17 @@ func NewTrieState(t *trie.Trie) (*TrieState, error) {
 	return ts, nil
 }
 
+// SetFlatState sets the flat state to read the values of the keys whose trie
+// nodes are not loaded from, for the state root given which must be the state
+// root of the trie. The nodes not loaded are not modified, so the values of
+// their keys are the values at this state root even once the trie is modified.
+func (s *TrieState) SetFlatState(flatState FlatState, root common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.flatState = flatState
+	s.flatRoot = root
+}
+
 // Trie returns the TrieState's underlying trie
 func (s *TrieState) Trie() *trie.Trie {
 	return s.t
@@ -52,6 +81,7 @@ func (s *TrieState) BeginStorageTransaction() {
 	defer s.lock.Unlock()
 	s.oldTrie = s.t
 	s.t = s.t.Snapshot()
+	s.oldIndexOps = append([]IndexOperation(nil), s.indexOps...)
 }
 
 // CommitStorageTransaction commits all storage changes made since BeginStorageTransaction was called.
@@ -59,6 +89,7 @@ func (s *TrieState) CommitStorageTransaction() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.oldTrie = nil
+	s.oldIndexOps = nil
 }
 
 // RollbackStorageTransaction rolls back all storage changes made since BeginStorageTransaction was called.
@@ -67,12 +98,16 @@ func (s *TrieState) RollbackStorageTransaction() {
 	defer s.lock.Unlock()
 	s.t = s.oldTrie
 	s.oldTrie = nil
+	s.indexOps = s.oldIndexOps
+	s.oldIndexOps = nil
 }
 
 // Set sets a key-value pair in the trie
 func (s *TrieState) Set(key, value []byte) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceSet, nil, key, value, true)
+	s.recordKey(nil, key)
 	s.t.Put(key, value)
 }
 
@@ -80,7 +115,31 @@ func (s *TrieState) Set(key, value []byte) {
 func (s *TrieState) Get(key []byte) []byte {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	return s.t.Get(key)
+	value := s.get(key)
+	s.trace(TraceGet, nil, key, value, value != nil)
+	s.recordKey(nil, key)
+	return value
+}
+
+// get returns the value of the key given, read from the flat state if
+// it is set and the trie nodes on the path to the key are not loaded.
+func (s *TrieState) get(key []byte) (value []byte) {
+	if s.flatState == nil {
+		return s.t.Get(key)
+	}
+
+	value, loaded := s.t.GetLoaded(key)
+	if loaded {
+		return value
+	}
+
+	value, covered, err := s.flatState.Get(s.flatRoot, key)
+	if err != nil || !covered {
+		// the state root is no longer covered by the flat state,
+		// or the flat state cannot be read, so the trie is read.
+		return s.t.Get(key)
+	}
+	return value
 }
 
 // MustRoot returns the trie's root hash. It panics if it fails to compute the root.
@@ -88,6 +147,14 @@ func (s *TrieState) MustRoot() common.Hash {
 	return s.t.MustHash()
 }
 
+// SetVersion sets the state trie version used to
+// encode the trie nodes when computing the root hash.
+func (s *TrieState) SetVersion(version trie.Version) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.t.SetVersion(version)
+}
+
 // Root returns the trie's root hash
 func (s *TrieState) Root() (common.Hash, error) {
 	return s.t.Hash()
@@ -100,13 +167,16 @@ func (s *TrieState) Has(key []byte) bool {
 
 // Delete deletes a key from the trie
 func (s *TrieState) Delete(key []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.trace(TraceClear, nil, key, nil, false)
+	s.recordDelete(nil, key)
+
 	val := s.t.Get(key)
 	if val == nil {
 		return
 	}
 
-	s.lock.Lock()
-	defer s.lock.Unlock()
 	s.t.Delete(key)
 }
 
@@ -114,6 +184,8 @@ func (s *TrieState) Delete(key []byte) {
 func (s *TrieState) NextKey(key []byte) []byte {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
+	s.trace(TraceNextKey, nil, key, nil, false)
+	s.recordNextKey(nil, key)
 	return s.t.NextKey(key)
 }
 
@@ -121,6 +193,8 @@ func (s *TrieState) NextKey(key []byte) []byte {
 func (s *TrieState) ClearPrefix(prefix []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClearPrefix, nil, prefix, nil, false)
+	s.recordClearPrefix(nil, prefix)
 	s.t.ClearPrefix(prefix)
 	return nil
 }
@@ -129,6 +203,8 @@ func (s *TrieState) ClearPrefix(prefix []byte) error {
 func (s *TrieState) ClearPrefixLimit(prefix []byte, limit uint32) (uint32, bool) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClearPrefix, nil, prefix, nil, false)
+	s.recordClearPrefix(nil, prefix)
 
 	num, del := s.t.ClearPrefixLimit(prefix, limit)
 	return num, del
@@ -145,6 +221,7 @@ func (s *TrieState) TrieEntries() map[string][]byte {
 func (s *TrieState) SetChild(keyToChild []byte, child *trie.Trie) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.recordKey(nil, append(append([]byte{}, trie.ChildStorageKeyPrefix...), keyToChild...))
 	return s.t.PutChild(keyToChild, child)
 }
 
@@ -152,6 +229,8 @@ func (s *TrieState) SetChild(keyToChild []byte, child *trie.Trie) error {
 func (s *TrieState) SetChildStorage(keyToChild, key, value []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceSet, keyToChild, key, value, true)
+	s.recordKey(keyToChild, key)
 	return s.t.PutIntoChild(keyToChild, key, value)
 }
 
@@ -159,6 +238,7 @@ func (s *TrieState) SetChildStorage(keyToChild, key, value []byte) error {
 func (s *TrieState) GetChild(keyToChild []byte) (*trie.Trie, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
+	s.recordPrefix(keyToChild, nil)
 	return s.t.GetChild(keyToChild)
 }
 
@@ -166,13 +246,19 @@ func (s *TrieState) GetChild(keyToChild []byte) (*trie.Trie, error) {
 func (s *TrieState) GetChildStorage(keyToChild, key []byte) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	return s.t.GetFromChild(keyToChild, key)
+	value, err := s.t.GetFromChild(keyToChild, key)
+	s.trace(TraceGet, keyToChild, key, value, value != nil)
+	s.recordKey(keyToChild, key)
+	return value, err
 }
 
 // DeleteChild deletes a child trie from the main trie
 func (s *TrieState) DeleteChild(key []byte) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClearChild, key, nil, nil, false)
+	s.recordPrefix(key, nil)
+	s.recordDelete(nil, append(append([]byte{}, trie.ChildStorageKeyPrefix...), key...))
 	s.t.DeleteChild(key)
 }
 
@@ -181,12 +267,15 @@ func (s *TrieState) DeleteChild(key []byte) {
 func (s *TrieState) DeleteChildLimit(key []byte, limit *[]byte) (uint32, bool, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClearChild, key, nil, nil, false)
+	s.recordPrefix(key, nil)
 	tr, err := s.t.GetChild(key)
 	if err != nil {
 		return 0, false, err
 	}
 	qtyEntries := uint32(len(tr.Entries()))
 	if limit == nil {
+		s.recordDelete(nil, append(append([]byte{}, trie.ChildStorageKeyPrefix...), key...))
 		s.t.DeleteChild(key)
 		return qtyEntries, true, nil
 	}
@@ -217,6 +306,8 @@ func (s *TrieState) DeleteChildLimit(key []byte, limit *[]byte) (uint32, bool, e
 func (s *TrieState) ClearChildStorage(keyToChild, key []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClear, keyToChild, key, nil, false)
+	s.recordDelete(keyToChild, key)
 	return s.t.ClearFromChild(keyToChild, key)
 }
 
@@ -224,6 +315,8 @@ func (s *TrieState) ClearChildStorage(keyToChild, key []byte) error {
 func (s *TrieState) ClearPrefixInChild(keyToChild, prefix []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.trace(TraceClearPrefix, keyToChild, prefix, nil, false)
+	s.recordClearPrefix(keyToChild, prefix)
 
 	child, err := s.t.GetChild(keyToChild)
 	if err != nil {
@@ -241,6 +334,8 @@ func (s *TrieState) ClearPrefixInChild(keyToChild, prefix []byte) error {
 func (s *TrieState) GetChildNextKey(keyToChild, key []byte) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
+	s.trace(TraceNextKey, keyToChild, key, nil, false)
+	s.recordNextKey(keyToChild, key)
 	child, err := s.t.GetChild(keyToChild)
 	if err != nil {
 		return nil, err
@@ -6,10 +6,13 @@
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/ChainSafe/chaindb"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/stretchr/testify/require"
@@ -76,6 +79,22 @@ func TestTrieState_Root(t *testing.T) {
 	testFunc(ts)
 }
 
+func TestTrieState_SetVersion(t *testing.T) {
+	ts := newTestTrieState(t)
+	ts.Set([]byte("key"), bytes.Repeat([]byte{1}, 33))
+
+	rootV0 := ts.MustRoot()
+
+	ts.SetVersion(trie.V1)
+	rootV1 := ts.MustRoot()
+	require.NotEqual(t, rootV0, rootV1)
+
+	expected := trie.NewEmptyTrie()
+	expected.SetVersion(trie.V1)
+	expected.Put([]byte("key"), bytes.Repeat([]byte{1}, 33))
+	require.Equal(t, expected.MustHash(), rootV1)
+}
+
 func TestTrieState_ClearPrefix(t *testing.T) {
 	ts := newTestTrieState(t)
 
@@ -239,3 +258,79 @@ func TestTrieState_DeleteChildLimit(t *testing.T) {
 		require.Equal(t, test.expectedDelAll, all)
 	}
 }
+
+type testFlatState struct {
+	root    common.Hash
+	values  map[string][]byte
+	err     error
+	keysGot []string
+}
+
+func (f *testFlatState) Get(root common.Hash, key []byte) (value []byte, covered bool, err error) {
+	f.keysGot = append(f.keysGot, string(key))
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return f.values[string(key)], root == f.root, nil
+}
+
+func TestTrieState_SetFlatState(t *testing.T) {
+	db, err := chaindb.NewBadgerDB(&chaindb.Config{
+		InMemory: true,
+		DataDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	// the values are unique to each run, so the nodes are
+	// not found in the decoded nodes cache of the lazy tries.
+	salt := time.Now().UnixNano()
+	tr := trie.NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d padded to be hashed %d", i, salt)))
+	}
+	err = tr.Store(db)
+	require.NoError(t, err)
+	root := tr.MustHash()
+
+	newLazyTrieState := func(flatState FlatState) *TrieState {
+		lazyTrie, err := trie.NewLazyTrie(db, root)
+		require.NoError(t, err)
+		ts, err := NewTrieState(lazyTrie)
+		require.NoError(t, err)
+		ts.SetFlatState(flatState, root)
+		return ts
+	}
+
+	flatState := &testFlatState{
+		root: root,
+		values: map[string][]byte{
+			"key01": []byte("flat value01"),
+			"key02": []byte("flat value02"),
+		},
+	}
+	ts := newLazyTrieState(flatState)
+
+	// the nodes on the path to the key are not loaded, so the value is read from the flat state
+	require.Equal(t, []byte("flat value01"), ts.Get([]byte("key01")))
+
+	// the value of a modified key is read from the trie
+	ts.Set([]byte("key02"), []byte("modified"))
+	require.Equal(t, []byte("modified"), ts.Get([]byte("key02")))
+	require.Equal(t, []string{"key01"}, flatState.keysGot)
+
+	// the trie is read if the state root is not covered by the flat state
+	flatState = &testFlatState{root: common.Hash{1}}
+	ts = newLazyTrieState(flatState)
+	require.Equal(t, tr.Get([]byte("key03")), ts.Get([]byte("key03")))
+	require.Equal(t, []string{"key03"}, flatState.keysGot)
+
+	// the trie is read if the flat state cannot be read
+	flatState = &testFlatState{root: root, err: errors.New("test error")}
+	ts = newLazyTrieState(flatState)
+	require.Equal(t, tr.Get([]byte("key04")), ts.Get([]byte("key04")))
+	require.Equal(t, []string{"key04"}, flatState.keysGot)
+}
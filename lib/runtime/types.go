@@ -9,6 +9,7 @@
 	"github.com/ChainSafe/gossamer/lib/crypto"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime/offchain"
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
 )
 
 // NodeStorageType type to identify offchain storage type
@@ -70,6 +71,10 @@ type Context struct {
 	Transaction     TransactionState
 	SigVerifier     *crypto.SignatureVerifier
 	OffchainHTTPSet *offchain.HTTPSet
+	Sandbox         *sandbox.Store
+	// Supervisor calls the runtime's dispatch thunk for the sandboxed guests,
+	// it is set by the executor when the runtime code is instantiated.
+	Supervisor sandbox.Supervisor
 }
 
 // NewValidateTransactionError returns an error based on a return value from TaggedTransactionQueueValidateTransaction
@@ -4,6 +4,10 @@
 package runtime
 
 import (
+	"bytes"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 )
 
@@ -18,9 +22,39 @@ type Version interface {
 	ImplVersion() uint32
 	APIItems() []APIItem
 	TransactionVersion() uint32
+	StateVersion() uint8
 	Encode() ([]byte, error)
 }
 
+// coreVersionWithStateVersion is the first version of the Core runtime API
+// returning the state trie version at the end of the runtime version.
+const coreVersionWithStateVersion = 4
+
+// hasStateVersion returns true if the API items given contain
+// the Core runtime API with a version returning the state trie version.
+func hasStateVersion(apiItems []APIItem) bool {
+	version, ok := APIVersion(apiItems, CoreAPI)
+	return ok && version >= coreVersionWithStateVersion
+}
+
+// SetStorageStateVersion sets the state trie version of the storage given to the
+// state version of the runtime instance given, so the state root of a block
+// executed or built on this storage is calculated with the runtime's version.
+func SetStorageStateVersion(instance Instance, storage Storage) error {
+	version, err := instance.Version()
+	if err != nil {
+		return fmt.Errorf("cannot get runtime version: %w", err)
+	}
+
+	stateVersion, err := trie.ParseVersion(uint32(version.StateVersion()))
+	if err != nil {
+		return err
+	}
+
+	storage.SetVersion(stateVersion)
+	return nil
+}
+
 // APIItem struct to hold runtime API Name and Version
 type APIItem struct {
 	Name [8]byte
@@ -86,6 +120,11 @@ func (lvd *LegacyVersionData) TransactionVersion() uint32 {
 	return 0
 }
 
+// StateVersion returns the state trie version, which is always 0 for legacy runtimes
+func (lvd *LegacyVersionData) StateVersion() uint8 {
+	return 0
+}
+
 type legacyVersionData struct {
 	SpecName         []byte
 	ImplName         []byte
@@ -140,6 +179,7 @@ type VersionData struct {
 	implVersion        uint32
 	apiItems           []APIItem
 	transactionVersion uint32
+	stateVersion       uint8
 }
 
 // NewVersionData returns a new VersionData
@@ -192,6 +232,16 @@ func (vd *VersionData) TransactionVersion() uint32 {
 	return vd.transactionVersion
 }
 
+// StateVersion returns the state trie version
+func (vd *VersionData) StateVersion() uint8 {
+	return vd.stateVersion
+}
+
+// SetStateVersion sets the state trie version
+func (vd *VersionData) SetStateVersion(stateVersion uint8) {
+	vd.stateVersion = stateVersion
+}
+
 type versionData struct {
 	SpecName           []byte
 	ImplName           []byte
@@ -218,17 +268,32 @@ func (vd *VersionData) Encode() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	// the state version is only encoded from version 4 of the Core runtime API
+	if hasStateVersion(vd.apiItems) {
+		enc = append(enc, vd.stateVersion)
+	}
+
 	return enc, nil
 }
 
 // Decode to scale decode []byte to VersionAPI struct
 func (vd *VersionData) Decode(in []byte) error {
 	var info versionData
-	err := scale.Unmarshal(in, &info)
+	decoder := scale.NewDecoder(bytes.NewReader(in))
+	err := decoder.Decode(&info)
 	if err != nil {
 		return err
 	}
 
+	var stateVersion uint8
+	if hasStateVersion(info.APIItems) {
+		err = decoder.Decode(&stateVersion)
+		if err != nil {
+			return fmt.Errorf("cannot decode state version: %w", err)
+		}
+	}
+
 	vd.specName = info.SpecName
 	vd.implName = info.ImplName
 	vd.authoringVersion = info.AuthoringVersion
@@ -236,6 +301,7 @@ func (vd *VersionData) Decode(in []byte) error {
 	vd.implVersion = info.ImplVersion
 	vd.apiItems = info.APIItems
 	vd.transactionVersion = info.TransactionVersion
+	vd.stateVersion = stateVersion
 
 	return nil
 }
@@ -57,3 +57,42 @@ func TestLegacyVersionData(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, version, dec)
 }
+
+func TestVersionData_StateVersion(t *testing.T) {
+	coreAPIItem := APIItem{
+		Name: [8]byte{0xdf, 0x6a, 0xcb, 0x68, 0x99, 0x07, 0x60, 0x9b},
+		Ver:  4,
+	}
+
+	version := NewVersionData(
+		[]byte("polkadot"),
+		[]byte("parity-polkadot"),
+		0,
+		9250,
+		0,
+		[]APIItem{coreAPIItem},
+		12,
+	)
+	version.SetStateVersion(1)
+
+	b, err := version.Encode()
+	require.NoError(t, err)
+	require.Equal(t, byte(1), b[len(b)-1])
+
+	dec := new(VersionData)
+	err = dec.Decode(b)
+	require.NoError(t, err)
+	require.Equal(t, version, dec)
+	require.Equal(t, uint8(1), dec.StateVersion())
+
+	// the state version is not encoded before version 4 of the Core API
+	coreAPIItem.Ver = 3
+	version = NewVersionData(nil, nil, 0, 0, 0, []APIItem{coreAPIItem}, 0)
+	b, err = version.Encode()
+	require.NoError(t, err)
+
+	dec = new(VersionData)
+	err = dec.Decode(b)
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), dec.StateVersion())
+}
@@ -0,0 +1,363 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package wasmer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	wasm "github.com/wasmerio/go-ext-wasm/wasmer"
+)
+
+// DefaultCacheMaxSize is the default maximum size in bytes of the compiled runtime cache.
+const DefaultCacheMaxSize = int64(1 << 30)
+
+const (
+	wasmerModulePath    = "github.com/wasmerio/go-ext-wasm"
+	cacheEntryExtension = ".wasmer"
+)
+
+// cacheEntryMagic prefixes the cache entry files, it must be changed if the entry format changes.
+var cacheEntryMagic = []byte("gsmrwc01")
+
+var (
+	errCacheEntryNotValid    = errors.New("cache entry not valid")
+	errCacheEntryChecksum    = errors.New("cache entry checksum mismatch")
+	errCacheEntryDeserialize = errors.New("cannot deserialize cache entry")
+	errImportsNotReadable    = errors.New("cannot read imports")
+)
+
+var (
+	defaultCacheMutex sync.RWMutex
+	defaultCache      *Cache
+)
+
+// SetDefaultCache sets the compiled runtime cache used by the instances
+// created without a cache in their configuration. A nil cache disables it.
+func SetDefaultCache(cache *Cache) {
+	defaultCacheMutex.Lock()
+	defer defaultCacheMutex.Unlock()
+	defaultCache = cache
+}
+
+func getDefaultCache() *Cache {
+	defaultCacheMutex.RLock()
+	defer defaultCacheMutex.RUnlock()
+	return defaultCache
+}
+
+// Cache is an on-disk cache of compiled runtime modules, so runtime code is only
+// compiled once. Entries are keyed by the blake2b hash of the code, the wasmer
+// version and the signatures of the host functions given to the runtime, and are checksummed so
+// corrupted entries are compiled again. The least recently used entries are
+// removed when the cache grows over its maximum size.
+type Cache struct {
+	directory string
+	maxSize   int64
+	mutex     sync.Mutex
+}
+
+// NewCache creates a compiled runtime cache in the given directory,
+// holding at most maxSize bytes of compiled modules.
+func NewCache(directory string, maxSize int64) (*Cache, error) {
+	err := os.MkdirAll(directory, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create cache directory: %w", err)
+	}
+
+	return &Cache{
+		directory: directory,
+		maxSize:   maxSize,
+	}, nil
+}
+
+// Module returns the compiled module of the given decompressed code, to be instantiated
+// with the given imports. The module is loaded from the
+// cache if it is found and valid, otherwise it is compiled and stored in the cache.
+// The module must be closed by the caller once instantiated.
+func (c *Cache) Module(code []byte, imports *wasm.Imports) (module wasm.Module, err error) {
+	codeHash, err := common.Blake2bHash(code)
+	if err != nil {
+		return module, fmt.Errorf("cannot hash code: %w", err)
+	}
+
+	key, err := cacheKey(imports)
+	if err != nil {
+		return module, fmt.Errorf("cannot compute cache key: %w", err)
+	}
+
+	path := c.entryPath(codeHash, key)
+	module, found := c.load(path, codeHash)
+	if found {
+		return module, nil
+	}
+
+	// the code is compiled without holding the mutex,
+	// so other runtimes are loaded meanwhile.
+	module, err = wasm.Compile(code)
+	if err != nil {
+		return module, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err = c.store(path, codeHash, module)
+	if err != nil {
+		logger.Warnf("cannot store compiled runtime in cache: %s", err)
+	}
+
+	return module, nil
+}
+
+// load loads the compiled module from the cache entry path, and returns false if
+// the entry is not found or is not valid, in which case the entry is removed.
+func (c *Cache) load(path string, codeHash common.Hash) (module wasm.Module, found bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	module, err := loadCacheEntry(path)
+	if err == nil {
+		logger.Debugf("loaded compiled runtime with code hash %s from cache", codeHash)
+		now := time.Now()
+		err = os.Chtimes(path, now, now)
+		if err != nil {
+			logger.Warnf("cannot update compiled runtime cache entry access time: %s", err)
+		}
+		return module, true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.Warnf("removing compiled runtime cache entry: %s", err)
+		c.remove(path)
+	}
+
+	return module, false
+}
+
+// entryPath returns the path of the cache entry for the code hash and cache key.
+func (c *Cache) entryPath(codeHash common.Hash, key string) string {
+	return filepath.Join(c.directory, hex.EncodeToString(codeHash[:])+"-"+key+cacheEntryExtension)
+}
+
+// store writes the serialized module to the cache entry path, removes the entries
+// of the same code compiled for other host functions or wasmer versions and
+// removes the least recently used entries if the cache is over its maximum size.
+func (c *Cache) store(path string, codeHash common.Hash, module wasm.Module) error {
+	serialized, err := module.Serialize()
+	if err != nil {
+		return fmt.Errorf("cannot serialize module: %w", err)
+	}
+
+	checksum, err := common.Blake2bHash(serialized)
+	if err != nil {
+		return fmt.Errorf("cannot compute checksum: %w", err)
+	}
+
+	entry := make([]byte, 0, len(cacheEntryMagic)+len(checksum)+len(serialized))
+	entry = append(entry, cacheEntryMagic...)
+	entry = append(entry, checksum[:]...)
+	entry = append(entry, serialized...)
+
+	// write to a temporary file first so a partially written entry is never loaded.
+	temporaryPath := path + ".tmp"
+	err = os.WriteFile(temporaryPath, entry, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write cache entry: %w", err)
+	}
+
+	err = os.Rename(temporaryPath, path)
+	if err != nil {
+		c.remove(temporaryPath)
+		return fmt.Errorf("cannot rename cache entry: %w", err)
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	codeHashPrefix := hex.EncodeToString(codeHash[:]) + "-"
+	size := int64(0)
+	kept := entries[:0]
+	for _, info := range entries {
+		entryPath := filepath.Join(c.directory, info.Name())
+		if entryPath != path && strings.HasPrefix(info.Name(), codeHashPrefix) {
+			c.remove(entryPath)
+			continue
+		}
+		size += info.Size()
+		kept = append(kept, info)
+	}
+
+	// remove the least recently used entries, never removing the entry just stored.
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].ModTime().Before(kept[j].ModTime())
+	})
+	for _, info := range kept {
+		if size <= c.maxSize {
+			break
+		}
+		entryPath := filepath.Join(c.directory, info.Name())
+		if entryPath == path {
+			continue
+		}
+		c.remove(entryPath)
+		size -= info.Size()
+	}
+
+	return nil
+}
+
+// entries returns the file information of the cache entries.
+func (c *Cache) entries() (entries []os.FileInfo, err error) {
+	dirEntries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cache directory: %w", err)
+	}
+
+	entries = make([]os.FileInfo, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != cacheEntryExtension {
+			continue
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("cannot get cache entry information: %w", err)
+		}
+		entries = append(entries, info)
+	}
+
+	return entries, nil
+}
+
+func (*Cache) remove(path string) {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warnf("cannot remove compiled runtime cache entry: %s", err)
+	}
+}
+
+// loadCacheEntry loads the compiled module from the cache entry
+// at the given path, after verifying its checksum.
+func loadCacheEntry(path string) (module wasm.Module, err error) {
+	entry, err := os.ReadFile(path)
+	if err != nil {
+		return module, err
+	}
+
+	headerLength := len(cacheEntryMagic) + common.HashLength
+	if len(entry) <= headerLength || !bytes.HasPrefix(entry, cacheEntryMagic) {
+		return module, fmt.Errorf("%w: %s", errCacheEntryNotValid, path)
+	}
+
+	serialized := entry[headerLength:]
+	checksum, err := common.Blake2bHash(serialized)
+	if err != nil {
+		return module, fmt.Errorf("cannot compute checksum: %w", err)
+	}
+
+	if !bytes.Equal(checksum[:], entry[len(cacheEntryMagic):headerLength]) {
+		return module, fmt.Errorf("%w: %s", errCacheEntryChecksum, path)
+	}
+
+	module, err = wasm.DeserializeModule(serialized)
+	if err != nil {
+		return module, fmt.Errorf("%w: %s: %s", errCacheEntryDeserialize, path, err)
+	}
+
+	return module, nil
+}
+
+// cacheKey returns the part of the cache entry key identifying
+// the wasmer version and the host functions of the imports.
+func cacheKey(imports *wasm.Imports) (key string, err error) {
+	signatures, err := importsSignatures(imports)
+	if err != nil {
+		return "", err
+	}
+
+	data := wasmerVersion() + "\n" + strings.Join(signatures, "\n")
+
+	hash, err := common.Blake2bHash([]byte(data))
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash[:8]), nil
+}
+
+// importsSignatures returns the sorted namespaces, names and signatures of the imports,
+// so the cache key changes when a host function is added, removed or has its signature
+// changed. The imports fields are not exported by wasmer, so they are read by reflection.
+func importsSignatures(imports *wasm.Imports) (signatures []string, err error) {
+	if imports == nil {
+		return nil, fmt.Errorf("%w: imports are nil", errImportsNotReadable)
+	}
+
+	importsMap := reflect.ValueOf(imports).Elem().FieldByName("imports")
+	if importsMap.Kind() != reflect.Map {
+		return nil, fmt.Errorf("%w: imports map not found", errImportsNotReadable)
+	}
+
+	signatures = make([]string, 0, importsMap.Len())
+	iterator := importsMap.MapRange()
+	for iterator.Next() {
+		name := iterator.Key().String()
+		imported := iterator.Value().Elem()
+		if imported.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: import %s is not a struct", errImportsNotReadable, name)
+		}
+
+		namespace := imported.FieldByName("namespace")
+		if namespace.Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: namespace of import %s not found", errImportsNotReadable, name)
+		}
+
+		signature := namespace.String() + "." + name
+		inputs, outputs := imported.FieldByName("wasmInputs"), imported.FieldByName("wasmOutputs")
+		if inputs.IsValid() && outputs.IsValid() {
+			signature += fmt.Sprintf(" function %v -> %v", inputs, outputs)
+		} else {
+			signature += " " + imported.Type().Name()
+		}
+
+		signatures = append(signatures, signature)
+	}
+
+	sort.Strings(signatures)
+	return signatures, nil
+}
+
+// wasmerVersion returns the version of the wasmer module the node is built with.
+func wasmerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, dependency := range info.Deps {
+		if dependency.Path != wasmerModulePath {
+			continue
+		}
+		if dependency.Replace != nil {
+			dependency = dependency.Replace
+		}
+		return dependency.Version + dependency.Sum
+	}
+
+	return "unknown"
+}
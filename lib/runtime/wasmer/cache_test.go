@@ -0,0 +1,227 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package wasmer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	wasm "github.com/wasmerio/go-ext-wasm/wasmer"
+)
+
+// newTestModuleCode returns the code of a wasm module exporting a function
+// with the given name, taking no argument and returning nothing.
+func newTestModuleCode(functionName string) []byte {
+	code := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: func () -> ()
+		0x03, 0x02, 0x01, 0x00, // function section: one function of type 0
+	}
+
+	exportSection := []byte{0x01, byte(len(functionName))}
+	exportSection = append(exportSection, functionName...)
+	exportSection = append(exportSection, 0x00, 0x00) // function 0
+	code = append(code, 0x07, byte(len(exportSection)))
+	code = append(code, exportSection...)
+
+	return append(code, 0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b) // code section: empty body
+}
+
+// newTestImports returns imports with a host function having the given signature,
+// they are only used to compute cache keys and cannot be called.
+func newTestImports(t *testing.T, function interface{}) *wasm.Imports {
+	t.Helper()
+
+	imports, err := wasm.NewImports().AppendFunction("ext_test_version_1", function, nil)
+	require.NoError(t, err)
+	return imports
+}
+
+func Test_Cache_Module(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewCache(t.TempDir(), DefaultCacheMaxSize)
+	require.NoError(t, err)
+
+	code := newTestModuleCode("test")
+	imports := newTestImports(t, func(unsafe.Pointer, int32) {})
+
+	codeHash, err := common.Blake2bHash(code)
+	require.NoError(t, err)
+	key, err := cacheKey(imports)
+	require.NoError(t, err)
+	path := cache.entryPath(codeHash, key)
+
+	// the module is compiled and stored
+	module, err := cache.Module(code, imports)
+	require.NoError(t, err)
+	assert.Equal(t, []wasm.ExportDescriptor{{Name: "test", Kind: wasm.ImportExportKindFunction}}, module.Exports)
+	module.Close()
+	require.FileExists(t, path)
+
+	// the module is loaded from the cache
+	module, err = loadCacheEntry(path)
+	require.NoError(t, err)
+	module.Close()
+
+	module, err = cache.Module(code, imports)
+	require.NoError(t, err)
+	assert.Len(t, module.Exports, 1)
+	module.Close()
+
+	// a corrupted entry is compiled again
+	entry, err := os.ReadFile(path)
+	require.NoError(t, err)
+	entry[len(entry)-1]++
+	err = os.WriteFile(path, entry, 0600)
+	require.NoError(t, err)
+
+	_, err = loadCacheEntry(path)
+	assert.ErrorIs(t, err, errCacheEntryChecksum)
+
+	module, err = cache.Module(code, imports)
+	require.NoError(t, err)
+	assert.Len(t, module.Exports, 1)
+	module.Close()
+
+	module, err = loadCacheEntry(path)
+	require.NoError(t, err)
+	module.Close()
+}
+
+func Test_Cache_Module_hostFunctionsChange(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	cache, err := NewCache(directory, DefaultCacheMaxSize)
+	require.NoError(t, err)
+
+	code := newTestModuleCode("test")
+
+	module, err := cache.Module(code, newTestImports(t, func(unsafe.Pointer, int32) {}))
+	require.NoError(t, err)
+	module.Close()
+
+	imports := newTestImports(t, func(unsafe.Pointer, int64) {})
+	module, err = cache.Module(code, imports)
+	require.NoError(t, err)
+	module.Close()
+
+	// the entry compiled for the previous host functions is removed
+	entries, err := cache.entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	codeHash, err := common.Blake2bHash(code)
+	require.NoError(t, err)
+	key, err := cacheKey(imports)
+	require.NoError(t, err)
+	assert.Equal(t, cache.entryPath(codeHash, key), filepath.Join(directory, entries[0].Name()))
+}
+
+func Test_Cache_store_eviction(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	cache, err := NewCache(directory, DefaultCacheMaxSize)
+	require.NoError(t, err)
+
+	imports := newTestImports(t, func(unsafe.Pointer, int32) {})
+	key, err := cacheKey(imports)
+	require.NoError(t, err)
+
+	codes := [][]byte{
+		newTestModuleCode("a"),
+		newTestModuleCode("b"),
+		newTestModuleCode("c"),
+	}
+	paths := make([]string, len(codes))
+	for i, code := range codes {
+		module, err := cache.Module(code, imports)
+		require.NoError(t, err)
+		module.Close()
+
+		codeHash, err := common.Blake2bHash(code)
+		require.NoError(t, err)
+		paths[i] = cache.entryPath(codeHash, key)
+
+		// make the first entry the least recently used one
+		accessTime := time.Now().Add(time.Duration(i-len(codes)) * time.Minute)
+		err = os.Chtimes(paths[i], accessTime, accessTime)
+		require.NoError(t, err)
+	}
+
+	info, err := os.Stat(paths[0])
+	require.NoError(t, err)
+	cache.maxSize = 2 * info.Size()
+
+	// storing the second entry again evicts the least recently used first entry
+	module, err := wasm.Compile(codes[1])
+	require.NoError(t, err)
+	defer module.Close()
+	codeHash, err := common.Blake2bHash(codes[1])
+	require.NoError(t, err)
+	err = cache.store(paths[1], codeHash, module)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, paths[0])
+	assert.FileExists(t, paths[1])
+	assert.FileExists(t, paths[2])
+}
+
+func Test_cacheKey(t *testing.T) {
+	t.Parallel()
+
+	imports, err := ImportsNodeRuntime()
+	require.NoError(t, err)
+	key, err := cacheKey(imports)
+	require.NoError(t, err)
+	assert.Len(t, key, 16)
+
+	sameImports, err := ImportsNodeRuntime()
+	require.NoError(t, err)
+	sameKey, err := cacheKey(sameImports)
+	require.NoError(t, err)
+	assert.Equal(t, key, sameKey)
+
+	// adding a host function changes the key
+	_, err = sameImports.AppendFunction("ext_test_version_1", func(unsafe.Pointer) {}, nil)
+	require.NoError(t, err)
+	otherKey, err := cacheKey(sameImports)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	_, err = cacheKey(nil)
+	assert.ErrorIs(t, err, errImportsNotReadable)
+}
+
+func Test_importsSignatures(t *testing.T) {
+	t.Parallel()
+
+	imports := wasm.NewImports()
+	_, err := imports.AppendFunction("ext_b_version_1", func(unsafe.Pointer, int32, int64) int32 { return 0 }, nil)
+	require.NoError(t, err)
+	_, err = imports.Namespace("other").AppendFunction("ext_a_version_1", func(unsafe.Pointer) {}, nil)
+	require.NoError(t, err)
+	memory, err := wasm.NewMemory(1, 0)
+	require.NoError(t, err)
+	defer memory.Close()
+	_, err = imports.Namespace("env").AppendMemory("memory", memory)
+	require.NoError(t, err)
+
+	signatures, err := importsSignatures(imports)
+	require.NoError(t, err)
+	expected := []string{
+		"env.ext_b_version_1 function [0 1] -> [0]",
+		"env.memory ImportMemory",
+		"other.ext_a_version_1 function [] -> []",
+	}
+	assert.Equal(t, expected, signatures)
+}
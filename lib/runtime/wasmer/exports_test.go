@@ -16,12 +16,14 @@
 	"github.com/ChainSafe/gossamer/lib/crypto/ed25519"
 	"github.com/ChainSafe/gossamer/lib/genesis"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/metadata"
 	"github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/lib/runtime/wasmer/testdata"
 	"github.com/ChainSafe/gossamer/lib/trie"
 	"github.com/ChainSafe/gossamer/lib/utils"
 	"github.com/ChainSafe/gossamer/pkg/scale"
 
+	ctypes "github.com/centrifuge/go-substrate-rpc-client/v3/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -128,6 +130,130 @@ func TestInstance_Version_PolkadotRuntime_v0917(t *testing.T) {
 	assert.Equal(t, expectedTransactionVersion, version.TransactionVersion())
 }
 
+func TestInstance_Metadata_PolkadotRuntime_v0917(t *testing.T) {
+	instance := NewTestInstance(t, runtime.POLKADOT_RUNTIME_v0917)
+	opaque, err := instance.Metadata()
+	require.NoError(t, err)
+
+	decoded, err := metadata.DecodeOpaque(opaque)
+	require.NoError(t, err)
+	require.Equal(t, uint8(14), decoded.Version)
+
+	// every type referenced by the type registry is in the type registry
+	for id := metadata.TypeID(0); int(id) < decoded.Types.Len(); id++ {
+		typ, err := decoded.Types.Type(id)
+		require.NoError(t, err)
+
+		var references []metadata.TypeID
+		for _, field := range typ.Def.Fields {
+			references = append(references, field.Type.ID)
+		}
+		for _, variant := range typ.Def.Variants {
+			for _, field := range variant.Fields {
+				references = append(references, field.Type.ID)
+			}
+		}
+		switch typ.Def.Kind {
+		case metadata.TypeDefSequence, metadata.TypeDefArray, metadata.TypeDefCompact:
+			references = append(references, typ.Def.Elem)
+		case metadata.TypeDefTuple:
+			references = append(references, typ.Def.Tuple...)
+		case metadata.TypeDefBitSequence:
+			references = append(references, typ.Def.BitStore, typ.Def.BitOrder)
+		}
+		for _, reference := range references {
+			_, err = decoded.Types.Type(reference)
+			require.NoError(t, err, "type %d (%s) references type %d", id, typ.PathString(), reference)
+		}
+	}
+
+	system, ok := decoded.Pallet("System")
+	require.True(t, ok)
+	assert.Equal(t, uint8(0), system.Index)
+	account, ok := system.Storage.Entry("Account")
+	require.True(t, ok)
+	assert.Equal(t, []metadata.StorageHasher{metadata.HasherBlake2128Concat}, account.Hashers)
+	require.Len(t, account.Keys, 1)
+	accountID, err := decoded.Types.Type(account.Keys[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sp_core::crypto::AccountId32", accountID.PathString())
+	accountInfo, err := decoded.Types.Type(account.Value.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "frame_system::AccountInfo", accountInfo.PathString())
+
+	balances, ok := decoded.Pallet("Balances")
+	require.True(t, ok)
+	assert.Equal(t, uint8(5), balances.Index)
+	require.NotEmpty(t, balances.Calls)
+	transfer := balances.Calls[0]
+	assert.Equal(t, "transfer", transfer.Name)
+	assert.Equal(t, uint8(0), transfer.Index)
+	require.Len(t, transfer.Fields, 2)
+	assert.Equal(t, "dest", transfer.Fields[0].Name)
+	assert.Equal(t, "value", transfer.Fields[1].Name)
+	assert.Equal(t, "Compact<T::Balance>", transfer.Fields[1].Type.Name)
+	existentialDeposit, ok := balances.Constant("ExistentialDeposit")
+	require.True(t, ok)
+	assert.Len(t, existentialDeposit.Value, 16)
+
+	// the calls of the pallet are the variants of the runtime call type,
+	// found with the Call type parameter of the extrinsic type.
+	extrinsic, err := decoded.Types.Type(decoded.Extrinsic.Type.ID)
+	require.NoError(t, err)
+	var callTypeID *metadata.TypeID
+	for _, param := range extrinsic.Params {
+		if param.Name == "Call" {
+			callTypeID = param.Type
+		}
+	}
+	require.NotNil(t, callTypeID)
+	callType, err := decoded.Types.Type(*callTypeID)
+	require.NoError(t, err)
+
+	var balancesCall *metadata.Variant
+	for i := range callType.Def.Variants {
+		if callType.Def.Variants[i].Name == "Balances" {
+			balancesCall = &callType.Def.Variants[i]
+		}
+	}
+	require.NotNil(t, balancesCall)
+	assert.Equal(t, balances.Index, balancesCall.Index)
+	require.Len(t, balancesCall.Fields, 1)
+	balancesCallType, err := decoded.Types.Type(balancesCall.Fields[0].Type.ID)
+	require.NoError(t, err)
+	assert.Equal(t, balances.Calls, balancesCallType.Def.Variants)
+
+	// a balances transfer to the account id 0x01...01 of 12345 decodes with the registry
+	encodedCall := []byte{balances.Index, transfer.Index, 0} // MultiAddress::Id
+	accountIDBytes := make([]byte, 32)
+	for i := range accountIDBytes {
+		accountIDBytes[i] = 1
+	}
+	encodedCall = append(encodedCall, accountIDBytes...)
+	encodedValue, err := scale.Marshal(big.NewInt(12345))
+	require.NoError(t, err)
+	encodedCall = append(encodedCall, encodedValue...)
+
+	call, err := decoded.Types.DecodeValue(*callTypeID, encodedCall)
+	require.NoError(t, err)
+	expectedCall := metadata.VariantValue{
+		Name:  "Balances",
+		Index: balances.Index,
+		Value: []interface{}{metadata.VariantValue{
+			Name:  "transfer",
+			Index: transfer.Index,
+			Value: map[string]interface{}{
+				"dest": metadata.VariantValue{
+					Name:  "Id",
+					Value: []interface{}{[]interface{}{accountIDBytes}},
+				},
+				"value": big.NewInt(12345),
+			},
+		}},
+	}
+	assert.Equal(t, expectedCall, call)
+}
+
 func TestInstance_Version_PolkadotRuntime(t *testing.T) {
 	expected := runtime.NewVersionData(
 		[]byte("polkadot"),
@@ -285,7 +411,11 @@ func balanceKey(t *testing.T, pub []byte) []byte {
 	return append(append(append(h0, h1...), h2...), pub...)
 }
 
-func TestNodeRuntime_ValidateTransaction(t *testing.T) {
+// newValidateTransactionRuntime returns a runtime instance with the gssmr genesis state,
+// where Alice can pay for transactions, initialised to build block 1, and the genesis header.
+func newValidateTransactionRuntime(t *testing.T) (rt runtime.Instance, genesisHeader *types.Header) {
+	t.Helper()
+
 	genesisPath := utils.GetGssmrGenesisRawPathTest(t)
 	gen, err := genesis.NewGenesisFromJSONRaw(genesisPath)
 	require.NoError(t, err)
@@ -304,7 +434,7 @@ func TestNodeRuntime_ValidateTransaction(t *testing.T) {
 	nodeStorage.BaseDB = runtime.NewInMemoryDB(t)
 	cfg.NodeStorage = nodeStorage
 
-	rt, err := NewRuntimeFromGenesis(cfg)
+	rt, err = NewRuntimeFromGenesis(cfg)
 	require.NoError(t, err)
 
 	alicePub := common.MustHexToBytes("0xd43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d")
@@ -327,22 +457,48 @@ func TestNodeRuntime_ValidateTransaction(t *testing.T) {
 	// this key is System.UpgradedToDualRefCount -> set to true since all accounts have been upgraded to v0.9 format
 	rt.(*Instance).ctx.Storage.Set(common.UpgradedToDualRefKey, []byte{1})
 
-	genesisHeader := &types.Header{
+	genesisHeader = &types.Header{
 		Number:    0,
 		StateRoot: genTrie.MustHash(),
 	}
 
+	return rt, genesisHeader
+}
+
+func TestNodeRuntime_ValidateTransaction(t *testing.T) {
+	rt, genesisHeader := newValidateTransactionRuntime(t)
+
 	extHex := runtime.NewTestExtrinsic(t, rt, genesisHeader.Hash(), genesisHeader.Hash(),
 		0, "System.remark", []byte{0xab, 0xcd})
 
 	extBytes := common.MustHexToBytes(extHex)
-	extBytes = append([]byte{byte(types.TxnExternal)}, extBytes...)
 
 	runtime.InitializeRuntimeToTest(t, rt, genesisHeader.Hash())
-	_, err = rt.ValidateTransaction(extBytes)
+	_, err := rt.ValidateTransaction(types.TxnExternal, extBytes, genesisHeader.Hash())
 	require.NoError(t, err)
 }
 
+func TestNodeRuntime_ValidateTransaction_InvalidSignature(t *testing.T) {
+	rt, genesisHeader := newValidateTransactionRuntime(t)
+
+	extHex := runtime.NewTestExtrinsic(t, rt, genesisHeader.Hash(), genesisHeader.Hash(),
+		0, "System.remark", []byte{0xab, 0xcd})
+
+	// the extrinsic signed by Alice is modified so its signature is not valid anymore
+	var ext ctypes.Extrinsic
+	err := ctypes.DecodeFromHexString(extHex, &ext)
+	require.NoError(t, err)
+	require.True(t, ext.Signature.Signature.IsSr25519)
+	ext.Signature.Signature.AsSr25519[0] ^= 0xff
+
+	extBytes, err := ctypes.EncodeToBytes(ext)
+	require.NoError(t, err)
+
+	runtime.InitializeRuntimeToTest(t, rt, genesisHeader.Hash())
+	_, err = rt.ValidateTransaction(types.TxnExternal, extBytes, genesisHeader.Hash())
+	require.ErrorIs(t, err, runtime.ErrInvalidTransaction)
+}
+
 func TestInstance_GrandpaAuthorities_NodeRuntime(t *testing.T) {
 	tt := trie.NewEmptyTrie()
 
@@ -514,6 +670,118 @@ func TestInstance_ExecuteBlock_NodeRuntime(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// newSignedExtrinsicsBlock returns a runtime instance with the gssmr genesis state, where Alice
+// can pay for transactions, and block 1 built on this state with two System.remark extrinsics
+// signed by Alice. The runtime state is reset to the genesis state before returning.
+func newSignedExtrinsicsBlock(t *testing.T) (instance *Instance, block *types.Block) {
+	t.Helper()
+
+	rt, genesisHeader := newValidateTransactionRuntime(t)
+	instance = rt.(*Instance)
+	parentTrie := instance.ctx.Storage.(*storage.TrieState).Trie().Snapshot()
+
+	digest := types.NewDigest()
+	preDigest, err := types.NewBabeSecondaryPlainPreDigest(0, 1).ToPreRuntimeDigest()
+	require.NoError(t, err)
+	err = digest.Add(*preDigest)
+	require.NoError(t, err)
+
+	header := &types.Header{
+		ParentHash: genesisHeader.Hash(),
+		Number:     1,
+		Digest:     digest,
+	}
+	err = instance.InitializeBlock(header)
+	require.NoError(t, err)
+
+	idata := types.NewInherentsData()
+	err = idata.SetInt64Inherent(types.Timstap0, 1)
+	require.NoError(t, err)
+	err = idata.SetInt64Inherent(types.Babeslot, 1)
+	require.NoError(t, err)
+	ienc, err := idata.Encode()
+	require.NoError(t, err)
+
+	inherentExts, err := instance.InherentExtrinsics(ienc)
+	require.NoError(t, err)
+	var exts [][]byte
+	err = scale.Unmarshal(inherentExts, &exts)
+	require.NoError(t, err)
+
+	for _, ext := range exts {
+		in, err := scale.Marshal(ext)
+		require.NoError(t, err)
+		ret, err := instance.ApplyExtrinsic(in)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0}, ret)
+	}
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		extHex := runtime.NewTestExtrinsic(t, instance, genesisHeader.Hash(), genesisHeader.Hash(),
+			nonce, "System.remark", []byte{0xab, 0xcd})
+		extBytes := common.MustHexToBytes(extHex)
+
+		ret, err := instance.ApplyExtrinsic(extBytes)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0}, ret)
+
+		// the block body holds the extrinsics without their length prefix
+		var ext []byte
+		err = scale.Unmarshal(extBytes, &ext)
+		require.NoError(t, err)
+		exts = append(exts, ext)
+	}
+
+	blockHeader, err := instance.FinalizeBlock()
+	require.NoError(t, err)
+	blockHeader.Number = header.Number
+
+	parentState, err := storage.NewTrieState(parentTrie)
+	require.NoError(t, err)
+	instance.SetContextStorage(parentState)
+
+	block = &types.Block{
+		Header: *blockHeader,
+		Body:   *types.NewBody(types.BytesArrayToExtrinsics(exts)),
+	}
+	return instance, block
+}
+
+func TestNodeRuntime_ExecuteBlock_SignedExtrinsics(t *testing.T) {
+	instance, block := newSignedExtrinsicsBlock(t)
+
+	// the runtime verifies the signatures of the extrinsics in a batch
+	_, err := instance.ExecuteBlock(block)
+	require.NoError(t, err)
+	assert.False(t, instance.ctx.SigVerifier.IsStarted())
+}
+
+func TestNodeRuntime_ExecuteBlock_InvalidSignature(t *testing.T) {
+	instance, block := newSignedExtrinsicsBlock(t)
+
+	// the signature of the last extrinsic signed by Alice is modified, so the batch
+	// verification fails and the whole block is rejected.
+	lastIndex := len(block.Body) - 1
+	extBytes, err := scale.Marshal([]byte(block.Body[lastIndex]))
+	require.NoError(t, err)
+	var ext ctypes.Extrinsic
+	err = ctypes.DecodeFromBytes(extBytes, &ext)
+	require.NoError(t, err)
+	require.True(t, ext.Signature.Signature.IsSr25519)
+	ext.Signature.Signature.AsSr25519[0] ^= 0xff
+
+	extBytes, err = ctypes.EncodeToBytes(ext)
+	require.NoError(t, err)
+	var corrupted []byte
+	err = scale.Unmarshal(extBytes, &corrupted)
+	require.NoError(t, err)
+	block.Body[lastIndex] = corrupted
+
+	_, err = instance.ExecuteBlock(block)
+	require.Error(t, err)
+	assert.False(t, instance.ctx.SigVerifier.IsStarted())
+}
+
 func TestInstance_ExecuteBlock_GossamerRuntime(t *testing.T) {
 	t.Skip() // TODO: this fails with "syscall frame is no longer valid" (#1026)
 	genesisPath := utils.GetGssmrGenesisRawPathTest(t)
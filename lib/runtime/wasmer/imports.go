@@ -8,6 +8,7 @@
 // extern void ext_logging_log_version_1(void *context, int32_t level, int64_t target, int64_t msg);
 // extern int32_t ext_logging_max_level_version_1(void *context);
 //
+// extern int64_t ext_sandbox_get_global_val_version_1(void *context, int32_t a, int64_t b);
 // extern void ext_sandbox_instance_teardown_version_1(void *context, int32_t a);
 // extern int32_t ext_sandbox_instantiate_version_1(void *context, int32_t a, int64_t b, int64_t c, int32_t d);
 // extern int32_t ext_sandbox_invoke_version_1(void *context, int32_t a, int64_t b, int64_t c, int32_t d, int32_t e, int32_t f);
@@ -34,6 +35,7 @@
 // extern void ext_crypto_start_batch_verify_version_1(void *context);
 //
 // extern int32_t ext_trie_blake2_256_root_version_1(void *context, int64_t a);
+// extern int32_t ext_trie_blake2_256_root_version_2(void *context, int64_t a, int32_t b);
 // extern int32_t ext_trie_blake2_256_ordered_root_version_1(void *context, int64_t a);
 // extern int32_t ext_trie_blake2_256_ordered_root_version_2(void *context, int64_t a, int32_t b);
 // extern int32_t ext_trie_blake2_256_verify_proof_version_1(void *context, int32_t a, int64_t b, int64_t c, int64_t d);
@@ -48,6 +50,7 @@
 // extern int64_t ext_default_child_storage_next_key_version_1(void *context, int64_t a, int64_t b);
 // extern int64_t ext_default_child_storage_read_version_1(void *context, int64_t a, int64_t b, int64_t c, int32_t d);
 // extern int64_t ext_default_child_storage_root_version_1(void *context, int64_t a);
+// extern int64_t ext_default_child_storage_root_version_2(void *context, int64_t a, int32_t b);
 // extern void ext_default_child_storage_set_version_1(void *context, int64_t a, int64_t b, int64_t c);
 // extern void ext_default_child_storage_storage_kill_version_1(void *context, int64_t a);
 // extern int32_t ext_default_child_storage_storage_kill_version_2(void *context, int64_t a, int64_t b);
@@ -79,6 +82,10 @@
 // extern void ext_offchain_sleep_until_version_1(void *context, int64_t a);
 // extern int64_t ext_offchain_http_request_start_version_1(void *context, int64_t a, int64_t b, int64_t c);
 // extern int64_t ext_offchain_http_request_add_header_version_1(void *context, int32_t a, int64_t k, int64_t v);
+// extern int64_t ext_offchain_http_request_write_body_version_1(void *context, int32_t a, int64_t b, int64_t c);
+// extern int64_t ext_offchain_http_response_wait_version_1(void *context, int64_t a, int64_t b);
+// extern int64_t ext_offchain_http_response_headers_version_1(void *context, int32_t a);
+// extern int64_t ext_offchain_http_response_read_body_version_1(void *context, int32_t a, int64_t b, int64_t c);
 //
 // extern void ext_storage_append_version_1(void *context, int64_t a, int64_t b);
 // extern int64_t ext_storage_changes_root_version_1(void *context, int64_t a);
@@ -118,6 +125,8 @@
 	"github.com/ChainSafe/gossamer/lib/crypto/secp256k1"
 	"github.com/ChainSafe/gossamer/lib/crypto/sr25519"
 	"github.com/ChainSafe/gossamer/lib/runtime"
+	"github.com/ChainSafe/gossamer/lib/runtime/offchain"
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
 	rtstorage "github.com/ChainSafe/gossamer/lib/runtime/storage"
 	"github.com/ChainSafe/gossamer/lib/transaction"
 	"github.com/ChainSafe/gossamer/lib/trie"
@@ -157,66 +166,250 @@ func ext_logging_max_level_version_1(context unsafe.Pointer) C.int32_t {
 }
 
 //export ext_transaction_index_index_version_1
-func ext_transaction_index_index_version_1(context unsafe.Pointer, a, b, c C.int32_t) {
+func ext_transaction_index_index_version_1(context unsafe.Pointer, extrinsic, size, contextHash C.int32_t) {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+	memory := instanceContext.Memory().Data()
+
+	hash := common.BytesToHash(memory[contextHash : contextHash+32])
+	runtimeCtx.Storage.IndexTransaction(uint32(extrinsic), uint32(size), hash)
 }
 
 //export ext_transaction_index_renew_version_1
-func ext_transaction_index_renew_version_1(context unsafe.Pointer, a, b C.int32_t) {
+func ext_transaction_index_renew_version_1(context unsafe.Pointer, extrinsic, contextHash C.int32_t) {
+	logger.Trace("executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+	memory := instanceContext.Memory().Data()
+
+	hash := common.BytesToHash(memory[contextHash : contextHash+32])
+	runtimeCtx.Storage.RenewTransaction(uint32(extrinsic), hash)
+}
+
+// sandboxReturnCode converts a sandbox return code to the int32 the runtime expects
+func sandboxReturnCode(code uint32) C.int32_t {
+	return C.int32_t(int32(code))
+}
+
+//export ext_sandbox_get_global_val_version_1
+func ext_sandbox_get_global_val_version_1(context unsafe.Pointer, instanceIdx C.int32_t, nameSpan C.int64_t) C.int64_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+	name := string(asMemorySlice(instanceContext, nameSpan))
+
+	var enc []byte
+	inst, err := runtimeCtx.Sandbox.Instance(uint32(instanceIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox instance: %s", err)
+		enc = []byte{0}
+	} else if value, ok := inst.GetGlobal(name); !ok {
+		enc = []byte{0}
+	} else {
+		vdt := sandbox.NewValue()
+		err = vdt.Set(value)
+		if err == nil {
+			enc, err = scale.Marshal(vdt)
+		}
+		if err != nil {
+			logger.Errorf("failed to encode global value: %s", err)
+			return 0
+		}
+		enc = append([]byte{1}, enc...)
+	}
+
+	ret, err := toWasmMemory(instanceContext, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate memory: %s", err)
+		return 0
+	}
+
+	return C.int64_t(ret)
 }
 
 //export ext_sandbox_instance_teardown_version_1
-func ext_sandbox_instance_teardown_version_1(context unsafe.Pointer, a C.int32_t) {
+func ext_sandbox_instance_teardown_version_1(context unsafe.Pointer, instanceIdx C.int32_t) {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	err := runtimeCtx.Sandbox.TeardownInstance(uint32(instanceIdx))
+	if err != nil {
+		logger.Errorf("failed to teardown sandbox instance: %s", err)
+	}
 }
 
 //export ext_sandbox_instantiate_version_1
-func ext_sandbox_instantiate_version_1(context unsafe.Pointer, a C.int32_t, x, y C.int64_t, z C.int32_t) C.int32_t {
+func ext_sandbox_instantiate_version_1(context unsafe.Pointer, dispatchThunk C.int32_t,
+	wasmCodeSpan, envDefSpan C.int64_t, statePtr C.int32_t) C.int32_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
-	return 0
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	// copy the inputs, since the supervisor memory may grow while the start function runs
+	code := append([]byte{}, asMemorySlice(instanceContext, wasmCodeSpan)...)
+	envDef := append([]byte{}, asMemorySlice(instanceContext, envDefSpan)...)
+
+	idx, err := runtimeCtx.Sandbox.Instantiate(uint32(dispatchThunk), code, envDef, uint32(statePtr),
+		runtimeCtx.Supervisor)
+	if errors.Is(err, sandbox.ErrExecution) {
+		logger.Errorf("failed to run sandbox instance start function: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	} else if err != nil {
+		logger.Errorf("failed to instantiate sandbox module: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrModule)
+	}
+
+	return C.int32_t(idx)
 }
 
 //export ext_sandbox_invoke_version_1
-func ext_sandbox_invoke_version_1(context unsafe.Pointer, a C.int32_t, x, y C.int64_t, z, d, e C.int32_t) C.int32_t {
+func ext_sandbox_invoke_version_1(context unsafe.Pointer, instanceIdx C.int32_t, exportNameSpan, argsSpan C.int64_t,
+	returnValPtr, returnValLen, statePtr C.int32_t) C.int32_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
-	return 0
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	exportName := string(asMemorySlice(instanceContext, exportNameSpan))
+
+	inst, err := runtimeCtx.Sandbox.Instance(uint32(instanceIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox instance: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	args, err := sandbox.DecodeValues(asMemorySlice(instanceContext, argsSpan))
+	if err != nil {
+		logger.Errorf("failed to decode sandbox invocation arguments: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	res, err := inst.Invoke(exportName, args, uint32(statePtr), runtimeCtx.Supervisor)
+	if err != nil {
+		logger.Errorf("failed to invoke %s on sandbox instance %d: %s", exportName, instanceIdx, err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	enc, err := sandbox.EncodeReturnValue(res)
+	if err != nil {
+		logger.Errorf("failed to encode sandbox return value: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	if len(enc) > int(uint32(returnValLen)) {
+		logger.Errorf("return value of %d bytes does not fit in buffer of %d bytes", len(enc), uint32(returnValLen))
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	// the memory is loaded after the invocation, since it may have grown in the meantime
+	memory := instanceContext.Memory().Data()
+	ptr := uint64(uint32(returnValPtr))
+	if ptr+uint64(len(enc)) > uint64(len(memory)) {
+		logger.Errorf("return value pointer 0x%x is out of bounds", ptr)
+		return sandboxReturnCode(sandbox.ReturnErrExecution)
+	}
+
+	copy(memory[ptr:], enc)
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
 //export ext_sandbox_memory_get_version_1
-func ext_sandbox_memory_get_version_1(context unsafe.Pointer, a, z, d, e C.int32_t) C.int32_t {
+func ext_sandbox_memory_get_version_1(context unsafe.Pointer, memoryIdx, offset, bufPtr, bufLen C.int32_t) C.int32_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
-	return 0
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+	memory := instanceContext.Memory().Data()
+
+	mem, err := runtimeCtx.Sandbox.Memory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	start, end := uint64(uint32(bufPtr)), uint64(uint32(bufPtr))+uint64(uint32(bufLen))
+	if end > uint64(len(memory)) {
+		logger.Errorf("buffer [0x%x, 0x%x) is out of bounds of supervisor memory", start, end)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	err = mem.Get(uint32(offset), memory[start:end])
+	if err != nil {
+		logger.Debugf("failed to read sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
 //export ext_sandbox_memory_new_version_1
-func ext_sandbox_memory_new_version_1(context unsafe.Pointer, a, z C.int32_t) C.int32_t {
+func ext_sandbox_memory_new_version_1(context unsafe.Pointer, initial, maximum C.int32_t) C.int32_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
-	return 0
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	idx, err := runtimeCtx.Sandbox.NewMemory(uint32(initial), uint32(maximum))
+	if err != nil {
+		logger.Errorf("failed to create sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrModule)
+	}
+
+	return C.int32_t(idx)
 }
 
 //export ext_sandbox_memory_set_version_1
-func ext_sandbox_memory_set_version_1(context unsafe.Pointer, a, z, d, e C.int32_t) C.int32_t {
+func ext_sandbox_memory_set_version_1(context unsafe.Pointer, memoryIdx, offset, valPtr, valLen C.int32_t) C.int32_t {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
-	return 0
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+	memory := instanceContext.Memory().Data()
+
+	mem, err := runtimeCtx.Sandbox.Memory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to get sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	start, end := uint64(uint32(valPtr)), uint64(uint32(valPtr))+uint64(uint32(valLen))
+	if end > uint64(len(memory)) {
+		logger.Errorf("value [0x%x, 0x%x) is out of bounds of supervisor memory", start, end)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	err = mem.Set(uint32(offset), memory[start:end])
+	if err != nil {
+		logger.Debugf("failed to write sandbox memory: %s", err)
+		return sandboxReturnCode(sandbox.ReturnErrOutOfBounds)
+	}
+
+	return sandboxReturnCode(sandbox.ReturnOK)
 }
 
 //export ext_sandbox_memory_teardown_version_1
-func ext_sandbox_memory_teardown_version_1(context unsafe.Pointer, a C.int32_t) {
+func ext_sandbox_memory_teardown_version_1(context unsafe.Pointer, memoryIdx C.int32_t) {
 	logger.Trace("executing...")
-	logger.Warn("unimplemented")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	err := runtimeCtx.Sandbox.TeardownMemory(uint32(memoryIdx))
+	if err != nil {
+		logger.Errorf("failed to teardown sandbox memory: %s", err)
+	}
 }
 
 //export ext_crypto_ed25519_generate_version_1
 func ext_crypto_ed25519_generate_version_1(context unsafe.Pointer, keyTypeID C.int32_t, seedSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -270,6 +463,7 @@ func ext_crypto_ed25519_generate_version_1(context unsafe.Pointer, keyTypeID C.i
 
 //export ext_crypto_ed25519_public_keys_version_1
 func ext_crypto_ed25519_public_keys_version_1(context unsafe.Pointer, keyTypeID C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -319,6 +513,7 @@ func ext_crypto_ed25519_public_keys_version_1(context unsafe.Pointer, keyTypeID
 
 //export ext_crypto_ed25519_sign_version_1
 func ext_crypto_ed25519_sign_version_1(context unsafe.Pointer, keyTypeID, key C.int32_t, msg C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -369,6 +564,7 @@ func ext_crypto_ed25519_sign_version_1(context unsafe.Pointer, keyTypeID, key C.
 
 //export ext_crypto_ed25519_verify_version_1
 func ext_crypto_ed25519_verify_version_1(context unsafe.Pointer, sig C.int32_t, msg C.int64_t, key C.int32_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -407,6 +603,7 @@ func ext_crypto_ed25519_verify_version_1(context unsafe.Pointer, sig C.int32_t,
 
 //export ext_crypto_secp256k1_ecdsa_recover_version_1
 func ext_crypto_secp256k1_ecdsa_recover_version_1(context unsafe.Pointer, sig, msg C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
@@ -444,12 +641,14 @@ func ext_crypto_secp256k1_ecdsa_recover_version_1(context unsafe.Pointer, sig, m
 
 //export ext_crypto_secp256k1_ecdsa_recover_version_2
 func ext_crypto_secp256k1_ecdsa_recover_version_2(context unsafe.Pointer, sig, msg C.int32_t) C.int64_t {
+	// the host function metrics are recorded by the version 1 host function
 	logger.Trace("executing...")
 	return ext_crypto_secp256k1_ecdsa_recover_version_1(context, sig, msg)
 }
 
 //export ext_crypto_ecdsa_verify_version_2
 func ext_crypto_ecdsa_verify_version_2(context unsafe.Pointer, sig C.int32_t, msg C.int64_t, key C.int32_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -498,6 +697,7 @@ func ext_crypto_ecdsa_verify_version_2(context unsafe.Pointer, sig C.int32_t, ms
 
 //export ext_crypto_secp256k1_ecdsa_recover_compressed_version_1
 func ext_crypto_secp256k1_ecdsa_recover_compressed_version_1(context unsafe.Pointer, sig, msg C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
@@ -536,6 +736,7 @@ func ext_crypto_secp256k1_ecdsa_recover_compressed_version_2(context unsafe.Poin
 
 //export ext_crypto_sr25519_generate_version_1
 func ext_crypto_sr25519_generate_version_1(context unsafe.Pointer, keyTypeID C.int32_t, seedSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -588,6 +789,7 @@ func ext_crypto_sr25519_generate_version_1(context unsafe.Pointer, keyTypeID C.i
 
 //export ext_crypto_sr25519_public_keys_version_1
 func ext_crypto_sr25519_public_keys_version_1(context unsafe.Pointer, keyTypeID C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -637,6 +839,7 @@ func ext_crypto_sr25519_public_keys_version_1(context unsafe.Pointer, keyTypeID
 
 //export ext_crypto_sr25519_sign_version_1
 func ext_crypto_sr25519_sign_version_1(context unsafe.Pointer, keyTypeID, key C.int32_t, msg C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
@@ -683,6 +886,7 @@ func ext_crypto_sr25519_sign_version_1(context unsafe.Pointer, keyTypeID, key C.
 
 //export ext_crypto_sr25519_verify_version_1
 func ext_crypto_sr25519_verify_version_1(context unsafe.Pointer, sig C.int32_t, msg C.int64_t, key C.int32_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -707,7 +911,7 @@ func ext_crypto_sr25519_verify_version_1(context unsafe.Pointer, sig C.int32_t,
 			PubKey:     pub.Encode(),
 			Sign:       signature,
 			Msg:        message,
-			VerifyFunc: sr25519.VerifySignature,
+			VerifyFunc: sr25519.VerifySignatureDeprecated,
 		}
 		sigVerifier.Add(&signature)
 		return 1
@@ -715,8 +919,7 @@ func ext_crypto_sr25519_verify_version_1(context unsafe.Pointer, sig C.int32_t,
 
 	if ok, err := pub.VerifyDeprecated(message, signature); err != nil || !ok {
 		logger.Debugf("failed to validate signature: %s", err)
-		// this fails at block 3876, which seems to be expected, based on discussions
-		return 1
+		return 0
 	}
 
 	logger.Debug("verified sr25519 signature")
@@ -725,6 +928,7 @@ func ext_crypto_sr25519_verify_version_1(context unsafe.Pointer, sig C.int32_t,
 
 //export ext_crypto_sr25519_verify_version_2
 func ext_crypto_sr25519_verify_version_2(context unsafe.Pointer, sig C.int32_t, msg C.int64_t, key C.int32_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -766,31 +970,59 @@ func ext_crypto_sr25519_verify_version_2(context unsafe.Pointer, sig C.int32_t,
 
 //export ext_crypto_start_batch_verify_version_1
 func ext_crypto_start_batch_verify_version_1(context unsafe.Pointer) {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
-	// TODO: fix and re-enable signature verification (#1405)
-	// beginBatchVerify(context)
+	instanceContext := wasm.IntoInstanceContext(context)
+	sigVerifier := instanceContext.Data().(*runtime.Context).SigVerifier
+	sigVerifier.Start()
 }
 
 //export ext_crypto_finish_batch_verify_version_1
 func ext_crypto_finish_batch_verify_version_1(context unsafe.Pointer) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionCrypto)()
 	logger.Debug("executing...")
 
-	// TODO: fix and re-enable signature verification (#1405)
-	// return finishBatchVerify(context)
+	instanceContext := wasm.IntoInstanceContext(context)
+	sigVerifier := instanceContext.Data().(*runtime.Context).SigVerifier
+
+	if !sigVerifier.Finish() {
+		logger.Error("failed to verify batch of signatures")
+		return 0
+	}
+
 	return 1
 }
 
 //export ext_trie_blake2_256_root_version_1
 func ext_trie_blake2_256_root_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
 	logger.Debug("executing...")
+	return trieRoot(context, dataSpan, trie.V0)
+}
+
+//export ext_trie_blake2_256_root_version_2
+func ext_trie_blake2_256_root_version_2(context unsafe.Pointer, dataSpan C.int64_t, version C.int32_t) C.int32_t {
+	logger.Debug("executing...")
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return trieRoot(context, dataSpan, stateVersion)
+}
 
+// trieRoot computes the root hash of the trie built from the SCALE encoded
+// (key, value) tuples at dataSpan, using the given state trie version.
+func trieRoot(context unsafe.Pointer, dataSpan C.int64_t, version trie.Version) C.int32_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
 	data := asMemorySlice(instanceContext, dataSpan)
 
 	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
 
 	type kv struct {
 		Key, Value []byte
@@ -799,7 +1031,7 @@ type kv struct {
 	// this function is expecting an array of (key, value) tuples
 	var kvs []kv
 	if err := scale.Unmarshal(data, &kvs); err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("failed scale decoding data: %s", err)
 		return 0
 	}
 
@@ -810,17 +1042,17 @@ type kv struct {
 	// allocate memory for value and copy value to memory
 	ptr, err := runtimeCtx.Allocator.Allocate(32)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("failed allocating: %s", err)
 		return 0
 	}
 
 	hash, err := t.Hash()
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_root_version_1]: %s", err)
+		logger.Errorf("failed computing trie Merkle root hash: %s", err)
 		return 0
 	}
 
-	logger.Debugf("[ext_trie_blake2_256_root_version_1]: root hash is %s", hash)
+	logger.Debugf("root hash is %s", hash)
 	copy(memory[ptr:ptr+32], hash[:])
 	return C.int32_t(ptr)
 }
@@ -828,24 +1060,44 @@ type kv struct {
 //export ext_trie_blake2_256_ordered_root_version_1
 func ext_trie_blake2_256_ordered_root_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
 	logger.Debug("executing...")
+	return trieOrderedRoot(context, dataSpan, trie.V0)
+}
+
+//export ext_trie_blake2_256_ordered_root_version_2
+func ext_trie_blake2_256_ordered_root_version_2(context unsafe.Pointer, dataSpan C.int64_t, version C.int32_t) C.int32_t {
+	logger.Debug("executing...")
 
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return trieOrderedRoot(context, dataSpan, stateVersion)
+}
+
+// trieOrderedRoot computes the root hash of the trie built from the SCALE encoded
+// values at dataSpan, keyed by their compact encoded index, using the given state
+// trie version.
+func trieOrderedRoot(context unsafe.Pointer, dataSpan C.int64_t, version trie.Version) C.int32_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	memory := instanceContext.Memory().Data()
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
 	data := asMemorySlice(instanceContext, dataSpan)
 
 	t := trie.NewEmptyTrie()
+	t.SetVersion(version)
 	var values [][]byte
 	err := scale.Unmarshal(data, &values)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("failed scale decoding data: %s", err)
 		return 0
 	}
 
 	for i, val := range values {
 		key, err := scale.Marshal(big.NewInt(int64(i)))
 		if err != nil {
-			logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+			logger.Errorf("failed scale encoding value index %d: %s", i, err)
 			return 0
 		}
 		logger.Tracef(
@@ -858,27 +1110,21 @@ func ext_trie_blake2_256_ordered_root_version_1(context unsafe.Pointer, dataSpan
 	// allocate memory for value and copy value to memory
 	ptr, err := runtimeCtx.Allocator.Allocate(32)
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("failed allocating: %s", err)
 		return 0
 	}
 
 	hash, err := t.Hash()
 	if err != nil {
-		logger.Errorf("[ext_trie_blake2_256_ordered_root_version_1]: %s", err)
+		logger.Errorf("failed computing trie Merkle root hash: %s", err)
 		return 0
 	}
 
-	logger.Debugf("[ext_trie_blake2_256_ordered_root_version_1]: root hash is %s", hash)
+	logger.Debugf("root hash is %s", hash)
 	copy(memory[ptr:ptr+32], hash[:])
 	return C.int32_t(ptr)
 }
 
-//export ext_trie_blake2_256_ordered_root_version_2
-func ext_trie_blake2_256_ordered_root_version_2(context unsafe.Pointer, dataSpan C.int64_t, version C.int32_t) C.int32_t {
-	// TODO: update to use state trie version 1 (#2418)
-	return ext_trie_blake2_256_ordered_root_version_1(context, dataSpan)
-}
-
 //export ext_trie_blake2_256_verify_proof_version_1
 func ext_trie_blake2_256_verify_proof_version_1(context unsafe.Pointer, rootSpan C.int32_t, proofSpan, keySpan, valueSpan C.int64_t) C.int32_t {
 	logger.Debug("executing...")
@@ -981,6 +1227,7 @@ func ext_misc_runtime_version_version_1(context unsafe.Pointer, dataSpan C.int64
 
 //export ext_default_child_storage_read_version_1
 func ext_default_child_storage_read_version_1(context unsafe.Pointer, childStorageKey, key, valueOut C.int64_t, offset C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1011,6 +1258,7 @@ func ext_default_child_storage_read_version_1(context unsafe.Pointer, childStora
 
 //export ext_default_child_storage_clear_version_1
 func ext_default_child_storage_clear_version_1(context unsafe.Pointer, childStorageKey, keySpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1028,6 +1276,7 @@ func ext_default_child_storage_clear_version_1(context unsafe.Pointer, childStor
 
 //export ext_default_child_storage_clear_prefix_version_1
 func ext_default_child_storage_clear_prefix_version_1(context unsafe.Pointer, childStorageKey, prefixSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1045,6 +1294,7 @@ func ext_default_child_storage_clear_prefix_version_1(context unsafe.Pointer, ch
 
 //export ext_default_child_storage_exists_version_1
 func ext_default_child_storage_exists_version_1(context unsafe.Pointer, childStorageKey, key C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1063,6 +1313,7 @@ func ext_default_child_storage_exists_version_1(context unsafe.Pointer, childSto
 
 //export ext_default_child_storage_get_version_1
 func ext_default_child_storage_get_version_1(context unsafe.Pointer, childStorageKey, key C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1085,6 +1336,7 @@ func ext_default_child_storage_get_version_1(context unsafe.Pointer, childStorag
 
 //export ext_default_child_storage_next_key_version_1
 func ext_default_child_storage_next_key_version_1(context unsafe.Pointer, childStorageKey, key C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1107,8 +1359,30 @@ func ext_default_child_storage_next_key_version_1(context unsafe.Pointer, childS
 
 //export ext_default_child_storage_root_version_1
 func ext_default_child_storage_root_version_1(context unsafe.Pointer, childStorageKey C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
+	return defaultChildStorageRoot(context, childStorageKey, trie.V0)
+}
 
+//export ext_default_child_storage_root_version_2
+func ext_default_child_storage_root_version_2(context unsafe.Pointer,
+	childStorageKey C.int64_t, version C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
+	logger.Debug("executing...")
+
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return defaultChildStorageRoot(context, childStorageKey, stateVersion)
+}
+
+// defaultChildStorageRoot returns the root hash of the child trie
+// at the given key, computed using the given state trie version.
+func defaultChildStorageRoot(context unsafe.Pointer,
+	childStorageKey C.int64_t, version trie.Version) C.int64_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	storage := instanceContext.Data().(*runtime.Context).Storage
 
@@ -1118,6 +1392,8 @@ func ext_default_child_storage_root_version_1(context unsafe.Pointer, childStora
 		return 0
 	}
 
+	child.SetVersion(version)
+
 	childRoot, err := child.Hash()
 	if err != nil {
 		logger.Errorf("failed to encode child root: %s", err)
@@ -1135,6 +1411,7 @@ func ext_default_child_storage_root_version_1(context unsafe.Pointer, childStora
 
 //export ext_default_child_storage_set_version_1
 func ext_default_child_storage_set_version_1(context unsafe.Pointer, childStorageKeySpan, keySpan, valueSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1157,6 +1434,7 @@ func ext_default_child_storage_set_version_1(context unsafe.Pointer, childStorag
 
 //export ext_default_child_storage_storage_kill_version_1
 func ext_default_child_storage_storage_kill_version_1(context unsafe.Pointer, childStorageKeySpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1169,6 +1447,7 @@ func ext_default_child_storage_storage_kill_version_1(context unsafe.Pointer, ch
 
 //export ext_default_child_storage_storage_kill_version_2
 func ext_default_child_storage_storage_kill_version_2(context unsafe.Pointer, childStorageKeySpan, lim C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1209,6 +1488,7 @@ func (someRemain) Index() uint {
 
 //export ext_default_child_storage_storage_kill_version_3
 func ext_default_child_storage_storage_kill_version_3(context unsafe.Pointer, childStorageKeySpan, lim C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	ctx := instanceContext.Data().(*runtime.Context)
@@ -1261,6 +1541,7 @@ func ext_default_child_storage_storage_kill_version_3(context unsafe.Pointer, ch
 
 //export ext_allocator_free_version_1
 func ext_allocator_free_version_1(context unsafe.Pointer, addr C.int32_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionAllocator)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
@@ -1274,6 +1555,7 @@ func ext_allocator_free_version_1(context unsafe.Pointer, addr C.int32_t) {
 
 //export ext_allocator_malloc_version_1
 func ext_allocator_malloc_version_1(context unsafe.Pointer, size C.int32_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionAllocator)()
 	logger.Tracef("executing with size %d...", int64(size))
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1291,6 +1573,7 @@ func ext_allocator_malloc_version_1(context unsafe.Pointer, size C.int32_t) C.in
 
 //export ext_hashing_blake2_128_version_1
 func ext_hashing_blake2_128_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1317,6 +1600,7 @@ func ext_hashing_blake2_128_version_1(context unsafe.Pointer, dataSpan C.int64_t
 
 //export ext_hashing_blake2_256_version_1
 func ext_hashing_blake2_256_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1341,6 +1625,7 @@ func ext_hashing_blake2_256_version_1(context unsafe.Pointer, dataSpan C.int64_t
 
 //export ext_hashing_keccak_256_version_1
 func ext_hashing_keccak_256_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1365,6 +1650,7 @@ func ext_hashing_keccak_256_version_1(context unsafe.Pointer, dataSpan C.int64_t
 
 //export ext_hashing_sha2_256_version_1
 func ext_hashing_sha2_256_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1384,6 +1670,7 @@ func ext_hashing_sha2_256_version_1(context unsafe.Pointer, dataSpan C.int64_t)
 
 //export ext_hashing_twox_256_version_1
 func ext_hashing_twox_256_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1408,6 +1695,7 @@ func ext_hashing_twox_256_version_1(context unsafe.Pointer, dataSpan C.int64_t)
 
 //export ext_hashing_twox_128_version_1
 func ext_hashing_twox_128_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	data := asMemorySlice(instanceContext, dataSpan)
@@ -1433,6 +1721,7 @@ func ext_hashing_twox_128_version_1(context unsafe.Pointer, dataSpan C.int64_t)
 
 //export ext_hashing_twox_64_version_1
 func ext_hashing_twox_64_version_1(context unsafe.Pointer, dataSpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionHashing)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1459,6 +1748,7 @@ func ext_hashing_twox_64_version_1(context unsafe.Pointer, dataSpan C.int64_t) C
 
 //export ext_offchain_index_set_version_1
 func ext_offchain_index_set_version_1(context unsafe.Pointer, keySpan, valueSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
@@ -1476,6 +1766,7 @@ func ext_offchain_index_set_version_1(context unsafe.Pointer, keySpan, valueSpan
 
 //export ext_offchain_local_storage_clear_version_1
 func ext_offchain_local_storage_clear_version_1(context unsafe.Pointer, kind C.int32_t, key C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
@@ -1501,6 +1792,7 @@ func ext_offchain_local_storage_clear_version_1(context unsafe.Pointer, kind C.i
 
 //export ext_offchain_is_validator_version_1
 func ext_offchain_is_validator_version_1(context unsafe.Pointer) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1513,6 +1805,7 @@ func ext_offchain_is_validator_version_1(context unsafe.Pointer) C.int32_t {
 
 //export ext_offchain_local_storage_compare_and_set_version_1
 func ext_offchain_local_storage_compare_and_set_version_1(context unsafe.Pointer, kind C.int32_t, key, oldValue, newValue C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1552,6 +1845,7 @@ func ext_offchain_local_storage_compare_and_set_version_1(context unsafe.Pointer
 
 //export ext_offchain_local_storage_get_version_1
 func ext_offchain_local_storage_get_version_1(context unsafe.Pointer, kind C.int32_t, key C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1582,6 +1876,7 @@ func ext_offchain_local_storage_get_version_1(context unsafe.Pointer, kind C.int
 
 //export ext_offchain_local_storage_set_version_1
 func ext_offchain_local_storage_set_version_1(context unsafe.Pointer, kind C.int32_t, key, value C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1606,6 +1901,7 @@ func ext_offchain_local_storage_set_version_1(context unsafe.Pointer, kind C.int
 
 //export ext_offchain_network_state_version_1
 func ext_offchain_network_state_version_1(context unsafe.Pointer) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	runtimeCtx := instanceContext.Data().(*runtime.Context)
@@ -1636,6 +1932,7 @@ func ext_offchain_network_state_version_1(context unsafe.Pointer) C.int64_t {
 
 //export ext_offchain_random_seed_version_1
 func ext_offchain_random_seed_version_1(context unsafe.Pointer) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1653,6 +1950,7 @@ func ext_offchain_random_seed_version_1(context unsafe.Pointer) C.int32_t {
 
 //export ext_offchain_submit_transaction_version_1
 func ext_offchain_submit_transaction_version_1(context unsafe.Pointer, data C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1680,14 +1978,16 @@ func ext_offchain_submit_transaction_version_1(context unsafe.Pointer, data C.in
 
 //export ext_offchain_timestamp_version_1
 func ext_offchain_timestamp_version_1(_ unsafe.Pointer) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Trace("executing...")
 
-	now := time.Now().Unix()
+	now := time.Now().UnixMilli()
 	return C.int64_t(now)
 }
 
 //export ext_offchain_sleep_until_version_1
 func ext_offchain_sleep_until_version_1(_ unsafe.Pointer, deadline C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Trace("executing...")
 
 	dur := time.Until(time.UnixMilli(int64(deadline)))
@@ -1698,6 +1998,7 @@ func ext_offchain_sleep_until_version_1(_ unsafe.Pointer, deadline C.int64_t) {
 
 //export ext_offchain_http_request_start_version_1
 func ext_offchain_http_request_start_version_1(context unsafe.Pointer, methodSpan, uriSpan, metaSpan C.int64_t) C.int64_t { // skipcq: RVV-B0012
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1740,6 +2041,7 @@ func ext_offchain_http_request_start_version_1(context unsafe.Pointer, methodSpa
 
 //export ext_offchain_http_request_add_header_version_1
 func ext_offchain_http_request_add_header_version_1(context unsafe.Pointer, reqID C.int32_t, nameSpan, valueSpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 
@@ -1779,6 +2081,190 @@ func ext_offchain_http_request_add_header_version_1(context unsafe.Pointer, reqI
 	return C.int64_t(ptr)
 }
 
+// decodeDeadline decodes the SCALE encoded optional deadline, in milliseconds since the UNIX epoch,
+// passed to the offchain HTTP host functions. A nil deadline means waiting indefinitely.
+func decodeDeadline(enc []byte) (*time.Time, error) {
+	var millis *uint64
+	err := scale.Unmarshal(enc, &millis)
+	if err != nil {
+		return nil, err
+	}
+
+	if millis == nil {
+		return nil, nil
+	}
+
+	deadline := time.UnixMilli(int64(*millis))
+	return &deadline, nil
+}
+
+// httpErrorResult sets the result to the offchain.HTTPError matching the given error
+func httpErrorResult(result *scale.Result, err error) error {
+	httpErr := offchain.HTTPErrorIO
+	errors.As(err, &httpErr)
+	return result.Set(scale.Err, httpErr)
+}
+
+//export ext_offchain_http_request_write_body_version_1
+func ext_offchain_http_request_write_body_version_1(context unsafe.Pointer, reqID C.int32_t,
+	chunkSpan, deadlineSpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
+	logger.Debug("executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	result := scale.NewResult(nil, offchain.HTTPError(0))
+
+	deadline, err := decodeDeadline(asMemorySlice(instanceContext, deadlineSpan))
+	if err != nil {
+		logger.Errorf("failed to decode deadline: %s", err)
+		err = result.Set(scale.Err, offchain.HTTPErrorInvalid)
+	} else {
+		chunk := asMemorySlice(instanceContext, chunkSpan)
+		err = runtimeCtx.OffchainHTTPSet.WriteBody(int16(reqID), chunk, deadline)
+		if err != nil {
+			logger.Debugf("failed to write request body: %s", err)
+			err = httpErrorResult(&result, err)
+		} else {
+			err = result.Set(scale.OK, nil)
+		}
+	}
+
+	if err != nil {
+		logger.Errorf("failed to set the result data: %s", err)
+		return C.int64_t(0)
+	}
+
+	enc, err := scale.Marshal(result)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return C.int64_t(0)
+	}
+
+	ptr, err := toWasmMemory(instanceContext, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return C.int64_t(0)
+	}
+
+	return C.int64_t(ptr)
+}
+
+//export ext_offchain_http_response_wait_version_1
+func ext_offchain_http_response_wait_version_1(context unsafe.Pointer, idsSpan, deadlineSpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
+	logger.Debug("executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	var ids []int16
+	err := scale.Unmarshal(asMemorySlice(instanceContext, idsSpan), &ids)
+	if err != nil {
+		logger.Errorf("failed to decode request ids: %s", err)
+		return C.int64_t(0)
+	}
+
+	deadline, err := decodeDeadline(asMemorySlice(instanceContext, deadlineSpan))
+	if err != nil {
+		logger.Errorf("failed to decode deadline: %s", err)
+		return C.int64_t(0)
+	}
+
+	statuses := scale.NewVaryingDataTypeSlice(offchain.NewHTTPRequestStatus())
+	err = statuses.Add(runtimeCtx.OffchainHTTPSet.Wait(ids, deadline)...)
+	if err != nil {
+		logger.Errorf("failed to set the request statuses: %s", err)
+		return C.int64_t(0)
+	}
+
+	enc, err := scale.Marshal(statuses)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the request statuses: %s", err)
+		return C.int64_t(0)
+	}
+
+	ptr, err := toWasmMemory(instanceContext, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return C.int64_t(0)
+	}
+
+	return C.int64_t(ptr)
+}
+
+//export ext_offchain_http_response_headers_version_1
+func ext_offchain_http_response_headers_version_1(context unsafe.Pointer, reqID C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
+	logger.Debug("executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	headers := runtimeCtx.OffchainHTTPSet.ResponseHeaders(int16(reqID))
+	enc, err := scale.Marshal(headers)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the response headers: %s", err)
+		return C.int64_t(0)
+	}
+
+	ptr, err := toWasmMemory(instanceContext, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return C.int64_t(0)
+	}
+
+	return C.int64_t(ptr)
+}
+
+//export ext_offchain_http_response_read_body_version_1
+func ext_offchain_http_response_read_body_version_1(context unsafe.Pointer, reqID C.int32_t,
+	bufferSpan, deadlineSpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionOffchain)()
+	logger.Debug("executing...")
+
+	instanceContext := wasm.IntoInstanceContext(context)
+	runtimeCtx := instanceContext.Data().(*runtime.Context)
+
+	result := scale.NewResult(uint32(0), offchain.HTTPError(0))
+
+	deadline, err := decodeDeadline(asMemorySlice(instanceContext, deadlineSpan))
+	if err != nil {
+		logger.Errorf("failed to decode deadline: %s", err)
+		err = result.Set(scale.Err, offchain.HTTPErrorInvalid)
+	} else {
+		buffer := asMemorySlice(instanceContext, bufferSpan)
+		var n int
+		n, err = runtimeCtx.OffchainHTTPSet.ReadBody(int16(reqID), buffer, deadline)
+		if err != nil {
+			logger.Debugf("failed to read response body: %s", err)
+			err = httpErrorResult(&result, err)
+		} else {
+			err = result.Set(scale.OK, uint32(n))
+		}
+	}
+
+	if err != nil {
+		logger.Errorf("failed to set the result data: %s", err)
+		return C.int64_t(0)
+	}
+
+	enc, err := scale.Marshal(result)
+	if err != nil {
+		logger.Errorf("failed to scale marshal the result: %s", err)
+		return C.int64_t(0)
+	}
+
+	ptr, err := toWasmMemory(instanceContext, enc)
+	if err != nil {
+		logger.Errorf("failed to allocate result on memory: %s", err)
+		return C.int64_t(0)
+	}
+
+	return C.int64_t(ptr)
+}
+
 func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 	nextLength := big.NewInt(1)
 	var valueRes []byte
@@ -1826,6 +2312,7 @@ func storageAppend(storage runtime.Storage, key, valueToAppend []byte) error {
 
 //export ext_storage_append_version_1
 func ext_storage_append_version_1(context unsafe.Pointer, keySpan, valueSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	ctx := instanceContext.Data().(*runtime.Context)
@@ -1848,6 +2335,7 @@ func ext_storage_append_version_1(context unsafe.Pointer, keySpan, valueSpan C.i
 
 //export ext_storage_changes_root_version_1
 func ext_storage_changes_root_version_1(context unsafe.Pointer, parentHashSpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 	logger.Debug("returning None")
 
@@ -1864,6 +2352,7 @@ func ext_storage_changes_root_version_1(context unsafe.Pointer, parentHashSpan C
 
 //export ext_storage_clear_version_1
 func ext_storage_clear_version_1(context unsafe.Pointer, keySpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	ctx := instanceContext.Data().(*runtime.Context)
@@ -1877,6 +2366,7 @@ func ext_storage_clear_version_1(context unsafe.Pointer, keySpan C.int64_t) {
 
 //export ext_storage_clear_prefix_version_1
 func ext_storage_clear_prefix_version_1(context unsafe.Pointer, prefixSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	ctx := instanceContext.Data().(*runtime.Context)
@@ -1893,6 +2383,7 @@ func ext_storage_clear_prefix_version_1(context unsafe.Pointer, prefixSpan C.int
 
 //export ext_storage_clear_prefix_version_2
 func ext_storage_clear_prefix_version_2(context unsafe.Pointer, prefixSpan, lim C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1938,6 +2429,7 @@ func ext_storage_clear_prefix_version_2(context unsafe.Pointer, prefixSpan, lim
 
 //export ext_storage_exists_version_1
 func ext_storage_exists_version_1(context unsafe.Pointer, keySpan C.int64_t) C.int32_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	storage := instanceContext.Data().(*runtime.Context).Storage
@@ -1955,6 +2447,7 @@ func ext_storage_exists_version_1(context unsafe.Pointer, keySpan C.int64_t) C.i
 
 //export ext_storage_get_version_1
 func ext_storage_get_version_1(context unsafe.Pointer, keySpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -1978,6 +2471,7 @@ func ext_storage_get_version_1(context unsafe.Pointer, keySpan C.int64_t) C.int6
 
 //export ext_storage_next_key_version_1
 func ext_storage_next_key_version_1(context unsafe.Pointer, keySpan C.int64_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -2001,6 +2495,7 @@ func ext_storage_next_key_version_1(context unsafe.Pointer, keySpan C.int64_t) C
 
 //export ext_storage_read_version_1
 func ext_storage_read_version_1(context unsafe.Pointer, keySpan, valueOut C.int64_t, offset C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -2039,11 +2534,32 @@ func ext_storage_read_version_1(context unsafe.Pointer, keySpan, valueOut C.int6
 
 //export ext_storage_root_version_1
 func ext_storage_root_version_1(context unsafe.Pointer) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
+	logger.Trace("executing...")
+	return storageRoot(context, trie.V0)
+}
+
+//export ext_storage_root_version_2
+func ext_storage_root_version_2(context unsafe.Pointer, version C.int32_t) C.int64_t {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
+	stateVersion, err := trie.ParseVersion(uint32(version))
+	if err != nil {
+		logger.Errorf("failed parsing state version: %s", err)
+		return 0
+	}
+
+	return storageRoot(context, stateVersion)
+}
+
+// storageRoot returns the root hash of the storage,
+// computed using the given state trie version.
+func storageRoot(context unsafe.Pointer, version trie.Version) C.int64_t {
 	instanceContext := wasm.IntoInstanceContext(context)
 	storage := instanceContext.Data().(*runtime.Context).Storage
 
+	storage.SetVersion(version)
 	root, err := storage.Root()
 	if err != nil {
 		logger.Errorf("failed to get storage root: %s", err)
@@ -2061,14 +2577,9 @@ func ext_storage_root_version_1(context unsafe.Pointer) C.int64_t {
 	return C.int64_t(rootSpan)
 }
 
-//export ext_storage_root_version_2
-func ext_storage_root_version_2(context unsafe.Pointer, version C.int32_t) C.int64_t {
-	// TODO: update to use state trie version 1 (#2418)
-	return ext_storage_root_version_1(context)
-}
-
 //export ext_storage_set_version_1
 func ext_storage_set_version_1(context unsafe.Pointer, keySpan, valueSpan C.int64_t) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Trace("executing...")
 
 	instanceContext := wasm.IntoInstanceContext(context)
@@ -2089,6 +2600,7 @@ func ext_storage_set_version_1(context unsafe.Pointer, keySpan, valueSpan C.int6
 
 //export ext_storage_start_transaction_version_1
 func ext_storage_start_transaction_version_1(context unsafe.Pointer) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	instanceContext.Data().(*runtime.Context).Storage.BeginStorageTransaction()
@@ -2096,6 +2608,7 @@ func ext_storage_start_transaction_version_1(context unsafe.Pointer) {
 
 //export ext_storage_rollback_transaction_version_1
 func ext_storage_rollback_transaction_version_1(context unsafe.Pointer) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	instanceContext.Data().(*runtime.Context).Storage.RollbackStorageTransaction()
@@ -2103,6 +2616,7 @@ func ext_storage_rollback_transaction_version_1(context unsafe.Pointer) {
 
 //export ext_storage_commit_transaction_version_1
 func ext_storage_commit_transaction_version_1(context unsafe.Pointer) {
+	defer runtime.StartHostFunction(runtime.HostFunctionStorage)()
 	logger.Debug("[ext_storage_commit_transaction_version_1] executing...")
 	instanceContext := wasm.IntoInstanceContext(context)
 	instanceContext.Data().(*runtime.Context).Storage.CommitStorageTransaction()
@@ -2341,6 +2855,10 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_default_child_storage_root_version_2", ext_default_child_storage_root_version_2, C.ext_default_child_storage_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_default_child_storage_set_version_1", ext_default_child_storage_set_version_1, C.ext_default_child_storage_set_version_1)
 	if err != nil {
 		return nil, err
@@ -2465,6 +2983,26 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_offchain_http_request_write_body_version_1", ext_offchain_http_request_write_body_version_1, C.ext_offchain_http_request_write_body_version_1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = imports.Append("ext_offchain_http_response_wait_version_1", ext_offchain_http_response_wait_version_1, C.ext_offchain_http_response_wait_version_1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = imports.Append("ext_offchain_http_response_headers_version_1", ext_offchain_http_response_headers_version_1, C.ext_offchain_http_response_headers_version_1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = imports.Append("ext_offchain_http_response_read_body_version_1", ext_offchain_http_response_read_body_version_1, C.ext_offchain_http_response_read_body_version_1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = imports.Append("ext_sandbox_get_global_val_version_1", ext_sandbox_get_global_val_version_1, C.ext_sandbox_get_global_val_version_1)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_sandbox_instance_teardown_version_1", ext_sandbox_instance_teardown_version_1, C.ext_sandbox_instance_teardown_version_1)
 	if err != nil {
 		return nil, err
@@ -2567,6 +3105,10 @@ func ImportsNodeRuntime() (*wasm.Imports, error) { //nolint:gocyclo
 	if err != nil {
 		return nil, err
 	}
+	_, err = imports.Append("ext_trie_blake2_256_root_version_2", ext_trie_blake2_256_root_version_2, C.ext_trie_blake2_256_root_version_2)
+	if err != nil {
+		return nil, err
+	}
 	_, err = imports.Append("ext_trie_blake2_256_verify_proof_version_1", ext_trie_blake2_256_verify_proof_version_1, C.ext_trie_blake2_256_verify_proof_version_1)
 	if err != nil {
 		return nil, err
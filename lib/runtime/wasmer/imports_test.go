@@ -53,6 +53,7 @@ func Test_ext_offchain_timestamp_version_1(t *testing.T) {
 	runtimeFunc, ok := inst.vm.Exports["rtm_ext_offchain_timestamp_version_1"]
 	require.True(t, ok)
 
+	before := time.Now().UnixMilli()
 	res, err := runtimeFunc(0, 0)
 	require.NoError(t, err)
 
@@ -62,7 +63,9 @@ func Test_ext_offchain_timestamp_version_1(t *testing.T) {
 	err = scale.Unmarshal(data, &timestamp)
 	require.NoError(t, err)
 
-	expected := time.Now().Unix()
+	// the timestamp is in milliseconds
+	expected := time.Now().UnixMilli()
+	require.GreaterOrEqual(t, timestamp, before)
 	require.GreaterOrEqual(t, expected, timestamp)
 }
 
@@ -4,23 +4,22 @@
 package wasmer
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ChainSafe/gossamer/internal/log"
 	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/ChainSafe/gossamer/lib/keystore"
 	"github.com/ChainSafe/gossamer/lib/runtime"
 	"github.com/ChainSafe/gossamer/lib/runtime/offchain"
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
 	"github.com/ChainSafe/gossamer/lib/trie"
 
 	"github.com/ChainSafe/gossamer/lib/crypto"
 
 	wasm "github.com/wasmerio/go-ext-wasm/wasmer"
-
-	"github.com/klauspost/compress/zstd"
 )
 
 // Name represents the name of the interpreter
@@ -29,6 +28,7 @@
 // Check that runtime interfaces are satisfied
 var (
 	_ runtime.Instance = (*Instance)(nil)
+	_ runtime.Cloner   = (*Instance)(nil)
 	_ runtime.Memory   = (*wasm.Memory)(nil)
 
 	logger = log.NewFromGlobal(
@@ -41,6 +41,9 @@
 type Config struct {
 	runtime.InstanceConfig
 	Imports func() (*wasm.Imports, error)
+	// Cache is the compiled runtime cache used to instantiate the code,
+	// the cache set with SetDefaultCache is used if it is nil.
+	Cache *Cache
 }
 
 // Instance represents a v0.8 runtime go-wasmer instance
@@ -48,8 +51,17 @@ type Instance struct {
 	vm       wasm.Instance
 	ctx      *runtime.Context
 	imports  func() (*wasm.Imports, error)
+	cache    *Cache
 	isClosed bool
 	codeHash common.Hash
+	// code is the decompressed wasm code of the instance, kept to clone it.
+	code []byte
+	// heapPages is the number of heap pages given to the runtime,
+	// as found at :heappages when the instance was set up.
+	heapPages uint64
+	// version is the version of the code, used to dispatch the
+	// runtime API calls. It is nil until Core_version is called.
+	version runtime.Version
 	sync.Mutex
 }
 
@@ -96,52 +108,10 @@ func NewInstance(code []byte, cfg *Config) (*Instance, error) {
 		return nil, errors.New("code is empty")
 	}
 
-	var err error
-	code, err = decompressWasm(code)
-	if err != nil {
-		return nil, fmt.Errorf("cannot decompress WASM code: %w", err)
-	}
-
 	logger.Patch(log.SetLevel(cfg.LogLvl), log.SetCallerFunc(true))
 
-	imports, err := cfg.Imports()
-	if err != nil {
-		return nil, err
-	}
-
-	// Provide importable memory for newer runtimes
-	// TODO: determine memory descriptor size that the runtime wants from the wasm. (#1268)
-	// should be doable w/ wasmer 1.0.0.
-	memory, err := wasm.NewMemory(23, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = imports.AppendMemory("memory", memory)
-	if err != nil {
-		return nil, err
-	}
-
-	// Instantiates the WebAssembly module.
-	instance, err := wasm.NewInstanceWithImports(code, imports)
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO: get __heap_base exported value from runtime.
-	// wasmer 0.3.x does not support this, but wasmer 1.0.0 does (#1268)
-	heapBase := runtime.DefaultHeapBase
-
-	// Assume imported memory is used if runtime does not export any
-	if !instance.HasMemory() {
-		instance.Memory = memory
-	}
-
-	allocator := runtime.NewAllocator(instance.Memory, heapBase)
-
 	runtimeCtx := &runtime.Context{
 		Storage:         cfg.Storage,
-		Allocator:       allocator,
 		Keystore:        cfg.Keystore,
 		Validator:       cfg.Role == byte(4),
 		NodeStorage:     cfg.NodeStorage,
@@ -149,35 +119,73 @@ func NewInstance(code []byte, cfg *Config) (*Instance, error) {
 		Transaction:     cfg.Transaction,
 		SigVerifier:     crypto.NewSignatureVerifier(logger),
 		OffchainHTTPSet: offchain.NewHTTPSet(),
+		Sandbox:         sandbox.NewStore(),
 	}
 
-	logger.Debugf("NewInstance called with runtimeCtx: %v", runtimeCtx)
-	instance.SetContextData(runtimeCtx)
+	codeHash := cfg.CodeHash
+	if codeHash == (common.Hash{}) {
+		var err error
+		codeHash, err = common.Blake2bHash(code)
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash code: %w", err)
+		}
+	}
+
+	cache := cfg.Cache
+	if cache == nil {
+		cache = getDefaultCache()
+	}
 
 	inst := &Instance{
-		vm:       instance,
 		ctx:      runtimeCtx,
 		imports:  cfg.Imports,
-		codeHash: cfg.CodeHash,
+		cache:    cache,
+		codeHash: codeHash,
+	}
+
+	err := inst.setupInstanceVM(code)
+	if err != nil {
+		return nil, err
 	}
 
+	logger.Debugf("NewInstance called with runtimeCtx: %v", runtimeCtx)
 	return inst, nil
 }
 
-// decompressWasm decompresses a Wasm blob that may or may not be compressed with zstd
-// ref: https://github.com/paritytech/substrate/blob/master/primitives/maybe-compressed-blob/src/lib.rs
-func decompressWasm(code []byte) ([]byte, error) {
-	compressionFlag := []byte{82, 188, 83, 118, 70, 219, 142, 5}
-	if !bytes.HasPrefix(code, compressionFlag) {
-		return code, nil
+// Clone returns a new instance running the same code with the same number of heap
+// pages, sharing the node services of the instance but with its own memory and
+// context. The storage of the clone is nil and must be set before calling it.
+func (in *Instance) Clone() (runtime.Instance, error) {
+	in.Lock()
+	code, heapPages := in.code, in.heapPages
+	in.Unlock()
+
+	clone := &Instance{
+		ctx: &runtime.Context{
+			Keystore:        in.ctx.Keystore,
+			Validator:       in.ctx.Validator,
+			NodeStorage:     in.ctx.NodeStorage,
+			Network:         in.ctx.Network,
+			Transaction:     in.ctx.Transaction,
+			SigVerifier:     crypto.NewSignatureVerifier(logger),
+			OffchainHTTPSet: offchain.NewHTTPSet(),
+			Sandbox:         sandbox.NewStore(),
+		},
+		imports:  in.imports,
+		cache:    in.cache,
+		codeHash: in.codeHash,
 	}
 
-	decoder, err := zstd.NewReader(nil)
+	err := clone.instantiate(code, heapPages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		return nil, fmt.Errorf("cannot instantiate clone: %w", err)
 	}
 
-	return decoder.DecodeAll(code[len(compressionFlag):], nil)
+	in.Lock()
+	clone.version = in.version
+	in.Unlock()
+
+	return clone, nil
 }
 
 // GetCodeHash returns the code of the instance
@@ -185,6 +193,11 @@ func (in *Instance) GetCodeHash() common.Hash {
 	return in.codeHash
 }
 
+// GetHeapPages returns the number of heap pages given to the runtime
+func (in *Instance) GetHeapPages() uint64 {
+	return in.heapPages
+}
+
 // GetContext returns the context of the instance
 func (in *Instance) GetContext() *runtime.Context {
 	return in.ctx
@@ -206,6 +219,7 @@ func (in *Instance) UpdateRuntimeCode(code []byte) error {
 func (in *Instance) CheckRuntimeVersion(code []byte) (runtime.Version, error) {
 	tmp := &Instance{
 		imports: in.imports,
+		cache:   in.cache,
 		ctx:     in.ctx,
 	}
 
@@ -220,15 +234,39 @@ func (in *Instance) CheckRuntimeVersion(code []byte) (runtime.Version, error) {
 	return tmp.Version()
 }
 
+// setupInstanceVM instantiates the given code, with a memory sized to give the
+// runtime the number of heap pages stored at :heappages in the context storage.
 func (in *Instance) setupInstanceVM(code []byte) error {
+	code, err := runtime.DecompressWasm(code)
+	if err != nil {
+		return fmt.Errorf("cannot decompress WASM code: %w", err)
+	}
+
+	heapPages, err := runtime.GetHeapPages(in.ctx.Storage)
+	if err != nil {
+		return fmt.Errorf("cannot get heap pages: %w", err)
+	}
+
+	return in.instantiate(code, heapPages)
+}
+
+// instantiate instantiates the given decompressed code with a memory sized to give
+// the runtime the heap pages given, and sets up the allocator to start at the heap
+// base exported by the code.
+func (in *Instance) instantiate(code []byte, heapPages uint64) error {
+	moduleMemory, err := runtime.GetModuleMemory(code)
+	if err != nil {
+		return fmt.Errorf("cannot get memory of wasm module: %w", err)
+	}
+	pages := moduleMemory.Pages(heapPages)
+
 	imports, err := in.imports()
 	if err != nil {
 		return err
 	}
 
-	// TODO: determine memory descriptor size that the runtime wants from the wasm.
-	// should be doable w/ wasmer 1.0.0. (#1268)
-	memory, err := wasm.NewMemory(23, 0)
+	// Provide importable memory for newer runtimes
+	memory, err := wasm.NewMemory(pages, moduleMemory.MaximumPages)
 	if err != nil {
 		return err
 	}
@@ -238,8 +276,18 @@ func (in *Instance) setupInstanceVM(code []byte) error {
 		return err
 	}
 
+	// the code is compiled with a function to call the sandbox dispatch thunk
+	compiledCode, err := addDispatchThunkCaller(code)
+	if err != nil {
+		return fmt.Errorf("cannot add dispatch thunk caller: %w", err)
+	}
+
 	// Instantiates the WebAssembly module.
-	in.vm, err = wasm.NewInstanceWithImports(code, imports)
+	if in.cache == nil {
+		in.vm, err = wasm.NewInstanceWithImports(compiledCode, imports)
+	} else {
+		in.vm, err = in.instantiateFromCache(compiledCode, imports)
+	}
 	if err != nil {
 		return err
 	}
@@ -247,17 +295,35 @@ func (in *Instance) setupInstanceVM(code []byte) error {
 	// Assume imported memory is used if runtime does not export any
 	if !in.vm.HasMemory() {
 		in.vm.Memory = memory
+	} else if currentPages := in.vm.Memory.Length() / runtime.PageSize; currentPages < pages {
+		// the runtime defines its own memory, grow it to give the runtime its heap pages.
+		err = in.vm.Memory.Grow(pages - currentPages)
+		if err != nil {
+			return fmt.Errorf("cannot grow memory by %d pages: %w", pages-currentPages, err)
+		}
 	}
 
-	// TODO: get __heap_base exported value from runtime.
-	// wasmer 0.3.x does not support this, but wasmer 1.0.0 does (#1268)
-	heapBase := runtime.DefaultHeapBase
-
-	in.ctx.Allocator = runtime.NewAllocator(in.vm.Memory, heapBase)
+	in.code = code
+	in.heapPages = heapPages
+	in.version = nil
+	in.isClosed = false
+	in.ctx.Allocator = runtime.NewAllocator(in.vm.Memory, moduleMemory.HeapBase)
+	in.ctx.Supervisor = &sandboxSupervisor{instance: in}
 	in.vm.SetContextData(in.ctx)
 	return nil
 }
 
+// instantiateFromCache instantiates the compiled module of the code from the cache.
+func (in *Instance) instantiateFromCache(code []byte, imports *wasm.Imports) (wasm.Instance, error) {
+	module, err := in.cache.Module(code, imports)
+	if err != nil {
+		return wasm.Instance{}, fmt.Errorf("cannot get compiled module: %w", err)
+	}
+	defer module.Close()
+
+	return module.InstantiateWithImports(imports)
+}
+
 // SetContextStorage sets the runtime's storage. It should be set before calls to the below functions.
 func (in *Instance) SetContextStorage(s runtime.Storage) {
 	in.Lock()
@@ -321,7 +387,15 @@ func (in *Instance) exec(function string, data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("could not find exported function %s", function)
 	}
 
+	if tracer, ok := in.ctx.Storage.(runtime.CallTracer); ok {
+		tracer.TraceCall(function)
+		defer tracer.TraceCall("")
+	}
+
+	memoryLength := in.vm.Memory.Length()
+	start := time.Now()
 	res, err := runtimeFunc(int32(ptr), datalen)
+	runtime.ObserveExportCall(function, time.Since(start), in.vm.Memory.Length()-memoryLength)
 	if err != nil {
 		return nil, err
 	}
@@ -336,6 +410,7 @@ func (in *Instance) malloc(size uint32) (uint32, error) {
 
 func (in *Instance) clear() {
 	in.ctx.Allocator.Clear()
+	in.ctx.Sandbox.Reset()
 }
 
 // NodeStorage to get reference to runtime node service
@@ -0,0 +1,354 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package wasmer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// dispatchThunkCallerExport is the name of the function added to the runtime code
+// to call its dispatch thunk, see addDispatchThunkCaller.
+const dispatchThunkCallerExport = "__gossamer_call_dispatch_thunk"
+
+var (
+	errNoFunctionTable   = errors.New("runtime has no function table")
+	errWasmCodeNotValid  = errors.New("wasm code not valid")
+	errOutOfBoundsResult = errors.New("dispatch thunk result out of memory bounds")
+)
+
+// sandboxSupervisor routes calls made by sandboxed guests to the functions
+// they imported from the supervisor runtime.
+type sandboxSupervisor struct {
+	instance *Instance
+}
+
+// Dispatch implements sandbox.Supervisor. The dispatch thunk is an entry of the runtime's
+// indirect function table, which the go-ext-wasm bindings cannot call, so it is called
+// through the function added to the runtime code by addDispatchThunkCaller.
+// The arguments are allocated in the runtime memory for the call, and the result
+// allocated by the runtime is copied and freed, as Substrate does.
+func (s *sandboxSupervisor) Dispatch(dispatchThunk uint32, args []byte, state, index uint32) ([]byte, error) {
+	callThunk, ok := s.instance.vm.Exports[dispatchThunkCallerExport]
+	if !ok {
+		return nil, fmt.Errorf("%w: thunk %d, function %d", errNoFunctionTable, dispatchThunk, index)
+	}
+
+	allocator := s.instance.ctx.Allocator
+	argsPtr, err := allocator.Allocate(uint32(len(args)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate arguments: %w", err)
+	}
+	copy(s.instance.vm.Memory.Data()[argsPtr:], args)
+
+	ret, err := callThunk(int32(dispatchThunk), int32(argsPtr), int32(len(args)), int32(state), int32(index))
+	// the arguments are freed even if the call failed, so trapped calls do not leak them
+	deallocErr := allocator.Deallocate(argsPtr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot call dispatch thunk %d: %w", dispatchThunk, err)
+	} else if deallocErr != nil {
+		return nil, fmt.Errorf("cannot free arguments: %w", deallocErr)
+	}
+
+	// the pointer is in the upper half of the result and the length in the lower half
+	span := uint64(ret.ToI64())
+	resultPtr, resultLen := uint32(span>>32), uint32(span)
+
+	// the memory is loaded after the call, since it may have grown in the meantime
+	memory := s.instance.vm.Memory.Data()
+	if uint64(resultPtr)+uint64(resultLen) > uint64(len(memory)) {
+		return nil, fmt.Errorf("%w: pointer 0x%x, length %d", errOutOfBoundsResult, resultPtr, resultLen)
+	}
+	result := append([]byte{}, memory[resultPtr:resultPtr+resultLen]...)
+
+	err = allocator.Deallocate(resultPtr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot free result: %w", err)
+	}
+
+	return result, nil
+}
+
+// Wasm binary format constants used by addDispatchThunkCaller.
+const (
+	wasmHeaderLength = 8
+
+	wasmSectionCustom    = 0
+	wasmSectionType      = 1
+	wasmSectionImport    = 2
+	wasmSectionFunction  = 3
+	wasmSectionTable     = 4
+	wasmSectionExport    = 7
+	wasmSectionCode      = 10
+	wasmSectionDataCount = 12
+
+	wasmExternFunction = 0
+	wasmExternTable    = 1
+	wasmExternMemory   = 2
+	wasmExternGlobal   = 3
+
+	wasmTypeFunction = 0x60
+	wasmTypeI32      = 0x7f
+	wasmTypeI64      = 0x7e
+)
+
+type wasmSection struct {
+	id       byte
+	contents []byte
+}
+
+// addDispatchThunkCaller returns the given code with an exported function calling the entry
+// of the function table given as first argument with the four other arguments, which is
+// the signature of sp-sandbox's dispatch thunk:
+//
+//	(func (param $thunk i32) (param i32 i32 i32 i32) (result i64)
+//	  local.get 1 local.get 2 local.get 3 local.get 4 local.get 0
+//	  call_indirect (param i32 i32 i32 i32) (result i64))
+//
+// The code is returned unchanged if it has no function table.
+func addDispatchThunkCaller(code []byte) ([]byte, error) {
+	sections, err := readWasmSections(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var importedFunctions, localFunctions, types uint32
+	hasTable := false
+	for _, section := range sections {
+		switch section.id {
+		case wasmSectionType:
+			types, _, err = readWasmVectorLength(section.contents)
+		case wasmSectionFunction:
+			localFunctions, _, err = readWasmVectorLength(section.contents)
+		case wasmSectionTable:
+			var tables uint32
+			tables, _, err = readWasmVectorLength(section.contents)
+			hasTable = hasTable || tables > 0
+		case wasmSectionImport:
+			var importedTables uint32
+			importedFunctions, importedTables, err = countWasmImports(section.contents)
+			hasTable = hasTable || importedTables > 0
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: section %d: %s", errWasmCodeNotValid, section.id, err)
+		}
+	}
+
+	if !hasTable {
+		return code, nil
+	}
+
+	thunkType, callerType := types, types+1
+	thunkSignature := []byte{wasmTypeFunction, 4, wasmTypeI32, wasmTypeI32, wasmTypeI32, wasmTypeI32, 1, wasmTypeI64}
+	callerSignature := []byte{wasmTypeFunction, 5, wasmTypeI32, wasmTypeI32, wasmTypeI32, wasmTypeI32, wasmTypeI32, 1,
+		wasmTypeI64}
+
+	body := []byte{0x00} // no locals
+	for _, local := range []byte{1, 2, 3, 4, 0} {
+		body = append(body, 0x20, local) // local.get
+	}
+	body = append(body, 0x11) // call_indirect
+	body = appendUvarint(body, thunkType)
+	body = append(body, 0x00, 0x0b) // table 0, end
+
+	export := appendUvarint(nil, uint32(len(dispatchThunkCallerExport)))
+	export = append(export, dispatchThunkCallerExport...)
+	export = append(export, wasmExternFunction)
+	export = appendUvarint(export, importedFunctions+localFunctions)
+
+	sections, err = appendWasmVectorItems(sections, wasmSectionType, thunkSignature, callerSignature)
+	if err != nil {
+		return nil, err
+	}
+	sections, err = appendWasmVectorItems(sections, wasmSectionFunction, appendUvarint(nil, callerType))
+	if err != nil {
+		return nil, err
+	}
+	sections, err = appendWasmVectorItems(sections, wasmSectionExport, export)
+	if err != nil {
+		return nil, err
+	}
+	sections, err = appendWasmVectorItems(sections, wasmSectionCode,
+		append(appendUvarint(nil, uint32(len(body))), body...))
+	if err != nil {
+		return nil, err
+	}
+
+	modified := append([]byte{}, code[:wasmHeaderLength]...)
+	for _, section := range sections {
+		modified = append(modified, section.id)
+		modified = appendUvarint(modified, uint32(len(section.contents)))
+		modified = append(modified, section.contents...)
+	}
+	return modified, nil
+}
+
+func readWasmSections(code []byte) (sections []wasmSection, err error) {
+	if len(code) < wasmHeaderLength {
+		return nil, fmt.Errorf("%w: header too short", errWasmCodeNotValid)
+	}
+
+	for offset := wasmHeaderLength; offset < len(code); {
+		id := code[offset]
+		offset++
+
+		size, n := binary.Uvarint(code[offset:])
+		if n <= 0 || size > uint64(len(code)-offset-n) {
+			return nil, fmt.Errorf("%w: section %d size not valid", errWasmCodeNotValid, id)
+		}
+		offset += n
+
+		sections = append(sections, wasmSection{
+			id:       id,
+			contents: code[offset : offset+int(size)],
+		})
+		offset += int(size)
+	}
+
+	return sections, nil
+}
+
+// appendWasmVectorItems appends the items to the vector making up the section with the given
+// id, which is added in its place in the section order if the code does not have it.
+func appendWasmVectorItems(sections []wasmSection, id byte, items ...[]byte) ([]wasmSection, error) {
+	position := len(sections)
+	for i, section := range sections {
+		if section.id == id {
+			position = i
+			break
+		}
+		if section.id != wasmSectionCustom && wasmSectionOrder(section.id) > wasmSectionOrder(id) {
+			position = i
+			sections = append(sections[:i], append([]wasmSection{{id: id, contents: []byte{0}}}, sections[i:]...)...)
+			break
+		}
+	}
+	if position == len(sections) {
+		sections = append(sections, wasmSection{id: id, contents: []byte{0}})
+	}
+
+	length, n, err := readWasmVectorLength(sections[position].contents)
+	if err != nil {
+		return nil, fmt.Errorf("%w: section %d: %s", errWasmCodeNotValid, id, err)
+	}
+
+	contents := appendUvarint(nil, length+uint32(len(items)))
+	contents = append(contents, sections[position].contents[n:]...)
+	for _, item := range items {
+		contents = append(contents, item...)
+	}
+	sections[position].contents = contents
+	return sections, nil
+}
+
+// wasmSectionOrder returns the position of the non custom section
+// with the given id in the order the sections must appear in.
+func wasmSectionOrder(id byte) byte {
+	switch {
+	case id == wasmSectionDataCount:
+		return wasmSectionCode
+	case id >= wasmSectionCode:
+		return id + 1
+	default:
+		return id
+	}
+}
+
+// countWasmImports returns the number of functions and tables imported by the import section.
+func countWasmImports(contents []byte) (functions, tables uint32, err error) {
+	length, offset, err := readWasmVectorLength(contents)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	readUvarint := func() (uint64, error) {
+		value, n := binary.Uvarint(contents[offset:])
+		if n <= 0 {
+			return 0, errors.New("integer not valid")
+		}
+		offset += n
+		return value, nil
+	}
+	readByte := func() (byte, error) {
+		if offset >= len(contents) {
+			return 0, errors.New("unexpected end of section")
+		}
+		offset++
+		return contents[offset-1], nil
+	}
+	readLimits := func() error {
+		flags, err := readByte()
+		if err != nil {
+			return err
+		}
+		_, err = readUvarint()
+		if err != nil || flags&1 == 0 {
+			return err
+		}
+		_, err = readUvarint()
+		return err
+	}
+
+	for i := uint32(0); i < length; i++ {
+		// module and field names
+		for j := 0; j < 2; j++ {
+			nameLength, err := readUvarint()
+			if err != nil {
+				return 0, 0, err
+			}
+			if nameLength > uint64(len(contents)-offset) {
+				return 0, 0, errors.New("unexpected end of section")
+			}
+			offset += int(nameLength)
+		}
+
+		kind, err := readByte()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		switch kind {
+		case wasmExternFunction:
+			functions++
+			_, err = readUvarint()
+		case wasmExternTable:
+			tables++
+			_, err = readByte() // element type
+			if err == nil {
+				err = readLimits()
+			}
+		case wasmExternMemory:
+			err = readLimits()
+		case wasmExternGlobal:
+			_, err = readByte() // value type
+			if err == nil {
+				_, err = readByte() // mutability
+			}
+		default:
+			err = fmt.Errorf("import kind %d not valid", kind)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return functions, tables, nil
+}
+
+// readWasmVectorLength returns the length of the vector at the start
+// of the given section contents, and the number of bytes read.
+func readWasmVectorLength(contents []byte) (length uint32, n int, err error) {
+	value, n := binary.Uvarint(contents)
+	if n <= 0 || value > uint64(^uint32(0)) {
+		return 0, 0, errors.New("vector length not valid")
+	}
+	return uint32(value), n, nil
+}
+
+func appendUvarint(buffer []byte, value uint32) []byte {
+	var encoded [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(encoded[:], uint64(value))
+	return append(buffer, encoded[:n]...)
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package wasmer
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/lib/runtime/sandbox"
+	"github.com/ChainSafe/gossamer/lib/runtime/storage"
+	"github.com/ChainSafe/gossamer/pkg/scale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The modules below are hand assembled.
+
+func wasmModule(sections ...[]byte) (module []byte) {
+	module = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, section := range sections {
+		module = append(module, section...)
+	}
+	return module
+}
+
+func newWasmSection(id byte, items ...[]byte) []byte {
+	contents := wasmVec(items...)
+	return append(appendUvarint([]byte{id}, uint32(len(contents))), contents...)
+}
+
+func wasmVec(items ...[]byte) (vec []byte) {
+	vec = appendUvarint(nil, uint32(len(items)))
+	for _, item := range items {
+		vec = append(vec, item...)
+	}
+	return vec
+}
+
+func wasmName(name string) []byte {
+	return append(appendUvarint(nil, uint32(len(name))), name...)
+}
+
+func wasmBody(locals []byte, code ...byte) []byte {
+	body := append(locals, code...)
+	body = append(body, 0x0b)
+	return append(appendUvarint(nil, uint32(len(body))), body...)
+}
+
+// wasmI64Const returns the i64.const instruction for the given non negative value.
+func wasmI64Const(value int64) []byte {
+	instruction := []byte{0x42}
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 && b&0x40 == 0 {
+			return append(instruction, b)
+		}
+		instruction = append(instruction, b|0x80)
+	}
+}
+
+func wasmSpan(ptr, length uint32) int64 {
+	return int64(ptr) | int64(length)<<32
+}
+
+func concat(parts ...[]byte) (out []byte) {
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// sandboxGuest is
+// (module
+//
+//	(import "env" "double" (func (param i32) (result i32)))
+//	(func (export "call_double") (param i32) (result i32) local.get 0 call 0))
+var sandboxGuest = wasmModule(
+	newWasmSection(0x01, []byte{0x60, 0x01, 0x7f, 0x01, 0x7f}),
+	newWasmSection(0x02, concat(wasmName("env"), wasmName("double"), []byte{0x00, 0x00})),
+	newWasmSection(0x03, []byte{0x00}),
+	newWasmSection(0x07, concat(wasmName("call_double"), []byte{0x00, 0x01})),
+	newWasmSection(0x0a, wasmBody([]byte{0x00}, 0x20, 0x00, 0x10, 0x00)),
+)
+
+// Memory layout of the sandbox supervisor.
+const (
+	guestCodePtr  = 0x100
+	envDefPtr     = 0x200
+	exportNamePtr = 0x300
+	argsPtr       = 0x340
+	// resultPtr holds the code returned by ext_sandbox_invoke_version_1,
+	// followed by the return value of the guest function.
+	resultPtr    = 0x400
+	returnValLen = 16
+)
+
+// newSandboxSupervisor returns a supervisor runtime with a dispatch thunk at index 1 of its
+// function table, which returns I32(arg * f + state) for a call with the single I32 argument
+// arg to the supervisor function f. Its "run" function instantiates the guest given with the
+// environment definition given, and invokes its "call_double" function with the argument given
+// and the state 2. It returns the sandbox return code followed by the guest return value.
+func newSandboxSupervisor(t *testing.T, guest, envDef, args []byte) []byte {
+	t.Helper()
+
+	thunk := concat(
+		// ptr = ext_allocator_malloc_version_1(7)
+		[]byte{0x41, 0x07, 0x10, 0x02, 0x21, 0x04},
+		// Ok(ReturnValue::Value(Value::I32(...)))
+		[]byte{0x20, 0x04, 0x41, 0x80, 0x02, 0x3b, 0x00, 0x00},
+		[]byte{0x20, 0x04, 0x41, 0x00, 0x3a, 0x00, 0x02},
+		// the value is the argument, after the vector length and value tag, times f plus the state
+		[]byte{0x20, 0x04, 0x20, 0x00, 0x28, 0x00, 0x02, 0x20, 0x03, 0x6c, 0x20, 0x02, 0x6a, 0x36, 0x00, 0x03},
+		// ptr << 32 | 7
+		[]byte{0x20, 0x04, 0xad}, wasmI64Const(32), []byte{0x86}, wasmI64Const(7), []byte{0x84},
+	)
+	return newSandboxSupervisorWithThunk(t, thunk, guest, envDef, args)
+}
+
+// newSandboxSupervisorWithThunk returns the supervisor runtime of newSandboxSupervisor
+// with the given body for its dispatch thunk.
+func newSandboxSupervisorWithThunk(t *testing.T, thunk, guest, envDef, args []byte) []byte {
+	t.Helper()
+
+	exportName := "call_double"
+	run := concat(
+		[]byte{0x41, 0x01},
+		wasmI64Const(wasmSpan(guestCodePtr, uint32(len(guest)))),
+		wasmI64Const(wasmSpan(envDefPtr, uint32(len(envDef)))),
+		[]byte{0x41, 0x00, 0x10, 0x00, 0x21, 0x02},
+		appendUvarint([]byte{0x41}, resultPtr),
+		[]byte{0x20, 0x02},
+		wasmI64Const(wasmSpan(exportNamePtr, uint32(len(exportName)))),
+		wasmI64Const(wasmSpan(argsPtr, uint32(len(args)))),
+		appendUvarint([]byte{0x41}, resultPtr+4),
+		[]byte{0x41, returnValLen, 0x41, 0x02, 0x10, 0x01},
+		[]byte{0x36, 0x02, 0x00},
+		wasmI64Const(wasmSpan(resultPtr, 4+returnValLen)),
+	)
+
+	dataSegment := func(ptr uint32, data []byte) []byte {
+		return concat([]byte{0x00}, appendUvarint([]byte{0x41}, ptr), []byte{0x0b}, appendUvarint(nil, uint32(len(data))),
+			data)
+	}
+
+	return wasmModule(
+		newWasmSection(0x01,
+			[]byte{0x60, 0x04, 0x7f, 0x7e, 0x7e, 0x7f, 0x01, 0x7f},
+			[]byte{0x60, 0x06, 0x7f, 0x7e, 0x7e, 0x7f, 0x7f, 0x7f, 0x01, 0x7f},
+			[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},
+			[]byte{0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7e},
+			[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e},
+		),
+		newWasmSection(0x02,
+			concat(wasmName("env"), wasmName("ext_sandbox_instantiate_version_1"), []byte{0x00, 0x00}),
+			concat(wasmName("env"), wasmName("ext_sandbox_invoke_version_1"), []byte{0x00, 0x01}),
+			concat(wasmName("env"), wasmName("ext_allocator_malloc_version_1"), []byte{0x00, 0x02}),
+			concat(wasmName("env"), wasmName("memory"), []byte{0x02, 0x00, 0x01}),
+		),
+		newWasmSection(0x03, []byte{0x03}, []byte{0x04}),
+		newWasmSection(0x04, []byte{0x70, 0x00, 0x02}),
+		newWasmSection(0x07, concat(wasmName("run"), []byte{0x00, 0x04})),
+		newWasmSection(0x09, []byte{0x00, 0x41, 0x01, 0x0b, 0x01, 0x03}),
+		newWasmSection(0x0a,
+			wasmBody([]byte{0x01, 0x01, 0x7f}, thunk...),
+			wasmBody([]byte{0x01, 0x01, 0x7f}, run...),
+		),
+		newWasmSection(0x0b,
+			dataSegment(guestCodePtr, guest),
+			dataSegment(envDefPtr, envDef),
+			dataSegment(exportNamePtr, []byte(exportName)),
+			dataSegment(argsPtr, args),
+		),
+	)
+}
+
+func Test_ext_sandbox_invoke_version_1_dispatch(t *testing.T) {
+	t.Parallel()
+
+	envDef, err := scale.Marshal([]sandbox.EnvironmentEntry{{
+		Module: []byte("env"),
+		Field:  []byte("double"),
+		Kind:   sandbox.ExternFunction,
+		Index:  2,
+	}})
+	require.NoError(t, err)
+
+	args, err := sandbox.EncodeValues([]scale.VaryingDataTypeValue{sandbox.I32(20)})
+	require.NoError(t, err)
+
+	code := newSandboxSupervisor(t, sandboxGuest, envDef, args)
+
+	trieState, err := storage.NewTrieState(nil)
+	require.NoError(t, err)
+	cfg := &Config{Imports: ImportsNodeRuntime}
+	cfg.Storage = trieState
+	cfg.LogLvl = DefaultTestLogLvl
+
+	instance, err := NewInstance(code, cfg)
+	require.NoError(t, err)
+	defer instance.Stop()
+
+	result, err := instance.Exec("run", nil)
+	require.NoError(t, err)
+
+	expectedValue, err := sandbox.EncodeReturnValue(sandbox.I32(42))
+	require.NoError(t, err)
+	expected := make([]byte, 4+returnValLen)
+	copy(expected[4:], expectedValue)
+	assert.Equal(t, expected, result)
+
+	// the guest instances only live for the duration of the call
+	_, err = instance.ctx.Sandbox.Instance(0)
+	assert.Error(t, err)
+}
+
+func Test_sandboxSupervisor_Dispatch_trap(t *testing.T) {
+	t.Parallel()
+
+	// the dispatch thunk is unreachable
+	code := newSandboxSupervisorWithThunk(t, []byte{0x00}, sandboxGuest, nil, nil)
+
+	trieState, err := storage.NewTrieState(nil)
+	require.NoError(t, err)
+	cfg := &Config{Imports: ImportsNodeRuntime}
+	cfg.Storage = trieState
+	cfg.LogLvl = DefaultTestLogLvl
+
+	instance, err := NewInstance(code, cfg)
+	require.NoError(t, err)
+	defer instance.Stop()
+
+	args, err := sandbox.EncodeValues([]scale.VaryingDataTypeValue{sandbox.I32(20)})
+	require.NoError(t, err)
+
+	// the pointer freed is reused by the next allocation of the same size,
+	// unless it is the first pointer of the heap.
+	allocator := instance.ctx.Allocator
+	_, err = allocator.Allocate(uint32(len(args)))
+	require.NoError(t, err)
+	argsPtr, err := allocator.Allocate(uint32(len(args)))
+	require.NoError(t, err)
+	err = allocator.Deallocate(argsPtr)
+	require.NoError(t, err)
+
+	supervisor := &sandboxSupervisor{instance: instance}
+	_, err = supervisor.Dispatch(1, args, 2, 0)
+	require.Error(t, err)
+
+	// the arguments are freed even though the dispatch thunk trapped
+	ptr, err := allocator.Allocate(uint32(len(args)))
+	require.NoError(t, err)
+	assert.Equal(t, argsPtr, ptr)
+}
+
+func Test_addDispatchThunkCaller(t *testing.T) {
+	t.Parallel()
+
+	modified, err := addDispatchThunkCaller(sandboxGuest)
+	require.NoError(t, err)
+	assert.Equal(t, sandboxGuest, modified)
+
+	_, err = addDispatchThunkCaller([]byte{0x00, 0x61})
+	assert.ErrorIs(t, err, errWasmCodeNotValid)
+}
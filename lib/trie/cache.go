@@ -0,0 +1,146 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultNodeCacheSize is the default maximum size in bytes of the
+// cache of trie nodes decoded from the database.
+const DefaultNodeCacheSize = 64 * 1024 * 1024
+
+// nodeCacheEntryOverhead is an estimate of the memory used by a cached
+// node in addition to its encoding, used to account for the cache size.
+const nodeCacheEntryOverhead = 256
+
+var (
+	nodeCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "gossamer_trie_node_cache",
+		Name:      "hits_total",
+		Help:      "total number of trie nodes found in the decoded nodes cache",
+	})
+	nodeCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "gossamer_trie_node_cache",
+		Name:      "misses_total",
+		Help:      "total number of trie nodes not found in the decoded nodes cache",
+	})
+	nodeCacheSizeGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gossamer_trie_node_cache",
+		Name:      "size_bytes",
+		Help:      "estimated size of the decoded nodes cache",
+	})
+	nodeCacheEntriesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gossamer_trie_node_cache",
+		Name:      "entries",
+		Help:      "number of trie nodes in the decoded nodes cache",
+	})
+)
+
+// nodeCache is the process wide cache of the trie nodes decoded from the
+// database, shared by all the lazy tries and GetFromDB lookups.
+var nodeCache = newDecodedNodeCache(DefaultNodeCacheSize)
+
+// SetNodeCacheSize sets the maximum size in bytes of the process wide cache
+// of trie nodes decoded from the database, evicting the least recently used
+// nodes if needed. A size of 0 disables the cache.
+func SetNodeCacheSize(maxSize int) {
+	nodeCache.setMaxSize(maxSize)
+}
+
+// decodedNodeCache is a thread safe least recently used cache of decoded
+// trie nodes keyed by node hash, bounded by the estimated size of the nodes.
+// The nodes cached are shared and must never be modified.
+type decodedNodeCache struct {
+	mutex   sync.Mutex
+	maxSize int
+	size    int
+	// hashToElement maps node hashes to the elements of the linked list.
+	hashToElement map[string]*list.Element
+	// linkedList holds the cached nodes from the most to the least recently used.
+	linkedList *list.List
+}
+
+type decodedNodeCacheEntry struct {
+	hash string
+	node Node
+	size int
+}
+
+func newDecodedNodeCache(maxSize int) *decodedNodeCache {
+	return &decodedNodeCache{
+		maxSize:       maxSize,
+		hashToElement: make(map[string]*list.Element),
+		linkedList:    list.New(),
+	}
+}
+
+// get returns the cached node with the hash given, or nil if it is not cached.
+func (c *decodedNodeCache) get(hash []byte) (n Node) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.hashToElement[string(hash)]
+	if !ok {
+		nodeCacheMisses.Inc()
+		return nil
+	}
+
+	nodeCacheHits.Inc()
+	c.linkedList.MoveToFront(element)
+	return element.Value.(*decodedNodeCacheEntry).node
+}
+
+// put caches the node given with its hash and the length of its encoding.
+func (c *decodedNodeCache) put(hash []byte, n Node, encodingLength int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	size := encodingLength + nodeCacheEntryOverhead
+	if size > c.maxSize {
+		return
+	}
+
+	element, ok := c.hashToElement[string(hash)]
+	if ok {
+		// node decoded concurrently and already cached
+		c.linkedList.MoveToFront(element)
+		return
+	}
+
+	entry := &decodedNodeCacheEntry{
+		hash: string(hash),
+		node: n,
+		size: size,
+	}
+	c.hashToElement[entry.hash] = c.linkedList.PushFront(entry)
+	c.size += size
+	c.evict()
+}
+
+func (c *decodedNodeCache) setMaxSize(maxSize int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxSize = maxSize
+	c.evict()
+}
+
+// evict removes the least recently used nodes until
+// the cache size is within its maximum size.
+// It must be called with the mutex held.
+func (c *decodedNodeCache) evict() {
+	for c.size > c.maxSize {
+		element := c.linkedList.Back()
+		entry := c.linkedList.Remove(element).(*decodedNodeCacheEntry)
+		delete(c.hashToElement, entry.hash)
+		c.size -= entry.size
+	}
+
+	nodeCacheSizeGauge.Set(float64(c.size))
+	nodeCacheEntriesGauge.Set(float64(c.linkedList.Len()))
+}
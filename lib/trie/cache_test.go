@@ -0,0 +1,82 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodedNodeCache(t *testing.T) {
+	t.Parallel()
+
+	const entrySize = 10 + nodeCacheEntryOverhead
+	cache := newDecodedNodeCache(2 * entrySize)
+
+	leafA := &node.Leaf{Key: []byte{1}}
+	leafB := &node.Leaf{Key: []byte{2}}
+	leafC := &node.Leaf{Key: []byte{3}}
+
+	cache.put([]byte("a"), leafA, 10)
+	cache.put([]byte("b"), leafB, 10)
+	assert.Same(t, leafA, cache.get([]byte("a")))
+	assert.Same(t, leafB, cache.get([]byte("b")))
+	assert.Nil(t, cache.get([]byte("c")))
+
+	// a is the least recently used node and is evicted
+	cache.put([]byte("c"), leafC, 10)
+	assert.Nil(t, cache.get([]byte("a")))
+	assert.Same(t, leafB, cache.get([]byte("b")))
+	assert.Same(t, leafC, cache.get([]byte("c")))
+	assert.Equal(t, 2*entrySize, cache.size)
+
+	// node already cached is kept
+	cache.put([]byte("c"), leafA, 10)
+	assert.Same(t, leafC, cache.get([]byte("c")))
+
+	// node larger than the cache is not cached
+	cache.put([]byte("d"), leafA, 2*entrySize)
+	assert.Nil(t, cache.get([]byte("d")))
+
+	cache.setMaxSize(entrySize)
+	assert.Nil(t, cache.get([]byte("b")))
+	assert.Same(t, leafC, cache.get([]byte("c")))
+	assert.Equal(t, entrySize, cache.size)
+
+	cache.setMaxSize(0)
+	assert.Nil(t, cache.get([]byte("c")))
+	assert.Equal(t, 0, cache.size)
+	assert.Empty(t, cache.hashToElement)
+}
+
+func Test_LazyTrie_sharesDecodedNodes(t *testing.T) {
+	t.Parallel()
+
+	expected, db := newLazyTestTrie(t, V0)
+	key := []byte("key042")
+
+	first, err := NewLazyTrie(db, expected.MustHash())
+	require.NoError(t, err)
+	assert.Equal(t, expected.Get(key), first.Get(key))
+
+	hits := testutil.ToFloat64(nodeCacheHits)
+
+	second, err := NewLazyTrie(db, expected.MustHash())
+	require.NoError(t, err)
+	assert.Equal(t, expected.Get(key), second.Get(key))
+	assert.Greater(t, testutil.ToFloat64(nodeCacheHits), hits)
+
+	// modifying a trie does not modify the nodes shared with the other trie
+	second.Put(key, []byte("modified"))
+	assert.Equal(t, expected.Get(key), first.Get(key))
+	assert.Equal(t, expected.MustHash(), first.MustHash())
+
+	third, err := NewLazyTrie(db, expected.MustHash())
+	require.NoError(t, err)
+	assert.Equal(t, expected.Get(key), third.Get(key))
+}
@@ -19,6 +19,7 @@
 // A child trie is added as a node (K, V) in the main trie. K is the child storage key
 // associated to the child trie, and V is the root hash of the child trie.
 func (t *Trie) PutChild(keyToChild []byte, child *Trie) error {
+	child.SetVersion(t.version)
 	childHash, err := child.Hash()
 	if err != nil {
 		return err
@@ -33,8 +34,10 @@ func (t *Trie) PutChild(keyToChild []byte, child *Trie) error {
 // GetChild returns the child trie at key :child_storage:[keyToChild]
 func (t *Trie) GetChild(keyToChild []byte) (*Trie, error) {
 	key := append(ChildStorageKeyPrefix, keyToChild...)
-	childHash := t.Get(key)
-	if childHash == nil {
+	childHash, err := t.GetWithError(key)
+	if err != nil {
+		return nil, err
+	} else if childHash == nil {
 		return nil, fmt.Errorf("%w at key 0x%x%x", ErrChildTrieDoesNotExist, ChildStorageKeyPrefix, keyToChild)
 	}
 
@@ -77,8 +80,7 @@ func (t *Trie) GetFromChild(keyToChild, key []byte) ([]byte, error) {
 		return nil, fmt.Errorf("%w at key 0x%x%x", ErrChildTrieDoesNotExist, ChildStorageKeyPrefix, keyToChild)
 	}
 
-	val := child.Get(key)
-	return val, nil
+	return child.GetWithError(key)
 }
 
 // DeleteChild deletes the child storage trie
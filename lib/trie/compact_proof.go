@@ -0,0 +1,275 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+var (
+	// ErrRootNotInProof is returned when the root node is not in the proof.
+	ErrRootNotInProof = errors.New("root node not found in proof")
+
+	// ErrCompactProofRootMismatch is returned when the root hash of a compact proof
+	// does not match the expected root hash.
+	ErrCompactProofRootMismatch = errors.New("compact proof root hash mismatch")
+
+	// ErrIncompleteCompactProof is returned when a compact proof ends before
+	// all the nodes referenced by its nodes are decoded.
+	ErrIncompleteCompactProof = errors.New("incomplete compact proof")
+
+	// ErrExtraneousCompactProofNodes is returned when a compact proof has nodes
+	// which are not part of its state trie or of one of its child tries.
+	ErrExtraneousCompactProofNodes = errors.New("extraneous nodes in compact proof")
+
+	// ErrInvalidChildTrieRoot is returned when the value stored at
+	// a child trie key is not a valid child trie root hash.
+	ErrInvalidChildTrieRoot = errors.New("invalid child trie root hash")
+)
+
+// EncodeCompactProof returns the compact proof of the proof given, for the trie
+// with the root hash given and its child tries. The compact proof is the one used
+// by Substrate: nodes are listed in depth-first order, the hashes of the children
+// listed in the proof are omitted from the encoding of their parent, and values
+// stored by hash are listed after their node. The nodes of the child tries in the
+// proof are listed after the nodes of the state trie, in the order of their keys.
+// Note Substrate only uses compact proofs for its state sync protocol, which is
+// not implemented, and its light client protocol uses non compact proofs, so
+// compact proofs are only returned by the RPC methods for now.
+func EncodeCompactProof(proof [][]byte, rootHash []byte) (compactProof [][]byte, err error) {
+	proofDB, err := newProofDatabase(proof)
+	if err != nil {
+		return nil, err
+	}
+
+	compactProof, err = encodeCompactNode(proofDB, rootHash, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode state trie: %w", err)
+	}
+
+	childRootHashes, err := childTrieRootsInProof(proof, rootHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, childRootHash := range childRootHashes {
+		_, ok := proofDB[string(childRootHash)]
+		if !ok {
+			// the nodes of the child trie are not in the proof
+			continue
+		}
+
+		compactProof, err = encodeCompactNode(proofDB, childRootHash, compactProof)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode child trie with root hash 0x%x: %w", childRootHash, err)
+		}
+	}
+
+	return compactProof, nil
+}
+
+// encodeCompactNode appends the compact encoding of the node with the hash
+// given, followed by its value stored by hash and by the compact encodings of
+// its children if they are in the proof, to the compact proof given.
+func encodeCompactNode(proofDB proofDatabase, hash []byte,
+	compactProof [][]byte) (newCompactProof [][]byte, err error) {
+	encoding, ok := proofDB[string(hash)]
+	if !ok {
+		return nil, fmt.Errorf("%w: for hash 0x%x", ErrRootNotInProof, hash)
+	}
+
+	valueHash, children, err := node.DecodeReferences(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 0x%x: %s", ErrDecodeNode, encoding, err)
+	}
+
+	// the compact encoding of the node is set once the omitted children are known.
+	nodeIndex := len(compactProof)
+	compactProof = append(compactProof, nil)
+
+	value, omitValue := proofDB[string(valueHash)]
+	if valueHash != nil && omitValue {
+		compactProof = append(compactProof, value)
+	}
+
+	var omittedChildren [node.ChildrenCapacity]bool
+	for i, childHash := range children {
+		if len(childHash) != common.HashLength {
+			// no child or child inlined in the node
+			continue
+		}
+
+		_, ok := proofDB[string(childHash)]
+		if !ok {
+			continue
+		}
+
+		omittedChildren[i] = true
+		compactProof, err = encodeCompactNode(proofDB, childHash, compactProof)
+		if err != nil {
+			// Note: do not wrap error since this is called recursively.
+			return nil, err
+		}
+	}
+
+	compactProof[nodeIndex], err = node.EncodeCompact(encoding, omittedChildren, valueHash != nil && omitValue)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compact encode node 0x%x: %w", encoding, err)
+	}
+
+	return compactProof, nil
+}
+
+// DecodeCompactProof returns the proof of the compact proof given, checking
+// the root hash of its state trie is the root hash given. The proof is made of
+// the encodings of the nodes and of the values stored by hash, of the state
+// trie and of its child tries, which can be used to verify the proof.
+func DecodeCompactProof(compactProof [][]byte, rootHash []byte) (proof [][]byte, err error) {
+	if len(compactProof) == 0 {
+		return nil, ErrEmptyProof
+	}
+
+	compactProof, stateRootHash, proof, err := decodeCompactNode(compactProof, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode state trie: %w", err)
+	}
+
+	if !bytes.Equal(stateRootHash, rootHash) {
+		return nil, fmt.Errorf("%w: expected 0x%x but got 0x%x",
+			ErrCompactProofRootMismatch, rootHash, stateRootHash)
+	}
+
+	childRootHashes, err := childTrieRootsInProof(proof, rootHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Child tries are listed in the order of their keys, and the nodes
+	// of the child tries not accessed are not in the compact proof.
+	var nextChildRootHash []byte
+	for _, childRootHash := range childRootHashes {
+		if nextChildRootHash == nil && len(compactProof) > 0 {
+			compactProof, nextChildRootHash, proof, err = decodeCompactNode(compactProof, proof)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode child trie: %w", err)
+			}
+		}
+
+		if bytes.Equal(childRootHash, nextChildRootHash) {
+			nextChildRootHash = nil
+		}
+	}
+
+	if nextChildRootHash != nil {
+		return nil, fmt.Errorf("%w: child trie with root hash 0x%x",
+			ErrExtraneousCompactProofNodes, nextChildRootHash)
+	} else if len(compactProof) > 0 {
+		return nil, fmt.Errorf("%w: %d nodes left",
+			ErrExtraneousCompactProofNodes, len(compactProof))
+	}
+
+	return proof, nil
+}
+
+// decodeCompactNode decodes the first node of the compact proof given, and its
+// value and children listed after it. It appends the encodings of the node, its
+// value and its children to the proof given, and returns the compact proof left
+// to decode and the hash of the node.
+func decodeCompactNode(compactProof, proof [][]byte) (
+	remaining [][]byte, hash []byte, newProof [][]byte, err error) {
+	if len(compactProof) == 0 {
+		return nil, nil, nil, ErrIncompleteCompactProof
+	}
+
+	compactEncoding := compactProof[0]
+	compactProof = compactProof[1:]
+
+	var valueHash []byte
+	if node.CompactValueOmitted(compactEncoding) {
+		if len(compactProof) == 0 {
+			return nil, nil, nil, fmt.Errorf("%w: value of node 0x%x is missing",
+				ErrIncompleteCompactProof, compactEncoding)
+		}
+
+		value := compactProof[0]
+		compactProof = compactProof[1:]
+		digest, err := common.Blake2bHash(value)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("cannot hash value: %w", err)
+		}
+		valueHash = digest[:]
+		proof = append(proof, value)
+	}
+
+	_, references, err := node.DecodeReferences(compactEncoding)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: 0x%x: %s", ErrDecodeNode, compactEncoding, err)
+	}
+
+	var childrenHashes [node.ChildrenCapacity][]byte
+	for i, reference := range references {
+		if reference == nil || len(reference) > 0 {
+			// no child, or child not omitted
+			continue
+		}
+
+		compactProof, childrenHashes[i], proof, err = decodeCompactNode(compactProof, proof)
+		if err != nil {
+			// Note: do not wrap error since this is called recursively.
+			return nil, nil, nil, err
+		}
+	}
+
+	encoding, err := node.DecodeCompact(compactEncoding, childrenHashes, valueHash)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: 0x%x: %s", ErrDecodeNode, compactEncoding, err)
+	}
+
+	digest, err := common.Blake2bHash(encoding)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot hash node: %w", err)
+	}
+	proof = append(proof, encoding)
+
+	return compactProof, digest[:], proof, nil
+}
+
+// childTrieRootsInProof returns the root hashes of the child tries
+// whose key is in the proof given, in the order of their keys.
+func childTrieRootsInProof(proof [][]byte, rootHash []byte) (rootHashes [][]byte, err error) {
+	proofTrie := NewEmptyTrie()
+	err = proofTrie.LoadFromProof(proof, rootHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLoadFromProof, err)
+	}
+
+	for _, key := range proofTrie.GetKeysWithPrefix(ChildStorageKeyPrefix) {
+		value := proofTrie.Get(key)
+		if value == nil {
+			// the node of the key is not in the proof
+			continue
+		} else if len(value) != common.HashLength {
+			return nil, fmt.Errorf("%w: 0x%x for key 0x%x", ErrInvalidChildTrieRoot, value, key)
+		}
+		rootHashes = append(rootHashes, value)
+	}
+
+	return rootHashes, nil
+}
+
+// VerifyCompactProof verifies the keys and values given are in the compact proof
+// given, for the trie with the root hash given. It behaves as VerifyProof.
+func VerifyCompactProof(compactProof [][]byte, root []byte, items []Pair) (bool, error) {
+	proof, err := DecodeCompactProof(compactProof, root)
+	if err != nil {
+		return false, fmt.Errorf("cannot decode compact proof: %w", err)
+	}
+
+	return VerifyProof(proof, root, items)
+}
@@ -0,0 +1,264 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/internal/trie/record"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proofSet returns the proof items given as a set,
+// since the order of the proof items does not matter.
+func proofSet(proof [][]byte) (set map[string]struct{}) {
+	set = make(map[string]struct{}, len(proof))
+	for _, item := range proof {
+		set[string(item)] = struct{}{}
+	}
+	return set
+}
+
+func Test_EncodeCompactProof_DecodeCompactProof(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []Version{V0, V1} {
+		version := version
+		t.Run(version.String(), func(t *testing.T) {
+			t.Parallel()
+
+			tr, db := newLazyTestTrie(t, version)
+			rootHash := tr.MustHash()
+			keys := [][]byte{[]byte("key042"), []byte("key200"), {0xab, 3}, []byte("missing")}
+
+			proof, err := GenerateProof(rootHash[:], keys, db)
+			require.NoError(t, err)
+
+			compactProof, err := EncodeCompactProof(proof, rootHash[:])
+			require.NoError(t, err)
+
+			var proofSize, compactProofSize int
+			for _, item := range proof {
+				proofSize += len(item)
+			}
+			for _, item := range compactProof {
+				compactProofSize += len(item)
+			}
+			assert.Less(t, compactProofSize, proofSize)
+
+			decodedProof, err := DecodeCompactProof(compactProof, rootHash[:])
+			require.NoError(t, err)
+
+			// nodes inlined in their parent are not in the compact proof.
+			expectedProof := make([][]byte, 0, len(proof))
+			for _, item := range proof {
+				if len(item) >= common.HashLength {
+					expectedProof = append(expectedProof, item)
+				}
+			}
+			assert.Equal(t, proofSet(expectedProof), proofSet(decodedProof))
+
+			items := []Pair{
+				{Key: []byte("key042"), Value: tr.Get([]byte("key042"))},
+				{Key: []byte("key200"), Value: tr.Get([]byte("key200"))},
+				{Key: []byte{0xab, 3}, Value: []byte{3}},
+			}
+			ok, err := VerifyCompactProof(compactProof, rootHash[:], items)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func Test_EncodeCompactProof_childTries(t *testing.T) {
+	t.Parallel()
+
+	tr, _ := newLazyTestTrie(t, V0)
+	otherChild := NewEmptyTrie()
+	for i := 0; i < 32; i++ {
+		otherChild.Put([]byte(fmt.Sprintf("other%02d", i)), bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	err := tr.PutChild([]byte("other"), otherChild)
+	require.NoError(t, err)
+	rootHash := tr.MustHash()
+
+	child, err := tr.GetChild([]byte("child"))
+	require.NoError(t, err)
+	childRootHash := child.MustHash()
+
+	recorder := record.NewRecorder()
+	for _, keyToChild := range [][]byte{[]byte("child"), []byte("other")} {
+		childKey := append(append([]byte{}, ChildStorageKeyPrefix...), keyToChild...)
+		err = tr.RecordPath(childKey, recorder)
+		require.NoError(t, err)
+	}
+	// only the child trie at "child" is accessed
+	err = child.RecordPath([]byte("child07"), recorder)
+	require.NoError(t, err)
+
+	var proof [][]byte
+	for _, recordedNode := range recorder.GetNodes() {
+		if len(recordedNode.Hash) == common.HashLength {
+			proof = append(proof, recordedNode.RawData)
+		}
+	}
+
+	compactProof, err := EncodeCompactProof(proof, rootHash[:])
+	require.NoError(t, err)
+
+	decodedProof, err := DecodeCompactProof(compactProof, rootHash[:])
+	require.NoError(t, err)
+	assert.Equal(t, proofSet(proof), proofSet(decodedProof))
+
+	ok, err := VerifyProof(decodedProof, childRootHash[:], []Pair{
+		{Key: []byte("child07"), Value: []byte("child value 07")},
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	t.Run("extraneous child trie nodes", func(t *testing.T) {
+		t.Parallel()
+
+		otherDB := newTestDB(t)
+		err := otherChild.Store(otherDB)
+		require.NoError(t, err)
+		otherRootHash := otherChild.MustHash()
+		otherProof, err := GenerateProof(otherRootHash[:], [][]byte{[]byte("other01")}, otherDB)
+		require.NoError(t, err)
+		otherCompactProof, err := EncodeCompactProof(otherProof, otherRootHash[:])
+		require.NoError(t, err)
+
+		// the child trie at "other" is after the child trie at "child"
+		invalidCompactProof := append(append([][]byte{}, compactProof...), otherCompactProof...)
+		_, err = DecodeCompactProof(invalidCompactProof, rootHash[:])
+		assert.NoError(t, err)
+
+		// the child trie at "child" is already decoded
+		invalidCompactProof = append(invalidCompactProof, compactProof[len(compactProof)-1])
+		_, err = DecodeCompactProof(invalidCompactProof, rootHash[:])
+		assert.ErrorIs(t, err, ErrExtraneousCompactProofNodes)
+	})
+}
+
+func Test_DecodeCompactProof_errors(t *testing.T) {
+	t.Parallel()
+
+	tr, db := newLazyTestTrie(t, V1)
+	rootHash := tr.MustHash()
+	proof, err := GenerateProof(rootHash[:], [][]byte{[]byte("key042")}, db)
+	require.NoError(t, err)
+	compactProof, err := EncodeCompactProof(proof, rootHash[:])
+	require.NoError(t, err)
+
+	_, err = DecodeCompactProof(nil, rootHash[:])
+	assert.ErrorIs(t, err, ErrEmptyProof)
+
+	_, err = DecodeCompactProof(compactProof, EmptyHash[:])
+	assert.ErrorIs(t, err, ErrCompactProofRootMismatch)
+
+	_, err = DecodeCompactProof(compactProof[:len(compactProof)-1], rootHash[:])
+	assert.ErrorIs(t, err, ErrIncompleteCompactProof)
+
+	_, err = DecodeCompactProof(append(compactProof, compactProof[0]), rootHash[:])
+	assert.ErrorIs(t, err, ErrExtraneousCompactProofNodes)
+
+	_, err = EncodeCompactProof(proof, EmptyHash[:])
+	assert.ErrorIs(t, err, ErrRootNotInProof)
+}
+
+// Test_EncodeCompactProof_vectors checks the compact proofs match the compact
+// proofs encoded by Substrate's sp_trie::encode_compact, assembled following
+// its node codec. The hashes of the children and values in the proofs are
+// omitted, so only the hashes of the nodes outside the proofs are listed.
+func Test_EncodeCompactProof_vectors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hashed value", func(t *testing.T) {
+		t.Parallel()
+
+		tr := NewEmptyTrie()
+		tr.SetVersion(V1)
+		tr.Put([]byte{0x12}, bytes.Repeat([]byte{0xaa}, 32))
+		tr.Put([]byte{0x34}, bytes.Repeat([]byte{0xbb}, 40))
+		db := newTestDB(t)
+		err := tr.Store(db)
+		require.NoError(t, err)
+
+		rootHash := tr.MustHash()
+		require.Equal(t, common.MustHexToHash(
+			"0xe32f626f6edbe35834205b3d207a6fd5a0bc84e7f9d038e610770b67cbb5c651"), rootHash)
+
+		proof, err := GenerateProof(rootHash[:], [][]byte{{0x12}, {0x34}}, db)
+		require.NoError(t, err)
+
+		compactProof, err := EncodeCompactProof(proof, rootHash[:])
+		require.NoError(t, err)
+
+		expectedCompactProof := [][]byte{
+			// branch without value, children at 1 and 3 omitted
+			common.MustHexToBytes("0x800a000000"),
+			// leaf with key nibble 2 and its inlined value
+			append(common.MustHexToBytes("0x410280"), bytes.Repeat([]byte{0xaa}, 32)...),
+			// escaped leaf with key nibble 4 and its value omitted
+			common.MustHexToBytes("0x01410400"),
+			// value of the leaf with key nibble 4
+			bytes.Repeat([]byte{0xbb}, 40),
+		}
+		assert.Equal(t, expectedCompactProof, compactProof)
+
+		decodedProof, err := DecodeCompactProof(expectedCompactProof, rootHash[:])
+		require.NoError(t, err)
+		assert.Equal(t, proofSet(proof), proofSet(decodedProof))
+	})
+
+	t.Run("child trie", func(t *testing.T) {
+		t.Parallel()
+
+		child := NewEmptyTrie()
+		child.Put([]byte{0x12}, bytes.Repeat([]byte{0xcc}, 32))
+		child.Put([]byte{0x34}, bytes.Repeat([]byte{0xdd}, 32))
+		tr := NewEmptyTrie()
+		err := tr.PutChild([]byte("c"), child)
+		require.NoError(t, err)
+
+		rootHash := tr.MustHash()
+		require.Equal(t, common.MustHexToHash(
+			"0x72955fb44f7f4239971b6e56c510a93ad5c128b9818acaed3be5de8ed3c287fd"), rootHash)
+
+		recorder := record.NewRecorder()
+		err = tr.RecordPath(append(append([]byte{}, ChildStorageKeyPrefix...), 'c'), recorder)
+		require.NoError(t, err)
+		err = child.RecordPath([]byte{0x12}, recorder)
+		require.NoError(t, err)
+
+		var proof [][]byte
+		for _, recordedNode := range recorder.GetNodes() {
+			proof = append(proof, recordedNode.RawData)
+		}
+
+		compactProof, err := EncodeCompactProof(proof, rootHash[:])
+		require.NoError(t, err)
+
+		expectedCompactProof := [][]byte{
+			// state trie leaf with key ":child_storage:default:c" and the child trie root hash
+			common.MustHexToBytes("0x703a6368696c645f73746f726167653a64656661756c743a6380" +
+				"78a0a3807e6ab751d28a0f15a856a9d14508463f4d7742c9a0b8d71103cd7a9f"),
+			// child trie branch without value, with its child at 1 omitted
+			// and the hash of its child at 3, which is not in the proof
+			common.MustHexToBytes("0x800a000080" +
+				"61057428e3ae32ceb8cb673fd38eb45919a623e731c0b36a46698c009f3d3203"),
+			// child trie leaf with key nibble 2 and its inlined value
+			append(common.MustHexToBytes("0x410280"), bytes.Repeat([]byte{0xcc}, 32)...),
+		}
+		assert.Equal(t, expectedCompactProof, compactProof)
+
+		decodedProof, err := DecodeCompactProof(expectedCompactProof, rootHash[:])
+		require.NoError(t, err)
+		assert.Equal(t, proofSet(proof), proofSet(decodedProof))
+	})
+}
@@ -8,7 +8,6 @@
 	"errors"
 	"fmt"
 
-	"github.com/ChainSafe/gossamer/internal/trie/codec"
 	"github.com/ChainSafe/gossamer/internal/trie/node"
 	"github.com/ChainSafe/gossamer/lib/common"
 
@@ -20,6 +19,16 @@
 	ErrDecodeNode = errors.New("cannot decode node")
 )
 
+// Getter gets a value from a key-value store.
+type Getter interface {
+	Get(key []byte) (value []byte, err error)
+}
+
+// Putter puts a value in a key-value store.
+type Putter interface {
+	Put(key, value []byte) error
+}
+
 // Store stores each trie node in the database,
 // where the key is the hash of the encoded node
 // and the value is the encoded node.
@@ -46,7 +55,7 @@ func (t *Trie) store(db chaindb.Batch, n Node) error {
 		return nil
 	}
 
-	encoding, hash, err := n.EncodeAndHash(n == t.root)
+	encoding, hash, err := n.EncodeAndHash(n == t.root, t.version.MaxInlineValue())
 	if err != nil {
 		return err
 	}
@@ -56,6 +65,11 @@ func (t *Trie) store(db chaindb.Batch, n Node) error {
 		return err
 	}
 
+	err = storeHashedValue(db, n, encoding)
+	if err != nil {
+		return err
+	}
+
 	switch n.Type() {
 	case node.BranchType, node.BranchWithValueType:
 		branch := n.(*node.Branch)
@@ -64,7 +78,7 @@ func (t *Trie) store(db chaindb.Batch, n Node) error {
 				continue
 			}
 
-			err = t.store(db, child)
+			err = t.store(db, t.resolve(child))
 			if err != nil {
 				return err
 			}
@@ -79,6 +93,8 @@ func (t *Trie) store(db chaindb.Batch, n Node) error {
 }
 
 // LoadFromProof sets a partial trie based on the proof slice of encoded nodes.
+// The proof slice can also contain values stored by hash in the trie nodes,
+// and values which are not in the proof are left hashed in their node.
 // Note this is exported because it is imported  is used by:
 // https://github.com/ComposableFi/ibc-go/blob/6d62edaa1a3cb0768c430dab81bb195e0b0c72db/modules/light-clients/11-beefy/types/client_state.go#L78
 func (t *Trie) LoadFromProof(proofEncodedNodes [][]byte, rootHash []byte) error {
@@ -86,45 +102,66 @@ func (t *Trie) LoadFromProof(proofEncodedNodes [][]byte, rootHash []byte) error
 		return ErrEmptyProof
 	}
 
-	proofHashToNode := make(map[string]Node, len(proofEncodedNodes))
+	proofHashToEncoding, err := newProofDatabase(proofEncodedNodes)
+	if err != nil {
+		return err
+	}
 
-	for i, rawNode := range proofEncodedNodes {
-		decodedNode, err := node.Decode(bytes.NewReader(rawNode))
-		if err != nil {
-			return fmt.Errorf("%w: at index %d: 0x%x",
-				ErrDecodeNode, i, rawNode)
-		}
+	rootEncoding, ok := proofHashToEncoding[string(rootHash)]
+	if !ok {
+		// root is not in the proof
+		return nil
+	}
 
-		const dirty = false
-		decodedNode.SetDirty(dirty)
-		decodedNode.SetEncodingAndHash(rawNode, nil)
+	root, err := node.Decode(bytes.NewReader(rootEncoding))
+	if err != nil {
+		return fmt.Errorf("%w: root node 0x%x: %s", ErrDecodeNode, rootEncoding, err)
+	}
 
-		_, hash, err := decodedNode.EncodeAndHash(false)
-		if err != nil {
-			return fmt.Errorf("cannot encode and hash node at index %d: %w", i, err)
-		}
+	const dirty = false
+	root.SetDirty(dirty)
+	root.SetEncodingAndHash(rootEncoding, rootHash)
+	t.root = root
+
+	return t.loadProof(proofHashToEncoding, t.root)
+}
 
-		proofHash := common.BytesToHex(hash)
-		proofHashToNode[proofHash] = decodedNode
+// proofDatabase maps the blake2b hashes of the proof items to the proof items.
+type proofDatabase map[string][]byte
 
-		if bytes.Equal(hash, rootHash) {
-			// Found root in proof
-			t.root = decodedNode
+func newProofDatabase(proof [][]byte) (proofDB proofDatabase, err error) {
+	proofDB = make(proofDatabase, len(proof))
+	for i, encoding := range proof {
+		hash, err := common.Blake2bHash(encoding)
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash proof item at index %d: %w", i, err)
 		}
+		proofDB[string(hash[:])] = encoding
 	}
+	return proofDB, nil
+}
 
-	t.loadProof(proofHashToNode, t.root)
-
-	return nil
+// Get returns the proof item with the given hash.
+func (p proofDatabase) Get(hash []byte) (encoding []byte, err error) {
+	encoding, ok := p[string(hash)]
+	if !ok {
+		return nil, fmt.Errorf("%w: 0x%x", chaindb.ErrKeyNotFound, hash)
+	}
+	return encoding, nil
 }
 
 // loadProof is a recursive function that will create all the trie paths based
 // on the mapped proofs slice starting at the root
-func (t *Trie) loadProof(proofHashToNode map[string]Node, n Node) {
+func (t *Trie) loadProof(proofHashToEncoding proofDatabase, n Node) (err error) {
+	err = loadHashedValue(proofHashToEncoding, n)
+	if err != nil && !errors.Is(err, chaindb.ErrKeyNotFound) {
+		return err
+	}
+
 	switch n.Type() {
 	case node.BranchType, node.BranchWithValueType:
 	default:
-		return
+		return nil
 	}
 
 	branch := n.(*node.Branch)
@@ -134,15 +171,40 @@ func (t *Trie) loadProof(proofHashToNode map[string]Node, n Node) {
 			continue
 		}
 
-		proofHash := common.BytesToHex(child.GetHash())
-		node, ok := proofHashToNode[proofHash]
-		if !ok {
+		hash := child.GetHash()
+		encoding := hash
+		if len(hash) == common.HashLength {
+			var ok bool
+			encoding, ok = proofHashToEncoding[string(hash)]
+			if !ok {
+				// node not in the proof
+				continue
+			}
+		} else if child.GetKey() != nil || child.GetValue() != nil {
+			// leaf already decoded inline in the branch
 			continue
 		}
+		// else the child is inlined in the branch with an encoding
+		// of less than 32 bytes, which is stored as its hash digest.
+
+		decodedNode, err := node.Decode(bytes.NewReader(encoding))
+		if err != nil {
+			return fmt.Errorf("%w: child at index %d: 0x%x: %s",
+				ErrDecodeNode, i, encoding, err)
+		}
 
-		branch.Children[i] = node
-		t.loadProof(proofHashToNode, node)
+		const dirty = false
+		decodedNode.SetDirty(dirty)
+		decodedNode.SetEncodingAndHash(encoding, hash)
+
+		branch.Children[i] = decodedNode
+		err = t.loadProof(proofHashToEncoding, decodedNode)
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 // Load reconstructs the trie from the database from the given root hash.
@@ -173,6 +235,11 @@ func (t *Trie) Load(db chaindb.Database, rootHash common.Hash) error {
 }
 
 func (t *Trie) load(db chaindb.Database, n Node) error {
+	err := loadHashedValue(db, n)
+	if err != nil {
+		return err
+	}
+
 	switch n.Type() {
 	case node.BranchType, node.BranchWithValueType:
 	default: // not a branch
@@ -192,7 +259,7 @@ func (t *Trie) load(db chaindb.Database, n Node) error {
 		if len(hash) == 0 && isLeaf {
 			// node has already been loaded inline
 			// just set encoding + hash digest
-			_, _, err := child.EncodeAndHash(false)
+			_, _, err := child.EncodeAndHash(false, t.version.MaxInlineValue())
 			if err != nil {
 				return err
 			}
@@ -234,6 +301,7 @@ func (t *Trie) load(db chaindb.Database, n Node) error {
 
 	for _, key := range t.GetKeysWithPrefix(ChildStorageKeyPrefix) {
 		childTrie := NewEmptyTrie()
+		childTrie.version = t.version
 		value := t.Get(key)
 		rootHash := common.BytesToHash(value)
 		err := childTrie.Load(db, rootHash)
@@ -270,7 +338,7 @@ func (t *Trie) PopulateNodeHashes(n Node, hashesSet map[common.Hash]struct{}) {
 		hash := common.BytesToHash(child.GetHash())
 		hashesSet[hash] = struct{}{}
 
-		t.PopulateNodeHashes(child, hashesSet)
+		t.PopulateNodeHashes(t.resolve(child), hashesSet)
 	}
 }
 
@@ -300,90 +368,30 @@ func (t *Trie) ClearPrefixFromDB(db chaindb.Database, prefix []byte) error {
 }
 
 // GetFromDB retrieves a value at the given key from the trie using the database.
-// It recursively descends into the trie using the database starting
-// from the root node until it reaches the node with the given key.
-// It then reads the value from the database.
+// It descends into the trie starting from the root node until it reaches the node
+// with the given key, decoding the nodes from the database or getting them from
+// the decoded nodes cache.
+// Note it does not copy the value so modifying the value bytes
+// slice will modify the value of the node cached.
 func GetFromDB(db chaindb.Database, rootHash common.Hash, key []byte) (
 	value []byte, err error) {
 	if rootHash == EmptyHash {
 		return nil, nil
 	}
 
-	k := codec.KeyLEToNibbles(key)
-
-	encodedRootNode, err := db.Get(rootHash[:])
-	if err != nil {
-		return nil, fmt.Errorf("cannot find root hash key %s: %w", rootHash, err)
-	}
-
-	reader := bytes.NewReader(encodedRootNode)
-	rootNode, err := node.Decode(reader)
-	if err != nil {
-		return nil, fmt.Errorf("cannot decode root node: %w", err)
-	}
-
-	return getFromDB(db, rootNode, k)
-}
-
-// getFromDB recursively searches through the trie and database
-// for the value corresponding to a key.
-// Note it does not copy the value so modifying the value bytes
-// slice will modify the value of the node in the trie.
-func getFromDB(db chaindb.Database, n Node, key []byte) (
-	value []byte, err error) {
-	leaf, ok := n.(*node.Leaf)
-	if ok {
-		if bytes.Equal(leaf.Key, key) {
-			return leaf.Value, nil
-		}
-		return nil, nil
-	}
-
-	branch := n.(*node.Branch)
-	// Key is equal to the key of this branch or is empty
-	if len(key) == 0 || bytes.Equal(branch.Key, key) {
-		return branch.Value, nil
-	}
-
-	commonPrefixLength := lenCommonPrefix(branch.Key, key)
-	if len(key) < len(branch.Key) && bytes.Equal(branch.Key[:commonPrefixLength], key) {
-		// The key to search is a prefix of the node key and is smaller than the node key.
-		// Example: key to search: 0xabcd
-		//          branch key:    0xabcdef
-		return nil, nil
-	}
-
-	// childIndex is the nibble after the common prefix length in the key being searched.
-	childIndex := key[commonPrefixLength]
-	child := branch.Children[childIndex]
-	if child == nil {
-		return nil, nil
-	}
-
-	// Child can be either inlined or a hash pointer.
-	childHash := child.GetHash()
-	_, isLeaf := child.(*node.Leaf)
-	if len(childHash) == 0 && isLeaf {
-		return getFromDB(db, child, key[commonPrefixLength+1:])
-	}
-
-	encodedChild, err := db.Get(childHash)
+	t := &Trie{lazy: newLazyLoader(db)}
+	const isRoot = true
+	t.root, err = t.lazy.decode(rootHash[:], isRoot)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"cannot find child with hash 0x%x in database: %w",
-			childHash, err)
+		return nil, fmt.Errorf("cannot load root node: %w", err)
 	}
 
-	reader := bytes.NewReader(encodedChild)
-	decodedChild, err := node.Decode(reader)
+	value = t.Get(key)
+	err = t.loadErr()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"cannot decode child node with hash 0x%x: %w",
-			childHash, err)
+		return nil, err
 	}
-
-	return getFromDB(db, decodedChild, key[commonPrefixLength+1:])
-	// Note: do not wrap error since it's called recursively.
+	return value, nil
 }
 
 // WriteDirty writes all dirty nodes to the database and sets them to clean
@@ -403,7 +411,7 @@ func (t *Trie) writeDirty(db chaindb.Batch, n Node) error {
 		return nil
 	}
 
-	encoding, hash, err := n.EncodeAndHash(n == t.root)
+	encoding, hash, err := n.EncodeAndHash(n == t.root, t.version.MaxInlineValue())
 	if err != nil {
 		return fmt.Errorf(
 			"cannot encode and hash node with hash 0x%x: %w",
@@ -417,6 +425,13 @@ func (t *Trie) writeDirty(db chaindb.Batch, n Node) error {
 			hash, err)
 	}
 
+	err = storeHashedValue(db, n, encoding)
+	if err != nil {
+		return fmt.Errorf(
+			"cannot put hashed value of node with hash 0x%x in database: %w",
+			hash, err)
+	}
+
 	switch n.Type() {
 	case node.BranchType, node.BranchWithValueType:
 	default: // not a branch
@@ -451,7 +466,8 @@ func (t *Trie) writeDirty(db chaindb.Batch, n Node) error {
 
 // GetInsertedNodeHashes returns a set of hashes with all
 // the hashes of all nodes that were inserted in the state trie
-// since the last snapshot.
+// since the last snapshot, including the hashes of the values
+// of these nodes stored by hash in the database.
 // We need to compute the hash values of each newly inserted node.
 func (t *Trie) GetInsertedNodeHashes() (hashesSet map[common.Hash]struct{}, err error) {
 	hashesSet = make(map[common.Hash]struct{})
@@ -469,7 +485,7 @@ func (t *Trie) getInsertedNodeHashes(n Node, hashes map[common.Hash]struct{}) (e
 		return nil
 	}
 
-	_, hash, err := n.EncodeAndHash(n == t.root)
+	encoding, hash, err := n.EncodeAndHash(n == t.root, t.version.MaxInlineValue())
 	if err != nil {
 		return fmt.Errorf(
 			"cannot encode and hash node with hash 0x%x: %w",
@@ -478,6 +494,11 @@ func (t *Trie) getInsertedNodeHashes(n Node, hashes map[common.Hash]struct{}) (e
 
 	hashes[common.BytesToHash(hash)] = struct{}{}
 
+	valueKey := hashedValueKey(n, encoding)
+	if valueKey != nil {
+		hashes[common.BytesToHash(valueKey)] = struct{}{}
+	}
+
 	switch n.Type() {
 	case node.BranchType, node.BranchWithValueType:
 	default: // not a branch
@@ -502,7 +523,8 @@ func (t *Trie) getInsertedNodeHashes(n Node, hashes map[common.Hash]struct{}) (e
 }
 
 // GetDeletedNodeHashes returns a set of all the hashes of nodes that were
-// deleted from the trie since the last snapshot was made.
+// deleted from the trie since the last snapshot was made, including the
+// hashes of the values of these nodes stored by hash in the database.
 // The returned set is a copy of the internal set to prevent data races.
 func (t *Trie) GetDeletedNodeHashes() (hashesSet map[common.Hash]struct{}) {
 	hashesSet = make(map[common.Hash]struct{}, len(t.deletedKeys))
@@ -511,3 +533,94 @@ func (t *Trie) GetDeletedNodeHashes() (hashesSet map[common.Hash]struct{}) {
 	}
 	return hashesSet
 }
+
+// storeHashedValue stores the value of the node given in the database,
+// using its blake2b hash as key, if the node encoding stores the value by hash.
+// Nodes holding only the hash of their value, which was not loaded from the
+// database, are skipped since their value is already in the database.
+func storeHashedValue(db Putter, n Node, encoding []byte) error {
+	if hasHashedValue(n) || !node.EncodingHasHashedValue(encoding) {
+		return nil
+	}
+
+	value := n.GetValue()
+	valueHash, err := common.Blake2bHash(value)
+	if err != nil {
+		return fmt.Errorf("cannot hash value: %w", err)
+	}
+
+	return db.Put(valueHash[:], value)
+}
+
+// hashedValueKey returns the database key of the value of the node given,
+// which is the blake2b hash of the value, if the node encoding given stores
+// the value by hash. It returns nil otherwise.
+func hashedValueKey(n Node, encoding []byte) (key []byte) {
+	if !node.EncodingHasHashedValue(encoding) {
+		return nil
+	}
+
+	if hasHashedValue(n) {
+		return n.GetValue()
+	}
+
+	valueHash := common.MustBlake2bHash(n.GetValue())
+	return valueHash[:]
+}
+
+// hasHashedValue returns true if the node given holds
+// the hash of its value instead of the value itself.
+func hasHashedValue(n Node) bool {
+	switch impl := n.(type) {
+	case *node.Leaf:
+		return impl.HashedValue
+	case *node.Branch:
+		return impl.HashedValue
+	default:
+		return false
+	}
+}
+
+// nodeEncoding returns the encoding of the node given
+// cached when it was last encoded or decoded, if any.
+func nodeEncoding(n Node) (encoding []byte) {
+	switch impl := n.(type) {
+	case *node.Leaf:
+		return impl.Encoding
+	case *node.Branch:
+		return impl.Encoding
+	default:
+		return nil
+	}
+}
+
+// loadHashedValue replaces the hash of the value of the node given,
+// if its value is stored by hash, with the value from the database.
+func loadHashedValue(db Getter, n Node) error {
+	switch impl := n.(type) {
+	case *node.Leaf:
+		if !impl.HashedValue {
+			return nil
+		}
+
+		value, err := db.Get(impl.Value)
+		if err != nil {
+			return fmt.Errorf("cannot find value with hash 0x%x: %w", impl.Value, err)
+		}
+		impl.Value = value
+		impl.HashedValue = false
+	case *node.Branch:
+		if !impl.HashedValue {
+			return nil
+		}
+
+		value, err := db.Get(impl.Value)
+		if err != nil {
+			return fmt.Errorf("cannot find value with hash 0x%x: %w", impl.Value, err)
+		}
+		impl.Value = value
+		impl.HashedValue = false
+	}
+
+	return nil
+}
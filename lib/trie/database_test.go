@@ -7,6 +7,8 @@
 	"testing"
 
 	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/lib/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -209,3 +211,109 @@ func Test_Trie_PutChild_Store_Load(t *testing.T) {
 		assert.Equal(t, trie.String(), trieFromDB.String())
 	}
 }
+
+func Test_Trie_Store_Load_V1(t *testing.T) {
+	t.Parallel()
+
+	const size = 200
+	trie, keyValues := makeSeededTrie(t, size)
+	largeValue := make([]byte, 40)
+	largeValue[0] = 1
+	trie.Put([]byte("large value key"), largeValue)
+	keyValues["large value key"] = largeValue
+
+	rootHashV0 := trie.MustHash()
+	trie.SetVersion(V1)
+	rootHash := trie.MustHash()
+	assert.NotEqual(t, rootHashV0, rootHash)
+
+	db := newTestDB(t)
+	err := trie.Store(db)
+	require.NoError(t, err)
+
+	trieFromDB := NewEmptyTrie()
+	trieFromDB.SetVersion(V1)
+	err = trieFromDB.Load(db, rootHash)
+	require.NoError(t, err)
+	assert.Equal(t, trie.String(), trieFromDB.String())
+	assert.Equal(t, rootHash, trieFromDB.MustHash())
+
+	for keyString, expectedValue := range keyValues {
+		key := []byte(keyString)
+		value, err := GetFromDB(db, rootHash, key)
+		require.NoError(t, err)
+		assert.Equal(t, expectedValue, value)
+	}
+}
+
+func Test_Trie_NodeHashes_V1(t *testing.T) {
+	t.Parallel()
+
+	largeValue := func(b byte) []byte {
+		value := make([]byte, 40)
+		value[0] = b
+		return value
+	}
+	valueKey := func(value []byte) common.Hash {
+		return common.MustBlake2bHash(value)
+	}
+
+	trie := NewEmptyTrie()
+	trie.SetVersion(V1)
+	trie.Put([]byte{0x01}, largeValue(1))
+	trie.Put([]byte{0x02}, largeValue(2))
+	trie.Put([]byte{0x03}, largeValue(3))
+
+	inserted, err := trie.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	for _, b := range []byte{1, 2, 3} {
+		assert.Contains(t, inserted, valueKey(largeValue(b)))
+	}
+
+	db := newTestDB(t)
+	err = trie.Store(db)
+	require.NoError(t, err)
+
+	trie = trie.Snapshot()
+	trie.Put([]byte{0x01}, largeValue(4))
+	trie.Delete([]byte{0x02})
+
+	deleted := trie.GetDeletedNodeHashes()
+	assert.Contains(t, deleted, valueKey(largeValue(1)))
+	assert.Contains(t, deleted, valueKey(largeValue(2)))
+	assert.NotContains(t, deleted, valueKey(largeValue(3)))
+
+	inserted, err = trie.GetInsertedNodeHashes()
+	require.NoError(t, err)
+	assert.Contains(t, inserted, valueKey(largeValue(4)))
+	assert.NotContains(t, inserted, valueKey(largeValue(1)))
+}
+
+func Test_storeHashedValue(t *testing.T) {
+	t.Parallel()
+
+	value := make([]byte, 40)
+	valueHash := common.MustBlake2bHash(value)
+
+	// the leaf decoded holds only the hash of its value
+	leaf := &node.Leaf{Key: []byte{1}, Value: valueHash[:], HashedValue: true}
+	encoding, _, err := leaf.EncodeAndHash(false, V1.MaxInlineValue())
+	require.NoError(t, err)
+
+	db := newTestDB(t)
+	err = storeHashedValue(db, leaf, encoding)
+	require.NoError(t, err)
+	has, err := db.Has(valueHash[:])
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	leaf = &node.Leaf{Key: []byte{1}, Value: value}
+	encoding, _, err = leaf.EncodeAndHash(false, V1.MaxInlineValue())
+	require.NoError(t, err)
+
+	err = storeHashedValue(db, leaf, encoding)
+	require.NoError(t, err)
+	stored, err := db.Get(valueHash[:])
+	require.NoError(t, err)
+	assert.Equal(t, value, stored)
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/internal/trie/codec"
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+)
+
+// ChangeKind is the kind of change of a key between two tries.
+type ChangeKind uint8
+
+const (
+	// Added is the kind of change of a key only in the new trie.
+	Added ChangeKind = iota
+	// Modified is the kind of change of a key with different values in both tries.
+	Modified
+	// Deleted is the kind of change of a key only in the old trie.
+	Deleted
+)
+
+func (c ChangeKind) String() string {
+	switch c {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Deleted:
+		return "deleted"
+	default:
+		panic(fmt.Sprintf("change kind %d not implemented", c))
+	}
+}
+
+// Change is a key whose value differs between two tries.
+type Change struct {
+	// KeyToChild is the key of the child trie of the change, without the
+	// child storage key prefix, or nil if the change is in the state trie.
+	KeyToChild []byte
+	// Key is the key changed in little Endian format.
+	Key  []byte
+	Kind ChangeKind
+	// OldValue is the value in the old trie, which is nil if the key is added.
+	OldValue []byte
+	// NewValue is the value in the new trie, which is nil if the key is deleted.
+	NewValue []byte
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("{KeyToChild: 0x%x, Key: 0x%x, Kind: %s, OldValue: 0x%x, NewValue: 0x%x}",
+		c.KeyToChild, c.Key, c.Kind, c.OldValue, c.NewValue)
+}
+
+// Diff returns the keys added, modified and deleted from the old trie to the
+// new trie given, in the state trie and in its child tries. The changes of
+// the state trie are in lexicographic order of their keys, followed by the
+// changes of each child trie changed, in the order of their child trie keys.
+// Both tries are traversed at once, and subtrees with the same hash at the
+// same position are skipped, so the cost is proportional to the changes.
+func Diff(oldTrie, newTrie *Trie) (changes []Change, err error) {
+	differ := &trieDiffer{oldTrie: oldTrie, newTrie: newTrie}
+	err = differ.diff(oldTrie.root, nil, newTrie.root, nil)
+	if err != nil {
+		return nil, err
+	}
+	changes = differ.changes
+
+	for _, change := range changes {
+		if !bytes.HasPrefix(change.Key, ChildStorageKeyPrefix) {
+			continue
+		}
+
+		keyToChild := change.Key[len(ChildStorageKeyPrefix):]
+		childChanges, err := diffChildTries(oldTrie, newTrie, keyToChild, change)
+		if err != nil {
+			return nil, fmt.Errorf("cannot diff child tries at key 0x%x: %w", keyToChild, err)
+		}
+		changes = append(changes, childChanges...)
+	}
+
+	return changes, nil
+}
+
+// diffChildTries returns the changes between the old and new child tries
+// at the child trie key given, whose root hash changed as given.
+func diffChildTries(oldTrie, newTrie *Trie, keyToChild []byte,
+	rootHashChange Change) (changes []Change, err error) {
+	oldChild, newChild := NewEmptyTrie(), NewEmptyTrie()
+	if rootHashChange.OldValue != nil {
+		oldChild, err = getExistingChild(oldTrie, keyToChild)
+		if err != nil {
+			return nil, fmt.Errorf("in old trie: %w", err)
+		}
+	}
+	if rootHashChange.NewValue != nil {
+		newChild, err = getExistingChild(newTrie, keyToChild)
+		if err != nil {
+			return nil, fmt.Errorf("in new trie: %w", err)
+		}
+	}
+
+	differ := &trieDiffer{
+		oldTrie:    oldChild,
+		newTrie:    newChild,
+		keyToChild: keyToChild,
+	}
+	err = differ.diff(oldChild.root, nil, newChild.root, nil)
+	if err != nil {
+		return nil, err
+	}
+	return differ.changes, nil
+}
+
+// getExistingChild returns the child trie at the child trie key given,
+// or an error if the child trie is not loaded in the trie given.
+func getExistingChild(t *Trie, keyToChild []byte) (child *Trie, err error) {
+	child, err = t.GetChild(keyToChild)
+	if err != nil {
+		return nil, err
+	} else if child == nil {
+		return nil, fmt.Errorf("%w: not loaded", ErrChildTrieDoesNotExist)
+	}
+	return child, nil
+}
+
+// trieDiffer accumulates the changes between two tries.
+type trieDiffer struct {
+	oldTrie    *Trie
+	newTrie    *Trie
+	keyToChild []byte
+	changes    []Change
+}
+
+// diff appends the changes between the old node and the new node given,
+// where each prefix given is the key in nibbles of the parent branch of the
+// node followed by the index of the node in the branch children.
+func (d *trieDiffer) diff(oldNode Node, oldPrefix []byte,
+	newNode Node, newPrefix []byte) (err error) {
+	switch {
+	case oldNode == nil && newNode == nil:
+		return nil
+	case oldNode == nil:
+		return d.addAll(d.newTrie, newNode, newPrefix, Added)
+	case newNode == nil:
+		return d.addAll(d.oldTrie, oldNode, oldPrefix, Deleted)
+	case bytes.Equal(oldPrefix, newPrefix) && sameSubtree(oldNode, newNode):
+		return nil
+	}
+
+	oldNode, err = resolveForDiff(d.oldTrie, oldNode)
+	if err != nil {
+		return err
+	}
+	newNode, err = resolveForDiff(d.newTrie, newNode)
+	if err != nil {
+		return err
+	}
+
+	oldKey := concatenateSlices(oldPrefix, oldNode.GetKey())
+	newKey := concatenateSlices(newPrefix, newNode.GetKey())
+
+	switch {
+	case bytes.Equal(oldKey, newKey):
+		d.addValueChange(oldKey, nodeValue(oldNode), nodeValue(newNode))
+		for i := 0; i < node.ChildrenCapacity; i++ {
+			childPrefix := concatenateSlices(oldKey, intToByteSlice(i))
+			err = d.diff(nodeChild(oldNode, i), childPrefix, nodeChild(newNode, i), childPrefix)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case bytes.HasPrefix(newKey, oldKey):
+		// the new node is in the subtree of a child of the old node.
+		d.addValueChange(oldKey, nodeValue(oldNode), nil)
+		newIndex := int(newKey[len(oldKey)])
+		for i := 0; i < node.ChildrenCapacity; i++ {
+			childPrefix := concatenateSlices(oldKey, intToByteSlice(i))
+			if i == newIndex {
+				err = d.diff(nodeChild(oldNode, i), childPrefix, newNode, newPrefix)
+			} else {
+				err = d.diff(nodeChild(oldNode, i), childPrefix, nil, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case bytes.HasPrefix(oldKey, newKey):
+		// the old node is in the subtree of a child of the new node.
+		d.addValueChange(newKey, nil, nodeValue(newNode))
+		oldIndex := int(oldKey[len(newKey)])
+		for i := 0; i < node.ChildrenCapacity; i++ {
+			childPrefix := concatenateSlices(newKey, intToByteSlice(i))
+			if i == oldIndex {
+				err = d.diff(oldNode, oldPrefix, nodeChild(newNode, i), childPrefix)
+			} else {
+				err = d.diff(nil, nil, nodeChild(newNode, i), childPrefix)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case bytes.Compare(oldKey, newKey) < 0:
+		// the subtrees do not overlap
+		err = d.addAll(d.oldTrie, oldNode, oldPrefix, Deleted)
+		if err != nil {
+			return err
+		}
+		return d.addAll(d.newTrie, newNode, newPrefix, Added)
+	default:
+		err = d.addAll(d.newTrie, newNode, newPrefix, Added)
+		if err != nil {
+			return err
+		}
+		return d.addAll(d.oldTrie, oldNode, oldPrefix, Deleted)
+	}
+}
+
+// addAll appends a change of the kind given for each key of the subtree of
+// the node given, where the prefix given is the key in nibbles of the parent
+// branch of the node followed by the index of the node in the branch children.
+func (d *trieDiffer) addAll(t *Trie, n Node, prefix []byte, kind ChangeKind) (err error) {
+	if n == nil {
+		return nil
+	}
+
+	n, err = resolveForDiff(t, n)
+	if err != nil {
+		return err
+	}
+
+	fullKey := concatenateSlices(prefix, n.GetKey())
+	value := nodeValue(n)
+	if value != nil {
+		change := Change{
+			KeyToChild: d.keyToChild,
+			Key:        codec.NibblesToKeyLE(fullKey),
+			Kind:       kind,
+		}
+		if kind == Added {
+			change.NewValue = value
+		} else {
+			change.OldValue = value
+		}
+		d.changes = append(d.changes, change)
+	}
+
+	for i := 0; i < node.ChildrenCapacity; i++ {
+		childPrefix := concatenateSlices(fullKey, intToByteSlice(i))
+		err = d.addAll(t, nodeChild(n, i), childPrefix, kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addValueChange appends the change of the value at the key in nibbles given,
+// if the old and new values differ. A nil value means there is no value.
+func (d *trieDiffer) addValueChange(key, oldValue, newValue []byte) {
+	change := Change{
+		KeyToChild: d.keyToChild,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+	}
+
+	switch {
+	case oldValue == nil && newValue == nil:
+		return
+	case oldValue == nil:
+		change.Kind = Added
+	case newValue == nil:
+		change.Kind = Deleted
+	case bytes.Equal(oldValue, newValue):
+		return
+	default:
+		change.Kind = Modified
+	}
+
+	change.Key = codec.NibblesToKeyLE(key)
+	d.changes = append(d.changes, change)
+}
+
+// sameSubtree returns true if the two nodes given have the same subtree,
+// which is the case if they are the same node, or if they both have the
+// same hash computed from their current content.
+func sameSubtree(a, b Node) bool {
+	if a == b {
+		return true
+	}
+
+	if a.IsDirty() || b.IsDirty() {
+		return false
+	}
+
+	aHash, bHash := a.GetHash(), b.GetHash()
+	return len(aHash) > 0 && bytes.Equal(aHash, bHash)
+}
+
+// resolveForDiff resolves the node given from the trie given,
+// and returns an error if it cannot be resolved.
+func resolveForDiff(t *Trie, n Node) (resolved Node, err error) {
+	resolved = t.resolve(n)
+	if leaf, ok := resolved.(*node.Leaf); ok && leaf.IsStub() {
+		return nil, t.loadErr()
+	}
+	return resolved, nil
+}
+
+// nodeValue returns the value of the node given, which is nil
+// if and only if the node is a branch without value.
+func nodeValue(n Node) (value []byte) {
+	if branch, ok := n.(*node.Branch); ok {
+		return branch.Value
+	}
+
+	value = n.GetValue()
+	if value == nil {
+		// a leaf always has a value
+		value = []byte{}
+	}
+	return value
+}
+
+// nodeChild returns the child at the index given
+// of the node given if it is a branch, and nil otherwise.
+func nodeChild(n Node, index int) (child Node) {
+	branch, ok := n.(*node.Branch)
+	if !ok {
+		return nil
+	}
+	return branch.Children[index]
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectedChanges returns the changes between the old and new entries given,
+// in lexicographic order of their keys.
+func expectedChanges(keyToChild []byte, oldEntries, newEntries map[string][]byte) (changes []Change) {
+	for key, oldValue := range oldEntries {
+		newValue, ok := newEntries[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{KeyToChild: keyToChild, Key: []byte(key),
+				Kind: Deleted, OldValue: oldValue})
+		case !bytes.Equal(oldValue, newValue):
+			changes = append(changes, Change{KeyToChild: keyToChild, Key: []byte(key),
+				Kind: Modified, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for key, newValue := range newEntries {
+		_, ok := oldEntries[key]
+		if !ok {
+			changes = append(changes, Change{KeyToChild: keyToChild, Key: []byte(key),
+				Kind: Added, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Key, changes[j].Key) < 0
+	})
+	return changes
+}
+
+func Test_Diff(t *testing.T) {
+	t.Parallel()
+
+	generator := newGenerator()
+	const size = 500
+	oldEntries := generateKeyValues(t, generator, size)
+
+	oldTrie := NewEmptyTrie()
+	for key, value := range oldEntries {
+		oldTrie.Put([]byte(key), value)
+	}
+
+	newTrie := oldTrie.Snapshot()
+	newEntries := make(map[string][]byte, len(oldEntries))
+	for key, value := range oldEntries {
+		newEntries[key] = value
+	}
+
+	i := 0
+	for key := range oldEntries {
+		switch i % 10 {
+		case 0:
+			newTrie.Delete([]byte(key))
+			delete(newEntries, key)
+		case 1:
+			newValue := generateRandBytes(t, 1+generator.Intn(64), generator)
+			newTrie.Put([]byte(key), newValue)
+			newEntries[key] = newValue
+		case 2:
+			// key with the old key as prefix
+			newKey := append([]byte(key), byte(i))
+			newValue := generateRandBytes(t, 1+generator.Intn(64), generator)
+			newTrie.Put(newKey, newValue)
+			newEntries[string(newKey)] = newValue
+		}
+		i++
+	}
+	for key, value := range generateKeyValues(t, generator, size/10) {
+		newTrie.Put([]byte(key), value)
+		newEntries[key] = value
+	}
+
+	// the old trie is hashed and the new trie is not
+	_ = oldTrie.MustHash()
+
+	changes, err := Diff(oldTrie, newTrie)
+	require.NoError(t, err)
+	assert.Equal(t, expectedChanges(nil, oldEntries, newEntries), changes)
+
+	changes, err = Diff(newTrie, oldTrie)
+	require.NoError(t, err)
+	assert.Equal(t, expectedChanges(nil, newEntries, oldEntries), changes)
+
+	changes, err = Diff(newTrie, newTrie)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = Diff(NewEmptyTrie(), newTrie)
+	require.NoError(t, err)
+	assert.Equal(t, expectedChanges(nil, nil, newEntries), changes)
+}
+
+func Test_Diff_childTries(t *testing.T) {
+	t.Parallel()
+
+	oldTrie, _ := newLazyTestTrie(t, V0)
+	newTrie := oldTrie.DeepCopy()
+
+	err := newTrie.PutIntoChild([]byte("child"), []byte("child07"), []byte("modified"))
+	require.NoError(t, err)
+	err = newTrie.PutIntoChild([]byte("child"), []byte("child64"), []byte("added"))
+	require.NoError(t, err)
+
+	added := NewEmptyTrie()
+	added.Put([]byte("added child key"), []byte("added child value"))
+	err = newTrie.PutChild([]byte("added child"), added)
+	require.NoError(t, err)
+	newTrie.Put([]byte("key042"), []byte("modified"))
+
+	changes, err := Diff(oldTrie, newTrie)
+	require.NoError(t, err)
+
+	childKey := append(append([]byte{}, ChildStorageKeyPrefix...), "child"...)
+	addedChildKey := append(append([]byte{}, ChildStorageKeyPrefix...), "added child"...)
+	expected := []Change{
+		{Key: addedChildKey, Kind: Added, NewValue: newTrie.Get(addedChildKey)},
+		{Key: childKey, Kind: Modified, OldValue: oldTrie.Get(childKey), NewValue: newTrie.Get(childKey)},
+		{Key: []byte("key042"), Kind: Modified, OldValue: oldTrie.Get([]byte("key042")),
+			NewValue: []byte("modified")},
+		{KeyToChild: []byte("added child"), Key: []byte("added child key"), Kind: Added,
+			NewValue: []byte("added child value")},
+		{KeyToChild: []byte("child"), Key: []byte("child07"), Kind: Modified,
+			OldValue: []byte("child value 07"), NewValue: []byte("modified")},
+		{KeyToChild: []byte("child"), Key: []byte("child64"), Kind: Added, NewValue: []byte("added")},
+	}
+	assert.Equal(t, expected, changes)
+}
+
+// countingGetter counts the database reads of a lazy trie.
+type countingGetter struct {
+	Getter
+	reads int
+}
+
+func (c *countingGetter) Get(key []byte) (value []byte, err error) {
+	c.reads++
+	return c.Getter.Get(key)
+}
+
+func Test_Diff_lazyTries(t *testing.T) {
+	t.Parallel()
+
+	// the values are unique to each run, so the nodes
+	// are not found in the shared decoded nodes cache.
+	salt := newGenerator().Uint64()
+	oldTrie := NewEmptyTrie()
+	for i := 0; i < 1000; i++ {
+		oldTrie.Put([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("diffed value %03d %020d", i, salt)))
+	}
+	newTrie := oldTrie.Snapshot()
+	newTrie.Put([]byte("key500"), []byte("modified"))
+	newTrie.Delete([]byte("key501"))
+
+	db := newTestDB(t)
+	err := oldTrie.Store(db)
+	require.NoError(t, err)
+	err = newTrie.Store(db)
+	require.NoError(t, err)
+
+	getter := &countingGetter{Getter: db}
+	oldLazyTrie, err := NewLazyTrie(getter, oldTrie.MustHash())
+	require.NoError(t, err)
+	newLazyTrie, err := NewLazyTrie(getter, newTrie.MustHash())
+	require.NoError(t, err)
+	getter.reads = 0
+
+	changes, err := Diff(oldLazyTrie, newLazyTrie)
+	require.NoError(t, err)
+
+	expected := []Change{
+		{Key: []byte("key500"), Kind: Modified, OldValue: oldTrie.Get([]byte("key500")), NewValue: []byte("modified")},
+		{Key: []byte("key501"), Kind: Deleted, OldValue: oldTrie.Get([]byte("key501"))},
+	}
+	assert.Equal(t, expected, changes)
+	// only the nodes on the paths to the changed keys are read
+	assert.Less(t, getter.reads, 20)
+}
+
+func Test_ChangeKind_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "added", Added.String())
+	assert.Equal(t, "modified", Modified.String())
+	assert.Equal(t, "deleted", Deleted.String())
+	assert.PanicsWithValue(t, "change kind 3 not implemented", func() {
+		_ = ChangeKind(3).String()
+	})
+}
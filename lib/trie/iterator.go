@@ -0,0 +1,329 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/internal/trie/codec"
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// IteratorOptions are the options of an Iterator.
+type IteratorOptions struct {
+	// Prefix, if not empty, restricts the iteration
+	// to the keys starting with it, in little Endian format.
+	Prefix []byte
+	// StartAfter, if not empty, is the key in little Endian format after
+	// which the iteration starts, or before which it starts if Reverse is
+	// true. The key itself is excluded, so an iteration can be resumed by
+	// setting it to the last key returned by a previous iterator.
+	StartAfter []byte
+	// Reverse is true to iterate in reverse lexicographic order.
+	Reverse bool
+}
+
+// iteratorFrame is a node on the path from the root
+// to the current position of an iterator.
+type iteratorFrame struct {
+	node Node
+	// fullKey is the key of the node in nibbles, from the root of the trie.
+	fullKey []byte
+	// childIndex is the index of the next child to visit,
+	// which is out of the children range once all are visited.
+	childIndex int
+	// visited is true once the node itself, as opposed to its children,
+	// is visited, which is when its value is considered by an Iterator.
+	visited bool
+}
+
+// Iterator iterates over the keys and values of a trie in lexicographic
+// order of their keys, resolving the nodes of lazy tries only when they are
+// traversed, so the keys and values are never all held in memory.
+// The trie must not be modified while it is iterated over.
+type Iterator struct {
+	trie       *Trie
+	prefix     []byte
+	startAfter []byte
+	reverse    bool
+	stack      []iteratorFrame
+	key        []byte
+	value      []byte
+	err        error
+}
+
+// NewIterator returns an iterator over the keys and values
+// of the trie, positioned before the first key-value pair.
+func (t *Trie) NewIterator(options IteratorOptions) (iterator *Iterator) {
+	iterator = &Iterator{
+		trie:    t,
+		reverse: options.Reverse,
+	}
+
+	if len(options.Prefix) > 0 {
+		iterator.prefix = codec.KeyLEToNibbles(options.Prefix)
+	}
+	if len(options.StartAfter) > 0 {
+		iterator.startAfter = codec.KeyLEToNibbles(options.StartAfter)
+	}
+
+	if t.root != nil {
+		iterator.push(t.root, nil)
+	}
+
+	return iterator
+}
+
+// Next moves the iterator to the next key-value pair and returns true,
+// or returns false if there is no pair left or an error occurred,
+// in which case the error is returned by Err.
+func (i *Iterator) Next() bool {
+	for len(i.stack) > 0 {
+		frame := &i.stack[len(i.stack)-1]
+
+		// the key of a node is before the keys of its descendants
+		if !i.reverse && !frame.visited {
+			frame.visited = true
+			if i.setPair(frame) {
+				return true
+			}
+		}
+
+		child, childPrefix := i.nextChild(frame)
+		if child != nil {
+			i.push(child, childPrefix)
+			continue
+		}
+
+		if i.reverse && !frame.visited {
+			frame.visited = true
+			if i.setPair(frame) {
+				i.stack = i.stack[:len(i.stack)-1]
+				return true
+			}
+		}
+
+		i.stack = i.stack[:len(i.stack)-1]
+	}
+
+	i.key, i.value = nil, nil
+	return false
+}
+
+// Key returns the key in little Endian format of the current pair.
+func (i *Iterator) Key() (keyLE []byte) {
+	return i.key
+}
+
+// Value returns the value of the current pair.
+func (i *Iterator) Value() (value []byte) {
+	return i.value
+}
+
+// Err returns the error encountered during the iteration, if any.
+func (i *Iterator) Err() error {
+	return i.err
+}
+
+// setPair sets the current pair to the key and value of the node of the
+// frame given if it has a value in the range iterated over, and returns true
+// in this case.
+func (i *Iterator) setPair(frame *iteratorFrame) (ok bool) {
+	var value []byte
+	switch n := frame.node.(type) {
+	case *node.Leaf:
+		value = n.Value
+	case *node.Branch:
+		if n.Value == nil {
+			return false
+		}
+		value = n.Value
+	}
+
+	if !bytes.HasPrefix(frame.fullKey, i.prefix) {
+		return false
+	}
+
+	if i.startAfter != nil {
+		comparison := bytes.Compare(frame.fullKey, i.startAfter)
+		if (!i.reverse && comparison <= 0) || (i.reverse && comparison >= 0) {
+			return false
+		}
+	}
+
+	i.key = codec.NibblesToKeyLE(frame.fullKey)
+	i.value = value
+	return true
+}
+
+// nextChild returns the next child of the node of the frame given which may
+// have keys in the range iterated over, and the key prefix of the child.
+// It returns a nil child if there is no such child left.
+func (i *Iterator) nextChild(frame *iteratorFrame) (child Node, childPrefix []byte) {
+	branch, ok := frame.node.(*node.Branch)
+	if !ok {
+		return nil, nil
+	}
+
+	step := 1
+	if i.reverse {
+		step = -1
+	}
+
+	for ; frame.childIndex >= 0 && frame.childIndex < node.ChildrenCapacity; frame.childIndex += step {
+		child = branch.Children[frame.childIndex]
+		if child == nil {
+			continue
+		}
+
+		childPrefix = concatenateSlices(frame.fullKey, intToByteSlice(frame.childIndex))
+		if i.outOfRange(childPrefix) {
+			continue
+		}
+
+		frame.childIndex += step
+		return child, childPrefix
+	}
+
+	return nil, nil
+}
+
+// push resolves the node given and pushes it on the stack of the iterator
+// if its keys may be in the range iterated over. The prefix given is the key
+// prefix in nibbles of the node, excluding its own partial key.
+func (i *Iterator) push(n Node, prefix []byte) {
+	n = i.trie.resolve(n)
+	if leaf, ok := n.(*node.Leaf); ok && leaf.IsStub() {
+		i.stack = nil
+		i.err = i.trie.loadErr()
+		return
+	}
+
+	fullKey := concatenateSlices(prefix, n.GetKey())
+	if i.outOfRange(fullKey) {
+		return
+	}
+
+	childIndex := 0
+	if i.reverse {
+		childIndex = node.ChildrenCapacity - 1
+	}
+
+	i.stack = append(i.stack, iteratorFrame{
+		node:       n,
+		fullKey:    fullKey,
+		childIndex: childIndex,
+	})
+}
+
+// outOfRange returns true if none of the keys starting with the key
+// prefix in nibbles given are in the range iterated over.
+func (i *Iterator) outOfRange(keyPrefix []byte) bool {
+	if !bytes.HasPrefix(keyPrefix, i.prefix) && !bytes.HasPrefix(i.prefix, keyPrefix) {
+		return true
+	}
+
+	if i.startAfter == nil {
+		return false
+	}
+
+	length := len(keyPrefix)
+	if len(i.startAfter) < length {
+		length = len(i.startAfter)
+	}
+	comparison := bytes.Compare(keyPrefix[:length], i.startAfter[:length])
+	if i.reverse {
+		// keys longer than the start key and starting with it are after it.
+		return comparison > 0 || (comparison == 0 && len(keyPrefix) > len(i.startAfter))
+	}
+	return comparison < 0
+}
+
+// NodeIterator iterates over the nodes of a trie stored in the database by
+// their hash, from the root node in depth-first order, resolving the nodes of
+// lazy tries only when they are traversed. The nodes inlined in their parent
+// are not iterated over. The trie must not be modified while it is iterated over.
+type NodeIterator struct {
+	trie  *Trie
+	stack []iteratorFrame
+	hash  []byte
+	err   error
+}
+
+// NewNodeIterator returns an iterator over the nodes of the trie
+// stored by hash, positioned before the root node.
+func (t *Trie) NewNodeIterator() (iterator *NodeIterator) {
+	iterator = &NodeIterator{trie: t}
+	if t.root != nil {
+		iterator.stack = []iteratorFrame{{node: t.root}}
+	}
+	return iterator
+}
+
+// Next moves the iterator to the next node and returns true, or returns
+// false if there is no node left or an error occurred, in which case the
+// error is returned by Err.
+func (i *NodeIterator) Next() bool {
+	for len(i.stack) > 0 {
+		frame := &i.stack[len(i.stack)-1]
+
+		if !frame.visited {
+			frame.visited = true
+			isRoot := len(i.stack) == 1
+			hash, err := i.resolve(frame, isRoot)
+			if err != nil {
+				i.stack = nil
+				i.err = err
+				return false
+			}
+
+			if len(hash) == common.HashLength {
+				i.hash = hash
+				return true
+			}
+		}
+
+		branch, ok := frame.node.(*node.Branch)
+		if !ok || frame.childIndex >= node.ChildrenCapacity {
+			i.stack = i.stack[:len(i.stack)-1]
+			continue
+		}
+
+		child := branch.Children[frame.childIndex]
+		frame.childIndex++
+		if child != nil {
+			i.stack = append(i.stack, iteratorFrame{node: child})
+		}
+	}
+
+	i.hash = nil
+	return false
+}
+
+// Hash returns the hash of the current node.
+func (i *NodeIterator) Hash() (hash []byte) {
+	return i.hash
+}
+
+// Err returns the error encountered during the iteration, if any.
+func (i *NodeIterator) Err() error {
+	return i.err
+}
+
+// resolve resolves the node of the frame given and returns its hash,
+// which is its encoding if it is inlined in its parent.
+func (i *NodeIterator) resolve(frame *iteratorFrame, isRoot bool) (hash []byte, err error) {
+	frame.node = i.trie.resolve(frame.node)
+	if leaf, ok := frame.node.(*node.Leaf); ok && leaf.IsStub() {
+		return nil, i.trie.loadErr()
+	}
+
+	_, hash, err = frame.node.EncodeAndHash(isRoot, i.trie.version.MaxInlineValue())
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode node: %w", err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectedIteratorKeys returns the keys of the trie given in the range
+// defined by the options given, in the order they should be iterated over.
+func expectedIteratorKeys(tr *Trie, options IteratorOptions) (keys []string) {
+	for key := range tr.Entries() {
+		if !bytes.HasPrefix([]byte(key), options.Prefix) {
+			continue
+		}
+
+		if len(options.StartAfter) > 0 {
+			comparison := bytes.Compare([]byte(key), options.StartAfter)
+			if (!options.Reverse && comparison <= 0) || (options.Reverse && comparison >= 0) {
+				continue
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	if options.Reverse {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+	return keys
+}
+
+func Test_Trie_NewIterator(t *testing.T) {
+	t.Parallel()
+
+	tr, db := newLazyTestTrie(t, V1)
+	lazyTrie, err := NewLazyTrie(db, tr.MustHash())
+	require.NoError(t, err)
+
+	testCases := map[string]IteratorOptions{
+		"all keys":                   {},
+		"all keys reverse":           {Reverse: true},
+		"prefix":                     {Prefix: []byte("key1")},
+		"prefix reverse":             {Prefix: []byte("key1"), Reverse: true},
+		"prefix of a single key":     {Prefix: []byte("key042")},
+		"prefix matching no key":     {Prefix: []byte("kez")},
+		"start after":                {StartAfter: []byte("key100")},
+		"start after reverse":        {StartAfter: []byte("key100"), Reverse: true},
+		"start after missing key":    {StartAfter: []byte("key1000")},
+		"start after key prefix":     {StartAfter: []byte("key")},
+		"start after key prefix rev": {StartAfter: []byte("key"), Reverse: true},
+		"start after last key":       {StartAfter: []byte{0xff}},
+		"prefix and start after":     {Prefix: []byte("key1"), StartAfter: []byte("key15")},
+		"prefix and start after reverse": {
+			Prefix: []byte("key1"), StartAfter: []byte("key15"), Reverse: true,
+		},
+		"start after before prefix": {Prefix: []byte{0xab}, StartAfter: []byte("key")},
+	}
+
+	for name, options := range testCases {
+		options := options
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			expectedKeys := expectedIteratorKeys(tr, options)
+
+			for _, iteratedTrie := range []*Trie{tr, lazyTrie} {
+				var keys []string
+				iterator := iteratedTrie.NewIterator(options)
+				for iterator.Next() {
+					key := iterator.Key()
+					keys = append(keys, string(key))
+					assert.Equal(t, tr.Get(key), iterator.Value())
+				}
+				require.NoError(t, iterator.Err())
+
+				assert.Equal(t, expectedKeys, keys)
+				assert.Nil(t, iterator.Key())
+				assert.Nil(t, iterator.Value())
+			}
+		})
+	}
+}
+
+func Test_Iterator_resume(t *testing.T) {
+	t.Parallel()
+
+	tr, _ := newLazyTestTrie(t, V0)
+
+	for _, reverse := range []bool{false, true} {
+		options := IteratorOptions{Prefix: []byte("key"), Reverse: reverse}
+		expectedKeys := expectedIteratorKeys(tr, options)
+
+		const pageSize = 10
+		var keys []string
+		for {
+			iterator := tr.NewIterator(options)
+			pageKeys := 0
+			for pageKeys < pageSize && iterator.Next() {
+				keys = append(keys, string(iterator.Key()))
+				pageKeys++
+			}
+			require.NoError(t, iterator.Err())
+
+			if pageKeys < pageSize {
+				break
+			}
+			options.StartAfter = iterator.Key()
+		}
+
+		assert.Equal(t, expectedKeys, keys)
+	}
+}
+
+func Test_Iterator_emptyTrie(t *testing.T) {
+	t.Parallel()
+
+	iterator := NewEmptyTrie().NewIterator(IteratorOptions{})
+	assert.False(t, iterator.Next())
+	assert.NoError(t, iterator.Err())
+
+	nodeIterator := NewEmptyTrie().NewNodeIterator()
+	assert.False(t, nodeIterator.Next())
+	assert.NoError(t, nodeIterator.Err())
+}
+
+func Test_Iterator_missingNode(t *testing.T) {
+	t.Parallel()
+
+	// the values are unique to each run, so the nodes
+	// are not found in the shared decoded nodes cache.
+	salt := newGenerator().Uint64()
+	tr := NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("iterated value %02d %020d", i, salt)))
+	}
+
+	db := newTestDB(t)
+	err := tr.Store(db)
+	require.NoError(t, err)
+
+	// remove the node of the last child of the root from the database
+	root := tr.root.(*node.Branch)
+	var missingHash []byte
+	for _, child := range root.Children {
+		if child != nil {
+			missingHash = child.GetHash()
+		}
+	}
+	err = db.Del(missingHash)
+	require.NoError(t, err)
+
+	lazyTrie, err := NewLazyTrie(db, tr.MustHash())
+	require.NoError(t, err)
+
+	iterator := lazyTrie.NewIterator(IteratorOptions{})
+	var keys int
+	for iterator.Next() {
+		keys++
+	}
+	assert.Less(t, keys, 64)
+	assert.ErrorIs(t, iterator.Err(), chaindb.ErrKeyNotFound)
+
+	nodeIterator := lazyTrie.NewNodeIterator()
+	for nodeIterator.Next() {
+	}
+	assert.ErrorIs(t, nodeIterator.Err(), chaindb.ErrKeyNotFound)
+}
+
+func Test_Trie_NewNodeIterator(t *testing.T) {
+	t.Parallel()
+
+	tr := NewEmptyTrie()
+	for i := 0; i < 256; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%03d", i)), bytes.Repeat([]byte{byte(i)}, 1+i%32))
+	}
+	// small keys and values for nodes inlined in their parent
+	for i := 0; i < 16; i++ {
+		tr.Put([]byte{0xab, byte(i)}, []byte{byte(i)})
+	}
+
+	db := newTestDB(t)
+	err := tr.Store(db)
+	require.NoError(t, err)
+	rootHash := tr.MustHash()
+
+	// all the nodes stored in the database are from the trie, and
+	// the nodes inlined in their parent are stored by their encoding.
+	expectedHashes := make(map[string]struct{})
+	databaseIterator := db.NewIterator()
+	for databaseIterator.Next() {
+		if len(databaseIterator.Key()) == common.HashLength {
+			expectedHashes[string(databaseIterator.Key())] = struct{}{}
+		}
+	}
+	databaseIterator.Release()
+
+	lazyTrie, err := NewLazyTrie(db, rootHash)
+	require.NoError(t, err)
+
+	for _, iteratedTrie := range []*Trie{tr, lazyTrie} {
+		hashes := make(map[string]struct{})
+		iterator := iteratedTrie.NewNodeIterator()
+		for iterator.Next() {
+			hash := iterator.Hash()
+			if len(hashes) == 0 {
+				assert.Equal(t, rootHash.ToBytes(), hash)
+			}
+			assert.Len(t, hash, common.HashLength)
+			hashes[string(hash)] = struct{}{}
+		}
+		require.NoError(t, iterator.Err())
+
+		assert.Equal(t, expectedHashes, hashes)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/lib/common"
+)
+
+// lazyLoader resolves the nodes of a lazy trie from its database
+// when they are traversed.
+type lazyLoader struct {
+	db Getter
+	// mutex protects err since nodes can be resolved
+	// concurrently when reading the trie.
+	mutex sync.Mutex
+	// err is the first error encountered resolving a node.
+	err error
+}
+
+func newLazyLoader(db Getter) *lazyLoader {
+	return &lazyLoader{db: db}
+}
+
+// copy returns a loader using the same database for a copy of the trie,
+// keeping the error encountered so far. It returns nil for a nil loader.
+func (l *lazyLoader) copy() *lazyLoader {
+	if l == nil {
+		return nil
+	}
+	return &lazyLoader{
+		db:  l.db,
+		err: l.getErr(),
+	}
+}
+
+// setErr keeps the error given if no error was encountered before.
+func (l *lazyLoader) setErr(err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// getErr returns the first error encountered resolving a node.
+func (l *lazyLoader) getErr() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.err
+}
+
+// NewLazyTrie creates a trie backed by the database given, where only the
+// root node and the root nodes of the child tries are loaded from the database.
+// The other nodes are decoded from the database by their hash only when they are
+// traversed, and are shared with the other tries through the decoded nodes cache.
+// Nodes read are not kept in memory, whereas nodes on the path to the nodes
+// modified are kept, so only the modified nodes are written back to the
+// database by WriteDirty.
+// If a node cannot be resolved from the database, the error is returned by Hash.
+func NewLazyTrie(db Getter, rootHash common.Hash) (t *Trie, err error) {
+	t = NewEmptyTrie()
+	if rootHash == EmptyHash {
+		return t, nil
+	}
+
+	// The nodes decoded from the database are at generation 0 and may be
+	// shared through the decoded nodes cache, so the trie starts at generation 1
+	// for these nodes to be copied before being modified.
+	t.generation = 1
+	t.lazy = newLazyLoader(db)
+	const isRoot = true
+	t.root, err = t.lazy.decode(rootHash.ToBytes(), isRoot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load root node: %w", err)
+	}
+
+	for _, key := range t.GetKeysWithPrefix(ChildStorageKeyPrefix) {
+		childRootHash := common.BytesToHash(t.Get(key))
+		childTrie, err := NewLazyTrie(db, childRootHash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load child trie with root hash %s: %w", childRootHash, err)
+		}
+		t.childTries[childRootHash] = childTrie
+	}
+
+	err = t.loadErr()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load child tries: %w", err)
+	}
+
+	return t, nil
+}
+
+// decode returns the node with the hash given, from the decoded nodes cache
+// or decoded from the database. The hash is the encoding of the node if the
+// node is inlined in its parent. The root node is always read from the database,
+// to check the trie is still stored in the database.
+// The node returned may be shared through the cache and must not be modified.
+func (l *lazyLoader) decode(hash []byte, isRoot bool) (n Node, err error) {
+	if len(hash) < common.HashLength {
+		return l.decodeEncoding(hash, hash)
+	}
+
+	var encoding []byte
+	if isRoot {
+		encoding, err = l.db.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find node with hash 0x%x in database: %w", hash, err)
+		}
+	}
+
+	n = nodeCache.get(hash)
+	if n != nil {
+		return n, nil
+	}
+
+	if encoding == nil {
+		encoding, err = l.db.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find node with hash 0x%x in database: %w", hash, err)
+		}
+	}
+
+	n, err = l.decodeEncoding(hash, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCache.put(hash, n, len(encoding))
+	return n, nil
+}
+
+// decodeEncoding decodes the node with the hash and encoding given,
+// and loads its value from the database if it is stored by hash.
+func (l *lazyLoader) decodeEncoding(hash, encoding []byte) (n Node, err error) {
+	n, err = node.Decode(bytes.NewReader(encoding))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode node with hash 0x%x: %w", hash, err)
+	}
+
+	n.SetDirty(false)
+	n.SetEncodingAndHash(encoding, hash)
+
+	err = loadHashedValue(l.db, n)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load value of node with hash 0x%x: %w", hash, err)
+	}
+
+	branch, ok := n.(*node.Branch)
+	if !ok {
+		return n, nil
+	}
+
+	for _, child := range branch.Children {
+		leaf, ok := child.(*node.Leaf)
+		if !ok || leaf.HashDigest != nil {
+			continue
+		}
+		// The leaf is inlined in the branch and decoded with it, and is already
+		// stored in the database as part of the encoding of the branch.
+		// Its value is small enough to be inlined whatever the trie version.
+		_, _, err = leaf.EncodeAndHash(false, node.NoMaxInlineValue)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode inlined leaf: %w", err)
+		}
+		leaf.SetDirty(false)
+	}
+
+	return n, nil
+}
+
+// loadErr returns the first error encountered loading
+// a node of the trie from the database, if the trie is lazy.
+func (t *Trie) loadErr() error {
+	if t.lazy == nil {
+		return nil
+	}
+	return t.lazy.getErr()
+}
+
+// resolve returns the node stood for by the node given if it is a stub of
+// a lazy trie, decoding it from the database, or the node given otherwise.
+// The error encountered decoding the node is returned by Hash, and the stub
+// is returned in this case.
+func (t *Trie) resolve(n Node) Node {
+	if t.lazy == nil {
+		return n
+	}
+
+	leaf, ok := n.(*node.Leaf)
+	if !ok || !leaf.IsStub() {
+		return n
+	}
+
+	const isRoot = false
+	resolved, err := t.lazy.decode(leaf.HashDigest, isRoot)
+	if err != nil {
+		t.lazy.setErr(err)
+		return n
+	}
+	return resolved
+}
+
+// resolveLoaded returns the node stood for by the node given if it is a stub
+// of a lazy trie which can be resolved without reading the database, since
+// it is in the decoded nodes cache or inlined in its parent, or the node given
+// otherwise. It returns false if the node has to be decoded from the database.
+func (t *Trie) resolveLoaded(n Node) (resolved Node, ok bool) {
+	if t.lazy == nil {
+		return n, true
+	}
+
+	leaf, isLeaf := n.(*node.Leaf)
+	if !isLeaf || !leaf.IsStub() {
+		return n, true
+	}
+
+	if len(leaf.HashDigest) < common.HashLength {
+		return t.resolve(n), true
+	}
+
+	resolved = nodeCache.get(leaf.HashDigest)
+	if resolved == nil {
+		return n, false
+	}
+	return resolved, true
+}
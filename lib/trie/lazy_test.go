@@ -0,0 +1,254 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ChainSafe/chaindb"
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/ChainSafe/gossamer/internal/trie/record"
+	"github.com/ChainSafe/gossamer/lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLazyTestTrie(t *testing.T, version Version) (tr *Trie, db chaindb.Database) {
+	t.Helper()
+
+	tr = NewEmptyTrie()
+	tr.SetVersion(version)
+	for i := 0; i < 256; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%03d", i)), bytes.Repeat([]byte{byte(i)}, 1+i%64))
+	}
+	// small keys and values for nodes inlined in their parent
+	for i := 0; i < 16; i++ {
+		tr.Put([]byte{0xab, byte(i)}, []byte{byte(i)})
+	}
+
+	child := NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		child.Put([]byte(fmt.Sprintf("child%02d", i)), []byte(fmt.Sprintf("child value %02d", i)))
+	}
+	err := tr.PutChild([]byte("child"), child)
+	require.NoError(t, err)
+
+	db = newTestDB(t)
+	err = tr.Store(db)
+	require.NoError(t, err)
+
+	return tr, db
+}
+
+func Test_NewLazyTrie(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []Version{V0, V1} {
+		version := version
+		t.Run(version.String(), func(t *testing.T) {
+			t.Parallel()
+
+			expected, db := newLazyTestTrie(t, version)
+
+			lazyTrie, err := NewLazyTrie(db, expected.MustHash())
+			require.NoError(t, err)
+			lazyTrie.SetVersion(version)
+
+			// only the root node is loaded
+			root := lazyTrie.root.(*node.Branch)
+			for _, child := range root.Children {
+				if child != nil {
+					assert.True(t, child.(*node.Leaf).IsStub())
+				}
+			}
+
+			assert.Equal(t, expected.MustHash(), lazyTrie.MustHash())
+			assert.Equal(t, expected.Entries(), lazyTrie.Entries())
+			assert.Equal(t, expected.Get([]byte("key042")), lazyTrie.Get([]byte("key042")))
+			value, err := lazyTrie.GetWithError([]byte("key043"))
+			require.NoError(t, err)
+			assert.Equal(t, expected.Get([]byte("key043")), value)
+			assert.Equal(t, []byte{0xab, 3}, lazyTrie.NextKey([]byte{0xab, 2}))
+			assert.Equal(t, expected.GetKeysWithPrefix([]byte("key1")), lazyTrie.GetKeysWithPrefix([]byte("key1")))
+
+			value, err = lazyTrie.GetFromChild([]byte("child"), []byte("child07"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("child value 07"), value)
+		})
+	}
+}
+
+func Test_NewLazyTrie_emptyHash(t *testing.T) {
+	t.Parallel()
+
+	lazyTrie, err := NewLazyTrie(newTestDB(t), EmptyHash)
+	require.NoError(t, err)
+	assert.Equal(t, NewEmptyTrie(), lazyTrie)
+}
+
+func Test_NewLazyTrie_rootNotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLazyTrie(newTestDB(t), common.Hash{1})
+	assert.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+}
+
+func Test_LazyTrie_modifications(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []Version{V0, V1} {
+		version := version
+		t.Run(version.String(), func(t *testing.T) {
+			t.Parallel()
+
+			expected, db := newLazyTestTrie(t, version)
+			lazyTrie, err := NewLazyTrie(db, expected.MustHash())
+			require.NoError(t, err)
+			lazyTrie.SetVersion(version)
+
+			for _, tr := range []*Trie{expected, lazyTrie} {
+				tr.Put([]byte("key042"), []byte("modified"))
+				tr.Put([]byte("key0420"), []byte("inserted"))
+				tr.Delete([]byte("key100"))
+				tr.Delete([]byte{0xab, 1})
+				tr.ClearPrefix([]byte("key2"))
+				tr.ClearPrefixLimit([]byte("key1"), 5)
+				err = tr.PutIntoChild([]byte("child"), []byte("child08"), []byte("modified"))
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, expected.MustHash(), lazyTrie.MustHash())
+			assert.Equal(t, expected.Entries(), lazyTrie.Entries())
+
+			// the descendants of the nodes not loaded are not counted
+			assert.LessOrEqual(t, lazyTrie.root.(*node.Branch).Descendants,
+				expected.root.(*node.Branch).Descendants)
+
+			// only the modified nodes are written back to the database
+			insertedHashes, err := lazyTrie.GetInsertedNodeHashes()
+			require.NoError(t, err)
+			expectedInsertedHashes, err := expected.GetInsertedNodeHashes()
+			require.NoError(t, err)
+			assert.Equal(t, expectedInsertedHashes, insertedHashes)
+
+			err = lazyTrie.WriteDirty(db)
+			require.NoError(t, err)
+
+			reloaded, err := NewLazyTrie(db, expected.MustHash())
+			require.NoError(t, err)
+			assert.Equal(t, expected.Entries(), reloaded.Entries())
+		})
+	}
+}
+
+func Test_LazyTrie_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	expected, db := newLazyTestTrie(t, V0)
+	lazyTrie, err := NewLazyTrie(db, expected.MustHash())
+	require.NoError(t, err)
+
+	snapshot := lazyTrie.Snapshot()
+	snapshot.Put([]byte("key042"), []byte("modified"))
+	snapshot.Delete([]byte("key043"))
+
+	assert.Equal(t, []byte("modified"), snapshot.Get([]byte("key042")))
+	assert.Nil(t, snapshot.Get([]byte("key043")))
+	assert.Equal(t, expected.MustHash(), lazyTrie.MustHash())
+	assert.Equal(t, expected.Get([]byte("key042")), lazyTrie.Get([]byte("key042")))
+	assert.Equal(t, expected.Get([]byte("key043")), lazyTrie.Get([]byte("key043")))
+	assert.Contains(t, snapshot.GetDeletedNodeHashes(), common.BytesToHash(lazyTrie.root.GetHash()))
+}
+
+func Test_LazyTrie_GetLoaded(t *testing.T) {
+	t.Parallel()
+
+	// the values are unique to each run, so the nodes
+	// are not found in the shared decoded nodes cache.
+	salt := newGenerator().Uint64()
+	tr := NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value %02d padded to be hashed %d", i, salt)))
+	}
+
+	db := newTestDB(t)
+	err := tr.Store(db)
+	require.NoError(t, err)
+
+	lazyTrie, err := NewLazyTrie(db, tr.MustHash())
+	require.NoError(t, err)
+
+	value, loaded := lazyTrie.GetLoaded([]byte("key07"))
+	assert.False(t, loaded)
+	assert.Nil(t, value)
+
+	// the nodes on the path to the key are decoded by Get
+	expected := lazyTrie.Get([]byte("key07"))
+	value, loaded = lazyTrie.GetLoaded([]byte("key07"))
+	assert.True(t, loaded)
+	assert.Equal(t, expected, value)
+
+	// the key is not in the trie
+	value, loaded = lazyTrie.GetLoaded([]byte("other"))
+	assert.True(t, loaded)
+	assert.Nil(t, value)
+
+	// modified nodes are loaded
+	lazyTrie.Put([]byte("key08"), []byte("modified"))
+	value, loaded = lazyTrie.GetLoaded([]byte("key08"))
+	assert.True(t, loaded)
+	assert.Equal(t, []byte("modified"), value)
+
+	// the nodes of a trie which is not lazy are all loaded
+	value, loaded = tr.GetLoaded([]byte("key09"))
+	assert.True(t, loaded)
+	assert.Equal(t, tr.Get([]byte("key09")), value)
+}
+
+func Test_LazyTrie_nodeNotFound(t *testing.T) {
+	t.Parallel()
+
+	// the values are unique to each run, so the nodes
+	// are not found in the shared decoded nodes cache.
+	salt := newGenerator().Uint64()
+	tr := NewEmptyTrie()
+	for i := 0; i < 64; i++ {
+		tr.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value %02d padded to be hashed %d", i, salt)))
+	}
+
+	db := newTestDB(t)
+	err := tr.Store(db)
+	require.NoError(t, err)
+
+	// remove the node of a child of the root from the database
+	root := tr.root.(*node.Branch)
+	var missingHash []byte
+	for _, child := range root.Children {
+		if child != nil {
+			missingHash = child.GetHash()
+			break
+		}
+	}
+	err = db.Del(missingHash)
+	require.NoError(t, err)
+
+	lazyTrie, err := NewLazyTrie(db, tr.MustHash())
+	require.NoError(t, err)
+
+	for i := 0; i < 64; i++ {
+		_ = lazyTrie.Get([]byte(fmt.Sprintf("key%02d", i)))
+	}
+
+	_, err = lazyTrie.Hash()
+	assert.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+
+	value, err := lazyTrie.GetWithError([]byte("key00"))
+	assert.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+	assert.Nil(t, value)
+
+	err = lazyTrie.RecordPath([]byte("key00"), record.NewRecorder())
+	assert.ErrorIs(t, err, chaindb.ErrKeyNotFound)
+}
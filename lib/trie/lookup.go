@@ -6,23 +6,186 @@
 import (
 	"bytes"
 
+	"github.com/ChainSafe/gossamer/internal/trie/codec"
 	"github.com/ChainSafe/gossamer/internal/trie/node"
 	"github.com/ChainSafe/gossamer/internal/trie/record"
+	"github.com/ChainSafe/gossamer/lib/common"
 )
 
-var _ recorder = (*record.Recorder)(nil)
+var _ Recorder = (*record.Recorder)(nil)
 
-type recorder interface {
+// Recorder records the trie nodes visited, given with their hash and encoding.
+// The hash of a node is its encoding if the node is inlined in its parent.
+// Values stored by hash are recorded as well, given with their hash.
+type Recorder interface {
 	Record(hash, rawData []byte)
 }
 
+// RecordPath records all the nodes on the path from the root to the node
+// with the given key, or to where the key would be if it is not in the trie.
+func (t *Trie) RecordPath(keyLE []byte, recorder Recorder) error {
+	return findAndRecord(t, codec.KeyLEToNibbles(keyLE), recorder)
+}
+
+// RecordPrefix records all the nodes on the path from the root to the nodes
+// with keys starting with the given prefix, as well as all these nodes.
+func (t *Trie) RecordPrefix(prefixLE []byte, recorder Recorder) error {
+	prefix := codec.KeyLEToNibbles(prefixLE)
+	err := t.findPrefixAndRecord(t.root, prefix, recorder, true, t.version.MaxInlineValue())
+	if err != nil {
+		return err
+	}
+	return t.loadErr()
+}
+
+// RecordDelete records the nodes RecordPath records for the given key, as well as
+// the node merged with its parent branch when the key is deleted from the trie
+// given, which is the trie t with the modifications made since it was recorded.
+// The merged node is needed to compute the root hash once the key is deleted.
+func (t *Trie) RecordDelete(keyLE []byte, modified *Trie, recorder Recorder) error {
+	key := codec.KeyLEToNibbles(keyLE)
+	err := findAndRecord(t, key, recorder)
+	if err != nil {
+		return err
+	}
+
+	return t.recordMergedNode(modified, key, false, recorder)
+}
+
+// RecordClearPrefix records the nodes RecordPrefix records for the given prefix, as
+// well as the node merged with its parent branch when the keys with the prefix are
+// deleted from the trie given, which is the trie t with the modifications made since
+// it was recorded. The merged node is needed to compute the root hash once the keys
+// are deleted.
+func (t *Trie) RecordClearPrefix(prefixLE []byte, modified *Trie, recorder Recorder) error {
+	err := t.RecordPrefix(prefixLE, recorder)
+	if err != nil {
+		return err
+	}
+
+	return t.recordMergedNode(modified, codec.KeyLEToNibbles(prefixLE), true, recorder)
+}
+
+// recordMergedNode records the nodes on the path to the node of the modified trie given
+// merged with its parent branch when the nibbles key, or all the keys starting with it if
+// isPrefix is true, are deleted. The node is recorded as found in the trie t, which has it
+// at the same position if the node was not modified.
+func (t *Trie) recordMergedNode(modified *Trie, key []byte, isPrefix bool, recorder Recorder) error {
+	mergedPath := modified.mergedNodePath(key, isPrefix)
+	err := modified.loadErr()
+	if err != nil {
+		return err
+	} else if mergedPath == nil {
+		return nil
+	}
+
+	maxInlineValue := t.version.MaxInlineValue()
+	isCurrentRoot := true
+	for n := t.root; n != nil; isCurrentRoot = false {
+		n = t.resolve(n)
+		enc, hash, err := n.EncodeAndHash(isCurrentRoot, maxInlineValue)
+		if err != nil {
+			return err
+		}
+		recorder.Record(hash, enc)
+
+		b, ok := n.(*node.Branch)
+		if !ok || len(mergedPath) <= len(b.Key) || !bytes.HasPrefix(mergedPath, b.Key) {
+			break
+		}
+		n = b.Children[mergedPath[len(b.Key)]]
+		mergedPath = mergedPath[len(b.Key)+1:]
+	}
+
+	return t.loadErr()
+}
+
+// mergedNodePath returns the nibbles path from the root to the node merged with its parent
+// branch when the nibbles key given, or all the keys starting with it if isPrefix is true,
+// are deleted, which is the path to the parent branch followed by the index of the node in
+// the branch. It returns nil if no node is merged.
+func (t *Trie) mergedNodePath(key []byte, isPrefix bool) (path []byte) {
+	var parent *node.Branch
+	var parentPath, nodePath []byte
+	var index byte
+
+	for n := t.root; n != nil; {
+		n = t.resolve(n)
+		nodeKey := n.GetKey()
+		branch, isBranch := n.(*node.Branch)
+
+		switch {
+		case isPrefix && len(key) <= len(nodeKey):
+			if !bytes.HasPrefix(nodeKey, key) {
+				return nil
+			}
+			// the node is removed with all the nodes under it
+			return onlyChildPath(parent, parentPath, int(index))
+		case !isPrefix && bytes.Equal(nodeKey, key):
+			if n.GetValue() == nil {
+				return nil
+			} else if isBranch {
+				// the branch loses its value
+				return onlyChildPath(branch, nodePath, -1)
+			}
+			// the leaf is removed
+			return onlyChildPath(parent, parentPath, int(index))
+		case !isBranch || len(key) <= len(nodeKey) || !bytes.HasPrefix(key, nodeKey):
+			return nil
+		}
+
+		parent, parentPath, index = branch, nodePath, key[len(nodeKey)]
+		nodePath = append(append(append([]byte{}, nodePath...), nodeKey...), index)
+		key = key[len(nodeKey)+1:]
+		n = branch.Children[index]
+	}
+
+	return nil
+}
+
+// onlyChildPath returns the path to the only child of the branch at the path given other than
+// the child at the excluded index, if the branch has no value, since the branch is merged with
+// this child once its other child or its value is removed. It returns nil otherwise.
+func onlyChildPath(branch *node.Branch, branchPath []byte, excludedIndex int) (path []byte) {
+	if branch == nil || (excludedIndex >= 0 && branch.Value != nil) {
+		return nil
+	}
+
+	onlyChild := -1
+	for i, child := range branch.Children {
+		if child == nil || i == excludedIndex {
+			continue
+		} else if onlyChild >= 0 {
+			return nil
+		}
+		onlyChild = i
+	}
+
+	if onlyChild < 0 {
+		return nil
+	}
+
+	path = append(append([]byte{}, branchPath...), branch.Key...)
+	return append(path, byte(onlyChild))
+}
+
 // findAndRecord search for a desired key recording all the nodes in the path including the desired node
-func findAndRecord(t *Trie, key []byte, recorder recorder) error {
-	return find(t.root, key, recorder, true)
+func findAndRecord(t *Trie, key []byte, recorder Recorder) error {
+	err := t.find(t.root, key, recorder, true, t.version.MaxInlineValue())
+	if err != nil {
+		return err
+	}
+	return t.loadErr()
 }
 
-func find(parent Node, key []byte, recorder recorder, isCurrentRoot bool) error {
-	enc, hash, err := parent.EncodeAndHash(isCurrentRoot)
+func (t *Trie) find(parent Node, key []byte, recorder Recorder, isCurrentRoot bool, maxInlineValue int) error {
+	if parent == nil {
+		return nil
+	}
+
+	parent = t.resolve(parent)
+
+	enc, hash, err := parent.EncodeAndHash(isCurrentRoot, maxInlineValue)
 	if err != nil {
 		return err
 	}
@@ -32,6 +195,9 @@ func find(parent Node, key []byte, recorder recorder, isCurrentRoot bool) error
 	switch parent.Type() {
 	case node.BranchType, node.BranchWithValueType:
 	default: // not a branch
+		if bytes.Equal(parent.GetKey(), key) {
+			return recordHashedValue(parent, enc, recorder)
+		}
 		return nil
 	}
 
@@ -41,13 +207,99 @@ func find(parent Node, key []byte, recorder recorder, isCurrentRoot bool) error
 
 	// found the value at this node
 	if bytes.Equal(b.Key, key) || len(key) == 0 {
-		return nil
+		return recordHashedValue(b, enc, recorder)
 	}
 
 	// did not find value
-	if bytes.Equal(b.Key[:length], key) && len(key) < len(b.Key) {
+	if length < len(b.Key) {
+		return nil
+	}
+
+	return t.find(b.Children[key[length]], key[length+1:], recorder, false, maxInlineValue)
+}
+
+// findPrefixAndRecord records the nodes on the path to the nodes with keys
+// starting with the given nibbles prefix, and all the nodes under them.
+func (t *Trie) findPrefixAndRecord(parent Node, prefix []byte, recorder Recorder,
+	isCurrentRoot bool, maxInlineValue int) error {
+	if parent == nil {
+		return nil
+	}
+
+	parent = t.resolve(parent)
+
+	enc, hash, err := parent.EncodeAndHash(isCurrentRoot, maxInlineValue)
+	if err != nil {
+		return err
+	}
+
+	recorder.Record(hash, enc)
+
+	key := parent.GetKey()
+	if len(prefix) <= len(key) {
+		if !bytes.HasPrefix(key, prefix) {
+			return nil
+		}
+		return t.recordAll(parent, enc, recorder, maxInlineValue)
+	}
+
+	b, ok := parent.(*node.Branch)
+	if !ok || !bytes.HasPrefix(prefix, key) {
+		return nil
+	}
+
+	prefix = prefix[len(key):]
+	return t.findPrefixAndRecord(b.Children[prefix[0]], prefix[1:], recorder, false, maxInlineValue)
+}
+
+// recordAll records the hashed value of the node with the given encoding,
+// and all the nodes under it with their hashed values.
+func (t *Trie) recordAll(n Node, encoding []byte, recorder Recorder, maxInlineValue int) error {
+	err := recordHashedValue(n, encoding, recorder)
+	if err != nil {
+		return err
+	}
+
+	b, ok := n.(*node.Branch)
+	if !ok {
 		return nil
 	}
 
-	return find(b.Children[key[length]], key[length+1:], recorder, false)
+	for _, child := range b.Children {
+		if child == nil {
+			continue
+		}
+
+		child = t.resolve(child)
+		enc, hash, err := child.EncodeAndHash(false, maxInlineValue)
+		if err != nil {
+			return err
+		}
+		recorder.Record(hash, enc)
+
+		err = t.recordAll(child, enc, recorder, maxInlineValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordHashedValue records the value of the node given by its hash
+// if the node encoding stores the value by hash, so the value can be
+// found in the proof.
+func recordHashedValue(n Node, encoding []byte, recorder Recorder) error {
+	if !node.EncodingHasHashedValue(encoding) {
+		return nil
+	}
+
+	value := n.GetValue()
+	valueHash, err := common.Blake2bHash(value)
+	if err != nil {
+		return err
+	}
+
+	recorder.Record(valueHash[:], value)
+	return nil
 }
@@ -0,0 +1,148 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/internal/trie/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLookupTestTrie() *Trie {
+	trie := NewEmptyTrie()
+	trie.Put([]byte("cat"), []byte("cat value padded to 32 bytes ---"))
+	trie.Put([]byte("catapulta"), []byte("catapulta value padded to 32 ---"))
+	trie.Put([]byte("catapora"), []byte("catapora value padded to 32 ----"))
+	trie.Put([]byte("dog"), []byte("dog value padded to 32 bytes ---"))
+	trie.Put([]byte("doguinho"), []byte("doguinho value padded to 32 ----"))
+	return trie
+}
+
+func recordedTrie(t *testing.T, trie *Trie, recorder *record.Recorder) *Trie {
+	t.Helper()
+
+	proof := make([][]byte, 0, len(recorder.GetNodes()))
+	for _, n := range recorder.GetNodes() {
+		proof = append(proof, n.RawData)
+	}
+
+	proofTrie := NewEmptyTrie()
+	err := proofTrie.LoadFromProof(proof, trie.MustHash().ToBytes())
+	require.NoError(t, err)
+	return proofTrie
+}
+
+func Test_Trie_RecordPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		key      []byte
+		found    []string
+		notFound []string
+	}{
+		"leaf": {
+			key:      []byte("catapora"),
+			found:    []string{"catapora"},
+			notFound: []string{"catapulta", "dog"},
+		},
+		"branch": {
+			key:      []byte("cat"),
+			found:    []string{"cat"},
+			notFound: []string{"catapora", "dog"},
+		},
+		"missing key in branch child": {
+			key:      []byte("catb"),
+			found:    []string{"cat"},
+			notFound: []string{"catapora", "dog"},
+		},
+		"missing key diverging from branch key": {
+			key:      []byte("dag"),
+			found:    []string{"dog"},
+			notFound: []string{"cat", "doguinho"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			trie := newLookupTestTrie()
+			recorder := record.NewRecorder()
+
+			err := trie.RecordPath(testCase.key, recorder)
+			require.NoError(t, err)
+
+			proofTrie := recordedTrie(t, trie, recorder)
+			assert.Equal(t, trie.Get(testCase.key), proofTrie.Get(testCase.key))
+			for _, key := range testCase.found {
+				assert.Equal(t, trie.Get([]byte(key)), proofTrie.Get([]byte(key)), key)
+			}
+			for _, key := range testCase.notFound {
+				assert.Nil(t, proofTrie.Get([]byte(key)), key)
+			}
+		})
+	}
+
+	t.Run("empty trie", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := record.NewRecorder()
+		err := NewEmptyTrie().RecordPath([]byte("key"), recorder)
+		require.NoError(t, err)
+		assert.Empty(t, recorder.GetNodes())
+	})
+}
+
+func Test_Trie_RecordPrefix(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		prefix   []byte
+		found    []string
+		notFound []string
+	}{
+		"empty prefix": {
+			found: []string{"cat", "catapulta", "catapora", "dog", "doguinho"},
+		},
+		"prefix of branch": {
+			prefix:   []byte("cata"),
+			found:    []string{"cat", "catapulta", "catapora"},
+			notFound: []string{"dog", "doguinho"},
+		},
+		"prefix in leaf key": {
+			prefix:   []byte("dogu"),
+			found:    []string{"doguinho"},
+			notFound: []string{"cat", "catapora"},
+		},
+		"no key with prefix": {
+			prefix:   []byte("e"),
+			notFound: []string{"cat", "catapora", "dog", "doguinho"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			trie := newLookupTestTrie()
+			recorder := record.NewRecorder()
+
+			err := trie.RecordPrefix(testCase.prefix, recorder)
+			require.NoError(t, err)
+
+			proofTrie := recordedTrie(t, trie, recorder)
+			assert.Equal(t, trie.GetKeysWithPrefix(testCase.prefix), proofTrie.GetKeysWithPrefix(testCase.prefix))
+			for _, key := range testCase.found {
+				assert.Equal(t, trie.Get([]byte(key)), proofTrie.Get([]byte(key)), key)
+			}
+			for _, key := range testCase.notFound {
+				assert.Nil(t, proofTrie.Get([]byte(key)), key)
+			}
+		})
+	}
+}
@@ -36,8 +36,8 @@
 func GenerateProof(root []byte, keys [][]byte, db chaindb.Database) ([][]byte, error) {
 	trackedProofs := make(map[string][]byte)
 
-	proofTrie := NewEmptyTrie()
-	if err := proofTrie.Load(db, common.BytesToHash(root)); err != nil {
+	proofTrie, err := NewLazyTrie(db, common.BytesToHash(root))
+	if err != nil {
 		return nil, err
 	}
 
@@ -45,7 +45,7 @@ func GenerateProof(root []byte, keys [][]byte, db chaindb.Database) ([][]byte, e
 		nk := codec.KeyLEToNibbles(k)
 
 		recorder := record.NewRecorder()
-		err := findAndRecord(proofTrie, nk, recorder)
+		err = findAndRecord(proofTrie, nk, recorder)
 		if err != nil {
 			return nil, err
 		}
@@ -66,6 +66,42 @@ func GenerateProof(root []byte, keys [][]byte, db chaindb.Database) ([][]byte, e
 	return proofs, nil
 }
 
+// GenerateChildProof returns the proof of the keys given in the child trie at the
+// child trie key given, in the trie with the root given. The proof contains the
+// nodes proving the child trie root hash in the trie, followed by the nodes
+// proving the keys in the child trie.
+func GenerateChildProof(root, keyToChild []byte, keys [][]byte, db chaindb.Database) ([][]byte, error) {
+	childStorageKey := concatenateSlices(ChildStorageKeyPrefix, keyToChild)
+	proof, err := GenerateProof(root, [][]byte{childStorageKey}, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate proof of child trie root hash: %w", err)
+	}
+
+	childRootHash, err := GetFromDB(db, common.BytesToHash(root), childStorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get child trie root hash: %w", err)
+	} else if childRootHash == nil {
+		return nil, fmt.Errorf("%w at key 0x%x", ErrChildTrieDoesNotExist, childStorageKey)
+	}
+
+	childProof, err := GenerateProof(childRootHash, keys, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate proof of child trie keys: %w", err)
+	}
+
+	inProof := make(map[string]struct{}, len(proof))
+	for _, encoding := range proof {
+		inProof[string(encoding)] = struct{}{}
+	}
+	for _, encoding := range childProof {
+		if _, ok := inProof[string(encoding)]; !ok {
+			proof = append(proof, encoding)
+		}
+	}
+
+	return proof, nil
+}
+
 // Pair holds the key and value to check while verifying the proof
 type Pair struct{ Key, Value []byte }
 
@@ -101,3 +137,23 @@ func VerifyProof(proof [][]byte, root []byte, items []Pair) (bool, error) {
 
 	return true, nil
 }
+
+// VerifyChildProof ensures the given items are in the child trie at the child trie
+// key given, by verifying the child trie root hash is in the trie with the root
+// given and then verifying the items in the child trie, using the same proof.
+func VerifyChildProof(proof [][]byte, root, keyToChild []byte, items []Pair) (bool, error) {
+	proofTrie := NewEmptyTrie()
+	if err := proofTrie.LoadFromProof(proof, root); err != nil {
+		return false, fmt.Errorf("%w: %s", ErrLoadFromProof, err)
+	}
+
+	childStorageKey := concatenateSlices(ChildStorageKeyPrefix, keyToChild)
+	childRootHash := proofTrie.Get(childStorageKey)
+	if childRootHash == nil {
+		return false, fmt.Errorf("%w: child trie root hash at key 0x%x", ErrKeyNotFound, childStorageKey)
+	} else if len(childRootHash) != common.HashLength {
+		return false, fmt.Errorf("%w: 0x%x at key 0x%x", ErrInvalidChildTrieRoot, childRootHash, childStorageKey)
+	}
+
+	return VerifyProof(proof, childRootHash, items)
+}
@@ -4,6 +4,7 @@
 package trie
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ChainSafe/chaindb"
@@ -225,3 +226,98 @@ func TestLeafNodes_SameHash_DifferentPaths_GenerateAndVerifyProof(t *testing.T)
 	require.NoError(t, err)
 	require.True(t, ok)
 }
+
+func TestGenerateAndVerifyProof_V1(t *testing.T) {
+	t.Parallel()
+
+	memdb, err := chaindb.NewBadgerDB(&chaindb.Config{
+		InMemory: true,
+		DataDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	var (
+		largeValue = []byte("a value larger than thirty two bytes")
+		smallValue = []byte("small")
+		key1       = []byte("worlda")
+		key2       = []byte("worldb")
+		key3       = []byte("world")
+	)
+
+	tt := NewEmptyTrie()
+	tt.SetVersion(V1)
+	tt.Put(key1, largeValue)
+	tt.Put(key2, smallValue)
+	tt.Put(key3, largeValue)
+
+	err = tt.Store(memdb)
+	require.NoError(t, err)
+
+	hash, err := tt.Hash()
+	require.NoError(t, err)
+
+	proof, err := GenerateProof(hash.ToBytes(), [][]byte{key1, key2, key3}, memdb)
+	require.NoError(t, err)
+	require.Contains(t, proof, largeValue)
+
+	pairs := []Pair{
+		{Key: key1, Value: largeValue},
+		{Key: key2, Value: smallValue},
+		{Key: key3, Value: largeValue},
+	}
+
+	ok, err := VerifyProof(proof, hash.ToBytes(), pairs)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// without the value in the proof, the value cannot be verified
+	proofWithoutValue := make([][]byte, 0, len(proof))
+	for _, item := range proof {
+		if !bytes.Equal(item, largeValue) {
+			proofWithoutValue = append(proofWithoutValue, item)
+		}
+	}
+
+	ok, err = VerifyProof(proofWithoutValue, hash.ToBytes(), pairs[:1])
+	require.ErrorIs(t, err, ErrValueNotFound)
+	require.False(t, ok)
+}
+
+func TestGenerateAndVerifyChildProof(t *testing.T) {
+	t.Parallel()
+
+	tr, db := newLazyTestTrie(t, V1)
+	root := tr.MustHash().ToBytes()
+	keys := [][]byte{[]byte("child07"), []byte("child42")}
+
+	proof, err := GenerateChildProof(root, []byte("child"), keys, db)
+	require.NoError(t, err)
+
+	pairs := []Pair{
+		{Key: []byte("child07"), Value: []byte("child value 07")},
+		{Key: []byte("child42"), Value: []byte("child value 42")},
+	}
+	ok, err := VerifyChildProof(proof, root, []byte("child"), pairs)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the proof of the state trie keys only does not prove the child trie root hash
+	stateProof, err := GenerateProof(root, [][]byte{[]byte("key042")}, db)
+	require.NoError(t, err)
+	ok, err = VerifyChildProof(stateProof, root, []byte("child"), pairs)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.False(t, ok)
+
+	// the keys not in the child trie are not proven
+	ok, err = VerifyChildProof(proof, root, []byte("child"), []Pair{{Key: []byte("child64")}})
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.False(t, ok)
+
+	ok, err = VerifyChildProof(proof, root, []byte("child"),
+		[]Pair{{Key: []byte("child07"), Value: []byte("other value")}})
+	require.ErrorIs(t, err, ErrValueNotFound)
+	require.False(t, ok)
+
+	_, err = GenerateChildProof(root, []byte("no child"), keys, db)
+	require.ErrorIs(t, err, ErrChildTrieDoesNotExist)
+}
@@ -6,6 +6,7 @@
 import (
 	"bytes"
 	"fmt"
+	"hash"
 
 	"github.com/ChainSafe/gossamer/internal/trie/codec"
 	"github.com/ChainSafe/gossamer/internal/trie/node"
@@ -22,6 +23,12 @@ type Trie struct {
 	root        Node
 	childTries  map[common.Hash]*Trie
 	deletedKeys map[common.Hash]struct{}
+	// version is the state trie version used to encode
+	// the nodes modified, and defaults to V0.
+	version Version
+	// lazy resolves the nodes of the trie not loaded in memory
+	// from the database, and is nil if the trie is in memory.
+	lazy *lazyLoader
 }
 
 // NewEmptyTrie creates a trie with a nil root
@@ -53,6 +60,8 @@ func (t *Trie) Snapshot() (newTrie *Trie) {
 			generation:  childTrie.generation + 1,
 			root:        childTrie.root.Copy(rootCopySettings),
 			deletedKeys: make(map[common.Hash]struct{}),
+			version:     childTrie.version,
+			lazy:        childTrie.lazy.copy(),
 		}
 	}
 
@@ -61,6 +70,23 @@ func (t *Trie) Snapshot() (newTrie *Trie) {
 		root:        t.root,
 		childTries:  childTries,
 		deletedKeys: make(map[common.Hash]struct{}),
+		version:     t.version,
+		lazy:        t.lazy.copy(),
+	}
+}
+
+// Version returns the state trie version of the trie.
+func (t *Trie) Version() Version {
+	return t.version
+}
+
+// SetVersion sets the state trie version used to encode the nodes of
+// the trie and its child tries which are modified from now on.
+// Nodes which are not modified keep their existing encoding.
+func (t *Trie) SetVersion(version Version) {
+	t.version = version
+	for _, childTrie := range t.childTries {
+		childTrie.SetVersion(version)
 	}
 }
 
@@ -100,16 +126,27 @@ func updateGeneration(currentNode Node, trieGeneration uint64,
 	newNode Node) {
 	newNode = currentNode.Copy(copySettings)
 	newNode.SetGeneration(trieGeneration)
+	registerDeletedNode(currentNode, deletedHashes)
+	return newNode
+}
 
+// registerDeletedNode adds the hash of the node given to the deleted
+// hashes given, as well as the hash of its value if it is stored by hash.
+func registerDeletedNode(n Node, deletedHashes map[common.Hash]struct{}) {
 	// The hash of the node from a previous snapshotted trie
 	// is usually already computed.
-	deletedHashBytes := currentNode.GetHash()
+	deletedHashBytes := n.GetHash()
 	if len(deletedHashBytes) > 0 {
 		deletedHash := common.BytesToHash(deletedHashBytes)
 		deletedHashes[deletedHash] = struct{}{}
 	}
 
-	return newNode
+	// The value of the node is deleted with it if it is stored
+	// by hash, and is inserted back if a new node keeps it.
+	valueKey := hashedValueKey(n, nodeEncoding(n))
+	if valueKey != nil {
+		deletedHashes[common.BytesToHash(valueKey)] = struct{}{}
+	}
 }
 
 // DeepCopy deep copies the trie and returns
@@ -124,6 +161,8 @@ func (t *Trie) DeepCopy() (trieCopy *Trie) {
 
 	trieCopy = &Trie{
 		generation: t.generation,
+		version:    t.version,
+		lazy:       t.lazy.copy(),
 	}
 
 	if t.deletedKeys != nil {
@@ -155,8 +194,9 @@ func (t *Trie) RootNode() Node {
 	return t.root.Copy(copySettings)
 }
 
-// encodeRoot writes the encoding of the root node to the buffer.
-func encodeRoot(root node.Node, buffer node.Buffer) (err error) {
+// encodeRoot writes the encoding of the root node to the buffer,
+// hashing values larger than maxInlineValue.
+func encodeRoot(root node.Node, buffer node.Buffer, maxInlineValue int) (err error) {
 	if root == nil {
 		_, err = buffer.Write([]byte{0})
 		if err != nil {
@@ -164,7 +204,7 @@ func encodeRoot(root node.Node, buffer node.Buffer) (err error) {
 		}
 		return nil
 	}
-	return root.Encode(buffer)
+	return root.Encode(buffer, maxInlineValue)
 }
 
 // MustHash returns the hashed root of the trie.
@@ -178,31 +218,57 @@ func (t *Trie) MustHash() common.Hash {
 	return h
 }
 
-// Hash returns the hashed root of the trie.
+// Hash returns the hashed root of the trie, using its state trie version.
+// For a lazy trie, it returns the first error encountered loading a node
+// from the database, since the trie may not have been modified as expected.
 func (t *Trie) Hash() (rootHash common.Hash, err error) {
+	err = t.loadErr()
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("cannot load trie node from database: %w", err)
+	}
+
 	buffer := pools.EncodingBuffers.Get().(*bytes.Buffer)
 	buffer.Reset()
 	defer pools.EncodingBuffers.Put(buffer)
 
-	err = encodeRoot(t.root, buffer)
+	err = encodeRoot(t.root, buffer, t.version.MaxInlineValue())
 	if err != nil {
 		return [32]byte{}, err
 	}
 
-	return common.Blake2bHash(buffer.Bytes()) // TODO optimisation: use hashers sync pools
+	hasher := pools.Hashers.Get().(hash.Hash)
+	hasher.Reset()
+	defer pools.Hashers.Put(hasher)
+
+	_, err = hasher.Write(buffer.Bytes())
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("cannot hash root encoding: %w", err)
+	}
+
+	hasher.Sum(rootHash[:0])
+	return rootHash, nil
+}
+
+// SetParallelHashingThreshold sets the minimum number of modified nodes
+// in the subtrie of a branch for it to be hashed concurrently with its siblings
+// when hashing a trie. It defaults to node.DefaultParallelEncodingThreshold.
+func SetParallelHashingThreshold(nodes uint32) {
+	node.SetParallelEncodingThreshold(nodes)
 }
 
 // Entries returns all the key-value pairs in the trie as a map of keys to values
 // where the keys are encoded in Little Endian.
 func (t *Trie) Entries() map[string][]byte {
-	return entries(t.root, nil, make(map[string][]byte))
+	return t.entries(t.root, nil, make(map[string][]byte))
 }
 
-func entries(parent Node, prefix []byte, kv map[string][]byte) map[string][]byte {
+func (t *Trie) entries(parent Node, prefix []byte, kv map[string][]byte) map[string][]byte {
 	if parent == nil {
 		return kv
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		parentKey := parent.GetKey()
 		fullKeyNibbles := concatenateSlices(prefix, parentKey)
@@ -222,7 +288,7 @@ func entries(parent Node, prefix []byte, kv map[string][]byte) map[string][]byte
 
 	for i, child := range branch.Children {
 		childPrefix := concatenateSlices(prefix, branch.Key, intToByteSlice(i))
-		entries(child, childPrefix, kv)
+		t.entries(child, childPrefix, kv)
 	}
 
 	return kv
@@ -234,7 +300,7 @@ func (t *Trie) NextKey(keyLE []byte) (nextKeyLE []byte) {
 	prefix := []byte(nil)
 	key := codec.KeyLEToNibbles(keyLE)
 
-	nextKey := findNextKey(t.root, prefix, key)
+	nextKey := t.findNextKey(t.root, prefix, key)
 	if nextKey == nil {
 		return nil
 	}
@@ -243,11 +309,13 @@ func (t *Trie) NextKey(keyLE []byte) (nextKeyLE []byte) {
 	return nextKeyLE
 }
 
-func findNextKey(parent Node, prefix, searchKey []byte) (nextKey []byte) {
+func (t *Trie) findNextKey(parent Node, prefix, searchKey []byte) (nextKey []byte) {
 	if parent == nil {
 		return nil
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		parentLeaf := parent.(*node.Leaf)
 		return findNextKeyLeaf(parentLeaf, prefix, searchKey)
@@ -255,7 +323,7 @@ func findNextKey(parent Node, prefix, searchKey []byte) (nextKey []byte) {
 
 	// Branch
 	parentBranch := parent.(*node.Branch)
-	return findNextKeyBranch(parentBranch, prefix, searchKey)
+	return t.findNextKeyBranch(parentBranch, prefix, searchKey)
 }
 
 func findNextKeyLeaf(leaf *node.Leaf, prefix, searchKey []byte) (nextKey []byte) {
@@ -269,12 +337,12 @@ func findNextKeyLeaf(leaf *node.Leaf, prefix, searchKey []byte) (nextKey []byte)
 	return fullKey
 }
 
-func findNextKeyBranch(parentBranch *node.Branch, prefix, searchKey []byte) (nextKey []byte) {
+func (t *Trie) findNextKeyBranch(parentBranch *node.Branch, prefix, searchKey []byte) (nextKey []byte) {
 	fullKey := concatenateSlices(prefix, parentBranch.Key)
 
 	if bytes.Equal(searchKey, fullKey) {
 		const startChildIndex = 0
-		return findNextKeyChild(parentBranch.Children, startChildIndex, fullKey, searchKey)
+		return t.findNextKeyChild(parentBranch.Children, startChildIndex, fullKey, searchKey)
 	}
 
 	if keyIsLexicographicallyBigger(searchKey, fullKey) {
@@ -282,7 +350,7 @@ func findNextKeyBranch(parentBranch *node.Branch, prefix, searchKey []byte) (nex
 			return nil
 		} else if len(searchKey) > len(fullKey) {
 			startChildIndex := searchKey[len(fullKey)]
-			return findNextKeyChild(parentBranch.Children,
+			return t.findNextKeyChild(parentBranch.Children,
 				startChildIndex, fullKey, searchKey)
 		}
 	}
@@ -292,7 +360,7 @@ func findNextKeyBranch(parentBranch *node.Branch, prefix, searchKey []byte) (nex
 		return fullKey
 	}
 	const startChildIndex = 0
-	return findNextKeyChild(parentBranch.Children, startChildIndex,
+	return t.findNextKeyChild(parentBranch.Children, startChildIndex,
 		fullKey, searchKey)
 }
 
@@ -305,7 +373,7 @@ func keyIsLexicographicallyBigger(key, key2 []byte) (bigger bool) {
 
 // findNextKeyChild searches for a next key in the children
 // given and returns a next key or nil if no next key is found.
-func findNextKeyChild(children [16]node.Node, startIndex byte,
+func (t *Trie) findNextKeyChild(children [16]node.Node, startIndex byte,
 	fullKey, key []byte) (nextKey []byte) {
 	for i := startIndex; i < node.ChildrenCapacity; i++ {
 		child := children[i]
@@ -314,7 +382,7 @@ func findNextKeyChild(children [16]node.Node, startIndex byte,
 		}
 
 		childFullKey := concatenateSlices(fullKey, []byte{i})
-		next := findNextKey(child, childFullKey, key)
+		next := t.findNextKey(child, childFullKey, key)
 		if len(next) > 0 {
 			return next
 		}
@@ -347,6 +415,8 @@ func (t *Trie) insert(parent Node, key, value []byte) (newParent Node, nodesCrea
 		}, nodesCreated
 	}
 
+	parent = t.resolve(parent)
+
 	// TODO ensure all values have dirty set to true
 
 	switch parent.Type() {
@@ -526,26 +596,28 @@ func (t *Trie) GetKeysWithPrefix(prefixLE []byte) (keysLE [][]byte) {
 
 	prefix := []byte{}
 	key := prefixNibbles
-	return getKeysWithPrefix(t.root, prefix, key, keysLE)
+	return t.getKeysWithPrefix(t.root, prefix, key, keysLE)
 }
 
 // getKeysWithPrefix returns all keys in little Endian format that have the
 // prefix given. The prefix and key byte slices are in nibbles format.
 // TODO pass in map of keysLE if order is not needed.
 // TODO do all processing on nibbles keys and then convert to LE.
-func getKeysWithPrefix(parent Node, prefix, key []byte,
+func (t *Trie) getKeysWithPrefix(parent Node, prefix, key []byte,
 	keysLE [][]byte) (newKeysLE [][]byte) {
 	if parent == nil {
 		return keysLE
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		parentLeaf := parent.(*node.Leaf)
 		return getKeysWithPrefixFromLeaf(parentLeaf, prefix, key, keysLE)
 	}
 
 	parentBranch := parent.(*node.Branch)
-	return getKeysWithPrefixFromBranch(parentBranch, prefix, key, keysLE)
+	return t.getKeysWithPrefixFromBranch(parentBranch, prefix, key, keysLE)
 }
 
 func getKeysWithPrefixFromLeaf(parent *node.Leaf, prefix, key []byte,
@@ -557,10 +629,10 @@ func getKeysWithPrefixFromLeaf(parent *node.Leaf, prefix, key []byte,
 	return keysLE
 }
 
-func getKeysWithPrefixFromBranch(parent *node.Branch, prefix, key []byte,
+func (t *Trie) getKeysWithPrefixFromBranch(parent *node.Branch, prefix, key []byte,
 	keysLE [][]byte) (newKeysLE [][]byte) {
 	if len(key) == 0 || bytes.HasPrefix(parent.Key, key) {
-		return addAllKeys(parent, prefix, keysLE)
+		return t.addAllKeys(parent, prefix, keysLE)
 	}
 
 	noPossiblePrefixedKeys :=
@@ -575,18 +647,20 @@ func getKeysWithPrefixFromBranch(parent *node.Branch, prefix, key []byte,
 	child := parent.Children[childIndex]
 	childPrefix := makeChildPrefix(prefix, parent.Key, int(childIndex))
 	childKey := key[1:]
-	return getKeysWithPrefix(child, childPrefix, childKey, keysLE)
+	return t.getKeysWithPrefix(child, childPrefix, childKey, keysLE)
 }
 
 // addAllKeys appends all keys of descendant nodes of the parent node
 // to the slice of keys given and returns this slice.
 // It uses the prefix in nibbles format to determine the full key.
 // The slice of keys has its keys formatted in little Endian.
-func addAllKeys(parent Node, prefix []byte, keysLE [][]byte) (newKeysLE [][]byte) {
+func (t *Trie) addAllKeys(parent Node, prefix []byte, keysLE [][]byte) (newKeysLE [][]byte) {
 	if parent == nil {
 		return keysLE
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		keyLE := makeFullKeyLE(prefix, parent.GetKey())
 		keysLE = append(keysLE, keyLE)
@@ -602,7 +676,7 @@ func addAllKeys(parent Node, prefix []byte, keysLE [][]byte) (newKeysLE [][]byte
 
 	for i, child := range branchParent.Children {
 		childPrefix := makeChildPrefix(prefix, branchParent.Key, i)
-		keysLE = addAllKeys(child, childPrefix, keysLE)
+		keysLE = t.addAllKeys(child, childPrefix, keysLE)
 	}
 
 	return keysLE
@@ -625,22 +699,63 @@ func makeChildPrefix(branchPrefix, branchKey []byte,
 // Note the key argument is given in little Endian format.
 func (t *Trie) Get(keyLE []byte) (value []byte) {
 	keyNibbles := codec.KeyLEToNibbles(keyLE)
-	return retrieve(t.root, keyNibbles)
+	const onlyLoaded = false
+	value, _ = t.retrieve(t.root, keyNibbles, onlyLoaded)
+	return value
+}
+
+// GetLoaded returns the value in the node of the trie which matches
+// its key with the key given, as Get does, without decoding the nodes
+// of a lazy trie from the database. The value returned is nil and loaded
+// is false if a node on the path to the key has to be decoded from the
+// database, since it is neither decoded yet nor in the decoded nodes cache.
+// Note the key argument is given in little Endian format.
+func (t *Trie) GetLoaded(keyLE []byte) (value []byte, loaded bool) {
+	keyNibbles := codec.KeyLEToNibbles(keyLE)
+	const onlyLoaded = true
+	return t.retrieve(t.root, keyNibbles, onlyLoaded)
 }
 
-func retrieve(parent Node, key []byte) (value []byte) {
+// GetWithError returns the value in the node of the trie
+// which matches its key with the key given, and the error
+// encountered loading the nodes of the trie from the database
+// if the trie is lazy, in which case the value returned is nil.
+// Note the key argument is given in little Endian format.
+func (t *Trie) GetWithError(keyLE []byte) (value []byte, err error) {
+	value = t.Get(keyLE)
+	err = t.loadErr()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load trie node: %w", err)
+	}
+	return value, nil
+}
+
+// retrieve returns the value of the key given in the subtrie of the parent
+// node given. If onlyLoaded is true, the nodes of a lazy trie which are not
+// loaded are not decoded from the database, and loaded is false if the key
+// is in the subtrie of such a node.
+func (t *Trie) retrieve(parent Node, key []byte, onlyLoaded bool) (value []byte, loaded bool) {
 	if parent == nil {
-		return nil
+		return nil, true
+	}
+
+	if onlyLoaded {
+		parent, loaded = t.resolveLoaded(parent)
+		if !loaded {
+			return nil, false
+		}
+	} else {
+		parent = t.resolve(parent)
 	}
 
 	if parent.Type() == node.LeafType {
 		leaf := parent.(*node.Leaf)
-		return retrieveFromLeaf(leaf, key)
+		return retrieveFromLeaf(leaf, key), true
 	}
 
 	// Branches
 	branch := parent.(*node.Branch)
-	return retrieveFromBranch(branch, key)
+	return t.retrieveFromBranch(branch, key, onlyLoaded)
 }
 
 func retrieveFromLeaf(leaf *node.Leaf, key []byte) (value []byte) {
@@ -650,20 +765,21 @@ func retrieveFromLeaf(leaf *node.Leaf, key []byte) (value []byte) {
 	return nil
 }
 
-func retrieveFromBranch(branch *node.Branch, key []byte) (value []byte) {
+func (t *Trie) retrieveFromBranch(branch *node.Branch, key []byte, onlyLoaded bool) (
+	value []byte, loaded bool) {
 	if len(key) == 0 || bytes.Equal(branch.Key, key) {
-		return branch.Value
+		return branch.Value, true
 	}
 
 	if len(branch.Key) > len(key) && bytes.HasPrefix(branch.Key, key) {
-		return nil
+		return nil, true
 	}
 
 	commonPrefixLength := lenCommonPrefix(branch.Key, key)
 	childIndex := key[commonPrefixLength]
 	childKey := key[commonPrefixLength+1:]
 	child := branch.Children[childIndex]
-	return retrieve(child, childKey)
+	return t.retrieve(child, childKey, onlyLoaded)
 }
 
 // ClearPrefixLimit deletes the keys having the prefix given in little
@@ -691,6 +807,8 @@ func (t *Trie) clearPrefixLimit(parent Node, prefix []byte, limit uint32) (
 		return nil, 0, 0, true
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		leaf := parent.(*node.Leaf)
 		// if prefix is not found, it's also all deleted.
@@ -745,7 +863,7 @@ func (t *Trie) clearPrefixLimitBranch(branch *node.Branch, prefix []byte, limit
 	branch = t.prepBranchForMutation(branch, copySettings)
 	branch.Children[childIndex] = child
 	branch.SubDescendants(nodesRemoved)
-	newParent, branchChildMerged := handleDeletion(branch, prefix)
+	newParent, branchChildMerged := t.handleDeletion(branch, prefix)
 	if branchChildMerged {
 		nodesRemoved++
 	}
@@ -779,7 +897,7 @@ func (t *Trie) clearPrefixLimitChild(branch *node.Branch, prefix []byte, limit u
 	branch.Children[childIndex] = child
 	branch.SubDescendants(nodesRemoved)
 
-	newParent, branchChildMerged := handleDeletion(branch, prefix)
+	newParent, branchChildMerged := t.handleDeletion(branch, prefix)
 	if branchChildMerged {
 		nodesRemoved++
 	}
@@ -800,6 +918,8 @@ func (t *Trie) deleteNodesLimit(parent Node, prefix []byte, limit uint32) (
 		return nil, valuesDeleted, nodesRemoved
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		valuesDeleted, nodesRemoved = 1, 1
 		return nil, valuesDeleted, nodesRemoved
@@ -831,7 +951,7 @@ func (t *Trie) deleteNodesLimit(parent Node, prefix []byte, limit uint32) (
 
 		branch.SetDirty(true)
 
-		newParent, branchChildMerged = handleDeletion(branch, fullKey)
+		newParent, branchChildMerged = t.handleDeletion(branch, fullKey)
 		if branchChildMerged {
 			nodesRemoved++
 		}
@@ -875,6 +995,8 @@ func (t *Trie) clearPrefix(parent Node, prefix []byte) (
 		return nil, nodesRemoved
 	}
 
+	parent = t.resolve(parent)
+
 	if bytes.HasPrefix(parent.GetKey(), prefix) {
 		nodesRemoved = 1
 		if parent.Type() != node.LeafType { // branch
@@ -906,7 +1028,7 @@ func (t *Trie) clearPrefix(parent Node, prefix []byte) (
 		branch = t.prepBranchForMutation(branch, copySettings)
 		branch.Children[childIndex] = nil
 		var branchChildMerged bool
-		newParent, branchChildMerged = handleDeletion(branch, prefix)
+		newParent, branchChildMerged = t.handleDeletion(branch, prefix)
 		if branchChildMerged {
 			nodesRemoved++
 		}
@@ -933,7 +1055,7 @@ func (t *Trie) clearPrefix(parent Node, prefix []byte) (
 	branch = t.prepBranchForMutation(branch, copySettings)
 	branch.SubDescendants(nodesRemoved)
 	branch.Children[childIndex] = child
-	newParent, branchChildMerged := handleDeletion(branch, prefix)
+	newParent, branchChildMerged := t.handleDeletion(branch, prefix)
 	if branchChildMerged {
 		nodesRemoved++
 	}
@@ -956,8 +1078,13 @@ func (t *Trie) delete(parent Node, key []byte) (
 		return nil, false, nodesRemoved
 	}
 
+	parent = t.resolve(parent)
+
 	if parent.Type() == node.LeafType {
 		if deleteLeaf(parent, key) == nil {
+			if parent.GetGeneration() < t.generation {
+				registerDeletedNode(parent, t.deletedKeys)
+			}
 			const nodesRemoved = 1
 			return nil, true, nodesRemoved
 		}
@@ -987,7 +1114,7 @@ func (t *Trie) deleteBranch(branch *node.Branch, key []byte) (
 		branch.Value = nil
 		deleted = true
 		var branchChildMerged bool
-		newParent, branchChildMerged = handleDeletion(branch, key)
+		newParent, branchChildMerged = t.handleDeletion(branch, key)
 		if branchChildMerged {
 			nodesRemoved = 1
 		}
@@ -1010,7 +1137,7 @@ func (t *Trie) deleteBranch(branch *node.Branch, key []byte) (
 	branch.SubDescendants(nodesRemoved)
 	branch.Children[childIndex] = newChild
 
-	newParent, branchChildMerged := handleDeletion(branch, key)
+	newParent, branchChildMerged := t.handleDeletion(branch, key)
 	if branchChildMerged {
 		nodesRemoved++
 	}
@@ -1024,7 +1151,7 @@ func (t *Trie) deleteBranch(branch *node.Branch, key []byte) (
 // In this first case, branchChildMerged is returned as true to keep track of the removal
 // of one node in callers.
 // If the branch has a value and no child, it will be changed into a leaf.
-func handleDeletion(branch *node.Branch, key []byte) (newNode Node, branchChildMerged bool) {
+func (t *Trie) handleDeletion(branch *node.Branch, key []byte) (newNode Node, branchChildMerged bool) {
 	childrenCount := 0
 	firstChildIndex := -1
 	for i, child := range branch.Children {
@@ -1053,7 +1180,7 @@ func handleDeletion(branch *node.Branch, key []byte) (newNode Node, branchChildM
 	case childrenCount == 1 && branch.Value == nil:
 		const branchChildMerged = true
 		childIndex := firstChildIndex
-		child := branch.Children[firstChildIndex]
+		child := t.resolve(branch.Children[firstChildIndex])
 
 		if child.Type() == node.LeafType {
 			childLeafKey := child.GetKey()
@@ -1014,7 +1014,7 @@ func Test_encodeRoot_fuzz(t *testing.T) {
 			assert.Equal(t, value, retrievedValue)
 		}
 		buffer := bytes.NewBuffer(nil)
-		err := trie.root.Encode(buffer)
+		err := trie.root.Encode(buffer, node.NoMaxInlineValue)
 		require.NoError(t, err)
 		require.NotEmpty(t, buffer.Bytes())
 	}
@@ -6,6 +6,7 @@
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"reflect"
 	"testing"
 
@@ -396,7 +397,7 @@ type bufferCalls struct {
 					Return(testCase.bufferCalls.bytesReturn)
 			}
 
-			err := encodeRoot(testCase.root, buffer)
+			err := encodeRoot(testCase.root, buffer, node.NoMaxInlineValue)
 
 			assert.ErrorIs(t, err, testCase.errWrapped)
 			if testCase.errWrapped != nil {
@@ -510,6 +511,32 @@ func Test_Trie_Hash(t *testing.T) {
 	}
 }
 
+func Test_Trie_Hash_parallel(t *testing.T) {
+	// not parallel since it sets the global parallel hashing threshold
+	defer SetParallelHashingThreshold(node.DefaultParallelEncodingThreshold)
+
+	generator := newGenerator()
+	const size = 5000
+	keyValues := generateKeyValues(t, generator, size)
+
+	buildTrie := func() *Trie {
+		trie := NewEmptyTrie()
+		for keyString, value := range keyValues {
+			trie.Put([]byte(keyString), value)
+		}
+		return trie
+	}
+
+	SetParallelHashingThreshold(math.MaxUint32)
+	sequentialHash := buildTrie().MustHash()
+
+	for _, threshold := range []uint32{0, 1, 16, node.DefaultParallelEncodingThreshold} {
+		SetParallelHashingThreshold(threshold)
+		hash := buildTrie().MustHash()
+		assert.Equal(t, sequentialHash, hash, "threshold %d", threshold)
+	}
+}
+
 func entriesMatch(t *testing.T, expected, actual map[string][]byte) {
 	t.Helper()
 
@@ -969,7 +996,7 @@ func Test_nextKey(t *testing.T) {
 
 			originalTrie := testCase.trie.DeepCopy()
 
-			nextKey := findNextKey(testCase.trie.root, nil, testCase.key)
+			nextKey := testCase.trie.findNextKey(testCase.trie.root, nil, testCase.key)
 
 			assert.Equal(t, testCase.nextKey, nextKey)
 			assert.Equal(t, *originalTrie, testCase.trie) // ensure no mutation
@@ -1908,7 +1935,8 @@ func Test_getKeysWithPrefix(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			keys := getKeysWithPrefix(testCase.parent,
+			trie := new(Trie)
+			keys := trie.getKeysWithPrefix(testCase.parent,
 				testCase.prefix, testCase.key, testCase.keys)
 
 			assert.Equal(t, testCase.expectedKeys, keys)
@@ -1977,7 +2005,8 @@ func Test_addAllKeys(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			keys := addAllKeys(testCase.parent,
+			trie := new(Trie)
+			keys := trie.addAllKeys(testCase.parent,
 				testCase.prefix, testCase.keys)
 
 			assert.Equal(t, testCase.expectedKeys, keys)
@@ -2130,9 +2159,12 @@ func Test_retrieve(t *testing.T) {
 				expectedParent = testCase.parent.Copy(copySettings)
 			}
 
-			value := retrieve(testCase.parent, testCase.key)
+			trie := new(Trie)
+			const onlyLoaded = false
+			value, loaded := trie.retrieve(testCase.parent, testCase.key, onlyLoaded)
 
 			assert.Equal(t, testCase.value, value)
+			assert.True(t, loaded)
 			assert.Equal(t, expectedParent, testCase.parent)
 		})
 	}
@@ -3712,7 +3744,8 @@ func Test_handleDeletion(t *testing.T) {
 				copy(expectedKey, testCase.deletedKey)
 			}
 
-			newNode, branchChildMerged := handleDeletion(testCase.branch, testCase.deletedKey)
+			trie := new(Trie)
+			newNode, branchChildMerged := trie.handleDeletion(testCase.branch, testCase.deletedKey)
 
 			assert.Equal(t, testCase.newNode, newNode)
 			assert.Equal(t, testCase.branchChildMerged, branchChildMerged)
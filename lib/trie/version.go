@@ -0,0 +1,59 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+)
+
+// Version is the state trie version, which defines how the
+// trie nodes are encoded to calculate the trie root hash.
+type Version uint8
+
+const (
+	// V0 is the state trie version 0, where values are always inlined in their node.
+	V0 Version = iota
+	// V1 is the state trie version 1, where values larger than
+	// 32 bytes are stored by hash in their node.
+	V1
+)
+
+// ErrVersionNotValid is returned when a state trie version is not valid.
+var ErrVersionNotValid = errors.New("state trie version not valid")
+
+// ParseVersion returns the state trie version of the given number,
+// as found in the runtime version or given to host functions.
+func ParseVersion(v uint32) (version Version, err error) {
+	switch v {
+	case uint32(V0), uint32(V1):
+		return Version(v), nil
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrVersionNotValid, v)
+	}
+}
+
+// MaxInlineValue returns the maximum size of a value
+// inlined in its node for the state trie version.
+func (v Version) MaxInlineValue() int {
+	switch v {
+	case V1:
+		return 32
+	default:
+		return node.NoMaxInlineValue
+	}
+}
+
+func (v Version) String() string {
+	switch v {
+	case V0:
+		return "v0"
+	case V1:
+		return "v1"
+	default:
+		return fmt.Sprintf("v%d (not valid)", uint8(v))
+	}
+}
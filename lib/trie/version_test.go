@@ -0,0 +1,62 @@
+// Copyright 2022 ChainSafe Systems (ON)
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/ChainSafe/gossamer/internal/trie/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		v          uint32
+		version    Version
+		errWrapped error
+		errMessage string
+	}{
+		"v0": {
+			version: V0,
+		},
+		"v1": {
+			v:       1,
+			version: V1,
+		},
+		"invalid": {
+			v:          2,
+			errWrapped: ErrVersionNotValid,
+			errMessage: "state trie version not valid: 2",
+		},
+		"invalid truncated to v1": {
+			v:          257,
+			errWrapped: ErrVersionNotValid,
+			errMessage: "state trie version not valid: 257",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			version, err := ParseVersion(testCase.v)
+
+			assert.Equal(t, testCase.version, version)
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
+
+func Test_Version_MaxInlineValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, node.NoMaxInlineValue, V0.MaxInlineValue())
+	assert.Equal(t, 32, V1.MaxInlineValue())
+}